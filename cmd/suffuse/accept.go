@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/ipc"
+)
+
+func newAcceptCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "accept",
+		Short: "Apply the clipboard update held by a server started with --hold",
+		Long: `Asks the server running on this host, started with --hold or
+--hold-executables, to write the update it holds from another machine to
+the system clipboard. Only the latest update is held; --discard drops it
+instead, leaving the clipboard as it is. "suffuse paste" prints the content
+before accepting it, and "suffuse undo" restores what accepting it
+replaced.`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, _ []string) error { return runAccept(cmd.Context(), v.GetBool("discard")) },
+	}
+
+	cmd.Flags().Bool("discard", false, "drop the held update instead of applying it")
+	return cmd
+}
+
+func runAccept(ctx context.Context, discard bool) error {
+	if !ipc.IsRunning() {
+		return fmt.Errorf("no suffuse server on this host (IPC socket %s)", ipc.SocketPath())
+	}
+	conn, err := dialIPC()
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewClipboardServiceClient(conn).Accept(ctx, &pb.AcceptRequest{Discard: discard})
+	if err != nil {
+		return fmt.Errorf("accept: %w", err)
+	}
+	verb := "Applied"
+	if discard {
+		verb = "Discarded"
+	}
+	fmt.Printf("%s %s from %s, received %s.\n",
+		verb, strings.Join(resp.AvailableTypes, ", "), dash(resp.Source), tsAge(resp.ReceivedAt))
+	return nil
+}
@@ -0,0 +1,579 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/ipc"
+)
+
+func newAdminCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "admin",
+		Short: "Hub maintenance operations for operators",
+		Long: `Administrative operations against a running suffuse server.
+
+Connects via the local IPC socket when a daemon is running on this host.
+Pass --host to target a remote server directly over TCP.
+
+Destructive operations require --yes. "admin profile" only works over the
+IPC socket, against a server on this host.`,
+	}
+	cmd.AddCommand(newAdminClearCmd())
+	cmd.AddCommand(newAdminRotateTokenCmd())
+	cmd.AddCommand(newAdminProfileCmd())
+	cmd.AddCommand(newAdminBlobsCmd())
+	cmd.AddCommand(newAdminJournalCmd())
+	cmd.AddCommand(newAdminDevicesCmd())
+	cmd.AddCommand(newAdminReloadCmd())
+	return cmd
+}
+
+// addAdminConnFlags adds the connection flags shared by all admin subcommands.
+func addAdminConnFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.String("host", "", "suffuse server host (uses the IPC socket if unset and available)")
+	f.Int("port", 8752, "suffuse server port")
+	f.String("token", "", "shared secret")
+	f.String("tls-ca", "", `CA certificate file to verify the server's certificate against, or "system" (default: verify the key derived from the token)`)
+	f.String("via-ssh", "", "reach the server through this SSH jump host, [user@]host[:port] (only port 22 needs to be open)")
+	f.String("source", defaultSource(), "source identifier")
+	addConfigFlag(cmd)
+}
+
+func newAdminClearCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Clear stored clipboard contents on the server",
+		Long: `Drops the content the server holds for the given clipboards, or for every
+clipboard with --all. Useful for incident response after sensitive data was
+copied. Peers' system clipboards are not modified. Large items held as blobs
+are removed by the server's next collection, or right away with
+"suffuse admin blobs prune".
+
+  suffuse admin clear --all --yes
+  suffuse admin clear --clipboard work --clipboard scratch --yes`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(_ *cobra.Command, _ []string) error { return runAdminClear(v) },
+	}
+
+	f := cmd.Flags()
+	f.StringSlice("clipboard", nil, "clipboard to clear (repeatable)")
+	f.Bool("all", false, "clear every clipboard")
+	f.Bool("yes", false, "confirm the operation")
+	addAdminConnFlags(cmd)
+	cmd.MarkFlagsMutuallyExclusive("clipboard", "all")
+	cmd.MarkFlagsOneRequired("clipboard", "all")
+
+	return cmd
+}
+
+func runAdminClear(v *viper.Viper) error {
+	if !v.GetBool("yes") {
+		return errors.New("refusing to clear clipboards without --yes")
+	}
+
+	conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), v.GetString("token"), v.GetString("source"), v.GetString("tls-ca"), v.GetString("via-ssh"))
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewAdminServiceClient(conn).Clear(context.Background(), &pb.ClearRequest{
+		Clipboards: v.GetStringSlice("clipboard"),
+		All:        v.GetBool("all"),
+	})
+	if err != nil {
+		return fmt.Errorf("clear: %w", err)
+	}
+
+	if len(resp.Cleared) == 0 {
+		fmt.Println("Nothing to clear.")
+		return nil
+	}
+	for _, cb := range resp.Cleared {
+		fmt.Printf("cleared %s\n", cb)
+	}
+	return nil
+}
+
+func newAdminRotateTokenCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "rotate-token",
+		Short: "Switch the server to a new shared secret without a flag day",
+		Long: `Makes a new token primary on the running server while the current token
+stays valid for --grace, then prints the configuration to persist it.
+
+A random token is generated unless --new-token is given. Roll the new token
+out to clients and federated servers before the grace period ends; clients
+still on the old token are rejected afterwards.
+
+  suffuse admin rotate-token --grace 168h --yes`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(_ *cobra.Command, _ []string) error { return runAdminRotateToken(v) },
+	}
+
+	f := cmd.Flags()
+	f.String("new-token", "", "token to switch to (generated if unset)")
+	f.Duration("grace", 7*24*time.Hour, "how long the current token stays valid")
+	f.Bool("yes", false, "confirm the operation")
+	addAdminConnFlags(cmd)
+
+	return cmd
+}
+
+func runAdminRotateToken(v *viper.Viper) error {
+	if !v.GetBool("yes") {
+		return errors.New("refusing to rotate the token without --yes")
+	}
+
+	next := v.GetString("new-token")
+	if next == "" {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return fmt.Errorf("generate token: %w", err)
+		}
+		next = base64.RawURLEncoding.EncodeToString(buf)
+	}
+	current := v.GetString("token")
+
+	conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), current, v.GetString("source"), v.GetString("tls-ca"), v.GetString("via-ssh"))
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewAdminServiceClient(conn).RotateToken(context.Background(), &pb.RotateTokenRequest{
+		Token: next,
+		Grace: durationpb.New(v.GetDuration("grace")),
+	})
+	if err != nil {
+		return fmt.Errorf("rotate-token: %w", err)
+	}
+
+	expires := resp.PreviousExpiresAt.AsTime().UTC().Format(time.RFC3339)
+	prev := current
+	if prev == "" {
+		prev = "<previous token>"
+	}
+	fmt.Printf("New token:        %s\n", next)
+	fmt.Printf("Previous expires: %s\n\n", expires)
+	fmt.Println("The change is live but not persisted. Update the server's suffuse.toml:")
+	fmt.Printf("  token         = %q\n", next)
+	fmt.Printf("  accept-tokens = [%q]\n\n", prev+"@"+expires)
+	fmt.Println("Then set the new token on every client and downstream server.")
+	return nil
+}
+
+func newAdminBlobsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "blobs",
+		Short: "Manage the server's store of large clipboard items",
+	}
+	cmd.AddCommand(newAdminBlobsPruneCmd())
+	return cmd
+}
+
+func newAdminBlobsPruneCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove blobs no clipboard references any more",
+		Long: `Removes blobs of large clipboard items that no clipboard references any
+more, without waiting for the server's periodic collection (--blob-ttl).
+Run it after "admin clear" to drop the content of cleared clipboards from
+memory right away.
+
+--unused-for spares blobs stored or fetched more recently, so peers that
+received a reference just before the item was replaced can still fetch it.
+
+  suffuse admin blobs prune --dry-run
+  suffuse admin blobs prune --unused-for 10m`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, _ []string) error { return runAdminBlobsPrune(cmd.Context(), v) },
+	}
+
+	f := cmd.Flags()
+	f.Duration("unused-for", 0, "only prune blobs not used for this long")
+	f.Bool("dry-run", false, "report what would be pruned without removing anything")
+	addAdminConnFlags(cmd)
+
+	return cmd
+}
+
+func runAdminBlobsPrune(ctx context.Context, v *viper.Viper) error {
+	conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), v.GetString("token"), v.GetString("source"), v.GetString("tls-ca"), v.GetString("via-ssh"))
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	dryRun := v.GetBool("dry-run")
+	resp, err := pb.NewAdminServiceClient(conn).PruneBlobs(ctx, &pb.PruneBlobsRequest{
+		UnusedFor: durationpb.New(v.GetDuration("unused-for")),
+		DryRun:    dryRun,
+	})
+	if err != nil {
+		return fmt.Errorf("blobs prune: %w", err)
+	}
+
+	verb := "Pruned"
+	if dryRun {
+		verb = "Would prune"
+	}
+	fmt.Printf("%s %d blobs (%s); %d blobs (%s) remain.\n",
+		verb, resp.Pruned, fmtBytes(resp.PrunedBytes), resp.Remaining, fmtBytes(resp.RemainingBytes))
+	return nil
+}
+
+func newAdminDevicesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "devices",
+		Short: "List and revoke devices paired with \"suffuse pair\"",
+	}
+	cmd.AddCommand(newAdminDevicesListCmd())
+	cmd.AddCommand(newAdminDevicesRevokeCmd())
+	return cmd
+}
+
+func newAdminDevicesListCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List paired devices with their roles and when they were last seen",
+		Long: `Lists the devices paired with the server through "suffuse pair": their
+name, role, clipboards, the fingerprint of their TLS key and when their
+token was last accepted since the server started.`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, _ []string) error { return runAdminDevicesList(cmd.Context(), v) },
+	}
+
+	cmd.Flags().Bool("json", false, "output raw JSON")
+	addAdminConnFlags(cmd)
+	return cmd
+}
+
+func runAdminDevicesList(ctx context.Context, v *viper.Viper) error {
+	conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), v.GetString("token"), v.GetString("source"), v.GetString("tls-ca"), v.GetString("via-ssh"))
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewAdminServiceClient(conn).ListDevices(ctx, &pb.ListDevicesRequest{})
+	if err != nil {
+		return fmt.Errorf("devices list: %w", err)
+	}
+
+	if v.GetBool("json") {
+		enc, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Println(string(enc))
+		return nil
+	}
+	if len(resp.Devices) == 0 {
+		fmt.Println("No paired devices.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tROLE\tCLIPBOARDS\tFINGERPRINT\tPAIRED\tLAST SEEN")
+	_, _ = fmt.Fprintln(tw, "----\t----\t----------\t-----------\t------\t---------")
+	for _, d := range resp.Devices {
+		clipboards := strings.Join(d.Clipboards, ",")
+		if clipboards == "" {
+			clipboards = "all"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			d.Name, d.Role, clipboards, d.Fingerprint,
+			d.PairedAt.AsTime().Local().Format(time.DateOnly), tsAge(d.LastSeen),
+		)
+	}
+	return tw.Flush()
+}
+
+func newAdminDevicesRevokeCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "revoke <name|fingerprint>",
+		Short: "Stop accepting a paired device's token",
+		Long: `Revokes a paired device, e.g. a lost laptop: the server stops accepting
+its token at once and ends the streams it has open, and forgets it across
+restarts. Nobody else's token changes. A name several devices share is
+refused; revoke those by the fingerprint "admin devices list" shows.
+
+  suffuse admin devices revoke old-laptop --yes`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAdminDevicesRevoke(cmd.Context(), v, args[0])
+		},
+	}
+
+	cmd.Flags().Bool("yes", false, "confirm the operation")
+	addAdminConnFlags(cmd)
+	return cmd
+}
+
+func runAdminDevicesRevoke(ctx context.Context, v *viper.Viper, device string) error {
+	if !v.GetBool("yes") {
+		return errors.New("refusing to revoke a device without --yes")
+	}
+	conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), v.GetString("token"), v.GetString("source"), v.GetString("tls-ca"), v.GetString("via-ssh"))
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewAdminServiceClient(conn).RevokeDevice(ctx, &pb.RevokeDeviceRequest{Device: device})
+	if err != nil {
+		return fmt.Errorf("devices revoke: %w", err)
+	}
+	fmt.Printf("Revoked %s (%s, %s).\n", resp.Device.Name, resp.Device.Role, resp.Device.Fingerprint)
+	return nil
+}
+
+func newAdminJournalCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "journal",
+		Short: "Show what the server did with recent publishes",
+		Long: `Lists entries of the server's event journal (server --journal): each
+publish with its source, clipboard, MIME types and size, and what became of
+it for every peer. Content is never recorded.
+
+A publish is stored, or suppressed as a duplicate or loop, or refused as
+oversized or by a write rule or quota; stored content cleared after the
+server's --clipboard-ttl is recorded as expired. A delivery is delivered, dropped
+(the peer's queue was full), filtered (the peer accepts none of the types),
+hop-limit (not relayed further) or unavailable (referenced content could
+not be fetched).
+
+--involving matches entries published by or delivered to a source, so
+"why did my paste never arrive on laptop" starts with:
+
+  suffuse admin journal --involving laptop --since 1h
+  suffuse admin journal --event 3FZ7QK2M`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, _ []string) error { return runAdminJournal(cmd.Context(), v) },
+	}
+
+	f := cmd.Flags()
+	f.Duration("since", 0, "only show entries from this long ago or later")
+	f.String("involving", "", "only show entries published by or delivered to this source")
+	f.String("clipboard", "", "only show entries for this clipboard")
+	f.String("event", "", "only show entries for this event ID (or a prefix of it)")
+	f.Int("limit", 100, "show at most this many of the most recent entries")
+	f.Bool("json", false, "output raw JSON")
+	addAdminConnFlags(cmd)
+
+	return cmd
+}
+
+func runAdminJournal(ctx context.Context, v *viper.Viper) error {
+	conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), v.GetString("token"), v.GetString("source"), v.GetString("tls-ca"), v.GetString("via-ssh"))
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	req := &pb.JournalRequest{
+		Source:    v.GetString("involving"),
+		Clipboard: v.GetString("clipboard"),
+		EventId:   v.GetString("event"),
+		Limit:     uint32(max(0, v.GetInt("limit"))),
+	}
+	if since := v.GetDuration("since"); since > 0 {
+		req.Since = timestamppb.New(time.Now().Add(-since))
+	}
+	resp, err := pb.NewAdminServiceClient(conn).Journal(ctx, req)
+	if err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+
+	if v.GetBool("json") {
+		enc, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Println(string(enc))
+		return nil
+	}
+	if len(resp.Entries) == 0 {
+		fmt.Println("No matching entries.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "TIME\tEVENT\tKIND\tCLIPBOARD\tSOURCE\tPEER\tTYPES\tSIZE\tOUTCOME")
+	_, _ = fmt.Fprintln(tw, "----\t-----\t----\t---------\t------\t----\t-----\t----\t-------")
+	for _, e := range resp.Entries {
+		event := e.EventId
+		if len(event) > 8 {
+			event = event[:8]
+		}
+		peer := e.PeerSource
+		if peer == "" {
+			peer = e.Peer
+		}
+		outcome := e.Outcome
+		if e.Detail != "" {
+			outcome += ": " + e.Detail
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Time.AsTime().Local().Format("15:04:05.000"), dash(event), e.Kind, e.Clipboard,
+			dash(e.Source), dash(peer), dash(strings.Join(e.MimeTypes, ",")), fmtBytes(e.Size), outcome,
+		)
+	}
+	return tw.Flush()
+}
+
+// dash returns s, or "-" when it is empty.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func newAdminProfileCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Capture CPU, heap, and goroutine profiles from the local server",
+		Long: `Asks the server running on this host to capture runtime profiles and
+writes them to --output as suffuse-<profile>-<time>.pb.gz, ready for
+"go tool pprof". The CPU profile samples for --seconds; the others are
+snapshots taken when it ends.
+
+Profiles are served only on the local IPC socket, so pprof never has to be
+exposed on the network.
+
+  suffuse admin profile --seconds 30
+  suffuse admin profile --profiles heap,goroutine --output /tmp`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, _ []string) error { return runAdminProfile(cmd.Context(), v) },
+	}
+
+	f := cmd.Flags()
+	f.Int("seconds", 30, "how long to sample the CPU profile")
+	f.StringSlice("profiles", []string{"cpu", "heap", "goroutine"}, "profiles to capture (cpu, heap, goroutine, allocs, block, mutex, threadcreate)")
+	f.String("output", ".", "directory to write the profiles to")
+	addConfigFlag(cmd)
+
+	return cmd
+}
+
+func runAdminProfile(ctx context.Context, v *viper.Viper) error {
+	seconds := v.GetInt("seconds")
+	if seconds < 1 {
+		return errors.New("--seconds must be at least 1")
+	}
+	dir := v.GetString("output")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if !ipc.IsRunning() {
+		return fmt.Errorf("no suffuse server on this host (IPC socket %s); profiles are only served locally", ipc.SocketPath())
+	}
+	conn, err := grpc.NewClient("unix://"+ipc.SocketPath(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(256<<20)),
+	)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	d := time.Duration(seconds) * time.Second
+	profiles := getStringSlice(v, "profiles")
+	if slices.Contains(profiles, "cpu") {
+		fmt.Fprintf(os.Stderr, "Profiling CPU for %s…\n", d)
+	}
+	resp, err := pb.NewAdminServiceClient(conn).Profile(ctx, &pb.ProfileRequest{
+		Profiles: profiles,
+		Duration: durationpb.New(d),
+	})
+	if err != nil {
+		return fmt.Errorf("profile: %w", err)
+	}
+
+	stamp := time.Now().Format("20060102-150405")
+	for _, p := range resp.Profiles {
+		path := filepath.Join(dir, fmt.Sprintf("suffuse-%s-%s.pb.gz", p.Name, stamp))
+		if err := os.WriteFile(path, p.Data, 0o644); err != nil {
+			return err
+		}
+		fmt.Println(path)
+	}
+	return nil
+}
+
+func newAdminReloadCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "reload",
+		Short: "Reload the server's configuration file",
+		Long: `Makes the server reread its configuration file and apply the settings that
+can change while it runs, as sending it SIGHUP does: the log level, the hub
+rules (mirrors, protected clipboards, content filters, snippets, item types
+and size limits) and the upstream links. Prints the settings that changed.
+An invalid configuration is refused and the running one stays in force.
+
+  suffuse admin reload`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(_ *cobra.Command, _ []string) error { return runAdminReload(v) },
+	}
+	addAdminConnFlags(cmd)
+	return cmd
+}
+
+func runAdminReload(v *viper.Viper) error {
+	conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), v.GetString("token"), v.GetString("source"), v.GetString("tls-ca"), v.GetString("via-ssh"))
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewAdminServiceClient(conn).Reload(context.Background(), &pb.ReloadRequest{})
+	if err != nil {
+		return fmt.Errorf("reload: %w", err)
+	}
+	if len(resp.Changed) == 0 {
+		fmt.Println("Configuration unchanged.")
+		return nil
+	}
+	for _, name := range resp.Changed {
+		fmt.Printf("changed %s\n", name)
+	}
+	return nil
+}
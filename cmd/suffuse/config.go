@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"go.klb.dev/suffuse/internal/e2e"
 	"go.klb.dev/suffuse/internal/logging"
+	"go.klb.dev/suffuse/internal/signing"
 )
 
 // bindViper wires a command's flags into a viper instance with the standard
@@ -69,6 +72,84 @@ func configPaths() []string {
 	return paths
 }
 
+// getStringSlice returns a list-valued setting, additionally splitting
+// comma-separated entries so SUFFUSE_* env vars can carry lists
+// (e.g. SUFFUSE_UPSTREAM_PUBLISH=default,team/*).
+func getStringSlice(v *viper.Viper, key string) []string {
+	var out []string
+	for _, s := range v.GetStringSlice(key) {
+		for _, part := range strings.Split(s, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+// addE2EFlag adds the --e2e-key flag to a client command.
+func addE2EFlag(cmd *cobra.Command) {
+	cmd.Flags().StringToString("e2e-key", nil,
+		"clipboard=passphrase pairs for end-to-end encrypted clipboards (repeatable)")
+}
+
+// loadKeyring builds the end-to-end encryption keyring from the [e2e-keys]
+// config table, SUFFUSE_E2E_KEYS ("clipboard=passphrase,…"), and --e2e-key,
+// later sources overriding earlier ones per clipboard.
+//
+// Viper lower-cases config-file keys, so clipboards with upper-case names must
+// be keyed via the env var or flag.
+func loadKeyring(v *viper.Viper) (*e2e.Keyring, error) {
+	keys := make(map[string]string)
+	for cb, pass := range v.GetStringMapString("e2e-keys") {
+		keys[cb] = pass
+	}
+	if env := os.Getenv("SUFFUSE_E2E_KEYS"); env != "" {
+		for _, pair := range strings.Split(env, ",") {
+			cb, pass, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("SUFFUSE_E2E_KEYS: expected clipboard=passphrase, got %q", pair)
+			}
+			keys[strings.TrimSpace(cb)] = pass
+		}
+	}
+	for cb, pass := range v.GetStringMapString("e2e-key") {
+		keys[cb] = pass
+	}
+	return e2e.NewKeyring(keys)
+}
+
+// addSigningFlags adds the flags selecting the device key that signs copies.
+func addSigningFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("sign", false, "sign copies with this device's key (see \"suffuse identity\")")
+	cmd.Flags().String("signing-key", signing.DefaultPath(), "this device's signing key, created if missing")
+}
+
+// loadSigningKey returns the device key when --sign is set, or nil.
+func loadSigningKey(v *viper.Viper) (*signing.Key, error) {
+	if !v.GetBool("sign") {
+		return nil, nil
+	}
+	return signing.LoadKey(v.GetString("signing-key"))
+}
+
+// addTrustFlag adds the --signer flag to a command that verifies signatures.
+func addTrustFlag(cmd *cobra.Command) {
+	cmd.Flags().StringToString("signer", nil,
+		`name=public-key pairs of trusted signers, as "suffuse identity" prints them (repeatable)`)
+}
+
+// loadTrust builds the trusted signers from the [signers] config table and
+// --signer, the flag overriding the table per name. As with [e2e-keys], names
+// from the config file are lower-cased.
+func loadTrust(v *viper.Viper) (*signing.Trust, error) {
+	signers := v.GetStringMapString("signers")
+	for name, key := range v.GetStringMapString("signer") {
+		signers[name] = key
+	}
+	return signing.NewTrust(signers)
+}
+
 // addLoggingFlags adds the standard logging flags to a command.
 func addLoggingFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool("no-background", false, "run interactively: tinter logs + debug level")
@@ -83,6 +164,11 @@ func addConfigFlag(cmd *cobra.Command) {
 
 // setupLogging reads logging flags from viper and configures slog.
 func setupLogging(v *viper.Viper) {
-	interactive := v.GetBool("no-background") || logging.IsTTY(os.Stderr)
-	resolveLogging(interactive, v.GetString("log-format"), v.GetString("log-level"))
+	resolveLogging(interactiveLogging(v), v.GetString("log-format"), v.GetString("log-level"))
+}
+
+// interactiveLogging reports whether logs go to a person rather than to a
+// service manager, which makes debug the default level.
+func interactiveLogging(v *viper.Viper) bool {
+	return v.GetBool("no-background") || logging.IsTTY(os.Stderr)
 }
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -12,6 +14,7 @@ import (
 	"google.golang.org/grpc"
 
 	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/chunk"
 	"go.klb.dev/suffuse/internal/hub"
 	"go.klb.dev/suffuse/internal/ipc"
 )
@@ -22,25 +25,55 @@ func newCopyCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "copy",
 		Short: "Copy stdin to the suffuse clipboard (like pbcopy)",
-		Long:  `Reads stdin and publishes it to the suffuse clipboard via gRPC.`,
+		Long: `Reads stdin and publishes it to the suffuse clipboard via gRPC.
+
+Clipboards with an end-to-end key (--e2e-key, SUFFUSE_E2E_KEYS, or the
+[e2e-keys] config table) are encrypted before leaving this host; the server
+only stores ciphertext:
+
+  echo s3cret | suffuse copy --clipboard secrets --e2e-key secrets=passphrase
+
+--sign signs the copy with this device's key, so receivers that trust it see
+who copied it whatever --source says (see "suffuse identity"). Encrypted
+copies are signed before they are sealed.
+
+  suffuse copy --sign < notes.txt
+
+--batch publishes many copies at once from a file of JSON lines ("-" for
+stdin), each to its own clipboard, in as few calls as their size allows:
+
+  {"clipboard": "team/notes", "text": "standup at 10"}
+  {"clipboard": "shots", "mime": "image/png", "data": "<base64>"}
+
+clipboard and mime default to --clipboard and --mime. Copies are published
+in order; a refused copy does not stop the rest, and each failure is
+reported.`,
 		Args:    cobra.NoArgs,
 		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
 		RunE:    func(_ *cobra.Command, _ []string) error { return runCopy(v) },
 	}
 
 	f := cmd.Flags()
-	f.String("host", "", "suffuse server host (probes docker/podman/localhost if unset)")
+	f.String("host", "", "suffuse server host (probes docker/podman/localhost, then mDNS, if unset)")
 	f.Int("port", 8752, "suffuse server port")
 	f.String("token", "", "shared secret")
+	f.String("tls-ca", "", `CA certificate file to verify the server's certificate against, or "system" (default: verify the key derived from the token)`)
+	f.String("via-ssh", "", "reach the server through this SSH jump host, [user@]host[:port] (only port 22 needs to be open)")
 	f.String("mime", "text/plain", "MIME type of the data being copied")
 	f.String("source", defaultSource(), "source identifier")
 	f.String("clipboard", hub.DefaultClipboard, "clipboard namespace")
+	f.String("batch", "", `publish the copies in this file of JSON lines ("-" for stdin) instead of stdin's content`)
+	addE2EFlag(cmd)
+	addSigningFlags(cmd)
 	addConfigFlag(cmd)
 
 	return cmd
 }
 
 func runCopy(v *viper.Viper) error {
+	if path := v.GetString("batch"); path != "" {
+		return runCopyBatch(v, path)
+	}
 	data, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		return fmt.Errorf("read stdin: %w", err)
@@ -56,28 +89,155 @@ func runCopy(v *viper.Viper) error {
 	host      := v.GetString("host")
 	port      := v.GetInt("port")
 
-	var conn *grpc.ClientConn
+	keyring, err := loadKeyring(v)
+	if err != nil {
+		return err
+	}
+	key, err := loadSigningKey(v)
+	if err != nil {
+		return err
+	}
+	items, err := key.Sign([]*pb.ClipboardItem{{Mime: mime, Data: data}})
+	if err == nil {
+		items, err = keyring.Seal(canonicalClipboard(clipboard), items)
+	}
+	if err != nil {
+		return err
+	}
 
-	if ipc.IsRunning() {
+	req := &pb.CopyRequest{
+		Source:    source,
+		Clipboard: clipboard,
+		Items:     items,
+	}
+
+	// Copies that fit in one message go to a local server in one request.
+	var pipe *ipc.Pipe
+	if hub.PayloadSize(items) <= chunk.Size {
+		pipe = dialPipe(v.GetString("via-ssh"))
+	}
+	if pipe != nil {
+		defer pipe.Close()
+		err = pipe.Copy(context.Background(), req)
+	} else {
+		var conn *grpc.ClientConn
+		if v.GetString("via-ssh") == "" && ipc.IsRunning() {
+			conn, err = dialIPC()
+		}
+		if conn == nil {
+			conn, err = dialServer(host, port, token, source, v.GetString("tls-ca"), v.GetString("via-ssh"))
+			if err != nil {
+				return fmt.Errorf("dial: %w", err)
+			}
+		}
+		defer conn.Close()
+		err = copyItems(context.Background(), pb.NewClipboardServiceClient(conn), req)
+	}
+	if err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+	slog.Debug("copied", "mime", mime, "bytes", len(data), "encrypted", keyring.Encrypted(canonicalClipboard(clipboard)), "signed", key != nil)
+	return nil
+}
+
+// batchCopy is one line of a --batch file. Exactly one of Text and Data
+// (base64) is set.
+type batchCopy struct {
+	Clipboard string  `json:"clipboard"`
+	Mime      string  `json:"mime"`
+	Text      *string `json:"text"`
+	Data      []byte  `json:"data"`
+}
+
+// readBatch reads the copies of a --batch file, defaulting their clipboard
+// and MIME type.
+func readBatch(path, clipboard, mime string) ([]batchCopy, error) {
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+	var out []batchCopy
+	dec := json.NewDecoder(in)
+	for n := 1; ; n++ {
+		var c batchCopy
+		if err := dec.Decode(&c); errors.Is(err, io.EOF) {
+			return out, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: copy %d: %w", path, n, err)
+		}
+		if (c.Text == nil) == (c.Data == nil) {
+			return nil, fmt.Errorf("%s: copy %d: needs one of text and data", path, n)
+		}
+		if c.Text != nil {
+			c.Data = []byte(*c.Text)
+		}
+		if c.Clipboard == "" {
+			c.Clipboard = clipboard
+		}
+		if c.Mime == "" {
+			c.Mime = mime
+		}
+		out = append(out, c)
+	}
+}
+
+func runCopyBatch(v *viper.Viper, path string) error {
+	source := v.GetString("source")
+	batch, err := readBatch(path, v.GetString("clipboard"), v.GetString("mime"))
+	if err != nil {
+		return err
+	}
+	keyring, err := loadKeyring(v)
+	if err != nil {
+		return err
+	}
+	key, err := loadSigningKey(v)
+	if err != nil {
+		return err
+	}
+	copies := make([]*pb.CopyRequest, len(batch))
+	for i, c := range batch {
+		items, err := key.Sign([]*pb.ClipboardItem{{Mime: c.Mime, Data: c.Data}})
+		if err == nil {
+			items, err = keyring.Seal(canonicalClipboard(c.Clipboard), items)
+		}
+		if err != nil {
+			return err
+		}
+		copies[i] = &pb.CopyRequest{Source: source, Clipboard: c.Clipboard, Items: items}
+	}
+
+	var conn *grpc.ClientConn
+	if v.GetString("via-ssh") == "" && ipc.IsRunning() {
 		conn, err = dialIPC()
 	}
 	if conn == nil {
-		conn, err = dialServer(host, port, token, source)
+		conn, err = dialServer(v.GetString("host"), v.GetInt("port"), v.GetString("token"), source, v.GetString("tls-ca"), v.GetString("via-ssh"))
 		if err != nil {
 			return fmt.Errorf("dial: %w", err)
 		}
 	}
 	defer conn.Close()
 
-	client := pb.NewClipboardServiceClient(conn)
-	_, err = client.Copy(context.Background(), &pb.CopyRequest{
-		Source:    source,
-		Clipboard: clipboard,
-		Items:     []*pb.ClipboardItem{{Mime: mime, Data: data}},
-	})
+	errs, err := copyBatch(context.Background(), pb.NewClipboardServiceClient(conn), copies)
 	if err != nil {
 		return fmt.Errorf("copy: %w", err)
 	}
-	slog.Debug("copied", "mime", mime, "bytes", len(data))
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "copy %d to %s: %v\n", i+1, canonicalClipboard(batch[i].Clipboard), err)
+		}
+	}
+	slog.Debug("copied batch", "copies", len(copies), "failed", failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d copies failed", failed, len(copies))
+	}
 	return nil
 }
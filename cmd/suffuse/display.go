@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"go.klb.dev/suffuse/internal/logging"
+)
+
+// sourceDisplay is one entry of the [sources] config table, which maps a
+// source name to how it is shown by status and similar commands:
+//
+//	[sources.laptop-7f3a]
+//	name  = "Kevin's laptop"
+//	color = "cyan"
+type sourceDisplay struct {
+	Name  string `mapstructure:"name"`
+	Color string `mapstructure:"color"`
+}
+
+// ansiColors maps color names to SGR foreground codes. Every code renders as
+// an escape of the same length, which keeps tabwriter columns aligned.
+var ansiColors = map[string]string{
+	"black": "30", "red": "31", "green": "32", "yellow": "33",
+	"blue": "34", "magenta": "35", "cyan": "36", "white": "37",
+	"gray": "90", "bright-red": "91", "bright-green": "92", "bright-yellow": "93",
+	"bright-blue": "94", "bright-magenta": "95", "bright-cyan": "96", "bright-white": "97",
+}
+
+// sourceStyler renders source names using the [sources] config table.
+type sourceStyler struct {
+	sources map[string]sourceDisplay // lower-cased source → display
+	color   bool
+}
+
+// newSourceStyler loads the [sources] table from v. Colors are used only when
+// stdout is a terminal and NO_COLOR is unset.
+func newSourceStyler(v *viper.Viper) (*sourceStyler, error) {
+	raw := make(map[string]sourceDisplay)
+	if err := v.UnmarshalKey("sources", &raw); err != nil {
+		return nil, fmt.Errorf("config: sources: %w", err)
+	}
+	s := &sourceStyler{
+		sources: make(map[string]sourceDisplay, len(raw)),
+		color:   logging.IsTTY(os.Stdout) && os.Getenv("NO_COLOR") == "",
+	}
+	for src, d := range raw {
+		if d.Color != "" {
+			if _, ok := ansiColors[strings.ToLower(d.Color)]; !ok {
+				return nil, fmt.Errorf("config: sources.%s: unknown color %q", src, d.Color)
+			}
+		}
+		// Viper lower-cases table keys, so match sources case-insensitively.
+		s.sources[strings.ToLower(src)] = d
+	}
+	return s, nil
+}
+
+// name returns the display name for source, or source itself.
+func (s *sourceStyler) name(source string) string {
+	if d, ok := s.sources[strings.ToLower(source)]; ok && d.Name != "" {
+		return d.Name
+	}
+	return source
+}
+
+// render returns the display name for source, wrapped in its color when
+// colors are enabled. With colors on, every value carries an escape sequence
+// of equal length, colored or not, so column widths stay consistent.
+func (s *sourceStyler) render(source string) string {
+	code := "39" // default foreground
+	if d, ok := s.sources[strings.ToLower(source)]; ok && d.Color != "" {
+		code = ansiColors[strings.ToLower(d.Color)]
+	}
+	return s.wrap(code, s.name(source))
+}
+
+// plain returns text padded with the same escape overhead as render, for
+// other cells (headers) in a column of rendered sources.
+func (s *sourceStyler) plain(text string) string {
+	return s.wrap("39", text)
+}
+
+func (s *sourceStyler) wrap(code, text string) string {
+	if !s.color {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
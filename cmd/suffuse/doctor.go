@@ -0,0 +1,391 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/ipc"
+)
+
+const redacted = "[REDACTED]"
+
+func newDoctorCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check this host's suffuse setup and collect a bug-report bundle",
+		Long: `Checks the local installation: version and platform, the config file in
+use, the IPC socket, and whether a server answers Status.
+
+With --bundle, also writes a .tar.gz for attaching to an issue containing:
+  doctor.txt   the checks above
+  config.json  the config file as parsed, with secrets redacted
+  env.txt      SUFFUSE_* environment variables, with secrets redacted
+  status.json  peer list, drop counters, and hub stats from the server
+  logs.txt     recent server logs (journald, /tmp/suffuse.log, the Windows
+               event log, or --log-file)
+
+Tokens, passphrases, webhook secrets and headers, and URL credentials are
+redacted, and every secret found in the configuration is also scrubbed from
+the logs. Clipboard contents are never collected. Review the bundle before
+sharing it.
+
+  suffuse doctor
+  suffuse doctor --bundle --output /tmp/suffuse-bug.tar.gz`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, _ []string) error { return runDoctor(cmd.Context(), v) },
+	}
+
+	f := cmd.Flags()
+	f.String("host", "", "suffuse server host (uses the IPC socket if unset and available)")
+	f.Int("port", 8752, "suffuse server port")
+	f.String("token", "", "shared secret")
+	f.String("tls-ca", "", `CA certificate file to verify the server's certificate against, or "system" (default: verify the key derived from the token)`)
+	f.String("via-ssh", "", "reach the server through this SSH jump host, [user@]host[:port] (only port 22 needs to be open)")
+	f.String("source", defaultSource(), "source identifier")
+	f.Bool("bundle", false, "write a diagnostics bundle")
+	f.String("output", "", "bundle path (default: suffuse-doctor-<time>.tar.gz)")
+	f.String("log-file", "", "read recent logs from this file instead of the platform default")
+	f.Int("log-lines", 500, "number of recent log lines to include")
+	addConfigFlag(cmd)
+
+	return cmd
+}
+
+// diagnostics is everything doctor collects. secrets holds values that must
+// not appear in the bundle; scrub removes them from free-form text.
+type diagnostics struct {
+	report  bytes.Buffer
+	config  []byte
+	env     []byte
+	status  []byte
+	logs    []byte
+	secrets []string
+}
+
+func runDoctor(ctx context.Context, v *viper.Viper) error {
+	d := &diagnostics{}
+	d.addSecret(v.GetString("token"))
+
+	d.checkf("version:  suffuse %s (%s, %s/%s)", Version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	d.collectConfig(v.ConfigFileUsed())
+	d.collectEnv()
+	d.collectStatus(ctx, v)
+
+	fmt.Print(d.report.String())
+	if !v.GetBool("bundle") {
+		return nil
+	}
+
+	d.collectLogs(ctx, v.GetString("log-file"), v.GetInt("log-lines"))
+
+	path := v.GetString("output")
+	if path == "" {
+		path = fmt.Sprintf("suffuse-doctor-%s.tar.gz", time.Now().Format("20060102-150405"))
+	}
+	if err := d.writeBundle(path); err != nil {
+		return fmt.Errorf("bundle: %w", err)
+	}
+	fmt.Printf("\nBundle written to %s — review it before sharing.\n", path)
+	return nil
+}
+
+func (d *diagnostics) checkf(format string, args ...any) {
+	fmt.Fprintf(&d.report, format+"\n", args...)
+}
+
+func (d *diagnostics) addSecret(s string) {
+	if len(s) >= 4 && !slices.Contains(d.secrets, s) {
+		d.secrets = append(d.secrets, s)
+	}
+}
+
+// scrub replaces every known secret in b.
+func (d *diagnostics) scrub(b []byte) []byte {
+	for _, s := range d.secrets {
+		b = bytes.ReplaceAll(b, []byte(s), []byte(redacted))
+	}
+	return b
+}
+
+func (d *diagnostics) collectConfig(path string) {
+	if path == "" {
+		d.checkf("config:   no config file found")
+		d.config = []byte("null\n")
+		return
+	}
+	cv := viper.New()
+	cv.SetConfigFile(path)
+	if err := cv.ReadInConfig(); err != nil {
+		d.checkf("config:   %s: FAIL %v", path, err)
+		d.config = []byte("null\n")
+		return
+	}
+	d.checkf("config:   %s", path)
+	clean := d.redact("", cv.AllSettings())
+	d.config, _ = json.MarshalIndent(clean, "", "  ")
+}
+
+// redact returns a copy of the config value v stored under key with secret
+// values replaced. Every secret is remembered for scrubbing free-form text.
+func (d *diagnostics) redact(key string, v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			if secretKey(k) {
+				d.collectSecrets(val)
+				out[k] = redacted
+				continue
+			}
+			out[k] = d.redact(k, val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, val := range t {
+			out[i] = d.redact(key, val)
+		}
+		return out
+	case string:
+		if key == "url" || strings.HasSuffix(key, "-url") {
+			return d.redactURL(t)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+func (d *diagnostics) collectSecrets(v any) {
+	switch t := v.(type) {
+	case map[string]any:
+		for _, val := range t {
+			d.collectSecrets(val)
+		}
+	case []any:
+		for _, val := range t {
+			d.collectSecrets(val)
+		}
+	case string:
+		d.addSecret(t)
+		if _, after, ok := strings.Cut(t, "Bearer "); ok {
+			d.addSecret(after)
+		}
+		// accept-tokens entries carry an optional "@expiry" suffix; list
+		// env vars hold "a,b" or "name=secret,…".
+		for _, part := range strings.Split(t, ",") {
+			if secret, _, ok := strings.Cut(part, "@"); ok {
+				d.addSecret(secret)
+			}
+			if _, secret, ok := strings.Cut(part, "="); ok {
+				d.addSecret(secret)
+			}
+			d.addSecret(part)
+		}
+	}
+}
+
+// redactURL keeps a URL's scheme and host; credentials, paths, and queries
+// often carry secrets (e.g. Slack webhook URLs).
+func (d *diagnostics) redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		d.addSecret(s)
+		return redacted
+	}
+	if pass, ok := u.User.Password(); ok {
+		d.addSecret(pass)
+	}
+	if u.Path != "" && u.Path != "/" {
+		d.addSecret(u.Path)
+		for _, seg := range strings.Split(u.Path, "/") {
+			if len(seg) > 8 {
+				d.addSecret(seg)
+			}
+		}
+	}
+	out := u.Scheme + "://" + u.Host
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		out += "/" + redacted
+	}
+	return out
+}
+
+// secretKey reports whether a config or environment key holds a secret.
+func secretKey(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"token", "secret", "password", "passphrase", "key", "header", "authorization"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *diagnostics) collectEnv() {
+	var lines []string
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, "SUFFUSE_") {
+			continue
+		}
+		switch {
+		case secretKey(name):
+			d.collectSecrets(value)
+			value = redacted
+		case strings.HasSuffix(name, "_URL"):
+			value = d.redactURL(value)
+		}
+		lines = append(lines, name+"="+value)
+	}
+	slices.Sort(lines)
+	d.env = []byte(strings.Join(lines, "\n") + "\n")
+}
+
+func (d *diagnostics) collectStatus(ctx context.Context, v *viper.Viper) {
+	if ipc.IsRunning() {
+		d.checkf("ipc:      %s: listening", ipc.SocketPath())
+	} else {
+		d.checkf("ipc:      %s: no server on this host", ipc.SocketPath())
+	}
+
+	conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), v.GetString("token"), v.GetString("source"), v.GetString("tls-ca"), v.GetString("via-ssh"))
+	if err != nil {
+		d.checkf("server:   FAIL %v", err)
+		d.status = []byte("null\n")
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	resp, err := pb.NewClipboardServiceClient(conn).Status(ctx, &pb.StatusRequest{})
+	if err != nil {
+		d.checkf("server:   FAIL status: %v", err)
+		d.status = []byte("null\n")
+		return
+	}
+	d.checkf("server:   %s: %d peers", conn.Target(), len(resp.Peers))
+	if ui := resp.UpstreamInfo; ui != nil {
+		state := "connected"
+		if ui.ConnectedAt == nil || ui.ConnectedAt.AsTime().IsZero() {
+			state = "DISCONNECTED"
+		}
+		d.checkf("upstream: %s: %s", ui.Addr, state)
+	}
+	var dropped uint64
+	for _, n := range resp.Dropped {
+		dropped += n
+	}
+	if dropped > 0 {
+		d.checkf("drops:    %d events dropped by slow consumers (see suffuse status)", dropped)
+	}
+	d.status, _ = protojson.MarshalOptions{Multiline: true, EmitUnpopulated: true}.Marshal(resp)
+}
+
+func (d *diagnostics) collectLogs(ctx context.Context, file string, lines int) {
+	var (
+		out []byte
+		err error
+	)
+	switch {
+	case file != "":
+		out, err = tailFile(file, lines)
+	case runtime.GOOS == "linux":
+		out, err = exec.CommandContext(ctx, "journalctl", "-u", "suffuse", "-n", fmt.Sprint(lines), "--no-pager").Output()
+	case runtime.GOOS == "darwin":
+		out, err = tailFile("/tmp/suffuse.log", lines)
+	case runtime.GOOS == "windows":
+		out, err = exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command",
+			fmt.Sprintf("Get-EventLog -LogName Application -Source SuffuseServer -Newest %d | Format-List TimeGenerated,EntryType,Message", lines),
+		).Output()
+	default:
+		err = fmt.Errorf("no default log location on %s; use --log-file", runtime.GOOS)
+	}
+	if err != nil {
+		out = append(out, fmt.Sprintf("\n(log collection failed: %v)\n", err)...)
+	}
+	d.logs = d.scrub(out)
+}
+
+// tailFile returns the last n lines of the file at path.
+func tailFile(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var ring []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		ring = append(ring, sc.Text())
+		if len(ring) > n {
+			ring = ring[1:]
+		}
+	}
+	return []byte(strings.Join(ring, "\n") + "\n"), sc.Err()
+}
+
+func (d *diagnostics) writeBundle(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	dir := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".tar")
+	now := time.Now()
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{"doctor.txt", d.report.Bytes()},
+		{"config.json", d.config},
+		{"env.txt", d.env},
+		{"status.json", d.status},
+		{"logs.txt", d.logs},
+	} {
+		data := d.scrub(file.data)
+		hdr := &tar.Header{Name: dir + "/" + file.name, Mode: 0o600, Size: int64(len(data)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
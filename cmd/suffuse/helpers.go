@@ -3,14 +3,25 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"net"
 	"os"
+	"slices"
+	"strconv"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 
 	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/compress"
+	"go.klb.dev/suffuse/internal/hub"
 	"go.klb.dev/suffuse/internal/ipc"
+	"go.klb.dev/suffuse/internal/mdns"
+	"go.klb.dev/suffuse/internal/sshtunnel"
 	"go.klb.dev/suffuse/internal/tlsconf"
 )
 
@@ -52,6 +63,15 @@ func defaultSource() string {
 	return h
 }
 
+// canonicalClipboard maps an empty clipboard name to the default clipboard,
+// matching the server's normalization.
+func canonicalClipboard(name string) string {
+	if name == "" {
+		return hub.DefaultClipboard
+	}
+	return name
+}
+
 // defaultHosts is the probe order used when no explicit --host is given.
 // IPC is tried first (before any TCP) by the callers via ipc.IsRunning().
 var defaultHosts = []string{
@@ -69,16 +89,60 @@ func dialIPC() (*grpc.ClientConn, error) {
 	)
 }
 
+// dialPipe returns a pipelined connection to the local server for a copy or
+// paste, or nil to use gRPC: with an SSH jump host, or when no local server
+// answers pipelined requests.
+func dialPipe(via string) *ipc.Pipe {
+	if via != "" {
+		return nil
+	}
+	pipe, err := ipc.DialPipe()
+	if err != nil {
+		return nil
+	}
+	return pipe
+}
+
+// dialAuto connects via the local IPC socket when a daemon is running and no
+// explicit host or SSH jump host was requested, falling back to dialServer
+// otherwise. Unlike dialIPC it presents token on the socket too, as admin RPCs
+// require it.
+func dialAuto(host string, port int, token, source, ca, via string) (*grpc.ClientConn, error) {
+	if host == "" && via == "" && ipc.IsRunning() {
+		if conn, err := grpc.NewClient("unix://"+ipc.SocketPath(), dialOpts(token, source)...); err == nil {
+			return conn, nil
+		}
+	}
+	return dialServer(host, port, token, source, ca, via)
+}
+
+// clientTLS returns the credentials verifying the server: against ca when
+// set, otherwise by the key derived from token.
+func clientTLS(token, ca string) (credentials.TransportCredentials, error) {
+	if ca != "" {
+		return tlsconf.CACredentials(ca)
+	}
+	if token == "" {
+		token = tlsconf.DefaultPassphrase
+	}
+	return tlsconf.ClientCredentials(token)
+}
+
 // dialServer probes hosts in order and returns the first reachable TLS connection.
-// If host is non-empty only that host is tried. Port defaults to 8752.
-// token is used for both TLS key derivation and per-RPC auth.
-func dialServer(host string, port int, token, source string) (*grpc.ClientConn, error) {
-	conn, _, err := dialServerResolved(host, port, token, source)
+// If host is non-empty only that host is tried; otherwise servers advertised
+// via mDNS are tried after defaultHosts. Port defaults to 8752.
+// token is used for both TLS key derivation and per-RPC auth; with ca set
+// the server's certificate is verified against it instead (see
+// tlsconf.CACredentials). With via, "[user@]host[:port]", connections go
+// through that SSH jump host, which resolves the hosts, and mDNS is skipped.
+func dialServer(host string, port int, token, source, ca, via string) (*grpc.ClientConn, error) {
+	conn, _, err := dialServerResolved(host, port, token, source, ca, via)
 	return conn, err
 }
 
-// dialServerResolved is like dialServer but also returns the resolved host name.
-func dialServerResolved(host string, port int, token, source string) (*grpc.ClientConn, string, error) {
+// dialServerResolved is like dialServer but also returns the address it
+// connected to.
+func dialServerResolved(host string, port int, token, source, ca, via string) (*grpc.ClientConn, string, error) {
 	if port == 0 {
 		port = 8752
 	}
@@ -86,40 +150,79 @@ func dialServerResolved(host string, port int, token, source string) (*grpc.Clie
 	if host != "" {
 		hosts = []string{host}
 	}
-	passphrase := token
-	if passphrase == "" {
-		passphrase = tlsconf.DefaultPassphrase
-	}
-	creds, err := tlsconf.ClientCredentials(passphrase)
+	creds, err := clientTLS(token, ca)
 	if err != nil {
 		return nil, "", fmt.Errorf("tls credentials: %w", err)
 	}
+	creds = tlsconf.Observe(creds, func(hs tlsconf.Handshake) {
+		slog.Debug("TLS handshake", "addr", hs.Addr, "duration", hs.Duration.Round(time.Millisecond), "resumed", hs.Resumed)
+	})
 	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
 	if token != "" || source != "" {
 		opts = append(opts, grpc.WithPerRPCCredentials(&clientCreds{token: token, source: source}))
 	}
+	target := func(addr string) string { return addr }
+	if via != "" {
+		tunnel, err := sshtunnel.New(via)
+		if err != nil {
+			return nil, "", err
+		}
+		opts = append(opts, grpc.WithContextDialer(tunnel.DialContext))
+		target = sshtunnel.Target
+	}
 	var lastErr error
 	for _, h := range hosts {
-		addr := fmt.Sprintf("%s:%d", h, port)
-		conn, err := grpc.NewClient(addr, opts...)
-		if err != nil {
-			lastErr = fmt.Errorf("%s: %w", addr, err)
-			continue
+		addr := net.JoinHostPort(h, strconv.Itoa(port))
+		conn, err := probeServer(addr, target(addr), opts)
+		if err == nil {
+			return conn, addr, nil
 		}
-		// Verify reachability with a short timeout
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		client := pb.NewClipboardServiceClient(conn)
-		_, err = client.Status(ctx, &pb.StatusRequest{})
+		lastErr = err
+	}
+	if host == "" && via == "" {
+		ctx, cancel := context.WithTimeout(context.Background(), discoverWait)
+		servers, err := mdns.Browse(ctx)
 		cancel()
-		if err == nil {
-			return conn, h, nil
+		if err != nil {
+			slog.Debug("mDNS discovery failed", "err", err)
+		}
+		for _, s := range servers {
+			conn, err := probeServer(s.Addr, s.Addr, opts)
+			if err == nil {
+				slog.Debug("server discovered via mDNS", "instance", s.Instance, "addr", s.Addr)
+				return conn, s.Addr, nil
+			}
+			lastErr = err
 		}
-		_ = conn.Close()
-		lastErr = fmt.Errorf("%s: %w", addr, err)
 	}
 	return nil, "", fmt.Errorf("no reachable suffuse server: %w", lastErr)
 }
 
+// discoverWait is how long dialServer listens for mDNS answers.
+const discoverWait = time.Second
+
+// probeServer connects to addr through target, its gRPC target, and verifies
+// it answers as a suffuse server with the credentials in opts. Calls on the connection are compressed if the
+// server accepts it, which the probe settles.
+func probeServer(addr, target string, opts []grpc.DialOption) (*grpc.ClientConn, error) {
+	conn, err := grpc.NewClient(target, slices.Concat(opts, compress.DialOptions())...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", addr, err)
+	}
+	// Verify reachability with a short timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	client := pb.NewClipboardServiceClient(conn)
+	_, err = client.Status(ctx, &pb.StatusRequest{})
+	cancel()
+	// PermissionDenied means the server answered but hides Status on its
+	// TCP listener (--no-public-status); it is still reachable.
+	if err == nil || status.Code(err) == codes.PermissionDenied {
+		return conn, nil
+	}
+	_ = conn.Close()
+	return nil, fmt.Errorf("%s: %w", addr, err)
+}
+
 // dialOpts returns gRPC dial options for the local IPC socket (insecure).
 func dialOpts(token, source string) []grpc.DialOption {
 	opts := []grpc.DialOption{
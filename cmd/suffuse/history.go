@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/e2e"
+	"go.klb.dev/suffuse/internal/hub"
+)
+
+// previewLen is the number of characters of text shown per history entry.
+const previewLen = 60
+
+func newHistoryCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List earlier contents of the suffuse clipboard",
+		Long: `Lists what the clipboard held before its current content, most recent
+first, when the server keeps history (server --history). Entry 1 is the
+content the current one replaced.
+
+"history show" prints an entry, like "suffuse paste" prints the current
+content, and "history grep" finds entries by their text:
+
+  suffuse history
+  suffuse history show 2 --mime image/png > earlier.png
+  suffuse history grep 'https://.*example' --copy`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, _ []string) error { return runHistory(cmd.Context(), v) },
+	}
+
+	f := cmd.Flags()
+	f.String("clipboard", hub.DefaultClipboard, "clipboard namespace")
+	f.Int("limit", 0, "show at most this many entries (0 shows all)")
+	f.Bool("json", false, "output raw JSON")
+	addHistoryConnFlags(cmd)
+
+	cmd.AddCommand(newHistoryShowCmd())
+	cmd.AddCommand(newHistoryGrepCmd())
+	return cmd
+}
+
+func newHistoryShowCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:     "show <n>",
+		Short:   "Print an earlier clipboard content to stdout",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("entry must be a number from 1, got %q", args[0])
+			}
+			return runHistoryShow(cmd.Context(), v, n)
+		},
+	}
+
+	f := cmd.Flags()
+	f.String("clipboard", hub.DefaultClipboard, "clipboard namespace")
+	f.String("mime", "text/plain", "preferred MIME type to output")
+	addHistoryConnFlags(cmd)
+	return cmd
+}
+
+func newHistoryGrepCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "grep <pattern>",
+		Short: "List earlier clipboard contents whose text matches a pattern",
+		Long: `Lists the entries of the clipboard's history whose text matches pattern, a
+regular expression (RE2 syntax), most recent first, numbered like "suffuse
+history". It fails when no entry matches.
+
+--copy makes the most recent match the clipboard's content again, on every
+host, with all the types it had:
+
+  suffuse history grep -i 'github.com/.*/pull' --copy`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, args []string) error { return runHistoryGrep(cmd.Context(), v, args[0]) },
+	}
+
+	f := cmd.Flags()
+	f.String("clipboard", hub.DefaultClipboard, "clipboard namespace")
+	f.BoolP("ignore-case", "i", false, "match upper and lower case alike")
+	f.Bool("copy", false, "copy the most recent match to the clipboard")
+	addHistoryConnFlags(cmd)
+	return cmd
+}
+
+// addHistoryConnFlags adds the connection and key flags shared by the
+// history commands.
+func addHistoryConnFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.String("host", "", "suffuse server host (uses the IPC socket if unset and available)")
+	f.Int("port", 8752, "suffuse server port")
+	f.String("token", "", "shared secret")
+	f.String("tls-ca", "", `CA certificate file to verify the server's certificate against, or "system" (default: verify the key derived from the token)`)
+	f.String("via-ssh", "", "reach the server through this SSH jump host, [user@]host[:port] (only port 22 needs to be open)")
+	f.String("source", defaultSource(), "source identifier")
+	addE2EFlag(cmd)
+	addConfigFlag(cmd)
+}
+
+// fetchHistory returns up to limit entries of the clipboard's history with
+// the items of type mime, or of every type when mime is empty, decrypted for
+// end-to-end encrypted clipboards.
+func fetchHistory(ctx context.Context, v *viper.Viper, limit int, mime string) (*pb.HistoryResponse, error) {
+	keyring, err := loadKeyring(v)
+	if err != nil {
+		return nil, err
+	}
+	cb := canonicalClipboard(v.GetString("clipboard"))
+	var accepts []string
+	if mime != "" {
+		accepts = []string{mime}
+	}
+	if keyring.Encrypted(cb) {
+		// The server only sees the sealed item; filter after decrypting.
+		accepts = []string{e2e.MIME}
+	}
+
+	conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), v.GetString("token"), v.GetString("source"), v.GetString("tls-ca"), v.GetString("via-ssh"))
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewClipboardServiceClient(conn).History(ctx, &pb.HistoryRequest{
+		Clipboard: cb,
+		Accepts:   accepts,
+		Limit:     uint32(max(0, limit)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	for _, e := range resp.Entries {
+		if !keyring.Encrypted(cb) {
+			continue
+		}
+		if e.Items, err = keyring.Open(cb, e.Items); err != nil {
+			return nil, err
+		}
+		e.AvailableTypes = e.AvailableTypes[:0]
+		for _, it := range e.Items {
+			e.AvailableTypes = append(e.AvailableTypes, it.Mime)
+		}
+	}
+	return resp, nil
+}
+
+func runHistory(ctx context.Context, v *viper.Viper) error {
+	resp, err := fetchHistory(ctx, v, v.GetInt("limit"), "text/plain")
+	if err != nil {
+		return err
+	}
+
+	if v.GetBool("json") {
+		enc, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Println(string(enc))
+		return nil
+	}
+	if len(resp.Entries) == 0 {
+		fmt.Println("No earlier contents.")
+		return nil
+	}
+	all := make([]int, len(resp.Entries))
+	for i := range all {
+		all[i] = i
+	}
+	return printHistory(resp.Entries, all)
+}
+
+// printHistory lists the entries at the indexes in show, numbered from 1 by
+// their place in entries.
+func printHistory(entries []*pb.HistoryEntry, show []int) error {
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "#\tSTORED\tSOURCE\tTYPES\tTEXT")
+	_, _ = fmt.Fprintln(tw, "-\t------\t------\t-----\t----")
+	for _, i := range show {
+		e := entries[i]
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n",
+			i+1, tsAge(e.StoredAt), dash(e.Source), dash(strings.Join(e.AvailableTypes, ",")), dash(preview(entryText(e))))
+	}
+	return tw.Flush()
+}
+
+// entryText returns the text/plain content of e, or "" when it has none.
+func entryText(e *pb.HistoryEntry) string {
+	for _, it := range e.Items {
+		if it.Mime == "text/plain" {
+			return string(it.Data)
+		}
+	}
+	return ""
+}
+
+func runHistoryGrep(ctx context.Context, v *viper.Viper, pattern string) error {
+	if v.GetBool("ignore-case") {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("pattern: %w", err)
+	}
+	// Every type is fetched, so a match is copied back whole.
+	resp, err := fetchHistory(ctx, v, 0, "")
+	if err != nil {
+		return err
+	}
+	var matches []int
+	for i, e := range resp.Entries {
+		if text := entryText(e); text != "" && re.MatchString(text) {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == 0 {
+		return errors.New("no earlier content matches")
+	}
+	if err := printHistory(resp.Entries, matches); err != nil {
+		return err
+	}
+	if !v.GetBool("copy") {
+		return nil
+	}
+	if err := copyEntry(ctx, v, resp.Entries[matches[0]]); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "copied entry %d to %s\n", matches[0]+1, canonicalClipboard(v.GetString("clipboard")))
+	return nil
+}
+
+// copyEntry publishes the content of a history entry as the clipboard's
+// content, sealed again for end-to-end encrypted clipboards.
+func copyEntry(ctx context.Context, v *viper.Viper, e *pb.HistoryEntry) error {
+	keyring, err := loadKeyring(v)
+	if err != nil {
+		return err
+	}
+	cb := canonicalClipboard(v.GetString("clipboard"))
+	items, err := keyring.Seal(cb, e.Items)
+	if err != nil {
+		return err
+	}
+	conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), v.GetString("token"), v.GetString("source"), v.GetString("tls-ca"), v.GetString("via-ssh"))
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+	req := &pb.CopyRequest{Source: v.GetString("source"), Clipboard: cb, Items: items}
+	if err := copyItems(ctx, pb.NewClipboardServiceClient(conn), req); err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+	return nil
+}
+
+func runHistoryShow(ctx context.Context, v *viper.Viper, n int) error {
+	mime := v.GetString("mime")
+	resp, err := fetchHistory(ctx, v, n, mime)
+	if err != nil {
+		return err
+	}
+	if n > len(resp.Entries) {
+		return fmt.Errorf("the clipboard history has %d entries", len(resp.Entries))
+	}
+	for _, it := range resp.Entries[n-1].Items {
+		if it.Mime == mime {
+			_, err = os.Stdout.Write(it.Data)
+			return err
+		}
+	}
+	return nil
+}
+
+// preview returns the start of text on one line, for listing.
+func preview(text string) string {
+	text = strings.Join(strings.FieldsFunc(text, unicode.IsSpace), " ")
+	if r := []rune(text); len(r) > previewLen {
+		text = string(r[:previewLen-1]) + "…"
+	}
+	return text
+}
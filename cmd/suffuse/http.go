@@ -1 +1,127 @@
 package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/localpeer"
+)
+
+// readiness gathers what /readyz checks. Nil components are not configured
+// and are skipped.
+type readiness struct {
+	local           *localpeer.Peer
+	upstreams       *upstreamSet
+	requireUpstream bool
+}
+
+// checks returns the state of each component and whether all required ones
+// are ready. The hub itself is ready as soon as the listener serves requests.
+func (rd readiness) checks() (map[string]string, bool) {
+	out := map[string]string{"hub": "ok"}
+	ready := true
+	if rd.local != nil {
+		if rd.local.Running() {
+			out["local_clipboard"] = "ok"
+		} else {
+			out["local_clipboard"] = "not running"
+			ready = false
+		}
+	}
+	// With several upstreams each is checked under "upstream <addr>"; every
+	// one must be connected when they are required.
+	upstreams := rd.upstreams.list()
+	for _, up := range upstreams {
+		key := "upstream"
+		if len(upstreams) > 1 {
+			key += " " + up.UpstreamInfo().Addr
+		}
+		switch {
+		case up.Connected():
+			out[key] = "ok"
+		case rd.requireUpstream:
+			out[key] = "not connected"
+			ready = false
+		default:
+			out[key] = "not connected (not required)"
+		}
+	}
+	return out, ready
+}
+
+// healthHandler serves the unauthenticated probe endpoints:
+//
+//	/healthz — liveness: 200 whenever the process is serving HTTP.
+//	/readyz  — readiness: 200 when every required component is up, else 503,
+//	           with a JSON breakdown of the checks either way.
+//
+// Other paths fall through to next.
+func healthHandler(rd readiness, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte("ok\n"))
+		case "/readyz":
+			checks, ready := rd.checks()
+			status := "ok"
+			code := http.StatusOK
+			if !ready {
+				status, code = "unavailable", http.StatusServiceUnavailable
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(code)
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": status, "checks": checks})
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// healthInterval is how often the gRPC health statuses are brought up to date.
+const healthInterval = time.Second
+
+// serveHealth keeps the grpc.health.v1 statuses of hs in step with the HTTP
+// probes until ctx is done: the server as a whole ("") is SERVING like
+// /healthz, and suffuse.v1.ClipboardService is SERVING when /readyz would
+// answer 200, NOT_SERVING otherwise.
+func serveHealth(ctx context.Context, hs *health.Server, rd readiness) {
+	t := time.NewTicker(healthInterval)
+	defer t.Stop()
+	for {
+		st := healthpb.HealthCheckResponse_NOT_SERVING
+		if _, ready := rd.checks(); ready {
+			st = healthpb.HealthCheckResponse_SERVING
+		}
+		hs.SetServingStatus(pb.ClipboardService_ServiceDesc.ServiceName, st)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// acmeHTTPHandler serves the plain-HTTP listener of --acme-http-addr: ACME
+// HTTP-01 challenges, the health probes, and a redirect of everything else
+// to the TLS listener on port.
+func acmeHTTPHandler(m *autocert.Manager, rd readiness, port string) http.Handler {
+	redirect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		u := *r.URL
+		u.Scheme, u.Host = "https", net.JoinHostPort(host, port)
+		http.Redirect(w, r, u.String(), http.StatusFound)
+	})
+	return m.HTTPHandler(healthHandler(rd, redirect))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"go.klb.dev/suffuse/internal/signing"
+)
+
+func newIdentityCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "identity",
+		Short: "Print this device's public signing key",
+		Long: `Prints the public half of the key "suffuse copy --sign" and "suffuse server
+--sign" sign copies with, creating the key if this device has none yet, and
+the lines that make other devices trust it under --name. Add them to the
+[signers] table of their config file, or pass the key with --signer:
+
+  suffuse identity --name alice-laptop
+
+The private key stays in --signing-key; anyone holding that file can sign
+as this device, so keep it private.`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(_ *cobra.Command, _ []string) error { return runIdentity(v) },
+	}
+
+	f := cmd.Flags()
+	f.String("name", defaultSource(), "name to trust the key under")
+	f.String("signing-key", signing.DefaultPath(), "this device's signing key, created if missing")
+	addConfigFlag(cmd)
+
+	return cmd
+}
+
+func runIdentity(v *viper.Viper) error {
+	key, err := signing.LoadKey(v.GetString("signing-key"))
+	if err != nil {
+		return err
+	}
+	name := strings.ToLower(v.GetString("name"))
+	fmt.Println(key.PublicKey())
+	fmt.Println()
+	fmt.Println("# suffuse.toml on devices that should trust this one:")
+	fmt.Println("[signers]")
+	fmt.Printf("%q = %q\n", name, key.PublicKey())
+	return nil
+}
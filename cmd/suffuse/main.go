@@ -3,6 +3,7 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -22,7 +23,8 @@ containers, and SSH sessions over an encrypted TCP connection (opportunistic
 TLS — self-signed cert, no CA required).
 
 Run "suffuse server" on each host. Use --upstream to federate servers together.
-Use "suffuse copy/paste/status" as CLI tools on any host running a server.
+Use "suffuse copy/paste/status" as CLI tools on any host running a server,
+and "suffuse stop/restart" to stop or restart it.
 
 Config file search order (first found wins):
   /etc/suffuse/suffuse.toml
@@ -38,9 +40,25 @@ See "suffuse server --help" for the full flag reference.`,
 		newServerCmd(),
 		newCopyCmd(),
 		newPasteCmd(),
+		newHistoryCmd(),
+		newUndoCmd(),
+		newSnapshotCmd(),
+		newAcceptCmd(),
 		newStatusCmd(),
+		newWatchCmd(),
+		newTopCmd(),
+		newAdminCmd(),
+		newPairCmd(),
+		newIdentityCmd(),
+		newDoctorCmd(),
+		newStopCmd(),
+		newRestartCmd(),
+		newSimulateCmd(),
 		newVersionCmd(),
 	)
+	if m := platformServiceManager(); m != nil {
+		root.AddCommand(newServiceCmd(m))
+	}
 
 	if err := root.Execute(); err != nil {
 		os.Exit(1)
@@ -60,14 +78,17 @@ func newVersionCmd() *cobra.Command {
 
 // resolveLogging sets up the global slog logger after flags are parsed.
 func resolveLogging(interactive bool, formatStr, levelStr string) {
-	format := logging.ParseFormat(formatStr)
-	level := logging.ParseLevel(levelStr)
+	logging.Setup(logging.ParseFormat(formatStr), logLevel(interactive, levelStr))
+}
+
+// logLevel parses levelStr, defaulting to debug for interactive runs and to
+// info otherwise.
+func logLevel(interactive bool, levelStr string) slog.Level {
 	if levelStr == "" {
 		if interactive {
-			level = logging.ParseLevel("debug")
-		} else {
-			level = logging.ParseLevel("info")
+			return slog.LevelDebug
 		}
+		return slog.LevelInfo
 	}
-	logging.Setup(format, level)
+	return logging.ParseLevel(levelStr)
 }
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/pairing"
+	"go.klb.dev/suffuse/internal/sshtunnel"
+	"go.klb.dev/suffuse/internal/tlsconf"
+)
+
+// pairTimeout bounds a pairing exchange.
+const pairTimeout = 30 * time.Second
+
+func newPairCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "pair",
+		Short: "Issue a short-lived code a new device exchanges for its own token",
+		Long: `Asks the server for a six-digit pairing code. Running "suffuse pair join"
+with the code on a new device gives that device a token of its own, with
+--role and --clipboards, so the shared secret never needs copying to it.
+
+The code is valid for --ttl, for one device and three attempts; a new code
+replaces the last. It never crosses the network, so it is safe to read out.
+The server must run with --token, and keeps issued tokens in its
+--pairing-file across restarts.
+
+  suffuse pair --role read-only --clipboards team/*
+  suffuse pair join build-box 123456`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, _ []string) error { return runPair(cmd.Context(), v) },
+	}
+
+	f := cmd.Flags()
+	f.String("name", "", "name of the issued token (default: the name the device pairs with)")
+	f.String("role", "read-write", "role of the issued token: admin|read-write|read-only")
+	f.StringSlice("clipboards", nil, "clipboard patterns the issued token is limited to (default: all)")
+	f.Duration("ttl", pairing.DefaultTTL, "how long the code is valid")
+	addAdminConnFlags(cmd)
+
+	cmd.AddCommand(newPairJoinCmd())
+	return cmd
+}
+
+func runPair(ctx context.Context, v *viper.Viper) error {
+	conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), v.GetString("token"), v.GetString("source"), v.GetString("tls-ca"), v.GetString("via-ssh"))
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewAdminServiceClient(conn).StartPairing(ctx, &pb.StartPairingRequest{
+		Name:       v.GetString("name"),
+		Role:       v.GetString("role"),
+		Clipboards: getStringSlice(v, "clipboards"),
+		Ttl:        durationpb.New(v.GetDuration("ttl")),
+	})
+	if err != nil {
+		return fmt.Errorf("pair: %w", err)
+	}
+
+	host := v.GetString("host")
+	if host == "" {
+		host, _ = hostname()
+	}
+	if port := v.GetInt("port"); port != 8752 {
+		host = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	code := resp.Code
+	if len(code) == 6 {
+		code = code[:3] + " " + code[3:]
+	}
+	fmt.Printf("Pairing code: %s (valid until %s)\n\n", code, resp.ExpiresAt.AsTime().Local().Format(time.TimeOnly))
+	fmt.Println("On the new device, run:")
+	fmt.Printf("  suffuse pair join %s %s\n", host, resp.Code)
+	return nil
+}
+
+func newPairJoinCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "join <host[:port]> <code>",
+		Short: "Exchange a pairing code for a token from the server showing it",
+		Long: `Exchanges the code shown by "suffuse pair" on the server's host for a
+token of this device's own, then prints the configuration to use it.
+
+The exchange proves both sides know the code, so it needs no token or
+verified TLS and a server impersonating the real one learns nothing.`,
+		Args:    cobra.ExactArgs(2),
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPairJoin(cmd.Context(), v, args[0], args[1])
+		},
+	}
+
+	f := cmd.Flags()
+	f.String("name", defaultSource(), "name this device pairs with")
+	f.Int("port", 8752, "suffuse server port for a host given without one")
+	f.String("via-ssh", "", "reach the server through this SSH jump host, [user@]host[:port] (only port 22 needs to be open)")
+	addConfigFlag(cmd)
+	return cmd
+}
+
+func runPairJoin(ctx context.Context, v *viper.Viper, target, code string) error {
+	client, err := pairing.NewClient(code)
+	if err != nil {
+		return err
+	}
+	host, port := target, v.GetInt("port")
+	if h, p, err := net.SplitHostPort(target); err == nil {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return fmt.Errorf("port %q: %w", p, err)
+		}
+		host, port = h, n
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+
+	target = addr
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(tlsconf.UnverifiedCredentials())}
+	if via := v.GetString("via-ssh"); via != "" {
+		tunnel, err := sshtunnel.New(via)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.WithContextDialer(tunnel.DialContext))
+		target = sshtunnel.Target(addr)
+	}
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(ctx, pairTimeout)
+	defer cancel()
+
+	stream, err := pb.NewClipboardServiceClient(conn).Pair(ctx)
+	if err != nil {
+		return fmt.Errorf("pair: %w", err)
+	}
+	if err := stream.Send(&pb.PairRequest{Device: v.GetString("name"), Share: client.Share()}); err != nil {
+		return fmt.Errorf("pair: %w", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("pair: %w", err)
+	}
+	confirm, err := client.Confirm(resp.Share, resp.Confirm)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&pb.PairRequest{Confirm: confirm}); err != nil {
+		return fmt.Errorf("pair: %w", err)
+	}
+	resp, err = stream.Recv()
+	if err != nil {
+		return fmt.Errorf("pair: %w", err)
+	}
+	cred, err := client.Open(resp.Credential)
+	if err != nil {
+		return err
+	}
+	_ = stream.CloseSend()
+
+	fmt.Printf("Paired with %s as %q (%s).\n\n", addr, cred.Name, cred.Role)
+	fmt.Println("Add to this device's suffuse.toml:")
+	fmt.Printf("  host  = %q\n", host)
+	if port != 8752 {
+		fmt.Printf("  port  = %d\n", port)
+	}
+	fmt.Printf("  token = %q\n", cred.Token)
+	if cred.Name != defaultSource() {
+		// Servers with --bind-sources only accept the token's own name.
+		fmt.Printf("  source = %q\n", cred.Name)
+	}
+	fmt.Println()
+	fmt.Println("A server federating with it uses upstream-host and upstream-token instead.")
+	return nil
+}
@@ -2,16 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/e2e"
 	"go.klb.dev/suffuse/internal/hub"
 	"go.klb.dev/suffuse/internal/ipc"
+	"go.klb.dev/suffuse/internal/signing"
 )
 
 func newPasteCmd() *cobra.Command {
@@ -25,20 +30,55 @@ func newPasteCmd() *cobra.Command {
 If the clipboard contains only types not matching --mime, nothing is printed
 (exit 0). To retrieve an image:
 
-  suffuse paste --mime image/png > screenshot.png`,
+  suffuse paste --mime image/png > screenshot.png
+
+When the server runs with --host-clipboards, --from-host retrieves the last
+copy made on a specific host even if the shared clipboard was overwritten:
+
+  suffuse paste --from-host laptop
+
+When the server keeps history (server --history), --age and --before paste
+what the clipboard held at an earlier time instead, e.g. content overwritten
+minutes ago. --before takes a clock time (today, or yesterday if that is
+still to come), a date and time, or an RFC 3339 timestamp. Who copied it and
+when is printed on stderr:
+
+  suffuse paste --age 10m
+  suffuse paste --before 14:30
+  suffuse paste --before "2026-10-15 09:00"
+
+End-to-end encrypted clipboards are decrypted locally with the key configured
+for the clipboard (see "suffuse copy --help").
+
+Signed copies (see "suffuse copy --sign") are verified; content whose
+signature does not match is never printed. --verify also refuses content
+that is unsigned or signed by a key not listed with --signer or in the
+[signers] config table, and names the signer on stderr:
+
+  suffuse paste --verify --signer alice-laptop=ed25519:…`,
 		Args:    cobra.NoArgs,
 		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
 		RunE:    func(_ *cobra.Command, _ []string) error { return runPaste(v) },
 	}
 
 	f := cmd.Flags()
-	f.String("host", "", "suffuse server host (probes docker/podman/localhost if unset)")
+	f.String("host", "", "suffuse server host (probes docker/podman/localhost, then mDNS, if unset)")
 	f.Int("port", 8752, "suffuse server port")
 	f.String("token", "", "shared secret")
+	f.String("tls-ca", "", `CA certificate file to verify the server's certificate against, or "system" (default: verify the key derived from the token)`)
+	f.String("via-ssh", "", "reach the server through this SSH jump host, [user@]host[:port] (only port 22 needs to be open)")
 	f.String("mime", "text/plain", "preferred MIME type to output")
 	f.String("source", defaultSource(), "source identifier")
 	f.String("clipboard", hub.DefaultClipboard, "clipboard namespace")
+	f.String("from-host", "", `paste the last copy made by this source (reads "host/<source>")`)
+	f.Duration("age", 0, "paste what the clipboard held this long ago, from its history")
+	f.String("before", "", `paste what the clipboard held at this time, e.g. "14:30", from its history`)
+	addE2EFlag(cmd)
+	f.Bool("verify", false, "only print content signed by a trusted signer, and name it on stderr")
+	addTrustFlag(cmd)
 	addConfigFlag(cmd)
+	cmd.MarkFlagsMutuallyExclusive("clipboard", "from-host")
+	cmd.MarkFlagsMutuallyExclusive("age", "before")
 
 	return cmd
 }
@@ -51,32 +91,82 @@ func runPaste(v *viper.Viper) error {
 	host      := v.GetString("host")
 	port      := v.GetInt("port")
 
-	var (
-		conn *grpc.ClientConn
-		err  error
-	)
+	if fromHost := v.GetString("from-host"); fromHost != "" {
+		clipboard = hub.HostClipboard(fromHost)
+	}
 
-	if ipc.IsRunning() {
-		conn, err = dialIPC()
+	at, err := pasteAt(v.GetDuration("age"), v.GetString("before"), time.Now())
+	if err != nil {
+		return err
 	}
-	if conn == nil {
-		conn, err = dialServer(host, port, token, source)
-		if err != nil {
-			return fmt.Errorf("dial: %w", err)
-		}
+	keyring, err := loadKeyring(v)
+	if err != nil {
+		return err
+	}
+	trust, err := loadTrust(v)
+	if err != nil {
+		return err
+	}
+	cb := canonicalClipboard(clipboard)
+	accepts := []string{mime, signing.MIME}
+	if keyring.Encrypted(cb) {
+		// The server only sees the sealed item; filter after decrypting.
+		accepts = []string{e2e.MIME}
 	}
-	defer conn.Close()
 
-	client := pb.NewClipboardServiceClient(conn)
-	resp, err := client.Paste(context.Background(), &pb.PasteRequest{
+	req := &pb.PasteRequest{
 		Clipboard: clipboard,
-		Accepts:   []string{mime},
-	})
+		Accepts:   accepts,
+	}
+	if !at.IsZero() {
+		req.At = timestamppb.New(at)
+	}
+
+	var resp *pb.PasteResponse
+	if pipe := dialPipe(v.GetString("via-ssh")); pipe != nil {
+		defer pipe.Close()
+		resp, err = pipe.Paste(context.Background(), req)
+	} else {
+		var conn *grpc.ClientConn
+		if v.GetString("via-ssh") == "" && ipc.IsRunning() {
+			conn, err = dialIPC()
+		}
+		if conn == nil {
+			conn, err = dialServer(host, port, token, source, v.GetString("tls-ca"), v.GetString("via-ssh"))
+			if err != nil {
+				return fmt.Errorf("dial: %w", err)
+			}
+		}
+		defer conn.Close()
+		resp, err = pasteItems(context.Background(), pb.NewClipboardServiceClient(conn), req)
+	}
 	if err != nil {
 		return fmt.Errorf("paste: %w", err)
 	}
+	if req.At != nil {
+		if resp.StoredAt == nil {
+			// Servers without time-travel paste ignore At.
+			return errors.New("paste: the server cannot paste earlier content; upgrade it")
+		}
+		fmt.Fprintf(os.Stderr, "copied by %s at %s\n", resp.Source, resp.StoredAt.AsTime().Local().Format(time.RFC3339))
+	}
 
-	for _, it := range resp.Items {
+	items, err := keyring.Open(cb, resp.Items)
+	if err != nil {
+		return err
+	}
+	verify := trust.Verify
+	if v.GetBool("verify") {
+		verify = trust.Require
+	}
+	items, signer, err := verify(items)
+	if err != nil {
+		return err
+	}
+	if v.GetBool("verify") {
+		fmt.Fprintf(os.Stderr, "signed by %s at %s\n", signer, signer.At.Local().Format(time.RFC3339))
+	}
+	for _, it := range items {
 		if it.Mime == mime {
 			_, err = os.Stdout.Write(it.Data)
 			return err
@@ -84,3 +174,35 @@ func runPaste(v *viper.Viper) error {
 	}
 	return nil
 }
+
+// beforeLayouts are the forms --before accepts, most specific first.
+var beforeLayouts = []string{time.RFC3339, time.DateTime, "2006-01-02 15:04", time.TimeOnly, "15:04"}
+
+// pasteAt returns the time --age or --before asks for, or the zero time for
+// the current content. A clock time without a date is the last one before
+// now.
+func pasteAt(age time.Duration, before string, now time.Time) (time.Time, error) {
+	switch {
+	case age < 0:
+		return time.Time{}, fmt.Errorf("--age %s: must not be negative", age)
+	case age > 0:
+		return now.Add(-age), nil
+	case before == "":
+		return time.Time{}, nil
+	}
+	for _, layout := range beforeLayouts {
+		t, err := time.ParseInLocation(layout, before, time.Local)
+		if err != nil {
+			continue
+		}
+		if layout == time.TimeOnly || layout == "15:04" {
+			y, m, d := now.Date()
+			t = time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+			if t.After(now) {
+				t = t.AddDate(0, 0, -1)
+			}
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf(`--before %q: expected a time like "14:30", "2006-01-02 15:04" or RFC 3339`, before)
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"go.klb.dev/suffuse/internal/federation"
+	"go.klb.dev/suffuse/internal/grpcservice"
+	"go.klb.dev/suffuse/internal/hub"
+	"go.klb.dev/suffuse/internal/logging"
+)
+
+// Settings the server applies when it reloads its configuration, by the
+// name of their key (a table's name for tables such as [snippets]). Changes
+// to any other setting take effect at the next start.
+var (
+	reloadLogKeys = []string{"log-level"}
+
+	reloadRulesKeys = []string{
+		"mirrors", "protected-clipboards", "content-filters", "url-rewrites", "snippets",
+		"snippet", "allow-types", "deny-types", "derive-types", "no-transcode", "max-item-size",
+		"max-payload-size",
+	}
+
+	reloadUpstreamKeys = []string{
+		"upstream-host", "upstream-port", "upstream-token", "upstream-source",
+		"upstream-publish", "upstream-pin", "tls-ca",
+	}
+)
+
+// loadRules reads the hub rules from v and validates them.
+func loadRules(v *viper.Viper) (hub.Rules, error) {
+	var r hub.Rules
+	if err := v.UnmarshalKey("mirrors", &r.Mirrors); err != nil {
+		return r, fmt.Errorf("mirrors: %w", err)
+	}
+	if err := hub.CheckMirrors(r.Mirrors); err != nil {
+		return r, err
+	}
+	if err := v.UnmarshalKey("protected-clipboards", &r.WriteRules); err != nil {
+		return r, fmt.Errorf("protected-clipboards: %w", err)
+	}
+	if err := hub.CheckWriteRules(r.WriteRules); err != nil {
+		return r, err
+	}
+	if err := v.UnmarshalKey("content-filters", &r.ContentFilters); err != nil {
+		return r, fmt.Errorf("content-filters: %w", err)
+	}
+	if err := hub.CheckContentFilters(r.ContentFilters); err != nil {
+		return r, err
+	}
+	if err := v.UnmarshalKey("url-rewrites", &r.URLRewrites); err != nil {
+		return r, fmt.Errorf("url-rewrites: %w", err)
+	}
+	if err := hub.CheckURLRewrites(r.URLRewrites); err != nil {
+		return r, err
+	}
+	// Keys are matched case-insensitively; viper lower-cases those of the
+	// [snippets] table, and --snippet overrides the table per key.
+	r.Snippets = make(map[string]string)
+	for _, table := range []string{"snippets", "snippet"} {
+		for key, text := range v.GetStringMapString(table) {
+			r.Snippets[strings.ToLower(key)] = text
+		}
+	}
+	r.Types = hub.TypePolicy{Allow: getStringSlice(v, "allow-types"), Deny: getStringSlice(v, "deny-types")}
+	if err := hub.CheckTypePolicy(r.Types); err != nil {
+		return r, err
+	}
+	r.Derive = getStringSlice(v, "derive-types")
+	if err := hub.CheckDerive(r.Derive); err != nil {
+		return r, err
+	}
+	r.Transcode = !v.GetBool("no-transcode")
+	r.MaxItemSize = v.GetInt("max-item-size")
+	r.MaxPayloadSize = v.GetInt("max-payload-size")
+	if r.MaxItemSize < 0 || r.MaxPayloadSize < 0 {
+		return r, errors.New("max-item-size and max-payload-size must not be negative")
+	}
+	return r, nil
+}
+
+// upstreamConfigs returns the configuration of each upstream link given in
+// v. base supplies the server's token and source, the defaults of
+// --upstream-token and --upstream-source, and the settings shared by every
+// link.
+func upstreamConfigs(v *viper.Viper, base federation.Config) ([]federation.Config, error) {
+	addrs, err := joinUpstreams(getStringSlice(v, "upstream-host"), v.GetInt("upstream-port"))
+	if err != nil {
+		return nil, err
+	}
+	if t := v.GetString("upstream-token"); t != "" {
+		base.Token = t
+	}
+	if s := v.GetString("upstream-source"); s != "" {
+		base.Source = s
+	}
+	base.CA = v.GetString("tls-ca")
+	base.Publish = getStringSlice(v, "upstream-publish")
+	base.Pin = getStringSlice(v, "upstream-pin")
+	cfgs := make([]federation.Config, 0, len(addrs))
+	for _, addr := range addrs {
+		cfg := base
+		cfg.Addr = addr
+		cfgs = append(cfgs, cfg)
+	}
+	return cfgs, nil
+}
+
+// upstreamSet runs the server's upstream links and replaces those whose
+// configuration changes when the server reloads. A nil set has no links.
+type upstreamSet struct {
+	h *hub.Hub
+
+	mu    sync.Mutex
+	links []*upstreamLink // in configuration order
+}
+
+type upstreamLink struct {
+	cfg  federation.Config
+	up   *federation.Upstream
+	stop context.CancelFunc
+	done chan struct{} // closed when up.Run has returned
+}
+
+// set runs a link for each of cfgs, keeping the running links whose
+// configuration is unchanged. A link being replaced has stopped and left the
+// hub before its successor starts, as both use the same peer ID.
+func (s *upstreamSet) set(cfgs []federation.Config) error {
+	s.mu.Lock()
+	old := s.links
+	s.mu.Unlock()
+
+	running := make(map[string]*upstreamLink)
+	for _, l := range old {
+		i := slices.IndexFunc(cfgs, func(cfg federation.Config) bool { return cfg.Addr == l.cfg.Addr })
+		if i >= 0 && reflect.DeepEqual(cfgs[i], l.cfg) {
+			running[l.cfg.Addr] = l
+			continue
+		}
+		l.stop()
+		<-l.done
+	}
+
+	var links []*upstreamLink
+	var errs []error
+	for _, cfg := range cfgs {
+		if l, ok := running[cfg.Addr]; ok {
+			links = append(links, l)
+			continue
+		}
+		up, err := federation.New(cfg, s.h)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("federation: %w", err))
+			continue
+		}
+		ctx, stop := context.WithCancel(context.Background())
+		l := &upstreamLink{cfg: cfg, up: up, stop: stop, done: make(chan struct{})}
+		go func() {
+			defer close(l.done)
+			up.Run(ctx)
+		}()
+		links = append(links, l)
+	}
+
+	s.mu.Lock()
+	s.links = links
+	s.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// list returns the running upstreams.
+func (s *upstreamSet) list() []*federation.Upstream {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*federation.Upstream, len(s.links))
+	for i, l := range s.links {
+		out[i] = l.up
+	}
+	return out
+}
+
+// providers returns the running upstreams for grpcservice.
+func (s *upstreamSet) providers() []grpcservice.UpstreamInfoProvider {
+	var out []grpcservice.UpstreamInfoProvider
+	for _, up := range s.list() {
+		out = append(out, up)
+	}
+	return out
+}
+
+// reloader rereads the server's configuration and applies the settings that
+// can change while it runs: the log level, the hub rules and the upstream
+// links. Flags and SUFFUSE_* variables keep their precedence over the file,
+// so only settings they do not set can change.
+type reloader struct {
+	cmd       *cobra.Command
+	h         *hub.Hub
+	svc       *grpcservice.Service
+	upstreams *upstreamSet
+	base      federation.Config // see upstreamConfigs
+
+	mu sync.Mutex
+	v  *viper.Viper // the configuration last applied
+}
+
+// reload applies the configuration as it is now and returns the names of
+// the settings that changed. An invalid configuration is not applied at all.
+func (r *reloader) reload() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	nv := viper.New()
+	if err := bindViper(r.cmd, nv); err != nil {
+		return nil, err
+	}
+	rules, err := loadRules(nv)
+	if err != nil {
+		return nil, err
+	}
+	cfgs, err := upstreamConfigs(nv, r.base)
+	if err != nil {
+		return nil, err
+	}
+	for _, cfg := range cfgs {
+		if err := federation.CheckConfig(cfg); err != nil {
+			return nil, fmt.Errorf("upstream %s: %w", cfg.Addr, err)
+		}
+	}
+
+	var changed, ignored []string
+	for _, key := range settingNames(r.v, nv) {
+		if reflect.DeepEqual(r.v.Get(key), nv.Get(key)) {
+			continue
+		}
+		if slices.Contains(reloadLogKeys, key) || slices.Contains(reloadRulesKeys, key) || slices.Contains(reloadUpstreamKeys, key) {
+			changed = append(changed, key)
+		} else {
+			ignored = append(ignored, key)
+		}
+	}
+	changes := func(keys []string) bool {
+		return slices.ContainsFunc(changed, func(k string) bool { return slices.Contains(keys, k) })
+	}
+
+	if changes(reloadLogKeys) {
+		logging.SetLevel(logLevel(interactiveLogging(nv), nv.GetString("log-level")))
+	}
+	if changes(reloadRulesKeys) {
+		r.h.SetRules(rules)
+	}
+	if changes(reloadUpstreamKeys) {
+		if err := r.upstreams.set(cfgs); err != nil {
+			slog.Error("upstream links not all restarted", "err", err)
+		}
+		r.svc.SetUpstreams(r.upstreams.providers())
+	}
+	r.v = nv
+
+	if len(ignored) > 0 {
+		slog.Warn("configuration changes take effect only after a restart", "settings", ignored)
+	}
+	slog.Info("configuration reloaded", "changed", changed)
+	return changed, nil
+}
+
+// settingNames returns the top-level keys set in either configuration, a
+// table's keys under the table's name.
+func settingNames(a, b *viper.Viper) []string {
+	var names []string
+	for _, key := range append(a.AllKeys(), b.AllKeys()...) {
+		name, _, _ := strings.Cut(key, ".")
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// reloadOnSignal reloads the configuration on each SIGHUP.
+func reloadOnSignal(r *reloader) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	for range ch {
+		if _, err := r.reload(); err != nil {
+			slog.Error("configuration not reloaded", "err", err)
+		}
+	}
+}
@@ -3,28 +3,63 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	gwruntime "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/acme/autocert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 
 	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/accesslog"
+	"go.klb.dev/suffuse/internal/audit"
+	"go.klb.dev/suffuse/internal/blob"
+	"go.klb.dev/suffuse/internal/cache"
 	"go.klb.dev/suffuse/internal/clip"
+	"go.klb.dev/suffuse/internal/compress"
+	"go.klb.dev/suffuse/internal/enrich"
+	"go.klb.dev/suffuse/internal/fault"
 	"go.klb.dev/suffuse/internal/federation"
+	"go.klb.dev/suffuse/internal/files"
 	"go.klb.dev/suffuse/internal/grpcservice"
+	"go.klb.dev/suffuse/internal/guest"
 	"go.klb.dev/suffuse/internal/hub"
 	"go.klb.dev/suffuse/internal/ipc"
+	"go.klb.dev/suffuse/internal/journal"
 	"go.klb.dev/suffuse/internal/localpeer"
+	"go.klb.dev/suffuse/internal/logging"
+	"go.klb.dev/suffuse/internal/mdns"
+	"go.klb.dev/suffuse/internal/pairing"
+	"go.klb.dev/suffuse/internal/probe"
+	"go.klb.dev/suffuse/internal/quota"
+	"go.klb.dev/suffuse/internal/reexec"
+	"go.klb.dev/suffuse/internal/remotewrite"
+	"go.klb.dev/suffuse/internal/sdnotify"
+	"go.klb.dev/suffuse/internal/shaping"
+	"go.klb.dev/suffuse/internal/snapshot"
+	"go.klb.dev/suffuse/internal/sshtunnel"
 	"go.klb.dev/suffuse/internal/tlsconf"
+	"go.klb.dev/suffuse/internal/tokens"
+	"go.klb.dev/suffuse/internal/tracing"
+	"go.klb.dev/suffuse/internal/webhook"
+	"go.klb.dev/suffuse/internal/winsvc"
 )
 
 // keepalive timing constants.
@@ -34,6 +69,16 @@ const (
 	kaMinTime = 10 * time.Second
 )
 
+// Message size bounds: gRPC's default receive limit, the headroom added to
+// --max-payload-size when raising it, and how much larger than a gRPC
+// message a gateway request body may decompress to, base64 and JSON
+// included.
+const (
+	defaultMaxRecvMsgSize = 4 << 20
+	recvMsgOverhead       = 1 << 20
+	gatewayBodyFactor     = 2
+)
+
 func newServerCmd() *cobra.Command {
 	v := viper.New()
 
@@ -45,6 +90,13 @@ The server also participates as a local clipboard peer by default.
 
 Both gRPC and HTTP/JSON (grpc-gateway) are served on the same TCP port over
 TLS. A Unix IPC socket is also opened for local CLI tools (copy/paste/status).
+copy and paste send their request over it in one write, without setting up
+a gRPC connection, so editors that run them in quick succession are answered
+in well under a millisecond each from the server's own state. The server
+locks the socket path before it starts, so a second server on the same host
+refuses to run, naming the first one's pid, rather than take the socket and
+the clipboard over; set SUFFUSE_SOCKET to give another server its own.
+"suffuse stop" and "suffuse restart" stop and restart the server over it.
 
 Transport security
   All TCP connections use TLS encrypted with a key derived from --token.
@@ -53,25 +105,621 @@ Transport security
   still encrypted, but any other suffuse instance with the default will connect.
   Set a custom token to restrict access to instances sharing that secret.
 
+  --tls-cert and --tls-key serve a certificate of your own (corporate CA,
+  Let's Encrypt) to clients that verify certificates the usual way:
+  browsers, curl, and suffuse commands given --tls-ca. Those without it
+  still get the key derived from the token, so both work at once.
+  The files are read again within a minute of a renewal. Clients still
+  need the token to be authorized. --tls-ca verifies upstream servers in
+  the same way, against a CA file or the system trust store ("system").
+
+  --acme-domain obtains such a certificate from Let's Encrypt instead, for
+  a server with a public DNS name, and renews it before it expires, so
+  browsers use the HTTP/JSON gateway without warnings. The CA checks the
+  name with an HTTP-01 challenge on --acme-http-addr (port 80, which must
+  be reachable from the internet; other requests there are redirected to
+  the TLS port), or a TLS-ALPN-01 challenge when --addr is on port 443.
+  Certificates and the account key are kept in --acme-cache. --acme-email
+  registers a contact address; --acme-directory points at another ACME CA,
+  such as Let's Encrypt's staging environment.
+
+LAN discovery
+  The server advertises itself via mDNS as _suffuse._tcp (instance name
+  --source), so copy, paste, status and the other commands find it on the
+  local network when no --host is given and no local server answers. Only the
+  name, port and version are published; clients still need the matching
+  token to connect. --no-mdns turns the advertisement off; servers listening
+  on a loopback address never advertise.
+
+Health probes
+  The HTTP listener answers GET /healthz (liveness) and GET /readyz
+  (readiness: local clipboard peer running and, with
+  --ready-requires-upstream, the upstream link connected) without a token,
+  for Kubernetes and uptime monitors. Probes are not access-logged. The
+  gRPC listener and IPC socket serve the standard grpc.health.v1 Health
+  service to the same effect: service "" is SERVING while the server runs,
+  and "suffuse.v1.ClipboardService" when it is ready. Run by systemd with
+  Type=notify, the server reports readiness once it listens and, when the
+  unit sets WatchdogSec, keeps the watchdog fed while its hub answers.
+  Started as a Windows service (see "suffuse service --help"), it reports
+  itself running once it listens and logs to the Event Log.
+
+Exposure
+  The TCP listener serves gRPC reflection, the Status peer list, and admin
+  RPCs by default. --no-reflection, --no-public-status, and --admin-ipc-only
+  remove them from the network; the IPC socket, restricted to the local user,
+  always serves everything and needs no token.
+
+Token rotation
+  --accept-tokens lists further tokens the server accepts, each optionally
+  with an expiry ("old-secret@2026-11-01" or an RFC 3339 time). Clients tell
+  the server which key they expect during the TLS handshake, so both old and
+  new tokens work until the old one expires. "suffuse admin rotate-token"
+  switches a running server to a new token with a grace period for the old.
+
+Per-peer tokens
+  [[tokens]] tables in the config file give each person or machine a token
+  of its own with a role: admin (everything, including admin RPCs and
+  federation links), read-write (copy, paste, watch, status) or read-only
+  (paste, watch, status). A clipboards list limits the token to matching
+  clipboards; status then hides the others. --token, which must be set,
+  and --accept-tokens keep full access. Per-peer tokens are configured in
+  the config file only; see suffuse.toml.example.
+
+  The source name peers show in status, events and history is whatever the
+  client sends. --bind-sources binds it to the credential: a per-peer or
+  paired token may then only present its own name (or the patterns in its
+  sources list), and calls naming another are refused, so one peer cannot
+  pass itself off as another. Calls naming no source get the token's name.
+  The shared tokens and the guest token stay unbound.
+
+Pairing
+  "suffuse pair" issues a six-digit code, valid for one device and five
+  minutes by default, that "suffuse pair join" on a new device exchanges
+  for a per-peer token of its own. The code authenticates the exchange
+  itself and never crosses the network. Issued tokens are kept in
+  --pairing-file and loaded on start. "suffuse admin devices list" shows
+  the paired devices and when they were last seen, and "admin devices
+  revoke" cuts one off at once without changing anyone else's token.
+  Pairing requires --token. Devices that federate with this server need
+  --role admin.
+
+Guest access
+  --guest-token is a token meant to be handed out, e.g. to a conference-room
+  display: anyone holding it may paste from and watch the clipboards
+  matching --guest-clipboards ("guest" by default), and nothing else. Each
+  client host may make --guest-rate calls a minute, not counting Status
+  and health checks, and at most --guest-max-watchers guests may watch at
+  once; calls beyond either get ResourceExhausted. Guests are not told
+  apart, so the token identifies no one. It requires --token and must
+  differ from it.
+
+Protected clipboards
+  [[protected-clipboards]] tables in the config file make clipboards
+  read-only for everyone but the listed sources and per-peer tokens, e.g.
+  an "announcements" clipboard only the ops host may set. Copies from other
+  clients are refused, and events from federation links are not published.
+  Sources are chosen by the clients themselves; list tokens where that
+  matters.
+
+Content filters
+  [[content-filters]] tables in the config file hold back copies whose text
+  matches a regular expression, e.g. cloud credentials or private keys:
+  "drop" refuses the whole copy, "redact" replaces the matches. They apply
+  to this host's clipboard and to copies from clients, before the content
+  reaches any other machine; events from federation links are filtered by
+  the server they were copied on.
+
+URL rewriting
+  [[url-rewrites]] tables rewrite the http(s) links in copied text/plain
+  items: strip-params removes query parameters such as "utm_*" and host
+  maps a host, e.g. an internal name, to another. Rules with on = "publish"
+  (the default) apply to copies made here; on = "receive" applies to content
+  arriving over federation links, so each site maps links to the hosts it
+  knows them by. Signed copies are left as they are.
+
+Type policy
+  --allow-types and --deny-types are MIME type patterns ("text/*",
+  "application/x-ms-shortcut") limiting what this server relays at all,
+  unlike the types each peer accepts. Items of a denied type, or with
+  --allow-types of a type not listed, are removed from copies from
+  clients, events from federation links and the local clipboard before
+  they are stored; a copy left with no items is refused, and clients get
+  a PermissionDenied error. --deny-types wins over --allow-types. Items
+  encrypted end to end travel as application/x-suffuse-e2e and copied
+  files as application/x-suffuse-files, so an allowlist must name those
+  types to pass them. Probes are not affected.
+
+Text representations
+  --derive-types lists the text types added to copies lacking them,
+  converted from the text representation they carry, so peers accepting
+  only some text types still receive the content: text/plain from
+  text/markdown or text/html, text/markdown from text/html, and text/html
+  from text/markdown or text/plain. text/plain is derived by default; pass
+  --derive-types "" to derive nothing. Types the type policy bans are not
+  derived, and signed copies are left as they are.
+
+  Text items that are not UTF-8, as legacy applications copy in the code
+  page of their system, are converted to UTF-8 before anything else reads
+  them, with the character set they were in recorded as the item's
+  original_charset. The character set is the one a charset parameter of
+  the type declares, or else detected: UTF-16, Windows-1252, Shift_JIS,
+  EUC-JP, GBK, Big5 or EUC-KR. --no-transcode relays such text as it is.
+
+Size limits
+  --max-item-size and --max-payload-size cap the size of a single clipboard
+  item and of all items of one copy, so a 100 MB screenshot does not flow
+  to every peer. They apply to copies from clients, events from federation
+  links and the local clipboard alike. Clients get an InvalidArgument error
+  naming the item and the limit; other oversized content is logged, dropped
+  and counted in "suffuse status". The suffuse CLI moves items over 1 MiB
+  in chunks, so its copies are bounded only by these limits. Other gRPC
+  clients sending single-message copies are held to 4 MiB per copy;
+  --max-payload-size raises that bound.
+
+Per-source quotas
+  --quota-copies limits how many copies each source may make per hour and
+  --quota-bytes how many bytes it may copy per day, so one chatty automation
+  account cannot flood a shared hub. Both recover continuously rather than
+  at the top of the hour. --quota-action decides what happens to a copy
+  over quota: reject (the default) refuses it, throttle delays it until the
+  quota allows, up to a minute, and warn accepts it and logs a warning.
+  Quotas apply to copies made on this server; federated content counts
+  where it was copied. "suffuse status --usage" shows each source's
+  standing.
+
+Host clipboards
+  With --host-clipboards every publish is also kept in a private clipboard
+  named "host/<source>", so a host's own last copy survives when another peer
+  overwrites the shared clipboard. Retrieve it with
+  "suffuse paste --from-host <source>".
+
+Clipboard history
+  With --history N the server keeps the last N earlier contents of each
+  clipboard in memory, so a copy that was overwritten is not lost. "suffuse
+  history" lists them and "suffuse history show <n>" prints one. History is
+  not cached or federated; each server keeps its own.
+
+Clipboard expiry
+  --clipboard-ttl clears a clipboard's content the given time after it was
+  copied, so a password copied from a manager does not linger on every
+  machine: "5m" applies to every clipboard, "passwords=30s" to the clipboards
+  a pattern matches, ahead of a plain duration; repeat the flag to combine
+  them. Peers are told: the local clipboard is emptied if it still holds the
+  expired content, and Watch streams receive an event marked expired. The
+  content's history entries go too. Every server of a federation applies its
+  own TTLs, so give them all the option.
+
+Local conflicts
+  When an update from another host arrives less than --conflict-window after
+  the local clipboard changed, overwriting it would lose a copy made moments
+  ago. --conflict decides: remote-wins (the default) applies the update,
+  local-wins leaves the local clipboard as it is (the update is still the
+  server's content and pastes with "suffuse paste"), and keep-both applies
+  the update after adding the local content to the history, which needs
+  --history. Whatever the policy, "suffuse undo" restores the local content
+  the last update overwrote.
+
+Preferred types
+  --prefer-types ranks the types written to the local clipboard, most
+  preferred first and separated by ">": of the types an update carries
+  that a ranking names, only the best is written. With
+  "text/plain>text/html" an update carrying both pastes as plain text, so a
+  terminal or editor gets no styled HTML; an update with HTML only still
+  writes it. Types may be patterns ("text/plain>text/*"); give several
+  rankings to rank unrelated types, and types no ranking names are written
+  as usual. Only this host's clipboard is affected: the server keeps every
+  type for other peers, and "suffuse paste" returns them all.
+
+Held updates
+  With --hold, updates from other hosts are not written to the local
+  clipboard as they arrive: the latest is held, with a desktop notification
+  where one can be shown (notify-send on Linux, osascript on macOS, a toast
+  on Windows when not run as the service), until "suffuse accept" applies
+  it or "suffuse accept --discard" drops it. A local copy drops the held
+  update, which it supersedes. --conflict has no effect while holding.
+
+  --hold-executables holds only updates that look like programs or
+  scripts, as a safety net against copies meant to be pasted into a shell
+  or run: executable types (application/x-msdownload, shell scripts, ...),
+  the magic numbers of PE, ELF and Mach-O binaries, text starting with
+  "#!", and copied files with executable names, permissions or content.
+
+Sensitive content
+  Password managers mark what they copy so clipboard tools leave it alone:
+  x-kde-passwordManagerHint on Linux, org.nspasteboard.ConcealedType on
+  macOS and ExcludeClipboardContentFromMonitorProcessing on Windows. Such
+  copies stay on this host unless --sync-sensitive is set, in which case
+  they are synced along with the marker and marked again on the other
+  hosts. On Linux the fallback poller, used without data-control or a
+  direct X11 connection, does not see the marker.
+
+Universal Clipboard
+  Apple's Universal Clipboard syncs the clipboard between the Apple devices
+  of one account by itself, so a Mac running suffuse too receives what
+  another of those devices copied twice. --universal-clipboard decides:
+  publish (the default) publishes what arrives through Universal Clipboard
+  like any copy; ignore leaves it to the device it was copied on; isolate
+  also keeps updates from other hosts out of Universal Clipboard (macOS 13
+  and later), so each Mac takes them from suffuse alone.
+
+Named clipboards
+  The system clipboard is synced with the "default" clipboard; --clipboard
+  names another, e.g. "work", so this host shares only with the hosts and
+  clients using that name. copy, paste and watch read the same clipboard
+  key from the config file, so one line there moves them all.
+
+End-to-end encryption
+  --e2e-key clipboard=passphrase (or the [e2e-keys] config table, shared
+  with the CLI) encrypts a clipboard between the hosts holding its key:
+  this host's clipboard is sealed before it is published and updates are
+  opened before they are written, so hubs in between, such as one on a
+  VPS, relay only ciphertext. Content filters, webhooks and the web UI see
+  none of it, and hosts without the key do not receive it.
+
+Signed copies
+  The source an update names is whatever its client says. --sign signs
+  this host's clipboard changes with the device key in --signing-key (see
+  "suffuse identity"), so receivers that list its public key with --signer
+  or in the [signers] config table know who copied them; "suffuse paste
+  --verify" and "suffuse watch" show the signer. Updates written to this
+  host's clipboard are verified first: their signer is logged, and content
+  whose signature does not match is not written.
+
+Primary selection
+  On Linux, --primary also syncs the primary selection — the text last
+  selected with the mouse, pasted with the middle button — with its own
+  clipboard, --primary-clipboard (default "primary"), so it never mixes with
+  the regular clipboard. Under Wayland this needs a compositor with
+  data-control; under X11 it is followed through XFIXES, or polled without
+  it. Only text is synced, and a selection is published once it stops
+  changing.
+
+Copied files
+  Files copied in a file manager travel as a text/uri-list of file:// URLs,
+  mapped to CF_HDROP on Windows and NSFilenamesPboardType on macOS (Linux
+  needs Wayland data-control or X11; the fallback poller carries text and
+  images only).
+  On their own they only paste where the same paths exist. With
+  --transfer-files the server holding the files attaches their content, up
+  to --transfer-files-max-bytes per copy, and a server with the option set
+  unpacks arriving files into --transfer-files-dir and pastes those copies,
+  so a file copied on one machine can be pasted on another. The 16 most
+  recent pasted sets are kept. Both ends need the option; larger copies
+  carry the references only.
+
+Terminal clipboard over SSH
+  --clipboard-backend=osc52 reaches the clipboard through OSC 52 escape
+  sequences on the controlling terminal instead of a display server, so a
+  server started inside an SSH session on a headless host pushes copies to
+  the clipboard of the terminal emulator on your desk. tmux and screen are
+  passed through. Only text is carried, changes made in the terminal are not
+  noticed, and the terminal must allow OSC 52 (most do for writes).
+
+Clipboard cache
+  With --cache the server keeps the latest contents of its clipboards, most
+  recent first up to --cache-max-bytes, in --cache-file, encrypted with a key
+  derived from --token. They are restored on start, so after a reboot the
+  last clipboard is back on the local clipboard and pasteable before the
+  upstream link reconnects. "suffuse admin clear" also clears the cache.
+
+Event journal
+  With --journal the server records every publish — source, clipboard, MIME
+  types, size and event ID, never the content — and what became of it for
+  each peer: delivered, dropped by a full queue, filtered by the peer's
+  accepted types, stopped by the hop limit, or refused by a write rule,
+  quota or size limit. "suffuse admin journal" queries it, e.g. to find out
+  why a copy never reached a host. Entries go to --journal-file, which
+  together with its predecessor (".1") is kept under --journal-max-bytes.
+
+Audit log
+  --audit-log /var/log/suffuse/audit.jsonl records every Copy, Paste,
+  History, Fetch, Watch and Status call, over TCP, the IPC socket or the
+  HTTP/JSON gateway, as a JSON line when it ends: method, client address
+  (and the address the gateway forwarded for), token name, source,
+  clipboard, MIME types, item count and bytes moved, and whether it was
+  allowed, denied for its token, or failed. Content is never recorded.
+  Lines are written as calls end and the file is never trimmed; rotate it
+  with logrotate's copytruncate. "-" writes to standard output.
+
+Snapshots
+  "suffuse snapshot save <name>" saves the content of several clipboards at
+  once under a name, one file each in --snapshot-dir, and "suffuse snapshot
+  restore <name>" sets them all back, or none if any is refused. Snapshot
+  files are readable only by the server's user but not encrypted.
+
+Slow consumers
+  Every peer has a bounded queue. When one fills up (a stalled watcher, a
+  congested federation link) --slow-consumer decides what is lost, and
+  "suffuse status" counts it against the peer: drop-newest, the default,
+  drops the new event; drop-oldest drops the oldest queued one instead, so
+  the peer catches up to the latest content; block waits up to
+  --slow-consumer-timeout for room before dropping, holding up delivery to
+  the peers after it; disconnect drops the event and also ends Watch and
+  Federate streams so they reconnect and resync from the latest clipboard
+  contents, while the local clipboard and upstream link drop. --queue-size
+  sets the queue length of every peer, or of one kind: watch, localpeer,
+  federation-upstream, federation-downstream, webhook or processor.
+  "suffuse status" shows how full each peer's queue is.
+
+Duplicate suppression
+  In a mesh of federated servers and watchers the same item can come back
+  from several peers at once. With --dedup-window (e.g. 2s) a publish whose
+  content matches what its clipboard was set to less than that long ago is
+  not stored or fanned out again, whichever peer it comes from. Independent
+  of the window, every publish carries an event ID across federation links,
+  and an event that reaches a server a second time over another path (a
+  diamond or a cycle in the topology) is not published again. "suffuse
+  status" shows how many publishes were suppressed.
+
+Hop limit
+  Every event crossing a federation link carries a hop limit, decremented at
+  each link and capped by each server at its own --max-hops (default 8).
+  Content published on this server starts at --max-hops; once the limit is
+  spent an event is still stored here but relayed no further, so a cycle in
+  the federation topology or an unexpectedly deep chain of servers cannot
+  pass content around indefinitely. "suffuse status" shows how many events
+  were stopped.
+
+  Events also carry their origin path: the --source of every server they
+  were published on. A server drops an event that returns to it, so a cycle
+  is cut after one round rather than when the hop limit runs out. Sources
+  must therefore be unique across the federation.
+
+Referenced transfer
+  Items larger than --blob-threshold bytes (default 1 MiB) are kept once in
+  a content-addressed blob store. Downstream servers and watchers that opt in
+  receive a reference (SHA-256 and size) instead of the data and fetch it with
+  the Fetch RPC (GET /v1/blobs/{sha256}) only when they need it; every other
+  peer still receives the content inline. 0 disables referencing. Blobs no
+  clipboard references any more are removed once unused for --blob-ttl;
+  "suffuse admin blobs prune" removes them immediately.
+
+  Text items are referenced from --text-threshold bytes (default 256 KiB)
+  and keep their first --text-preview bytes (default 4 KiB) inline as a
+  preview, cut at the end of a line, so a huge log paste reaches watchers
+  at once and is fetched in full only when it is pasted.
+
+HTTP access log
+  Requests to the HTTP/JSON gateway are logged with method, path, status,
+  duration, size, and client address, tagged log=access (gRPC calls are not).
+  Behind a reverse proxy, --access-log-trust-proxy takes the client address
+  from Forwarded/X-Forwarded-For. --access-log-sample thins out busy
+  endpoints, e.g. "/v1/paste=10" logs one in ten successful pastes; errors
+  are always logged.
+
+Compression
+  Clients and downstream servers compress their gRPC calls with gzip, and
+  the server answers each in kind; with servers that predate compression
+  they fall back to plain calls. The HTTP/JSON gateway compresses responses
+  for clients sending "Accept-Encoding: gzip" and accepts request bodies
+  sent with "Content-Encoding: gzip". Calls over the IPC socket are never
+  compressed.
+
+Webhooks
+  [[webhooks]] tables in the config file POST a JSON description of each
+  change to matching clipboards (source, clipboard, MIME types, size, and
+  with include-payload the content itself) to a URL, e.g. to post copied
+  links into Slack or trigger CI from a "commands" clipboard. Requests can be
+  HMAC-signed; failures are retried with back-off and then written to a
+  dead-letter log. Webhooks are configured in the config file only; see
+  suffuse.toml.example.
+
+Enrichment
+  [[processors]] tables in the config file derive an item from copied
+  content after it was delivered, e.g. text/plain recognised in an
+  image/png by an OCR command, or text/markdown converted from text/html
+  by an HTTP service. The item is added to the content and published again
+  under the same event ID, unless the clipboard changed meanwhile, so
+  every peer and federated server gets it as a follow-up of the copy.
+  Content that already has an item of the produced type, signed copies
+  and content encrypted end to end are left alone.
+
+Snippets
+  Copying a key of the [snippets] config table (or a --snippet flag) to the
+  "snippet" clipboard publishes the snippet's text instead, to every peer of
+  the clipboard; other copies to it are published as they are. Keys are
+  matched case-insensitively, ignoring surrounding space. Like content
+  filters they apply to this host's clipboard and to copies from clients. A
+  [[mirrors]] rule from "snippet" to "default" puts the text on every
+  host's clipboard.
+
+Clipboard mirrors
+  [[mirrors]] tables in the config file copy everything published on one
+  clipboard into another, optionally limited to some MIME types, e.g.
+  "default" into "readonly-display" with images stripped. two-way mirrors
+  in both directions. A mirrored copy is never mirrored again, so two-way
+  rules cannot loop. Each server applies its own rules, to local and
+  federated publishes alike; the copies reach downstream servers watching
+  the destination but are not forwarded upstream.
+
+Metrics remote write
+  --remote-write-url pushes hub metrics (peers, publishes, bytes transferred,
+  drops, webhook deliveries) to a Prometheus remote-write endpoint every
+  --remote-write-interval, for setups without a Prometheus that can scrape
+  the server. Series carry job="suffuse", instance=<source>, and any
+  --remote-write-labels (e.g. "env=prod,site=lab").
+
+Tracing
+  --otlp-endpoint exports OpenTelemetry traces to a collector: host:port for
+  OTLP over gRPC (TLS unless --otlp-insecure), or an http(s):// URL for OTLP
+  over HTTP. --otlp-headers adds headers to every export, e.g. an API key.
+  Each call the server answers, each publish on its hub, each event it
+  forwards over a federation link and each delivery to a watcher or the
+  local clipboard is a span; forwarded events carry their trace to the next
+  server, so one trace follows a copy across the federation to every host
+  it reached. --trace-sample-ratio records a fraction of the traces started
+  here; traces continued from another server follow its decision. Servers
+  without --otlp-endpoint still pass trace context on.
+
 Federation
   Use --upstream to federate this server with another suffuse hub. Clipboard
   events flow both ways. The upstream accept filter stays in sync with local
   peer capabilities (e.g. text-only peers won't pull binary data from upstream).
+  Local copies on every clipboard are forwarded upstream, watched locally or
+  not; --upstream-publish restricts forwarding to clipboards matching the
+  given patterns (e.g. "default,team/*"), so private clipboards never leave
+  the site. --upstream-pin keeps the listed clipboards
+  subscribed, with every MIME type, even while nothing local watches them,
+  so a relay holds their latest content for clients that connect only now
+  and then.
+
+  --upstream-host takes a comma-separated list (e.g. "hq,backup:9000") to
+  join several hubs at once, so servers can form a mesh rather than a tree.
+  Entries without a port use --upstream-port; token, source, publish and
+  pin settings apply to every upstream. Each link has its own stream,
+  subscription and outbox, and events are relayed between them, so content
+  copied under one hub reaches the others through this server. An event
+  that arrives again over a second path is recognised by its ID and
+  dropped. With --ready-requires-upstream every link must be up.
+
+  --upstream-via-ssh user@bastion reaches the upstreams through an SSH jump
+  host, for networks where only port 22 is open; the bastion resolves the
+  upstream hosts. It is authenticated against ~/.ssh/known_hosts, with the
+  keys of ssh-agent or the unencrypted ones in ~/.ssh, and the link is
+  reopened when it drops. Clients take --via-ssh the same way.
+
+  A dropped link is redialled straight away, with a short back-off, and
+  the stream reopened as soon as the connection is back rather than after
+  the reconnect delay. Reconnects resume the TLS session, one round trip
+  instead of a full handshake, so a flapping Wi-Fi link resumes sync in
+  tens of milliseconds; the handshake time and whether it was resumed are
+  logged when the stream connects.
+
+Traffic shaping
+  --defer-hours (e.g. "09:00-17:00", local time; "22:00-07:00" spans
+  midnight) and --defer-metered hold back non-text items such as images and
+  files on the upstream link during those hours or while the network is
+  metered (detected via NetworkManager on Linux and the connection cost on
+  Windows). Text still flows both ways. Items from upstream arrive as
+  metadata only and are fetched once conditions allow; local copies are
+  forwarded with their text, and the rest follows if they are still the
+  latest content then. "suffuse status" shows when items are being deferred.
+
+End-to-end probes
+  With --probe-interval (e.g. 30s) the server publishes a tiny probe on the
+  hidden clipboard "_probe/<source>" that often. Every server above it in
+  the federation answers, and the round trip to each is shown by "suffuse
+  status" and exported as metrics. A server that answered before but misses
+  three probes in a row is logged as not answering. Probes travel every
+  upstream link whatever --upstream-publish allows, and are kept out of
+  host clipboards, webhooks, the cache and the journal. Answers are told
+  apart by --source, which must be unique across the federation.
+
+Reloading
+  On SIGHUP, "suffuse admin reload" or, as a Windows service, a
+  paramchange control, the server rereads its config file and applies
+  what can change while it runs: log-level, mirrors, protected-clipboards,
+  content-filters, url-rewrites, snippets, allow-types, deny-types,
+  derive-types, no-transcode, max-item-size, max-payload-size and the
+  upstream-* settings and tls-ca.
+  Upstream links whose settings are unchanged stay connected; the others
+  are reconnected, added or closed. Flags and SUFFUSE_* variables still
+  take precedence over the file. An invalid file is refused and logged,
+  leaving the running configuration in force; changes to other settings
+  are logged as taking effect at the next start. A reload that raises
+  max-payload-size above the 4 MiB message bound of the start only helps
+  copies sent in chunks.
 
 Flags, environment variables, and config-file keys
-  Flag                Env var                     Config key
-  ───────────────────────────────────────────────────────────
-  --addr              SUFFUSE_ADDR                addr
-  --token             SUFFUSE_TOKEN               token
-  --source            SUFFUSE_SOURCE              source
-  --no-local          SUFFUSE_NO_LOCAL            no-local
-  --upstream-host     SUFFUSE_UPSTREAM_HOST       upstream-host
-  --upstream-port     SUFFUSE_UPSTREAM_PORT       upstream-port
-  --upstream-token    SUFFUSE_UPSTREAM_TOKEN      upstream-token
-  --upstream-source   SUFFUSE_UPSTREAM_SOURCE     upstream-source
-  --log-level         SUFFUSE_LOG_LEVEL           log-level    (debug|info|warn|error)
-  --log-format        SUFFUSE_LOG_FORMAT          log-format   (auto|text|json)
-  --config            (flag only)
+  Flag                        Env var                           Config key
+  ───────────────────────────────────────────────────────────────────────────
+  --addr                      SUFFUSE_ADDR                      addr
+  --token                     SUFFUSE_TOKEN                     token
+  --accept-tokens             SUFFUSE_ACCEPT_TOKENS             accept-tokens
+  --bind-sources              SUFFUSE_BIND_SOURCES              bind-sources
+  --tls-cert                  SUFFUSE_TLS_CERT                  tls-cert
+  --tls-key                   SUFFUSE_TLS_KEY                   tls-key
+  --acme-domain               SUFFUSE_ACME_DOMAIN               acme-domain
+  --acme-email                SUFFUSE_ACME_EMAIL                acme-email
+  --acme-cache                SUFFUSE_ACME_CACHE                acme-cache
+  --acme-directory            SUFFUSE_ACME_DIRECTORY            acme-directory
+  --acme-http-addr            SUFFUSE_ACME_HTTP_ADDR            acme-http-addr
+  --tls-ca                    SUFFUSE_TLS_CA                    tls-ca
+  --guest-token               SUFFUSE_GUEST_TOKEN               guest-token
+  --guest-clipboards          SUFFUSE_GUEST_CLIPBOARDS          guest-clipboards
+  --guest-rate                SUFFUSE_GUEST_RATE                guest-rate
+  --guest-max-watchers        SUFFUSE_GUEST_MAX_WATCHERS        guest-max-watchers
+  --source                    SUFFUSE_SOURCE                    source
+  --no-local                  SUFFUSE_NO_LOCAL                  no-local
+  --clipboard                 SUFFUSE_CLIPBOARD                 clipboard
+  --e2e-key                   SUFFUSE_E2E_KEYS                  [e2e-keys]
+  --sign                      SUFFUSE_SIGN                      sign
+  --signing-key               SUFFUSE_SIGNING_KEY               signing-key
+  --signer                    [signers] (config table or flag)
+  --clipboard-backend         SUFFUSE_CLIPBOARD_BACKEND         clipboard-backend        (auto|osc52)
+  --host-clipboards           SUFFUSE_HOST_CLIPBOARDS           host-clipboards
+  --primary                   SUFFUSE_PRIMARY                   primary
+  --primary-clipboard         SUFFUSE_PRIMARY_CLIPBOARD         primary-clipboard
+  --history                   SUFFUSE_HISTORY                   history
+  --clipboard-ttl             SUFFUSE_CLIPBOARD_TTL             clipboard-ttl
+  --conflict                  SUFFUSE_CONFLICT                  conflict                 (remote-wins|local-wins|keep-both)
+  --conflict-window           SUFFUSE_CONFLICT_WINDOW           conflict-window
+  --prefer-types              SUFFUSE_PREFER_TYPES              prefer-types
+  --hold                      SUFFUSE_HOLD                      hold
+  --hold-executables          SUFFUSE_HOLD_EXECUTABLES          hold-executables
+  --sync-sensitive            SUFFUSE_SYNC_SENSITIVE            sync-sensitive
+  --universal-clipboard       SUFFUSE_UNIVERSAL_CLIPBOARD       universal-clipboard      (publish|ignore|isolate)
+  --transfer-files            SUFFUSE_TRANSFER_FILES            transfer-files
+  --transfer-files-dir        SUFFUSE_TRANSFER_FILES_DIR        transfer-files-dir
+  --transfer-files-max-bytes  SUFFUSE_TRANSFER_FILES_MAX_BYTES  transfer-files-max-bytes
+  --slow-consumer             SUFFUSE_SLOW_CONSUMER             slow-consumer            (drop-newest|drop-oldest|block|disconnect)
+  --slow-consumer-timeout     SUFFUSE_SLOW_CONSUMER_TIMEOUT     slow-consumer-timeout
+  --queue-size                SUFFUSE_QUEUE_SIZE                queue-size
+  --dedup-window              SUFFUSE_DEDUP_WINDOW              dedup-window
+  --max-hops                  SUFFUSE_MAX_HOPS                  max-hops
+  --allow-types               SUFFUSE_ALLOW_TYPES               allow-types
+  --deny-types                SUFFUSE_DENY_TYPES                deny-types
+  --derive-types              SUFFUSE_DERIVE_TYPES              derive-types
+  --no-transcode              SUFFUSE_NO_TRANSCODE              no-transcode
+  --max-item-size             SUFFUSE_MAX_ITEM_SIZE             max-item-size
+  --max-payload-size          SUFFUSE_MAX_PAYLOAD_SIZE          max-payload-size
+  --quota-copies              SUFFUSE_QUOTA_COPIES              quota-copies
+  --quota-bytes               SUFFUSE_QUOTA_BYTES               quota-bytes
+  --quota-action              SUFFUSE_QUOTA_ACTION              quota-action             (warn|throttle|reject)
+  --blob-threshold            SUFFUSE_BLOB_THRESHOLD            blob-threshold
+  --blob-ttl                  SUFFUSE_BLOB_TTL                  blob-ttl
+  --text-threshold            SUFFUSE_TEXT_THRESHOLD            text-threshold
+  --text-preview              SUFFUSE_TEXT_PREVIEW              text-preview
+  --cache                     SUFFUSE_CACHE                     cache
+  --cache-file                SUFFUSE_CACHE_FILE                cache-file
+  --cache-max-bytes           SUFFUSE_CACHE_MAX_BYTES           cache-max-bytes
+  --journal                   SUFFUSE_JOURNAL                   journal
+  --journal-file              SUFFUSE_JOURNAL_FILE              journal-file
+  --journal-max-bytes         SUFFUSE_JOURNAL_MAX_BYTES         journal-max-bytes
+  --audit-log                 SUFFUSE_AUDIT_LOG                 audit-log
+  --snapshot-dir              SUFFUSE_SNAPSHOT_DIR              snapshot-dir
+  --snippet                   [snippets] (config table or flag)
+  --pairing-file              SUFFUSE_PAIRING_FILE              pairing-file
+  --no-mdns                   SUFFUSE_NO_MDNS                   no-mdns
+  --no-reflection             SUFFUSE_NO_REFLECTION             no-reflection
+  --no-public-status          SUFFUSE_NO_PUBLIC_STATUS          no-public-status
+  --admin-ipc-only            SUFFUSE_ADMIN_IPC_ONLY            admin-ipc-only
+  --access-log                SUFFUSE_ACCESS_LOG                access-log
+  --access-log-trust-proxy    SUFFUSE_ACCESS_LOG_TRUST_PROXY    access-log-trust-proxy
+  --access-log-sample         SUFFUSE_ACCESS_LOG_SAMPLE         access-log-sample
+  --remote-write-url          SUFFUSE_REMOTE_WRITE_URL          remote-write-url
+  --remote-write-interval     SUFFUSE_REMOTE_WRITE_INTERVAL     remote-write-interval
+  --remote-write-token        SUFFUSE_REMOTE_WRITE_TOKEN        remote-write-token
+  --remote-write-labels       SUFFUSE_REMOTE_WRITE_LABELS       remote-write-labels
+  --otlp-endpoint             SUFFUSE_OTLP_ENDPOINT             otlp-endpoint
+  --otlp-insecure             SUFFUSE_OTLP_INSECURE             otlp-insecure
+  --otlp-headers              SUFFUSE_OTLP_HEADERS              otlp-headers
+  --trace-sample-ratio        SUFFUSE_TRACE_SAMPLE_RATIO        trace-sample-ratio
+  --upstream-host             SUFFUSE_UPSTREAM_HOST             upstream-host
+  --upstream-port             SUFFUSE_UPSTREAM_PORT             upstream-port
+  --upstream-token            SUFFUSE_UPSTREAM_TOKEN            upstream-token
+  --upstream-source           SUFFUSE_UPSTREAM_SOURCE           upstream-source
+  --upstream-publish          SUFFUSE_UPSTREAM_PUBLISH          upstream-publish
+  --upstream-pin              SUFFUSE_UPSTREAM_PIN              upstream-pin
+  --upstream-via-ssh          SUFFUSE_UPSTREAM_VIA_SSH          upstream-via-ssh
+  --defer-hours               SUFFUSE_DEFER_HOURS               defer-hours
+  --defer-metered             SUFFUSE_DEFER_METERED             defer-metered
+  --probe-interval            SUFFUSE_PROBE_INTERVAL            probe-interval
+  --ready-requires-upstream   SUFFUSE_READY_REQUIRES_UPSTREAM   ready-requires-upstream
+  --log-level                 SUFFUSE_LOG_LEVEL                 log-level                (debug|info|warn|error)
+  --log-format                SUFFUSE_LOG_FORMAT                log-format               (auto|text|json)
+  --config                    (flag only)
 
 Config file search order (first found wins)
   /etc/suffuse/suffuse.toml
@@ -81,99 +729,653 @@ Config file search order (first found wins)
 Precedence: defaults → config file → SUFFUSE_* env vars → CLI flags`,
 		Args:    cobra.NoArgs,
 		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
-		RunE:    func(_ *cobra.Command, _ []string) error { return runServer(v) },
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if winsvc.IsService() {
+				return winsvc.Run(func(ctx context.Context, ws *winsvc.Service) error {
+					return runServer(ctx, cmd, v, ws)
+				})
+			}
+			return runServer(context.Background(), cmd, v, nil)
+		},
 	}
 
 	f := cmd.Flags()
 	f.String("addr", "0.0.0.0:8752", "TCP listen address (gRPC + HTTP/JSON, TLS)")
 	f.String("token", "", `shared secret — used for TLS key derivation and per-RPC auth.
 	If unset, defaults to "suffuse" for encryption (no per-RPC auth).`)
+	f.String("tls-cert", "", "PEM certificate chain served to clients that verify certificates (e.g. from Let's Encrypt); needs --tls-key")
+	f.String("tls-key", "", "PEM private key of --tls-cert")
+	f.StringSlice("acme-domain", nil, "public DNS names to obtain and renew a certificate for from Let's Encrypt (ACME)")
+	f.String("acme-email", "", "contact address registered with the ACME certificate authority")
+	f.String("acme-cache", tlsconf.DefaultACMEDir(), "directory the ACME account key and certificates are kept in")
+	f.String("acme-directory", "", "ACME directory URL (default: Let's Encrypt production)")
+	f.String("acme-http-addr", ":80", `plain-HTTP listen address answering ACME HTTP-01 challenges ("" disables)`)
+	f.String("tls-ca", "", `CA certificate file to verify upstream servers' certificates against, or "system" (default: verify the key derived from the token)`)
+	f.StringSlice("accept-tokens", nil, `additional tokens accepted during a rotation, as "secret" or "secret@expiry"`)
+	f.Bool("bind-sources", false, "allow per-peer tokens to present only their own name as source")
+	f.String("guest-token", "", "token giving anyone holding it read-only, rate-limited access to the guest clipboards")
+	f.StringSlice("guest-clipboards", []string{guest.DefaultClipboard}, "clipboard patterns guests may paste from and watch")
+	f.Int("guest-rate", guest.DefaultRequestsPerMinute, "calls each guest host may make per minute")
+	f.Int("guest-max-watchers", guest.DefaultMaxWatchers, "guest watch streams open at once")
 	f.Bool("no-local", false, "disable local clipboard integration (relay/hub-only mode)")
 	f.String("source", defaultSource(), "name for this host shown in peer lists")
-	f.String("upstream-host", "", "upstream suffuse server host (enables federation)")
-	f.Int("upstream-port", 8752, "upstream suffuse server port")
+	f.String("clipboard", hub.DefaultClipboard, "clipboard the system clipboard is synced with")
+	f.String("clipboard-backend", "auto", "local clipboard backend: auto|osc52 (terminal escape sequences, for SSH sessions)")
+	f.Bool("host-clipboards", false, `also keep each source's last copy in a private "host/<source>" clipboard`)
+	f.Bool("primary", false, "also sync the primary selection (middle-click paste; Linux only)")
+	f.String("primary-clipboard", "primary", "clipboard the primary selection is synced with")
+	f.Int("history", 0, "earlier contents kept per clipboard for \"suffuse history\" (0 disables)")
+	f.StringSlice("clipboard-ttl", nil, `clear clipboard content this long after it was copied, as "5m" or "clipboard=5m" (repeatable)`)
+	f.String("conflict", string(localpeer.ConflictRemoteWins), "what to do with an update arriving just after a local copy: remote-wins|local-wins|keep-both")
+	f.Duration("conflict-window", localpeer.DefaultConflictWindow, "how soon after a local copy an arriving update counts as a conflict")
+	f.StringSlice("prefer-types", nil, `rankings of the types written to the local clipboard, e.g. "text/plain>text/html" to write only the text of an update carrying both`)
+	f.Bool("hold", false, "hold updates from other hosts until \"suffuse accept\" applies them to the local clipboard")
+	f.Bool("hold-executables", false, "hold only updates that look like programs or scripts until \"suffuse accept\" applies them")
+	f.Bool("sync-sensitive", false, "also sync content a password manager marked sensitive")
+	f.String("universal-clipboard", string(localpeer.UniversalPublish), "what to do with content Apple's Universal Clipboard brings from another device: publish|ignore|isolate")
+	f.Bool("transfer-files", false, "send the content of copied files along with their references, and unpack files pasted from other hosts")
+	f.String("transfer-files-dir", files.DefaultDir(), "directory files pasted from other hosts are unpacked into")
+	f.Int64("transfer-files-max-bytes", files.DefaultMaxBytes, "largest total size of the files sent with one copy")
+	f.String("slow-consumer", string(hub.SlowConsumerDrop), "policy for peers that fall behind: drop-newest|drop-oldest|block|disconnect")
+	f.Duration("slow-consumer-timeout", hub.DefaultBlockTimeout, "how long --slow-consumer block waits for room in a peer's queue")
+	f.StringSlice("queue-size", nil, `events queued for each peer, as "256" or "watch=256" for one kind (repeatable)`)
+	f.Int("max-hops", hub.DefaultMaxHops, "number of federation links an event may cross before it is no longer relayed")
+	f.Duration("dedup-window", 0, "suppress publishes repeating a clipboard's content set less than this long ago (0 disables)")
+	f.StringSlice("allow-types", nil, `MIME type patterns relayed, e.g. "text/*" (default: all types)`)
+	f.StringSlice("deny-types", nil, `MIME type patterns never relayed, e.g. "application/x-ms-shortcut"`)
+	f.StringSlice("derive-types", []string{hub.TypeText}, "text types (text/plain, text/markdown, text/html) added to copies lacking them, converted from the text they carry")
+	f.Bool("no-transcode", false, "relay text that is not UTF-8 as it is instead of converting it to UTF-8")
+	f.Int("max-item-size", 0, "largest clipboard item in bytes accepted from any peer (0 disables)")
+	f.Int("max-payload-size", 0, "largest total size in bytes of the items of one copy (0 disables)")
+	f.Int("quota-copies", 0, "copies each source may make per hour (0 disables)")
+	f.Int64("quota-bytes", 0, "bytes each source may copy per day (0 disables)")
+	f.String("quota-action", string(quota.ActionReject), "what happens to a copy over quota: warn|throttle|reject")
+	f.Int("blob-threshold", blob.DefaultThreshold, "items larger than this many bytes are sent by reference to peers that fetch on demand (0 disables)")
+	f.Int("text-threshold", blob.DefaultTextThreshold, "text items larger than this many bytes are sent by reference, with a preview, to peers that fetch on demand (0: as --blob-threshold)")
+	f.Int("text-preview", blob.DefaultTextPreview, "bytes of a text item sent by reference kept inline as its preview (0 disables)")
+	f.Duration("blob-ttl", blob.DefaultTTL, "how long a blob no clipboard references is kept after its last use")
+	f.Bool("cache", false, "keep recent clipboard contents in an encrypted file and restore them on start")
+	f.String("cache-file", cache.DefaultPath(), "clipboard cache file")
+	f.Int("cache-max-bytes", cache.DefaultMaxBytes, "maximum size of cached clipboard content")
+	f.Bool("journal", false, "record publishes and their delivery to each peer, without content, for \"suffuse admin journal\"")
+	f.String("journal-file", journal.DefaultPath(), "event journal file")
+	f.Int64("journal-max-bytes", journal.DefaultMaxBytes, "disk space the event journal may use")
+	f.String("audit-log", "", `record every clipboard call, without content, to this file ("-" for stdout)`)
+	f.String("snapshot-dir", snapshot.DefaultDir(), "directory the snapshots kept by \"suffuse snapshot save\" are saved in")
+	f.StringToString("snippet", nil, `key=text snippets a copy of the key to the "snippet" clipboard is expanded to (repeatable)`)
+	f.String("pairing-file", pairing.DefaultPath(), "file tokens issued to devices by \"suffuse pair\" are kept in")
+	f.Bool("no-mdns", false, "do not advertise the server on the local network via mDNS")
+	f.Bool("no-reflection", false, "disable gRPC server reflection on the TCP listener")
+	f.Bool("no-public-status", false, "reject Status (peer list) on the TCP listener; IPC still serves it")
+	f.Bool("admin-ipc-only", false, "serve admin RPCs only on the local IPC socket")
+	f.Bool("access-log", true, "log each HTTP/JSON gateway request")
+	f.Bool("access-log-trust-proxy", false, "take client addresses from Forwarded/X-Forwarded-For headers")
+	f.StringSlice("access-log-sample", nil, `log one in N successful requests per path prefix, as "prefix=N"`)
+	f.String("remote-write-url", "", "Prometheus remote-write endpoint to push metrics to (user:pass@ for basic auth)")
+	f.Duration("remote-write-interval", remotewrite.DefaultInterval, "interval between metric pushes")
+	f.String("remote-write-token", "", "bearer token for the remote-write endpoint")
+	f.StringSlice("remote-write-labels", nil, `labels added to every pushed series, as "name=value"`)
+	f.String("otlp-endpoint", "", "OpenTelemetry collector to export traces to: host:port (OTLP/gRPC) or http(s) URL (OTLP/HTTP)")
+	f.Bool("otlp-insecure", false, "export OTLP over gRPC without TLS")
+	f.StringSlice("otlp-headers", nil, `headers sent with every trace export, as "name=value"`)
+	f.Float64("trace-sample-ratio", tracing.DefaultSampleRatio, "fraction of the traces started on this server that are exported")
+	f.StringSlice("upstream-host", nil, "upstream suffuse servers, host or host:port (enables federation)")
+	f.Int("upstream-port", 8752, "upstream suffuse server port for hosts given without one")
 	f.String("upstream-token", "", "shared secret for upstream server (defaults to --token)")
 	f.String("upstream-source", "", "source name sent to upstream (defaults to --source)")
+	f.Bool("ready-requires-upstream", false, "report /readyz unavailable while an upstream link is down")
+	f.StringSlice("upstream-publish", nil, "clipboard patterns forwarded upstream (default: all)")
+	f.StringSlice("upstream-pin", nil, "clipboards always subscribed from upstream, even without local watchers")
+	f.String("upstream-via-ssh", "", "reach upstream servers through this SSH jump host, [user@]host[:port]")
+	f.StringSlice("defer-hours", nil, "daily HH:MM-HH:MM windows during which non-text items are held back on the upstream link")
+	f.Bool("defer-metered", false, "hold back non-text items on the upstream link while the network is metered")
+	f.Duration("probe-interval", 0, "send an end-to-end probe through the upstream links this often and measure the answers (0 disables)")
+	addE2EFlag(cmd)
+	addSigningFlags(cmd)
+	addTrustFlag(cmd)
 	addLoggingFlags(cmd)
 	addConfigFlag(cmd)
 
+	// Fault injection for integration and soak tests; deliberately hidden.
+	f.Uint64("fault-seed", 0, "seed for injected faults (0 picks one and logs it)")
+	f.Duration("fault-latency", 0, "delay gRPC messages and calls by up to this long")
+	f.Float64("fault-drop", 0, "probability that a gRPC message or call is lost")
+	f.Float64("fault-disconnect", 0, "probability that a gRPC stream is cut before a message")
+	for _, name := range []string{"fault-seed", "fault-latency", "fault-drop", "fault-disconnect"} {
+		_ = f.MarkHidden(name)
+	}
+
 	return cmd
 }
 
-func runServer(v *viper.Viper) error {
-	setupLogging(v)
+// runServer runs the server until ctx is done. ws is the Windows service
+// the server runs as, or nil.
+func runServer(ctx context.Context, cmd *cobra.Command, v *viper.Viper, ws *winsvc.Service) error {
+	if ws != nil {
+		// The service control manager discards standard error.
+		logging.SetupHandler(logLevel(false, v.GetString("log-level")), ws.LogHandler)
+	} else {
+		setupLogging(v)
+	}
 
 	addr := v.GetString("addr")
 	token := v.GetString("token")
 	noLocal := v.GetBool("no-local")
 	source := v.GetString("source")
-	upstreamHost := v.GetString("upstream-host")
-	upstreamPort := v.GetInt("upstream-port")
-	upstreamToken := v.GetString("upstream-token")
-	upstreamSource := v.GetString("upstream-source")
+	hostClipboards := v.GetBool("host-clipboards")
+	slowConsumer, err := hub.ParseSlowConsumerPolicy(v.GetString("slow-consumer"))
+	if err != nil {
+		return err
+	}
+	var queueSizes []hub.QueueSize
+	for _, s := range getStringSlice(v, "queue-size") {
+		size, err := hub.ParseQueueSize(s)
+		if err != nil {
+			return err
+		}
+		queueSizes = append(queueSizes, size)
+	}
+	historySize := v.GetInt("history")
+	if historySize < 0 {
+		return errors.New("history must not be negative")
+	}
+	var clipboardTTLs []hub.ClipboardTTL
+	for _, s := range getStringSlice(v, "clipboard-ttl") {
+		ttl, err := hub.ParseClipboardTTL(s)
+		if err != nil {
+			return err
+		}
+		clipboardTTLs = append(clipboardTTLs, ttl)
+	}
+	conflict, err := localpeer.ParseConflictPolicy(v.GetString("conflict"))
+	if err != nil {
+		return err
+	}
+	if conflict == localpeer.ConflictKeepBoth && historySize == 0 {
+		return errors.New("conflict keep-both needs --history")
+	}
+	prefer, err := localpeer.ParseTypePreferences(getStringSlice(v, "prefer-types"))
+	if err != nil {
+		return err
+	}
+	universal, err := localpeer.ParseUniversalPolicy(v.GetString("universal-clipboard"))
+	if err != nil {
+		return err
+	}
+	maxHops := v.GetInt("max-hops")
+	if maxHops < 1 {
+		return errors.New("max-hops must be at least 1")
+	}
+	clipboardBackend := v.GetString("clipboard-backend")
+	if clipboardBackend != "auto" && clipboardBackend != "osc52" {
+		return errors.New(`clipboard-backend must be "auto" or "osc52"`)
+	}
+	blobs := blob.NewStore(blob.Config{
+		Threshold:     v.GetInt("blob-threshold"),
+		TextThreshold: v.GetInt("text-threshold"),
+		TextPreview:   v.GetInt("text-preview"),
+	})
+	noMDNS := v.GetBool("no-mdns")
+	noReflection := v.GetBool("no-reflection")
+	noPublicStatus := v.GetBool("no-public-status")
+	adminIPCOnly := v.GetBool("admin-ipc-only")
+	accessLog := v.GetBool("access-log")
+	accessLogTrustProxy := v.GetBool("access-log-trust-proxy")
+	accessLogSample, err := accesslog.ParseSample(getStringSlice(v, "access-log-sample"))
+	if err != nil {
+		return err
+	}
+	remoteWriteLabels, err := remotewrite.ParseLabels(getStringSlice(v, "remote-write-labels"))
+	if err != nil {
+		return err
+	}
+	otlpHeaders, err := tracing.ParseHeaders(getStringSlice(v, "otlp-headers"))
+	if err != nil {
+		return err
+	}
+	var webhookCfgs []webhook.Config
+	if err := v.UnmarshalKey("webhooks", &webhookCfgs); err != nil {
+		return fmt.Errorf("webhooks: %w", err)
+	}
+	var processorCfgs []enrich.Config
+	if err := v.UnmarshalKey("processors", &processorCfgs); err != nil {
+		return fmt.Errorf("processors: %w", err)
+	}
+	rules, err := loadRules(v)
+	if err != nil {
+		return err
+	}
+	var tunnel *sshtunnel.Tunnel
+	if via := v.GetString("upstream-via-ssh"); via != "" {
+		if tunnel, err = sshtunnel.New(via); err != nil {
+			return err
+		}
+	}
+	quotaAction, err := quota.ParseAction(v.GetString("quota-action"))
+	if err != nil {
+		return err
+	}
+	quotas, err := quota.New(quota.Config{
+		CopiesPerHour: v.GetInt("quota-copies"),
+		BytesPerDay:   v.GetInt64("quota-bytes"),
+		Action:        quotaAction,
+	})
+	if err != nil {
+		return err
+	}
+	faults, err := fault.New(fault.Config{
+		Seed:       v.GetUint64("fault-seed"),
+		Latency:    v.GetDuration("fault-latency"),
+		Drop:       v.GetFloat64("fault-drop"),
+		Disconnect: v.GetFloat64("fault-disconnect"),
+	})
+	if err != nil {
+		return err
+	}
+	shapingPolicy, err := shaping.New(shaping.Config{
+		Hours:   getStringSlice(v, "defer-hours"),
+		Metered: v.GetBool("defer-metered"),
+	})
+	if err != nil {
+		return err
+	}
 
-	var upstreamAddr string
-	if upstreamHost != "" {
-		upstreamAddr = fmt.Sprintf("%s:%d", upstreamHost, upstreamPort)
+	upstreamBase := federation.Config{
+		Token:   token,
+		Source:  source,
+		Tunnel:  tunnel,
+		Blobs:   blobs,
+		Shaping: shapingPolicy,
+	}
+	upstreamCfgs, err := upstreamConfigs(v, upstreamBase)
+	if err != nil {
+		return err
 	}
 
-	if upstreamToken == "" {
-		upstreamToken = token
+	var acceptTokens []tokens.Token
+	for _, s := range getStringSlice(v, "accept-tokens") {
+		t, err := tokens.Parse(s)
+		if err != nil {
+			return fmt.Errorf("accept-tokens: %w", err)
+		}
+		acceptTokens = append(acceptTokens, t)
+	}
+	var scopedTokens []tokens.Scoped
+	if err := v.UnmarshalKey("tokens", &scopedTokens); err != nil {
+		return fmt.Errorf("tokens: %w", err)
+	}
+	pairingFile := v.GetString("pairing-file")
+	paired, err := pairing.Load(pairingFile)
+	if err != nil {
+		return err
 	}
-	if upstreamSource == "" {
-		upstreamSource = source
+	if token != "" {
+		// Paired tokens are only enforced, and only issued, with a token.
+		scopedTokens = append(scopedTokens, paired...)
+	}
+	guestCfg := guest.Config{
+		Token:             v.GetString("guest-token"),
+		Clipboards:        getStringSlice(v, "guest-clipboards"),
+		RequestsPerMinute: v.GetInt("guest-rate"),
+		MaxWatchers:       v.GetInt("guest-max-watchers"),
+	}
+	guests, err := guest.New(guestCfg)
+	if err != nil {
+		return err
+	}
+	if guestCfg.Token != "" {
+		if token == "" {
+			return errors.New("--guest-token requires --token to be set")
+		}
+		// A shared token would grant guests full access.
+		isShared := func(t tokens.Token) bool { return t.Value == guestCfg.Token }
+		if guestCfg.Token == token || slices.ContainsFunc(acceptTokens, isShared) {
+			return errors.New("--guest-token must differ from --token and --accept-tokens")
+		}
+		scopedTokens = append(scopedTokens, guestCfg.Scoped())
+	}
+	if err := tokens.CheckScoped(scopedTokens); err != nil {
+		return err
+	}
+	if len(scopedTokens) > 0 && token == "" {
+		// The empty token disables auth, which would leave the roles
+		// unenforced.
+		return errors.New("[[tokens]] require --token to be set")
+	}
+	tokenSet := tokens.NewSet(token, acceptTokens, scopedTokens)
+	if v.GetBool("bind-sources") {
+		tokenSet.BindSources()
 	}
 
-	// Derive TLS config from the token (default passphrase when unset).
+	// Derive TLS keys from every accepted token (default passphrase when
+	// unset) and keep them in step with rotations and expiries.
 	// NextProtos ["h2", "http/1.1"] lets ALPN negotiate correctly for both
 	// gRPC (HTTP/2) and HTTP/JSON gateway (HTTP/1.1) clients on the same port.
-	tlsPassphrase := token
-	if tlsPassphrase == "" {
-		tlsPassphrase = tlsconf.DefaultPassphrase
-	}
-	serverTLSCfg, clientCreds, err := tlsconf.ServerConfig(tlsPassphrase)
+	keySet, err := tlsconf.NewKeySet(tlsPassphrases(tokenSet.Active())...)
 	if err != nil {
 		return fmt.Errorf("TLS setup: %w", err)
 	}
+	tokenSet.OnChange(func(active []string) {
+		if err := keySet.Set(tlsPassphrases(active)...); err != nil {
+			slog.Error("TLS key update failed", "err", err)
+			return
+		}
+		slog.Info("accepted tokens changed", "count", len(active))
+	})
+	if certPath, keyPath := v.GetString("tls-cert"), v.GetString("tls-key"); certPath != "" || keyPath != "" {
+		if certPath == "" || keyPath == "" {
+			return errors.New("--tls-cert and --tls-key must be given together")
+		}
+		certFile, err := tlsconf.LoadCertFile(certPath, keyPath)
+		if err != nil {
+			return fmt.Errorf("TLS setup: %w", err)
+		}
+		keySet.SetCertSource(certFile)
+		slog.Info("serving operator-provided TLS certificate", "cert", certPath)
+	}
+	var acmeMgr *autocert.Manager
+	if domains := getStringSlice(v, "acme-domain"); len(domains) > 0 {
+		if v.GetString("tls-cert") != "" {
+			return errors.New("--acme-domain and --tls-cert are mutually exclusive")
+		}
+		acmeMgr, err = tlsconf.NewACME(tlsconf.ACMEConfig{
+			Domains:      domains,
+			Email:        v.GetString("acme-email"),
+			CacheDir:     v.GetString("acme-cache"),
+			DirectoryURL: v.GetString("acme-directory"),
+		})
+		if err != nil {
+			return fmt.Errorf("TLS setup: %w", err)
+		}
+		keySet.SetCertSource(acmeMgr)
+		slog.Info("obtaining TLS certificates via ACME", "domains", domains, "cache", v.GetString("acme-cache"))
+	}
+	serverTLSCfg, clientCreds := keySet.ServerConfig(), keySet.ClientCredentials()
+	if acmeMgr != nil {
+		serverTLSCfg.NextProtos = append(serverTLSCfg.NextProtos, tlsconf.ACMEProto)
+	}
 
 	slog.Info("suffuse server starting",
 		"version", Version,
 		"addr", addr,
 		"local_clip", !noLocal,
-		"upstream", upstreamAddr,
+		"host_clipboards", hostClipboards,
+		"upstreams", len(upstreamCfgs),
 	)
 
-	h := hub.New()
+	// The journal and the cache write out what they still hold once
+	// persistCtx is cancelled. finish waits for that, and for buffered spans
+	// to be exported, before the server returns or execs itself afresh.
+	persistCtx, stopPersist := context.WithCancel(ctx)
+	var persisting sync.WaitGroup
+	var shutdownTracing func(context.Context) error
+	finish := sync.OnceFunc(func() {
+		stopPersist()
+		persisting.Wait()
+		if shutdownTracing != nil {
+			shutdownTracing(context.Background()) //nolint:errcheck
+		}
+	})
+	defer finish()
+
+	var eventJournal *journal.Journal
+	if v.GetBool("journal") {
+		eventJournal, err = journal.New(journal.Config{
+			Path:     v.GetString("journal-file"),
+			MaxBytes: v.GetInt64("journal-max-bytes"),
+		})
+		if err != nil {
+			return err
+		}
+		persisting.Go(func() { eventJournal.Run(persistCtx) })
+	}
+
+	var auditLog *audit.Log
+	if path := v.GetString("audit-log"); path != "" {
+		if auditLog, err = audit.New(path); err != nil {
+			return err
+		}
+	}
+
+	if endpoint := v.GetString("otlp-endpoint"); endpoint != "" {
+		shutdown, err := tracing.Setup(context.Background(), tracing.Config{
+			Endpoint:    endpoint,
+			Insecure:    v.GetBool("otlp-insecure"),
+			Headers:     otlpHeaders,
+			SampleRatio: v.GetFloat64("trace-sample-ratio"),
+			Instance:    source,
+			Version:     Version,
+		})
+		if err != nil {
+			return err
+		}
+		shutdownTracing = shutdown
+		slog.Info("exporting traces", "endpoint", endpoint, "sample_ratio", v.GetFloat64("trace-sample-ratio"))
+	}
+
+	h := hub.New(hub.Config{
+		HostClipboards: hostClipboards,
+		HistorySize:    historySize,
+		ClipboardTTLs:  clipboardTTLs,
+		SlowConsumer:   slowConsumer,
+		BlockTimeout:   v.GetDuration("slow-consumer-timeout"),
+		QueueSizes:     queueSizes,
+		DedupWindow:    v.GetDuration("dedup-window"),
+		MaxHops:        maxHops,
+		Name:           source,
+		Rules:          rules,
+		Quota:          quotas,
+		Journal:        eventJournal,
+		Blobs:          blobs,
+	})
+
+	go h.RunBlobGC(context.Background(), v.GetDuration("blob-ttl"))
+	go h.RunExpiry(context.Background())
+
+	// Restore cached contents before any peer registers, so the local
+	// clipboard and the first pastes see them.
+	if v.GetBool("cache") {
+		c, err := cache.New(cache.Config{
+			Path:       v.GetString("cache-file"),
+			Passphrase: tlsPassphrases([]string{token})[0],
+			MaxBytes:   v.GetInt("cache-max-bytes"),
+		}, h)
+		if err != nil {
+			return err
+		}
+		if n, err := c.Load(); err != nil {
+			slog.Warn("clipboard cache not restored", "err", err)
+		} else if n > 0 {
+			slog.Info("clipboard cache restored", "clipboards", n)
+		}
+		persisting.Go(func() { c.Run(persistCtx) })
+	}
+
+	upstreams := &upstreamSet{h: h}
+	rd := readiness{upstreams: upstreams, requireUpstream: v.GetBool("ready-requires-upstream")}
+	var local grpcservice.LocalClipboard
 
+	localClipboard := canonicalClipboard(v.GetString("clipboard"))
+	keyring, err := loadKeyring(v)
+	if err != nil {
+		return err
+	}
+	signer, err := loadSigningKey(v)
+	if err != nil {
+		return err
+	}
+	trust, err := loadTrust(v)
+	if err != nil {
+		return err
+	}
+	if v.GetBool("primary") && canonicalClipboard(v.GetString("primary-clipboard")) == localClipboard {
+		return errors.New("--primary-clipboard must differ from --clipboard")
+	}
+
+	// The IPC socket and the TCP port are taken before anything touches the
+	// clipboard, so a second server on this host stops here.
+	ipcLn, err := ipc.Listen()
+	if err != nil {
+		var running *ipc.RunningError
+		if errors.As(err, &running) {
+			return fmt.Errorf("%w; set SUFFUSE_SOCKET to run another", err)
+		}
+		slog.Warn("IPC socket unavailable", "err", err)
+	}
+	tcpLn, err := net.Listen("tcp", addr)
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return fmt.Errorf("listen %s: address in use, by another suffuse server or another program; choose another with --addr", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", addr, err)
+	}
 	if !noLocal {
-		backend := clip.New()
-		lp := localpeer.New(h, backend, source)
+		var backend clip.Backend
+		if clipboardBackend == "osc52" {
+			if backend, err = clip.NewOSC52(); err != nil {
+				return fmt.Errorf("clipboard backend osc52: %w", err)
+			}
+		} else {
+			backend = clip.New()
+		}
+		var ft *files.Transfer
+		if v.GetBool("transfer-files") {
+			if ft, err = files.New(files.Config{
+				Dir:      v.GetString("transfer-files-dir"),
+				MaxBytes: v.GetInt64("transfer-files-max-bytes"),
+			}); err != nil {
+				return err
+			}
+		}
+		lp := localpeer.New(h, backend, localpeer.Config{
+			Source:          source,
+			Clipboard:       localClipboard,
+			Files:           ft,
+			Conflict:        conflict,
+			ConflictWindow:  v.GetDuration("conflict-window"),
+			Hold:            v.GetBool("hold"),
+			HoldExecutables: v.GetBool("hold-executables"),
+			SyncSensitive:   v.GetBool("sync-sensitive"),
+			Keys:            keyring,
+			Signer:          signer,
+			Trust:           trust,
+			Prefer:          prefer,
+			Universal:       universal,
+		})
+		rd.local = lp
+		local = lp
 		go lp.Run()
+
+		if v.GetBool("primary") {
+			if primary, err := clip.NewPrimary(); err != nil {
+				slog.Warn("primary selection unavailable", "err", err)
+			} else {
+				go localpeer.New(h, primary, localpeer.Config{
+					Source:        source,
+					Clipboard:     v.GetString("primary-clipboard"),
+					SyncSensitive: v.GetBool("sync-sensitive"),
+					Keys:          keyring,
+					Signer:        signer,
+					Trust:         trust,
+					Prefer:        prefer,
+				}).Run()
+			}
+		}
 	}
 
-	// Federation
-	var upstreamProvider grpcservice.UpstreamInfoProvider
-	if upstreamAddr != "" {
-		up, err := federation.New(federation.Config{
-			Addr:   upstreamAddr,
-			Token:  upstreamToken,
-			Source: upstreamSource,
-		}, h)
+	for _, wc := range webhookCfgs {
+		hook, err := webhook.New(wc, h)
+		if err != nil {
+			return err
+		}
+		go hook.Run(context.Background())
+	}
+	for _, pc := range processorCfgs {
+		proc, err := enrich.New(pc, h)
 		if err != nil {
-			return fmt.Errorf("federation: %w", err)
+			return err
 		}
-		upstreamProvider = up
-		ctx, cancel := context.WithCancel(context.Background())
-		_ = cancel
-		go up.Run(ctx)
+		go proc.Run(context.Background())
 	}
 
-	svc := grpcservice.New(h, token, upstreamProvider)
+	if u := v.GetString("remote-write-url"); u != "" {
+		if _, ok := remoteWriteLabels["job"]; !ok {
+			remoteWriteLabels["job"] = "suffuse"
+		}
+		if _, ok := remoteWriteLabels["instance"]; !ok {
+			remoteWriteLabels["instance"] = source
+		}
+		rw, err := remotewrite.New(remotewrite.Config{
+			URL:         u,
+			Interval:    v.GetDuration("remote-write-interval"),
+			BearerToken: v.GetString("remote-write-token"),
+			Labels:      remoteWriteLabels,
+		}, remotewrite.HubCollector(h))
+		if err != nil {
+			return err
+		}
+		go rw.Run(context.Background())
+	}
+
+	// Federation
+	// Each upstream gets its own stream, outbox and subscription; events
+	// reaching this server over two of them are suppressed by event ID.
+	if err := upstreams.set(upstreamCfgs); err != nil {
+		return err
+	}
+	if len(upstreamCfgs) == 0 && shapingPolicy != nil {
+		slog.Warn("--defer-hours and --defer-metered have no effect without --upstream-host")
+	}
+	if interval := v.GetDuration("probe-interval"); interval > 0 {
+		if len(upstreamCfgs) == 0 {
+			slog.Warn("--probe-interval has no effect without --upstream-host")
+		} else {
+			prober, err := probe.New(probe.Config{Interval: interval, Source: source}, h)
+			if err != nil {
+				return err
+			}
+			go prober.Run(context.Background())
+		}
+	}
+
+	var pairer *pairing.Pairer
+	if token != "" {
+		pairer = pairing.New(pairingFile, tokenSet)
+	}
+	svc := grpcservice.New(h, tokenSet, upstreams.providers(), local, pairer, guests, snapshot.New(v.GetString("snapshot-dir")), source, Version)
+	rl := &reloader{cmd: cmd, h: h, svc: svc, upstreams: upstreams, base: upstreamBase, v: v}
+	svc.SetReload(rl.reload)
+
+	// "suffuse stop" and "suffuse restart" end serving as cancelling ctx
+	// does; a restart then execs the server afresh.
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	var restart atomic.Bool
+	svc.SetShutdown(func(r bool) {
+		restart.Store(r)
+		stop()
+	})
+	go reloadOnSignal(rl)
+	ws.OnReload(func() {
+		if _, err := rl.reload(); err != nil {
+			slog.Error("configuration not reloaded", "err", err)
+		}
+	})
 
 	// gRPC server — no grpc.Creds here; TLS is handled at the listener level.
 	// grpcSrv.ServeHTTP implements http.Handler so it plugs into the shared
 	// http.Server below.
-	grpcSrv := grpc.NewServer(
+	// The audit interceptors go first, so calls refused by the others are
+	// recorded too.
+	ipcOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(svc.AuditUnary(auditLog)),
+		grpc.ChainStreamInterceptor(svc.AuditStream(auditLog)),
+		tracing.ServerOption(),
+	}
+	grpcOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(svc.AuditUnary(auditLog), svc.GuestUnary()),
+		grpc.ChainStreamInterceptor(svc.AuditStream(auditLog), svc.GuestStream()),
+		tracing.ServerOption(),
 		grpc.KeepaliveParams(keepalive.ServerParameters{
 			Time:    kaTime,
 			Timeout: kaTimeout,
@@ -182,18 +1384,46 @@ func runServer(v *viper.Viper) error {
 			MinTime:             kaMinTime,
 			PermitWithoutStream: true,
 		}),
-	)
+	}
+	recvLimit := defaultMaxRecvMsgSize
+	if rules.MaxPayloadSize > defaultMaxRecvMsgSize {
+		// Leave room for the rest of the message, so copies up to the
+		// limit reach Copy and get its error rather than gRPC's.
+		recvLimit = rules.MaxPayloadSize + recvMsgOverhead
+		recvOpt := grpc.MaxRecvMsgSize(recvLimit)
+		grpcOpts = append(grpcOpts, recvOpt)
+		ipcOpts = append(ipcOpts, recvOpt)
+	}
+	grpcOpts = append(grpcOpts, faults.ServerOptions()...)
+	ipcOpts = append(ipcOpts, faults.ServerOptions()...)
+	if noPublicStatus {
+		grpcOpts = append(grpcOpts, grpc.ChainUnaryInterceptor(
+			grpcservice.DenyMethods(pb.ClipboardService_Status_FullMethodName),
+		))
+	}
+	grpcSrv := grpc.NewServer(grpcOpts...)
 	pb.RegisterClipboardServiceServer(grpcSrv, svc)
-	reflection.Register(grpcSrv)
+	if !adminIPCOnly {
+		pb.RegisterAdminServiceServer(grpcSrv, svc.Admin())
+	}
+	if !noReflection {
+		reflection.Register(grpcSrv)
+	}
+	healthSrv := health.NewServer()
+	healthpb.RegisterHealthServer(grpcSrv, healthSrv)
+	go serveHealth(context.Background(), healthSrv, rd)
 
 	// IPC socket — Unix domain socket, no TLS needed.
-	if ln, err := ipc.Listen(); err != nil {
-		slog.Warn("IPC socket unavailable", "err", err)
-	} else {
+	var ipcSrv *grpc.Server
+	if ipcLn != nil {
 		slog.Info("IPC socket listening", "path", ipc.SocketPath())
-		ipcSrv := grpc.NewServer()
+		ipcSrv = grpc.NewServer(ipcOpts...)
 		pb.RegisterClipboardServiceServer(ipcSrv, svc)
-		go ipcSrv.Serve(ln) //nolint:errcheck
+		pb.RegisterAdminServiceServer(ipcSrv, svc.Admin())
+		healthpb.RegisterHealthServer(ipcSrv, healthSrv)
+		// Pipelined requests pass the interceptors ipcOpts installs too;
+		// ipc.Split traces them itself.
+		go ipcSrv.Serve(ipc.Split(ipcLn, svc, svc.AuditUnary(auditLog), faults.Unary())) //nolint:errcheck
 	}
 
 	// HTTP/JSON gateway — dials back to the local gRPC port using the derived
@@ -203,30 +1433,148 @@ func runServer(v *viper.Viper) error {
 	defer gwCancel()
 	if err := pb.RegisterClipboardServiceHandlerFromEndpoint(
 		gwCtx, gwMux, addr,
-		[]grpc.DialOption{grpc.WithTransportCredentials(clientCreds)},
+		[]grpc.DialOption{grpc.WithTransportCredentials(clientCreds), tracing.DialOption()},
 	); err != nil {
 		return fmt.Errorf("gateway registration: %w", err)
 	}
+	if !adminIPCOnly {
+		if err := pb.RegisterAdminServiceHandlerFromEndpoint(
+			gwCtx, gwMux, addr,
+			[]grpc.DialOption{grpc.WithTransportCredentials(clientCreds)},
+		); err != nil {
+			return fmt.Errorf("admin gateway registration: %w", err)
+		}
+	}
+
+	gwHandler := compress.Handler(gatewayBodyFactor*int64(recvLimit), gwMux)
+	if accessLog {
+		gwHandler = accesslog.Handler(accesslog.Config{
+			TrustForwarded: accessLogTrustProxy,
+			Sample:         accessLogSample,
+		}, gwHandler)
+	}
+
+	gwHandler = healthHandler(rd, gwHandler)
 
 	// Single TLS listener for both gRPC and HTTP/JSON.
 	// The handler routes by Content-Type: gRPC requests have
 	// "application/grpc" and arrive over HTTP/2; everything else goes to the
 	// gateway mux.
-	tcpLn, err := net.Listen("tcp", addr)
-	if err != nil {
-		return fmt.Errorf("listen %s: %w", addr, err)
-	}
 	tlsLn := tls.NewListener(tcpLn, serverTLSCfg)
 	slog.Info("listening", "addr", tcpLn.Addr())
 
+	if httpAddr := v.GetString("acme-http-addr"); acmeMgr != nil && httpAddr != "" {
+		_, port, _ := net.SplitHostPort(tcpLn.Addr().String())
+		acmeSrv := &http.Server{Addr: httpAddr, Handler: acmeHTTPHandler(acmeMgr, rd, port), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			slog.Info("answering ACME HTTP-01 challenges", "addr", httpAddr)
+			if err := acmeSrv.ListenAndServe(); err != nil {
+				slog.Warn("ACME HTTP-01 listener failed, only TLS-ALPN-01 challenges on port 443 can be answered", "addr", httpAddr, "err", err)
+			}
+		}()
+	}
+
+	// Advertise on the LAN so clients without --host can find the server.
+	if ta := tcpLn.Addr().(*net.TCPAddr); !noMDNS && !ta.IP.IsLoopback() {
+		r, err := mdns.NewResponder(mdns.Config{
+			Instance: source,
+			Port:     ta.Port,
+			TXT:      []string{"source=" + source, "version=" + Version},
+		})
+		if err != nil {
+			slog.Warn("mDNS advertisement unavailable", "err", err)
+		} else {
+			slog.Info("advertising via mDNS", "service", mdns.ServiceType, "instance", source, "port", ta.Port)
+			go r.Run(context.Background())
+		}
+	}
+
+	// Under systemd (Type=notify), report readiness now that the listener is
+	// up, and keep the watchdog fed while the hub answers: Peers takes the
+	// hub's lock, so a wedged hub stops the pings.
+	if ok, err := sdnotify.Notify("READY=1"); err != nil {
+		slog.Warn("systemd notification failed", "err", err)
+	} else if ok {
+		go sdnotify.RunWatchdog(context.Background(), func() bool { h.Peers(); return true })
+	}
+	ws.Ready()
+
 	httpSrv := &http.Server{
 		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
 				grpcSrv.ServeHTTP(w, r)
 			} else {
-				gwMux.ServeHTTP(w, r)
+				gwHandler.ServeHTTP(w, r)
 			}
 		}),
 	}
-	return httpSrv.Serve(tlsLn)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		<-ctx.Done()
+		stopHTTP(httpSrv, time.Second)
+	}()
+	if err := httpSrv.Serve(tlsLn); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	if ipcSrv != nil {
+		stopGRPC(ipcSrv, time.Second)
+	}
+	<-closed
+	finish()
+	if restart.Load() {
+		slog.Info("restarting", "pid", os.Getpid())
+		return reexec.Exec()
+	}
+	slog.Info("server stopped")
+	return nil
+}
+
+// stopGRPC stops srv gracefully, so calls in flight, such as the Shutdown
+// call that stopped the server, are answered, and ends the streams still
+// open after grace.
+func stopGRPC(srv *grpc.Server, grace time.Duration) {
+	t := time.AfterFunc(grace, srv.Stop)
+	defer t.Stop()
+	srv.GracefulStop()
+}
+
+// stopHTTP shuts srv down like stopGRPC: calls in flight get grace to
+// finish before the connections still open are closed.
+func stopHTTP(srv *http.Server, grace time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		srv.Close() //nolint:errcheck
+	}
+}
+
+// tlsPassphrases maps accepted tokens to TLS passphrases, substituting the
+// default passphrase for the empty token.
+func tlsPassphrases(active []string) []string {
+	out := make([]string, len(active))
+	for i, t := range active {
+		if t == "" {
+			t = tlsconf.DefaultPassphrase
+		}
+		out[i] = t
+	}
+	return out
+}
+
+// joinUpstreams turns --upstream-host entries into addresses: an entry
+// without a port gets port. The same address may not be given twice.
+func joinUpstreams(hosts []string, port int) ([]string, error) {
+	var addrs []string
+	for _, h := range hosts {
+		addr := h
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			addr = net.JoinHostPort(strings.Trim(h, "[]"), strconv.Itoa(port))
+		}
+		if slices.Contains(addrs, addr) {
+			return nil, fmt.Errorf("upstream-host: %s given twice", addr)
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
 }
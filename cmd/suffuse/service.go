@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"go.klb.dev/suffuse/internal/launchd"
+	"go.klb.dev/suffuse/internal/winsvc"
+)
+
+// serviceManager is the platform's service manager as "suffuse service"
+// drives it: the Windows service control manager or launchd.
+type serviceManager struct {
+	kind        string // what the manager runs, "service" or "agent"
+	defaultName string
+	long        string
+	// install registers "suffuse server" with args and returns what to
+	// tell the user.
+	install                func(name string, manual bool, args []string) (string, error)
+	uninstall, start, stop func(name string) error
+}
+
+// platformServiceManager returns the service manager of this platform, or
+// nil when suffuse does not install itself with it.
+func platformServiceManager() *serviceManager {
+	switch {
+	case winsvc.Supported:
+		return &serviceManager{
+			kind:        "service",
+			defaultName: winsvc.DefaultName,
+			long: `Registers "suffuse server" as a Windows service, and starts, stops and
+removes it. Run these from an Administrator prompt:
+
+  suffuse service install -- --token mysecret
+  suffuse service start
+  suffuse service stop
+  suffuse service uninstall
+
+Arguments after "--" are passed to "suffuse server" each time the service
+starts; settings can also go in C:\ProgramData\suffuse\suffuse.toml, which
+the service reads. The service starts at boot unless installed with
+--manual, and is restarted when it fails.
+
+The service reports itself running once it listens, logs to the Windows
+Event Log (Windows Logs > Application) under its name, and reloads its
+configuration on "sc.exe control SuffuseServer paramchange" as it does on
+"suffuse admin reload".`,
+			install: func(name string, manual bool, args []string) (string, error) {
+				err := winsvc.Install(winsvc.Config{
+					Name:        name,
+					DisplayName: "Suffuse Clipboard Hub",
+					Description: "Suffuse shared-clipboard hub. Distributes clipboard events to all connected peers.",
+					Args:        args,
+					Manual:      manual,
+				})
+				return fmt.Sprintf("service %s installed; start it with \"suffuse service start\"", name), err
+			},
+			uninstall: winsvc.Uninstall,
+			start:     winsvc.Start,
+			stop:      winsvc.Stop,
+		}
+	case launchd.Supported:
+		return &serviceManager{
+			kind:        "agent",
+			defaultName: launchd.DefaultLabel,
+			long: `Installs "suffuse server" as a launchd agent of the current user, which
+starts it at login and restarts it when it exits, and starts, stops and
+removes it:
+
+  suffuse service install -- --token mysecret
+  suffuse service stop
+  suffuse service start
+  suffuse service uninstall
+
+Arguments after "--" are passed to "suffuse server" each time the agent
+starts; settings can also go in ~/.config/suffuse/suffuse.toml. The
+agent's property list is written to ~/Library/LaunchAgents/<name>.plist,
+replacing one install.sh installed, and the agent logs to
+~/Library/Logs/suffuse.log.
+
+With --manual the agent is neither started at login nor restarted; "suffuse
+service start" runs it. "suffuse service stop" unloads the agent until the
+next start or login.`,
+			install: func(name string, manual bool, args []string) (string, error) {
+				err := launchd.Install(launchd.Config{Label: name, Args: args, Manual: manual})
+				if manual {
+					return fmt.Sprintf("agent %s installed; start it with \"suffuse service start\"", name), err
+				}
+				logPath, _ := launchd.DefaultLogPath()
+				return fmt.Sprintf("agent %s installed and started; it logs to %s", name, logPath), err
+			},
+			uninstall: launchd.Uninstall,
+			start:     launchd.Start,
+			stop:      launchd.Stop,
+		}
+	}
+	return nil
+}
+
+func newServiceCmd(m *serviceManager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "service",
+		Short: "Install and control suffuse server as a " + m.kind,
+		Long:  m.long,
+	}
+	cmd.PersistentFlags().String("name", m.defaultName, "name of the "+m.kind)
+	cmd.AddCommand(newServiceInstallCmd(m))
+	for _, c := range []struct {
+		use, short string
+		run        func(name string) error
+		done       string
+	}{
+		{"uninstall", "Stop and remove the " + m.kind, m.uninstall, "removed"},
+		{"start", "Start the " + m.kind, m.start, "started"},
+		{"stop", "Stop the " + m.kind, m.stop, "stopped"},
+	} {
+		cmd.AddCommand(&cobra.Command{
+			Use:   c.use,
+			Short: c.short,
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				name, _ := cmd.Flags().GetString("name")
+				if err := c.run(name); err != nil {
+					return err
+				}
+				fmt.Printf("%s %s %s\n", m.kind, name, c.done)
+				return nil
+			},
+		})
+	}
+	return cmd
+}
+
+func newServiceInstallCmd(m *serviceManager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install [-- server flags...]",
+		Short: "Install suffuse server as a " + m.kind,
+		Long: `Installs this executable as a ` + m.kind + ` running "suffuse server" with the
+given flags. Install the executable where it is to stay first, as the
+` + m.kind + ` runs it from its current path.
+
+  suffuse service install -- --token mysecret --upstream-host hub.example.com`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, _ := cmd.Flags().GetString("name")
+			manual, _ := cmd.Flags().GetBool("manual")
+			msg, err := m.install(name, manual, append([]string{"server"}, args...))
+			if err != nil {
+				return err
+			}
+			fmt.Println(msg)
+			return nil
+		},
+	}
+	cmd.Flags().Bool("manual", false, "do not start the "+m.kind+" at boot or login")
+	return cmd
+}
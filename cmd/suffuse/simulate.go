@@ -0,0 +1,469 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+func newSimulateCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Soak-test a server with simulated peers",
+		Long: `Connects --peers simulated peers to a running server, each with a
+connection and a Watch stream of its own, and has randomly chosen peers copy
+to --clipboard at --rate, e.g. "10/s" or "300/m". Every copy carries its
+send time, so each delivery measures the fanout latency from copy to watcher.
+
+Every --report-every a line shows the copies made, deliveries received and
+missed (not received within 10s), latency percentiles, events the server
+dropped, and the server's heap and goroutines. A summary follows when
+--duration ends or on Ctrl-C. Use a clipboard nobody else watches; point it
+at a test server before releases, not at a production hub.
+
+  suffuse simulate --peers 500 --rate 10/s --duration 10m`,
+		Hidden:  true,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, _ []string) error { return runSimulate(cmd.Context(), v) },
+	}
+
+	f := cmd.Flags()
+	f.String("host", "", "suffuse server host (uses the IPC socket if unset and available)")
+	f.Int("port", 8752, "suffuse server port")
+	f.String("token", "", "shared secret")
+	f.String("tls-ca", "", `CA certificate file to verify the server's certificate against, or "system" (default: verify the key derived from the token)`)
+	f.String("via-ssh", "", "reach the server through this SSH jump host, [user@]host[:port] (only port 22 needs to be open)")
+	f.String("source", "simulate", "source name prefix of the simulated peers")
+	f.String("clipboard", "simulate", "clipboard the simulated peers copy to and watch")
+	f.Int("peers", 100, "number of simulated peers")
+	f.String("rate", "10/s", `copies per second ("N/s") or minute ("N/m") across all peers`)
+	f.Int("size", 256, "size of each copy in bytes")
+	f.Duration("duration", 0, "how long to run (0: until interrupted)")
+	f.Duration("report-every", 10*time.Second, "interval between report lines")
+	f.String("log-level", "info", "log level: debug|info|warn|error")
+	addConfigFlag(cmd)
+
+	return cmd
+}
+
+// simulatePrefix starts the text of every simulated copy, followed by its
+// sequence number and send time.
+const simulatePrefix = "suffuse-simulate"
+
+// deliveryDeadline is how long a copy may take to reach every peer before
+// the peers that have not seen it count as missed.
+const deliveryDeadline = 10 * time.Second
+
+// simulation is the shared state of a run.
+type simulation struct {
+	clipboard string
+	size      int
+	peers     []*simPeer
+
+	copyErrs   atomic.Uint64
+	reconnects atomic.Uint64
+
+	mu        sync.Mutex
+	seq       uint64
+	pending   map[uint64]*simCopy
+	copies    uint64
+	delivered uint64
+	missed    uint64
+	interval  latencies // since the last report
+	total     latencies
+}
+
+type simPeer struct {
+	index  int
+	conn   *grpc.ClientConn
+	client pb.ClipboardServiceClient
+}
+
+// simCopy tracks the deliveries of one copy.
+type simCopy struct {
+	sent    time.Time
+	seen    []bool // by peer index
+	warmup  bool
+	waiting int
+}
+
+func runSimulate(ctx context.Context, v *viper.Viper) error {
+	resolveLogging(true, "auto", v.GetString("log-level"))
+	perCopy, err := parseRate(v.GetString("rate"))
+	if err != nil {
+		return err
+	}
+	n := v.GetInt("peers")
+	if n < 1 {
+		return errors.New("--peers must be at least 1")
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := &simulation{
+		clipboard: canonicalClipboard(v.GetString("clipboard")),
+		size:      v.GetInt("size"),
+		pending:   make(map[uint64]*simCopy),
+	}
+	defer func() {
+		for _, p := range s.peers {
+			p.conn.Close()
+		}
+	}()
+	fmt.Printf("Connecting %d peers...\n", n)
+	for i := range n {
+		source := fmt.Sprintf("%s-%03d", v.GetString("source"), i+1)
+		conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), v.GetString("token"), source, v.GetString("tls-ca"), v.GetString("via-ssh"))
+		if err != nil {
+			return fmt.Errorf("dial peer %d: %w", i+1, err)
+		}
+		s.peers = append(s.peers, &simPeer{index: i, conn: conn, client: pb.NewClipboardServiceClient(conn)})
+	}
+
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	var watchers sync.WaitGroup
+	for _, p := range s.peers {
+		watchers.Go(func() { s.watch(watchCtx, p) })
+	}
+	defer func() {
+		stopWatching()
+		watchers.Wait()
+	}()
+
+	if !s.warmUp(ctx) {
+		return ctx.Err()
+	}
+	statusClient := s.peers[0].client
+	before := simStatus(statusClient)
+	if d := v.GetDuration("duration"); d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	fmt.Printf("%-8s %8s %10s %8s %9s %9s %9s %8s %10s %10s\n",
+		"ELAPSED", "COPIES", "DELIVERED", "MISSED", "P50", "P99", "MAX", "DROPPED", "HEAP", "GOROUTINES")
+	start := time.Now()
+	copyTick := time.NewTicker(perCopy)
+	defer copyTick.Stop()
+	report := time.NewTicker(v.GetDuration("report-every"))
+	defer report.Stop()
+	var inflight sync.WaitGroup
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-copyTick.C:
+			p := s.peers[rand.IntN(len(s.peers))]
+			inflight.Go(func() { s.copy(ctx, p, false) })
+		case <-report.C:
+			s.expire(time.Now().Add(-deliveryDeadline))
+			s.report(time.Since(start), before, simStatus(statusClient))
+		}
+	}
+	inflight.Wait()
+	elapsed := time.Since(start)
+	fmt.Printf("Waiting %s for the last deliveries...\n", deliveryDeadline)
+	time.Sleep(deliveryDeadline)
+	s.expire(time.Now())
+	after := simStatus(statusClient)
+	s.report(elapsed, before, after)
+	s.summary(elapsed, before, after)
+	return nil
+}
+
+// parseRate converts "N/s", "N/m" or a bare N (per second) to the interval
+// between copies.
+func parseRate(s string) (time.Duration, error) {
+	num, unit, _ := strings.Cut(s, "/")
+	per := time.Second
+	switch unit {
+	case "", "s":
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return 0, fmt.Errorf("--rate %q: unit must be s, m or h", s)
+	}
+	rate, err := strconv.ParseFloat(num, 64)
+	if err != nil || rate <= 0 || math.IsInf(rate, 0) {
+		return 0, fmt.Errorf("--rate %q: not a positive number of copies", s)
+	}
+	interval := time.Duration(float64(per) / rate)
+	if interval <= 0 {
+		return 0, fmt.Errorf("--rate %q: too fast", s)
+	}
+	return interval, nil
+}
+
+// warmUp copies until every peer has received one, so the peers' Watch
+// streams are known to be registered before measuring starts.
+func (s *simulation) warmUp(ctx context.Context) bool {
+	fmt.Println("Waiting for every peer to watch...")
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		seq, ok := s.copy(ctx, s.peers[0], true)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-t.C:
+		}
+		if !ok {
+			continue
+		}
+		// Copies are forgotten once every peer has received them.
+		s.mu.Lock()
+		c := s.pending[seq]
+		s.mu.Unlock()
+		if c == nil {
+			return true
+		}
+		slog.Debug("peers not yet watching", "waiting", c.waiting)
+	}
+}
+
+// copy publishes one simulated copy from p and returns its sequence number
+// and whether the server accepted it.
+func (s *simulation) copy(ctx context.Context, p *simPeer, warmup bool) (uint64, bool) {
+	s.mu.Lock()
+	s.seq++
+	seq := s.seq
+	now := time.Now()
+	// Recorded before the copy, as deliveries can arrive before it returns.
+	s.pending[seq] = &simCopy{sent: now, seen: make([]bool, len(s.peers)), warmup: warmup, waiting: len(s.peers)}
+	s.mu.Unlock()
+
+	text := fmt.Sprintf("%s %d %d ", simulatePrefix, seq, now.UnixNano())
+	if pad := s.size - len(text); pad > 0 {
+		text += strings.Repeat("x", pad)
+	}
+	_, err := p.client.Copy(ctx, &pb.CopyRequest{
+		Clipboard: s.clipboard,
+		Items:     []*pb.ClipboardItem{{Mime: "text/plain", Data: []byte(text)}},
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		delete(s.pending, seq)
+		if ctx.Err() == nil {
+			slog.Warn("simulated copy failed", "peer", p.index+1, "err", err)
+			s.copyErrs.Add(1)
+		}
+		return seq, false
+	}
+	if !warmup {
+		s.copies++
+	}
+	return seq, true
+}
+
+// watch receives the clipboard on behalf of p until ctx is done,
+// reconnecting when the stream ends.
+func (s *simulation) watch(ctx context.Context, p *simPeer) {
+	for {
+		stream, err := p.client.Watch(ctx, &pb.WatchRequest{Clipboard: s.clipboard, Accepts: []string{"text/plain"}})
+		for err == nil {
+			var resp *pb.WatchResponse
+			if resp, err = stream.Recv(); err == nil {
+				s.receive(p, resp)
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Debug("simulated watch ended, reconnecting", "peer", p.index+1, "err", err)
+		s.reconnects.Add(1)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (s *simulation) receive(p *simPeer, resp *pb.WatchResponse) {
+	now := time.Now()
+	for _, it := range resp.Items {
+		fields := strings.Fields(string(it.Data))
+		if it.Mime != "text/plain" || len(fields) < 3 || fields[0] != simulatePrefix {
+			continue
+		}
+		seq, err1 := strconv.ParseUint(fields[1], 10, 64)
+		sent, err2 := strconv.ParseInt(fields[2], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		s.mu.Lock()
+		// Unknown copies are from earlier runs or already expired; repeats
+		// come from reconnecting watches.
+		if c := s.pending[seq]; c != nil && !c.seen[p.index] {
+			c.seen[p.index] = true
+			c.waiting--
+			if !c.warmup {
+				d := now.Sub(time.Unix(0, sent))
+				s.delivered++
+				s.interval.add(d)
+				s.total.add(d)
+			}
+			if c.waiting == 0 {
+				delete(s.pending, seq)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// expire counts the peers that have not received copies sent before
+// deadline as missed.
+func (s *simulation) expire(deadline time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for seq, c := range s.pending {
+		if c.sent.After(deadline) {
+			continue
+		}
+		if !c.warmup {
+			s.missed += uint64(c.waiting)
+		}
+		delete(s.pending, seq)
+	}
+}
+
+// simStatus returns the server's status, or nil if it is not available.
+func simStatus(client pb.ClipboardServiceClient) *pb.StatusResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := client.Status(ctx, &pb.StatusRequest{})
+	if err != nil {
+		slog.Debug("server status unavailable", "err", err)
+		return nil
+	}
+	return resp
+}
+
+// droppedSince returns the events the server dropped between two statuses.
+func droppedSince(before, after *pb.StatusResponse) string {
+	if before == nil || after == nil {
+		return "-"
+	}
+	var n uint64
+	for name, count := range after.Dropped {
+		n += count - before.Dropped[name]
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+func (s *simulation) report(elapsed time.Duration, before, now *pb.StatusResponse) {
+	s.mu.Lock()
+	l := s.interval
+	s.interval = latencies{}
+	copies, delivered, missed := s.copies, s.delivered, s.missed
+	s.mu.Unlock()
+	heap, goroutines := "-", "-"
+	if now != nil && now.Stats != nil {
+		heap = fmtBytes(now.Stats.HeapBytes)
+		goroutines = strconv.FormatUint(uint64(now.Stats.Goroutines), 10)
+	}
+	fmt.Printf("%-8s %8d %10d %8d %9s %9s %9s %8s %10s %10s\n",
+		elapsed.Round(time.Second), copies, delivered, missed,
+		fmtLatency(l.quantile(0.5)), fmtLatency(l.quantile(0.99)), fmtLatency(l.max),
+		droppedSince(before, now), heap, goroutines)
+}
+
+func (s *simulation) summary(elapsed time.Duration, before, after *pb.StatusResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expected := s.delivered + s.missed
+	fmt.Println()
+	fmt.Printf("Peers:       %d, %d watch reconnects\n", len(s.peers), s.reconnects.Load())
+	fmt.Printf("Copies:      %d in %s (%.1f/s), %d failed\n", s.copies, elapsed.Round(time.Second),
+		float64(s.copies)/elapsed.Seconds(), s.copyErrs.Load())
+	if expected > 0 {
+		fmt.Printf("Deliveries:  %d of %d (%.2f%% missed)\n", s.delivered, expected, 100*float64(s.missed)/float64(expected))
+	}
+	fmt.Printf("Latency:     p50 %s, p90 %s, p99 %s, max %s\n", fmtLatency(s.total.quantile(0.5)),
+		fmtLatency(s.total.quantile(0.9)), fmtLatency(s.total.quantile(0.99)), fmtLatency(s.total.max))
+	fmt.Printf("Dropped:     %s events on the server\n", droppedSince(before, after))
+	if before != nil && after != nil && before.Stats != nil && after.Stats != nil {
+		fmt.Printf("Server heap: %s → %s, goroutines %d → %d\n", fmtBytes(before.Stats.HeapBytes),
+			fmtBytes(after.Stats.HeapBytes), before.Stats.Goroutines, after.Stats.Goroutines)
+	}
+}
+
+func fmtLatency(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "-"
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	case d < time.Second:
+		return d.Round(100 * time.Microsecond).String()
+	default:
+		return d.Round(time.Millisecond).String()
+	}
+}
+
+// latencies is a histogram of delivery latencies in buckets latencyGrowth
+// apart, so percentiles of long runs need no sample list.
+type latencies struct {
+	counts []uint64
+	n      uint64
+	max    time.Duration
+}
+
+const (
+	latencyBase   = 10 * time.Microsecond
+	latencyGrowth = 1.05
+)
+
+func (l *latencies) add(d time.Duration) {
+	i := 0
+	if d > latencyBase {
+		i = int(math.Ceil(math.Log(float64(d)/float64(latencyBase)) / math.Log(latencyGrowth)))
+	}
+	if i >= len(l.counts) {
+		l.counts = append(l.counts, make([]uint64, i+1-len(l.counts))...)
+	}
+	l.counts[i]++
+	l.n++
+	l.max = max(l.max, d)
+}
+
+// quantile returns the upper bound of the bucket holding the q quantile,
+// at most the largest latency seen.
+func (l *latencies) quantile(q float64) time.Duration {
+	if l.n == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(l.n)))
+	var seen uint64
+	for i, c := range l.counts {
+		if seen += c; seen >= rank {
+			return min(time.Duration(float64(latencyBase)*math.Pow(latencyGrowth, float64(i))), l.max)
+		}
+	}
+	return l.max
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+func newSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save the content of several clipboards and restore it later",
+		Long: `Takes a snapshot of the content of several clipboards at once, so it can
+be put back after a script or other automation rewrote them.
+
+Snapshots are kept on the server under a name, or with --file in a local
+file (JSON, content base64-encoded) that the server never stores:
+
+  suffuse snapshot save before-deploy --clipboard notes --clipboard build
+  ./deploy.sh
+  suffuse snapshot restore before-deploy
+
+  suffuse snapshot save --file clipboards.json
+  suffuse snapshot restore --file clipboards.json
+
+Without --clipboard every clipboard the token covers is saved; clipboards
+without content are left out. A restore is all or nothing: each clipboard is
+checked like a copy (write rules, content filters, size limits, quotas)
+before any is set, and a refusal leaves them all as they were. Restored
+content is published under --source, reaching every peer like a copy.
+End-to-end encrypted clipboards are saved and restored as ciphertext.`,
+	}
+	cmd.AddCommand(newSnapshotSaveCmd())
+	cmd.AddCommand(newSnapshotRestoreCmd())
+	cmd.AddCommand(newSnapshotListCmd())
+	cmd.AddCommand(newSnapshotDeleteCmd())
+	return cmd
+}
+
+func newSnapshotSaveCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:     "save [name]",
+		Short:   "Snapshot clipboards on the server, or to --file",
+		Args:    cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSnapshotSave(cmd.Context(), v, strings.Join(args, ""))
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringSlice("clipboard", nil, "clipboard to save (repeatable; default: all)")
+	f.String("file", "", "write the snapshot to this file instead of keeping it on the server")
+	addAdminConnFlags(cmd)
+	return cmd
+}
+
+func newSnapshotRestoreCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:     "restore [name]",
+		Short:   "Set clipboards back to a snapshot kept on the server, or in --file",
+		Args:    cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSnapshotRestore(cmd.Context(), v, strings.Join(args, ""))
+		},
+	}
+
+	cmd.Flags().String("file", "", "restore the snapshot saved in this file")
+	addAdminConnFlags(cmd)
+	return cmd
+}
+
+func newSnapshotListCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List the snapshots kept on the server",
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, _ []string) error { return runSnapshotList(cmd.Context(), v) },
+	}
+
+	addAdminConnFlags(cmd)
+	return cmd
+}
+
+func newSnapshotDeleteCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:     "delete <name>",
+		Short:   "Delete a snapshot kept on the server",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, args []string) error { return runSnapshotDelete(cmd.Context(), v, args[0]) },
+	}
+
+	addAdminConnFlags(cmd)
+	return cmd
+}
+
+// snapshotClient dials the server for a snapshot command. The caller calls
+// the returned function to close the connection.
+func snapshotClient(v *viper.Viper) (pb.ClipboardServiceClient, func() error, error) {
+	conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), v.GetString("token"), v.GetString("source"), v.GetString("tls-ca"), v.GetString("via-ssh"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("dial: %w", err)
+	}
+	return pb.NewClipboardServiceClient(conn), conn.Close, nil
+}
+
+// nameOrFile checks that a command got exactly one of a snapshot name and
+// --file.
+func nameOrFile(name, file string) error {
+	if (name == "") == (file == "") {
+		return errors.New("give a snapshot name or --file")
+	}
+	return nil
+}
+
+func runSnapshotSave(ctx context.Context, v *viper.Viper, name string) error {
+	file := v.GetString("file")
+	if err := nameOrFile(name, file); err != nil {
+		return err
+	}
+	client, closeConn, err := snapshotClient(v)
+	if err != nil {
+		return err
+	}
+	defer closeConn() //nolint:errcheck
+
+	snap, err := client.CreateSnapshot(ctx, &pb.CreateSnapshotRequest{
+		Name:       name,
+		Clipboards: v.GetStringSlice("clipboard"),
+	})
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	if len(snap.Clipboards) == 0 {
+		fmt.Fprintln(os.Stderr, "warning: no clipboard holds content; the snapshot is empty")
+	}
+	if file != "" {
+		data, err := protojson.MarshalOptions{Multiline: true}.Marshal(snap)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, append(data, '\n'), 0o600); err != nil {
+			return err
+		}
+		name = file
+	}
+	for _, c := range snap.Clipboards {
+		fmt.Printf("saved %s (%s, %d bytes)\n", c.Clipboard, strings.Join(c.AvailableTypes, ","), c.Size)
+	}
+	fmt.Printf("Snapshot %s holds %d clipboards.\n", name, len(snap.Clipboards))
+	return nil
+}
+
+func runSnapshotRestore(ctx context.Context, v *viper.Viper, name string) error {
+	file := v.GetString("file")
+	if err := nameOrFile(name, file); err != nil {
+		return err
+	}
+	req := &pb.RestoreSnapshotRequest{Source: v.GetString("source")}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		snap := &pb.Snapshot{}
+		if err := protojson.Unmarshal(data, snap); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		req.From = &pb.RestoreSnapshotRequest_Snapshot{Snapshot: snap}
+	} else {
+		req.From = &pb.RestoreSnapshotRequest_Name{Name: name}
+	}
+
+	client, closeConn, err := snapshotClient(v)
+	if err != nil {
+		return err
+	}
+	defer closeConn() //nolint:errcheck
+
+	resp, err := client.RestoreSnapshot(ctx, req)
+	if err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+	for _, cb := range resp.Restored {
+		fmt.Printf("restored %s\n", cb)
+	}
+	return nil
+}
+
+func runSnapshotList(ctx context.Context, v *viper.Viper) error {
+	client, closeConn, err := snapshotClient(v)
+	if err != nil {
+		return err
+	}
+	defer closeConn() //nolint:errcheck
+
+	resp, err := client.ListSnapshots(ctx, &pb.ListSnapshotsRequest{})
+	if err != nil {
+		return fmt.Errorf("list snapshots: %w", err)
+	}
+	if len(resp.Snapshots) == 0 {
+		fmt.Println("No snapshots.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tCREATED\tBY\tCLIPBOARDS")
+	_, _ = fmt.Fprintln(tw, "----\t-------\t--\t----------")
+	for _, snap := range resp.Snapshots {
+		names := make([]string, len(snap.Clipboards))
+		for i, c := range snap.Clipboards {
+			names[i] = c.Clipboard
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			snap.Name, tsAge(snap.CreatedAt), dash(snap.CreatedBy), dash(strings.Join(names, ",")))
+	}
+	return tw.Flush()
+}
+
+func runSnapshotDelete(ctx context.Context, v *viper.Viper, name string) error {
+	client, closeConn, err := snapshotClient(v)
+	if err != nil {
+		return err
+	}
+	defer closeConn() //nolint:errcheck
+
+	if _, err := client.DeleteSnapshot(ctx, &pb.DeleteSnapshotRequest{Name: name}); err != nil {
+		return fmt.Errorf("delete snapshot: %w", err)
+	}
+	fmt.Printf("deleted %s\n", name)
+	return nil
+}
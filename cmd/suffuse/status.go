@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -25,17 +28,49 @@ func newStatusCmd() *cobra.Command {
 		Use:   "status",
 		Short: "Show connected peers",
 		Long: `Displays all peers currently connected to the suffuse server,
-including source name, address, role, clipboard, and last-seen time.
+including source name, address, role, clipboard, last-seen time, how full
+its send queue is, and the number of events each peer has dropped because
+it could not keep up.
+Server-wide drop totals per subsystem are listed above the peer table and
+flagged with WARN once they reach --warn-dropped. Server-side webhooks are
+followed by their delivery metrics (delivered, retried, dead-lettered,
+queued), with dead-lettered counts flagged the same way.
+
+--usage shows what sync has cost instead: clipboard content received and
+sent in total and per clipboard since the server started, and per peer for
+its current connection. Blob references count only when their content is
+fetched. On servers with per-source quotas it also lists each recent
+source's copies and bytes counted against them and how many of its copies
+went over; the peer view names the sources that did.
+
+--federation follows the upstream links instead: the server, its upstreams,
+their upstreams and so on, as a tree indented by hop count, each with its
+version, peers per role, publishes and the state of the link it was reached
+over. Every server probes its own upstreams, so the view reaches servers
+this host cannot connect to.
+
+Peers are grouped by role (or by clipboard with --group-by clipboard) and
+sorted by name within each group. Sources can be given friendly names and
+colors in the config file:
+
+  [sources.laptop-7f3a]
+  name  = "Kevin's laptop"
+  color = "cyan"    # red, green, yellow, blue, magenta, cyan, white, gray,
+                    # or bright-<color>; disabled when NO_COLOR is set
 
 Connects via the local IPC socket when a daemon is running on this host.
 Pass --host to query a remote server directly over TCP.
 
 Flags and their environment variables / config-file keys
-  --host    SUFFUSE_HOST    host
-  --port    SUFFUSE_PORT    port    (default: 8752)
-  --token   SUFFUSE_TOKEN   token
-  --source  SUFFUSE_SOURCE  source
-  --json    (no env/config equivalent)
+  --host          SUFFUSE_HOST          host
+  --port          SUFFUSE_PORT          port          (default: 8752)
+  --token         SUFFUSE_TOKEN         token
+  --source        SUFFUSE_SOURCE        source
+  --warn-dropped  SUFFUSE_WARN_DROPPED  warn-dropped  (default: 1)
+  --group-by      SUFFUSE_GROUP_BY      group-by      (role|clipboard, default: role)
+  --usage         (no env/config equivalent)
+  --federation    (no env/config equivalent)
+  --json          (no env/config equivalent)
 
 Config file search order (first found wins)
   /etc/suffuse/suffuse.toml
@@ -49,11 +84,18 @@ Precedence: defaults → config file → SUFFUSE_* env vars → CLI flags`,
 	}
 
 	f := cmd.Flags()
-	f.String("host", "", "suffuse server host (probes docker/podman/localhost if unset)")
+	f.String("host", "", "suffuse server host (probes docker/podman/localhost, then mDNS, if unset)")
 	f.Int("port", 8752, "suffuse server port")
 	f.String("token", "", "shared secret")
+	f.String("tls-ca", "", `CA certificate file to verify the server's certificate against, or "system" (default: verify the key derived from the token)`)
+	f.String("via-ssh", "", "reach the server through this SSH jump host, [user@]host[:port] (only port 22 needs to be open)")
 	f.String("source", defaultSource(), "source identifier")
 	f.Bool("json", false, "output raw JSON")
+	f.Uint64("warn-dropped", 1, "flag subsystems with at least this many dropped events")
+	f.String("group-by", "role", "group the peer table by role or clipboard")
+	f.Bool("usage", false, "show bytes received and sent per clipboard and peer")
+	f.Bool("federation", false, "show the tree of upstream servers")
+	cmd.MarkFlagsMutuallyExclusive("usage", "federation")
 	addConfigFlag(cmd)
 
 	return cmd
@@ -65,15 +107,25 @@ func runStatus(cmd *cobra.Command, v *viper.Viper) error {
 	host    := v.GetString("host")
 	port    := v.GetInt("port")
 	jsonOut := v.GetBool("json")
+	warnAt  := v.GetUint64("warn-dropped")
+	groupBy := v.GetString("group-by")
+	usage   := v.GetBool("usage")
+
+	if groupBy != "role" && groupBy != "clipboard" {
+		return fmt.Errorf("--group-by must be role or clipboard, got %q", groupBy)
+	}
+	styler, err := newSourceStyler(v)
+	if err != nil {
+		return err
+	}
 
 	var (
 		conn       *grpc.ClientConn
 		transport  string
 		remoteAddr string // non-empty when querying a remote server over TCP
-		err        error
 	)
 
-	if !cmd.Flags().Changed("host") && ipc.IsRunning() {
+	if !cmd.Flags().Changed("host") && v.GetString("via-ssh") == "" && ipc.IsRunning() {
 		conn, err = dialIPC()
 		if err == nil {
 			transport = fmt.Sprintf("ipc (%s)", ipc.SocketPath())
@@ -83,21 +135,35 @@ func runStatus(cmd *cobra.Command, v *viper.Viper) error {
 	}
 
 	if conn == nil {
-		var resolvedHost string
-		conn, resolvedHost, err = dialServerResolved(host, port, token, source)
+		conn, remoteAddr, err = dialServerResolved(host, port, token, source, v.GetString("tls-ca"), v.GetString("via-ssh"))
 		if err != nil {
 			return fmt.Errorf("dial: %w", err)
 		}
-		remoteAddr = fmt.Sprintf("%s:%d", resolvedHost, port)
 		if host != "" {
-			transport = fmt.Sprintf("tcp (%s:%d)", host, port)
+			transport = fmt.Sprintf("tcp (%s)", remoteAddr)
 		} else {
-			transport = fmt.Sprintf("tcp (port %d, auto-probed)", port)
+			transport = fmt.Sprintf("tcp (%s, auto-probed)", remoteAddr)
+		}
+		if via := v.GetString("via-ssh"); via != "" {
+			transport += " via ssh " + via
 		}
 	}
 	defer conn.Close()
 
 	client := pb.NewClipboardServiceClient(conn)
+	if v.GetBool("federation") {
+		fed, err := client.FederationStatus(context.Background(), &pb.FederationStatusRequest{})
+		if err != nil {
+			return fmt.Errorf("federation status: %w", err)
+		}
+		if jsonOut {
+			enc, _ := json.MarshalIndent(fed, "", "  ")
+			fmt.Println(string(enc))
+			return nil
+		}
+		printFederation(fed, transport, styler)
+		return nil
+	}
 	resp, err := client.Status(context.Background(), &pb.StatusRequest{})
 	if err != nil {
 		return fmt.Errorf("status: %w", err)
@@ -109,15 +175,29 @@ func runStatus(cmd *cobra.Command, v *viper.Viper) error {
 		return nil
 	}
 
-	printStatus(resp, source, transport, remoteAddr)
+	sortPeers(resp.Peers, groupBy, styler)
+	if usage {
+		printUsage(resp, transport, remoteAddr, styler)
+		return nil
+	}
+	printStatus(resp, source, transport, remoteAddr, warnAt, styler)
 	return nil
 }
 
-func printStatus(resp *pb.StatusResponse, mySource, transport string, remoteAddr string) {
+func printStatus(resp *pb.StatusResponse, mySource, transport string, remoteAddr string, warnAt uint64, styler *sourceStyler) {
 	w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
 
 	fmt.Fprintf(w, "Transport:\t%s\n", transport)
-	if ui := resp.UpstreamInfo; ui != nil {
+	for _, p := range resp.Peers {
+		if b := p.Backend; b != nil {
+			fmt.Fprintf(w, "Backend:\t%s\n", describeBackend(b))
+		}
+	}
+	upstreams := resp.Upstreams
+	if len(upstreams) == 0 && resp.UpstreamInfo != nil {
+		upstreams = []*pb.UpstreamInfo{resp.UpstreamInfo} // older server
+	}
+	for _, ui := range upstreams {
 		fmt.Fprintf(w, "Upstream:\t%s\n", ui.Addr)
 		if ui.ConnectedAt != nil && !ui.ConnectedAt.AsTime().IsZero() {
 			t := ui.ConnectedAt.AsTime()
@@ -126,6 +206,47 @@ func printStatus(resp *pb.StatusResponse, mySource, transport string, remoteAddr
 		if ui.LastSeen != nil && !ui.LastSeen.AsTime().IsZero() {
 			fmt.Fprintf(w, "Last seen:\t%s\n", fmtAge(ui.LastSeen.AsTime()))
 		}
+		if ui.Deferring != "" {
+			fmt.Fprintf(w, "Deferring:\tnon-text items (%s)\n", ui.Deferring)
+		}
+	}
+	if st := resp.Stats; st != nil && st.HeapBytes > 0 {
+		fmt.Fprintf(w, "Memory:\t%s heap, %d goroutines\n", fmtBytes(st.HeapBytes), st.Goroutines)
+	}
+	if st := resp.Stats; st != nil && st.Blobs > 0 {
+		fmt.Fprintf(w, "Blobs:\t%d (%s)\n", st.Blobs, fmtBytes(st.BlobBytes))
+	}
+	if st := resp.Stats; st != nil && st.Duplicates > 0 {
+		fmt.Fprintf(w, "Duplicates:\t%d suppressed\n", st.Duplicates)
+	}
+	if st := resp.Stats; st != nil && st.HopLimited > 0 {
+		fmt.Fprintf(w, "Hop limit:\t%d events not relayed\n", st.HopLimited)
+	}
+	if st := resp.Stats; st != nil && st.Loops > 0 {
+		fmt.Fprintf(w, "Loops:\t%d events dropped on returning here\n", st.Loops)
+	}
+	if st := resp.Stats; st != nil && st.Oversized > 0 {
+		fmt.Fprintf(w, "Oversized:\t%d publishes refused\n", st.Oversized)
+	}
+	label := "Over quota:"
+	for _, q := range resp.Quotas {
+		if q.Exceeded > 0 {
+			fmt.Fprintf(w, "%s\t%s %d\n", label, styler.render(q.Source), q.Exceeded)
+			label = ""
+		}
+	}
+	subsystems := slices.Sorted(maps.Keys(resp.Dropped))
+	for i, name := range subsystems {
+		label := ""
+		if i == 0 {
+			label = "Dropped:"
+		}
+		n := resp.Dropped[name]
+		warn := ""
+		if warnAt > 0 && n >= warnAt {
+			warn = "  WARN"
+		}
+		fmt.Fprintf(w, "%s\t%s %d%s\n", label, name, n, warn)
 	}
 	fmt.Fprintln(w)
 	_ = w.Flush()
@@ -136,12 +257,14 @@ func printStatus(resp *pb.StatusResponse, mySource, transport string, remoteAddr
 	}
 
 	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintf(tw, "\tSOURCE\tADDR\tROLE\tCLIPBOARD\tCONNECTED\tLAST SEEN\tACCEPTS\n")
-	_, _ = fmt.Fprintf(tw, "\t------\t----\t----\t---------\t---------\t---------\t-------\n")
+	_, _ = fmt.Fprintf(tw, "\t%s\tADDR\tROLE\tCLIPBOARD\tCONNECTED\tLAST SEEN\tQUEUE\tDROPPED\tACCEPTS\n", styler.plain("SOURCE"))
+	_, _ = fmt.Fprintf(tw, "\t%s\t----\t----\t---------\t---------\t---------\t-----\t-------\t-------\n", styler.plain("------"))
 	for _, p := range resp.Peers {
 		accepts := "*"
 		if len(p.AcceptedTypes) > 0 {
 			accepts = strings.Join(p.AcceptedTypes, ",")
+		} else if p.Backend != nil && !p.Backend.AnyMimeType {
+			accepts = "-" // headless backend stores nothing
 		}
 		// Mark the row that represents this client.
 		// For upstream rows (role=="upstream"), never mark as self.
@@ -156,14 +279,276 @@ func printStatus(resp *pb.StatusResponse, mySource, transport string, remoteAddr
 		if addr == "local" && remoteAddr != "" {
 			addr = remoteAddr
 		}
-		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-			marker, p.Source, addr, p.Role, p.Clipboard,
-			tsAge(p.ConnectedAt), tsAge(p.LastSeen), accepts,
+		queue := "-"
+		if p.QueueSize > 0 {
+			queue = fmt.Sprintf("%d/%d", p.Queued, p.QueueSize)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			marker, styler.render(p.Source), addr, p.Role, p.Clipboard,
+			tsAge(p.ConnectedAt), tsAge(p.LastSeen), queue, p.Dropped, accepts,
+		)
+	}
+	_ = tw.Flush()
+
+	printWebhooks(resp.Peers, warnAt)
+	printProbes(resp.Peers)
+}
+
+// printUsage lists content received and sent in total, per clipboard, and
+// per connected peer.
+func printUsage(resp *pb.StatusResponse, transport, remoteAddr string, styler *sourceStyler) {
+	w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Transport:\t%s\n", transport)
+	if st := resp.Stats; st != nil {
+		fmt.Fprintf(w, "Received:\t%s\n", fmtBytes(st.BytesIn))
+		fmt.Fprintf(w, "Sent:\t%s\n", fmtBytes(st.BytesOut))
+	}
+	fmt.Fprintln(w)
+	_ = w.Flush()
+
+	if len(resp.Usage) > 0 {
+		tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "CLIPBOARD\tIN\tOUT")
+		_, _ = fmt.Fprintln(tw, "---------\t--\t---")
+		for _, u := range resp.Usage {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", u.Clipboard, fmtBytes(u.BytesIn), fmtBytes(u.BytesOut))
+		}
+		_ = tw.Flush()
+		fmt.Println()
+	}
+
+	if len(resp.Quotas) > 0 {
+		tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "%s\tCOPIES/HOUR\tBYTES/DAY\tEXCEEDED\n", styler.plain("SOURCE"))
+		_, _ = fmt.Fprintf(tw, "%s\t-----------\t---------\t--------\n", styler.plain("------"))
+		for _, q := range resp.Quotas {
+			copies, bytes := "-", "-" // not limited
+			if q.CopiesLimit > 0 {
+				copies = fmt.Sprintf("%d of %d", q.Copies, q.CopiesLimit)
+			}
+			if q.BytesLimit > 0 {
+				bytes = fmtBytes(q.Bytes) + " of " + fmtBytes(q.BytesLimit)
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", styler.render(q.Source), copies, bytes, q.Exceeded)
+		}
+		_ = tw.Flush()
+		fmt.Println()
+	}
+
+	if len(resp.Peers) == 0 {
+		fmt.Println("No peers connected.")
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "%s\tADDR\tROLE\tCLIPBOARD\tCONNECTED\tIN\tOUT\n", styler.plain("SOURCE"))
+	_, _ = fmt.Fprintf(tw, "%s\t----\t----\t---------\t---------\t--\t---\n", styler.plain("------"))
+	for _, p := range resp.Peers {
+		addr := p.Addr
+		if addr == "local" && remoteAddr != "" {
+			addr = remoteAddr
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			styler.render(p.Source), addr, p.Role, p.Clipboard,
+			tsAge(p.ConnectedAt), fmtBytes(p.BytesIn), fmtBytes(p.BytesOut),
+		)
+	}
+	_ = tw.Flush()
+}
+
+// printFederation lists the servers of a federation tree depth first, each
+// indented by its distance from the first. LINK describes the link over which
+// the server on that row was reached.
+func printFederation(resp *pb.FederationStatusResponse, transport string, styler *sourceStyler) {
+	fmt.Printf("Transport: %s\n\n", transport)
+
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "HOP\t%s\tVERSION\tPEERS\tPUBLISHES\tLINK\n", styler.plain("SOURCE"))
+	_, _ = fmt.Fprintf(tw, "---\t%s\t-------\t-----\t---------\t----\n", styler.plain("------"))
+	for _, n := range resp.Nodes {
+		var peers []string
+		for _, role := range slices.Sorted(maps.Keys(n.Peers)) {
+			peers = append(peers, fmt.Sprintf("%d %s", n.Peers[role], role))
+		}
+		publishes := "-"
+		if n.Stats != nil {
+			publishes = strconv.FormatUint(n.Stats.Publishes, 10)
+		}
+		source := styler.render(n.Source)
+		if n.Source == "" {
+			source = styler.plain("?") // not reached
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s%s\t%s\t%s\t%s\t%s\n",
+			n.Hops, strings.Repeat("  ", int(n.Hops)), source, n.Version, strings.Join(peers, ", "), publishes, describeLink(n.UpstreamInfo))
+	}
+	_ = tw.Flush()
+
+	for _, n := range resp.Nodes {
+		switch {
+		case n.Error == "":
+		case n.Source == "":
+			fmt.Printf("\n%s: %s\n", n.UpstreamInfo.GetAddr(), n.Error)
+		default:
+			fmt.Printf("\n%s: %s\n", styler.name(n.Source), n.Error)
+		}
+	}
+}
+
+// describeLink summarises an upstream link, e.g. "up 5m ago, seen 2s ago".
+func describeLink(ui *pb.UpstreamInfo) string {
+	if ui == nil {
+		return "-"
+	}
+	link := "down"
+	if ui.ConnectedAt != nil && !ui.ConnectedAt.AsTime().IsZero() {
+		link = "up " + tsAge(ui.ConnectedAt)
+		if ui.LastSeen != nil {
+			link += ", seen " + tsAge(ui.LastSeen)
+		}
+	}
+	if ui.Deferring != "" {
+		link += " (deferring: " + ui.Deferring + ")"
+	}
+	return ui.Addr + " " + link
+}
+
+// fmtBytes formats a byte count with a binary unit, e.g. "1.5 MiB".
+func fmtBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// describeBackend summarises a clipboard backend's capabilities, e.g.
+// "Linux clipboard (poll) — poll every 250ms, one type per write".
+func describeBackend(b *pb.ClipboardBackend) string {
+	watch := b.Watch
+	switch {
+	case b.Watch == "poll" && b.PollInterval != nil:
+		watch = "poll every " + b.PollInterval.AsDuration().String()
+	case b.Watch == "event":
+		watch = "change events"
+	case b.Watch == "none":
+		watch = "no change detection"
+	}
+	writes := "one type per write"
+	switch {
+	case len(b.MimeTypes) == 0 && !b.AnyMimeType:
+		writes = "writes discarded"
+	case b.AtomicWrite:
+		writes = "atomic multi-type writes"
+	}
+	return fmt.Sprintf("%s — %s, %s", b.Name, watch, writes)
+}
+
+// printWebhooks lists delivery metrics for webhook peers, flagging hooks
+// with at least warnAt dead-lettered events.
+func printWebhooks(peers []*pb.PeerInfo, warnAt uint64) {
+	var hooks []*pb.PeerInfo
+	for _, p := range peers {
+		if p.Webhook != nil {
+			hooks = append(hooks, p)
+		}
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "WEBHOOK\tDELIVERED\tRETRIED\tDEAD\tQUEUED\tLAST DELIVERY\tLAST ERROR")
+	_, _ = fmt.Fprintln(tw, "-------\t---------\t-------\t----\t------\t-------------\t----------")
+	for _, p := range hooks {
+		s := p.Webhook
+		last := "never"
+		if s.LastDeliveredAt != nil {
+			last = tsAge(s.LastDeliveredAt)
+		}
+		lastErr := s.LastError
+		if lastErr == "" {
+			lastErr = "-"
+		}
+		dead := fmt.Sprint(s.DeadLettered)
+		if warnAt > 0 && s.DeadLettered >= warnAt {
+			dead += " WARN"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%d\t%s\t%s\n",
+			p.Source, s.Delivered, s.Retried, dead, s.Queued, last, lastErr,
 		)
 	}
 	_ = tw.Flush()
 }
 
+// printProbes lists the servers answering this server's end-to-end probes.
+func printProbes(peers []*pb.PeerInfo) {
+	for _, p := range peers {
+		s := p.Probe
+		if s == nil {
+			continue
+		}
+		fmt.Println()
+		fmt.Printf("Probes: every %s, %d sent, %d unanswered\n", s.Interval.AsDuration(), s.Sent, s.Unanswered)
+		if len(s.Targets) == 0 {
+			fmt.Println("No server has answered yet.")
+			continue
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "TARGET\tLATENCY\tLAST ANSWER\tANSWERED\tMISSED\tSTATE")
+		_, _ = fmt.Fprintln(tw, "------\t-------\t-----------\t--------\t------\t-----")
+		for _, t := range s.Targets {
+			state := "ok"
+			if t.Failing > 0 {
+				state = fmt.Sprintf("%d missed in a row", t.Failing)
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\n",
+				t.Source, t.Latency.AsDuration().Round(time.Microsecond), tsAge(t.LastAnswer), t.Answered, t.Missed, state,
+			)
+		}
+		_ = tw.Flush()
+	}
+}
+
+// roleOrder ranks roles for grouping: this server's own clipboard first,
+// then federation links, then clients, then webhooks, processors and probes.
+var roleOrder = map[string]int{"both": 0, "upstream": 1, "downstream": 2, "client": 3, "webhook": 4, "processor": 5, "probe": 6}
+
+// sortPeers orders peers by group (role or clipboard), then display name,
+// then address, so the table is stable between runs.
+func sortPeers(peers []*pb.PeerInfo, groupBy string, styler *sourceStyler) {
+	slices.SortStableFunc(peers, func(a, b *pb.PeerInfo) int {
+		if groupBy == "clipboard" {
+			if c := strings.Compare(a.Clipboard, b.Clipboard); c != 0 {
+				return c
+			}
+		}
+		ra, oka := roleOrder[a.Role]
+		rb, okb := roleOrder[b.Role]
+		if !oka {
+			ra = len(roleOrder)
+		}
+		if !okb {
+			rb = len(roleOrder)
+		}
+		if ra != rb {
+			return ra - rb
+		}
+		if groupBy == "role" {
+			if c := strings.Compare(a.Clipboard, b.Clipboard); c != 0 {
+				return c
+			}
+		}
+		if c := strings.Compare(strings.ToLower(styler.name(a.Source)), strings.ToLower(styler.name(b.Source))); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Addr, b.Addr)
+	})
+}
+
 func tsAge(ts *timestamppb.Timestamp) string {
 	if ts == nil {
 		return "-"
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/connectivity"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/ipc"
+)
+
+// shutdownWait bounds how long stop and restart wait for the server to go
+// away and, for restart, to come back.
+const shutdownWait = 10 * time.Second
+
+func newStopCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the suffuse server running on this host",
+		Long: `Asks the server running on this host to stop, over its IPC socket, and
+waits until it has. Calls and streams in flight get a second to finish,
+and the clipboard cache and event journal are written out before it exits.
+
+A server run by systemd, launchd or "suffuse service" is started again by
+its service manager if it is set to restart on exit; stop it there instead
+to keep it stopped.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error { return runShutdown(cmd.Context(), false) },
+	}
+}
+
+func newRestartCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restart",
+		Short: "Restart the suffuse server running on this host",
+		Long: `Asks the server running on this host to stop, over its IPC socket, and to
+start again in the same process, with the same arguments and environment,
+and waits until it listens again. The server rereads its configuration
+file and, if the suffuse binary was replaced, runs the new one; settings
+"suffuse admin reload" cannot change take effect this way.
+
+Restarting needs Unix; the IPC socket is not implemented on Windows yet.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error { return runShutdown(cmd.Context(), true) },
+	}
+}
+
+func runShutdown(ctx context.Context, restart bool) error {
+	if !ipc.IsRunning() {
+		return fmt.Errorf("no suffuse server on this host (IPC socket %s)", ipc.SocketPath())
+	}
+	conn, err := dialIPC()
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewAdminServiceClient(conn).Shutdown(ctx, &pb.ShutdownRequest{Restart: restart})
+	if err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+
+	// The server closes its socket and then the connection, once the call
+	// has been answered.
+	ctx, cancel := context.WithTimeout(ctx, shutdownWait)
+	defer cancel()
+	if conn.GetState() == connectivity.Ready && !conn.WaitForStateChange(ctx, connectivity.Ready) {
+		return fmt.Errorf("server (pid %d) still running after %s", resp.Pid, shutdownWait)
+	}
+	if !restart {
+		if err := waitIPC(ctx, false); err != nil {
+			return fmt.Errorf("server (pid %d) still running after %s", resp.Pid, shutdownWait)
+		}
+		fmt.Printf("Stopped suffuse server (pid %d).\n", resp.Pid)
+		return nil
+	}
+	if err := waitIPC(ctx, true); err != nil {
+		return fmt.Errorf("server (pid %d) stopped but did not come back within %s; see its log", resp.Pid, shutdownWait)
+	}
+	fmt.Printf("Restarted suffuse server (pid %d).\n", resp.Pid)
+	return nil
+}
+
+// waitIPC waits until the IPC socket answers, or until it no longer does.
+func waitIPC(ctx context.Context, running bool) error {
+	t := time.NewTicker(50 * time.Millisecond)
+	defer t.Stop()
+	for ipc.IsRunning() != running {
+		select {
+		case <-ctx.Done():
+			return errors.New("timed out")
+		case <-t.C:
+		}
+	}
+	return nil
+}
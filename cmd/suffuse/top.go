@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
+	"go.klb.dev/suffuse/internal/e2e"
+	"go.klb.dev/suffuse/internal/hub"
+	"go.klb.dev/suffuse/internal/logging"
+	"go.klb.dev/suffuse/internal/signing"
+	"go.klb.dev/suffuse/internal/term"
+)
+
+func newTopCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "Show peers, clipboard activity and throughput live",
+		Long: `Fills the terminal with a view of the server that keeps itself current:
+its throughput, memory and dropped events, the state of its upstream links
+and probes, the connected peers, and the latest copies to the watched
+clipboards with a preview of their text. Press Ctrl-C to quit.
+
+The server's status is fetched every --interval, and throughput is what it
+published and moved in between; copies appear as they happen. Only the
+clipboards given with --clipboard are watched, "default" unless set:
+
+  suffuse top --clipboard default,work --interval 1s
+
+Large items are not fetched: text shows the preview the server sends with
+it, and other items their size. End-to-end encrypted clipboards are
+decrypted with the configured key (see "suffuse copy --help").
+
+When standard output is not a terminal, every refresh is written out in
+turn, without redrawing, so the view can be logged.
+
+Connects via the local IPC socket when a daemon is running on this host.
+Pass --host to watch a remote server directly over TCP. "suffuse top"
+shows up among the peers it lists, as a client of each watched clipboard.`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, _ []string) error { return runTop(cmd, v) },
+	}
+
+	f := cmd.Flags()
+	f.String("host", "", "suffuse server host (uses the IPC socket if unset and available)")
+	f.Int("port", 8752, "suffuse server port")
+	f.String("token", "", "shared secret")
+	f.String("tls-ca", "", `CA certificate file to verify the server's certificate against, or "system" (default: verify the key derived from the token)`)
+	f.String("via-ssh", "", "reach the server through this SSH jump host, [user@]host[:port] (only port 22 needs to be open)")
+	f.String("source", defaultSource(), "source identifier")
+	f.StringSlice("clipboard", []string{hub.DefaultClipboard}, "clipboards whose copies are shown")
+	f.Duration("interval", 2*time.Second, "how often the server's status is fetched")
+	addE2EFlag(cmd)
+	addConfigFlag(cmd)
+
+	return cmd
+}
+
+// topActivity is the number of copies "suffuse top" remembers.
+const topActivity = 50
+
+// topCopy is a copy shown in the activity list.
+type topCopy struct {
+	at        time.Time
+	clipboard string
+	source    string
+	types     []string
+	size      int
+	preview   string
+}
+
+// topView is what "suffuse top" shows, updated from the Status calls and
+// Watch streams.
+type topView struct {
+	transport string
+	styler    *sourceStyler
+
+	status    *pb.StatusResponse
+	statusErr error
+	fetched   time.Time
+
+	// prev and prevAt are the counters of the previous status, from which
+	// the throughput is computed.
+	prev   *pb.HubStats
+	prevAt time.Time
+	rate   struct{ copies, in, out float64 }
+
+	copies    []topCopy // newest first
+	watchErrs map[string]error
+}
+
+func runTop(cmd *cobra.Command, v *viper.Viper) error {
+	interval := v.GetDuration("interval")
+	if interval <= 0 {
+		return fmt.Errorf("--interval must be positive, got %s", interval)
+	}
+	var clipboards []string
+	for _, cb := range getStringSlice(v, "clipboard") {
+		if cb = canonicalClipboard(cb); !slices.Contains(clipboards, cb) {
+			clipboards = append(clipboards, cb)
+		}
+	}
+	keyring, err := loadKeyring(v)
+	if err != nil {
+		return err
+	}
+	styler, err := newSourceStyler(v)
+	if err != nil {
+		return err
+	}
+	styler.color = false // colors would throw off truncating lines
+
+	host, via := v.GetString("host"), v.GetString("via-ssh")
+	conn, err := dialAuto(host, v.GetInt("port"), v.GetString("token"), v.GetString("source"), v.GetString("tls-ca"), via)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+	client := pb.NewClipboardServiceClient(conn)
+
+	view := &topView{transport: conn.Target(), styler: styler, watchErrs: make(map[string]error)}
+	if via != "" {
+		view.transport += " via ssh " + via
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	type watchUpdate struct {
+		clipboard string
+		copy      *topCopy
+		err       error
+	}
+	updates := make(chan watchUpdate)
+	for _, cb := range clipboards {
+		go func() {
+			for ctx.Err() == nil {
+				err := watchTop(ctx, client, cb, keyring, func(c topCopy) {
+					select {
+					case updates <- watchUpdate{clipboard: cb, copy: &c}:
+					case <-ctx.Done():
+					}
+				})
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case updates <- watchUpdate{clipboard: cb, err: err}:
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case <-time.After(interval):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	screen := logging.IsTTY(os.Stdout)
+	if screen {
+		_ = term.EnableEscapes(os.Stdout)
+		// The alternate screen keeps the shell's scrollback as it was.
+		fmt.Print("\x1b[?1049h\x1b[?25l")
+		defer fmt.Print("\x1b[?25h\x1b[?1049l")
+	}
+
+	poll := func() {
+		callCtx, cancel := context.WithTimeout(ctx, interval)
+		defer cancel()
+		resp, err := client.Status(callCtx, &pb.StatusRequest{})
+		if ctx.Err() == nil {
+			view.update(resp, err, time.Now())
+		}
+	}
+	draw := func() {
+		width, height := math.MaxInt, math.MaxInt // logged in full
+		if screen {
+			width, height = term.Size(os.Stdout)
+		}
+		frame := view.render(width, height, interval)
+		if screen {
+			// Home the cursor, clear each line's remainder and whatever is
+			// left below, rather than clearing the screen, which flickers.
+			frame = "\x1b[H" + strings.ReplaceAll(frame, "\n", "\x1b[K\n") + "\x1b[J"
+		} else {
+			frame += "\n"
+		}
+		_, _ = io.WriteString(os.Stdout, frame)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	poll()
+	draw()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			poll()
+		case u := <-updates:
+			if u.copy != nil {
+				view.watchErrs[u.clipboard] = nil
+				view.copies = append([]topCopy{*u.copy}, view.copies[:min(len(view.copies), topActivity-1)]...)
+			} else {
+				view.watchErrs[u.clipboard] = u.err
+			}
+			if !screen {
+				continue // batch output follows the status interval
+			}
+		}
+		draw()
+	}
+}
+
+// watchTop follows the copies to clipboard, passing each to add, until the
+// stream fails or ctx is done.
+func watchTop(ctx context.Context, client pb.ClipboardServiceClient, clipboard string, keyring *e2e.Keyring, add func(topCopy)) error {
+	stream, err := client.Watch(ctx, &pb.WatchRequest{Clipboard: clipboard, AcceptRefs: true})
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return errors.New("stream closed by the server")
+		}
+		if err != nil {
+			return err
+		}
+		c := topCopy{at: time.Now(), clipboard: resp.Clipboard, source: resp.Source}
+		if resp.Expired {
+			c.preview = "(expired)"
+			add(c)
+			continue
+		}
+		items := resp.Items
+		if keyring.Encrypted(resp.Clipboard) {
+			if opened, err := keyring.Open(resp.Clipboard, items); err == nil {
+				items = opened
+			}
+		}
+		for _, it := range items {
+			if it.Mime == signing.MIME {
+				continue
+			}
+			c.types = append(c.types, it.Mime)
+			c.size += blob.ItemSize(it)
+			switch {
+			case it.Mime == e2e.MIME:
+				c.preview = "(encrypted)"
+			case it.Mime != "text/plain" || c.preview != "":
+			case it.Ref != nil && len(it.Data) == 0:
+				c.preview = "(" + fmtBytes(it.Ref.Size) + " not fetched)"
+			case it.Ref != nil:
+				c.preview = escapeNewlines(strings.TrimSpace(string(it.Data))) + "…" // the server's preview
+			default:
+				c.preview = escapeNewlines(strings.TrimSpace(string(it.Data)))
+			}
+		}
+		add(c)
+	}
+}
+
+// update records a Status answer, computing the throughput since the
+// previous one.
+func (t *topView) update(resp *pb.StatusResponse, err error, now time.Time) {
+	t.statusErr = err
+	if err != nil {
+		return
+	}
+	t.status, t.fetched = resp, now
+	st := resp.Stats
+	if st == nil {
+		return
+	}
+	if t.prev != nil {
+		secs := now.Sub(t.prevAt).Seconds()
+		t.rate.copies = perSecond(t.prev.Publishes, st.Publishes, secs)
+		t.rate.in = perSecond(t.prev.BytesIn, st.BytesIn, secs)
+		t.rate.out = perSecond(t.prev.BytesOut, st.BytesOut, secs)
+	}
+	t.prev, t.prevAt = st, now
+}
+
+// perSecond is the rate at which a counter went from a to b in secs
+// seconds; zero when it went back, as after a server restart.
+func perSecond(a, b uint64, secs float64) float64 {
+	if b < a || secs <= 0 {
+		return 0
+	}
+	return float64(b-a) / secs
+}
+
+// render lays the view out in width columns and height lines. The peer and
+// activity lists share the lines the sections above them leave, and are cut
+// short with a count of the rows left out.
+func (t *topView) render(width, height int, interval time.Duration) string {
+	var head bytes.Buffer
+	refreshed := "never"
+	if !t.fetched.IsZero() {
+		refreshed = t.fetched.Format("15:04:05")
+	}
+	fmt.Fprintf(&head, "suffuse top — %s, refreshed %s, every %s\n\n", t.transport, refreshed, interval)
+	w := tabwriter.NewWriter(&head, 1, 0, 2, ' ', 0)
+	if t.statusErr != nil {
+		fmt.Fprintf(w, "Error:\t%v\n", t.statusErr)
+	}
+	for _, cb := range slices.Sorted(maps.Keys(t.watchErrs)) {
+		if err := t.watchErrs[cb]; err != nil {
+			fmt.Fprintf(w, "Error:\twatch %s: %v\n", cb, err)
+		}
+	}
+	resp := t.status
+	if st := resp.GetStats(); st != nil {
+		fmt.Fprintf(w, "Server:\tpeers %d, clipboards %d, heap %s, goroutines %d\n",
+			len(resp.Peers), st.Clipboards, fmtBytes(st.HeapBytes), st.Goroutines)
+		fmt.Fprintf(w, "Throughput:\tcopies %.1f/s, in %s/s, out %s/s  (in total: copies %d, in %s, out %s)\n",
+			t.rate.copies, fmtBytes(uint64(t.rate.in)), fmtBytes(uint64(t.rate.out)),
+			st.Publishes, fmtBytes(st.BytesIn), fmtBytes(st.BytesOut))
+	}
+	if len(resp.GetDropped()) > 0 {
+		var dropped []string
+		for _, name := range slices.Sorted(maps.Keys(resp.Dropped)) {
+			dropped = append(dropped, fmt.Sprintf("%s %d", name, resp.Dropped[name]))
+		}
+		fmt.Fprintf(w, "Dropped:\t%s\n", strings.Join(dropped, ", "))
+	}
+	label := "Upstream:"
+	for _, ui := range resp.GetUpstreams() {
+		fmt.Fprintf(w, "%s\t%s\n", label, describeLink(ui))
+		label = ""
+	}
+	label = "Probes:"
+	for _, p := range resp.GetPeers() {
+		for _, pt := range p.GetProbe().GetTargets() {
+			state := "ok"
+			if pt.Failing > 0 {
+				state = fmt.Sprintf("%d missed in a row", pt.Failing)
+			}
+			fmt.Fprintf(w, "%s\t%s %s, answered %s, %s\n",
+				label, t.styler.name(pt.Source), pt.Latency.AsDuration().Round(time.Microsecond), tsAge(pt.LastAnswer), state)
+			label = ""
+		}
+	}
+	_ = w.Flush()
+
+	headLines := strings.Split(strings.TrimSuffix(head.String(), "\n"), "\n")
+	// Each list takes a blank line and a header line besides its rows.
+	rows := max(height-len(headLines)-4, 2)
+	peerRows := min(len(resp.GetPeers()), rows/2)
+	copyRows := rows - peerRows
+
+	lines := headLines
+	lines = append(lines, "")
+	lines = append(lines, t.peers(peerRows)...)
+	lines = append(lines, "")
+	lines = append(lines, t.activity(copyRows)...)
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for i, l := range lines {
+		lines[i] = truncate(l, width)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// peers lays out the peer list in up to rows rows besides its header.
+func (t *topView) peers(rows int) []string {
+	peers := slices.Clone(t.status.GetPeers())
+	if len(peers) == 0 {
+		return []string{"No peers connected."}
+	}
+	sortPeers(peers, "role", t.styler)
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 1, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SOURCE\tADDR\tROLE\tCLIPBOARD\tCONNECTED\tLAST SEEN\tDROPPED\tIN\tOUT")
+	for _, p := range peers[:fitRows(len(peers), rows)] {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			t.styler.name(p.Source), p.Addr, p.Role, p.Clipboard, tsAge(p.ConnectedAt), tsAge(p.LastSeen),
+			p.Dropped, fmtBytes(p.BytesIn), fmtBytes(p.BytesOut))
+	}
+	_ = tw.Flush()
+	return withMore(b.String(), len(peers), rows, "peers")
+}
+
+// activity lays out the latest copies in up to rows rows besides the
+// header.
+func (t *topView) activity(rows int) []string {
+	copies := t.copies
+	if len(copies) == 0 {
+		return []string{"No copies yet."}
+	}
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 1, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "TIME\tCLIPBOARD\tSOURCE\tTYPES\tSIZE\tPREVIEW")
+	for _, c := range copies[:fitRows(len(copies), rows)] {
+		types := strings.Join(c.types, ",")
+		if types == "" {
+			types = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			c.at.Format("15:04:05"), c.clipboard, t.styler.name(c.source), types, fmtBytes(uint64(c.size)), c.preview)
+	}
+	_ = tw.Flush()
+	return withMore(b.String(), len(copies), rows, "copies")
+}
+
+// fitRows is the number of n rows shown in room for rows lines, keeping one
+// for the count of those left out.
+func fitRows(n, rows int) int {
+	if n <= rows {
+		return n
+	}
+	return max(rows-1, 0)
+}
+
+// withMore splits a laid-out list into lines, adding a count of the rows
+// left out.
+func withMore(table string, n, rows int, what string) []string {
+	lines := strings.Split(strings.TrimSuffix(table, "\n"), "\n")
+	if shown := fitRows(n, rows); shown < n {
+		lines = append(lines, fmt.Sprintf("… %d more %s", n-shown, what))
+	}
+	return lines
+}
+
+// truncate cuts s to width characters, marking the cut with an ellipsis.
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:max(width-1, 0)]) + "…"
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/chunk"
+	"go.klb.dev/suffuse/internal/hub"
+)
+
+// copyItems publishes req, in chunks with CopyStream when its content is
+// larger than a chunk. Servers that predate CopyStream get a single Copy.
+func copyItems(ctx context.Context, client pb.ClipboardServiceClient, req *pb.CopyRequest) error {
+	if hub.PayloadSize(req.Items) <= chunk.Size {
+		_, err := client.Copy(ctx, req)
+		return err
+	}
+	err := copyStream(ctx, client, req)
+	if status.Code(err) == codes.Unimplemented {
+		_, err = client.Copy(ctx, req)
+	}
+	return err
+}
+
+// copyBatch publishes copies, in order, with as few CopyBatch calls as fit
+// in a chunk's size each, and returns the error of each copy. Copies larger
+// than that are sent on their own with copyItems, and every copy with
+// copyItems to servers that predate CopyBatch. The error is returned for a
+// batch call that failed as a whole.
+func copyBatch(ctx context.Context, client pb.ClipboardServiceClient, copies []*pb.CopyRequest) ([]error, error) {
+	errs := make([]error, len(copies))
+	batched := true
+	for i := 0; i < len(copies); {
+		if !batched || hub.PayloadSize(copies[i].Items) > chunk.Size {
+			errs[i] = copyItems(ctx, client, copies[i])
+			i++
+			continue
+		}
+		j, size := i, 0
+		for j < len(copies) && size+hub.PayloadSize(copies[j].Items) <= chunk.Size {
+			size += hub.PayloadSize(copies[j].Items)
+			j++
+		}
+		resp, err := client.CopyBatch(ctx, &pb.CopyBatchRequest{Copies: copies[i:j]})
+		if status.Code(err) == codes.Unimplemented {
+			batched = false
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Results) != j-i {
+			return nil, fmt.Errorf("server answered %d of %d copies", len(resp.Results), j-i)
+		}
+		for k, r := range resp.Results {
+			if r.Code != int32(codes.OK) {
+				errs[i+k] = status.Error(codes.Code(r.Code), r.Error)
+			}
+		}
+		i = j
+	}
+	return errs, nil
+}
+
+func copyStream(ctx context.Context, client pb.ClipboardServiceClient, req *pb.CopyRequest) error {
+	stream, err := client.CopyStream(ctx)
+	if err != nil {
+		return err
+	}
+	for i, c := range chunk.Split(req.Items) {
+		msg := &pb.CopyChunk{Chunk: c}
+		if i == 0 {
+			msg.Clipboard, msg.Source = req.Clipboard, req.Source
+		}
+		if err := stream.Send(msg); errors.Is(err, io.EOF) {
+			// The server ended the call; CloseAndRecv has its status.
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+// pasteItems returns the content requested by req, received in chunks with
+// PasteStream so it is not bound by the size of a single message. Servers
+// that predate PasteStream get a single Paste.
+func pasteItems(ctx context.Context, client pb.ClipboardServiceClient, req *pb.PasteRequest) (*pb.PasteResponse, error) {
+	resp, err := pasteStream(ctx, client, req)
+	if status.Code(err) == codes.Unimplemented {
+		return client.Paste(ctx, req)
+	}
+	return resp, err
+}
+
+func pasteStream(ctx context.Context, client pb.ClipboardServiceClient, req *pb.PasteRequest) (*pb.PasteResponse, error) {
+	stream, err := client.PasteStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.PasteResponse{}
+	var asm chunk.Assembler
+	for first := true; ; first = false {
+		c, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			resp.Source, resp.Clipboard, resp.StoredAt = c.Source, c.Clipboard, c.StoredAt
+		}
+		if err := asm.Add(c.Chunk); err != nil {
+			return nil, err
+		}
+	}
+	resp.Items = asm.Items()
+	return resp, nil
+}
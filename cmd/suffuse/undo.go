@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/ipc"
+)
+
+func newUndoCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "undo",
+		Short: "Restore the local clipboard content the last synced copy replaced",
+		Long: `Asks the server running on this host to put back what the system clipboard
+held before the last copy from another machine overwrote it, for when a
+remote copy lands before you pasted your own.
+
+Only this host's clipboard changes: the restored content is not synced to
+the other machines (copy it again to share it). Content can be restored
+once, and an expired clipboard (server --clipboard-ttl) has nothing to
+restore.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error { return runUndo(cmd.Context()) },
+	}
+}
+
+func runUndo(ctx context.Context) error {
+	if !ipc.IsRunning() {
+		return fmt.Errorf("no suffuse server on this host (IPC socket %s)", ipc.SocketPath())
+	}
+	conn, err := dialIPC()
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewClipboardServiceClient(conn).Undo(ctx, &pb.UndoRequest{})
+	if err != nil {
+		return fmt.Errorf("undo: %w", err)
+	}
+	fmt.Printf("Restored %s, replaced by %s %s.\n",
+		strings.Join(resp.AvailableTypes, ", "), dash(resp.ReplacedBy), tsAge(resp.OverwrittenAt))
+	return nil
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
+	"go.klb.dev/suffuse/internal/e2e"
+	"go.klb.dev/suffuse/internal/hub"
+	"go.klb.dev/suffuse/internal/signing"
+)
+
+func newWatchCmd() *cobra.Command {
+	v := viper.New()
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Print clipboard changes as they happen",
+		Long: `Streams clipboard updates from the server and prints one line per event,
+for use in shell scripts.
+
+--format selects the output:
+  text     the text/plain payload, newlines escaped as \n (default)
+  json     one JSON object per line: time, source, clipboard, types, size,
+           text, signer, signature
+  printf   any other string, expanding the verbs below; \t and \n are honored
+  {{...}}  a Go template over the same fields as json, capitalized
+           (.Time .Source .Clipboard .Types .Size .Text .Signer .Signature)
+
+printf verbs
+  %s source     %c clipboard     %m MIME types (comma-separated)
+  %b size in bytes    %t text payload    %T time (RFC 3339)
+  %S signer     %% literal %
+
+  suffuse watch --format '%s\t%m\t%b'
+  suffuse watch --format '{{.Source}}: {{printf "%.40s" .Text}}'
+
+--json is short for --format json.
+
+Filtering
+  --accept limits which MIME types the server sends at all; events carrying
+  none of them are skipped. --mime instead skips events that offer none of
+  the given types or patterns ("image/*") but still lists every type they
+  have, e.g. to react to any copy that includes an image:
+
+  suffuse watch --mime 'image/*' --json
+
+--metadata-only asks the server for the MIME types of each event only, so
+no content crosses the connection however large the copy; text and size are
+then empty, and "suffuse paste" retrieves the content when it is needed.
+
+End-to-end encrypted clipboards are decrypted with the configured key (see
+"suffuse copy --help").
+
+Signed copies (see "suffuse copy --sign") are verified: signature is
+"trusted" for a signer listed with --signer or in the [signers] config table,
+"untrusted" for any other key, and "invalid" when the content does not match
+the signature; signer is the trusted name or the key. Both are empty for
+unsigned copies, and with --metadata-only. --verify prints only copies
+signed by a trusted signer:
+
+  suffuse watch --verify --format '%S: %t'`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error { return bindViper(cmd, v) },
+		RunE:    func(cmd *cobra.Command, _ []string) error { return runWatch(cmd.Context(), v) },
+	}
+
+	f := cmd.Flags()
+	f.String("host", "", "suffuse server host (uses the IPC socket if unset and available)")
+	f.Int("port", 8752, "suffuse server port")
+	f.String("token", "", "shared secret")
+	f.String("tls-ca", "", `CA certificate file to verify the server's certificate against, or "system" (default: verify the key derived from the token)`)
+	f.String("via-ssh", "", "reach the server through this SSH jump host, [user@]host[:port] (only port 22 needs to be open)")
+	f.String("source", defaultSource(), "source identifier")
+	f.String("clipboard", hub.DefaultClipboard, "clipboard namespace")
+	f.StringSlice("accept", nil, "only receive these MIME types (default: all)")
+	f.String("format", "text", "output format: text, json, a printf-style string, or a Go template")
+	f.Bool("json", false, "print one JSON object per event (same as --format json)")
+	f.StringSlice("mime", nil, `only print events offering one of these MIME types or patterns (e.g. "image/*")`)
+	f.Bool("metadata-only", false, "receive only the MIME types of each event, not its content")
+	cmd.MarkFlagsMutuallyExclusive("json", "format")
+	f.Bool("verify", false, "only print copies signed by a trusted signer")
+	addE2EFlag(cmd)
+	addTrustFlag(cmd)
+	addConfigFlag(cmd)
+
+	return cmd
+}
+
+// watchEvent is the data available to --format.
+type watchEvent struct {
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+	Clipboard string    `json:"clipboard"`
+	Types     []string  `json:"types"`
+	Size      int       `json:"size"`
+	Text      string    `json:"text,omitempty"`
+	Signer    string    `json:"signer,omitempty"`
+	// Signature is "trusted", "untrusted", "invalid", or "" when unsigned.
+	Signature string `json:"signature,omitempty"`
+}
+
+func runWatch(ctx context.Context, v *viper.Viper) error {
+	clipboard := canonicalClipboard(v.GetString("clipboard"))
+	accepts := getStringSlice(v, "accept")
+
+	formatName := v.GetString("format")
+	if v.GetBool("json") {
+		formatName = "json"
+	}
+	format, err := newWatchFormatter(formatName)
+	if err != nil {
+		return err
+	}
+	keyring, err := loadKeyring(v)
+	if err != nil {
+		return err
+	}
+	mimes := getStringSlice(v, "mime")
+	for _, m := range mimes {
+		if _, err := path.Match(m, ""); err != nil {
+			return fmt.Errorf("--mime %q: %w", m, err)
+		}
+	}
+	trust, err := loadTrust(v)
+	if err != nil {
+		return err
+	}
+	verify := v.GetBool("verify")
+	metadataOnly := v.GetBool("metadata-only")
+	if verify && metadataOnly {
+		return errors.New("--verify needs the content: it cannot be combined with --metadata-only")
+	}
+	if keyring.Encrypted(clipboard) {
+		// The server only sees the sealed item; filter after decrypting.
+		accepts = []string{e2e.MIME}
+	} else if len(accepts) > 0 {
+		accepts = append(accepts, signing.MIME)
+	}
+
+	conn, err := dialAuto(v.GetString("host"), v.GetInt("port"), v.GetString("token"), v.GetString("source"), v.GetString("tls-ca"), v.GetString("via-ssh"))
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	client := pb.NewClipboardServiceClient(conn)
+	stream, err := client.Watch(ctx, &pb.WatchRequest{
+		Clipboard:    clipboard,
+		Accepts:      accepts,
+		AcceptRefs:   true,
+		MetadataOnly: metadataOnly,
+	})
+	if err != nil {
+		return fmt.Errorf("watch: %w", err)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	wanted := getStringSlice(v, "accept")
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("watch: %w", err)
+		}
+		if metadataOnly {
+			ev := watchEvent{Time: time.Now(), Source: resp.Source, Clipboard: resp.Clipboard}
+			for _, mime := range resp.AvailableTypes {
+				if mime != signing.MIME && (len(wanted) == 0 || slices.Contains(wanted, mime)) {
+					ev.Types = append(ev.Types, mime)
+				}
+			}
+			if err := writeWatchEvent(out, format, ev, mimes); err != nil {
+				return err
+			}
+			continue
+		}
+		// Large items arrive as blob references; only the ones whose content
+		// is shown or needed for decryption are fetched.
+		items, err := fetchRefs(ctx, client, resp.Items, func(mime string) bool {
+			return mime == "text/plain" || mime == e2e.MIME || mime == signing.MIME
+		})
+		if err == nil {
+			items, err = keyring.Open(resp.Clipboard, items)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "watch: %s: %v\n", resp.Clipboard, err)
+			continue
+		}
+		ev := watchEvent{Time: time.Now(), Source: resp.Source, Clipboard: resp.Clipboard}
+		items, signer, err := trust.Verify(items)
+		switch {
+		case err != nil:
+			ev.Signature = "invalid"
+		case signer.Trusted():
+			ev.Signer, ev.Signature = signer.Name, "trusted"
+		case signer != nil:
+			ev.Signer, ev.Signature = signer.Key, "untrusted"
+		}
+		if verify && ev.Signature != "trusted" {
+			continue
+		}
+		for _, it := range items {
+			if len(wanted) > 0 && !slices.Contains(wanted, it.Mime) {
+				continue
+			}
+			ev.Types = append(ev.Types, it.Mime)
+			ev.Size += blob.ItemSize(it)
+			if it.Mime == "text/plain" {
+				ev.Text = string(it.Data)
+			}
+		}
+		if err := writeWatchEvent(out, format, ev, mimes); err != nil {
+			return err
+		}
+	}
+}
+
+// writeWatchEvent prints ev unless it has no types or offers none of mimes,
+// and flushes it so pipes see each line immediately.
+func writeWatchEvent(out *bufio.Writer, format watchFormatter, ev watchEvent, mimes []string) error {
+	if len(ev.Types) == 0 || !offersAny(ev.Types, mimes) {
+		return nil
+	}
+	if err := format(out, ev); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// offersAny reports whether any of types matches one of the path.Match
+// patterns; an empty pattern list matches everything.
+func offersAny(types, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, t := range types {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, t); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fetchRefs returns items with the blob references of types selected by need
+// replaced by their content, fetched from the server.
+func fetchRefs(ctx context.Context, client pb.ClipboardServiceClient, items []*pb.ClipboardItem, need func(mime string) bool) ([]*pb.ClipboardItem, error) {
+	out := make([]*pb.ClipboardItem, len(items))
+	for i, it := range items {
+		out[i] = it
+		if it.Ref == nil || !need(it.Mime) {
+			continue
+		}
+		resp, err := client.Fetch(ctx, &pb.FetchRequest{Sha256: it.Ref.Sha256})
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s: %w", it.Mime, err)
+		}
+		out[i] = &pb.ClipboardItem{Mime: it.Mime, OriginalCharset: it.OriginalCharset, Sha256: it.Sha256, Data: resp.Data}
+	}
+	return out, nil
+}
+
+// watchFormatter writes one event, including its trailing newline.
+type watchFormatter func(w io.Writer, ev watchEvent) error
+
+// newWatchFormatter parses a --format value.
+func newWatchFormatter(format string) (watchFormatter, error) {
+	switch {
+	case format == "text":
+		return func(w io.Writer, ev watchEvent) error {
+			_, err := fmt.Fprintln(w, escapeNewlines(ev.Text))
+			return err
+		}, nil
+	case format == "json":
+		return func(w io.Writer, ev watchEvent) error {
+			return json.NewEncoder(w).Encode(ev)
+		}, nil
+	case strings.Contains(format, "{{"):
+		tmpl, err := template.New("format").Parse(format)
+		if err != nil {
+			return nil, fmt.Errorf("--format: %w", err)
+		}
+		return func(w io.Writer, ev watchEvent) error {
+			if err := tmpl.Execute(w, ev); err != nil {
+				return fmt.Errorf("--format: %w", err)
+			}
+			_, err := io.WriteString(w, "\n")
+			return err
+		}, nil
+	default:
+		format = strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\\`, `\`).Replace(format)
+		return func(w io.Writer, ev watchEvent) error {
+			_, err := io.WriteString(w, expandWatchVerbs(format, ev)+"\n")
+			return err
+		}, nil
+	}
+}
+
+// expandWatchVerbs substitutes the printf-style verbs documented on the watch
+// command. Unknown verbs are left as-is.
+func expandWatchVerbs(format string, ev watchEvent) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch format[i] {
+		case 's':
+			b.WriteString(ev.Source)
+		case 'c':
+			b.WriteString(ev.Clipboard)
+		case 'm':
+			b.WriteString(strings.Join(ev.Types, ","))
+		case 'b':
+			b.WriteString(strconv.Itoa(ev.Size))
+		case 't':
+			b.WriteString(ev.Text)
+		case 'T':
+			b.WriteString(ev.Time.Format(time.RFC3339))
+		case 'S':
+			b.WriteString(ev.Signer)
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(format[i])
+		}
+	}
+	return b.String()
+}
+
+// escapeNewlines keeps a multi-line payload on one output line.
+func escapeNewlines(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
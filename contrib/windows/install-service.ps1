@@ -1,7 +1,10 @@
 #!/usr/bin/env pwsh
-# install-service.ps1 — register suffuse as a Windows service using sc.exe
+# install-service.ps1 — register suffuse as a Windows service
 # Run as Administrator.
 #
+# A wrapper around "suffuse service install", which registers the service,
+# its restart-on-failure actions and its Event Log source.
+#
 # Usage:
 #   .\install-service.ps1
 #   .\install-service.ps1 -BinPath "C:\Program Files\suffuse\suffuse.exe" `
@@ -22,48 +25,29 @@ param(
 
 $ErrorActionPreference = "Stop"
 
-function Register-SuffuseService {
-    param($Name, $DisplayName, $Description, $Args)
-
-    $existing = Get-Service -Name $Name -ErrorAction SilentlyContinue
-    if ($existing) {
-        Write-Host "Removing existing service: $Name"
-        Stop-Service -Name $Name -Force -ErrorAction SilentlyContinue
-        sc.exe delete $Name | Out-Null
-        Start-Sleep -Seconds 2
-    }
-
-    sc.exe create $Name `
-        binPath= "`"$BinPath`" $Args" `
-        DisplayName= $DisplayName `
-        start= auto | Out-Null
-
-    sc.exe description $Name $Description | Out-Null
-    sc.exe failure $Name reset= 60 actions= restart/5000/restart/5000/restart/10000 | Out-Null
+# ── Suffuse Server ────────────────────────────────────────────────────────────
 
-    Write-Host "Created service: $Name"
+$existing = Get-Service -Name "SuffuseServer" -ErrorAction SilentlyContinue
+if ($existing) {
+    Write-Host "Removing existing service: SuffuseServer"
+    & $BinPath service uninstall
+    if ($LASTEXITCODE -ne 0) { exit $LASTEXITCODE }
+    Start-Sleep -Seconds 2
 }
 
-# ── Suffuse Server ────────────────────────────────────────────────────────────
-
-$serverArgs = "server --addr `"$Addr`" --log-format json"
-if ($Token)        { $serverArgs += " --token `"$Token`"" }
+$serverArgs = @("--addr", $Addr)
+if ($Token)        { $serverArgs += @("--token", $Token) }
 if ($UpstreamHost) {
-    $serverArgs += " --upstream-host `"$UpstreamHost`" --upstream-port $UpstreamPort"
+    $serverArgs += @("--upstream-host", $UpstreamHost, "--upstream-port", $UpstreamPort)
 }
 
-Register-SuffuseService `
-    -Name        "SuffuseServer" `
-    -DisplayName "Suffuse Clipboard Hub" `
-    -Description "Suffuse shared-clipboard hub. Distributes clipboard events to all connected peers." `
-    -Args        $serverArgs
+& $BinPath service install -- @serverArgs
+if ($LASTEXITCODE -ne 0) { exit $LASTEXITCODE }
 
 Write-Host ""
 Write-Host "Service registered. Start with:"
 Write-Host "  Start-Service SuffuseServer"
-Write-Host ""
-Write-Host "Or start immediately:"
-Write-Host "  Start-Service SuffuseServer"
+Write-Host "  # or: suffuse service start"
 Write-Host ""
 Write-Host "Logs: Event Viewer > Windows Logs > Application (source: SuffuseServer)"
 Write-Host "      or: Get-EventLog -LogName Application -Source SuffuseServer -Newest 50"
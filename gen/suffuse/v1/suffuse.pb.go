@@ -10,6 +10,7 @@ import (
 	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
@@ -26,9 +27,23 @@ const (
 // ClipboardItem carries a single MIME representation of clipboard content.
 // data is raw bytes; the JSON gateway automatically base64-encodes this field.
 type ClipboardItem struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Mime          string                 `protobuf:"bytes,1,opt,name=mime,proto3" json:"mime,omitempty"`
-	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Mime  string                 `protobuf:"bytes,1,opt,name=mime,proto3" json:"mime,omitempty"`
+	Data  []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	// ref replaces data for an item held in the server's blob store; retrieve
+	// the content with Fetch. Only sent to peers that set accept_refs. A text
+	// item with a ref may keep the beginning of its content in data as a
+	// preview.
+	Ref *BlobRef `protobuf:"bytes,3,opt,name=ref,proto3" json:"ref,omitempty"`
+	// original_charset names the character set text was in before the server
+	// converted it to UTF-8, e.g. "windows-1252" or "shift_jis"; empty for
+	// text that was UTF-8 already and for other items.
+	OriginalCharset string `protobuf:"bytes,4,opt,name=original_charset,json=originalCharset,proto3" json:"original_charset,omitempty"`
+	// sha256 is the lowercase hex SHA-256 of the item's full content, whether
+	// carried in data or by ref. The server sets it on what it stores, so
+	// receivers tell repeated content apart by digest rather than by bytes;
+	// what clients send is recomputed. Empty from servers before digests.
+	Sha256        string `protobuf:"bytes,5,opt,name=sha256,proto3" json:"sha256,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -77,6 +92,81 @@ func (x *ClipboardItem) GetData() []byte {
 	return nil
 }
 
+func (x *ClipboardItem) GetRef() *BlobRef {
+	if x != nil {
+		return x.Ref
+	}
+	return nil
+}
+
+func (x *ClipboardItem) GetOriginalCharset() string {
+	if x != nil {
+		return x.OriginalCharset
+	}
+	return ""
+}
+
+func (x *ClipboardItem) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+// BlobRef identifies content in a server's content-addressed blob store.
+type BlobRef struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// sha256 is the lowercase hex SHA-256 of the content.
+	Sha256        string `protobuf:"bytes,1,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	Size          uint64 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlobRef) Reset() {
+	*x = BlobRef{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlobRef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlobRef) ProtoMessage() {}
+
+func (x *BlobRef) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlobRef.ProtoReflect.Descriptor instead.
+func (*BlobRef) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BlobRef) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *BlobRef) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
 type CopyRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// clipboard identifies the named clipboard (empty → "default").
@@ -90,7 +180,7 @@ type CopyRequest struct {
 
 func (x *CopyRequest) Reset() {
 	*x = CopyRequest{}
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[1]
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -102,7 +192,7 @@ func (x *CopyRequest) String() string {
 func (*CopyRequest) ProtoMessage() {}
 
 func (x *CopyRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[1]
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -115,7 +205,7 @@ func (x *CopyRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CopyRequest.ProtoReflect.Descriptor instead.
 func (*CopyRequest) Descriptor() ([]byte, []int) {
-	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{1}
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *CopyRequest) GetClipboard() string {
@@ -147,7 +237,7 @@ type CopyResponse struct {
 
 func (x *CopyResponse) Reset() {
 	*x = CopyResponse{}
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[2]
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -159,7 +249,7 @@ func (x *CopyResponse) String() string {
 func (*CopyResponse) ProtoMessage() {}
 
 func (x *CopyResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[2]
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -172,33 +262,31 @@ func (x *CopyResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CopyResponse.ProtoReflect.Descriptor instead.
 func (*CopyResponse) Descriptor() ([]byte, []int) {
-	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{2}
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{3}
 }
 
-type PasteRequest struct {
-	state     protoimpl.MessageState `protogen:"open.v1"`
-	Clipboard string                 `protobuf:"bytes,1,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
-	// accepts is an optional MIME filter (empty = return all types).
-	Accepts       []string `protobuf:"bytes,2,rep,name=accepts,proto3" json:"accepts,omitempty"`
+type CopyBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Copies        []*CopyRequest         `protobuf:"bytes,1,rep,name=copies,proto3" json:"copies,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PasteRequest) Reset() {
-	*x = PasteRequest{}
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[3]
+func (x *CopyBatchRequest) Reset() {
+	*x = CopyBatchRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PasteRequest) String() string {
+func (x *CopyBatchRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PasteRequest) ProtoMessage() {}
+func (*CopyBatchRequest) ProtoMessage() {}
 
-func (x *PasteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[3]
+func (x *CopyBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -209,49 +297,88 @@ func (x *PasteRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PasteRequest.ProtoReflect.Descriptor instead.
-func (*PasteRequest) Descriptor() ([]byte, []int) {
-	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use CopyBatchRequest.ProtoReflect.Descriptor instead.
+func (*CopyBatchRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *PasteRequest) GetClipboard() string {
+func (x *CopyBatchRequest) GetCopies() []*CopyRequest {
 	if x != nil {
-		return x.Clipboard
+		return x.Copies
 	}
-	return ""
+	return nil
 }
 
-func (x *PasteRequest) GetAccepts() []string {
+type CopyBatchResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// results holds the outcome of each copy, in the order of the request.
+	Results       []*CopyResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CopyBatchResponse) Reset() {
+	*x = CopyBatchResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CopyBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CopyBatchResponse) ProtoMessage() {}
+
+func (x *CopyBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[5]
 	if x != nil {
-		return x.Accepts
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CopyBatchResponse.ProtoReflect.Descriptor instead.
+func (*CopyBatchResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CopyBatchResponse) GetResults() []*CopyResult {
+	if x != nil {
+		return x.Results
 	}
 	return nil
 }
 
-type PasteResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Source        string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
-	Clipboard     string                 `protobuf:"bytes,2,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
-	Items         []*ClipboardItem       `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+type CopyResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// code is the gRPC status code the copy would have got from Copy: 0 (OK)
+	// when it was published, and error then empty.
+	Code          int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Error         string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PasteResponse) Reset() {
-	*x = PasteResponse{}
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[4]
+func (x *CopyResult) Reset() {
+	*x = CopyResult{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PasteResponse) String() string {
+func (x *CopyResult) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PasteResponse) ProtoMessage() {}
+func (*CopyResult) ProtoMessage() {}
 
-func (x *PasteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[4]
+func (x *CopyResult) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -262,60 +389,52 @@ func (x *PasteResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PasteResponse.ProtoReflect.Descriptor instead.
-func (*PasteResponse) Descriptor() ([]byte, []int) {
-	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use CopyResult.ProtoReflect.Descriptor instead.
+func (*CopyResult) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *PasteResponse) GetSource() string {
+func (x *CopyResult) GetCode() int32 {
 	if x != nil {
-		return x.Source
+		return x.Code
 	}
-	return ""
+	return 0
 }
 
-func (x *PasteResponse) GetClipboard() string {
+func (x *CopyResult) GetError() string {
 	if x != nil {
-		return x.Clipboard
+		return x.Error
 	}
 	return ""
 }
 
-func (x *PasteResponse) GetItems() []*ClipboardItem {
-	if x != nil {
-		return x.Items
-	}
-	return nil
-}
-
-type WatchRequest struct {
-	state     protoimpl.MessageState `protogen:"open.v1"`
-	Clipboard string                 `protobuf:"bytes,1,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
-	// accepts restricts which MIME types the server will send (empty = all).
-	// Example: ["text/plain"] lets a Neovim plugin ignore images server-side.
-	Accepts []string `protobuf:"bytes,2,rep,name=accepts,proto3" json:"accepts,omitempty"`
-	// metadata_only: if true, items is omitted from WatchResponse and the
-	// client should call Paste to retrieve content on demand.
-	MetadataOnly  bool `protobuf:"varint,3,opt,name=metadata_only,json=metadataOnly,proto3" json:"metadata_only,omitempty"`
+type CreateSnapshotRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// name keeps the snapshot on the server under this name: up to 64 letters,
+	// digits, '.', '-' and '_'. Empty returns the snapshot without keeping it.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// clipboards lists the clipboards to capture (empty → every clipboard the
+	// caller's token covers). Clipboards without content are left out.
+	Clipboards    []string `protobuf:"bytes,2,rep,name=clipboards,proto3" json:"clipboards,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *WatchRequest) Reset() {
-	*x = WatchRequest{}
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[5]
+func (x *CreateSnapshotRequest) Reset() {
+	*x = CreateSnapshotRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *WatchRequest) String() string {
+func (x *CreateSnapshotRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*WatchRequest) ProtoMessage() {}
+func (*CreateSnapshotRequest) ProtoMessage() {}
 
-func (x *WatchRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[5]
+func (x *CreateSnapshotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -326,62 +445,53 @@ func (x *WatchRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
-func (*WatchRequest) Descriptor() ([]byte, []int) {
-	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use CreateSnapshotRequest.ProtoReflect.Descriptor instead.
+func (*CreateSnapshotRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *WatchRequest) GetClipboard() string {
+func (x *CreateSnapshotRequest) GetName() string {
 	if x != nil {
-		return x.Clipboard
+		return x.Name
 	}
 	return ""
 }
 
-func (x *WatchRequest) GetAccepts() []string {
+func (x *CreateSnapshotRequest) GetClipboards() []string {
 	if x != nil {
-		return x.Accepts
+		return x.Clipboards
 	}
 	return nil
 }
 
-func (x *WatchRequest) GetMetadataOnly() bool {
-	if x != nil {
-		return x.MetadataOnly
-	}
-	return false
-}
-
-// WatchResponse is delivered to Watch subscribers whenever the clipboard
-// changes.
-type WatchResponse struct {
-	state     protoimpl.MessageState `protogen:"open.v1"`
-	Source    string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
-	Clipboard string                 `protobuf:"bytes,2,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
-	// items is empty when metadata_only was set in WatchRequest.
-	Items []*ClipboardItem `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
-	// available_types is always populated so metadata-only clients know what
-	// representations are available before calling Paste.
-	AvailableTypes []string `protobuf:"bytes,4,rep,name=available_types,json=availableTypes,proto3" json:"available_types,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+// Snapshot is the content of some clipboards at one moment.
+type Snapshot struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// name is empty for a snapshot that is not kept on the server.
+	Name      string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// created_by is the source of the caller that took the snapshot.
+	CreatedBy     string               `protobuf:"bytes,3,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
+	Clipboards    []*SnapshotClipboard `protobuf:"bytes,4,rep,name=clipboards,proto3" json:"clipboards,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *WatchResponse) Reset() {
-	*x = WatchResponse{}
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[6]
+func (x *Snapshot) Reset() {
+	*x = Snapshot{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *WatchResponse) String() string {
+func (x *Snapshot) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*WatchResponse) ProtoMessage() {}
+func (*Snapshot) ProtoMessage() {}
 
-func (x *WatchResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[6]
+func (x *Snapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -392,60 +502,69 @@ func (x *WatchResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use WatchResponse.ProtoReflect.Descriptor instead.
-func (*WatchResponse) Descriptor() ([]byte, []int) {
-	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use Snapshot.ProtoReflect.Descriptor instead.
+func (*Snapshot) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *WatchResponse) GetSource() string {
+func (x *Snapshot) GetName() string {
 	if x != nil {
-		return x.Source
+		return x.Name
 	}
 	return ""
 }
 
-func (x *WatchResponse) GetClipboard() string {
+func (x *Snapshot) GetCreatedAt() *timestamppb.Timestamp {
 	if x != nil {
-		return x.Clipboard
+		return x.CreatedAt
 	}
-	return ""
+	return nil
 }
 
-func (x *WatchResponse) GetItems() []*ClipboardItem {
+func (x *Snapshot) GetCreatedBy() string {
 	if x != nil {
-		return x.Items
+		return x.CreatedBy
 	}
-	return nil
+	return ""
 }
 
-func (x *WatchResponse) GetAvailableTypes() []string {
+func (x *Snapshot) GetClipboards() []*SnapshotClipboard {
 	if x != nil {
-		return x.AvailableTypes
+		return x.Clipboards
 	}
 	return nil
 }
 
-type StatusRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+type SnapshotClipboard struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Clipboard string                 `protobuf:"bytes,1,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	// source is the source of the content when the snapshot was taken.
+	Source string `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	// items is the content, with blob references resolved. Left out when
+	// the snapshot is listed or kept on the server.
+	Items          []*ClipboardItem `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+	AvailableTypes []string         `protobuf:"bytes,4,rep,name=available_types,json=availableTypes,proto3" json:"available_types,omitempty"`
+	// size is the total size of the items in bytes.
+	Size          int64 `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StatusRequest) Reset() {
-	*x = StatusRequest{}
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[7]
+func (x *SnapshotClipboard) Reset() {
+	*x = SnapshotClipboard{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StatusRequest) String() string {
+func (x *SnapshotClipboard) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StatusRequest) ProtoMessage() {}
+func (*SnapshotClipboard) ProtoMessage() {}
 
-func (x *StatusRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[7]
+func (x *SnapshotClipboard) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -456,41 +575,4182 @@ func (x *StatusRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
-func (*StatusRequest) Descriptor() ([]byte, []int) {
-	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{7}
-}
-
-// PeerInfo describes a single connected peer.
-type PeerInfo struct {
-	state  protoimpl.MessageState `protogen:"open.v1"`
-	Source string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
-	Addr   string                 `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
-	// role is one of "client", "upstream", or "both" (server with local clipboard).
-	Role          string                 `protobuf:"bytes,3,opt,name=role,proto3" json:"role,omitempty"`
-	Clipboard     string                 `protobuf:"bytes,4,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
-	AcceptedTypes []string               `protobuf:"bytes,5,rep,name=accepted_types,json=acceptedTypes,proto3" json:"accepted_types,omitempty"`
-	ConnectedAt   *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=connected_at,json=connectedAt,proto3" json:"connected_at,omitempty"`
-	LastSeen      *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+// Deprecated: Use SnapshotClipboard.ProtoReflect.Descriptor instead.
+func (*SnapshotClipboard) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SnapshotClipboard) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *SnapshotClipboard) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *SnapshotClipboard) GetItems() []*ClipboardItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *SnapshotClipboard) GetAvailableTypes() []string {
+	if x != nil {
+		return x.AvailableTypes
+	}
+	return nil
+}
+
+func (x *SnapshotClipboard) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type RestoreSnapshotRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to From:
+	//
+	//	*RestoreSnapshotRequest_Name
+	//	*RestoreSnapshotRequest_Snapshot
+	From isRestoreSnapshotRequest_From `protobuf_oneof:"from"`
+	// source is the source the restored content is published under.
+	Source        string `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreSnapshotRequest) Reset() {
+	*x = RestoreSnapshotRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreSnapshotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreSnapshotRequest) ProtoMessage() {}
+
+func (x *RestoreSnapshotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreSnapshotRequest.ProtoReflect.Descriptor instead.
+func (*RestoreSnapshotRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RestoreSnapshotRequest) GetFrom() isRestoreSnapshotRequest_From {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *RestoreSnapshotRequest) GetName() string {
+	if x != nil {
+		if x, ok := x.From.(*RestoreSnapshotRequest_Name); ok {
+			return x.Name
+		}
+	}
+	return ""
+}
+
+func (x *RestoreSnapshotRequest) GetSnapshot() *Snapshot {
+	if x != nil {
+		if x, ok := x.From.(*RestoreSnapshotRequest_Snapshot); ok {
+			return x.Snapshot
+		}
+	}
+	return nil
+}
+
+func (x *RestoreSnapshotRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+type isRestoreSnapshotRequest_From interface {
+	isRestoreSnapshotRequest_From()
+}
+
+type RestoreSnapshotRequest_Name struct {
+	// name restores a snapshot kept on the server.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3,oneof"`
+}
+
+type RestoreSnapshotRequest_Snapshot struct {
+	// snapshot restores one the caller kept, as returned by CreateSnapshot.
+	Snapshot *Snapshot `protobuf:"bytes,2,opt,name=snapshot,proto3,oneof"`
+}
+
+func (*RestoreSnapshotRequest_Name) isRestoreSnapshotRequest_From() {}
+
+func (*RestoreSnapshotRequest_Snapshot) isRestoreSnapshotRequest_From() {}
+
+type RestoreSnapshotResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// restored lists the clipboards set back to the snapshot's content.
+	Restored      []string `protobuf:"bytes,1,rep,name=restored,proto3" json:"restored,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreSnapshotResponse) Reset() {
+	*x = RestoreSnapshotResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreSnapshotResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreSnapshotResponse) ProtoMessage() {}
+
+func (x *RestoreSnapshotResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreSnapshotResponse.ProtoReflect.Descriptor instead.
+func (*RestoreSnapshotResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RestoreSnapshotResponse) GetRestored() []string {
+	if x != nil {
+		return x.Restored
+	}
+	return nil
+}
+
+type ListSnapshotsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSnapshotsRequest) Reset() {
+	*x = ListSnapshotsRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSnapshotsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSnapshotsRequest) ProtoMessage() {}
+
+func (x *ListSnapshotsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSnapshotsRequest.ProtoReflect.Descriptor instead.
+func (*ListSnapshotsRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{12}
+}
+
+type ListSnapshotsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Snapshots     []*Snapshot            `protobuf:"bytes,1,rep,name=snapshots,proto3" json:"snapshots,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSnapshotsResponse) Reset() {
+	*x = ListSnapshotsResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSnapshotsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSnapshotsResponse) ProtoMessage() {}
+
+func (x *ListSnapshotsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSnapshotsResponse.ProtoReflect.Descriptor instead.
+func (*ListSnapshotsResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListSnapshotsResponse) GetSnapshots() []*Snapshot {
+	if x != nil {
+		return x.Snapshots
+	}
+	return nil
+}
+
+type DeleteSnapshotRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSnapshotRequest) Reset() {
+	*x = DeleteSnapshotRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSnapshotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSnapshotRequest) ProtoMessage() {}
+
+func (x *DeleteSnapshotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSnapshotRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSnapshotRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DeleteSnapshotRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type DeleteSnapshotResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSnapshotResponse) Reset() {
+	*x = DeleteSnapshotResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSnapshotResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSnapshotResponse) ProtoMessage() {}
+
+func (x *DeleteSnapshotResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSnapshotResponse.ProtoReflect.Descriptor instead.
+func (*DeleteSnapshotResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{15}
+}
+
+type PasteRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Clipboard string                 `protobuf:"bytes,1,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	// accepts is an optional MIME filter (empty = return all types).
+	Accepts []string `protobuf:"bytes,2,rep,name=accepts,proto3" json:"accepts,omitempty"`
+	// accept_refs lets the server return large items as blob references
+	// instead of inline data.
+	AcceptRefs bool `protobuf:"varint,3,opt,name=accept_refs,json=acceptRefs,proto3" json:"accept_refs,omitempty"`
+	// at asks for what the clipboard held at that time instead of its current
+	// content, looked up in its history (server --history) when it changed
+	// since. NotFound when the server no longer keeps the content of that
+	// time.
+	At            *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=at,proto3" json:"at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PasteRequest) Reset() {
+	*x = PasteRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PasteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PasteRequest) ProtoMessage() {}
+
+func (x *PasteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PasteRequest.ProtoReflect.Descriptor instead.
+func (*PasteRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *PasteRequest) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *PasteRequest) GetAccepts() []string {
+	if x != nil {
+		return x.Accepts
+	}
+	return nil
+}
+
+func (x *PasteRequest) GetAcceptRefs() bool {
+	if x != nil {
+		return x.AcceptRefs
+	}
+	return false
+}
+
+func (x *PasteRequest) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+type PasteResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Source    string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Clipboard string                 `protobuf:"bytes,2,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	Items     []*ClipboardItem       `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+	// stored_at is when the content was published; set when at was given.
+	StoredAt      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=stored_at,json=storedAt,proto3" json:"stored_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PasteResponse) Reset() {
+	*x = PasteResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PasteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PasteResponse) ProtoMessage() {}
+
+func (x *PasteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PasteResponse.ProtoReflect.Descriptor instead.
+func (*PasteResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PasteResponse) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *PasteResponse) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *PasteResponse) GetItems() []*ClipboardItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *PasteResponse) GetStoredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StoredAt
+	}
+	return nil
+}
+
+type HistoryRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Clipboard string                 `protobuf:"bytes,1,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	// accepts is an optional MIME filter (empty = return all types).
+	Accepts []string `protobuf:"bytes,2,rep,name=accepts,proto3" json:"accepts,omitempty"`
+	// accept_refs lets the server return large items as blob references
+	// instead of inline data.
+	AcceptRefs bool `protobuf:"varint,3,opt,name=accept_refs,json=acceptRefs,proto3" json:"accept_refs,omitempty"`
+	// limit keeps the newest entries; 0 returns all the server keeps.
+	Limit         uint32 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryRequest) Reset() {
+	*x = HistoryRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryRequest) ProtoMessage() {}
+
+func (x *HistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryRequest.ProtoReflect.Descriptor instead.
+func (*HistoryRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *HistoryRequest) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *HistoryRequest) GetAccepts() []string {
+	if x != nil {
+		return x.Accepts
+	}
+	return nil
+}
+
+func (x *HistoryRequest) GetAcceptRefs() bool {
+	if x != nil {
+		return x.AcceptRefs
+	}
+	return false
+}
+
+func (x *HistoryRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type HistoryResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Clipboard string                 `protobuf:"bytes,1,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	// entries are the clipboard's earlier contents, newest first. The current
+	// content, returned by Paste, is not included.
+	Entries       []*HistoryEntry `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryResponse) Reset() {
+	*x = HistoryResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryResponse) ProtoMessage() {}
+
+func (x *HistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryResponse.ProtoReflect.Descriptor instead.
+func (*HistoryResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *HistoryResponse) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *HistoryResponse) GetEntries() []*HistoryEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// HistoryEntry is content a clipboard held before it was replaced.
+type HistoryEntry struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Source string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	// stored_at is when the content was published.
+	StoredAt *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=stored_at,json=storedAt,proto3" json:"stored_at,omitempty"`
+	// items is filtered by HistoryRequest.accepts; available_types lists every
+	// type the entry holds.
+	Items          []*ClipboardItem `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+	AvailableTypes []string         `protobuf:"bytes,4,rep,name=available_types,json=availableTypes,proto3" json:"available_types,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *HistoryEntry) Reset() {
+	*x = HistoryEntry{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryEntry) ProtoMessage() {}
+
+func (x *HistoryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryEntry.ProtoReflect.Descriptor instead.
+func (*HistoryEntry) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *HistoryEntry) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetStoredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StoredAt
+	}
+	return nil
+}
+
+func (x *HistoryEntry) GetItems() []*ClipboardItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *HistoryEntry) GetAvailableTypes() []string {
+	if x != nil {
+		return x.AvailableTypes
+	}
+	return nil
+}
+
+type UndoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UndoRequest) Reset() {
+	*x = UndoRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UndoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UndoRequest) ProtoMessage() {}
+
+func (x *UndoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UndoRequest.ProtoReflect.Descriptor instead.
+func (*UndoRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{21}
+}
+
+type UndoResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// available_types lists the types of the restored content.
+	AvailableTypes []string `protobuf:"bytes,1,rep,name=available_types,json=availableTypes,proto3" json:"available_types,omitempty"`
+	// replaced_by is the source of the update that had overwritten it, at
+	// overwritten_at.
+	ReplacedBy    string                 `protobuf:"bytes,2,opt,name=replaced_by,json=replacedBy,proto3" json:"replaced_by,omitempty"`
+	OverwrittenAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=overwritten_at,json=overwrittenAt,proto3" json:"overwritten_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UndoResponse) Reset() {
+	*x = UndoResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UndoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UndoResponse) ProtoMessage() {}
+
+func (x *UndoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UndoResponse.ProtoReflect.Descriptor instead.
+func (*UndoResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *UndoResponse) GetAvailableTypes() []string {
+	if x != nil {
+		return x.AvailableTypes
+	}
+	return nil
+}
+
+func (x *UndoResponse) GetReplacedBy() string {
+	if x != nil {
+		return x.ReplacedBy
+	}
+	return ""
+}
+
+func (x *UndoResponse) GetOverwrittenAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OverwrittenAt
+	}
+	return nil
+}
+
+type AcceptRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// discard drops the held update instead of applying it.
+	Discard       bool `protobuf:"varint,1,opt,name=discard,proto3" json:"discard,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AcceptRequest) Reset() {
+	*x = AcceptRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AcceptRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AcceptRequest) ProtoMessage() {}
+
+func (x *AcceptRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AcceptRequest.ProtoReflect.Descriptor instead.
+func (*AcceptRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *AcceptRequest) GetDiscard() bool {
+	if x != nil {
+		return x.Discard
+	}
+	return false
+}
+
+type AcceptResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// available_types lists the types of the held update.
+	AvailableTypes []string `protobuf:"bytes,1,rep,name=available_types,json=availableTypes,proto3" json:"available_types,omitempty"`
+	// source copied the update, which arrived at received_at.
+	Source        string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	ReceivedAt    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=received_at,json=receivedAt,proto3" json:"received_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AcceptResponse) Reset() {
+	*x = AcceptResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AcceptResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AcceptResponse) ProtoMessage() {}
+
+func (x *AcceptResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AcceptResponse.ProtoReflect.Descriptor instead.
+func (*AcceptResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *AcceptResponse) GetAvailableTypes() []string {
+	if x != nil {
+		return x.AvailableTypes
+	}
+	return nil
+}
+
+func (x *AcceptResponse) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *AcceptResponse) GetReceivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReceivedAt
+	}
+	return nil
+}
+
+// ItemChunk carries part of one clipboard item. A chunk with mime set starts
+// a new item; the chunks that follow without it append their data to it.
+type ItemChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mime          string                 `protobuf:"bytes,1,opt,name=mime,proto3" json:"mime,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ItemChunk) Reset() {
+	*x = ItemChunk{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ItemChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ItemChunk) ProtoMessage() {}
+
+func (x *ItemChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ItemChunk.ProtoReflect.Descriptor instead.
+func (*ItemChunk) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ItemChunk) GetMime() string {
+	if x != nil {
+		return x.Mime
+	}
+	return ""
+}
+
+func (x *ItemChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type CopyChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// clipboard and source are read from the first chunk of the stream only,
+	// with the meaning they have in CopyRequest.
+	Clipboard     string     `protobuf:"bytes,1,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	Source        string     `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	Chunk         *ItemChunk `protobuf:"bytes,3,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CopyChunk) Reset() {
+	*x = CopyChunk{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CopyChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CopyChunk) ProtoMessage() {}
+
+func (x *CopyChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CopyChunk.ProtoReflect.Descriptor instead.
+func (*CopyChunk) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *CopyChunk) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *CopyChunk) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *CopyChunk) GetChunk() *ItemChunk {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+type PasteChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// source, clipboard and stored_at are set on the first chunk of the
+	// stream only. A clipboard without content is answered with that chunk
+	// alone.
+	Source        string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Clipboard     string                 `protobuf:"bytes,2,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	Chunk         *ItemChunk             `protobuf:"bytes,3,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	StoredAt      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=stored_at,json=storedAt,proto3" json:"stored_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PasteChunk) Reset() {
+	*x = PasteChunk{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PasteChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PasteChunk) ProtoMessage() {}
+
+func (x *PasteChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PasteChunk.ProtoReflect.Descriptor instead.
+func (*PasteChunk) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *PasteChunk) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *PasteChunk) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *PasteChunk) GetChunk() *ItemChunk {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+func (x *PasteChunk) GetStoredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StoredAt
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Clipboard string                 `protobuf:"bytes,1,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	// accepts restricts which MIME types the server will send (empty = all).
+	// Example: ["text/plain"] lets a Neovim plugin ignore images server-side.
+	Accepts []string `protobuf:"bytes,2,rep,name=accepts,proto3" json:"accepts,omitempty"`
+	// metadata_only: if true, items is omitted from WatchResponse and the
+	// client should call Paste to retrieve content on demand.
+	MetadataOnly bool `protobuf:"varint,3,opt,name=metadata_only,json=metadataOnly,proto3" json:"metadata_only,omitempty"`
+	// accept_refs lets the server send large items as blob references instead
+	// of inline data; fetch the ones you need with Fetch.
+	AcceptRefs    bool `protobuf:"varint,4,opt,name=accept_refs,json=acceptRefs,proto3" json:"accept_refs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *WatchRequest) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetAccepts() []string {
+	if x != nil {
+		return x.Accepts
+	}
+	return nil
+}
+
+func (x *WatchRequest) GetMetadataOnly() bool {
+	if x != nil {
+		return x.MetadataOnly
+	}
+	return false
+}
+
+func (x *WatchRequest) GetAcceptRefs() bool {
+	if x != nil {
+		return x.AcceptRefs
+	}
+	return false
+}
+
+// WatchResponse is delivered to Watch subscribers whenever the clipboard
+// changes.
+type WatchResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Source    string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Clipboard string                 `protobuf:"bytes,2,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	// items is empty when metadata_only was set in WatchRequest.
+	Items []*ClipboardItem `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+	// available_types is always populated so metadata-only clients know what
+	// representations are available before calling Paste.
+	AvailableTypes []string `protobuf:"bytes,4,rep,name=available_types,json=availableTypes,proto3" json:"available_types,omitempty"`
+	// expired is set, with no items, when the clipboard's content outlived the
+	// server's --clipboard-ttl and was cleared.
+	Expired       bool `protobuf:"varint,5,opt,name=expired,proto3" json:"expired,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchResponse) Reset() {
+	*x = WatchResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchResponse) ProtoMessage() {}
+
+func (x *WatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchResponse.ProtoReflect.Descriptor instead.
+func (*WatchResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *WatchResponse) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *WatchResponse) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *WatchResponse) GetItems() []*ClipboardItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *WatchResponse) GetAvailableTypes() []string {
+	if x != nil {
+		return x.AvailableTypes
+	}
+	return nil
+}
+
+func (x *WatchResponse) GetExpired() bool {
+	if x != nil {
+		return x.Expired
+	}
+	return false
+}
+
+type FetchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// sha256 is BlobRef.sha256 of the wanted content.
+	Sha256        string `protobuf:"bytes,1,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FetchRequest) Reset() {
+	*x = FetchRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FetchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FetchRequest) ProtoMessage() {}
+
+func (x *FetchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FetchRequest.ProtoReflect.Descriptor instead.
+func (*FetchRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *FetchRequest) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+type FetchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FetchResponse) Reset() {
+	*x = FetchResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FetchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FetchResponse) ProtoMessage() {}
+
+func (x *FetchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FetchResponse.ProtoReflect.Descriptor instead.
+func (*FetchResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *FetchResponse) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type StatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusRequest) ProtoMessage() {}
+
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
+func (*StatusRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{32}
+}
+
+// PeerInfo describes a single connected peer.
+type PeerInfo struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Source string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Addr   string                 `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+	// role is one of "client", "upstream", "downstream" (federated server),
+	// "both" (server with local clipboard), "webhook" (server-side hook),
+	// "processor" (enrichment processor), or "probe" (end-to-end prober).
+	Role          string                 `protobuf:"bytes,3,opt,name=role,proto3" json:"role,omitempty"`
+	Clipboard     string                 `protobuf:"bytes,4,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	AcceptedTypes []string               `protobuf:"bytes,5,rep,name=accepted_types,json=acceptedTypes,proto3" json:"accepted_types,omitempty"`
+	ConnectedAt   *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=connected_at,json=connectedAt,proto3" json:"connected_at,omitempty"`
+	LastSeen      *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	// dropped counts events discarded because this peer could not keep up.
+	Dropped uint64 `protobuf:"varint,8,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	// webhook carries delivery metrics when role is "webhook".
+	Webhook *WebhookStats `protobuf:"bytes,9,opt,name=webhook,proto3" json:"webhook,omitempty"`
+	// backend describes the system clipboard when role is "both".
+	Backend *ClipboardBackend `protobuf:"bytes,10,opt,name=backend,proto3" json:"backend,omitempty"`
+	// bytes_in and bytes_out count clipboard content received from and sent
+	// to this peer during its current session. Blob references count only
+	// when their content is fetched.
+	BytesIn  uint64 `protobuf:"varint,11,opt,name=bytes_in,json=bytesIn,proto3" json:"bytes_in,omitempty"`
+	BytesOut uint64 `protobuf:"varint,12,opt,name=bytes_out,json=bytesOut,proto3" json:"bytes_out,omitempty"`
+	// probe carries round-trip results when role is "probe".
+	Probe *ProbeStats `protobuf:"bytes,13,opt,name=probe,proto3" json:"probe,omitempty"`
+	// queued and queue_size are the number of events waiting in this peer's
+	// send queue and its capacity; both zero for peers without one.
+	Queued        uint32 `protobuf:"varint,14,opt,name=queued,proto3" json:"queued,omitempty"`
+	QueueSize     uint32 `protobuf:"varint,15,opt,name=queue_size,json=queueSize,proto3" json:"queue_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *PeerInfo) Reset() {
 	*x = PeerInfo{}
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[8]
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerInfo) ProtoMessage() {}
+
+func (x *PeerInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerInfo.ProtoReflect.Descriptor instead.
+func (*PeerInfo) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *PeerInfo) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *PeerInfo) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *PeerInfo) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *PeerInfo) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *PeerInfo) GetAcceptedTypes() []string {
+	if x != nil {
+		return x.AcceptedTypes
+	}
+	return nil
+}
+
+func (x *PeerInfo) GetConnectedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ConnectedAt
+	}
+	return nil
+}
+
+func (x *PeerInfo) GetLastSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeen
+	}
+	return nil
+}
+
+func (x *PeerInfo) GetDropped() uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetWebhook() *WebhookStats {
+	if x != nil {
+		return x.Webhook
+	}
+	return nil
+}
+
+func (x *PeerInfo) GetBackend() *ClipboardBackend {
+	if x != nil {
+		return x.Backend
+	}
+	return nil
+}
+
+func (x *PeerInfo) GetBytesIn() uint64 {
+	if x != nil {
+		return x.BytesIn
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetBytesOut() uint64 {
+	if x != nil {
+		return x.BytesOut
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetProbe() *ProbeStats {
+	if x != nil {
+		return x.Probe
+	}
+	return nil
+}
+
+func (x *PeerInfo) GetQueued() uint32 {
+	if x != nil {
+		return x.Queued
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetQueueSize() uint32 {
+	if x != nil {
+		return x.QueueSize
+	}
+	return 0
+}
+
+// ClipboardBackend describes the capabilities of a server's system clipboard
+// backend. The local peer's accepted_types are derived from mime_types.
+type ClipboardBackend struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// mime_types lists the representations the backend can store; empty means
+	// it stores nothing (headless) unless any_mime_type is set.
+	MimeTypes []string `protobuf:"bytes,2,rep,name=mime_types,json=mimeTypes,proto3" json:"mime_types,omitempty"`
+	// watch is "event" (OS change notifications), "poll", or "none".
+	Watch string `protobuf:"bytes,3,opt,name=watch,proto3" json:"watch,omitempty"`
+	// poll_interval is set when watch is "poll".
+	PollInterval *durationpb.Duration `protobuf:"bytes,4,opt,name=poll_interval,json=pollInterval,proto3" json:"poll_interval,omitempty"`
+	// atomic_write reports that all mime_types are written in one clipboard
+	// transaction; otherwise only the first supported item of an update is kept.
+	AtomicWrite bool `protobuf:"varint,5,opt,name=atomic_write,json=atomicWrite,proto3" json:"atomic_write,omitempty"`
+	// any_mime_type reports that the backend stores every MIME type; mime_types
+	// is then empty.
+	AnyMimeType   bool `protobuf:"varint,6,opt,name=any_mime_type,json=anyMimeType,proto3" json:"any_mime_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClipboardBackend) Reset() {
+	*x = ClipboardBackend{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClipboardBackend) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClipboardBackend) ProtoMessage() {}
+
+func (x *ClipboardBackend) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClipboardBackend.ProtoReflect.Descriptor instead.
+func (*ClipboardBackend) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *ClipboardBackend) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ClipboardBackend) GetMimeTypes() []string {
+	if x != nil {
+		return x.MimeTypes
+	}
+	return nil
+}
+
+func (x *ClipboardBackend) GetWatch() string {
+	if x != nil {
+		return x.Watch
+	}
+	return ""
+}
+
+func (x *ClipboardBackend) GetPollInterval() *durationpb.Duration {
+	if x != nil {
+		return x.PollInterval
+	}
+	return nil
+}
+
+func (x *ClipboardBackend) GetAtomicWrite() bool {
+	if x != nil {
+		return x.AtomicWrite
+	}
+	return false
+}
+
+func (x *ClipboardBackend) GetAnyMimeType() bool {
+	if x != nil {
+		return x.AnyMimeType
+	}
+	return false
+}
+
+// WebhookStats describes deliveries by one server-side webhook since the
+// server started.
+type WebhookStats struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// delivered counts events the endpoint accepted with a 2xx response.
+	Delivered uint64 `protobuf:"varint,1,opt,name=delivered,proto3" json:"delivered,omitempty"`
+	// retried counts attempts repeated after a failed delivery.
+	Retried uint64 `protobuf:"varint,2,opt,name=retried,proto3" json:"retried,omitempty"`
+	// dead_lettered counts events abandoned after the last attempt or a
+	// non-retryable response.
+	DeadLettered uint64 `protobuf:"varint,3,opt,name=dead_lettered,json=deadLettered,proto3" json:"dead_lettered,omitempty"`
+	// queued is the number of events waiting for delivery.
+	Queued uint32 `protobuf:"varint,4,opt,name=queued,proto3" json:"queued,omitempty"`
+	// last_error describes the most recent failed attempt, if any.
+	LastError       string                 `protobuf:"bytes,5,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	LastDeliveredAt *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=last_delivered_at,json=lastDeliveredAt,proto3" json:"last_delivered_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *WebhookStats) Reset() {
+	*x = WebhookStats{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WebhookStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WebhookStats) ProtoMessage() {}
+
+func (x *WebhookStats) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WebhookStats.ProtoReflect.Descriptor instead.
+func (*WebhookStats) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *WebhookStats) GetDelivered() uint64 {
+	if x != nil {
+		return x.Delivered
+	}
+	return 0
+}
+
+func (x *WebhookStats) GetRetried() uint64 {
+	if x != nil {
+		return x.Retried
+	}
+	return 0
+}
+
+func (x *WebhookStats) GetDeadLettered() uint64 {
+	if x != nil {
+		return x.DeadLettered
+	}
+	return 0
+}
+
+func (x *WebhookStats) GetQueued() uint32 {
+	if x != nil {
+		return x.Queued
+	}
+	return 0
+}
+
+func (x *WebhookStats) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *WebhookStats) GetLastDeliveredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastDeliveredAt
+	}
+	return nil
+}
+
+// ProbeStats reports the end-to-end probes a server sends through its
+// federation every interval, and the servers that answered them.
+type ProbeStats struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Interval *durationpb.Duration   `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`
+	Targets  []*ProbeTarget         `protobuf:"bytes,2,rep,name=targets,proto3" json:"targets,omitempty"`
+	// sent counts the probes sent; unanswered those no server answered.
+	Sent          uint64 `protobuf:"varint,3,opt,name=sent,proto3" json:"sent,omitempty"`
+	Unanswered    uint64 `protobuf:"varint,4,opt,name=unanswered,proto3" json:"unanswered,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProbeStats) Reset() {
+	*x = ProbeStats{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbeStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeStats) ProtoMessage() {}
+
+func (x *ProbeStats) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeStats.ProtoReflect.Descriptor instead.
+func (*ProbeStats) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *ProbeStats) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+func (x *ProbeStats) GetTargets() []*ProbeTarget {
+	if x != nil {
+		return x.Targets
+	}
+	return nil
+}
+
+func (x *ProbeStats) GetSent() uint64 {
+	if x != nil {
+		return x.Sent
+	}
+	return 0
+}
+
+func (x *ProbeStats) GetUnanswered() uint64 {
+	if x != nil {
+		return x.Unanswered
+	}
+	return 0
+}
+
+// ProbeTarget is a server that has answered probes.
+type ProbeTarget struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Source string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	// latency is the round trip of the most recent answer.
+	Latency    *durationpb.Duration   `protobuf:"bytes,2,opt,name=latency,proto3" json:"latency,omitempty"`
+	LastAnswer *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=last_answer,json=lastAnswer,proto3" json:"last_answer,omitempty"`
+	// answered and missed count the probes this server answered or not.
+	Answered uint64 `protobuf:"varint,4,opt,name=answered,proto3" json:"answered,omitempty"`
+	Missed   uint64 `protobuf:"varint,5,opt,name=missed,proto3" json:"missed,omitempty"`
+	// failing counts the consecutive probes it has missed.
+	Failing       uint32 `protobuf:"varint,6,opt,name=failing,proto3" json:"failing,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProbeTarget) Reset() {
+	*x = ProbeTarget{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbeTarget) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeTarget) ProtoMessage() {}
+
+func (x *ProbeTarget) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeTarget.ProtoReflect.Descriptor instead.
+func (*ProbeTarget) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *ProbeTarget) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *ProbeTarget) GetLatency() *durationpb.Duration {
+	if x != nil {
+		return x.Latency
+	}
+	return nil
+}
+
+func (x *ProbeTarget) GetLastAnswer() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastAnswer
+	}
+	return nil
+}
+
+func (x *ProbeTarget) GetAnswered() uint64 {
+	if x != nil {
+		return x.Answered
+	}
+	return 0
+}
+
+func (x *ProbeTarget) GetMissed() uint64 {
+	if x != nil {
+		return x.Missed
+	}
+	return 0
+}
+
+func (x *ProbeTarget) GetFailing() uint32 {
+	if x != nil {
+		return x.Failing
+	}
+	return 0
+}
+
+type StatusResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Peers []*PeerInfo            `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	// upstream_info is populated when this server is federated to an upstream:
+	// the first of upstreams. Absent on standalone servers.
+	UpstreamInfo *UpstreamInfo `protobuf:"bytes,2,opt,name=upstream_info,json=upstreamInfo,proto3" json:"upstream_info,omitempty"`
+	// dropped is the cumulative number of discarded events per subsystem
+	// ("watch", "localpeer", "federation-upstream", "federation-downstream",
+	// "federation-outbox", "webhook") since the server started, including
+	// peers that have since disconnected.
+	Dropped map[string]uint64 `protobuf:"bytes,3,rep,name=dropped,proto3" json:"dropped,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// stats carries hub-wide counters since the server started.
+	Stats *HubStats `protobuf:"bytes,4,opt,name=stats,proto3" json:"stats,omitempty"`
+	// usage lists the content moved per clipboard since the server started.
+	Usage []*ClipboardUsage `protobuf:"bytes,5,rep,name=usage,proto3" json:"usage,omitempty"`
+	// upstreams describes every upstream link, in configuration order.
+	Upstreams []*UpstreamInfo `protobuf:"bytes,6,rep,name=upstreams,proto3" json:"upstreams,omitempty"`
+	// quotas lists the sources seen recently by a server with per-source
+	// quotas, ordered by source.
+	Quotas        []*SourceQuota `protobuf:"bytes,7,rep,name=quotas,proto3" json:"quotas,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusResponse) Reset() {
+	*x = StatusResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusResponse) ProtoMessage() {}
+
+func (x *StatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
+func (*StatusResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *StatusResponse) GetPeers() []*PeerInfo {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetUpstreamInfo() *UpstreamInfo {
+	if x != nil {
+		return x.UpstreamInfo
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetDropped() map[string]uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetStats() *HubStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetUsage() []*ClipboardUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetUpstreams() []*UpstreamInfo {
+	if x != nil {
+		return x.Upstreams
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetQuotas() []*SourceQuota {
+	if x != nil {
+		return x.Quotas
+	}
+	return nil
+}
+
+// SourceQuota is one source's standing against the per-source quotas.
+// copies and bytes are the amounts currently counted against the hourly and
+// daily quota, which recover continuously; a limit of 0 is unlimited.
+type SourceQuota struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Source      string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Copies      uint64                 `protobuf:"varint,2,opt,name=copies,proto3" json:"copies,omitempty"`
+	CopiesLimit uint64                 `protobuf:"varint,3,opt,name=copies_limit,json=copiesLimit,proto3" json:"copies_limit,omitempty"`
+	Bytes       uint64                 `protobuf:"varint,4,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	BytesLimit  uint64                 `protobuf:"varint,5,opt,name=bytes_limit,json=bytesLimit,proto3" json:"bytes_limit,omitempty"`
+	// exceeded counts the copies that went over a quota, whether they were
+	// accepted with a warning, throttled or rejected.
+	Exceeded      uint64 `protobuf:"varint,6,opt,name=exceeded,proto3" json:"exceeded,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SourceQuota) Reset() {
+	*x = SourceQuota{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SourceQuota) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SourceQuota) ProtoMessage() {}
+
+func (x *SourceQuota) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SourceQuota.ProtoReflect.Descriptor instead.
+func (*SourceQuota) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *SourceQuota) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *SourceQuota) GetCopies() uint64 {
+	if x != nil {
+		return x.Copies
+	}
+	return 0
+}
+
+func (x *SourceQuota) GetCopiesLimit() uint64 {
+	if x != nil {
+		return x.CopiesLimit
+	}
+	return 0
+}
+
+func (x *SourceQuota) GetBytes() uint64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *SourceQuota) GetBytesLimit() uint64 {
+	if x != nil {
+		return x.BytesLimit
+	}
+	return 0
+}
+
+func (x *SourceQuota) GetExceeded() uint64 {
+	if x != nil {
+		return x.Exceeded
+	}
+	return 0
+}
+
+// ClipboardUsage counts the content moved for one clipboard: bytes_in was
+// published to it, bytes_out delivered to peers or returned by Paste.
+type ClipboardUsage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Clipboard     string                 `protobuf:"bytes,1,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	BytesIn       uint64                 `protobuf:"varint,2,opt,name=bytes_in,json=bytesIn,proto3" json:"bytes_in,omitempty"`
+	BytesOut      uint64                 `protobuf:"varint,3,opt,name=bytes_out,json=bytesOut,proto3" json:"bytes_out,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClipboardUsage) Reset() {
+	*x = ClipboardUsage{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClipboardUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClipboardUsage) ProtoMessage() {}
+
+func (x *ClipboardUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClipboardUsage.ProtoReflect.Descriptor instead.
+func (*ClipboardUsage) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *ClipboardUsage) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *ClipboardUsage) GetBytesIn() uint64 {
+	if x != nil {
+		return x.BytesIn
+	}
+	return 0
+}
+
+func (x *ClipboardUsage) GetBytesOut() uint64 {
+	if x != nil {
+		return x.BytesOut
+	}
+	return 0
+}
+
+// HubStats summarises hub activity without revealing clipboard contents.
+type HubStats struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Publishes      uint64                 `protobuf:"varint,1,opt,name=publishes,proto3" json:"publishes,omitempty"`
+	PublishedBytes uint64                 `protobuf:"varint,2,opt,name=published_bytes,json=publishedBytes,proto3" json:"published_bytes,omitempty"`
+	// clipboards is the number of clipboards currently holding content.
+	Clipboards uint32 `protobuf:"varint,3,opt,name=clipboards,proto3" json:"clipboards,omitempty"`
+	// blobs and blob_bytes describe the blob store of referenced items.
+	Blobs     uint32 `protobuf:"varint,4,opt,name=blobs,proto3" json:"blobs,omitempty"`
+	BlobBytes uint64 `protobuf:"varint,5,opt,name=blob_bytes,json=blobBytes,proto3" json:"blob_bytes,omitempty"`
+	// blobs_pruned counts blobs removed by garbage collection or PruneBlobs.
+	BlobsPruned uint64 `protobuf:"varint,6,opt,name=blobs_pruned,json=blobsPruned,proto3" json:"blobs_pruned,omitempty"`
+	// bytes_in and bytes_out count all clipboard content received and sent,
+	// including blobs fetched from upstream or served by Fetch.
+	BytesIn  uint64 `protobuf:"varint,7,opt,name=bytes_in,json=bytesIn,proto3" json:"bytes_in,omitempty"`
+	BytesOut uint64 `protobuf:"varint,8,opt,name=bytes_out,json=bytesOut,proto3" json:"bytes_out,omitempty"`
+	// duplicates counts publishes suppressed because they repeated a
+	// clipboard's content within the server's dedup window, or because the
+	// same event had already arrived over another federation link.
+	Duplicates uint64 `protobuf:"varint,9,opt,name=duplicates,proto3" json:"duplicates,omitempty"`
+	// hop_limited counts events not relayed over a federation link because
+	// their hop limit was spent.
+	HopLimited uint64 `protobuf:"varint,10,opt,name=hop_limited,json=hopLimited,proto3" json:"hop_limited,omitempty"`
+	// loops counts events from federation links dropped because their path
+	// already passed through this server.
+	Loops uint64 `protobuf:"varint,11,opt,name=loops,proto3" json:"loops,omitempty"`
+	// oversized counts publishes refused for exceeding the server's item or
+	// payload size limit, from clients, federation links and the local
+	// clipboard alike.
+	Oversized uint64 `protobuf:"varint,12,opt,name=oversized,proto3" json:"oversized,omitempty"`
+	// heap_bytes and goroutines describe the server process: the memory held
+	// by live heap objects and the number of goroutines running.
+	HeapBytes     uint64 `protobuf:"varint,13,opt,name=heap_bytes,json=heapBytes,proto3" json:"heap_bytes,omitempty"`
+	Goroutines    uint32 `protobuf:"varint,14,opt,name=goroutines,proto3" json:"goroutines,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HubStats) Reset() {
+	*x = HubStats{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HubStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HubStats) ProtoMessage() {}
+
+func (x *HubStats) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HubStats.ProtoReflect.Descriptor instead.
+func (*HubStats) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *HubStats) GetPublishes() uint64 {
+	if x != nil {
+		return x.Publishes
+	}
+	return 0
+}
+
+func (x *HubStats) GetPublishedBytes() uint64 {
+	if x != nil {
+		return x.PublishedBytes
+	}
+	return 0
+}
+
+func (x *HubStats) GetClipboards() uint32 {
+	if x != nil {
+		return x.Clipboards
+	}
+	return 0
+}
+
+func (x *HubStats) GetBlobs() uint32 {
+	if x != nil {
+		return x.Blobs
+	}
+	return 0
+}
+
+func (x *HubStats) GetBlobBytes() uint64 {
+	if x != nil {
+		return x.BlobBytes
+	}
+	return 0
+}
+
+func (x *HubStats) GetBlobsPruned() uint64 {
+	if x != nil {
+		return x.BlobsPruned
+	}
+	return 0
+}
+
+func (x *HubStats) GetBytesIn() uint64 {
+	if x != nil {
+		return x.BytesIn
+	}
+	return 0
+}
+
+func (x *HubStats) GetBytesOut() uint64 {
+	if x != nil {
+		return x.BytesOut
+	}
+	return 0
+}
+
+func (x *HubStats) GetDuplicates() uint64 {
+	if x != nil {
+		return x.Duplicates
+	}
+	return 0
+}
+
+func (x *HubStats) GetHopLimited() uint64 {
+	if x != nil {
+		return x.HopLimited
+	}
+	return 0
+}
+
+func (x *HubStats) GetLoops() uint64 {
+	if x != nil {
+		return x.Loops
+	}
+	return 0
+}
+
+func (x *HubStats) GetOversized() uint64 {
+	if x != nil {
+		return x.Oversized
+	}
+	return 0
+}
+
+func (x *HubStats) GetHeapBytes() uint64 {
+	if x != nil {
+		return x.HeapBytes
+	}
+	return 0
+}
+
+func (x *HubStats) GetGoroutines() uint32 {
+	if x != nil {
+		return x.Goroutines
+	}
+	return 0
+}
+
+// UpstreamInfo carries federation connection metadata, allowing CLI tools to
+// display upstream server and connection state in status output.
+type UpstreamInfo struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Addr        string                 `protobuf:"bytes,1,opt,name=addr,proto3" json:"addr,omitempty"`
+	Source      string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	ConnectedAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=connected_at,json=connectedAt,proto3" json:"connected_at,omitempty"`
+	LastSeen    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	// deferring is why non-text items are currently held back on the link
+	// (e.g. "schedule 09:00-17:00", "metered connection"); empty when they
+	// are not.
+	Deferring     string `protobuf:"bytes,5,opt,name=deferring,proto3" json:"deferring,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpstreamInfo) Reset() {
+	*x = UpstreamInfo{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpstreamInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpstreamInfo) ProtoMessage() {}
+
+func (x *UpstreamInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpstreamInfo.ProtoReflect.Descriptor instead.
+func (*UpstreamInfo) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *UpstreamInfo) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *UpstreamInfo) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *UpstreamInfo) GetConnectedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ConnectedAt
+	}
+	return nil
+}
+
+func (x *UpstreamInfo) GetLastSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeen
+	}
+	return nil
+}
+
+func (x *UpstreamInfo) GetDeferring() string {
+	if x != nil {
+		return x.Deferring
+	}
+	return ""
+}
+
+// FederateMessage is exchanged in both directions on a Federate stream.
+type FederateMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Msg:
+	//
+	//	*FederateMessage_Event
+	//	*FederateMessage_Ack
+	//	*FederateMessage_Subscribe
+	Msg           isFederateMessage_Msg `protobuf_oneof:"msg"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FederateMessage) Reset() {
+	*x = FederateMessage{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FederateMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederateMessage) ProtoMessage() {}
+
+func (x *FederateMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederateMessage.ProtoReflect.Descriptor instead.
+func (*FederateMessage) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *FederateMessage) GetMsg() isFederateMessage_Msg {
+	if x != nil {
+		return x.Msg
+	}
+	return nil
+}
+
+func (x *FederateMessage) GetEvent() *FederationEvent {
+	if x != nil {
+		if x, ok := x.Msg.(*FederateMessage_Event); ok {
+			return x.Event
+		}
+	}
+	return nil
+}
+
+func (x *FederateMessage) GetAck() *FederationAck {
+	if x != nil {
+		if x, ok := x.Msg.(*FederateMessage_Ack); ok {
+			return x.Ack
+		}
+	}
+	return nil
+}
+
+func (x *FederateMessage) GetSubscribe() *FederationSubscribe {
+	if x != nil {
+		if x, ok := x.Msg.(*FederateMessage_Subscribe); ok {
+			return x.Subscribe
+		}
+	}
+	return nil
+}
+
+type isFederateMessage_Msg interface {
+	isFederateMessage_Msg()
+}
+
+type FederateMessage_Event struct {
+	Event *FederationEvent `protobuf:"bytes,1,opt,name=event,proto3,oneof"`
+}
+
+type FederateMessage_Ack struct {
+	Ack *FederationAck `protobuf:"bytes,2,opt,name=ack,proto3,oneof"`
+}
+
+type FederateMessage_Subscribe struct {
+	// subscribe is only sent downstream → upstream.
+	Subscribe *FederationSubscribe `protobuf:"bytes,3,opt,name=subscribe,proto3,oneof"`
+}
+
+func (*FederateMessage_Event) isFederateMessage_Msg() {}
+
+func (*FederateMessage_Ack) isFederateMessage_Msg() {}
+
+func (*FederateMessage_Subscribe) isFederateMessage_Msg() {}
+
+// FederationEvent carries one clipboard update between federated servers.
+type FederationEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id is assigned by the sender, unique per stream, and echoed in the
+	// receiver's FederationAck.
+	Id        uint64           `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Source    string           `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	Clipboard string           `protobuf:"bytes,3,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	Items     []*ClipboardItem `protobuf:"bytes,4,rep,name=items,proto3" json:"items,omitempty"`
+	// hop_limit is how many federation links the event may still cross,
+	// including this one. The receiver decrements it, caps it at its own
+	// maximum, and relays the event no further once it reaches zero. Zero
+	// from servers that predate hop limits counts as the receiver's maximum.
+	HopLimit uint32 `protobuf:"varint,5,opt,name=hop_limit,json=hopLimit,proto3" json:"hop_limit,omitempty"`
+	// event_id identifies the original publish on every link the event
+	// crosses, so a server reached over two paths publishes it once. Empty
+	// when stored content is resent on subscription, and from servers that
+	// predate event IDs.
+	EventId string `protobuf:"bytes,6,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	// path lists the sources of the servers the event has been published on,
+	// oldest first, ending with the sender. A server that finds its own
+	// source in it drops the event, ending a cycle after a single round.
+	// Empty from servers that predate origin paths.
+	Path []string `protobuf:"bytes,7,rep,name=path,proto3" json:"path,omitempty"`
+	// trace_context carries the W3C Trace Context headers (traceparent,
+	// tracestate) of the sender's span forwarding the event, so the receiver
+	// continues its trace. Empty when the sender does not export traces.
+	TraceContext  map[string]string `protobuf:"bytes,8,rep,name=trace_context,json=traceContext,proto3" json:"trace_context,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FederationEvent) Reset() {
+	*x = FederationEvent{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FederationEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederationEvent) ProtoMessage() {}
+
+func (x *FederationEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederationEvent.ProtoReflect.Descriptor instead.
+func (*FederationEvent) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *FederationEvent) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *FederationEvent) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *FederationEvent) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *FederationEvent) GetItems() []*ClipboardItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *FederationEvent) GetHopLimit() uint32 {
+	if x != nil {
+		return x.HopLimit
+	}
+	return 0
+}
+
+func (x *FederationEvent) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *FederationEvent) GetPath() []string {
+	if x != nil {
+		return x.Path
+	}
+	return nil
+}
+
+func (x *FederationEvent) GetTraceContext() map[string]string {
+	if x != nil {
+		return x.TraceContext
+	}
+	return nil
+}
+
+// FederationAck confirms that the receiver published the event with this id.
+type FederationAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FederationAck) Reset() {
+	*x = FederationAck{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FederationAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederationAck) ProtoMessage() {}
+
+func (x *FederationAck) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederationAck.ProtoReflect.Descriptor instead.
+func (*FederationAck) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *FederationAck) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// FederationSubscribe replaces the set of clipboards the downstream wants
+// from the upstream.
+type FederationSubscribe struct {
+	state      protoimpl.MessageState   `protogen:"open.v1"`
+	Clipboards []*ClipboardSubscription `protobuf:"bytes,1,rep,name=clipboards,proto3" json:"clipboards,omitempty"`
+	// accept_refs tells the upstream that the downstream fetches large items
+	// with Fetch, so they may be sent as blob references.
+	AcceptRefs bool `protobuf:"varint,2,opt,name=accept_refs,json=acceptRefs,proto3" json:"accept_refs,omitempty"`
+	// defer_non_text asks the upstream to send non-text items as blob
+	// references only, so the downstream receives their metadata and can put
+	// off the transfer. Clearing it makes the upstream resend the clipboards
+	// whose last event had items deferred.
+	DeferNonText  bool `protobuf:"varint,3,opt,name=defer_non_text,json=deferNonText,proto3" json:"defer_non_text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FederationSubscribe) Reset() {
+	*x = FederationSubscribe{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FederationSubscribe) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederationSubscribe) ProtoMessage() {}
+
+func (x *FederationSubscribe) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederationSubscribe.ProtoReflect.Descriptor instead.
+func (*FederationSubscribe) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *FederationSubscribe) GetClipboards() []*ClipboardSubscription {
+	if x != nil {
+		return x.Clipboards
+	}
+	return nil
+}
+
+func (x *FederationSubscribe) GetAcceptRefs() bool {
+	if x != nil {
+		return x.AcceptRefs
+	}
+	return false
+}
+
+func (x *FederationSubscribe) GetDeferNonText() bool {
+	if x != nil {
+		return x.DeferNonText
+	}
+	return false
+}
+
+// ClipboardSubscription selects one clipboard and the MIME types wanted from
+// it (empty accepts = all types).
+type ClipboardSubscription struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Clipboard     string                 `protobuf:"bytes,1,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	Accepts       []string               `protobuf:"bytes,2,rep,name=accepts,proto3" json:"accepts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClipboardSubscription) Reset() {
+	*x = ClipboardSubscription{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClipboardSubscription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClipboardSubscription) ProtoMessage() {}
+
+func (x *ClipboardSubscription) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClipboardSubscription.ProtoReflect.Descriptor instead.
+func (*ClipboardSubscription) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *ClipboardSubscription) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *ClipboardSubscription) GetAccepts() []string {
+	if x != nil {
+		return x.Accepts
+	}
+	return nil
+}
+
+type FederationStatusRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// path lists the sources of the servers the probe has already passed
+	// through, so a cycle in the federation topology ends it.
+	Path          []string `protobuf:"bytes,1,rep,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FederationStatusRequest) Reset() {
+	*x = FederationStatusRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FederationStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederationStatusRequest) ProtoMessage() {}
+
+func (x *FederationStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederationStatusRequest.ProtoReflect.Descriptor instead.
+func (*FederationStatusRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *FederationStatusRequest) GetPath() []string {
+	if x != nil {
+		return x.Path
+	}
+	return nil
+}
+
+type FederationStatusResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// nodes describes the server answering first, then the servers above it
+	// through each of its upstreams, depth first.
+	Nodes         []*FederationNode `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FederationStatusResponse) Reset() {
+	*x = FederationStatusResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FederationStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederationStatusResponse) ProtoMessage() {}
+
+func (x *FederationStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederationStatusResponse.ProtoReflect.Descriptor instead.
+func (*FederationStatusResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *FederationStatusResponse) GetNodes() []*FederationNode {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+// FederationNode summarises one server of a federation tree. Nodes are listed
+// depth first: each server is followed by the servers above it, one subtree
+// per upstream link.
+type FederationNode struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Source  string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Version string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	// peers counts the connected peers per role ("both", "client",
+	// "downstream", "upstream", "webhook").
+	Peers map[string]uint32 `protobuf:"bytes,3,rep,name=peers,proto3" json:"peers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Stats *HubStats         `protobuf:"bytes,4,opt,name=stats,proto3" json:"stats,omitempty"`
+	// upstream_info describes the link over which this node was reached from
+	// the node that probed it; absent on the first node.
+	UpstreamInfo *UpstreamInfo `protobuf:"bytes,5,opt,name=upstream_info,json=upstreamInfo,proto3" json:"upstream_info,omitempty"`
+	// error explains why the tree ends at this node: the upstream could not
+	// be probed (source is then empty), or the probe returned to a server it
+	// had passed.
+	Error string `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	// hops is the number of links between this node and the first one.
+	Hops          uint32 `protobuf:"varint,7,opt,name=hops,proto3" json:"hops,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FederationNode) Reset() {
+	*x = FederationNode{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FederationNode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederationNode) ProtoMessage() {}
+
+func (x *FederationNode) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederationNode.ProtoReflect.Descriptor instead.
+func (*FederationNode) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *FederationNode) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *FederationNode) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *FederationNode) GetPeers() map[string]uint32 {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+func (x *FederationNode) GetStats() *HubStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+func (x *FederationNode) GetUpstreamInfo() *UpstreamInfo {
+	if x != nil {
+		return x.UpstreamInfo
+	}
+	return nil
+}
+
+func (x *FederationNode) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *FederationNode) GetHops() uint32 {
+	if x != nil {
+		return x.Hops
+	}
+	return 0
+}
+
+type ClearRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// clipboards lists the clipboards to clear. Ignored when all is set.
+	Clipboards []string `protobuf:"bytes,1,rep,name=clipboards,proto3" json:"clipboards,omitempty"`
+	// all clears every clipboard known to the hub.
+	All           bool `protobuf:"varint,2,opt,name=all,proto3" json:"all,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClearRequest) Reset() {
+	*x = ClearRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClearRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearRequest) ProtoMessage() {}
+
+func (x *ClearRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearRequest.ProtoReflect.Descriptor instead.
+func (*ClearRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *ClearRequest) GetClipboards() []string {
+	if x != nil {
+		return x.Clipboards
+	}
+	return nil
+}
+
+func (x *ClearRequest) GetAll() bool {
+	if x != nil {
+		return x.All
+	}
+	return false
+}
+
+type ClearResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// cleared lists the clipboards that held content and were cleared.
+	Cleared       []string `protobuf:"bytes,1,rep,name=cleared,proto3" json:"cleared,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClearResponse) Reset() {
+	*x = ClearResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClearResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearResponse) ProtoMessage() {}
+
+func (x *ClearResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearResponse.ProtoReflect.Descriptor instead.
+func (*ClearResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *ClearResponse) GetCleared() []string {
+	if x != nil {
+		return x.Cleared
+	}
+	return nil
+}
+
+type RotateTokenRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// token becomes the new primary secret. Must not be empty.
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// grace is how long the previous primary stays valid. Zero revokes it
+	// immediately, disconnecting clients that have not switched.
+	Grace         *durationpb.Duration `protobuf:"bytes,2,opt,name=grace,proto3" json:"grace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateTokenRequest) Reset() {
+	*x = RotateTokenRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateTokenRequest) ProtoMessage() {}
+
+func (x *RotateTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateTokenRequest.ProtoReflect.Descriptor instead.
+func (*RotateTokenRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *RotateTokenRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *RotateTokenRequest) GetGrace() *durationpb.Duration {
+	if x != nil {
+		return x.Grace
+	}
+	return nil
+}
+
+type RotateTokenResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// previous_expires_at is when the previous token stops being accepted.
+	PreviousExpiresAt *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=previous_expires_at,json=previousExpiresAt,proto3" json:"previous_expires_at,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *RotateTokenResponse) Reset() {
+	*x = RotateTokenResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateTokenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateTokenResponse) ProtoMessage() {}
+
+func (x *RotateTokenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateTokenResponse.ProtoReflect.Descriptor instead.
+func (*RotateTokenResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *RotateTokenResponse) GetPreviousExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PreviousExpiresAt
+	}
+	return nil
+}
+
+type PruneBlobsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// unused_for spares unreferenced blobs stored or fetched more recently, for
+	// peers that received a reference just before it was replaced. Zero prunes
+	// every unreferenced blob.
+	UnusedFor *durationpb.Duration `protobuf:"bytes,1,opt,name=unused_for,json=unusedFor,proto3" json:"unused_for,omitempty"`
+	// dry_run reports what would be pruned without removing anything.
+	DryRun        bool `protobuf:"varint,2,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PruneBlobsRequest) Reset() {
+	*x = PruneBlobsRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PruneBlobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PruneBlobsRequest) ProtoMessage() {}
+
+func (x *PruneBlobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PruneBlobsRequest.ProtoReflect.Descriptor instead.
+func (*PruneBlobsRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *PruneBlobsRequest) GetUnusedFor() *durationpb.Duration {
+	if x != nil {
+		return x.UnusedFor
+	}
+	return nil
+}
+
+func (x *PruneBlobsRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type PruneBlobsResponse struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Pruned      uint32                 `protobuf:"varint,1,opt,name=pruned,proto3" json:"pruned,omitempty"`
+	PrunedBytes uint64                 `protobuf:"varint,2,opt,name=pruned_bytes,json=prunedBytes,proto3" json:"pruned_bytes,omitempty"`
+	// remaining and remaining_bytes describe the store afterwards.
+	Remaining      uint32 `protobuf:"varint,3,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	RemainingBytes uint64 `protobuf:"varint,4,opt,name=remaining_bytes,json=remainingBytes,proto3" json:"remaining_bytes,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PruneBlobsResponse) Reset() {
+	*x = PruneBlobsResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[56]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PruneBlobsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PruneBlobsResponse) ProtoMessage() {}
+
+func (x *PruneBlobsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[56]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PruneBlobsResponse.ProtoReflect.Descriptor instead.
+func (*PruneBlobsResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{56}
+}
+
+func (x *PruneBlobsResponse) GetPruned() uint32 {
+	if x != nil {
+		return x.Pruned
+	}
+	return 0
+}
+
+func (x *PruneBlobsResponse) GetPrunedBytes() uint64 {
+	if x != nil {
+		return x.PrunedBytes
+	}
+	return 0
+}
+
+func (x *PruneBlobsResponse) GetRemaining() uint32 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+func (x *PruneBlobsResponse) GetRemainingBytes() uint64 {
+	if x != nil {
+		return x.RemainingBytes
+	}
+	return 0
+}
+
+type JournalRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// since skips older entries.
+	Since *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=since,proto3" json:"since,omitempty"`
+	// source keeps entries published by or delivered to this source.
+	Source    string `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	Clipboard string `protobuf:"bytes,3,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	// event_id keeps entries whose event ID starts with it.
+	EventId string `protobuf:"bytes,4,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	// limit keeps the most recent entries (default 100).
+	Limit         uint32 `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JournalRequest) Reset() {
+	*x = JournalRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JournalRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JournalRequest) ProtoMessage() {}
+
+func (x *JournalRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JournalRequest.ProtoReflect.Descriptor instead.
+func (*JournalRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *JournalRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *JournalRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *JournalRequest) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *JournalRequest) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *JournalRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type JournalResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// entries are ordered oldest first.
+	Entries       []*JournalEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JournalResponse) Reset() {
+	*x = JournalResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JournalResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JournalResponse) ProtoMessage() {}
+
+func (x *JournalResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[58]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JournalResponse.ProtoReflect.Descriptor instead.
+func (*JournalResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{58}
+}
+
+func (x *JournalResponse) GetEntries() []*JournalEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// JournalEntry records what a server did with a publish: kind "publish" with
+// an outcome of stored, duplicate, loop, oversized or refused, or kind
+// "deliver" for one peer with an outcome of delivered, dropped, filtered,
+// hop-limit or unavailable.
+type JournalEntry struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Time      *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Kind      string                 `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	EventId   string                 `protobuf:"bytes,3,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	Clipboard string                 `protobuf:"bytes,4,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
+	// source published the content.
+	Source string `protobuf:"bytes,5,opt,name=source,proto3" json:"source,omitempty"`
+	// peer is the peer the content came from or was delivered to, and
+	// peer_source that peer's source name.
+	Peer       string   `protobuf:"bytes,6,opt,name=peer,proto3" json:"peer,omitempty"`
+	PeerSource string   `protobuf:"bytes,7,opt,name=peer_source,json=peerSource,proto3" json:"peer_source,omitempty"`
+	MimeTypes  []string `protobuf:"bytes,8,rep,name=mime_types,json=mimeTypes,proto3" json:"mime_types,omitempty"`
+	Size       uint64   `protobuf:"varint,9,opt,name=size,proto3" json:"size,omitempty"`
+	Outcome    string   `protobuf:"bytes,10,opt,name=outcome,proto3" json:"outcome,omitempty"`
+	// detail explains a refusal or failure.
+	Detail        string `protobuf:"bytes,11,opt,name=detail,proto3" json:"detail,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JournalEntry) Reset() {
+	*x = JournalEntry{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[59]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JournalEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JournalEntry) ProtoMessage() {}
+
+func (x *JournalEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[59]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JournalEntry.ProtoReflect.Descriptor instead.
+func (*JournalEntry) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{59}
+}
+
+func (x *JournalEntry) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *JournalEntry) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *JournalEntry) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *JournalEntry) GetClipboard() string {
+	if x != nil {
+		return x.Clipboard
+	}
+	return ""
+}
+
+func (x *JournalEntry) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *JournalEntry) GetPeer() string {
+	if x != nil {
+		return x.Peer
+	}
+	return ""
+}
+
+func (x *JournalEntry) GetPeerSource() string {
+	if x != nil {
+		return x.PeerSource
+	}
+	return ""
+}
+
+func (x *JournalEntry) GetMimeTypes() []string {
+	if x != nil {
+		return x.MimeTypes
+	}
+	return nil
+}
+
+func (x *JournalEntry) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *JournalEntry) GetOutcome() string {
+	if x != nil {
+		return x.Outcome
+	}
+	return ""
+}
+
+func (x *JournalEntry) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+type ProfileRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// profiles lists the profiles to capture: "cpu" or any runtime/pprof
+	// profile ("heap", "goroutine", "allocs", "block", "mutex", …). Empty
+	// captures cpu, heap, and goroutine.
+	Profiles []string `protobuf:"bytes,1,rep,name=profiles,proto3" json:"profiles,omitempty"`
+	// duration is how long the CPU profile samples; other profiles are
+	// snapshots taken when it ends.
+	Duration      *durationpb.Duration `protobuf:"bytes,2,opt,name=duration,proto3" json:"duration,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProfileRequest) Reset() {
+	*x = ProfileRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[60]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProfileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProfileRequest) ProtoMessage() {}
+
+func (x *ProfileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[60]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProfileRequest.ProtoReflect.Descriptor instead.
+func (*ProfileRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{60}
+}
+
+func (x *ProfileRequest) GetProfiles() []string {
+	if x != nil {
+		return x.Profiles
+	}
+	return nil
+}
+
+func (x *ProfileRequest) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+type ProfileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Profiles      []*Profile             `protobuf:"bytes,1,rep,name=profiles,proto3" json:"profiles,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProfileResponse) Reset() {
+	*x = ProfileResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[61]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProfileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProfileResponse) ProtoMessage() {}
+
+func (x *ProfileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[61]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProfileResponse.ProtoReflect.Descriptor instead.
+func (*ProfileResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{61}
+}
+
+func (x *ProfileResponse) GetProfiles() []*Profile {
+	if x != nil {
+		return x.Profiles
+	}
+	return nil
+}
+
+// Profile is one captured profile in gzipped pprof format.
+type Profile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Profile) Reset() {
+	*x = Profile{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[62]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Profile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Profile) ProtoMessage() {}
+
+func (x *Profile) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[62]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Profile.ProtoReflect.Descriptor instead.
+func (*Profile) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{62}
+}
+
+func (x *Profile) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Profile) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type ReloadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReloadRequest) Reset() {
+	*x = ReloadRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[63]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReloadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReloadRequest) ProtoMessage() {}
+
+func (x *ReloadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[63]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReloadRequest.ProtoReflect.Descriptor instead.
+func (*ReloadRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{63}
+}
+
+type ReloadResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// changed names the settings whose values changed, e.g. "log-level"; empty
+	// when the configuration was unchanged.
+	Changed       []string `protobuf:"bytes,1,rep,name=changed,proto3" json:"changed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReloadResponse) Reset() {
+	*x = ReloadResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[64]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReloadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReloadResponse) ProtoMessage() {}
+
+func (x *ReloadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[64]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReloadResponse.ProtoReflect.Descriptor instead.
+func (*ReloadResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{64}
+}
+
+func (x *ReloadResponse) GetChanged() []string {
+	if x != nil {
+		return x.Changed
+	}
+	return nil
+}
+
+type ShutdownRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// restart starts the server again, rereading its configuration, once it
+	// has stopped.
+	Restart       bool `protobuf:"varint,1,opt,name=restart,proto3" json:"restart,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShutdownRequest) Reset() {
+	*x = ShutdownRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[65]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShutdownRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShutdownRequest) ProtoMessage() {}
+
+func (x *ShutdownRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[65]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShutdownRequest.ProtoReflect.Descriptor instead.
+func (*ShutdownRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{65}
+}
+
+func (x *ShutdownRequest) GetRestart() bool {
+	if x != nil {
+		return x.Restart
+	}
+	return false
+}
+
+type ShutdownResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// pid is the process ID of the server; a restarted server keeps it.
+	Pid           int64 `protobuf:"varint,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShutdownResponse) Reset() {
+	*x = ShutdownResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[66]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShutdownResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShutdownResponse) ProtoMessage() {}
+
+func (x *ShutdownResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[66]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShutdownResponse.ProtoReflect.Descriptor instead.
+func (*ShutdownResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{66}
+}
+
+func (x *ShutdownResponse) GetPid() int64 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+type StartPairingRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// name identifies the issued token in logs; empty takes the name the
+	// device pairs with.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// role of the issued token: "admin", "read-write" or "read-only".
+	Role string `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`
+	// clipboards limits the issued token to clipboards matching these
+	// path.Match patterns; empty allows every clipboard.
+	Clipboards []string `protobuf:"bytes,3,rep,name=clipboards,proto3" json:"clipboards,omitempty"`
+	// ttl is how long the code is valid. Zero means five minutes.
+	Ttl           *durationpb.Duration `protobuf:"bytes,4,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartPairingRequest) Reset() {
+	*x = StartPairingRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[67]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartPairingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartPairingRequest) ProtoMessage() {}
+
+func (x *StartPairingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[67]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartPairingRequest.ProtoReflect.Descriptor instead.
+func (*StartPairingRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{67}
+}
+
+func (x *StartPairingRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *StartPairingRequest) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *StartPairingRequest) GetClipboards() []string {
+	if x != nil {
+		return x.Clipboards
+	}
+	return nil
+}
+
+func (x *StartPairingRequest) GetTtl() *durationpb.Duration {
+	if x != nil {
+		return x.Ttl
+	}
+	return nil
+}
+
+type StartPairingResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// code is the six-digit pairing code.
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartPairingResponse) Reset() {
+	*x = StartPairingResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[68]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartPairingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartPairingResponse) ProtoMessage() {}
+
+func (x *StartPairingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[68]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartPairingResponse.ProtoReflect.Descriptor instead.
+func (*StartPairingResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{68}
+}
+
+func (x *StartPairingResponse) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *StartPairingResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+type ListDevicesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDevicesRequest) Reset() {
+	*x = ListDevicesRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[69]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesRequest) ProtoMessage() {}
+
+func (x *ListDevicesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[69]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesRequest.ProtoReflect.Descriptor instead.
+func (*ListDevicesRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{69}
+}
+
+type ListDevicesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Devices       []*Device              `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDevicesResponse) Reset() {
+	*x = ListDevicesResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[70]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesResponse) ProtoMessage() {}
+
+func (x *ListDevicesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[70]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesResponse.ProtoReflect.Descriptor instead.
+func (*ListDevicesResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{70}
+}
+
+func (x *ListDevicesResponse) GetDevices() []*Device {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+// Device is a device paired with StartPairing.
+type Device struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Name       string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Role       string                 `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`
+	Clipboards []string               `protobuf:"bytes,3,rep,name=clipboards,proto3" json:"clipboards,omitempty"`
+	// fingerprint identifies the TLS key derived from the device's token.
+	Fingerprint string                 `protobuf:"bytes,4,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	PairedAt    *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=paired_at,json=pairedAt,proto3" json:"paired_at,omitempty"`
+	// last_seen is when the device's token was last accepted since the server
+	// started; unset if it has not been.
+	LastSeen      *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Device) Reset() {
+	*x = Device{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[71]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Device) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Device) ProtoMessage() {}
+
+func (x *Device) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[71]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Device.ProtoReflect.Descriptor instead.
+func (*Device) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{71}
+}
+
+func (x *Device) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Device) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *Device) GetClipboards() []string {
+	if x != nil {
+		return x.Clipboards
+	}
+	return nil
+}
+
+func (x *Device) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *Device) GetPairedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PairedAt
+	}
+	return nil
+}
+
+func (x *Device) GetLastSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeen
+	}
+	return nil
+}
+
+type RevokeDeviceRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// device is the name or fingerprint of the device to revoke.
+	Device        string `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeDeviceRequest) Reset() {
+	*x = RevokeDeviceRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[72]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PeerInfo) String() string {
+func (x *RevokeDeviceRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PeerInfo) ProtoMessage() {}
+func (*RevokeDeviceRequest) ProtoMessage() {}
 
-func (x *PeerInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[8]
+func (x *RevokeDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[72]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -501,85 +4761,215 @@ func (x *PeerInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PeerInfo.ProtoReflect.Descriptor instead.
-func (*PeerInfo) Descriptor() ([]byte, []int) {
-	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use RevokeDeviceRequest.ProtoReflect.Descriptor instead.
+func (*RevokeDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{72}
 }
 
-func (x *PeerInfo) GetSource() string {
+func (x *RevokeDeviceRequest) GetDevice() string {
 	if x != nil {
-		return x.Source
+		return x.Device
 	}
 	return ""
 }
 
-func (x *PeerInfo) GetAddr() string {
+type RevokeDeviceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Device        *Device                `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeDeviceResponse) Reset() {
+	*x = RevokeDeviceResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[73]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeDeviceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeDeviceResponse) ProtoMessage() {}
+
+func (x *RevokeDeviceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[73]
 	if x != nil {
-		return x.Addr
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *PeerInfo) GetRole() string {
+// Deprecated: Use RevokeDeviceResponse.ProtoReflect.Descriptor instead.
+func (*RevokeDeviceResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{73}
+}
+
+func (x *RevokeDeviceResponse) GetDevice() *Device {
 	if x != nil {
-		return x.Role
+		return x.Device
 	}
-	return ""
+	return nil
 }
 
-func (x *PeerInfo) GetClipboard() string {
+// PairRequest is a device's side of ClipboardService.Pair: first its name
+// and key share, then its confirmation.
+type PairRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Device string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	// share is the device's SPAKE2 key share, a compressed P-256 point.
+	Share []byte `protobuf:"bytes,2,opt,name=share,proto3" json:"share,omitempty"`
+	// confirm is the device's key confirmation MAC.
+	Confirm       []byte `protobuf:"bytes,3,opt,name=confirm,proto3" json:"confirm,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PairRequest) Reset() {
+	*x = PairRequest{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[74]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PairRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PairRequest) ProtoMessage() {}
+
+func (x *PairRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[74]
 	if x != nil {
-		return x.Clipboard
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PairRequest.ProtoReflect.Descriptor instead.
+func (*PairRequest) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{74}
+}
+
+func (x *PairRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
 	}
 	return ""
 }
 
-func (x *PeerInfo) GetAcceptedTypes() []string {
+func (x *PairRequest) GetShare() []byte {
 	if x != nil {
-		return x.AcceptedTypes
+		return x.Share
 	}
 	return nil
 }
 
-func (x *PeerInfo) GetConnectedAt() *timestamppb.Timestamp {
+func (x *PairRequest) GetConfirm() []byte {
 	if x != nil {
-		return x.ConnectedAt
+		return x.Confirm
 	}
 	return nil
 }
 
-func (x *PeerInfo) GetLastSeen() *timestamppb.Timestamp {
+// PairResponse is the server's side of ClipboardService.Pair: first its key
+// share and confirmation, then the sealed credential.
+type PairResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Share   []byte                 `protobuf:"bytes,1,opt,name=share,proto3" json:"share,omitempty"`
+	Confirm []byte                 `protobuf:"bytes,2,opt,name=confirm,proto3" json:"confirm,omitempty"`
+	// credential is the issued token as JSON, sealed with AES-GCM under the
+	// exchanged key, nonce first.
+	Credential    []byte `protobuf:"bytes,3,opt,name=credential,proto3" json:"credential,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PairResponse) Reset() {
+	*x = PairResponse{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[75]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PairResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PairResponse) ProtoMessage() {}
+
+func (x *PairResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[75]
 	if x != nil {
-		return x.LastSeen
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PairResponse.ProtoReflect.Descriptor instead.
+func (*PairResponse) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{75}
+}
+
+func (x *PairResponse) GetShare() []byte {
+	if x != nil {
+		return x.Share
 	}
 	return nil
 }
 
-type StatusResponse struct {
-	state protoimpl.MessageState `protogen:"open.v1"`
-	Peers []*PeerInfo            `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
-	// upstream_info is populated when this server is federated to an upstream.
-	// Absent on standalone servers.
-	UpstreamInfo  *UpstreamInfo `protobuf:"bytes,2,opt,name=upstream_info,json=upstreamInfo,proto3" json:"upstream_info,omitempty"`
+func (x *PairResponse) GetConfirm() []byte {
+	if x != nil {
+		return x.Confirm
+	}
+	return nil
+}
+
+func (x *PairResponse) GetCredential() []byte {
+	if x != nil {
+		return x.Credential
+	}
+	return nil
+}
+
+// SealedItems is the plaintext of an end-to-end encrypted clipboard update.
+// Clients marshal and encrypt it, then publish the ciphertext as a single
+// ClipboardItem of type "application/x-suffuse-e2e"; servers never see it.
+type SealedItems struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*ClipboardItem       `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StatusResponse) Reset() {
-	*x = StatusResponse{}
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[9]
+func (x *SealedItems) Reset() {
+	*x = SealedItems{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[76]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StatusResponse) String() string {
+func (x *SealedItems) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StatusResponse) ProtoMessage() {}
+func (*SealedItems) ProtoMessage() {}
 
-func (x *StatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[9]
+func (x *SealedItems) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[76]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -590,52 +4980,89 @@ func (x *StatusResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
-func (*StatusResponse) Descriptor() ([]byte, []int) {
-	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use SealedItems.ProtoReflect.Descriptor instead.
+func (*SealedItems) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{76}
 }
 
-func (x *StatusResponse) GetPeers() []*PeerInfo {
+func (x *SealedItems) GetItems() []*ClipboardItem {
 	if x != nil {
-		return x.Peers
+		return x.Items
 	}
 	return nil
 }
 
-func (x *StatusResponse) GetUpstreamInfo() *UpstreamInfo {
+// ClipboardCache is the plaintext of a server's encrypted on-disk cache of
+// recent clipboard contents, restored when the server starts.
+type ClipboardCache struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Clipboards    []*CachedClipboard     `protobuf:"bytes,1,rep,name=clipboards,proto3" json:"clipboards,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClipboardCache) Reset() {
+	*x = ClipboardCache{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[77]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClipboardCache) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClipboardCache) ProtoMessage() {}
+
+func (x *ClipboardCache) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[77]
 	if x != nil {
-		return x.UpstreamInfo
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClipboardCache.ProtoReflect.Descriptor instead.
+func (*ClipboardCache) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{77}
+}
+
+func (x *ClipboardCache) GetClipboards() []*CachedClipboard {
+	if x != nil {
+		return x.Clipboards
 	}
 	return nil
 }
 
-// UpstreamInfo carries federation connection metadata, allowing CLI tools to
-// display upstream server and connection state in status output.
-type UpstreamInfo struct {
+type CachedClipboard struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Addr          string                 `protobuf:"bytes,1,opt,name=addr,proto3" json:"addr,omitempty"`
+	Clipboard     string                 `protobuf:"bytes,1,opt,name=clipboard,proto3" json:"clipboard,omitempty"`
 	Source        string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
-	ConnectedAt   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=connected_at,json=connectedAt,proto3" json:"connected_at,omitempty"`
-	LastSeen      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	Items         []*ClipboardItem       `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UpstreamInfo) Reset() {
-	*x = UpstreamInfo{}
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[10]
+func (x *CachedClipboard) Reset() {
+	*x = CachedClipboard{}
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[78]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UpstreamInfo) String() string {
+func (x *CachedClipboard) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UpstreamInfo) ProtoMessage() {}
+func (*CachedClipboard) ProtoMessage() {}
 
-func (x *UpstreamInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_suffuse_v1_suffuse_proto_msgTypes[10]
+func (x *CachedClipboard) ProtoReflect() protoreflect.Message {
+	mi := &file_suffuse_v1_suffuse_proto_msgTypes[78]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -646,35 +5073,35 @@ func (x *UpstreamInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UpstreamInfo.ProtoReflect.Descriptor instead.
-func (*UpstreamInfo) Descriptor() ([]byte, []int) {
-	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use CachedClipboard.ProtoReflect.Descriptor instead.
+func (*CachedClipboard) Descriptor() ([]byte, []int) {
+	return file_suffuse_v1_suffuse_proto_rawDescGZIP(), []int{78}
 }
 
-func (x *UpstreamInfo) GetAddr() string {
+func (x *CachedClipboard) GetClipboard() string {
 	if x != nil {
-		return x.Addr
+		return x.Clipboard
 	}
 	return ""
 }
 
-func (x *UpstreamInfo) GetSource() string {
+func (x *CachedClipboard) GetSource() string {
 	if x != nil {
 		return x.Source
 	}
 	return ""
 }
 
-func (x *UpstreamInfo) GetConnectedAt() *timestamppb.Timestamp {
+func (x *CachedClipboard) GetItems() []*ClipboardItem {
 	if x != nil {
-		return x.ConnectedAt
+		return x.Items
 	}
 	return nil
 }
 
-func (x *UpstreamInfo) GetLastSeen() *timestamppb.Timestamp {
+func (x *CachedClipboard) GetUpdatedAt() *timestamppb.Timestamp {
 	if x != nil {
-		return x.LastSeen
+		return x.UpdatedAt
 	}
 	return nil
 }
@@ -684,32 +5111,130 @@ var File_suffuse_v1_suffuse_proto protoreflect.FileDescriptor
 const file_suffuse_v1_suffuse_proto_rawDesc = "" +
 	"\n" +
 	"\x18suffuse/v1/suffuse.proto\x12\n" +
-	"suffuse.v1\x1a\x1cgoogle/api/annotations.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"7\n" +
+	"suffuse.v1\x1a\x1cgoogle/api/annotations.proto\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa1\x01\n" +
 	"\rClipboardItem\x12\x12\n" +
 	"\x04mime\x18\x01 \x01(\tR\x04mime\x12\x12\n" +
-	"\x04data\x18\x02 \x01(\fR\x04data\"t\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\x12%\n" +
+	"\x03ref\x18\x03 \x01(\v2\x13.suffuse.v1.BlobRefR\x03ref\x12)\n" +
+	"\x10original_charset\x18\x04 \x01(\tR\x0foriginalCharset\x12\x16\n" +
+	"\x06sha256\x18\x05 \x01(\tR\x06sha256\"5\n" +
+	"\aBlobRef\x12\x16\n" +
+	"\x06sha256\x18\x01 \x01(\tR\x06sha256\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x04R\x04size\"t\n" +
 	"\vCopyRequest\x12\x1c\n" +
 	"\tclipboard\x18\x01 \x01(\tR\tclipboard\x12\x16\n" +
 	"\x06source\x18\x02 \x01(\tR\x06source\x12/\n" +
 	"\x05items\x18\x03 \x03(\v2\x19.suffuse.v1.ClipboardItemR\x05items\"\x0e\n" +
-	"\fCopyResponse\"F\n" +
+	"\fCopyResponse\"C\n" +
+	"\x10CopyBatchRequest\x12/\n" +
+	"\x06copies\x18\x01 \x03(\v2\x17.suffuse.v1.CopyRequestR\x06copies\"E\n" +
+	"\x11CopyBatchResponse\x120\n" +
+	"\aresults\x18\x01 \x03(\v2\x16.suffuse.v1.CopyResultR\aresults\"6\n" +
+	"\n" +
+	"CopyResult\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"K\n" +
+	"\x15CreateSnapshotRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1e\n" +
+	"\n" +
+	"clipboards\x18\x02 \x03(\tR\n" +
+	"clipboards\"\xb7\x01\n" +
+	"\bSnapshot\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x129\n" +
+	"\n" +
+	"created_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x1d\n" +
+	"\n" +
+	"created_by\x18\x03 \x01(\tR\tcreatedBy\x12=\n" +
+	"\n" +
+	"clipboards\x18\x04 \x03(\v2\x1d.suffuse.v1.SnapshotClipboardR\n" +
+	"clipboards\"\xb7\x01\n" +
+	"\x11SnapshotClipboard\x12\x1c\n" +
+	"\tclipboard\x18\x01 \x01(\tR\tclipboard\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12/\n" +
+	"\x05items\x18\x03 \x03(\v2\x19.suffuse.v1.ClipboardItemR\x05items\x12'\n" +
+	"\x0favailable_types\x18\x04 \x03(\tR\x0eavailableTypes\x12\x12\n" +
+	"\x04size\x18\x05 \x01(\x03R\x04size\"\x82\x01\n" +
+	"\x16RestoreSnapshotRequest\x12\x14\n" +
+	"\x04name\x18\x01 \x01(\tH\x00R\x04name\x122\n" +
+	"\bsnapshot\x18\x02 \x01(\v2\x14.suffuse.v1.SnapshotH\x00R\bsnapshot\x12\x16\n" +
+	"\x06source\x18\x03 \x01(\tR\x06sourceB\x06\n" +
+	"\x04from\"5\n" +
+	"\x17RestoreSnapshotResponse\x12\x1a\n" +
+	"\brestored\x18\x01 \x03(\tR\brestored\"\x16\n" +
+	"\x14ListSnapshotsRequest\"K\n" +
+	"\x15ListSnapshotsResponse\x122\n" +
+	"\tsnapshots\x18\x01 \x03(\v2\x14.suffuse.v1.SnapshotR\tsnapshots\"+\n" +
+	"\x15DeleteSnapshotRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"\x18\n" +
+	"\x16DeleteSnapshotResponse\"\x93\x01\n" +
 	"\fPasteRequest\x12\x1c\n" +
 	"\tclipboard\x18\x01 \x01(\tR\tclipboard\x12\x18\n" +
-	"\aaccepts\x18\x02 \x03(\tR\aaccepts\"v\n" +
+	"\aaccepts\x18\x02 \x03(\tR\aaccepts\x12\x1f\n" +
+	"\vaccept_refs\x18\x03 \x01(\bR\n" +
+	"acceptRefs\x12*\n" +
+	"\x02at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x02at\"\xaf\x01\n" +
 	"\rPasteResponse\x12\x16\n" +
 	"\x06source\x18\x01 \x01(\tR\x06source\x12\x1c\n" +
 	"\tclipboard\x18\x02 \x01(\tR\tclipboard\x12/\n" +
-	"\x05items\x18\x03 \x03(\v2\x19.suffuse.v1.ClipboardItemR\x05items\"k\n" +
+	"\x05items\x18\x03 \x03(\v2\x19.suffuse.v1.ClipboardItemR\x05items\x127\n" +
+	"\tstored_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\bstoredAt\"\x7f\n" +
+	"\x0eHistoryRequest\x12\x1c\n" +
+	"\tclipboard\x18\x01 \x01(\tR\tclipboard\x12\x18\n" +
+	"\aaccepts\x18\x02 \x03(\tR\aaccepts\x12\x1f\n" +
+	"\vaccept_refs\x18\x03 \x01(\bR\n" +
+	"acceptRefs\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\rR\x05limit\"c\n" +
+	"\x0fHistoryResponse\x12\x1c\n" +
+	"\tclipboard\x18\x01 \x01(\tR\tclipboard\x122\n" +
+	"\aentries\x18\x02 \x03(\v2\x18.suffuse.v1.HistoryEntryR\aentries\"\xb9\x01\n" +
+	"\fHistoryEntry\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x127\n" +
+	"\tstored_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\bstoredAt\x12/\n" +
+	"\x05items\x18\x03 \x03(\v2\x19.suffuse.v1.ClipboardItemR\x05items\x12'\n" +
+	"\x0favailable_types\x18\x04 \x03(\tR\x0eavailableTypes\"\r\n" +
+	"\vUndoRequest\"\x9b\x01\n" +
+	"\fUndoResponse\x12'\n" +
+	"\x0favailable_types\x18\x01 \x03(\tR\x0eavailableTypes\x12\x1f\n" +
+	"\vreplaced_by\x18\x02 \x01(\tR\n" +
+	"replacedBy\x12A\n" +
+	"\x0eoverwritten_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\roverwrittenAt\")\n" +
+	"\rAcceptRequest\x12\x18\n" +
+	"\adiscard\x18\x01 \x01(\bR\adiscard\"\x8e\x01\n" +
+	"\x0eAcceptResponse\x12'\n" +
+	"\x0favailable_types\x18\x01 \x03(\tR\x0eavailableTypes\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12;\n" +
+	"\vreceived_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"receivedAt\"3\n" +
+	"\tItemChunk\x12\x12\n" +
+	"\x04mime\x18\x01 \x01(\tR\x04mime\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"n\n" +
+	"\tCopyChunk\x12\x1c\n" +
+	"\tclipboard\x18\x01 \x01(\tR\tclipboard\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12+\n" +
+	"\x05chunk\x18\x03 \x01(\v2\x15.suffuse.v1.ItemChunkR\x05chunk\"\xa8\x01\n" +
+	"\n" +
+	"PasteChunk\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12\x1c\n" +
+	"\tclipboard\x18\x02 \x01(\tR\tclipboard\x12+\n" +
+	"\x05chunk\x18\x03 \x01(\v2\x15.suffuse.v1.ItemChunkR\x05chunk\x127\n" +
+	"\tstored_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\bstoredAt\"\x8c\x01\n" +
 	"\fWatchRequest\x12\x1c\n" +
 	"\tclipboard\x18\x01 \x01(\tR\tclipboard\x12\x18\n" +
 	"\aaccepts\x18\x02 \x03(\tR\aaccepts\x12#\n" +
-	"\rmetadata_only\x18\x03 \x01(\bR\fmetadataOnly\"\x9f\x01\n" +
+	"\rmetadata_only\x18\x03 \x01(\bR\fmetadataOnly\x12\x1f\n" +
+	"\vaccept_refs\x18\x04 \x01(\bR\n" +
+	"acceptRefs\"\xb9\x01\n" +
 	"\rWatchResponse\x12\x16\n" +
 	"\x06source\x18\x01 \x01(\tR\x06source\x12\x1c\n" +
 	"\tclipboard\x18\x02 \x01(\tR\tclipboard\x12/\n" +
 	"\x05items\x18\x03 \x03(\v2\x19.suffuse.v1.ClipboardItemR\x05items\x12'\n" +
-	"\x0favailable_types\x18\x04 \x03(\tR\x0eavailableTypes\"\x0f\n" +
-	"\rStatusRequest\"\x87\x02\n" +
+	"\x0favailable_types\x18\x04 \x03(\tR\x0eavailableTypes\x12\x18\n" +
+	"\aexpired\x18\x05 \x01(\bR\aexpired\"&\n" +
+	"\fFetchRequest\x12\x16\n" +
+	"\x06sha256\x18\x01 \x01(\tR\x06sha256\"#\n" +
+	"\rFetchResponse\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\"\x0f\n" +
+	"\rStatusRequest\"\xaa\x04\n" +
 	"\bPeerInfo\x12\x16\n" +
 	"\x06source\x18\x01 \x01(\tR\x06source\x12\x12\n" +
 	"\x04addr\x18\x02 \x01(\tR\x04addr\x12\x12\n" +
@@ -717,21 +5242,289 @@ const file_suffuse_v1_suffuse_proto_rawDesc = "" +
 	"\tclipboard\x18\x04 \x01(\tR\tclipboard\x12%\n" +
 	"\x0eaccepted_types\x18\x05 \x03(\tR\racceptedTypes\x12=\n" +
 	"\fconnected_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\vconnectedAt\x127\n" +
-	"\tlast_seen\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen\"{\n" +
+	"\tlast_seen\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen\x12\x18\n" +
+	"\adropped\x18\b \x01(\x04R\adropped\x122\n" +
+	"\awebhook\x18\t \x01(\v2\x18.suffuse.v1.WebhookStatsR\awebhook\x126\n" +
+	"\abackend\x18\n" +
+	" \x01(\v2\x1c.suffuse.v1.ClipboardBackendR\abackend\x12\x19\n" +
+	"\bbytes_in\x18\v \x01(\x04R\abytesIn\x12\x1b\n" +
+	"\tbytes_out\x18\f \x01(\x04R\bbytesOut\x12,\n" +
+	"\x05probe\x18\r \x01(\v2\x16.suffuse.v1.ProbeStatsR\x05probe\x12\x16\n" +
+	"\x06queued\x18\x0e \x01(\rR\x06queued\x12\x1d\n" +
+	"\n" +
+	"queue_size\x18\x0f \x01(\rR\tqueueSize\"\xe2\x01\n" +
+	"\x10ClipboardBackend\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"mime_types\x18\x02 \x03(\tR\tmimeTypes\x12\x14\n" +
+	"\x05watch\x18\x03 \x01(\tR\x05watch\x12>\n" +
+	"\rpoll_interval\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\fpollInterval\x12!\n" +
+	"\fatomic_write\x18\x05 \x01(\bR\vatomicWrite\x12\"\n" +
+	"\rany_mime_type\x18\x06 \x01(\bR\vanyMimeType\"\xea\x01\n" +
+	"\fWebhookStats\x12\x1c\n" +
+	"\tdelivered\x18\x01 \x01(\x04R\tdelivered\x12\x18\n" +
+	"\aretried\x18\x02 \x01(\x04R\aretried\x12#\n" +
+	"\rdead_lettered\x18\x03 \x01(\x04R\fdeadLettered\x12\x16\n" +
+	"\x06queued\x18\x04 \x01(\rR\x06queued\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x05 \x01(\tR\tlastError\x12F\n" +
+	"\x11last_delivered_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x0flastDeliveredAt\"\xaa\x01\n" +
+	"\n" +
+	"ProbeStats\x125\n" +
+	"\binterval\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\binterval\x121\n" +
+	"\atargets\x18\x02 \x03(\v2\x17.suffuse.v1.ProbeTargetR\atargets\x12\x12\n" +
+	"\x04sent\x18\x03 \x01(\x04R\x04sent\x12\x1e\n" +
+	"\n" +
+	"unanswered\x18\x04 \x01(\x04R\n" +
+	"unanswered\"\xe5\x01\n" +
+	"\vProbeTarget\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x123\n" +
+	"\alatency\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\alatency\x12;\n" +
+	"\vlast_answer\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"lastAnswer\x12\x1a\n" +
+	"\banswered\x18\x04 \x01(\x04R\banswered\x12\x16\n" +
+	"\x06missed\x18\x05 \x01(\x04R\x06missed\x12\x18\n" +
+	"\afailing\x18\x06 \x01(\rR\afailing\"\xc1\x03\n" +
 	"\x0eStatusResponse\x12*\n" +
 	"\x05peers\x18\x01 \x03(\v2\x14.suffuse.v1.PeerInfoR\x05peers\x12=\n" +
-	"\rupstream_info\x18\x02 \x01(\v2\x18.suffuse.v1.UpstreamInfoR\fupstreamInfo\"\xb2\x01\n" +
+	"\rupstream_info\x18\x02 \x01(\v2\x18.suffuse.v1.UpstreamInfoR\fupstreamInfo\x12A\n" +
+	"\adropped\x18\x03 \x03(\v2'.suffuse.v1.StatusResponse.DroppedEntryR\adropped\x12*\n" +
+	"\x05stats\x18\x04 \x01(\v2\x14.suffuse.v1.HubStatsR\x05stats\x120\n" +
+	"\x05usage\x18\x05 \x03(\v2\x1a.suffuse.v1.ClipboardUsageR\x05usage\x126\n" +
+	"\tupstreams\x18\x06 \x03(\v2\x18.suffuse.v1.UpstreamInfoR\tupstreams\x12/\n" +
+	"\x06quotas\x18\a \x03(\v2\x17.suffuse.v1.SourceQuotaR\x06quotas\x1a:\n" +
+	"\fDroppedEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\"\xb3\x01\n" +
+	"\vSourceQuota\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12\x16\n" +
+	"\x06copies\x18\x02 \x01(\x04R\x06copies\x12!\n" +
+	"\fcopies_limit\x18\x03 \x01(\x04R\vcopiesLimit\x12\x14\n" +
+	"\x05bytes\x18\x04 \x01(\x04R\x05bytes\x12\x1f\n" +
+	"\vbytes_limit\x18\x05 \x01(\x04R\n" +
+	"bytesLimit\x12\x1a\n" +
+	"\bexceeded\x18\x06 \x01(\x04R\bexceeded\"f\n" +
+	"\x0eClipboardUsage\x12\x1c\n" +
+	"\tclipboard\x18\x01 \x01(\tR\tclipboard\x12\x19\n" +
+	"\bbytes_in\x18\x02 \x01(\x04R\abytesIn\x12\x1b\n" +
+	"\tbytes_out\x18\x03 \x01(\x04R\bbytesOut\"\xb5\x03\n" +
+	"\bHubStats\x12\x1c\n" +
+	"\tpublishes\x18\x01 \x01(\x04R\tpublishes\x12'\n" +
+	"\x0fpublished_bytes\x18\x02 \x01(\x04R\x0epublishedBytes\x12\x1e\n" +
+	"\n" +
+	"clipboards\x18\x03 \x01(\rR\n" +
+	"clipboards\x12\x14\n" +
+	"\x05blobs\x18\x04 \x01(\rR\x05blobs\x12\x1d\n" +
+	"\n" +
+	"blob_bytes\x18\x05 \x01(\x04R\tblobBytes\x12!\n" +
+	"\fblobs_pruned\x18\x06 \x01(\x04R\vblobsPruned\x12\x19\n" +
+	"\bbytes_in\x18\a \x01(\x04R\abytesIn\x12\x1b\n" +
+	"\tbytes_out\x18\b \x01(\x04R\bbytesOut\x12\x1e\n" +
+	"\n" +
+	"duplicates\x18\t \x01(\x04R\n" +
+	"duplicates\x12\x1f\n" +
+	"\vhop_limited\x18\n" +
+	" \x01(\x04R\n" +
+	"hopLimited\x12\x14\n" +
+	"\x05loops\x18\v \x01(\x04R\x05loops\x12\x1c\n" +
+	"\toversized\x18\f \x01(\x04R\toversized\x12\x1d\n" +
+	"\n" +
+	"heap_bytes\x18\r \x01(\x04R\theapBytes\x12\x1e\n" +
+	"\n" +
+	"goroutines\x18\x0e \x01(\rR\n" +
+	"goroutines\"\xd0\x01\n" +
 	"\fUpstreamInfo\x12\x12\n" +
 	"\x04addr\x18\x01 \x01(\tR\x04addr\x12\x16\n" +
 	"\x06source\x18\x02 \x01(\tR\x06source\x12=\n" +
 	"\fconnected_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\vconnectedAt\x127\n" +
-	"\tlast_seen\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen2\xde\x02\n" +
+	"\tlast_seen\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen\x12\x1c\n" +
+	"\tdeferring\x18\x05 \x01(\tR\tdeferring\"\xbd\x01\n" +
+	"\x0fFederateMessage\x123\n" +
+	"\x05event\x18\x01 \x01(\v2\x1b.suffuse.v1.FederationEventH\x00R\x05event\x12-\n" +
+	"\x03ack\x18\x02 \x01(\v2\x19.suffuse.v1.FederationAckH\x00R\x03ack\x12?\n" +
+	"\tsubscribe\x18\x03 \x01(\v2\x1f.suffuse.v1.FederationSubscribeH\x00R\tsubscribeB\x05\n" +
+	"\x03msg\"\xe9\x02\n" +
+	"\x0fFederationEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12\x1c\n" +
+	"\tclipboard\x18\x03 \x01(\tR\tclipboard\x12/\n" +
+	"\x05items\x18\x04 \x03(\v2\x19.suffuse.v1.ClipboardItemR\x05items\x12\x1b\n" +
+	"\thop_limit\x18\x05 \x01(\rR\bhopLimit\x12\x19\n" +
+	"\bevent_id\x18\x06 \x01(\tR\aeventId\x12\x12\n" +
+	"\x04path\x18\a \x03(\tR\x04path\x12R\n" +
+	"\rtrace_context\x18\b \x03(\v2-.suffuse.v1.FederationEvent.TraceContextEntryR\ftraceContext\x1a?\n" +
+	"\x11TraceContextEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x1f\n" +
+	"\rFederationAck\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"\x9f\x01\n" +
+	"\x13FederationSubscribe\x12A\n" +
+	"\n" +
+	"clipboards\x18\x01 \x03(\v2!.suffuse.v1.ClipboardSubscriptionR\n" +
+	"clipboards\x12\x1f\n" +
+	"\vaccept_refs\x18\x02 \x01(\bR\n" +
+	"acceptRefs\x12$\n" +
+	"\x0edefer_non_text\x18\x03 \x01(\bR\fdeferNonText\"O\n" +
+	"\x15ClipboardSubscription\x12\x1c\n" +
+	"\tclipboard\x18\x01 \x01(\tR\tclipboard\x12\x18\n" +
+	"\aaccepts\x18\x02 \x03(\tR\aaccepts\"-\n" +
+	"\x17FederationStatusRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x03(\tR\x04path\"L\n" +
+	"\x18FederationStatusResponse\x120\n" +
+	"\x05nodes\x18\x01 \x03(\v2\x1a.suffuse.v1.FederationNodeR\x05nodes\"\xce\x02\n" +
+	"\x0eFederationNode\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12;\n" +
+	"\x05peers\x18\x03 \x03(\v2%.suffuse.v1.FederationNode.PeersEntryR\x05peers\x12*\n" +
+	"\x05stats\x18\x04 \x01(\v2\x14.suffuse.v1.HubStatsR\x05stats\x12=\n" +
+	"\rupstream_info\x18\x05 \x01(\v2\x18.suffuse.v1.UpstreamInfoR\fupstreamInfo\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x12\x12\n" +
+	"\x04hops\x18\a \x01(\rR\x04hops\x1a8\n" +
+	"\n" +
+	"PeersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\rR\x05value:\x028\x01\"@\n" +
+	"\fClearRequest\x12\x1e\n" +
+	"\n" +
+	"clipboards\x18\x01 \x03(\tR\n" +
+	"clipboards\x12\x10\n" +
+	"\x03all\x18\x02 \x01(\bR\x03all\")\n" +
+	"\rClearResponse\x12\x18\n" +
+	"\acleared\x18\x01 \x03(\tR\acleared\"[\n" +
+	"\x12RotateTokenRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12/\n" +
+	"\x05grace\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\x05grace\"a\n" +
+	"\x13RotateTokenResponse\x12J\n" +
+	"\x13previous_expires_at\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x11previousExpiresAt\"f\n" +
+	"\x11PruneBlobsRequest\x128\n" +
+	"\n" +
+	"unused_for\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\tunusedFor\x12\x17\n" +
+	"\adry_run\x18\x02 \x01(\bR\x06dryRun\"\x96\x01\n" +
+	"\x12PruneBlobsResponse\x12\x16\n" +
+	"\x06pruned\x18\x01 \x01(\rR\x06pruned\x12!\n" +
+	"\fpruned_bytes\x18\x02 \x01(\x04R\vprunedBytes\x12\x1c\n" +
+	"\tremaining\x18\x03 \x01(\rR\tremaining\x12'\n" +
+	"\x0fremaining_bytes\x18\x04 \x01(\x04R\x0eremainingBytes\"\xa9\x01\n" +
+	"\x0eJournalRequest\x120\n" +
+	"\x05since\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12\x1c\n" +
+	"\tclipboard\x18\x03 \x01(\tR\tclipboard\x12\x19\n" +
+	"\bevent_id\x18\x04 \x01(\tR\aeventId\x12\x14\n" +
+	"\x05limit\x18\x05 \x01(\rR\x05limit\"E\n" +
+	"\x0fJournalResponse\x122\n" +
+	"\aentries\x18\x01 \x03(\v2\x18.suffuse.v1.JournalEntryR\aentries\"\xbd\x02\n" +
+	"\fJournalEntry\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x12\n" +
+	"\x04kind\x18\x02 \x01(\tR\x04kind\x12\x19\n" +
+	"\bevent_id\x18\x03 \x01(\tR\aeventId\x12\x1c\n" +
+	"\tclipboard\x18\x04 \x01(\tR\tclipboard\x12\x16\n" +
+	"\x06source\x18\x05 \x01(\tR\x06source\x12\x12\n" +
+	"\x04peer\x18\x06 \x01(\tR\x04peer\x12\x1f\n" +
+	"\vpeer_source\x18\a \x01(\tR\n" +
+	"peerSource\x12\x1d\n" +
+	"\n" +
+	"mime_types\x18\b \x03(\tR\tmimeTypes\x12\x12\n" +
+	"\x04size\x18\t \x01(\x04R\x04size\x12\x18\n" +
+	"\aoutcome\x18\n" +
+	" \x01(\tR\aoutcome\x12\x16\n" +
+	"\x06detail\x18\v \x01(\tR\x06detail\"c\n" +
+	"\x0eProfileRequest\x12\x1a\n" +
+	"\bprofiles\x18\x01 \x03(\tR\bprofiles\x125\n" +
+	"\bduration\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\bduration\"B\n" +
+	"\x0fProfileResponse\x12/\n" +
+	"\bprofiles\x18\x01 \x03(\v2\x13.suffuse.v1.ProfileR\bprofiles\"1\n" +
+	"\aProfile\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"\x0f\n" +
+	"\rReloadRequest\"*\n" +
+	"\x0eReloadResponse\x12\x18\n" +
+	"\achanged\x18\x01 \x03(\tR\achanged\"+\n" +
+	"\x0fShutdownRequest\x12\x18\n" +
+	"\arestart\x18\x01 \x01(\bR\arestart\"$\n" +
+	"\x10ShutdownResponse\x12\x10\n" +
+	"\x03pid\x18\x01 \x01(\x03R\x03pid\"\x8a\x01\n" +
+	"\x13StartPairingRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04role\x18\x02 \x01(\tR\x04role\x12\x1e\n" +
+	"\n" +
+	"clipboards\x18\x03 \x03(\tR\n" +
+	"clipboards\x12+\n" +
+	"\x03ttl\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\x03ttl\"e\n" +
+	"\x14StartPairingResponse\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x129\n" +
+	"\n" +
+	"expires_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"\x14\n" +
+	"\x12ListDevicesRequest\"C\n" +
+	"\x13ListDevicesResponse\x12,\n" +
+	"\adevices\x18\x01 \x03(\v2\x12.suffuse.v1.DeviceR\adevices\"\xe4\x01\n" +
+	"\x06Device\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04role\x18\x02 \x01(\tR\x04role\x12\x1e\n" +
+	"\n" +
+	"clipboards\x18\x03 \x03(\tR\n" +
+	"clipboards\x12 \n" +
+	"\vfingerprint\x18\x04 \x01(\tR\vfingerprint\x127\n" +
+	"\tpaired_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\bpairedAt\x127\n" +
+	"\tlast_seen\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen\"-\n" +
+	"\x13RevokeDeviceRequest\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\"B\n" +
+	"\x14RevokeDeviceResponse\x12*\n" +
+	"\x06device\x18\x01 \x01(\v2\x12.suffuse.v1.DeviceR\x06device\"U\n" +
+	"\vPairRequest\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\x12\x14\n" +
+	"\x05share\x18\x02 \x01(\fR\x05share\x12\x18\n" +
+	"\aconfirm\x18\x03 \x01(\fR\aconfirm\"^\n" +
+	"\fPairResponse\x12\x14\n" +
+	"\x05share\x18\x01 \x01(\fR\x05share\x12\x18\n" +
+	"\aconfirm\x18\x02 \x01(\fR\aconfirm\x12\x1e\n" +
+	"\n" +
+	"credential\x18\x03 \x01(\fR\n" +
+	"credential\">\n" +
+	"\vSealedItems\x12/\n" +
+	"\x05items\x18\x01 \x03(\v2\x19.suffuse.v1.ClipboardItemR\x05items\"M\n" +
+	"\x0eClipboardCache\x12;\n" +
+	"\n" +
+	"clipboards\x18\x01 \x03(\v2\x1b.suffuse.v1.CachedClipboardR\n" +
+	"clipboards\"\xb3\x01\n" +
+	"\x0fCachedClipboard\x12\x1c\n" +
+	"\tclipboard\x18\x01 \x01(\tR\tclipboard\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12/\n" +
+	"\x05items\x18\x03 \x03(\v2\x19.suffuse.v1.ClipboardItemR\x05items\x129\n" +
+	"\n" +
+	"updated_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt2\xa5\f\n" +
 	"\x10ClipboardService\x12N\n" +
 	"\x04Copy\x12\x17.suffuse.v1.CopyRequest\x1a\x18.suffuse.v1.CopyResponse\"\x13\x82\xd3\xe4\x93\x02\r:\x01*\"\b/v1/copy\x12R\n" +
-	"\x05Paste\x12\x18.suffuse.v1.PasteRequest\x1a\x19.suffuse.v1.PasteResponse\"\x14\x82\xd3\xe4\x93\x02\x0e:\x01*\"\t/v1/paste\x12Q\n" +
+	"\x05Paste\x12\x18.suffuse.v1.PasteRequest\x1a\x19.suffuse.v1.PasteResponse\"\x14\x82\xd3\xe4\x93\x02\x0e:\x01*\"\t/v1/paste\x12W\n" +
+	"\aHistory\x12\x1a.suffuse.v1.HistoryRequest\x1a\x1b.suffuse.v1.HistoryResponse\"\x13\x82\xd3\xe4\x93\x02\r\x12\v/v1/history\x129\n" +
+	"\x04Undo\x12\x17.suffuse.v1.UndoRequest\x1a\x18.suffuse.v1.UndoResponse\x12?\n" +
+	"\x06Accept\x12\x19.suffuse.v1.AcceptRequest\x1a\x1a.suffuse.v1.AcceptResponse\x12c\n" +
+	"\tCopyBatch\x12\x1c.suffuse.v1.CopyBatchRequest\x1a\x1d.suffuse.v1.CopyBatchResponse\"\x19\x82\xd3\xe4\x93\x02\x13:\x01*\"\x0e/v1/copy:batch\x12c\n" +
+	"\x0eCreateSnapshot\x12!.suffuse.v1.CreateSnapshotRequest\x1a\x14.suffuse.v1.Snapshot\"\x18\x82\xd3\xe4\x93\x02\x12:\x01*\"\r/v1/snapshots\x12|\n" +
+	"\x0fRestoreSnapshot\x12\".suffuse.v1.RestoreSnapshotRequest\x1a#.suffuse.v1.RestoreSnapshotResponse\" \x82\xd3\xe4\x93\x02\x1a:\x01*\"\x15/v1/snapshots:restore\x12k\n" +
+	"\rListSnapshots\x12 .suffuse.v1.ListSnapshotsRequest\x1a!.suffuse.v1.ListSnapshotsResponse\"\x15\x82\xd3\xe4\x93\x02\x0f\x12\r/v1/snapshots\x12u\n" +
+	"\x0eDeleteSnapshot\x12!.suffuse.v1.DeleteSnapshotRequest\x1a\".suffuse.v1.DeleteSnapshotResponse\"\x1c\x82\xd3\xe4\x93\x02\x16*\x14/v1/snapshots/{name}\x12?\n" +
+	"\n" +
+	"CopyStream\x12\x15.suffuse.v1.CopyChunk\x1a\x18.suffuse.v1.CopyResponse(\x01\x12A\n" +
+	"\vPasteStream\x12\x18.suffuse.v1.PasteRequest\x1a\x16.suffuse.v1.PasteChunk0\x01\x12Q\n" +
 	"\x05Watch\x12\x18.suffuse.v1.WatchRequest\x1a\x19.suffuse.v1.WatchResponse\"\x11\x82\xd3\xe4\x93\x02\v\x12\t/v1/watch0\x01\x12S\n" +
 	"\x06Status\x12\x19.suffuse.v1.StatusRequest\x1a\x1a.suffuse.v1.StatusResponse\"\x12\x82\xd3\xe4\x93\x02\f\x12\n" +
-	"/v1/statusB-Z+go.klb.dev/suffuse/gen/suffuse/v1;suffusev1b\x06proto3"
+	"/v1/status\x12X\n" +
+	"\x05Fetch\x12\x18.suffuse.v1.FetchRequest\x1a\x19.suffuse.v1.FetchResponse\"\x1a\x82\xd3\xe4\x93\x02\x14\x12\x12/v1/blobs/{sha256}\x12H\n" +
+	"\bFederate\x12\x1b.suffuse.v1.FederateMessage\x1a\x1b.suffuse.v1.FederateMessage(\x010\x01\x12]\n" +
+	"\x10FederationStatus\x12#.suffuse.v1.FederationStatusRequest\x1a$.suffuse.v1.FederationStatusResponse\x12=\n" +
+	"\x04Pair\x12\x17.suffuse.v1.PairRequest\x1a\x18.suffuse.v1.PairResponse(\x010\x012\xe6\a\n" +
+	"\fAdminService\x12X\n" +
+	"\x05Clear\x12\x18.suffuse.v1.ClearRequest\x1a\x19.suffuse.v1.ClearResponse\"\x1a\x82\xd3\xe4\x93\x02\x14:\x01*\"\x0f/v1/admin/clear\x12q\n" +
+	"\vRotateToken\x12\x1e.suffuse.v1.RotateTokenRequest\x1a\x1f.suffuse.v1.RotateTokenResponse\"!\x82\xd3\xe4\x93\x02\x1b:\x01*\"\x16/v1/admin/rotate-token\x12m\n" +
+	"\n" +
+	"PruneBlobs\x12\x1d.suffuse.v1.PruneBlobsRequest\x1a\x1e.suffuse.v1.PruneBlobsResponse\" \x82\xd3\xe4\x93\x02\x1a:\x01*\"\x15/v1/admin/blobs/prune\x12]\n" +
+	"\aJournal\x12\x1a.suffuse.v1.JournalRequest\x1a\x1b.suffuse.v1.JournalResponse\"\x19\x82\xd3\xe4\x93\x02\x13\x12\x11/v1/admin/journal\x12B\n" +
+	"\aProfile\x12\x1a.suffuse.v1.ProfileRequest\x1a\x1b.suffuse.v1.ProfileResponse\x12o\n" +
+	"\fStartPairing\x12\x1f.suffuse.v1.StartPairingRequest\x1a .suffuse.v1.StartPairingResponse\"\x1c\x82\xd3\xe4\x93\x02\x16:\x01*\"\x11/v1/admin/pairing\x12i\n" +
+	"\vListDevices\x12\x1e.suffuse.v1.ListDevicesRequest\x1a\x1f.suffuse.v1.ListDevicesResponse\"\x19\x82\xd3\xe4\x93\x02\x13\x12\x11/v1/admin/devices\x12v\n" +
+	"\fRevokeDevice\x12\x1f.suffuse.v1.RevokeDeviceRequest\x1a .suffuse.v1.RevokeDeviceResponse\"#\x82\xd3\xe4\x93\x02\x1d:\x01*\"\x18/v1/admin/devices/revoke\x12\\\n" +
+	"\x06Reload\x12\x19.suffuse.v1.ReloadRequest\x1a\x1a.suffuse.v1.ReloadResponse\"\x1b\x82\xd3\xe4\x93\x02\x15:\x01*\"\x10/v1/admin/reload\x12E\n" +
+	"\bShutdown\x12\x1b.suffuse.v1.ShutdownRequest\x1a\x1c.suffuse.v1.ShutdownResponseB-Z+go.klb.dev/suffuse/gen/suffuse/v1;suffusev1b\x06proto3"
 
 var (
 	file_suffuse_v1_suffuse_proto_rawDescOnce sync.Once
@@ -745,44 +5538,224 @@ func file_suffuse_v1_suffuse_proto_rawDescGZIP() []byte {
 	return file_suffuse_v1_suffuse_proto_rawDescData
 }
 
-var file_suffuse_v1_suffuse_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_suffuse_v1_suffuse_proto_msgTypes = make([]protoimpl.MessageInfo, 82)
 var file_suffuse_v1_suffuse_proto_goTypes = []any{
-	(*ClipboardItem)(nil),         // 0: suffuse.v1.ClipboardItem
-	(*CopyRequest)(nil),           // 1: suffuse.v1.CopyRequest
-	(*CopyResponse)(nil),          // 2: suffuse.v1.CopyResponse
-	(*PasteRequest)(nil),          // 3: suffuse.v1.PasteRequest
-	(*PasteResponse)(nil),         // 4: suffuse.v1.PasteResponse
-	(*WatchRequest)(nil),          // 5: suffuse.v1.WatchRequest
-	(*WatchResponse)(nil),         // 6: suffuse.v1.WatchResponse
-	(*StatusRequest)(nil),         // 7: suffuse.v1.StatusRequest
-	(*PeerInfo)(nil),              // 8: suffuse.v1.PeerInfo
-	(*StatusResponse)(nil),        // 9: suffuse.v1.StatusResponse
-	(*UpstreamInfo)(nil),          // 10: suffuse.v1.UpstreamInfo
-	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+	(*ClipboardItem)(nil),            // 0: suffuse.v1.ClipboardItem
+	(*BlobRef)(nil),                  // 1: suffuse.v1.BlobRef
+	(*CopyRequest)(nil),              // 2: suffuse.v1.CopyRequest
+	(*CopyResponse)(nil),             // 3: suffuse.v1.CopyResponse
+	(*CopyBatchRequest)(nil),         // 4: suffuse.v1.CopyBatchRequest
+	(*CopyBatchResponse)(nil),        // 5: suffuse.v1.CopyBatchResponse
+	(*CopyResult)(nil),               // 6: suffuse.v1.CopyResult
+	(*CreateSnapshotRequest)(nil),    // 7: suffuse.v1.CreateSnapshotRequest
+	(*Snapshot)(nil),                 // 8: suffuse.v1.Snapshot
+	(*SnapshotClipboard)(nil),        // 9: suffuse.v1.SnapshotClipboard
+	(*RestoreSnapshotRequest)(nil),   // 10: suffuse.v1.RestoreSnapshotRequest
+	(*RestoreSnapshotResponse)(nil),  // 11: suffuse.v1.RestoreSnapshotResponse
+	(*ListSnapshotsRequest)(nil),     // 12: suffuse.v1.ListSnapshotsRequest
+	(*ListSnapshotsResponse)(nil),    // 13: suffuse.v1.ListSnapshotsResponse
+	(*DeleteSnapshotRequest)(nil),    // 14: suffuse.v1.DeleteSnapshotRequest
+	(*DeleteSnapshotResponse)(nil),   // 15: suffuse.v1.DeleteSnapshotResponse
+	(*PasteRequest)(nil),             // 16: suffuse.v1.PasteRequest
+	(*PasteResponse)(nil),            // 17: suffuse.v1.PasteResponse
+	(*HistoryRequest)(nil),           // 18: suffuse.v1.HistoryRequest
+	(*HistoryResponse)(nil),          // 19: suffuse.v1.HistoryResponse
+	(*HistoryEntry)(nil),             // 20: suffuse.v1.HistoryEntry
+	(*UndoRequest)(nil),              // 21: suffuse.v1.UndoRequest
+	(*UndoResponse)(nil),             // 22: suffuse.v1.UndoResponse
+	(*AcceptRequest)(nil),            // 23: suffuse.v1.AcceptRequest
+	(*AcceptResponse)(nil),           // 24: suffuse.v1.AcceptResponse
+	(*ItemChunk)(nil),                // 25: suffuse.v1.ItemChunk
+	(*CopyChunk)(nil),                // 26: suffuse.v1.CopyChunk
+	(*PasteChunk)(nil),               // 27: suffuse.v1.PasteChunk
+	(*WatchRequest)(nil),             // 28: suffuse.v1.WatchRequest
+	(*WatchResponse)(nil),            // 29: suffuse.v1.WatchResponse
+	(*FetchRequest)(nil),             // 30: suffuse.v1.FetchRequest
+	(*FetchResponse)(nil),            // 31: suffuse.v1.FetchResponse
+	(*StatusRequest)(nil),            // 32: suffuse.v1.StatusRequest
+	(*PeerInfo)(nil),                 // 33: suffuse.v1.PeerInfo
+	(*ClipboardBackend)(nil),         // 34: suffuse.v1.ClipboardBackend
+	(*WebhookStats)(nil),             // 35: suffuse.v1.WebhookStats
+	(*ProbeStats)(nil),               // 36: suffuse.v1.ProbeStats
+	(*ProbeTarget)(nil),              // 37: suffuse.v1.ProbeTarget
+	(*StatusResponse)(nil),           // 38: suffuse.v1.StatusResponse
+	(*SourceQuota)(nil),              // 39: suffuse.v1.SourceQuota
+	(*ClipboardUsage)(nil),           // 40: suffuse.v1.ClipboardUsage
+	(*HubStats)(nil),                 // 41: suffuse.v1.HubStats
+	(*UpstreamInfo)(nil),             // 42: suffuse.v1.UpstreamInfo
+	(*FederateMessage)(nil),          // 43: suffuse.v1.FederateMessage
+	(*FederationEvent)(nil),          // 44: suffuse.v1.FederationEvent
+	(*FederationAck)(nil),            // 45: suffuse.v1.FederationAck
+	(*FederationSubscribe)(nil),      // 46: suffuse.v1.FederationSubscribe
+	(*ClipboardSubscription)(nil),    // 47: suffuse.v1.ClipboardSubscription
+	(*FederationStatusRequest)(nil),  // 48: suffuse.v1.FederationStatusRequest
+	(*FederationStatusResponse)(nil), // 49: suffuse.v1.FederationStatusResponse
+	(*FederationNode)(nil),           // 50: suffuse.v1.FederationNode
+	(*ClearRequest)(nil),             // 51: suffuse.v1.ClearRequest
+	(*ClearResponse)(nil),            // 52: suffuse.v1.ClearResponse
+	(*RotateTokenRequest)(nil),       // 53: suffuse.v1.RotateTokenRequest
+	(*RotateTokenResponse)(nil),      // 54: suffuse.v1.RotateTokenResponse
+	(*PruneBlobsRequest)(nil),        // 55: suffuse.v1.PruneBlobsRequest
+	(*PruneBlobsResponse)(nil),       // 56: suffuse.v1.PruneBlobsResponse
+	(*JournalRequest)(nil),           // 57: suffuse.v1.JournalRequest
+	(*JournalResponse)(nil),          // 58: suffuse.v1.JournalResponse
+	(*JournalEntry)(nil),             // 59: suffuse.v1.JournalEntry
+	(*ProfileRequest)(nil),           // 60: suffuse.v1.ProfileRequest
+	(*ProfileResponse)(nil),          // 61: suffuse.v1.ProfileResponse
+	(*Profile)(nil),                  // 62: suffuse.v1.Profile
+	(*ReloadRequest)(nil),            // 63: suffuse.v1.ReloadRequest
+	(*ReloadResponse)(nil),           // 64: suffuse.v1.ReloadResponse
+	(*ShutdownRequest)(nil),          // 65: suffuse.v1.ShutdownRequest
+	(*ShutdownResponse)(nil),         // 66: suffuse.v1.ShutdownResponse
+	(*StartPairingRequest)(nil),      // 67: suffuse.v1.StartPairingRequest
+	(*StartPairingResponse)(nil),     // 68: suffuse.v1.StartPairingResponse
+	(*ListDevicesRequest)(nil),       // 69: suffuse.v1.ListDevicesRequest
+	(*ListDevicesResponse)(nil),      // 70: suffuse.v1.ListDevicesResponse
+	(*Device)(nil),                   // 71: suffuse.v1.Device
+	(*RevokeDeviceRequest)(nil),      // 72: suffuse.v1.RevokeDeviceRequest
+	(*RevokeDeviceResponse)(nil),     // 73: suffuse.v1.RevokeDeviceResponse
+	(*PairRequest)(nil),              // 74: suffuse.v1.PairRequest
+	(*PairResponse)(nil),             // 75: suffuse.v1.PairResponse
+	(*SealedItems)(nil),              // 76: suffuse.v1.SealedItems
+	(*ClipboardCache)(nil),           // 77: suffuse.v1.ClipboardCache
+	(*CachedClipboard)(nil),          // 78: suffuse.v1.CachedClipboard
+	nil,                              // 79: suffuse.v1.StatusResponse.DroppedEntry
+	nil,                              // 80: suffuse.v1.FederationEvent.TraceContextEntry
+	nil,                              // 81: suffuse.v1.FederationNode.PeersEntry
+	(*timestamppb.Timestamp)(nil),    // 82: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),      // 83: google.protobuf.Duration
 }
 var file_suffuse_v1_suffuse_proto_depIdxs = []int32{
-	0,  // 0: suffuse.v1.CopyRequest.items:type_name -> suffuse.v1.ClipboardItem
-	0,  // 1: suffuse.v1.PasteResponse.items:type_name -> suffuse.v1.ClipboardItem
-	0,  // 2: suffuse.v1.WatchResponse.items:type_name -> suffuse.v1.ClipboardItem
-	11, // 3: suffuse.v1.PeerInfo.connected_at:type_name -> google.protobuf.Timestamp
-	11, // 4: suffuse.v1.PeerInfo.last_seen:type_name -> google.protobuf.Timestamp
-	8,  // 5: suffuse.v1.StatusResponse.peers:type_name -> suffuse.v1.PeerInfo
-	10, // 6: suffuse.v1.StatusResponse.upstream_info:type_name -> suffuse.v1.UpstreamInfo
-	11, // 7: suffuse.v1.UpstreamInfo.connected_at:type_name -> google.protobuf.Timestamp
-	11, // 8: suffuse.v1.UpstreamInfo.last_seen:type_name -> google.protobuf.Timestamp
-	1,  // 9: suffuse.v1.ClipboardService.Copy:input_type -> suffuse.v1.CopyRequest
-	3,  // 10: suffuse.v1.ClipboardService.Paste:input_type -> suffuse.v1.PasteRequest
-	5,  // 11: suffuse.v1.ClipboardService.Watch:input_type -> suffuse.v1.WatchRequest
-	7,  // 12: suffuse.v1.ClipboardService.Status:input_type -> suffuse.v1.StatusRequest
-	2,  // 13: suffuse.v1.ClipboardService.Copy:output_type -> suffuse.v1.CopyResponse
-	4,  // 14: suffuse.v1.ClipboardService.Paste:output_type -> suffuse.v1.PasteResponse
-	6,  // 15: suffuse.v1.ClipboardService.Watch:output_type -> suffuse.v1.WatchResponse
-	9,  // 16: suffuse.v1.ClipboardService.Status:output_type -> suffuse.v1.StatusResponse
-	13, // [13:17] is the sub-list for method output_type
-	9,  // [9:13] is the sub-list for method input_type
-	9,  // [9:9] is the sub-list for extension type_name
-	9,  // [9:9] is the sub-list for extension extendee
-	0,  // [0:9] is the sub-list for field type_name
+	1,  // 0: suffuse.v1.ClipboardItem.ref:type_name -> suffuse.v1.BlobRef
+	0,  // 1: suffuse.v1.CopyRequest.items:type_name -> suffuse.v1.ClipboardItem
+	2,  // 2: suffuse.v1.CopyBatchRequest.copies:type_name -> suffuse.v1.CopyRequest
+	6,  // 3: suffuse.v1.CopyBatchResponse.results:type_name -> suffuse.v1.CopyResult
+	82, // 4: suffuse.v1.Snapshot.created_at:type_name -> google.protobuf.Timestamp
+	9,  // 5: suffuse.v1.Snapshot.clipboards:type_name -> suffuse.v1.SnapshotClipboard
+	0,  // 6: suffuse.v1.SnapshotClipboard.items:type_name -> suffuse.v1.ClipboardItem
+	8,  // 7: suffuse.v1.RestoreSnapshotRequest.snapshot:type_name -> suffuse.v1.Snapshot
+	8,  // 8: suffuse.v1.ListSnapshotsResponse.snapshots:type_name -> suffuse.v1.Snapshot
+	82, // 9: suffuse.v1.PasteRequest.at:type_name -> google.protobuf.Timestamp
+	0,  // 10: suffuse.v1.PasteResponse.items:type_name -> suffuse.v1.ClipboardItem
+	82, // 11: suffuse.v1.PasteResponse.stored_at:type_name -> google.protobuf.Timestamp
+	20, // 12: suffuse.v1.HistoryResponse.entries:type_name -> suffuse.v1.HistoryEntry
+	82, // 13: suffuse.v1.HistoryEntry.stored_at:type_name -> google.protobuf.Timestamp
+	0,  // 14: suffuse.v1.HistoryEntry.items:type_name -> suffuse.v1.ClipboardItem
+	82, // 15: suffuse.v1.UndoResponse.overwritten_at:type_name -> google.protobuf.Timestamp
+	82, // 16: suffuse.v1.AcceptResponse.received_at:type_name -> google.protobuf.Timestamp
+	25, // 17: suffuse.v1.CopyChunk.chunk:type_name -> suffuse.v1.ItemChunk
+	25, // 18: suffuse.v1.PasteChunk.chunk:type_name -> suffuse.v1.ItemChunk
+	82, // 19: suffuse.v1.PasteChunk.stored_at:type_name -> google.protobuf.Timestamp
+	0,  // 20: suffuse.v1.WatchResponse.items:type_name -> suffuse.v1.ClipboardItem
+	82, // 21: suffuse.v1.PeerInfo.connected_at:type_name -> google.protobuf.Timestamp
+	82, // 22: suffuse.v1.PeerInfo.last_seen:type_name -> google.protobuf.Timestamp
+	35, // 23: suffuse.v1.PeerInfo.webhook:type_name -> suffuse.v1.WebhookStats
+	34, // 24: suffuse.v1.PeerInfo.backend:type_name -> suffuse.v1.ClipboardBackend
+	36, // 25: suffuse.v1.PeerInfo.probe:type_name -> suffuse.v1.ProbeStats
+	83, // 26: suffuse.v1.ClipboardBackend.poll_interval:type_name -> google.protobuf.Duration
+	82, // 27: suffuse.v1.WebhookStats.last_delivered_at:type_name -> google.protobuf.Timestamp
+	83, // 28: suffuse.v1.ProbeStats.interval:type_name -> google.protobuf.Duration
+	37, // 29: suffuse.v1.ProbeStats.targets:type_name -> suffuse.v1.ProbeTarget
+	83, // 30: suffuse.v1.ProbeTarget.latency:type_name -> google.protobuf.Duration
+	82, // 31: suffuse.v1.ProbeTarget.last_answer:type_name -> google.protobuf.Timestamp
+	33, // 32: suffuse.v1.StatusResponse.peers:type_name -> suffuse.v1.PeerInfo
+	42, // 33: suffuse.v1.StatusResponse.upstream_info:type_name -> suffuse.v1.UpstreamInfo
+	79, // 34: suffuse.v1.StatusResponse.dropped:type_name -> suffuse.v1.StatusResponse.DroppedEntry
+	41, // 35: suffuse.v1.StatusResponse.stats:type_name -> suffuse.v1.HubStats
+	40, // 36: suffuse.v1.StatusResponse.usage:type_name -> suffuse.v1.ClipboardUsage
+	42, // 37: suffuse.v1.StatusResponse.upstreams:type_name -> suffuse.v1.UpstreamInfo
+	39, // 38: suffuse.v1.StatusResponse.quotas:type_name -> suffuse.v1.SourceQuota
+	82, // 39: suffuse.v1.UpstreamInfo.connected_at:type_name -> google.protobuf.Timestamp
+	82, // 40: suffuse.v1.UpstreamInfo.last_seen:type_name -> google.protobuf.Timestamp
+	44, // 41: suffuse.v1.FederateMessage.event:type_name -> suffuse.v1.FederationEvent
+	45, // 42: suffuse.v1.FederateMessage.ack:type_name -> suffuse.v1.FederationAck
+	46, // 43: suffuse.v1.FederateMessage.subscribe:type_name -> suffuse.v1.FederationSubscribe
+	0,  // 44: suffuse.v1.FederationEvent.items:type_name -> suffuse.v1.ClipboardItem
+	80, // 45: suffuse.v1.FederationEvent.trace_context:type_name -> suffuse.v1.FederationEvent.TraceContextEntry
+	47, // 46: suffuse.v1.FederationSubscribe.clipboards:type_name -> suffuse.v1.ClipboardSubscription
+	50, // 47: suffuse.v1.FederationStatusResponse.nodes:type_name -> suffuse.v1.FederationNode
+	81, // 48: suffuse.v1.FederationNode.peers:type_name -> suffuse.v1.FederationNode.PeersEntry
+	41, // 49: suffuse.v1.FederationNode.stats:type_name -> suffuse.v1.HubStats
+	42, // 50: suffuse.v1.FederationNode.upstream_info:type_name -> suffuse.v1.UpstreamInfo
+	83, // 51: suffuse.v1.RotateTokenRequest.grace:type_name -> google.protobuf.Duration
+	82, // 52: suffuse.v1.RotateTokenResponse.previous_expires_at:type_name -> google.protobuf.Timestamp
+	83, // 53: suffuse.v1.PruneBlobsRequest.unused_for:type_name -> google.protobuf.Duration
+	82, // 54: suffuse.v1.JournalRequest.since:type_name -> google.protobuf.Timestamp
+	59, // 55: suffuse.v1.JournalResponse.entries:type_name -> suffuse.v1.JournalEntry
+	82, // 56: suffuse.v1.JournalEntry.time:type_name -> google.protobuf.Timestamp
+	83, // 57: suffuse.v1.ProfileRequest.duration:type_name -> google.protobuf.Duration
+	62, // 58: suffuse.v1.ProfileResponse.profiles:type_name -> suffuse.v1.Profile
+	83, // 59: suffuse.v1.StartPairingRequest.ttl:type_name -> google.protobuf.Duration
+	82, // 60: suffuse.v1.StartPairingResponse.expires_at:type_name -> google.protobuf.Timestamp
+	71, // 61: suffuse.v1.ListDevicesResponse.devices:type_name -> suffuse.v1.Device
+	82, // 62: suffuse.v1.Device.paired_at:type_name -> google.protobuf.Timestamp
+	82, // 63: suffuse.v1.Device.last_seen:type_name -> google.protobuf.Timestamp
+	71, // 64: suffuse.v1.RevokeDeviceResponse.device:type_name -> suffuse.v1.Device
+	0,  // 65: suffuse.v1.SealedItems.items:type_name -> suffuse.v1.ClipboardItem
+	78, // 66: suffuse.v1.ClipboardCache.clipboards:type_name -> suffuse.v1.CachedClipboard
+	0,  // 67: suffuse.v1.CachedClipboard.items:type_name -> suffuse.v1.ClipboardItem
+	82, // 68: suffuse.v1.CachedClipboard.updated_at:type_name -> google.protobuf.Timestamp
+	2,  // 69: suffuse.v1.ClipboardService.Copy:input_type -> suffuse.v1.CopyRequest
+	16, // 70: suffuse.v1.ClipboardService.Paste:input_type -> suffuse.v1.PasteRequest
+	18, // 71: suffuse.v1.ClipboardService.History:input_type -> suffuse.v1.HistoryRequest
+	21, // 72: suffuse.v1.ClipboardService.Undo:input_type -> suffuse.v1.UndoRequest
+	23, // 73: suffuse.v1.ClipboardService.Accept:input_type -> suffuse.v1.AcceptRequest
+	4,  // 74: suffuse.v1.ClipboardService.CopyBatch:input_type -> suffuse.v1.CopyBatchRequest
+	7,  // 75: suffuse.v1.ClipboardService.CreateSnapshot:input_type -> suffuse.v1.CreateSnapshotRequest
+	10, // 76: suffuse.v1.ClipboardService.RestoreSnapshot:input_type -> suffuse.v1.RestoreSnapshotRequest
+	12, // 77: suffuse.v1.ClipboardService.ListSnapshots:input_type -> suffuse.v1.ListSnapshotsRequest
+	14, // 78: suffuse.v1.ClipboardService.DeleteSnapshot:input_type -> suffuse.v1.DeleteSnapshotRequest
+	26, // 79: suffuse.v1.ClipboardService.CopyStream:input_type -> suffuse.v1.CopyChunk
+	16, // 80: suffuse.v1.ClipboardService.PasteStream:input_type -> suffuse.v1.PasteRequest
+	28, // 81: suffuse.v1.ClipboardService.Watch:input_type -> suffuse.v1.WatchRequest
+	32, // 82: suffuse.v1.ClipboardService.Status:input_type -> suffuse.v1.StatusRequest
+	30, // 83: suffuse.v1.ClipboardService.Fetch:input_type -> suffuse.v1.FetchRequest
+	43, // 84: suffuse.v1.ClipboardService.Federate:input_type -> suffuse.v1.FederateMessage
+	48, // 85: suffuse.v1.ClipboardService.FederationStatus:input_type -> suffuse.v1.FederationStatusRequest
+	74, // 86: suffuse.v1.ClipboardService.Pair:input_type -> suffuse.v1.PairRequest
+	51, // 87: suffuse.v1.AdminService.Clear:input_type -> suffuse.v1.ClearRequest
+	53, // 88: suffuse.v1.AdminService.RotateToken:input_type -> suffuse.v1.RotateTokenRequest
+	55, // 89: suffuse.v1.AdminService.PruneBlobs:input_type -> suffuse.v1.PruneBlobsRequest
+	57, // 90: suffuse.v1.AdminService.Journal:input_type -> suffuse.v1.JournalRequest
+	60, // 91: suffuse.v1.AdminService.Profile:input_type -> suffuse.v1.ProfileRequest
+	67, // 92: suffuse.v1.AdminService.StartPairing:input_type -> suffuse.v1.StartPairingRequest
+	69, // 93: suffuse.v1.AdminService.ListDevices:input_type -> suffuse.v1.ListDevicesRequest
+	72, // 94: suffuse.v1.AdminService.RevokeDevice:input_type -> suffuse.v1.RevokeDeviceRequest
+	63, // 95: suffuse.v1.AdminService.Reload:input_type -> suffuse.v1.ReloadRequest
+	65, // 96: suffuse.v1.AdminService.Shutdown:input_type -> suffuse.v1.ShutdownRequest
+	3,  // 97: suffuse.v1.ClipboardService.Copy:output_type -> suffuse.v1.CopyResponse
+	17, // 98: suffuse.v1.ClipboardService.Paste:output_type -> suffuse.v1.PasteResponse
+	19, // 99: suffuse.v1.ClipboardService.History:output_type -> suffuse.v1.HistoryResponse
+	22, // 100: suffuse.v1.ClipboardService.Undo:output_type -> suffuse.v1.UndoResponse
+	24, // 101: suffuse.v1.ClipboardService.Accept:output_type -> suffuse.v1.AcceptResponse
+	5,  // 102: suffuse.v1.ClipboardService.CopyBatch:output_type -> suffuse.v1.CopyBatchResponse
+	8,  // 103: suffuse.v1.ClipboardService.CreateSnapshot:output_type -> suffuse.v1.Snapshot
+	11, // 104: suffuse.v1.ClipboardService.RestoreSnapshot:output_type -> suffuse.v1.RestoreSnapshotResponse
+	13, // 105: suffuse.v1.ClipboardService.ListSnapshots:output_type -> suffuse.v1.ListSnapshotsResponse
+	15, // 106: suffuse.v1.ClipboardService.DeleteSnapshot:output_type -> suffuse.v1.DeleteSnapshotResponse
+	3,  // 107: suffuse.v1.ClipboardService.CopyStream:output_type -> suffuse.v1.CopyResponse
+	27, // 108: suffuse.v1.ClipboardService.PasteStream:output_type -> suffuse.v1.PasteChunk
+	29, // 109: suffuse.v1.ClipboardService.Watch:output_type -> suffuse.v1.WatchResponse
+	38, // 110: suffuse.v1.ClipboardService.Status:output_type -> suffuse.v1.StatusResponse
+	31, // 111: suffuse.v1.ClipboardService.Fetch:output_type -> suffuse.v1.FetchResponse
+	43, // 112: suffuse.v1.ClipboardService.Federate:output_type -> suffuse.v1.FederateMessage
+	49, // 113: suffuse.v1.ClipboardService.FederationStatus:output_type -> suffuse.v1.FederationStatusResponse
+	75, // 114: suffuse.v1.ClipboardService.Pair:output_type -> suffuse.v1.PairResponse
+	52, // 115: suffuse.v1.AdminService.Clear:output_type -> suffuse.v1.ClearResponse
+	54, // 116: suffuse.v1.AdminService.RotateToken:output_type -> suffuse.v1.RotateTokenResponse
+	56, // 117: suffuse.v1.AdminService.PruneBlobs:output_type -> suffuse.v1.PruneBlobsResponse
+	58, // 118: suffuse.v1.AdminService.Journal:output_type -> suffuse.v1.JournalResponse
+	61, // 119: suffuse.v1.AdminService.Profile:output_type -> suffuse.v1.ProfileResponse
+	68, // 120: suffuse.v1.AdminService.StartPairing:output_type -> suffuse.v1.StartPairingResponse
+	70, // 121: suffuse.v1.AdminService.ListDevices:output_type -> suffuse.v1.ListDevicesResponse
+	73, // 122: suffuse.v1.AdminService.RevokeDevice:output_type -> suffuse.v1.RevokeDeviceResponse
+	64, // 123: suffuse.v1.AdminService.Reload:output_type -> suffuse.v1.ReloadResponse
+	66, // 124: suffuse.v1.AdminService.Shutdown:output_type -> suffuse.v1.ShutdownResponse
+	97, // [97:125] is the sub-list for method output_type
+	69, // [69:97] is the sub-list for method input_type
+	69, // [69:69] is the sub-list for extension type_name
+	69, // [69:69] is the sub-list for extension extendee
+	0,  // [0:69] is the sub-list for field type_name
 }
 
 func init() { file_suffuse_v1_suffuse_proto_init() }
@@ -790,15 +5763,24 @@ func file_suffuse_v1_suffuse_proto_init() {
 	if File_suffuse_v1_suffuse_proto != nil {
 		return
 	}
+	file_suffuse_v1_suffuse_proto_msgTypes[10].OneofWrappers = []any{
+		(*RestoreSnapshotRequest_Name)(nil),
+		(*RestoreSnapshotRequest_Snapshot)(nil),
+	}
+	file_suffuse_v1_suffuse_proto_msgTypes[43].OneofWrappers = []any{
+		(*FederateMessage_Event)(nil),
+		(*FederateMessage_Ack)(nil),
+		(*FederateMessage_Subscribe)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_suffuse_v1_suffuse_proto_rawDesc), len(file_suffuse_v1_suffuse_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   11,
+			NumMessages:   82,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_suffuse_v1_suffuse_proto_goTypes,
 		DependencyIndexes: file_suffuse_v1_suffuse_proto_depIdxs,
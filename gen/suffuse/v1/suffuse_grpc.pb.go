@@ -19,10 +19,24 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ClipboardService_Copy_FullMethodName   = "/suffuse.v1.ClipboardService/Copy"
-	ClipboardService_Paste_FullMethodName  = "/suffuse.v1.ClipboardService/Paste"
-	ClipboardService_Watch_FullMethodName  = "/suffuse.v1.ClipboardService/Watch"
-	ClipboardService_Status_FullMethodName = "/suffuse.v1.ClipboardService/Status"
+	ClipboardService_Copy_FullMethodName             = "/suffuse.v1.ClipboardService/Copy"
+	ClipboardService_Paste_FullMethodName            = "/suffuse.v1.ClipboardService/Paste"
+	ClipboardService_History_FullMethodName          = "/suffuse.v1.ClipboardService/History"
+	ClipboardService_Undo_FullMethodName             = "/suffuse.v1.ClipboardService/Undo"
+	ClipboardService_Accept_FullMethodName           = "/suffuse.v1.ClipboardService/Accept"
+	ClipboardService_CopyBatch_FullMethodName        = "/suffuse.v1.ClipboardService/CopyBatch"
+	ClipboardService_CreateSnapshot_FullMethodName   = "/suffuse.v1.ClipboardService/CreateSnapshot"
+	ClipboardService_RestoreSnapshot_FullMethodName  = "/suffuse.v1.ClipboardService/RestoreSnapshot"
+	ClipboardService_ListSnapshots_FullMethodName    = "/suffuse.v1.ClipboardService/ListSnapshots"
+	ClipboardService_DeleteSnapshot_FullMethodName   = "/suffuse.v1.ClipboardService/DeleteSnapshot"
+	ClipboardService_CopyStream_FullMethodName       = "/suffuse.v1.ClipboardService/CopyStream"
+	ClipboardService_PasteStream_FullMethodName      = "/suffuse.v1.ClipboardService/PasteStream"
+	ClipboardService_Watch_FullMethodName            = "/suffuse.v1.ClipboardService/Watch"
+	ClipboardService_Status_FullMethodName           = "/suffuse.v1.ClipboardService/Status"
+	ClipboardService_Fetch_FullMethodName            = "/suffuse.v1.ClipboardService/Fetch"
+	ClipboardService_Federate_FullMethodName         = "/suffuse.v1.ClipboardService/Federate"
+	ClipboardService_FederationStatus_FullMethodName = "/suffuse.v1.ClipboardService/FederationStatus"
+	ClipboardService_Pair_FullMethodName             = "/suffuse.v1.ClipboardService/Pair"
 )
 
 // ClipboardServiceClient is the client API for ClipboardService service.
@@ -43,11 +57,78 @@ type ClipboardServiceClient interface {
 	// Paste returns the most-recent clipboard content, optionally filtered by
 	// MIME type.
 	Paste(ctx context.Context, in *PasteRequest, opts ...grpc.CallOption) (*PasteResponse, error)
+	// History returns earlier contents of a clipboard, newest first, on
+	// servers that keep a history. FailedPrecondition when they do not.
+	History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error)
+	// Undo writes back the content of the server's local clipboard that the
+	// last update from the hub overwrote. The hub's content is unchanged.
+	// Served on the local IPC socket only; NotFound when nothing was
+	// overwritten, FailedPrecondition when the server has no local clipboard.
+	Undo(ctx context.Context, in *UndoRequest, opts ...grpc.CallOption) (*UndoResponse, error)
+	// Accept writes the update held for the server's local clipboard to it,
+	// or drops it, on servers started with --hold. Served on the local IPC
+	// socket only; NotFound when nothing is held, FailedPrecondition when
+	// updates are not held.
+	Accept(ctx context.Context, in *AcceptRequest, opts ...grpc.CallOption) (*AcceptResponse, error)
+	// CopyBatch publishes several copies in one call, in order, as that many
+	// Copy calls would: each is checked and published on its own and gets a
+	// result of its own, so a refused copy does not stop the rest. For
+	// scripts and replaying queued copies in one round trip. The batch as a
+	// whole is bound by the size of a single message.
+	CopyBatch(ctx context.Context, in *CopyBatchRequest, opts ...grpc.CallOption) (*CopyBatchResponse, error)
+	// CreateSnapshot captures the content of clipboards as it is now, e.g.
+	// before a script rewrites several of them. With a name the snapshot is
+	// kept on the server, replacing one of the same name, and returned without
+	// content; without one it is only returned, for the caller to keep. Bound
+	// by the size of a single message.
+	CreateSnapshot(ctx context.Context, in *CreateSnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error)
+	// RestoreSnapshot sets the clipboards of a snapshot back to its content,
+	// all or none: each is checked as a copy would be before any is published,
+	// and a refusal leaves every clipboard as it was.
+	RestoreSnapshot(ctx context.Context, in *RestoreSnapshotRequest, opts ...grpc.CallOption) (*RestoreSnapshotResponse, error)
+	// ListSnapshots returns the snapshots kept on the server whose clipboards
+	// the caller's token covers, newest first, without content.
+	ListSnapshots(ctx context.Context, in *ListSnapshotsRequest, opts ...grpc.CallOption) (*ListSnapshotsResponse, error)
+	// DeleteSnapshot removes a snapshot kept on the server. NotFound when there
+	// is none of that name.
+	DeleteSnapshot(ctx context.Context, in *DeleteSnapshotRequest, opts ...grpc.CallOption) (*DeleteSnapshotResponse, error)
+	// CopyStream is Copy with the content sent in chunks, so items larger than
+	// a single gRPC message can be copied. The server assembles the whole copy
+	// before publishing it. gRPC only — not exposed over HTTP/JSON.
+	CopyStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[CopyChunk, CopyResponse], error)
+	// PasteStream is Paste with the content returned in chunks. Items are
+	// always sent inline; accept_refs is ignored. gRPC only — not exposed over
+	// HTTP/JSON.
+	PasteStream(ctx context.Context, in *PasteRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PasteChunk], error)
 	// Watch opens a server-streaming RPC that delivers clipboard events as they
 	// arrive. The client controls filtering via WatchRequest.
 	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchResponse], error)
 	// Status returns a snapshot of all currently-connected peers.
 	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+	// Fetch returns the content of a blob referenced by a ClipboardItem. Items
+	// larger than the server's blob threshold are sent as references to peers
+	// that ask for them, which fetch the content only when they need it.
+	Fetch(ctx context.Context, in *FetchRequest, opts ...grpc.CallOption) (*FetchResponse, error)
+	// Federate opens the single persistent link between a downstream and an
+	// upstream server. The downstream sends its clipboard subscriptions and
+	// locally-published events; the upstream sends matching events back. Every
+	// event is acknowledged by the receiver. gRPC only — not exposed over
+	// HTTP/JSON.
+	Federate(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[FederateMessage, FederateMessage], error)
+	// FederationStatus summarises this server and, probing through each
+	// upstream link in turn, every server above it. Downstream servers call it
+	// on their federation connection. gRPC only — not exposed over HTTP/JSON.
+	FederationStatus(ctx context.Context, in *FederationStatusRequest, opts ...grpc.CallOption) (*FederationStatusResponse, error)
+	// Pair exchanges a pairing code issued by AdminService.StartPairing for a
+	// per-peer token. The code authenticates a SPAKE2 exchange instead of the
+	// caller's token, so the call needs no credentials and the connection no
+	// verified TLS. The device sends its name and key share, the server
+	// answers with its share and confirmation, the device sends its
+	// confirmation and the server returns the token sealed with the exchanged
+	// key. NotFound when no code is active, PermissionDenied on a wrong code,
+	// FailedPrecondition when the server cannot issue tokens. gRPC only — not
+	// exposed over HTTP/JSON.
+	Pair(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[PairRequest, PairResponse], error)
 }
 
 type clipboardServiceClient struct {
@@ -78,9 +159,121 @@ func (c *clipboardServiceClient) Paste(ctx context.Context, in *PasteRequest, op
 	return out, nil
 }
 
+func (c *clipboardServiceClient) History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HistoryResponse)
+	err := c.cc.Invoke(ctx, ClipboardService_History_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clipboardServiceClient) Undo(ctx context.Context, in *UndoRequest, opts ...grpc.CallOption) (*UndoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UndoResponse)
+	err := c.cc.Invoke(ctx, ClipboardService_Undo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clipboardServiceClient) Accept(ctx context.Context, in *AcceptRequest, opts ...grpc.CallOption) (*AcceptResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AcceptResponse)
+	err := c.cc.Invoke(ctx, ClipboardService_Accept_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clipboardServiceClient) CopyBatch(ctx context.Context, in *CopyBatchRequest, opts ...grpc.CallOption) (*CopyBatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CopyBatchResponse)
+	err := c.cc.Invoke(ctx, ClipboardService_CopyBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clipboardServiceClient) CreateSnapshot(ctx context.Context, in *CreateSnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Snapshot)
+	err := c.cc.Invoke(ctx, ClipboardService_CreateSnapshot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clipboardServiceClient) RestoreSnapshot(ctx context.Context, in *RestoreSnapshotRequest, opts ...grpc.CallOption) (*RestoreSnapshotResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RestoreSnapshotResponse)
+	err := c.cc.Invoke(ctx, ClipboardService_RestoreSnapshot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clipboardServiceClient) ListSnapshots(ctx context.Context, in *ListSnapshotsRequest, opts ...grpc.CallOption) (*ListSnapshotsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSnapshotsResponse)
+	err := c.cc.Invoke(ctx, ClipboardService_ListSnapshots_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clipboardServiceClient) DeleteSnapshot(ctx context.Context, in *DeleteSnapshotRequest, opts ...grpc.CallOption) (*DeleteSnapshotResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteSnapshotResponse)
+	err := c.cc.Invoke(ctx, ClipboardService_DeleteSnapshot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clipboardServiceClient) CopyStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[CopyChunk, CopyResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ClipboardService_ServiceDesc.Streams[0], ClipboardService_CopyStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CopyChunk, CopyResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClipboardService_CopyStreamClient = grpc.ClientStreamingClient[CopyChunk, CopyResponse]
+
+func (c *clipboardServiceClient) PasteStream(ctx context.Context, in *PasteRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PasteChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ClipboardService_ServiceDesc.Streams[1], ClipboardService_PasteStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PasteRequest, PasteChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClipboardService_PasteStreamClient = grpc.ServerStreamingClient[PasteChunk]
+
 func (c *clipboardServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &ClipboardService_ServiceDesc.Streams[0], ClipboardService_Watch_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &ClipboardService_ServiceDesc.Streams[2], ClipboardService_Watch_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -107,6 +300,52 @@ func (c *clipboardServiceClient) Status(ctx context.Context, in *StatusRequest,
 	return out, nil
 }
 
+func (c *clipboardServiceClient) Fetch(ctx context.Context, in *FetchRequest, opts ...grpc.CallOption) (*FetchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FetchResponse)
+	err := c.cc.Invoke(ctx, ClipboardService_Fetch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clipboardServiceClient) Federate(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[FederateMessage, FederateMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ClipboardService_ServiceDesc.Streams[3], ClipboardService_Federate_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[FederateMessage, FederateMessage]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClipboardService_FederateClient = grpc.BidiStreamingClient[FederateMessage, FederateMessage]
+
+func (c *clipboardServiceClient) FederationStatus(ctx context.Context, in *FederationStatusRequest, opts ...grpc.CallOption) (*FederationStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FederationStatusResponse)
+	err := c.cc.Invoke(ctx, ClipboardService_FederationStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clipboardServiceClient) Pair(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[PairRequest, PairResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ClipboardService_ServiceDesc.Streams[4], ClipboardService_Pair_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PairRequest, PairResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClipboardService_PairClient = grpc.BidiStreamingClient[PairRequest, PairResponse]
+
 // ClipboardServiceServer is the server API for ClipboardService service.
 // All implementations must embed UnimplementedClipboardServiceServer
 // for forward compatibility.
@@ -125,11 +364,78 @@ type ClipboardServiceServer interface {
 	// Paste returns the most-recent clipboard content, optionally filtered by
 	// MIME type.
 	Paste(context.Context, *PasteRequest) (*PasteResponse, error)
+	// History returns earlier contents of a clipboard, newest first, on
+	// servers that keep a history. FailedPrecondition when they do not.
+	History(context.Context, *HistoryRequest) (*HistoryResponse, error)
+	// Undo writes back the content of the server's local clipboard that the
+	// last update from the hub overwrote. The hub's content is unchanged.
+	// Served on the local IPC socket only; NotFound when nothing was
+	// overwritten, FailedPrecondition when the server has no local clipboard.
+	Undo(context.Context, *UndoRequest) (*UndoResponse, error)
+	// Accept writes the update held for the server's local clipboard to it,
+	// or drops it, on servers started with --hold. Served on the local IPC
+	// socket only; NotFound when nothing is held, FailedPrecondition when
+	// updates are not held.
+	Accept(context.Context, *AcceptRequest) (*AcceptResponse, error)
+	// CopyBatch publishes several copies in one call, in order, as that many
+	// Copy calls would: each is checked and published on its own and gets a
+	// result of its own, so a refused copy does not stop the rest. For
+	// scripts and replaying queued copies in one round trip. The batch as a
+	// whole is bound by the size of a single message.
+	CopyBatch(context.Context, *CopyBatchRequest) (*CopyBatchResponse, error)
+	// CreateSnapshot captures the content of clipboards as it is now, e.g.
+	// before a script rewrites several of them. With a name the snapshot is
+	// kept on the server, replacing one of the same name, and returned without
+	// content; without one it is only returned, for the caller to keep. Bound
+	// by the size of a single message.
+	CreateSnapshot(context.Context, *CreateSnapshotRequest) (*Snapshot, error)
+	// RestoreSnapshot sets the clipboards of a snapshot back to its content,
+	// all or none: each is checked as a copy would be before any is published,
+	// and a refusal leaves every clipboard as it was.
+	RestoreSnapshot(context.Context, *RestoreSnapshotRequest) (*RestoreSnapshotResponse, error)
+	// ListSnapshots returns the snapshots kept on the server whose clipboards
+	// the caller's token covers, newest first, without content.
+	ListSnapshots(context.Context, *ListSnapshotsRequest) (*ListSnapshotsResponse, error)
+	// DeleteSnapshot removes a snapshot kept on the server. NotFound when there
+	// is none of that name.
+	DeleteSnapshot(context.Context, *DeleteSnapshotRequest) (*DeleteSnapshotResponse, error)
+	// CopyStream is Copy with the content sent in chunks, so items larger than
+	// a single gRPC message can be copied. The server assembles the whole copy
+	// before publishing it. gRPC only — not exposed over HTTP/JSON.
+	CopyStream(grpc.ClientStreamingServer[CopyChunk, CopyResponse]) error
+	// PasteStream is Paste with the content returned in chunks. Items are
+	// always sent inline; accept_refs is ignored. gRPC only — not exposed over
+	// HTTP/JSON.
+	PasteStream(*PasteRequest, grpc.ServerStreamingServer[PasteChunk]) error
 	// Watch opens a server-streaming RPC that delivers clipboard events as they
 	// arrive. The client controls filtering via WatchRequest.
 	Watch(*WatchRequest, grpc.ServerStreamingServer[WatchResponse]) error
 	// Status returns a snapshot of all currently-connected peers.
 	Status(context.Context, *StatusRequest) (*StatusResponse, error)
+	// Fetch returns the content of a blob referenced by a ClipboardItem. Items
+	// larger than the server's blob threshold are sent as references to peers
+	// that ask for them, which fetch the content only when they need it.
+	Fetch(context.Context, *FetchRequest) (*FetchResponse, error)
+	// Federate opens the single persistent link between a downstream and an
+	// upstream server. The downstream sends its clipboard subscriptions and
+	// locally-published events; the upstream sends matching events back. Every
+	// event is acknowledged by the receiver. gRPC only — not exposed over
+	// HTTP/JSON.
+	Federate(grpc.BidiStreamingServer[FederateMessage, FederateMessage]) error
+	// FederationStatus summarises this server and, probing through each
+	// upstream link in turn, every server above it. Downstream servers call it
+	// on their federation connection. gRPC only — not exposed over HTTP/JSON.
+	FederationStatus(context.Context, *FederationStatusRequest) (*FederationStatusResponse, error)
+	// Pair exchanges a pairing code issued by AdminService.StartPairing for a
+	// per-peer token. The code authenticates a SPAKE2 exchange instead of the
+	// caller's token, so the call needs no credentials and the connection no
+	// verified TLS. The device sends its name and key share, the server
+	// answers with its share and confirmation, the device sends its
+	// confirmation and the server returns the token sealed with the exchanged
+	// key. NotFound when no code is active, PermissionDenied on a wrong code,
+	// FailedPrecondition when the server cannot issue tokens. gRPC only — not
+	// exposed over HTTP/JSON.
+	Pair(grpc.BidiStreamingServer[PairRequest, PairResponse]) error
 	mustEmbedUnimplementedClipboardServiceServer()
 }
 
@@ -146,12 +452,54 @@ func (UnimplementedClipboardServiceServer) Copy(context.Context, *CopyRequest) (
 func (UnimplementedClipboardServiceServer) Paste(context.Context, *PasteRequest) (*PasteResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method Paste not implemented")
 }
+func (UnimplementedClipboardServiceServer) History(context.Context, *HistoryRequest) (*HistoryResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method History not implemented")
+}
+func (UnimplementedClipboardServiceServer) Undo(context.Context, *UndoRequest) (*UndoResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Undo not implemented")
+}
+func (UnimplementedClipboardServiceServer) Accept(context.Context, *AcceptRequest) (*AcceptResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Accept not implemented")
+}
+func (UnimplementedClipboardServiceServer) CopyBatch(context.Context, *CopyBatchRequest) (*CopyBatchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CopyBatch not implemented")
+}
+func (UnimplementedClipboardServiceServer) CreateSnapshot(context.Context, *CreateSnapshotRequest) (*Snapshot, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateSnapshot not implemented")
+}
+func (UnimplementedClipboardServiceServer) RestoreSnapshot(context.Context, *RestoreSnapshotRequest) (*RestoreSnapshotResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RestoreSnapshot not implemented")
+}
+func (UnimplementedClipboardServiceServer) ListSnapshots(context.Context, *ListSnapshotsRequest) (*ListSnapshotsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListSnapshots not implemented")
+}
+func (UnimplementedClipboardServiceServer) DeleteSnapshot(context.Context, *DeleteSnapshotRequest) (*DeleteSnapshotResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteSnapshot not implemented")
+}
+func (UnimplementedClipboardServiceServer) CopyStream(grpc.ClientStreamingServer[CopyChunk, CopyResponse]) error {
+	return status.Error(codes.Unimplemented, "method CopyStream not implemented")
+}
+func (UnimplementedClipboardServiceServer) PasteStream(*PasteRequest, grpc.ServerStreamingServer[PasteChunk]) error {
+	return status.Error(codes.Unimplemented, "method PasteStream not implemented")
+}
 func (UnimplementedClipboardServiceServer) Watch(*WatchRequest, grpc.ServerStreamingServer[WatchResponse]) error {
 	return status.Error(codes.Unimplemented, "method Watch not implemented")
 }
 func (UnimplementedClipboardServiceServer) Status(context.Context, *StatusRequest) (*StatusResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method Status not implemented")
 }
+func (UnimplementedClipboardServiceServer) Fetch(context.Context, *FetchRequest) (*FetchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Fetch not implemented")
+}
+func (UnimplementedClipboardServiceServer) Federate(grpc.BidiStreamingServer[FederateMessage, FederateMessage]) error {
+	return status.Error(codes.Unimplemented, "method Federate not implemented")
+}
+func (UnimplementedClipboardServiceServer) FederationStatus(context.Context, *FederationStatusRequest) (*FederationStatusResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method FederationStatus not implemented")
+}
+func (UnimplementedClipboardServiceServer) Pair(grpc.BidiStreamingServer[PairRequest, PairResponse]) error {
+	return status.Error(codes.Unimplemented, "method Pair not implemented")
+}
 func (UnimplementedClipboardServiceServer) mustEmbedUnimplementedClipboardServiceServer() {}
 func (UnimplementedClipboardServiceServer) testEmbeddedByValue()                          {}
 
@@ -209,6 +557,168 @@ func _ClipboardService_Paste_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ClipboardService_History_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClipboardServiceServer).History(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClipboardService_History_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClipboardServiceServer).History(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClipboardService_Undo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UndoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClipboardServiceServer).Undo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClipboardService_Undo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClipboardServiceServer).Undo(ctx, req.(*UndoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClipboardService_Accept_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcceptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClipboardServiceServer).Accept(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClipboardService_Accept_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClipboardServiceServer).Accept(ctx, req.(*AcceptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClipboardService_CopyBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CopyBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClipboardServiceServer).CopyBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClipboardService_CopyBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClipboardServiceServer).CopyBatch(ctx, req.(*CopyBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClipboardService_CreateSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClipboardServiceServer).CreateSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClipboardService_CreateSnapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClipboardServiceServer).CreateSnapshot(ctx, req.(*CreateSnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClipboardService_RestoreSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreSnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClipboardServiceServer).RestoreSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClipboardService_RestoreSnapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClipboardServiceServer).RestoreSnapshot(ctx, req.(*RestoreSnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClipboardService_ListSnapshots_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSnapshotsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClipboardServiceServer).ListSnapshots(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClipboardService_ListSnapshots_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClipboardServiceServer).ListSnapshots(ctx, req.(*ListSnapshotsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClipboardService_DeleteSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClipboardServiceServer).DeleteSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClipboardService_DeleteSnapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClipboardServiceServer).DeleteSnapshot(ctx, req.(*DeleteSnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClipboardService_CopyStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ClipboardServiceServer).CopyStream(&grpc.GenericServerStream[CopyChunk, CopyResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClipboardService_CopyStreamServer = grpc.ClientStreamingServer[CopyChunk, CopyResponse]
+
+func _ClipboardService_PasteStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PasteRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ClipboardServiceServer).PasteStream(m, &grpc.GenericServerStream[PasteRequest, PasteChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClipboardService_PasteStreamServer = grpc.ServerStreamingServer[PasteChunk]
+
 func _ClipboardService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(WatchRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -238,32 +748,652 @@ func _ClipboardService_Status_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
-// ClipboardService_ServiceDesc is the grpc.ServiceDesc for ClipboardService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var ClipboardService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "suffuse.v1.ClipboardService",
-	HandlerType: (*ClipboardServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Copy",
-			Handler:    _ClipboardService_Copy_Handler,
-		},
-		{
-			MethodName: "Paste",
-			Handler:    _ClipboardService_Paste_Handler,
-		},
+func _ClipboardService_Fetch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FetchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClipboardServiceServer).Fetch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClipboardService_Fetch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClipboardServiceServer).Fetch(ctx, req.(*FetchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClipboardService_Federate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ClipboardServiceServer).Federate(&grpc.GenericServerStream[FederateMessage, FederateMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClipboardService_FederateServer = grpc.BidiStreamingServer[FederateMessage, FederateMessage]
+
+func _ClipboardService_FederationStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FederationStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClipboardServiceServer).FederationStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClipboardService_FederationStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClipboardServiceServer).FederationStatus(ctx, req.(*FederationStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClipboardService_Pair_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ClipboardServiceServer).Pair(&grpc.GenericServerStream[PairRequest, PairResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClipboardService_PairServer = grpc.BidiStreamingServer[PairRequest, PairResponse]
+
+// ClipboardService_ServiceDesc is the grpc.ServiceDesc for ClipboardService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ClipboardService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "suffuse.v1.ClipboardService",
+	HandlerType: (*ClipboardServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Copy",
+			Handler:    _ClipboardService_Copy_Handler,
+		},
+		{
+			MethodName: "Paste",
+			Handler:    _ClipboardService_Paste_Handler,
+		},
+		{
+			MethodName: "History",
+			Handler:    _ClipboardService_History_Handler,
+		},
+		{
+			MethodName: "Undo",
+			Handler:    _ClipboardService_Undo_Handler,
+		},
+		{
+			MethodName: "Accept",
+			Handler:    _ClipboardService_Accept_Handler,
+		},
+		{
+			MethodName: "CopyBatch",
+			Handler:    _ClipboardService_CopyBatch_Handler,
+		},
+		{
+			MethodName: "CreateSnapshot",
+			Handler:    _ClipboardService_CreateSnapshot_Handler,
+		},
+		{
+			MethodName: "RestoreSnapshot",
+			Handler:    _ClipboardService_RestoreSnapshot_Handler,
+		},
+		{
+			MethodName: "ListSnapshots",
+			Handler:    _ClipboardService_ListSnapshots_Handler,
+		},
+		{
+			MethodName: "DeleteSnapshot",
+			Handler:    _ClipboardService_DeleteSnapshot_Handler,
+		},
 		{
 			MethodName: "Status",
 			Handler:    _ClipboardService_Status_Handler,
 		},
+		{
+			MethodName: "Fetch",
+			Handler:    _ClipboardService_Fetch_Handler,
+		},
+		{
+			MethodName: "FederationStatus",
+			Handler:    _ClipboardService_FederationStatus_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "CopyStream",
+			Handler:       _ClipboardService_CopyStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "PasteStream",
+			Handler:       _ClipboardService_PasteStream_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "Watch",
 			Handler:       _ClipboardService_Watch_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "Federate",
+			Handler:       _ClipboardService_Federate_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Pair",
+			Handler:       _ClipboardService_Pair_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "suffuse/v1/suffuse.proto",
+}
+
+const (
+	AdminService_Clear_FullMethodName        = "/suffuse.v1.AdminService/Clear"
+	AdminService_RotateToken_FullMethodName  = "/suffuse.v1.AdminService/RotateToken"
+	AdminService_PruneBlobs_FullMethodName   = "/suffuse.v1.AdminService/PruneBlobs"
+	AdminService_Journal_FullMethodName      = "/suffuse.v1.AdminService/Journal"
+	AdminService_Profile_FullMethodName      = "/suffuse.v1.AdminService/Profile"
+	AdminService_StartPairing_FullMethodName = "/suffuse.v1.AdminService/StartPairing"
+	AdminService_ListDevices_FullMethodName  = "/suffuse.v1.AdminService/ListDevices"
+	AdminService_RevokeDevice_FullMethodName = "/suffuse.v1.AdminService/RevokeDevice"
+	AdminService_Reload_FullMethodName       = "/suffuse.v1.AdminService/Reload"
+	AdminService_Shutdown_FullMethodName     = "/suffuse.v1.AdminService/Shutdown"
+)
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AdminService exposes maintenance operations for hub operators. It shares
+// the listener and authentication scheme of ClipboardService.
+type AdminServiceClient interface {
+	// Clear drops the stored contents of the named clipboards (all clipboards
+	// when all is set), e.g. during incident response after sensitive data was
+	// copied.
+	Clear(ctx context.Context, in *ClearRequest, opts ...grpc.CallOption) (*ClearResponse, error)
+	// RotateToken makes a new shared secret primary while the current one stays
+	// valid for a grace period. The change is not persisted; update the
+	// server's configuration before it restarts.
+	RotateToken(ctx context.Context, in *RotateTokenRequest, opts ...grpc.CallOption) (*RotateTokenResponse, error)
+	// PruneBlobs removes blobs that no clipboard references any more ahead of
+	// the periodic collection, e.g. after Clear during incident response.
+	PruneBlobs(ctx context.Context, in *PruneBlobsRequest, opts ...grpc.CallOption) (*PruneBlobsResponse, error)
+	// Journal returns entries of the server's event journal: publishes and
+	// their delivery to each peer, without content. FailedPrecondition when
+	// the server keeps no journal.
+	Journal(ctx context.Context, in *JournalRequest, opts ...grpc.CallOption) (*JournalResponse, error)
+	// Profile captures runtime profiles of the server process. Served only on
+	// the local IPC socket and not exposed over HTTP/JSON, so pprof data never
+	// needs to be reachable from the network.
+	Profile(ctx context.Context, in *ProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error)
+	// StartPairing issues a short-lived code a new device exchanges for a
+	// per-peer token with ClipboardService.Pair, replacing any code issued
+	// before. FailedPrecondition when the server runs without a token, as
+	// issued tokens would go unenforced.
+	StartPairing(ctx context.Context, in *StartPairingRequest, opts ...grpc.CallOption) (*StartPairingResponse, error)
+	// ListDevices returns the devices paired with StartPairing.
+	// FailedPrecondition when the server runs without a token.
+	ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error)
+	// RevokeDevice stops accepting a paired device's token and ends the
+	// streams it has open, without changing anyone else's. NotFound when no
+	// device matches.
+	RevokeDevice(ctx context.Context, in *RevokeDeviceRequest, opts ...grpc.CallOption) (*RevokeDeviceResponse, error)
+	// Reload rereads the server's configuration file and applies the settings
+	// that can change while it runs, as SIGHUP does. FailedPrecondition when
+	// the configuration is invalid, leaving the running one in force.
+	Reload(ctx context.Context, in *ReloadRequest, opts ...grpc.CallOption) (*ReloadResponse, error)
+	// Shutdown stops the server once the call returns, or restarts it with the
+	// arguments it was started with. Served only on the local IPC socket and
+	// not exposed over HTTP/JSON.
+	Shutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*ShutdownResponse, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) Clear(ctx context.Context, in *ClearRequest, opts ...grpc.CallOption) (*ClearResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClearResponse)
+	err := c.cc.Invoke(ctx, AdminService_Clear_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) RotateToken(ctx context.Context, in *RotateTokenRequest, opts ...grpc.CallOption) (*RotateTokenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RotateTokenResponse)
+	err := c.cc.Invoke(ctx, AdminService_RotateToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) PruneBlobs(ctx context.Context, in *PruneBlobsRequest, opts ...grpc.CallOption) (*PruneBlobsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PruneBlobsResponse)
+	err := c.cc.Invoke(ctx, AdminService_PruneBlobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) Journal(ctx context.Context, in *JournalRequest, opts ...grpc.CallOption) (*JournalResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JournalResponse)
+	err := c.cc.Invoke(ctx, AdminService_Journal_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) Profile(ctx context.Context, in *ProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProfileResponse)
+	err := c.cc.Invoke(ctx, AdminService_Profile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) StartPairing(ctx context.Context, in *StartPairingRequest, opts ...grpc.CallOption) (*StartPairingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StartPairingResponse)
+	err := c.cc.Invoke(ctx, AdminService_StartPairing_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDevicesResponse)
+	err := c.cc.Invoke(ctx, AdminService_ListDevices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) RevokeDevice(ctx context.Context, in *RevokeDeviceRequest, opts ...grpc.CallOption) (*RevokeDeviceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RevokeDeviceResponse)
+	err := c.cc.Invoke(ctx, AdminService_RevokeDevice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) Reload(ctx context.Context, in *ReloadRequest, opts ...grpc.CallOption) (*ReloadResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReloadResponse)
+	err := c.cc.Invoke(ctx, AdminService_Reload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) Shutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*ShutdownResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ShutdownResponse)
+	err := c.cc.Invoke(ctx, AdminService_Shutdown_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+// All implementations must embed UnimplementedAdminServiceServer
+// for forward compatibility.
+//
+// AdminService exposes maintenance operations for hub operators. It shares
+// the listener and authentication scheme of ClipboardService.
+type AdminServiceServer interface {
+	// Clear drops the stored contents of the named clipboards (all clipboards
+	// when all is set), e.g. during incident response after sensitive data was
+	// copied.
+	Clear(context.Context, *ClearRequest) (*ClearResponse, error)
+	// RotateToken makes a new shared secret primary while the current one stays
+	// valid for a grace period. The change is not persisted; update the
+	// server's configuration before it restarts.
+	RotateToken(context.Context, *RotateTokenRequest) (*RotateTokenResponse, error)
+	// PruneBlobs removes blobs that no clipboard references any more ahead of
+	// the periodic collection, e.g. after Clear during incident response.
+	PruneBlobs(context.Context, *PruneBlobsRequest) (*PruneBlobsResponse, error)
+	// Journal returns entries of the server's event journal: publishes and
+	// their delivery to each peer, without content. FailedPrecondition when
+	// the server keeps no journal.
+	Journal(context.Context, *JournalRequest) (*JournalResponse, error)
+	// Profile captures runtime profiles of the server process. Served only on
+	// the local IPC socket and not exposed over HTTP/JSON, so pprof data never
+	// needs to be reachable from the network.
+	Profile(context.Context, *ProfileRequest) (*ProfileResponse, error)
+	// StartPairing issues a short-lived code a new device exchanges for a
+	// per-peer token with ClipboardService.Pair, replacing any code issued
+	// before. FailedPrecondition when the server runs without a token, as
+	// issued tokens would go unenforced.
+	StartPairing(context.Context, *StartPairingRequest) (*StartPairingResponse, error)
+	// ListDevices returns the devices paired with StartPairing.
+	// FailedPrecondition when the server runs without a token.
+	ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error)
+	// RevokeDevice stops accepting a paired device's token and ends the
+	// streams it has open, without changing anyone else's. NotFound when no
+	// device matches.
+	RevokeDevice(context.Context, *RevokeDeviceRequest) (*RevokeDeviceResponse, error)
+	// Reload rereads the server's configuration file and applies the settings
+	// that can change while it runs, as SIGHUP does. FailedPrecondition when
+	// the configuration is invalid, leaving the running one in force.
+	Reload(context.Context, *ReloadRequest) (*ReloadResponse, error)
+	// Shutdown stops the server once the call returns, or restarts it with the
+	// arguments it was started with. Served only on the local IPC socket and
+	// not exposed over HTTP/JSON.
+	Shutdown(context.Context, *ShutdownRequest) (*ShutdownResponse, error)
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+// UnimplementedAdminServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServiceServer struct{}
+
+func (UnimplementedAdminServiceServer) Clear(context.Context, *ClearRequest) (*ClearResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Clear not implemented")
+}
+func (UnimplementedAdminServiceServer) RotateToken(context.Context, *RotateTokenRequest) (*RotateTokenResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RotateToken not implemented")
+}
+func (UnimplementedAdminServiceServer) PruneBlobs(context.Context, *PruneBlobsRequest) (*PruneBlobsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method PruneBlobs not implemented")
+}
+func (UnimplementedAdminServiceServer) Journal(context.Context, *JournalRequest) (*JournalResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Journal not implemented")
+}
+func (UnimplementedAdminServiceServer) Profile(context.Context, *ProfileRequest) (*ProfileResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Profile not implemented")
+}
+func (UnimplementedAdminServiceServer) StartPairing(context.Context, *StartPairingRequest) (*StartPairingResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method StartPairing not implemented")
+}
+func (UnimplementedAdminServiceServer) ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListDevices not implemented")
+}
+func (UnimplementedAdminServiceServer) RevokeDevice(context.Context, *RevokeDeviceRequest) (*RevokeDeviceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RevokeDevice not implemented")
+}
+func (UnimplementedAdminServiceServer) Reload(context.Context, *ReloadRequest) (*ReloadResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Reload not implemented")
+}
+func (UnimplementedAdminServiceServer) Shutdown(context.Context, *ShutdownRequest) (*ShutdownResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Shutdown not implemented")
+}
+func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
+func (UnimplementedAdminServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServiceServer will
+// result in compilation errors.
+type UnsafeAdminServiceServer interface {
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+func RegisterAdminServiceServer(s grpc.ServiceRegistrar, srv AdminServiceServer) {
+	// If the following call panics, it indicates UnimplementedAdminServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AdminService_ServiceDesc, srv)
+}
+
+func _AdminService_Clear_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Clear(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_Clear_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Clear(ctx, req.(*ClearRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_RotateToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).RotateToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_RotateToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).RotateToken(ctx, req.(*RotateTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_PruneBlobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PruneBlobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).PruneBlobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_PruneBlobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).PruneBlobs(ctx, req.(*PruneBlobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_Journal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JournalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Journal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_Journal_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Journal(ctx, req.(*JournalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_Profile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Profile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_Profile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Profile(ctx, req.(*ProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_StartPairing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartPairingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).StartPairing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_StartPairing_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).StartPairing(ctx, req.(*StartPairingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ListDevices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDevicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListDevices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_ListDevices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListDevices(ctx, req.(*ListDevicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_RevokeDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).RevokeDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_RevokeDevice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).RevokeDevice(ctx, req.(*RevokeDeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_Reload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Reload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_Reload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Reload(ctx, req.(*ReloadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_Shutdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShutdownRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Shutdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_Shutdown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Shutdown(ctx, req.(*ShutdownRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "suffuse.v1.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Clear",
+			Handler:    _AdminService_Clear_Handler,
+		},
+		{
+			MethodName: "RotateToken",
+			Handler:    _AdminService_RotateToken_Handler,
+		},
+		{
+			MethodName: "PruneBlobs",
+			Handler:    _AdminService_PruneBlobs_Handler,
+		},
+		{
+			MethodName: "Journal",
+			Handler:    _AdminService_Journal_Handler,
+		},
+		{
+			MethodName: "Profile",
+			Handler:    _AdminService_Profile_Handler,
+		},
+		{
+			MethodName: "StartPairing",
+			Handler:    _AdminService_StartPairing_Handler,
+		},
+		{
+			MethodName: "ListDevices",
+			Handler:    _AdminService_ListDevices_Handler,
+		},
+		{
+			MethodName: "RevokeDevice",
+			Handler:    _AdminService_RevokeDevice_Handler,
+		},
+		{
+			MethodName: "Reload",
+			Handler:    _AdminService_Reload_Handler,
+		},
+		{
+			MethodName: "Shutdown",
+			Handler:    _AdminService_Shutdown_Handler,
+		},
 	},
+	Streams:  []grpc.StreamDesc{},
 	Metadata: "suffuse/v1/suffuse.proto",
 }
@@ -0,0 +1,152 @@
+// Package accesslog provides structured access logging for the HTTP/JSON
+// gateway. Entries go to the default slog logger tagged log=access, so they
+// can be filtered apart from gRPC and application logs.
+package accesslog
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Config controls the access log.
+type Config struct {
+	// TrustForwarded takes the client address from the Forwarded,
+	// X-Forwarded-For, or X-Real-IP headers when present. Enable only behind
+	// a reverse proxy that sets them; otherwise clients can spoof it.
+	TrustForwarded bool
+
+	// Sample maps a path prefix to N: only one in N successful requests
+	// under that prefix is logged. The longest matching prefix wins. Failed
+	// requests (status >= 400) are always logged.
+	Sample map[string]int
+}
+
+// ParseSample parses "prefix=N" entries into a Config.Sample map.
+func ParseSample(entries []string) (map[string]int, error) {
+	out := make(map[string]int, len(entries))
+	for _, e := range entries {
+		prefix, n, ok := strings.Cut(e, "=")
+		if !ok {
+			return nil, fmt.Errorf("accesslog: sample %q: expected prefix=N", e)
+		}
+		rate, err := strconv.Atoi(n)
+		if err != nil || rate < 1 {
+			return nil, fmt.Errorf("accesslog: sample %q: N must be a positive integer", e)
+		}
+		out[prefix] = rate
+	}
+	return out, nil
+}
+
+// sampler counts requests for one sampled prefix.
+type sampler struct {
+	prefix string
+	every  uint64
+	n      atomic.Uint64
+}
+
+// Handler wraps next with access logging.
+func Handler(cfg Config, next http.Handler) http.Handler {
+	log := slog.Default().With("log", "access")
+	var samplers []*sampler
+	for prefix, every := range cfg.Sample {
+		samplers = append(samplers, &sampler{prefix: prefix, every: uint64(every)})
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		if rw.status < 400 {
+			// Log the first request under a sampled prefix and every Nth after.
+			if s := match(samplers, r.URL.Path); s != nil && (s.n.Add(1)-1)%s.every != 0 {
+				return
+			}
+		}
+		log.LogAttrs(r.Context(), slog.LevelInfo, "http request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rw.status),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int64("bytes", rw.bytes),
+			slog.String("remote", remoteAddr(r, cfg.TrustForwarded)),
+		)
+	})
+}
+
+// match returns the sampler with the longest prefix of path, or nil.
+func match(samplers []*sampler, path string) *sampler {
+	var best *sampler
+	for _, s := range samplers {
+		if strings.HasPrefix(path, s.prefix) && (best == nil || len(s.prefix) > len(best.prefix)) {
+			best = s
+		}
+	}
+	return best
+}
+
+// remoteAddr returns the client IP, from forwarding headers when trusted.
+func remoteAddr(r *http.Request, trustForwarded bool) string {
+	if trustForwarded {
+		if fwd := r.Header.Get("Forwarded"); fwd != "" {
+			for _, part := range strings.Split(strings.Split(fwd, ",")[0], ";") {
+				if k, v, ok := strings.Cut(strings.TrimSpace(part), "="); ok && strings.EqualFold(k, "for") {
+					v = strings.Trim(v, `"`)
+					if host, _, err := net.SplitHostPort(v); err == nil {
+						v = host
+					}
+					return strings.Trim(v, "[]")
+				}
+			}
+		}
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			return strings.TrimSpace(strings.Split(xff, ",")[0])
+		}
+		if xr := r.Header.Get("X-Real-IP"); xr != "" {
+			return strings.TrimSpace(xr)
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// responseWriter records the status code and body size.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush keeps streaming responses (Watch) working through the wrapper.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,124 @@
+// Package audit records every clipboard call a server answers — who made it,
+// on which clipboard, which types and how much content moved, and whether it
+// was allowed — so deployments can account for data crossing the hub.
+// Clipboard content is never recorded.
+//
+// The audit log is a file of JSON lines, one per call, written as each call
+// ends. Unlike the event journal it is never rotated or trimmed by the
+// server; rotate it with a tool that truncates in place (logrotate's
+// copytruncate), or write it to standard output for a log collector.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Stdout is the path that writes the audit log to standard output.
+const Stdout = "-"
+
+// Outcomes of calls.
+const (
+	OutcomeOK = "ok"
+	// OutcomeDenied: the caller's token was missing, invalid, or does not
+	// allow the call.
+	OutcomeDenied = "denied"
+	// OutcomeFailed: the call was allowed but failed, e.g. refused by a size
+	// limit, content filter or quota.
+	OutcomeFailed = "failed"
+)
+
+// Entry is one audit record.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	// Transport is "tcp", or "ipc" for the local socket.
+	Transport string `json:"transport"`
+	Addr      string `json:"addr"`
+	// ForwardedFor is the client address the HTTP/JSON gateway, or a proxy,
+	// claims the call came from.
+	ForwardedFor string `json:"forwarded_for,omitempty"`
+	// Token is the name of the caller's scoped token; empty for the shared
+	// tokens and the IPC socket.
+	Token string `json:"token,omitempty"`
+	// Source is the source name the caller presented.
+	Source    string `json:"source,omitempty"`
+	Clipboard string `json:"clipboard,omitempty"`
+	// Origin is the source of the content returned, for reads.
+	Origin string   `json:"origin,omitempty"`
+	Types  []string `json:"types,omitempty"`
+	// Items counts the items copied or returned, Events the updates sent
+	// by a Watch.
+	Items  int `json:"items,omitempty"`
+	Events int `json:"events,omitempty"`
+	// Bytes is the content that moved, not counting blob references.
+	Bytes      int64  `json:"bytes"`
+	DurationMS int64  `json:"duration_ms"`
+	Outcome    string `json:"outcome"`
+	// Code is the gRPC status code of a call that did not succeed, and
+	// Detail its message.
+	Code   string `json:"code,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Log appends entries to the audit log. The zero of *Log (nil) records
+// nothing.
+type Log struct {
+	path string
+
+	mu sync.Mutex
+	w  io.Writer
+	// failed suppresses repeated write errors until a write succeeds.
+	failed bool
+}
+
+// New opens the audit log at path, creating it with mode 0600 and its
+// directory with 0700, or returns one writing to standard output for Stdout.
+func New(path string) (*Log, error) {
+	if path == Stdout {
+		return &Log{path: path, w: os.Stdout}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	return &Log{path: path, w: f}, nil
+}
+
+// Record appends e, stamping it with the current time when unset. Each entry
+// is written with a single write, so none is lost to a crash after its call
+// was answered.
+func (l *Log) Record(e Entry) {
+	if l == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.w.Write(line)
+	if err == nil {
+		l.failed = false
+		return
+	}
+	if !l.failed {
+		slog.Error("audit log write failed", "path", l.path, "err", err)
+	}
+	l.failed = true
+}
@@ -0,0 +1,396 @@
+// Package blob implements the server's content-addressed store for large
+// clipboard items.
+//
+// Items larger than the store's threshold are kept once, keyed by SHA-256,
+// and travel to peers that accept references as a BlobRef (hash + size)
+// instead of inline data. Such peers call ClipboardService.Fetch for the
+// content only when they actually need it, so a screenshot copied on one host
+// is not broadcast in full to every watcher and federated server.
+//
+// Text items above a lower threshold of their own can be referenced too,
+// keeping the beginning of the text inline as a preview: a watcher shows an
+// enormous log paste at once and fetches the rest only when it is pasted.
+//
+// A downstream server that receives references from its upstreams adds a
+// Fetcher for each; content it does not hold is fetched from upstream on
+// first use and cached locally.
+//
+// Blobs are reference counted by the hub's clipboards: Prune removes blobs
+// that no clipboard references and that have not been stored or read for a
+// grace period, so downstream servers still fetching a just-replaced item are
+// not cut short.
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// Defaults for the server's blob settings.
+const (
+	// DefaultThreshold is the item size above which items are stored by
+	// reference.
+	DefaultThreshold = 1 << 20
+	// DefaultTTL is how long an unreferenced blob is kept after its last use.
+	DefaultTTL = time.Hour
+	// DefaultTextThreshold is the text item size above which text items are
+	// stored by reference with a preview.
+	DefaultTextThreshold = 256 << 10
+	// DefaultTextPreview is the size of the preview of a referenced text
+	// item.
+	DefaultTextPreview = 4 << 10
+)
+
+var (
+	// ErrNotFound is returned for a blob that is neither stored nor fetchable.
+	ErrNotFound = errors.New("blob not found")
+	// ErrDeferred is returned by a Fetcher that declines to transfer a blob
+	// for now, e.g. while traffic shaping holds back non-text items.
+	ErrDeferred = errors.New("blob transfer deferred")
+)
+
+// Fetcher retrieves content the store does not hold, e.g. from the upstream
+// server that sent the reference.
+type Fetcher func(ctx context.Context, sum string) ([]byte, error)
+
+// Store is an in-memory content-addressed blob store. It is safe for
+// concurrent use.
+type Store struct {
+	cfg Config
+
+	mu       sync.Mutex
+	blobs    map[string]*entry // hex SHA-256 → content
+	bytes    int64             // total size of blobs
+	fetchers []*Fetcher
+
+	pruned      uint64
+	prunedBytes uint64
+}
+
+type entry struct {
+	data     []byte
+	lastUsed time.Time // last Put or Get
+}
+
+// Stats is a snapshot of the store for metrics and status.
+type Stats struct {
+	// Blobs and Bytes describe the blobs currently held.
+	Blobs int
+	Bytes int64
+	// Pruned and PrunedBytes count blobs removed by Prune since the store
+	// was created.
+	Pruned      uint64
+	PrunedBytes uint64
+}
+
+// PruneResult describes the blobs removed by one Prune.
+type PruneResult struct {
+	Blobs int
+	Bytes int64
+}
+
+// Config configures which items a Store references.
+type Config struct {
+	// Threshold is the item size in bytes above which items are stored by
+	// reference.
+	Threshold int
+	// TextThreshold is the size above which text items are stored by
+	// reference, when it is below Threshold.
+	TextThreshold int
+	// TextPreview is the size of the beginning of a referenced text item
+	// kept inline as its preview; zero keeps none.
+	TextPreview int
+}
+
+// NewStore returns an empty Store that references items as cfg describes.
+// Thresholds of zero or less disable referencing; the store then only
+// caches content fetched for references received from elsewhere.
+func NewStore(cfg Config) *Store {
+	return &Store{
+		cfg:   cfg,
+		blobs: make(map[string]*entry),
+	}
+}
+
+// AddFetcher adds a Fetcher consulted for blobs the store does not hold.
+// Fetchers are tried in the order they were added. remove takes f out again,
+// e.g. when its upstream link is closed.
+func (s *Store) AddFetcher(f Fetcher) (remove func()) {
+	p := &f
+	s.mu.Lock()
+	s.fetchers = append(s.fetchers, p)
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		// Get iterates over the slice it read without the lock, so it is
+		// replaced rather than modified.
+		s.fetchers = slices.DeleteFunc(slices.Clone(s.fetchers), func(q *Fetcher) bool { return q == p })
+		s.mu.Unlock()
+	}
+}
+
+// Put stores data and returns its reference.
+func (s *Store) Put(data []byte) *pb.BlobRef {
+	sum := Sum(data)
+	s.mu.Lock()
+	if e, ok := s.blobs[sum]; ok {
+		e.lastUsed = time.Now()
+	} else {
+		s.blobs[sum] = &entry{data: data, lastUsed: time.Now()}
+		s.bytes += int64(len(data))
+	}
+	s.mu.Unlock()
+	return &pb.BlobRef{Sha256: sum, Size: uint64(len(data))}
+}
+
+// Has reports whether the store holds the blob.
+func (s *Store) Has(sum string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.blobs[sum]
+	return ok
+}
+
+// Get returns the content of a blob, consulting the Fetchers in turn when
+// the store does not hold it. A Fetcher that fails is skipped for the next
+// one, except with ErrDeferred; the first failure is returned if none
+// succeeds. Fetched content is verified against sum and cached.
+func (s *Store) Get(ctx context.Context, sum string) ([]byte, error) {
+	s.mu.Lock()
+	e, ok := s.blobs[sum]
+	if ok {
+		e.lastUsed = time.Now()
+	}
+	fetchers := s.fetchers
+	s.mu.Unlock()
+	if ok {
+		return e.data, nil
+	}
+	firstErr := ErrNotFound
+	for _, fetch := range fetchers {
+		data, err := (*fetch)(ctx, sum)
+		if err == nil && Sum(data) != sum {
+			err = errors.New("content does not match its hash")
+		}
+		switch {
+		case err == nil:
+			s.Put(data)
+			return data, nil
+		case errors.Is(err, ErrDeferred):
+			return nil, fmt.Errorf("fetch blob %s: %w", short(sum), err)
+		case firstErr == ErrNotFound:
+			firstErr = err
+		}
+	}
+	if firstErr == ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return nil, fmt.Errorf("fetch blob %s: %w", short(sum), firstErr)
+}
+
+// Prune removes blobs for which referenced returns false and that have not
+// been used for unusedFor. With dryRun set nothing is removed and the result
+// describes what would have been.
+func (s *Store) Prune(referenced func(sum string) bool, unusedFor time.Duration, dryRun bool) PruneResult {
+	cutoff := time.Now().Add(-unusedFor)
+	var res PruneResult
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sum, e := range s.blobs {
+		if referenced(sum) || e.lastUsed.After(cutoff) {
+			continue
+		}
+		res.Blobs++
+		res.Bytes += int64(len(e.data))
+		if !dryRun {
+			delete(s.blobs, sum)
+		}
+	}
+	if !dryRun {
+		s.bytes -= res.Bytes
+		s.pruned += uint64(res.Blobs)
+		s.prunedBytes += uint64(res.Bytes)
+	}
+	return res
+}
+
+// Stats returns the current store counters.
+func (s *Store) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Stats{
+		Blobs:       len(s.blobs),
+		Bytes:       s.bytes,
+		Pruned:      s.pruned,
+		PrunedBytes: s.prunedBytes,
+	}
+}
+
+// Externalize returns items with every item larger than the threshold
+// stored and replaced by its reference, text items keeping a preview of
+// their content. items itself is not modified.
+func (s *Store) Externalize(items []*pb.ClipboardItem) []*pb.ClipboardItem {
+	if s.cfg.Threshold <= 0 && s.cfg.TextThreshold <= 0 {
+		return items
+	}
+	var out []*pb.ClipboardItem
+	for i, it := range items {
+		if it.Ref != nil || !s.references(it) {
+			if out != nil {
+				out = append(out, it)
+			}
+			continue
+		}
+		if out == nil {
+			out = append(make([]*pb.ClipboardItem, 0, len(items)), items[:i]...)
+		}
+		ref := &pb.ClipboardItem{Mime: it.Mime, OriginalCharset: it.OriginalCharset, Sha256: it.Sha256, Ref: s.Put(it.Data)}
+		if IsText(it.Mime) {
+			ref.Data = Preview(it.Data, s.cfg.TextPreview)
+		}
+		out = append(out, ref)
+	}
+	if out == nil {
+		return items
+	}
+	return out
+}
+
+// references reports whether it is large enough to be stored by reference.
+func (s *Store) references(it *pb.ClipboardItem) bool {
+	if s.cfg.Threshold > 0 && len(it.Data) > s.cfg.Threshold {
+		return true
+	}
+	return s.cfg.TextThreshold > 0 && len(it.Data) > s.cfg.TextThreshold && IsText(it.Mime)
+}
+
+// IsText reports whether items of type mime are text, which keep a preview
+// when referenced.
+func IsText(mime string) bool {
+	return strings.HasPrefix(mime, "text/")
+}
+
+// Preview returns the beginning of text, at most size bytes, ending with a
+// whole line when one ends in its second half and never inside a UTF-8
+// sequence. It returns nil for a size of zero or less.
+func Preview(text []byte, size int) []byte {
+	if size <= 0 {
+		return nil
+	}
+	if len(text) <= size {
+		return text
+	}
+	cut := size
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if nl := bytes.LastIndexByte(text[:cut], '\n'); nl >= size/2 {
+		cut = nl + 1
+	}
+	return slices.Clone(text[:cut])
+}
+
+// Resolve returns items with every reference replaced by its content.
+// items itself is not modified.
+func (s *Store) Resolve(ctx context.Context, items []*pb.ClipboardItem) ([]*pb.ClipboardItem, error) {
+	if !HasRefs(items) {
+		return items, nil
+	}
+	out := make([]*pb.ClipboardItem, len(items))
+	for i, it := range items {
+		if it.Ref == nil {
+			out[i] = it
+			continue
+		}
+		data, err := s.Get(ctx, it.Ref.Sha256)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", it.Mime, err)
+		}
+		out[i] = &pb.ClipboardItem{Mime: it.Mime, OriginalCharset: it.OriginalCharset, Sha256: it.Sha256, Data: data}
+	}
+	return out, nil
+}
+
+// HasRefs reports whether any item is a blob reference.
+func HasRefs(items []*pb.ClipboardItem) bool {
+	for _, it := range items {
+		if it.Ref != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// SameContent reports whether a and b hold the same types and content,
+// whether inline or referenced. Items carrying their digest are compared by
+// it without hashing their content again.
+func SameContent(a, b []*pb.ClipboardItem) bool {
+	return slices.EqualFunc(a, b, func(x, y *pb.ClipboardItem) bool {
+		return x.Mime == y.Mime && Digest(x) == Digest(y)
+	})
+}
+
+// Digest returns the hex SHA-256 of the content of it, whether inline or
+// referenced: the digest it carries, or else one computed.
+func Digest(it *pb.ClipboardItem) string {
+	if it.Sha256 != "" {
+		return it.Sha256
+	}
+	return contentSum(it)
+}
+
+// WithDigests returns items with the digest of each item's content set, so
+// comparing them later costs no more than comparing digests. Digests items
+// already carry are computed again, as their content may have changed since.
+// items itself is not modified.
+func WithDigests(items []*pb.ClipboardItem) []*pb.ClipboardItem {
+	out := make([]*pb.ClipboardItem, len(items))
+	for i, it := range items {
+		if sum := contentSum(it); it.Sha256 != sum {
+			out[i] = &pb.ClipboardItem{Mime: it.Mime, Data: it.Data, Ref: it.Ref, OriginalCharset: it.OriginalCharset, Sha256: sum}
+		} else {
+			out[i] = it
+		}
+	}
+	return out
+}
+
+func contentSum(it *pb.ClipboardItem) string {
+	if it.Ref != nil {
+		return it.Ref.Sha256
+	}
+	return Sum(it.Data)
+}
+
+// ItemSize returns the content size of it, whether inline or referenced.
+func ItemSize(it *pb.ClipboardItem) int {
+	if it.Ref != nil {
+		return int(it.Ref.Size)
+	}
+	return len(it.Data)
+}
+
+// Sum returns the hex SHA-256 of data, the key of a blob.
+func Sum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// short abbreviates a blob hash for error messages.
+func short(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
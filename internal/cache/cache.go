@@ -0,0 +1,270 @@
+// Package cache keeps the hub's recent clipboard contents in an encrypted
+// file, so a laptop's last clipboard is available again right after a
+// reboot — written back to the system clipboard and served to paste — before
+// the upstream link or any other peer has reconnected.
+//
+// The file holds the most recently updated clipboards that fit in a size
+// budget. It is encrypted with XChaCha20-Poly1305 under a key derived with
+// Argon2id from a passphrase (normally the server token) and a random salt
+// kept in the file header:
+//
+//	version (1) ‖ salt (16) ‖ nonce (24) ‖ ciphertext
+//
+// A cache written under a different passphrase cannot be opened; it is
+// ignored and replaced on the next save.
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
+	"go.klb.dev/suffuse/internal/hub"
+)
+
+// DefaultMaxBytes bounds the cached content when Config.MaxBytes is unset.
+const DefaultMaxBytes = 16 << 20
+
+const (
+	version       = 1
+	saltSize      = 16
+	flushInterval = 2 * time.Second
+	resolveWait   = 30 * time.Second
+)
+
+// additionalData binds the ciphertext to its purpose.
+var additionalData = []byte("suffuse-cache-v1")
+
+// ErrDecrypt is returned by Load for a cache that cannot be authenticated,
+// usually because the passphrase changed since it was written.
+var ErrDecrypt = errors.New("cache: decryption failed (passphrase changed?)")
+
+// Config describes the cache file.
+type Config struct {
+	// Path is the cache file. Its directory is created with mode 0700.
+	Path string
+	// Passphrase derives the encryption key. Must not be empty.
+	Passphrase string
+	// MaxBytes bounds the total item size kept; zero means DefaultMaxBytes.
+	// Clipboards that do not fit are left out, oldest first.
+	MaxBytes int
+}
+
+// Cache persists the contents of one hub.
+type Cache struct {
+	cfg  Config
+	h    *hub.Hub
+	salt []byte
+	key  []byte
+
+	saved uint64 // hub version last written or loaded
+}
+
+// DefaultPath returns the cache file location under the user's cache
+// directory, e.g. ~/.cache/suffuse/clipboard.cache on Linux.
+func DefaultPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "suffuse", "clipboard.cache")
+}
+
+// New returns a Cache for h. The salt of an existing cache file is reused so
+// it can be loaded; otherwise a new one is generated.
+func New(cfg Config, h *hub.Hub) (*Cache, error) {
+	if cfg.Passphrase == "" {
+		return nil, errors.New("cache: empty passphrase")
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	salt := make([]byte, saltSize)
+	if data, err := os.ReadFile(cfg.Path); err == nil && len(data) > 1+saltSize && data[0] == version {
+		copy(salt, data[1:])
+	} else if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("cache: salt: %w", err)
+	}
+	return &Cache{
+		cfg:  cfg,
+		h:    h,
+		salt: salt,
+		key:  argon2.IDKey([]byte(cfg.Passphrase), salt, 1, 64*1024, 4, chacha20poly1305.KeySize),
+	}, nil
+}
+
+// Load restores the cached clipboards into the hub and returns how many were
+// restored. A missing file is not an error.
+func (c *Cache) Load() (int, error) {
+	data, err := os.ReadFile(c.cfg.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("cache: %w", err)
+	}
+	plain, err := c.open(data)
+	if err != nil {
+		return 0, err
+	}
+	var cached pb.ClipboardCache
+	if err := proto.Unmarshal(plain, &cached); err != nil {
+		return 0, fmt.Errorf("cache: unmarshal: %w", err)
+	}
+	saved := make([]hub.StoredClipboard, 0, len(cached.Clipboards))
+	for _, cc := range cached.Clipboards {
+		saved = append(saved, hub.StoredClipboard{
+			Clipboard: cc.Clipboard,
+			Source:    cc.Source,
+			Items:     cc.Items,
+			UpdatedAt: cc.UpdatedAt.AsTime(),
+		})
+	}
+	c.h.Restore(saved)
+	_, c.saved = c.h.Snapshot()
+	return len(saved), nil
+}
+
+// Run writes the cache whenever the hub's contents change, checking every
+// few seconds, until ctx is cancelled, and once more then so changes made
+// since the last check, such as an admin clear, are not lost.
+func (c *Cache) Run(ctx context.Context) {
+	slog.Info("clipboard cache enabled", "path", c.cfg.Path, "max_bytes", c.cfg.MaxBytes)
+
+	t := time.NewTicker(flushInterval)
+	defer t.Stop()
+	for {
+		save := ctx
+		select {
+		case <-ctx.Done():
+			save = context.WithoutCancel(ctx)
+		case <-t.C:
+		}
+		if err := c.save(save); err != nil {
+			slog.Warn("clipboard cache write failed", "path", c.cfg.Path, "err", err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// save writes the current hub contents if they changed since the last save.
+// The file is removed when the hub holds nothing, e.g. after admin clear.
+func (c *Cache) save(ctx context.Context) error {
+	clipboards, ver := c.h.Snapshot()
+	if ver == c.saved {
+		return nil
+	}
+	if len(clipboards) == 0 {
+		if err := os.Remove(c.cfg.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		c.saved = ver
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveWait)
+	defer cancel()
+	var cached pb.ClipboardCache
+	budget := c.cfg.MaxBytes
+	for _, sc := range clipboards {
+		size := 0
+		for _, it := range sc.Items {
+			size += blob.ItemSize(it)
+		}
+		if size > budget {
+			continue
+		}
+		items, err := c.h.Resolve(ctx, sc.Items)
+		if err != nil {
+			slog.Debug("clipboard not cached", "clipboard", sc.Clipboard, "err", err)
+			continue
+		}
+		budget -= size
+		cached.Clipboards = append(cached.Clipboards, &pb.CachedClipboard{
+			Clipboard: sc.Clipboard,
+			Source:    sc.Source,
+			Items:     items,
+			UpdatedAt: timestamppb.New(sc.UpdatedAt),
+		})
+	}
+
+	plain, err := proto.Marshal(&cached)
+	if err != nil {
+		return err
+	}
+	sealed, err := c.seal(plain)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(c.cfg.Path, sealed); err != nil {
+		return err
+	}
+	c.saved = ver
+	return nil
+}
+
+func (c *Cache) seal(plain []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(c.key)
+	if err != nil {
+		return nil, err
+	}
+	head := 1 + saltSize + aead.NonceSize()
+	out := make([]byte, head, head+len(plain)+aead.Overhead())
+	out[0] = version
+	copy(out[1:], c.salt)
+	nonce := out[1+saltSize : head]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("cache: nonce: %w", err)
+	}
+	return aead.Seal(out, nonce, plain, additionalData), nil
+}
+
+func (c *Cache) open(data []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(c.key)
+	if err != nil {
+		return nil, err
+	}
+	head := 1 + saltSize + aead.NonceSize()
+	if len(data) < head || data[0] != version {
+		return nil, errors.New("cache: unsupported file format")
+	}
+	plain, err := aead.Open(nil, data[1+saltSize:head], data[head:], additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+// writeFile replaces path atomically with a file readable only by the owner.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".clipboard.cache-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,210 @@
+// Package charset converts clipboard text that is not UTF-8 to UTF-8. Legacy
+// applications put text on the clipboard in the code page of the system they
+// run on, Windows-1252 or Shift-JIS say, which peers reading it as UTF-8
+// show garbled.
+//
+// The character set is the one the MIME type declares, if any; otherwise it
+// is detected: UTF-16 by its byte order mark or its zero bytes, and the
+// legacy code pages by decoding the text with each and scoring how much the
+// result looks like text of the languages the code page is used for. The
+// detection is a heuristic that needs a few non-ASCII characters to tell the
+// East Asian code pages apart; Windows-1252 wins a tie.
+package charset
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+// UTF8 is the name of the character set ToUTF8 converts to.
+const UTF8 = "utf-8"
+
+// ToUTF8 returns data converted to UTF-8 and the name of the character set
+// it was converted from, e.g. "windows-1252": declared if that names a
+// known character set, or else the one detected. Data that is UTF-8 already,
+// or whose character set is not recognised, is returned as it is with an
+// empty name.
+func ToUTF8(data []byte, declared string) ([]byte, string) {
+	if declared != "" {
+		if enc, err := htmlindex.Get(declared); err == nil {
+			name, _ := htmlindex.Name(enc)
+			if name == UTF8 {
+				return data, ""
+			}
+			if out, err := enc.NewDecoder().Bytes(data); err == nil {
+				return out, name
+			}
+		}
+	}
+	if name, enc := utf16Of(data); enc != nil {
+		if out, err := enc.NewDecoder().Bytes(data); err == nil {
+			return out, name
+		}
+	}
+	if utf8.Valid(data) {
+		return data, ""
+	}
+	name, out := detect(data)
+	return out, name
+}
+
+// candidate is a character set detect tries.
+type candidate struct {
+	name string
+	enc  encoding.Encoding
+	// script reports whether r belongs to the scripts of the languages the
+	// character set is written in.
+	script func(r rune) bool
+	// common are frequent characters of those languages, which count
+	// extra: text misread in another code page rarely hits them.
+	common string
+}
+
+// candidates are the legacy code pages detect tries, Windows-1252 first so
+// it wins a tie.
+var candidates = []candidate{
+	{name: "windows-1252", enc: charmap.Windows1252, script: isLatin},
+	{name: "shift_jis", enc: japanese.ShiftJIS, script: isJapanese},
+	{name: "euc-jp", enc: japanese.EUCJP, script: isJapanese},
+	{name: "gbk", enc: simplifiedchinese.GBK, script: isCJK, common: "的一是不了在人有我他这个们中来上大为和国地到以说时要就出会可也你对生能而子那得于着下自之年过发后作里用道行所然家种事成方多经么去法学如都同现当没动面起看定天分还进好小部其些主样理心她本前开但因只从想实日"},
+	{name: "big5", enc: traditionalchinese.Big5, script: isCJK, common: "的一是不了在人有我他這個們中來上大為和國地到以說時要就出會可也你對生能而子那得於著下自之年過發後作裡用道行所然家種事成方多經麼去法學如都同現當沒動面起看定天分還進好小部其些主樣理心她本前開但因只從想實日"},
+	{name: "euc-kr", enc: korean.EUCKR, script: isKorean, common: "이다는에의가을하고지를로서한기도사대어인수자있것리나그아요들게정시으보해일전내면상제부만했주구장적말우없까여라세"},
+}
+
+// detect returns the legacy code page data is most likely in and
+// data converted from it, or an empty name and data unchanged when no
+// candidate decodes it.
+func detect(data []byte) (string, []byte) {
+	var (
+		best      []byte
+		bestName  string
+		bestScore int
+	)
+	for _, c := range candidates {
+		out, err := c.enc.NewDecoder().Bytes(data)
+		if err != nil || bytes.ContainsRune(out, utf8.RuneError) {
+			continue
+		}
+		if s := score(string(out), c); best == nil || s > bestScore {
+			best, bestName, bestScore = out, c.name, s
+		}
+	}
+	if best == nil {
+		return "", data
+	}
+	return bestName, best
+}
+
+// utf16Of returns the UTF-16 encoding data is in, judging by its byte order
+// mark or, without one, by most of its high or low bytes being zero as in
+// text mostly of Latin letters, or a nil encoding.
+func utf16Of(data []byte) (string, encoding.Encoding) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		return "utf-16le", xunicode.UTF16(xunicode.LittleEndian, xunicode.ExpectBOM)
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		return "utf-16be", xunicode.UTF16(xunicode.BigEndian, xunicode.ExpectBOM)
+	case len(data) < 4 || len(data)%2 != 0:
+		return "", nil
+	}
+	var even, odd int
+	for i := 0; i < len(data); i += 2 {
+		if data[i] == 0 {
+			even++
+		}
+		if data[i+1] == 0 {
+			odd++
+		}
+	}
+	switch half := len(data) / 4; {
+	case odd > half && even == 0:
+		return "utf-16le", xunicode.UTF16(xunicode.LittleEndian, xunicode.IgnoreBOM)
+	case even > half && odd == 0:
+		return "utf-16be", xunicode.UTF16(xunicode.BigEndian, xunicode.IgnoreBOM)
+	}
+	return "", nil
+}
+
+// score rates how much s, decoded with c, looks like text in c's languages:
+// each non-ASCII character of their scripts counts for it, and frequent ones
+// count extra; other non-ASCII characters count against it. Latin letters
+// and punctuation count only next to ASCII text, as they are in Windows-1252
+// text, while an East Asian code page misread as Windows-1252 yields runs of
+// them. An East Asian character wedged between ASCII letters counts for
+// nothing, since East Asian text does not run into Latin words that way,
+// while Latin text misread in an East Asian code page easily does.
+func score(s string, c candidate) int {
+	runes := []rune(s)
+	total := 0
+	for i, r := range runes {
+		if r < utf8.RuneSelf {
+			continue
+		}
+		prev, next := runeAt(runes, i-1), runeAt(runes, i+1)
+		switch {
+		case !c.script(r):
+			total -= 2
+		case c.enc == charmap.Windows1252:
+			switch {
+			case isASCIILetter(prev) || isASCIILetter(next):
+				total += 2
+			case prev < utf8.RuneSelf && next < utf8.RuneSelf:
+				total++
+			default:
+				total--
+			}
+		case isASCIILetter(prev) && isASCIILetter(next):
+		case strings.ContainsRune(c.common, r):
+			total += 3
+		default:
+			total += 2
+		}
+	}
+	return total
+}
+
+func runeAt(runes []rune, i int) rune {
+	if i < 0 || i >= len(runes) {
+		return ' '
+	}
+	return runes[i]
+}
+
+func isASCIILetter(r rune) bool {
+	return r < utf8.RuneSelf && unicode.IsLetter(r)
+}
+
+// isLatin reports whether r is a Latin-1 letter or typographic punctuation
+// of Windows-1252: quotes, dashes, the ellipsis, bullet and euro sign.
+func isLatin(r rune) bool {
+	return r >= 0xa0 && r <= 0xff && r != 0xd7 && r != 0xf7 ||
+		unicode.In(r, unicode.Latin) ||
+		strings.ContainsRune("‘’‚“”„–—…•€™", r)
+}
+
+// isCJK reports whether r is a Han character, CJK punctuation or a
+// full-width form, all shared by the East Asian code pages.
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		r >= 0x3000 && r <= 0x303f || // CJK symbols and punctuation
+		r >= 0xff01 && r <= 0xff5e // full-width ASCII
+}
+
+func isJapanese(r rune) bool {
+	return isCJK(r) || unicode.In(r, unicode.Hiragana, unicode.Katakana) && (r < 0xff61 || r > 0xff9f)
+}
+
+func isKorean(r rune) bool {
+	return isCJK(r) || unicode.Is(unicode.Hangul, r) && r >= 0xac00
+}
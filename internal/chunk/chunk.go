@@ -0,0 +1,63 @@
+// Package chunk splits clipboard items into bounded chunks for CopyStream and
+// PasteStream, and assembles them again, so content of any size can cross a
+// gRPC connection whose messages are limited to a few MiB.
+package chunk
+
+import (
+	"errors"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// Size is the most data a chunk carries.
+const Size = 1 << 20
+
+// Split returns the chunks carrying items. Every item starts a chunk of its
+// own, so an empty item is still sent. Items must be inline: blob references
+// are not chunked.
+func Split(items []*pb.ClipboardItem) []*pb.ItemChunk {
+	var out []*pb.ItemChunk
+	for _, it := range items {
+		data := it.Data
+		for first := true; first || len(data) > 0; first = false {
+			n := min(len(data), Size)
+			c := &pb.ItemChunk{Data: data[:n]}
+			if first {
+				c.Mime = it.Mime
+			}
+			out = append(out, c)
+			data = data[n:]
+		}
+	}
+	return out
+}
+
+// ErrNoItem is returned by Assembler.Add for data that arrives before the
+// chunk starting its item.
+var ErrNoItem = errors.New("chunk data before the start of an item")
+
+// Assembler rebuilds items from their chunks.
+type Assembler struct {
+	items []*pb.ClipboardItem
+}
+
+// Add appends c to the items assembled so far. A nil chunk adds nothing.
+func (a *Assembler) Add(c *pb.ItemChunk) error {
+	switch {
+	case c == nil:
+		return nil
+	case c.Mime != "":
+		a.items = append(a.items, &pb.ClipboardItem{Mime: c.Mime, Data: c.Data})
+	case len(a.items) == 0:
+		return ErrNoItem
+	default:
+		last := a.items[len(a.items)-1]
+		last.Data = append(last.Data, c.Data...)
+	}
+	return nil
+}
+
+// Items returns the items assembled so far.
+func (a *Assembler) Items() []*pb.ClipboardItem {
+	return a.items
+}
@@ -1,19 +1,173 @@
 // Package clip provides a unified interface to the system clipboard across
 // platforms. Build constraints select the appropriate implementation:
 //
-//	clip_darwin.go   — macOS via golang.design/x/clipboard + cgo changeCount
-//	clip_windows.go  — Windows via golang.design/x/clipboard + AddClipboardFormatListener
-//	clip_linux.go    — Linux via golang.design/x/clipboard, polling only
+//	clip_darwin.go   — macOS via NSPasteboard (cgo), polling changeCount
+//	clip_windows.go  — Windows via golang.design/x/clipboard (read), Win32 (write) + AddClipboardFormatListener
+//	clip_wayland.go  — Linux on Wayland via ext-/wlr-data-control, event driven
+//	clip_linux.go    — Linux via golang.design/x/clipboard, polling (X11, or Wayland without data-control)
+//	clip_x11_selection.go — Linux X11 CLIPBOARD and PRIMARY selections via XFIXES, event driven (polling without it)
+//	clip_osc52.go    — terminal emulator via OSC 52 escape sequences, write-mostly (NewOSC52)
 //	clip_other.go    — headless / container stub
 package clip
 
-import pb "go.klb.dev/suffuse/gen/suffuse/v1"
+import (
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
 
 // Backend is the interface that all platform clipboard implementations satisfy.
 type Backend interface {
 	Name() string
+	Capabilities() Capabilities
 	Read() ([]*pb.ClipboardItem, error)
+	// Write replaces the clipboard contents with items in a single clipboard
+	// transaction, so neither Watch nor other applications ever observe a
+	// partially written clipboard. Backends that can hold only one
+	// representation keep the first one they support. The change made by
+	// Write itself is not reported by Watch.
 	Write(items []*pb.ClipboardItem) error
 	Watch() <-chan struct{}
 	Close()
 }
+
+// Watch granularity reported in Capabilities.Watch.
+const (
+	WatchEvent = "event" // OS change notifications
+	WatchPoll  = "poll"  // periodic comparison of the clipboard contents
+	WatchNone  = "none"  // changes are never reported
+)
+
+// Capabilities describes what a Backend can hold and how it notices changes.
+// The local peer subscribes only to MIMETypes, so a backend is never handed
+// items it would have to drop.
+type Capabilities struct {
+	// MIMETypes lists the types Write can store and Read can return. Empty
+	// means the backend stores nothing, unless AnyMIMEType is set.
+	MIMETypes []string
+	// AnyMIMEType reports that Write stores items of any type, so the local
+	// peer subscribes to everything.
+	AnyMIMEType bool
+	// Watch is WatchEvent, WatchPoll or WatchNone.
+	Watch string
+	// PollInterval is the polling period when Watch is WatchPoll.
+	PollInterval time.Duration
+	// AtomicWrite reports that Write stores every one of MIMETypes in one
+	// transaction; otherwise only the first supported item is kept.
+	AtomicWrite bool
+}
+
+// Proto converts c to its wire form for PeerInfo.
+func (c Capabilities) Proto(name string) *pb.ClipboardBackend {
+	out := &pb.ClipboardBackend{
+		Name:        name,
+		MimeTypes:   c.MIMETypes,
+		Watch:       c.Watch,
+		AtomicWrite: c.AtomicWrite,
+		AnyMimeType: c.AnyMIMEType,
+	}
+	if c.Watch == WatchPoll {
+		out.PollInterval = durationpb.New(c.PollInterval)
+	}
+	return out
+}
+
+// SensitiveMime marks content copied from a password manager. Backends
+// return an item of this type with the data "secret" alongside such content,
+// when the clipboard carries the platform's marker (x-kde-passwordManagerHint
+// on Linux, org.nspasteboard.ConcealedType on macOS,
+// ExcludeClipboardContentFromMonitorProcessing on Windows), and set the
+// marker when they write one.
+const SensitiveMime = "x-kde-passwordManagerHint"
+
+// sensitiveValue is the data of a SensitiveMime item that marks content
+// sensitive.
+const sensitiveValue = "secret"
+
+// SensitiveItem returns the item marking content sensitive.
+func SensitiveItem() *pb.ClipboardItem {
+	return &pb.ClipboardItem{Mime: SensitiveMime, Data: []byte(sensitiveValue)}
+}
+
+// IsSensitive reports whether items are marked sensitive.
+func IsSensitive(items []*pb.ClipboardItem) bool {
+	for _, it := range items {
+		if it.Mime == SensitiveMime && string(it.Data) == sensitiveValue {
+			return true
+		}
+	}
+	return false
+}
+
+// UniversalClipboardMime marks content that reached this Mac from another
+// Apple device through Universal Clipboard: the macOS backend returns an
+// item of this type alongside content the pasteboard flags as remote
+// (com.apple.is-remote-clipboard). No backend writes it.
+const UniversalClipboardMime = "com.apple.is-remote-clipboard"
+
+// FromUniversalClipboard returns items without the UniversalClipboardMime
+// marker and reports whether they carried it.
+func FromUniversalClipboard(items []*pb.ClipboardItem) ([]*pb.ClipboardItem, bool) {
+	i := slices.IndexFunc(items, func(it *pb.ClipboardItem) bool { return it.Mime == UniversalClipboardMime })
+	if i < 0 {
+		return items, false
+	}
+	return slices.Delete(slices.Clone(items), i, i+1), true
+}
+
+// HostOnlyWriter is implemented by backends whose clipboard the operating
+// system itself shares with the user's other devices, as Universal Clipboard
+// does on macOS 13 and later. After SetHostOnly(true), Write keeps what it
+// stores on this device.
+type HostOnlyWriter interface {
+	SetHostOnly(hostOnly bool)
+}
+
+// URIListMime is the type of file references: copied files are carried as a
+// text/uri-list (RFC 2483) of file:// URLs, one per line, which backends map
+// to and from the platform's own file list (CF_HDROP, NSFilenamesPboardType).
+const URIListMime = "text/uri-list"
+
+// FormatURIList returns the text/uri-list naming paths, which must be
+// absolute.
+func FormatURIList(paths []string) []byte {
+	var b strings.Builder
+	for _, p := range paths {
+		p = filepath.ToSlash(p)
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p // C:/x → /C:/x
+		}
+		u := url.URL{Scheme: "file", Path: p}
+		b.WriteString(u.String())
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String())
+}
+
+// ParseURIList returns the local paths named by a text/uri-list. Comments,
+// URLs of other schemes and files on other hosts are skipped.
+func ParseURIList(data []byte) []string {
+	var paths []string
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") || u.Path == "" {
+			continue
+		}
+		p := u.Path
+		if len(p) > 2 && p[0] == '/' && p[2] == ':' {
+			p = p[1:] // /C:/x → C:/x
+		}
+		paths = append(paths, filepath.FromSlash(p))
+	}
+	return paths
+}
@@ -5,49 +5,169 @@ package clip
 // #cgo CFLAGS: -x objective-c
 // #cgo LDFLAGS: -framework Cocoa
 // #import <Cocoa/Cocoa.h>
+// #include <stdlib.h>
+// #include <string.h>
 //
 // NSInteger suffuse_changeCount() {
 //     return [[NSPasteboard generalPasteboard] changeCount];
 // }
+//
+// // The file list type. It is a property list of paths rather than data, which
+// // suffuse_read and suffuse_write exchange as newline-separated UTF-8.
+// static NSString* const suffuseFilenamesType = @"NSFilenamesPboardType";
+//
+// // Copies the data for uti into a malloc'd buffer, or returns NULL with
+// // *len = -1 if the pasteboard has no such representation. A PNG request is
+// // satisfied from TIFF data when no PNG is present, as screenshots and many
+// // apps only provide TIFF.
+// void* suffuse_read(const char* uti, int* len) {
+//     @autoreleasepool {
+//         NSPasteboard* pb = [NSPasteboard generalPasteboard];
+//         NSString* type = [NSString stringWithUTF8String:uti];
+//         NSData* data = nil;
+//         if ([type isEqualToString:suffuseFilenamesType]) {
+//             id paths = [pb propertyListForType:type];
+//             if ([paths isKindOfClass:[NSArray class]] && [paths count] > 0) {
+//                 data = [[paths componentsJoinedByString:@"\n"] dataUsingEncoding:NSUTF8StringEncoding];
+//             }
+//         } else {
+//             data = [pb dataForType:type];
+//         }
+//         if (data == nil && [type isEqualToString:NSPasteboardTypePNG]) {
+//             NSData* tiff = [pb dataForType:NSPasteboardTypeTIFF];
+//             if (tiff != nil) {
+//                 NSBitmapImageRep* rep = [NSBitmapImageRep imageRepWithData:tiff];
+//                 data = [rep representationUsingType:NSBitmapImageFileTypePNG properties:@{}];
+//             }
+//         }
+//         if (data == nil) {
+//             *len = -1;
+//             return NULL;
+//         }
+//         *len = (int)[data length];
+//         void* buf = malloc([data length] > 0 ? [data length] : 1);
+//         memcpy(buf, [data bytes], [data length]);
+//         return buf;
+//     }
+// }
+//
+// // Reports whether the pasteboard declares uti, whatever its data.
+// int suffuse_has_type(const char* uti) {
+//     @autoreleasepool {
+//         NSString* type = [NSString stringWithUTF8String:uti];
+//         return [[[NSPasteboard generalPasteboard] types] containsObject:type];
+//     }
+// }
+//
+// // Replaces the pasteboard contents with n representations. declareTypes
+// // clears the pasteboard once, so the whole write is a single changeCount
+// // bump; the new changeCount is returned. With hostOnly the pasteboard is
+// // cleared for contents Universal Clipboard leaves on this Mac instead,
+// // where macOS supports it.
+// NSInteger suffuse_write(int n, char** utis, void** datas, int* lens, int hostOnly) {
+//     @autoreleasepool {
+//         NSPasteboard* pb = [NSPasteboard generalPasteboard];
+//         NSMutableArray* types = [NSMutableArray arrayWithCapacity:n];
+//         for (int i = 0; i < n; i++) {
+//             [types addObject:[NSString stringWithUTF8String:utis[i]]];
+//         }
+//         BOOL cleared = NO;
+//         if (hostOnly) {
+//             if (@available(macOS 13.0, *)) {
+//                 [pb prepareForNewContentsWithOptions:NSPasteboardContentsCurrentHostOnly];
+//                 cleared = YES;
+//             }
+//         }
+//         if (!cleared) {
+//             [pb declareTypes:types owner:nil];
+//         }
+//         for (int i = 0; i < n; i++) {
+//             NSData* data = [NSData dataWithBytes:datas[i] length:lens[i]];
+//             if ([types[i] isEqualToString:suffuseFilenamesType]) {
+//                 NSString* paths = [[[NSString alloc] initWithData:data encoding:NSUTF8StringEncoding] autorelease];
+//                 [pb setPropertyList:[paths componentsSeparatedByString:@"\n"] forType:types[i]];
+//             } else {
+//                 [pb setData:data forType:types[i]];
+//             }
+//         }
+//         return [pb changeCount];
+//     }
+// }
 import "C"
 
 import (
 	"fmt"
-	"log/slog"
+	"strings"
+	"sync/atomic"
 	"time"
-
-	"golang.design/x/clipboard"
+	"unsafe"
 
 	pb "go.klb.dev/suffuse/gen/suffuse/v1"
 )
 
 const darwinPollInterval = 100 * time.Millisecond
 
+// darwinTypes maps MIME types to the pasteboard types (UTIs) read and
+// written, in the order items are returned by Read.
+var darwinTypes = []struct{ mime, uti string }{
+	{"text/plain", "public.utf8-plain-text"},
+	{"text/html", "public.html"},
+	{"text/rtf", "public.rtf"},
+	{"image/png", "public.png"},
+	{URIListMime, darwinFilenamesType},
+	{SensitiveMime, darwinConcealedType},
+}
+
+// darwinFilenamesType is the pasteboard type of copied files, exchanged with
+// the C helpers as newline-separated paths.
+const darwinFilenamesType = "NSFilenamesPboardType"
+
+// darwinConcealedType marks content password managers copy
+// (nspasteboard.org). Its presence counts, whatever its data.
+const darwinConcealedType = "org.nspasteboard.ConcealedType"
+
+// darwinRemoteType is set on content Universal Clipboard brought from
+// another device.
+const darwinRemoteType = "com.apple.is-remote-clipboard"
+
 type darwinBackend struct {
-	lastChange C.NSInteger
-	watchCh    chan struct{}
-	done       chan struct{}
+	// lastChange is the changeCount already accounted for: the last one
+	// seen by poll or produced by our own Write.
+	lastChange atomic.Int64
+	// hostOnly keeps what Write stores out of Universal Clipboard.
+	hostOnly atomic.Bool
+	watchCh  chan struct{}
+	done     chan struct{}
 }
 
-// New returns the macOS clipboard backend.
-// clipboard.Init is called here rather than in init() so that CLI sub-commands
-// (status, copy, paste) that never construct a Backend don't log spurious
-// warnings on headless systems.
+// New returns the macOS clipboard backend, which talks to NSPasteboard
+// directly so every representation of a clipboard update is read and
+// written in one pasteboard change.
 func New() Backend {
-	if err := clipboard.Init(); err != nil {
-		slog.Warn("clipboard init failed", "err", err)
-	}
 	b := &darwinBackend{
-		lastChange: C.suffuse_changeCount(),
-		watchCh:    make(chan struct{}, 1),
-		done:       make(chan struct{}),
+		watchCh: make(chan struct{}, 1),
+		done:    make(chan struct{}),
 	}
+	b.lastChange.Store(int64(C.suffuse_changeCount()))
 	go b.poll()
 	return b
 }
 
 func (b *darwinBackend) Name() string { return "macOS NSPasteboard" }
 
+func (b *darwinBackend) Capabilities() Capabilities {
+	mimes := make([]string, len(darwinTypes))
+	for i, t := range darwinTypes {
+		mimes[i] = t.mime
+	}
+	return Capabilities{
+		MIMETypes:    mimes,
+		Watch:        WatchPoll,
+		PollInterval: darwinPollInterval,
+		AtomicWrite:  true,
+	}
+}
+
 func (b *darwinBackend) poll() {
 	t := time.NewTicker(darwinPollInterval)
 	defer t.Stop()
@@ -56,9 +176,8 @@ func (b *darwinBackend) poll() {
 		case <-b.done:
 			return
 		case <-t.C:
-			cc := C.suffuse_changeCount()
-			if cc != b.lastChange {
-				b.lastChange = cc
+			cc := int64(C.suffuse_changeCount())
+			if b.lastChange.Swap(cc) != cc {
 				select {
 				case b.watchCh <- struct{}{}:
 				default:
@@ -70,28 +189,92 @@ func (b *darwinBackend) poll() {
 
 func (b *darwinBackend) Read() ([]*pb.ClipboardItem, error) {
 	var items []*pb.ClipboardItem
-	if text := clipboard.Read(clipboard.FmtText); text != nil {
-		items = append(items, &pb.ClipboardItem{Mime: "text/plain", Data: text})
+	for _, t := range darwinTypes {
+		uti := C.CString(t.uti)
+		var n C.int
+		buf := C.suffuse_read(uti, &n)
+		C.free(unsafe.Pointer(uti))
+		if buf == nil {
+			continue
+		}
+		data := C.GoBytes(buf, n)
+		C.free(buf)
+		switch t.uti {
+		case darwinFilenamesType:
+			data = FormatURIList(strings.Split(string(data), "\n"))
+		case darwinConcealedType:
+			data = SensitiveItem().Data
+		}
+		if len(data) > 0 {
+			items = append(items, &pb.ClipboardItem{Mime: t.mime, Data: data})
+		}
 	}
-	if img := clipboard.Read(clipboard.FmtImage); img != nil {
-		items = append(items, &pb.ClipboardItem{Mime: "image/png", Data: img})
+	if len(items) > 0 && b.hasType(darwinRemoteType) {
+		items = append(items, &pb.ClipboardItem{Mime: UniversalClipboardMime})
 	}
 	return items, nil
 }
 
+func (b *darwinBackend) hasType(uti string) bool {
+	cUTI := C.CString(uti)
+	defer C.free(unsafe.Pointer(cUTI))
+	return C.suffuse_has_type(cUTI) != 0
+}
+
+// Write replaces the pasteboard contents with all items at once. Our own
+// change is not reported by Watch.
 func (b *darwinBackend) Write(items []*pb.ClipboardItem) error {
-	for _, it := range items {
-		switch it.Mime {
-		case "text/plain":
-			clipboard.Write(clipboard.FmtText, it.Data)
-		case "image/png":
-			clipboard.Write(clipboard.FmtImage, it.Data)
-		default:
+	if len(items) == 0 {
+		return nil
+	}
+	utis := make([]string, len(items))
+	for i, it := range items {
+		for _, t := range darwinTypes {
+			if t.mime == it.Mime {
+				utis[i] = t.uti
+			}
+		}
+		if utis[i] == "" {
 			return fmt.Errorf("unsupported MIME type: %s", it.Mime)
 		}
 	}
+
+	n := len(items)
+	ptrSize := C.size_t(unsafe.Sizeof(uintptr(0)))
+	cUTIs := unsafe.Slice((**C.char)(C.malloc(C.size_t(n)*ptrSize)), n)
+	cData := unsafe.Slice((*unsafe.Pointer)(C.malloc(C.size_t(n)*ptrSize)), n)
+	cLens := unsafe.Slice((*C.int)(C.malloc(C.size_t(n)*C.size_t(unsafe.Sizeof(C.int(0))))), n)
+	defer func() {
+		for i := range n {
+			C.free(unsafe.Pointer(cUTIs[i]))
+			C.free(cData[i])
+		}
+		C.free(unsafe.Pointer(&cUTIs[0]))
+		C.free(unsafe.Pointer(&cData[0]))
+		C.free(unsafe.Pointer(&cLens[0]))
+	}()
+	for i, it := range items {
+		data := it.Data
+		if utis[i] == darwinFilenamesType {
+			data = []byte(strings.Join(ParseURIList(data), "\n"))
+		}
+		cUTIs[i] = C.CString(utis[i])
+		cData[i] = C.CBytes(data)
+		cLens[i] = C.int(len(data))
+	}
+
+	var hostOnly C.int
+	if b.hostOnly.Load() {
+		hostOnly = 1
+	}
+	cc := C.suffuse_write(C.int(n), &cUTIs[0], &cData[0], &cLens[0], hostOnly)
+	b.lastChange.Store(int64(cc))
 	return nil
 }
 
+// SetHostOnly keeps what Write stores out of Universal Clipboard, on macOS
+// 13 and later.
+func (b *darwinBackend) SetHostOnly(hostOnly bool) { b.hostOnly.Store(hostOnly) }
+
 func (b *darwinBackend) Watch() <-chan struct{} { return b.watchCh }
-func (b *darwinBackend) Close()                { close(b.done) }
+func (b *darwinBackend) Close()                 { close(b.done) }
@@ -10,6 +10,7 @@ type headlessBackend struct {
 }
 
 func (b *headlessBackend) Name() string                       { return "headless (no-op)" }
+func (b *headlessBackend) Capabilities() Capabilities         { return Capabilities{Watch: WatchNone} }
 func (b *headlessBackend) Read() ([]*pb.ClipboardItem, error) { return nil, nil }
 func (b *headlessBackend) Write(_ []*pb.ClipboardItem) error  { return nil }
 func (b *headlessBackend) Watch() <-chan struct{}              { return b.watchCh }
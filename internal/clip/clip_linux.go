@@ -6,6 +6,8 @@ import (
 	"bytes"
 	"fmt"
 	"log/slog"
+	"os"
+	"sync"
 	"time"
 
 	"golang.design/x/clipboard"
@@ -16,17 +18,36 @@ import (
 const linuxPollInterval = 250 * time.Millisecond
 
 type linuxBackend struct {
-	watchCh  chan struct{}
-	done     chan struct{}
+	watchCh chan struct{}
+	done    chan struct{}
+
+	mu       sync.Mutex // guards lastText and lastImg
 	lastText []byte
 	lastImg  []byte
 }
 
-// New returns the Linux clipboard backend, or a headless no-op backend if
-// the display environment is unavailable (e.g. a headless server without X11
-// or Wayland). clipboard.Init is called here rather than in init() so that
-// CLI sub-commands (status, copy, paste) don't trigger the warning.
+// New returns the native Wayland backend when the compositor supports a
+// data-control protocol, otherwise the X11 CLIPBOARD selection backend when
+// the X server accepts our connection, otherwise the polling Linux clipboard
+// backend through Xlib, or a headless no-op backend if the display
+// environment is unavailable (e.g. a headless server without X11 or
+// Wayland). clipboard.Init is called here rather than in init() so that CLI
+// sub-commands (status, copy, paste) don't trigger the warning.
 func New() Backend {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		wb, err := newWaylandBackend(false)
+		if err == nil {
+			return wb
+		}
+		slog.Info("Wayland data-control unavailable", "err", err)
+	}
+	if os.Getenv("DISPLAY") != "" {
+		xb, err := newX11SelectionBackend(x11Clipboard)
+		if err == nil {
+			return xb
+		}
+		slog.Info("X11 clipboard selection unavailable, polling instead", "err", err)
+	}
 	if err := clipboard.Init(); err != nil {
 		slog.Warn("clipboard unavailable, running headless", "err", err)
 		return &headlessBackend{watchCh: make(chan struct{})}
@@ -39,8 +60,34 @@ func New() Backend {
 	return b
 }
 
+// NewPrimary returns a backend for the primary selection, the text last
+// selected with the mouse and pasted with the middle button: through Wayland
+// data-control when available, otherwise from the X server named by DISPLAY
+// (which under XWayland sees X11 clients only).
+func NewPrimary() (Backend, error) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		wb, err := newWaylandBackend(true)
+		if err == nil {
+			return wb, nil
+		}
+		if os.Getenv("DISPLAY") == "" {
+			return nil, err
+		}
+		slog.Info("Wayland primary selection unavailable, using X11", "err", err)
+	}
+	return newX11SelectionBackend(x11Primary)
+}
+
 func (b *linuxBackend) Name() string { return "Linux clipboard (poll)" }
 
+func (b *linuxBackend) Capabilities() Capabilities {
+	return Capabilities{
+		MIMETypes:    []string{"text/plain", "image/png"},
+		Watch:        WatchPoll,
+		PollInterval: linuxPollInterval,
+	}
+}
+
 func (b *linuxBackend) poll() {
 	t := time.NewTicker(linuxPollInterval)
 	defer t.Stop()
@@ -49,11 +96,15 @@ func (b *linuxBackend) poll() {
 		case <-b.done:
 			return
 		case <-t.C:
+			// Held across the reads so a concurrent Write cannot be
+			// mistaken for an external change.
+			b.mu.Lock()
 			text := clipboard.Read(clipboard.FmtText)
 			img := clipboard.Read(clipboard.FmtImage)
-			if !bytes.Equal(text, b.lastText) || !bytes.Equal(img, b.lastImg) {
-				b.lastText = text
-				b.lastImg = img
+			changed := !bytes.Equal(text, b.lastText) || !bytes.Equal(img, b.lastImg)
+			b.lastText, b.lastImg = text, img
+			b.mu.Unlock()
+			if changed {
 				select {
 				case b.watchCh <- struct{}{}:
 				default:
@@ -74,16 +125,34 @@ func (b *linuxBackend) Read() ([]*pb.ClipboardItem, error) {
 	return items, nil
 }
 
+// Write takes clipboard ownership once with the first supported item.
+// golang.design/x/clipboard offers a single target per selection, so writing
+// every item in turn would expose each intermediate state to other
+// applications and to poll; only the leading (preferred) representation is
+// kept instead. Our own write is not reported by Watch.
 func (b *linuxBackend) Write(items []*pb.ClipboardItem) error {
 	for _, it := range items {
+		var format clipboard.Format
 		switch it.Mime {
 		case "text/plain":
-			clipboard.Write(clipboard.FmtText, it.Data)
+			format = clipboard.FmtText
 		case "image/png":
-			clipboard.Write(clipboard.FmtImage, it.Data)
+			format = clipboard.FmtImage
 		default:
-			return fmt.Errorf("unsupported MIME type: %s", it.Mime)
+			continue
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		clipboard.Write(format, it.Data)
+		if format == clipboard.FmtText {
+			b.lastText, b.lastImg = it.Data, nil
+		} else {
+			b.lastText, b.lastImg = nil, it.Data
 		}
+		return nil
+	}
+	if len(items) > 0 {
+		return fmt.Errorf("unsupported MIME type: %s", items[0].Mime)
 	}
 	return nil
 }
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package clip
+
+import "errors"
+
+// NewPrimary fails: the primary selection exists on Linux (X11 and Wayland)
+// only.
+func NewPrimary() (Backend, error) {
+	return nil, errors.New("no primary selection on this platform")
+}
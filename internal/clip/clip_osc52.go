@@ -0,0 +1,198 @@
+//go:build !windows
+
+package clip
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+const (
+	// osc52MaxBytes caps the text written in one sequence. Terminals apply
+	// their own, often smaller, limits and silently drop longer sequences.
+	osc52MaxBytes = 1 << 20
+	// osc52ReadTimeout bounds the wait for a terminal to answer a query;
+	// most terminals ignore queries unless reading is enabled.
+	osc52ReadTimeout = time.Second
+	// osc52ScreenChunk is the longest string GNU screen passes through in
+	// one DCS sequence.
+	osc52ScreenChunk = 768
+)
+
+// osc52Backend reaches the clipboard of the terminal emulator through OSC 52
+// escape sequences on the controlling terminal, so it works inside an SSH
+// session with no display server on the remote host. Only text is carried.
+// Terminals never report clipboard changes, so Watch never fires; Read
+// queries the terminal, which answers only if clipboard reading is enabled.
+type osc52Backend struct {
+	mu      sync.Mutex
+	tty     *os.File
+	wrap    string // "tmux", "screen" or ""
+	watchCh chan struct{}
+}
+
+// NewOSC52 opens the controlling terminal for an OSC 52 backend. It fails
+// when the process has no terminal, e.g. when run as a service.
+func NewOSC52() (Backend, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("no controlling terminal: %w", err)
+	}
+	b := &osc52Backend{tty: tty, watchCh: make(chan struct{})}
+	switch {
+	case os.Getenv("TMUX") != "":
+		b.wrap = "tmux"
+	case os.Getenv("STY") != "":
+		b.wrap = "screen"
+	}
+	return b, nil
+}
+
+func (b *osc52Backend) Name() string {
+	if b.wrap != "" {
+		return "OSC 52 terminal (via " + b.wrap + ")"
+	}
+	return "OSC 52 terminal"
+}
+
+func (b *osc52Backend) Capabilities() Capabilities {
+	return Capabilities{
+		MIMETypes:   []string{"text/plain"},
+		Watch:       WatchNone,
+		AtomicWrite: true,
+	}
+}
+
+// Write sends the first text/plain item to the terminal's clipboard.
+func (b *osc52Backend) Write(items []*pb.ClipboardItem) error {
+	for _, it := range items {
+		if it.Mime != "text/plain" {
+			continue
+		}
+		if len(it.Data) > osc52MaxBytes {
+			return fmt.Errorf("OSC 52: %d bytes of text exceeds the %d byte limit", len(it.Data), osc52MaxBytes)
+		}
+		seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString(it.Data) + "\x07"
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		_, err := b.tty.WriteString(b.passthrough(seq))
+		return err
+	}
+	return nil
+}
+
+// passthrough wraps seq so a terminal multiplexer forwards it to the outer
+// terminal instead of interpreting it.
+func (b *osc52Backend) passthrough(seq string) string {
+	switch b.wrap {
+	case "tmux":
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case "screen":
+		var sb strings.Builder
+		for len(seq) > 0 {
+			n := min(len(seq), osc52ScreenChunk)
+			sb.WriteString("\x1bP" + seq[:n] + "\x1b\\")
+			seq = seq[n:]
+		}
+		return sb.String()
+	}
+	return seq
+}
+
+// Read asks the terminal for its clipboard. A terminal that does not answer
+// within osc52ReadTimeout is treated as having an empty clipboard.
+func (b *osc52Backend) Read() ([]*pb.ClipboardItem, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	restore, err := b.rawMode()
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+
+	if _, err := b.tty.WriteString(b.passthrough("\x1b]52;c;?\x07")); err != nil {
+		return nil, err
+	}
+	if err := b.tty.SetReadDeadline(time.Now().Add(osc52ReadTimeout)); err != nil {
+		return nil, fmt.Errorf("OSC 52: %w", err)
+	}
+	defer b.tty.SetReadDeadline(time.Time{})
+
+	// The reply is ESC ] 52 ; c ; <base64> terminated by BEL or ST.
+	var reply []byte
+	buf := make([]byte, 4096)
+	for {
+		n, err := b.tty.Read(buf)
+		reply = append(reply, buf[:n]...)
+		if end := bytes.IndexByte(reply, '\a'); end >= 0 {
+			reply = reply[:end]
+			break
+		}
+		if end := bytes.Index(reply, []byte("\x1b\\")); end >= 0 {
+			reply = reply[:end]
+			break
+		}
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(reply) > base64.StdEncoding.EncodedLen(osc52MaxBytes)+64 {
+			return nil, errors.New("OSC 52: reply too long")
+		}
+	}
+	start := bytes.Index(reply, []byte("\x1b]52;"))
+	if start < 0 {
+		return nil, nil
+	}
+	_, payload, ok := bytes.Cut(reply[start+len("\x1b]52;"):], []byte(";"))
+	if !ok || len(payload) == 0 {
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(string(payload))
+	if err != nil {
+		return nil, fmt.Errorf("OSC 52: decode reply: %w", err)
+	}
+	return []*pb.ClipboardItem{{Mime: "text/plain", Data: data}}, nil
+}
+
+// rawMode switches the terminal to raw mode so the reply to a query is
+// neither echoed nor held back until a newline, and returns a function that
+// restores the previous settings. stty gets a terminal handle of its own:
+// passing b.tty to a child would switch it to blocking mode and disable the
+// read deadline.
+func (b *osc52Backend) rawMode() (restore func(), err error) {
+	stty := func(args ...string) ([]byte, error) {
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			return nil, err
+		}
+		defer tty.Close()
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = tty
+		return cmd.Output()
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("OSC 52: save terminal settings: %w", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("OSC 52: set raw mode: %w", err)
+	}
+	return func() { _, _ = stty(strings.TrimSpace(string(saved))) }, nil
+}
+
+func (b *osc52Backend) Watch() <-chan struct{} { return b.watchCh }
+
+func (b *osc52Backend) Close() { b.tty.Close() }
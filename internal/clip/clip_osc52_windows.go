@@ -0,0 +1,9 @@
+package clip
+
+import "errors"
+
+// NewOSC52 fails: the Windows console has no /dev/tty to send OSC 52
+// sequences to.
+func NewOSC52() (Backend, error) {
+	return nil, errors.New("OSC 52 backend is not supported on Windows")
+}
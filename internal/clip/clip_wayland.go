@@ -0,0 +1,417 @@
+//go:build linux
+
+package clip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// Data-control managers in order of preference. Both protocols have the
+// same requests and events; ext-data-control is the standardised successor
+// of wlr-data-control. They let a client without a focused surface own and
+// observe the selection, which the core wl_data_device does not.
+var dataControlManagers = []string{
+	"ext_data_control_manager_v1",
+	"zwlr_data_control_manager_v1",
+}
+
+// Request and event opcodes of the data-control interfaces.
+const (
+	dcManagerCreateDataSource = 0
+	dcManagerGetDataDevice    = 1
+
+	dcDeviceSetSelection        = 0
+	dcDeviceSetPrimarySelection = 2
+	dcDeviceEvDataOffer         = 0
+	dcDeviceEvSelection         = 1
+	dcDeviceEvFinished          = 2
+	dcDeviceEvPrimary           = 3
+
+	dcSourceOffer       = 0
+	dcSourceDestroy     = 1
+	dcSourceEvSend      = 0
+	dcSourceEvCancelled = 1
+
+	dcOfferReceive = 0
+	dcOfferDestroy = 1
+	dcOfferEvOffer = 0
+)
+
+// waylandOwnerMIME is offered alongside our own selections so the selection
+// event they cause is not reported by Watch.
+const waylandOwnerMIME = "application/x-suffuse-owner"
+
+// waylandTextTypes are the names text is offered under by GTK, Qt and X11
+// clients, in order of preference when reading. Writes offer them all, plus
+// the legacy X11 names.
+var (
+	waylandTextTypes  = []string{"text/plain;charset=utf-8", "text/plain", "UTF8_STRING"}
+	waylandLegacyText = []string{"TEXT", "STRING"}
+)
+
+const (
+	waylandReadTimeout = 2 * time.Second
+	waylandMaxItem     = 64 << 20
+)
+
+// waylandBackend owns the selection through a data-control protocol: changes
+// arrive as events, and every MIME type an application offers can be read
+// and written, not just text and PNG. With primary set it follows the primary
+// selection (middle-click paste) instead of the clipboard.
+type waylandBackend struct {
+	c       *wlConn
+	primary bool
+	iface   string
+	manager uint32
+	device  uint32
+	watchCh chan struct{}
+
+	mu        sync.Mutex
+	offers    map[uint32]*wlOffer          // announced, not yet selected
+	selection *wlOffer                     // current selection, nil if empty
+	sources   map[uint32]map[string][]byte // our sources → data per offered type
+	closed    bool
+}
+
+type wlOffer struct {
+	id    uint32
+	mimes []string
+}
+
+// newWaylandBackend connects to the compositor and binds a data-control
+// manager. It fails when the compositor supports neither protocol (e.g.
+// older GNOME releases), so New can fall back to polling, or when primary is
+// set and the protocol version lacks the primary selection.
+func newWaylandBackend(primary bool) (*waylandBackend, error) {
+	c, err := dialWayland()
+	if err != nil {
+		return nil, err
+	}
+	b := &waylandBackend{
+		c:       c,
+		primary: primary,
+		watchCh: make(chan struct{}, 1),
+		offers:  make(map[uint32]*wlOffer),
+		sources: make(map[uint32]map[string][]byte),
+	}
+	if err := b.setup(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	go b.loop()
+	return b, nil
+}
+
+func (b *waylandBackend) setup() error {
+	type global struct {
+		name    uint32
+		version uint32
+	}
+	globals := make(map[string]global)
+	registry := b.c.newID()
+	if err := b.c.request(wlDisplayID, 1, registry); err != nil { // wl_display.get_registry
+		return err
+	}
+	err := b.c.roundtrip(func(ev wlEvent) error {
+		switch {
+		case ev.sender == wlDisplayID && ev.opcode == 0:
+			return wlDisplayError(ev)
+		case ev.sender == registry && ev.opcode == 0: // wl_registry.global
+			a := wlArgs{b: ev.args}
+			name, iface, version := a.uint(), a.string(), a.uint()
+			if _, ok := globals[iface]; !ok && a.err == nil {
+				globals[iface] = global{name, version}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	seat, ok := globals["wl_seat"]
+	if !ok {
+		return errors.New("compositor has no seat")
+	}
+	for _, iface := range dataControlManagers {
+		g, ok := globals[iface]
+		if !ok {
+			continue
+		}
+		// wlr-data-control gained the primary selection in version 2;
+		// ext-data-control has it from the start.
+		version := uint32(1)
+		if b.primary && iface == "zwlr_data_control_manager_v1" {
+			if g.version < 2 {
+				return errors.New("compositor's wlr-data-control lacks the primary selection")
+			}
+			version = 2
+		}
+		b.iface = iface
+		b.manager = b.c.newID()
+		if err := b.c.request(registry, 0, g.name, iface, version, b.manager); err != nil { // wl_registry.bind
+			return err
+		}
+		break
+	}
+	if b.manager == 0 {
+		return errors.New("compositor supports neither ext-data-control nor wlr-data-control")
+	}
+	seatID := b.c.newID()
+	if err := b.c.request(registry, 0, seat.name, "wl_seat", uint32(1), seatID); err != nil {
+		return err
+	}
+	b.device = b.c.newID()
+	if err := b.c.request(b.manager, dcManagerGetDataDevice, b.device, seatID); err != nil {
+		return err
+	}
+	// Receive the current selection before the first Read.
+	return b.c.roundtrip(b.dispatch)
+}
+
+func (b *waylandBackend) Name() string {
+	what := "clipboard"
+	if b.primary {
+		what = "primary selection"
+	}
+	return fmt.Sprintf("Wayland %s (%s)", what, strings.TrimSuffix(strings.TrimPrefix(b.iface, "z"), "_manager_v1"))
+}
+
+func (b *waylandBackend) Capabilities() Capabilities {
+	return Capabilities{
+		AnyMIMEType: true,
+		Watch:       WatchEvent,
+		AtomicWrite: true,
+	}
+}
+
+// loop dispatches events until the connection fails or Close is called.
+func (b *waylandBackend) loop() {
+	defer close(b.watchCh)
+	for {
+		ev, err := b.c.next()
+		if err == nil {
+			err = b.dispatch(ev)
+		}
+		if err != nil {
+			b.mu.Lock()
+			closed := b.closed
+			b.mu.Unlock()
+			if !closed {
+				slog.Error("Wayland clipboard stopped", "err", err)
+			}
+			return
+		}
+	}
+}
+
+func (b *waylandBackend) dispatch(ev wlEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	a := wlArgs{b: ev.args}
+
+	switch {
+	case ev.sender == wlDisplayID:
+		if ev.opcode == 0 {
+			return wlDisplayError(ev)
+		}
+	case ev.sender == b.device:
+		switch ev.opcode {
+		case dcDeviceEvDataOffer:
+			id := a.uint()
+			b.offers[id] = &wlOffer{id: id}
+		case dcDeviceEvSelection, dcDeviceEvPrimary:
+			id := a.uint()
+			if (ev.opcode == dcDeviceEvPrimary) == b.primary {
+				b.setSelection(id)
+			} else if id != 0 {
+				// The other selection; not followed by this backend.
+				delete(b.offers, id)
+				_ = b.c.request(id, dcOfferDestroy)
+			}
+		case dcDeviceEvFinished:
+			return errors.New("data-control device finished (seat removed?)")
+		}
+	case b.offers[ev.sender] != nil:
+		if ev.opcode == dcOfferEvOffer {
+			o := b.offers[ev.sender]
+			o.mimes = append(o.mimes, a.string())
+		}
+	case b.sources[ev.sender] != nil:
+		switch ev.opcode {
+		case dcSourceEvSend:
+			mime := a.string()
+			fd, err := b.c.takeFD()
+			if err != nil {
+				return err
+			}
+			go serveSelection(fd, b.sources[ev.sender][mime])
+		case dcSourceEvCancelled:
+			delete(b.sources, ev.sender)
+			_ = b.c.request(ev.sender, dcSourceDestroy)
+		}
+	}
+	return a.err
+}
+
+// setSelection makes offer id (0 for an empty selection) current, destroying
+// the previous one, and reports changes made by other clients.
+// Must be called with b.mu held.
+func (b *waylandBackend) setSelection(id uint32) {
+	if old := b.selection; old != nil {
+		_ = b.c.request(old.id, dcOfferDestroy)
+	}
+	b.selection = b.offers[id]
+	delete(b.offers, id)
+	if b.selection == nil || slices.Contains(b.selection.mimes, waylandOwnerMIME) {
+		return
+	}
+	select {
+	case b.watchCh <- struct{}{}:
+	default:
+	}
+}
+
+// serveSelection writes data to a client pasting our selection.
+func serveSelection(fd int, data []byte) {
+	f := os.NewFile(uintptr(fd), "wayland-send")
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		slog.Debug("Wayland clipboard send failed", "err", err)
+	}
+}
+
+// Read returns every MIME type of the current selection. Text is returned
+// first as text/plain whichever name it was offered under; X11 target names
+// without a slash are skipped, except the SensitiveMime marker.
+func (b *waylandBackend) Read() ([]*pb.ClipboardItem, error) {
+	b.mu.Lock()
+	offer := b.selection
+	var mimes []string
+	if offer != nil {
+		mimes = slices.Clone(offer.mimes)
+	}
+	b.mu.Unlock()
+	if offer == nil {
+		return nil, nil
+	}
+
+	var items []*pb.ClipboardItem
+	for _, want := range waylandTextTypes {
+		if slices.Contains(mimes, want) {
+			data, err := b.receive(offer, want)
+			if err != nil {
+				return nil, err
+			}
+			if data != nil {
+				items = append(items, &pb.ClipboardItem{Mime: "text/plain", Data: data})
+			}
+			break
+		}
+	}
+	for _, mime := range mimes {
+		if (!strings.Contains(mime, "/") && mime != SensitiveMime) || strings.HasPrefix(mime, "text/plain") || mime == waylandOwnerMIME {
+			continue
+		}
+		data, err := b.receive(offer, mime)
+		if err != nil {
+			return nil, err
+		}
+		if data != nil {
+			items = append(items, &pb.ClipboardItem{Mime: mime, Data: data})
+		}
+	}
+	return items, nil
+}
+
+// receive reads one type of offer through a pipe. It returns nil without an
+// error when the selection has changed in the meantime; Watch reports the
+// new one.
+func (b *waylandBackend) receive(offer *wlOffer, mime string) ([]byte, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	b.mu.Lock()
+	if b.selection != offer {
+		b.mu.Unlock()
+		w.Close()
+		return nil, nil
+	}
+	err = b.c.request(offer.id, dcOfferReceive, mime, wlFD(w.Fd()))
+	b.mu.Unlock()
+	w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	_ = r.SetReadDeadline(time.Now().Add(waylandReadTimeout))
+	data, err := io.ReadAll(io.LimitReader(r, waylandMaxItem))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", mime, err)
+	}
+	return data, nil
+}
+
+// Write offers all items in one new selection. text/plain is also offered
+// under the names other toolkits look for.
+func (b *waylandBackend) Write(items []*pb.ClipboardItem) error {
+	data := make(map[string][]byte)
+	var mimes []string
+	add := func(mime string, d []byte) {
+		if _, ok := data[mime]; !ok {
+			data[mime] = d
+			mimes = append(mimes, mime)
+		}
+	}
+	for _, it := range items {
+		add(it.Mime, it.Data)
+		if it.Mime == "text/plain" {
+			for _, alias := range slices.Concat(waylandTextTypes, waylandLegacyText) {
+				add(alias, it.Data)
+			}
+		}
+	}
+	if len(mimes) == 0 {
+		return nil
+	}
+	add(waylandOwnerMIME, nil)
+
+	id := b.c.newID()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sources[id] = data
+	if err := b.c.request(b.manager, dcManagerCreateDataSource, id); err != nil {
+		return err
+	}
+	for _, mime := range mimes {
+		if err := b.c.request(id, dcSourceOffer, mime); err != nil {
+			return err
+		}
+	}
+	if b.primary {
+		return b.c.request(b.device, dcDeviceSetPrimarySelection, id)
+	}
+	return b.c.request(b.device, dcDeviceSetSelection, id)
+}
+
+func (b *waylandBackend) Watch() <-chan struct{} { return b.watchCh }
+
+func (b *waylandBackend) Close() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	b.c.Close()
+}
@@ -0,0 +1,222 @@
+//go:build linux
+
+package clip
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// wlConn is a minimal client for the Wayland wire protocol: enough to bind
+// globals, send requests with string, integer, object and fd arguments, and
+// decode events. Object IDs are allocated sequentially and never reused,
+// which the protocol permits.
+type wlConn struct {
+	conn *net.UnixConn
+
+	wmu    sync.Mutex // serialises requests and ID allocation
+	nextID uint32
+
+	// Read side, owned by the event loop.
+	buf []byte
+	fds []int
+}
+
+// wlEvent is one decoded event. args holds the raw argument bytes; fds the
+// file descriptors passed with it.
+type wlEvent struct {
+	sender uint32
+	opcode uint16
+	args   []byte
+}
+
+// wlFD marks a file descriptor argument in a request.
+type wlFD int
+
+const wlDisplayID = 1
+
+// dialWayland connects to the compositor named by WAYLAND_DISPLAY.
+func dialWayland() (*wlConn, error) {
+	name := os.Getenv("WAYLAND_DISPLAY")
+	if name == "" {
+		name = "wayland-0"
+	}
+	path := name
+	if !filepath.IsAbs(path) {
+		dir := os.Getenv("XDG_RUNTIME_DIR")
+		if dir == "" {
+			return nil, errors.New("XDG_RUNTIME_DIR is not set")
+		}
+		path = filepath.Join(dir, name)
+	}
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	return &wlConn{conn: conn, nextID: wlDisplayID + 1}, nil
+}
+
+// newID allocates an object ID.
+func (c *wlConn) newID() uint32 {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	id := c.nextID
+	c.nextID++
+	return id
+}
+
+// request sends a request to object. Arguments may be uint32, int32,
+// string, or wlFD; a new_id or object argument is a uint32.
+func (c *wlConn) request(object uint32, opcode uint16, args ...any) error {
+	body := make([]byte, 8, 64)
+	var fds []int
+	for _, a := range args {
+		switch v := a.(type) {
+		case uint32:
+			body = binary.NativeEndian.AppendUint32(body, v)
+		case int32:
+			body = binary.NativeEndian.AppendUint32(body, uint32(v))
+		case string:
+			n := len(v) + 1
+			body = binary.NativeEndian.AppendUint32(body, uint32(n))
+			body = append(body, v...)
+			body = append(body, make([]byte, pad4(n)-len(v))...)
+		case wlFD:
+			fds = append(fds, int(v))
+		default:
+			panic(fmt.Sprintf("wayland: unsupported argument type %T", a))
+		}
+	}
+	binary.NativeEndian.PutUint32(body[0:], object)
+	binary.NativeEndian.PutUint32(body[4:], uint32(len(body))<<16|uint32(opcode))
+
+	var oob []byte
+	if len(fds) > 0 {
+		oob = syscall.UnixRights(fds...)
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, _, err := c.conn.WriteMsgUnix(body, oob, nil)
+	return err
+}
+
+// next reads the next event, blocking until one is complete.
+func (c *wlConn) next() (wlEvent, error) {
+	for {
+		if len(c.buf) >= 8 {
+			size := int(binary.NativeEndian.Uint32(c.buf[4:]) >> 16)
+			if size < 8 {
+				return wlEvent{}, fmt.Errorf("wayland: bad message size %d", size)
+			}
+			if len(c.buf) >= size {
+				ev := wlEvent{
+					sender: binary.NativeEndian.Uint32(c.buf[0:]),
+					opcode: uint16(binary.NativeEndian.Uint32(c.buf[4:])),
+					args:   append([]byte(nil), c.buf[8:size]...),
+				}
+				c.buf = c.buf[size:]
+				return ev, nil
+			}
+		}
+		data := make([]byte, 4096)
+		oob := make([]byte, syscall.CmsgSpace(28*4))
+		n, oobn, _, _, err := c.conn.ReadMsgUnix(data, oob)
+		if err != nil {
+			return wlEvent{}, err
+		}
+		if n == 0 {
+			return wlEvent{}, errors.New("wayland: connection closed")
+		}
+		c.buf = append(c.buf, data[:n]...)
+		if oobn > 0 {
+			msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+			if err != nil {
+				return wlEvent{}, err
+			}
+			for _, m := range msgs {
+				fds, err := syscall.ParseUnixRights(&m)
+				if err == nil {
+					c.fds = append(c.fds, fds...)
+				}
+			}
+		}
+	}
+}
+
+// takeFD returns the next file descriptor received with an event.
+func (c *wlConn) takeFD() (int, error) {
+	if len(c.fds) == 0 {
+		return -1, errors.New("wayland: expected a file descriptor")
+	}
+	fd := c.fds[0]
+	c.fds = c.fds[1:]
+	return fd, nil
+}
+
+// roundtrip sends wl_display.sync and dispatches events to handle until the
+// callback fires.
+func (c *wlConn) roundtrip(handle func(wlEvent) error) error {
+	cb := c.newID()
+	if err := c.request(wlDisplayID, 0, cb); err != nil { // wl_display.sync
+		return err
+	}
+	for {
+		ev, err := c.next()
+		if err != nil {
+			return err
+		}
+		if ev.sender == cb {
+			return nil
+		}
+		if err := handle(ev); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *wlConn) Close() error { return c.conn.Close() }
+
+// wlArgs decodes event arguments in order.
+type wlArgs struct {
+	b   []byte
+	err error
+}
+
+func (a *wlArgs) uint() uint32 {
+	if len(a.b) < 4 {
+		a.err = errors.New("wayland: short event")
+		return 0
+	}
+	v := binary.NativeEndian.Uint32(a.b)
+	a.b = a.b[4:]
+	return v
+}
+
+func (a *wlArgs) string() string {
+	n := int(a.uint())
+	if n == 0 {
+		return ""
+	}
+	if len(a.b) < pad4(n) {
+		a.err = errors.New("wayland: short event")
+		return ""
+	}
+	s := string(a.b[:n-1])
+	a.b = a.b[pad4(n):]
+	return s
+}
+
+// wlDisplayError formats a wl_display.error event.
+func wlDisplayError(ev wlEvent) error {
+	a := wlArgs{b: ev.args}
+	object, code, msg := a.uint(), a.uint(), a.string()
+	return fmt.Errorf("wayland: protocol error on object %d (code %d): %s", object, code, msg)
+}
+
+func pad4(n int) int { return (n + 3) &^ 3 }
@@ -6,9 +6,7 @@ package clip
 //
 // #include <windows.h>
 // #include <stdlib.h>
-//
-// static HWND suffuse_create_listener_window();
-// static void suffuse_pump_messages(HWND hwnd, int* changed);
+// #include <string.h>
 //
 // static LRESULT CALLBACK suffuse_wnd_proc(HWND hwnd, UINT msg, WPARAM wp, LPARAM lp) {
 //     if (msg == WM_CLIPBOARDUPDATE) {
@@ -30,21 +28,120 @@ package clip
 //     return hwnd;
 // }
 //
-// static void suffuse_pump_messages(HWND hwnd, int* changed) {
+// // Blocks in GetMessage until the clipboard changes (returns 1) or the
+// // thread receives WM_QUIT (returns 0). Must run on the thread that created
+// // hwnd, which owns its message queue.
+// static int suffuse_wait_change(HWND hwnd) {
 //     MSG msg;
-//     *changed = 0;
-//     while (PeekMessage(&msg, hwnd, 0, 0, PM_REMOVE)) {
-//         if (msg.message == WM_USER + 1) { *changed = 1; }
+//     while (GetMessage(&msg, NULL, 0, 0) > 0) {
+//         if (msg.hwnd == hwnd && msg.message == WM_USER + 1) {
+//             return 1;
+//         }
 //         TranslateMessage(&msg);
 //         DispatchMessage(&msg);
 //     }
+//     return 0;
+// }
+//
+// static UINT suffuse_png_format() {
+//     return RegisterClipboardFormatA("PNG");
+// }
+//
+// static UINT suffuse_html_format() {
+//     return RegisterClipboardFormatA("HTML Format");
+// }
+//
+// static UINT suffuse_rtf_format() {
+//     return RegisterClipboardFormatA("Rich Text Format");
+// }
+//
+// // Password managers mark their copies with this format, and clipboard
+// // history and cloud sync leave out content whose other two are 0.
+// static UINT suffuse_exclude_format() {
+//     return RegisterClipboardFormatA("ExcludeClipboardContentFromMonitorProcessing");
+// }
+//
+// static UINT suffuse_history_format() {
+//     return RegisterClipboardFormatA("CanIncludeInClipboardHistory");
+// }
+//
+// static UINT suffuse_cloud_format() {
+//     return RegisterClipboardFormatA("CanUploadToCloudClipboard");
+// }
+//
+// // Replaces the clipboard contents with n formats in one
+// // OpenClipboard/CloseClipboard transaction, so listeners get a single
+// // WM_CLIPBOARDUPDATE for the complete set. Returns 0 on success or the
+// // failing call's GetLastError.
+// static DWORD suffuse_write(HWND hwnd, int n, UINT* formats, void** datas, SIZE_T* lens) {
+//     for (int tries = 0; !OpenClipboard(hwnd); tries++) {
+//         if (tries == 10) {
+//             return GetLastError();
+//         }
+//         Sleep(10);
+//     }
+//     EmptyClipboard();
+//     for (int i = 0; i < n; i++) {
+//         HGLOBAL h = GlobalAlloc(GMEM_MOVEABLE, lens[i]);
+//         if (h == NULL) {
+//             DWORD err = GetLastError();
+//             CloseClipboard();
+//             return err;
+//         }
+//         memcpy(GlobalLock(h), datas[i], lens[i]);
+//         GlobalUnlock(h);
+//         if (SetClipboardData(formats[i], h) == NULL) {
+//             DWORD err = GetLastError();
+//             GlobalFree(h);
+//             CloseClipboard();
+//             return err;
+//         }
+//     }
+//     CloseClipboard();
+//     return 0;
+// }
+//
+// // Copies the data of format into a malloc'd buffer, or returns NULL if
+// // the clipboard does not hold it.
+// static void* suffuse_read_format(HWND hwnd, UINT format, SIZE_T* len) {
+//     *len = 0;
+//     if (!IsClipboardFormatAvailable(format) || !OpenClipboard(hwnd)) {
+//         return NULL;
+//     }
+//     void* buf = NULL;
+//     HGLOBAL h = GetClipboardData(format);
+//     void* src = h != NULL ? GlobalLock(h) : NULL;
+//     if (src != NULL) {
+//         *len = GlobalSize(h);
+//         buf = malloc(*len > 0 ? *len : 1);
+//         memcpy(buf, src, *len);
+//         GlobalUnlock(h);
+//     }
+//     CloseClipboard();
+//     return buf;
+// }
+//
+// static void suffuse_quit(DWORD thread) {
+//     PostThreadMessage(thread, WM_QUIT, 0, 0);
+// }
+//
+// static void suffuse_destroy_listener_window(HWND hwnd) {
+//     RemoveClipboardFormatListener(hwnd);
+//     DestroyWindow(hwnd);
 // }
 import "C"
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"log/slog"
-	"time"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
 
 	"golang.design/x/clipboard"
 
@@ -52,9 +149,13 @@ import (
 )
 
 type windowsBackend struct {
-	hwnd    C.HWND
-	watchCh chan struct{}
-	done    chan struct{}
+	hwnd     C.HWND  // listener window, also the clipboard owner for writes
+	threadID C.DWORD // thread running the message loop
+	watchCh  chan struct{}
+
+	// ownSeq is the clipboard sequence number after our last Write; the
+	// update it triggers is not reported by Watch.
+	ownSeq atomic.Uint32
 }
 
 // New returns the Windows clipboard backend using AddClipboardFormatListener.
@@ -65,34 +166,44 @@ func New() Backend {
 	if err := clipboard.Init(); err != nil {
 		slog.Warn("clipboard init failed", "err", err)
 	}
-	hwnd := C.suffuse_create_listener_window()
-	b := &windowsBackend{
-		hwnd:    hwnd,
-		watchCh: make(chan struct{}, 1),
-		done:    make(chan struct{}),
-	}
-	go b.pump()
+	b := &windowsBackend{watchCh: make(chan struct{}, 1)}
+	ready := make(chan struct{})
+	go b.loop(ready)
+	<-ready
 	return b
 }
 
 func (b *windowsBackend) Name() string { return "Windows Clipboard" }
 
-func (b *windowsBackend) pump() {
-	t := time.NewTicker(50 * time.Millisecond)
-	defer t.Stop()
-	for {
+func (b *windowsBackend) Capabilities() Capabilities {
+	return Capabilities{
+		MIMETypes:   []string{"text/plain", "text/html", "text/rtf", "image/png", URIListMime, SensitiveMime},
+		Watch:       WatchEvent,
+		AtomicWrite: true,
+	}
+}
+
+// loop owns the listener window. A window's messages are delivered to the
+// thread that created it, so the goroutine is locked to one OS thread for
+// its lifetime and blocks in GetMessage between clipboard updates instead of
+// polling. Close ends it by posting WM_QUIT to that thread.
+func (b *windowsBackend) loop(ready chan<- struct{}) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hwnd := C.suffuse_create_listener_window()
+	b.hwnd = hwnd
+	b.threadID = C.GetCurrentThreadId()
+	close(ready)
+	defer C.suffuse_destroy_listener_window(hwnd)
+
+	for C.suffuse_wait_change(hwnd) != 0 {
+		if uint32(C.GetClipboardSequenceNumber()) == b.ownSeq.Load() {
+			continue
+		}
 		select {
-		case <-b.done:
-			return
-		case <-t.C:
-			var changed C.int
-			C.suffuse_pump_messages(b.hwnd, &changed)
-			if changed != 0 {
-				select {
-				case b.watchCh <- struct{}{}:
-				default:
-				}
-			}
+		case b.watchCh <- struct{}{}:
+		default:
 		}
 	}
 }
@@ -102,25 +213,230 @@ func (b *windowsBackend) Read() ([]*pb.ClipboardItem, error) {
 	if text := clipboard.Read(clipboard.FmtText); text != nil {
 		items = append(items, &pb.ClipboardItem{Mime: "text/plain", Data: text})
 	}
-	if img := clipboard.Read(clipboard.FmtImage); img != nil {
+	if html := parseCFHTML(b.readFormat(C.suffuse_html_format())); len(html) > 0 {
+		items = append(items, &pb.ClipboardItem{Mime: "text/html", Data: html})
+	}
+	// RTF writers may include the terminating NUL.
+	if rtf := bytes.TrimRight(b.readFormat(C.suffuse_rtf_format()), "\x00"); len(rtf) > 0 {
+		items = append(items, &pb.ClipboardItem{Mime: "text/rtf", Data: rtf})
+	}
+	if img := b.readImage(); img != nil {
 		items = append(items, &pb.ClipboardItem{Mime: "image/png", Data: img})
 	}
+	if paths := parseDropFiles(b.readFormat(C.CF_HDROP)); len(paths) > 0 {
+		items = append(items, &pb.ClipboardItem{Mime: URIListMime, Data: FormatURIList(paths)})
+	}
+	if C.IsClipboardFormatAvailable(C.suffuse_exclude_format()) != 0 {
+		items = append(items, SensitiveItem())
+	}
 	return items, nil
 }
 
+// readImage returns the clipboard's image as PNG, or nil: the registered
+// "PNG" format as it is when present, as browsers and image editors write
+// it, or else the bitmap converted with its alpha and color profile. Windows
+// synthesizes CF_DIBV5 from the other bitmap formats; palette bitmaps, which
+// dibToPNG does not handle, are left to the clipboard package.
+func (b *windowsBackend) readImage() []byte {
+	if img := b.readFormat(C.suffuse_png_format()); bytes.HasPrefix(img, pngSignature) {
+		return img
+	}
+	if dib := b.readFormat(C.CF_DIBV5); len(dib) > 0 {
+		img, err := dibToPNG(dib)
+		if err == nil {
+			return img
+		}
+		slog.Debug("clipboard bitmap not converted", "err", err)
+	}
+	return clipboard.Read(clipboard.FmtImage)
+}
+
+// readFormat returns the clipboard's data in format, or nil.
+func (b *windowsBackend) readFormat(format C.UINT) []byte {
+	var n C.SIZE_T
+	buf := C.suffuse_read_format(b.hwnd, format, &n)
+	if buf == nil {
+		return nil
+	}
+	defer C.free(buf)
+	return C.GoBytes(buf, C.int(n))
+}
+
+// Write replaces the clipboard contents in a single clipboard transaction.
+// Text is stored as CF_UNICODETEXT; HTML as the registered "HTML Format"
+// (CF_HTML) and RTF as "Rich Text Format"; images both as the registered
+// "PNG" format and as CF_DIBV5, with alpha and the PNG's color profile,
+// for applications that only understand bitmaps; file lists as CF_HDROP, which Explorer pastes as files; the
+// sensitive marker as the formats that keep content out of clipboard
+// managers, history and cloud sync.
 func (b *windowsBackend) Write(items []*pb.ClipboardItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	type format struct {
+		id   C.UINT
+		data []byte
+	}
+	var formats []format
 	for _, it := range items {
 		switch it.Mime {
 		case "text/plain":
-			clipboard.Write(clipboard.FmtText, it.Data)
+			formats = append(formats, format{C.CF_UNICODETEXT, utf16z(it.Data)})
+		case "text/html":
+			formats = append(formats, format{C.suffuse_html_format(), cfHTML(it.Data)})
+		case "text/rtf":
+			formats = append(formats, format{C.suffuse_rtf_format(), it.Data})
 		case "image/png":
-			clipboard.Write(clipboard.FmtImage, it.Data)
+			dib, err := pngToDIBV5(it.Data)
+			if err != nil {
+				return fmt.Errorf("image/png: %w", err)
+			}
+			formats = append(formats,
+				format{C.suffuse_png_format(), it.Data},
+				format{C.CF_DIBV5, dib},
+			)
+		case URIListMime:
+			formats = append(formats, format{C.CF_HDROP, dropFiles(ParseURIList(it.Data))})
+		case SensitiveMime:
+			no := []byte{0, 0, 0, 0} // DWORD 0
+			formats = append(formats,
+				format{C.suffuse_exclude_format(), no},
+				format{C.suffuse_history_format(), no},
+				format{C.suffuse_cloud_format(), no},
+			)
 		default:
 			return fmt.Errorf("unsupported MIME type: %s", it.Mime)
 		}
 	}
+
+	n := len(formats)
+	ptrSize := C.size_t(unsafe.Sizeof(uintptr(0)))
+	cFormats := unsafe.Slice((*C.UINT)(C.malloc(C.size_t(n)*C.size_t(unsafe.Sizeof(C.UINT(0))))), n)
+	cData := unsafe.Slice((*unsafe.Pointer)(C.malloc(C.size_t(n)*ptrSize)), n)
+	cLens := unsafe.Slice((*C.SIZE_T)(C.malloc(C.size_t(n)*C.size_t(unsafe.Sizeof(C.SIZE_T(0))))), n)
+	defer func() {
+		for i := range n {
+			C.free(cData[i])
+		}
+		C.free(unsafe.Pointer(&cFormats[0]))
+		C.free(unsafe.Pointer(&cData[0]))
+		C.free(unsafe.Pointer(&cLens[0]))
+	}()
+	for i, f := range formats {
+		cFormats[i] = f.id
+		cData[i] = C.CBytes(f.data)
+		cLens[i] = C.SIZE_T(len(f.data))
+	}
+
+	if code := C.suffuse_write(b.hwnd, C.int(n), &cFormats[0], &cData[0], &cLens[0]); code != 0 {
+		return fmt.Errorf("clipboard write failed: %w", syscall.Errno(code))
+	}
+	b.ownSeq.Store(uint32(C.GetClipboardSequenceNumber()))
+	return nil
+}
+
+// utf16z converts UTF-8 text to NUL-terminated UTF-16LE for CF_UNICODETEXT.
+func utf16z(text []byte) []byte {
+	u := utf16.Encode([]rune(string(text)))
+	out := make([]byte, 0, 2*len(u)+2)
+	for _, c := range u {
+		out = binary.LittleEndian.AppendUint16(out, c)
+	}
+	return append(out, 0, 0)
+}
+
+// cfHTML wraps an HTML fragment in the CF_HTML format: a header giving the
+// byte offsets of the document and of the fragment within it.
+func cfHTML(fragment []byte) []byte {
+	const header = "Version:0.9\r\nStartHTML:0000000000\r\nEndHTML:0000000000\r\n" +
+		"StartFragment:0000000000\r\nEndFragment:0000000000\r\n"
+	const prefix = "<html><body>\r\n<!--StartFragment-->"
+	const suffix = "<!--EndFragment-->\r\n</body></html>"
+	startFragment := len(header) + len(prefix)
+	endFragment := startFragment + len(fragment)
+	return fmt.Appendf(nil, "Version:0.9\r\nStartHTML:%010d\r\nEndHTML:%010d\r\n"+
+		"StartFragment:%010d\r\nEndFragment:%010d\r\n%s%s%s",
+		len(header), endFragment+len(suffix), startFragment, endFragment, prefix, fragment, suffix)
+}
+
+// parseCFHTML returns the fragment of CF_HTML data, or the whole document
+// when the header names no fragment.
+func parseCFHTML(data []byte) []byte {
+	offset := func(key string) int {
+		i := bytes.Index(data, []byte(key+":"))
+		if i < 0 {
+			return -1
+		}
+		v := data[i+len(key)+1:]
+		if end := bytes.IndexAny(v, "\r\n"); end >= 0 {
+			v = v[:end]
+		}
+		n, err := strconv.Atoi(string(bytes.TrimSpace(v)))
+		if err != nil || n < 0 || n > len(data) {
+			return -1
+		}
+		return n
+	}
+	for _, keys := range [][2]string{{"StartFragment", "EndFragment"}, {"StartHTML", "EndHTML"}} {
+		start, end := offset(keys[0]), offset(keys[1])
+		if start >= 0 && end > start {
+			return data[start:end]
+		}
+	}
 	return nil
 }
 
+// dropFilesSize is sizeof(DROPFILES): the offset of the file names, a
+// POINT, and the fNC and fWide flags.
+const dropFilesSize = 20
+
+// dropFiles builds CF_HDROP data naming paths: a DROPFILES header followed
+// by NUL-terminated UTF-16 names and a final NUL.
+func dropFiles(paths []string) []byte {
+	out := make([]byte, dropFilesSize)
+	binary.LittleEndian.PutUint32(out[0:], dropFilesSize) // pFiles
+	binary.LittleEndian.PutUint32(out[16:], 1)            // fWide
+	for _, p := range paths {
+		out = append(out, utf16z([]byte(p))...)
+	}
+	return append(out, 0, 0)
+}
+
+// parseDropFiles returns the paths named by CF_HDROP data.
+func parseDropFiles(data []byte) []string {
+	if len(data) < dropFilesSize {
+		return nil
+	}
+	off := int(binary.LittleEndian.Uint32(data[0:]))
+	wide := binary.LittleEndian.Uint32(data[16:]) != 0
+	if off < dropFilesSize || off > len(data) {
+		return nil
+	}
+	var paths []string
+	if !wide {
+		for _, name := range bytes.Split(data[off:], []byte{0}) {
+			if len(name) == 0 {
+				break
+			}
+			paths = append(paths, string(name))
+		}
+		return paths
+	}
+	var name []uint16
+	for i := off; i+1 < len(data); i += 2 {
+		c := binary.LittleEndian.Uint16(data[i:])
+		if c != 0 {
+			name = append(name, c)
+			continue
+		}
+		if len(name) == 0 {
+			break
+		}
+		paths = append(paths, string(utf16.Decode(name)))
+		name = name[:0]
+	}
+	return paths
+}
+
 func (b *windowsBackend) Watch() <-chan struct{} { return b.watchCh }
-func (b *windowsBackend) Close()                { close(b.done) }
+func (b *windowsBackend) Close()                 { C.suffuse_quit(b.threadID) }
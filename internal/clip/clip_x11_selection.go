@@ -0,0 +1,672 @@
+//go:build linux
+
+package clip
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+const (
+	// x11PrimarySettle is how long PRIMARY must stay unchanged before Watch
+	// reports it. Applications re-announce the selection while the user is
+	// still dragging over text; only the final selection is published.
+	x11PrimarySettle = 300 * time.Millisecond
+	x11ReadTimeout   = 2 * time.Second
+	x11MaxItem       = 64 << 20
+	// x11IncrTimeout is how long a client receiving our selection in chunks
+	// may take to ask for the next one before the transfer is abandoned.
+	x11IncrTimeout = 10 * time.Second
+	// x11PollInterval is how often the selection owner is checked when the
+	// X server lacks XFIXES to report changes.
+	x11PollInterval = 250 * time.Millisecond
+)
+
+// XFIXES requests and event mask used to follow selection owner changes.
+const (
+	xfixesQueryVersion         = 0
+	xfixesSelectSelectionInput = 2
+
+	xfixesSetSelectionOwnerMask      = 1
+	xfixesSelectionWindowDestroyMask = 2
+	xfixesSelectionClientCloseMask   = 4
+)
+
+// x11Selection describes a selection synced by x11SelectionBackend.
+type x11Selection struct {
+	name   string        // atom name
+	label  string        // for Name, logs and errors
+	mimes  []string      // types read and served, in Read order
+	settle time.Duration // quiet period before Watch reports a change
+}
+
+var (
+	// x11Primary is the text last selected with the mouse, pasted with the
+	// middle button. It holds text only.
+	x11Primary = x11Selection{
+		name:   "PRIMARY",
+		label:  "X11 PRIMARY selection",
+		mimes:  []string{"text/plain"},
+		settle: x11PrimarySettle,
+	}
+	// x11Clipboard is the regular clipboard. Types other than text are
+	// exchanged under targets named by their MIME type, as toolkits do, and
+	// password managers mark their copies with a SensitiveMime target.
+	x11Clipboard = x11Selection{
+		name:  "CLIPBOARD",
+		label: "X11 CLIPBOARD selection",
+		mimes: []string{"text/plain", "text/html", "text/rtf", "image/png", URIListMime, SensitiveMime},
+	}
+)
+
+// x11SelectionBackend syncs an X11 selection. Changes are reported through
+// the XFIXES extension or, on X servers without it, noticed by polling the
+// selection's owner and the time it took the selection over. Our own
+// selection is served to other clients until one of them takes it over.
+// Selections are read and served incrementally (INCR) when larger than a
+// request, and only the targets the owner lists in TARGETS are asked for.
+type x11SelectionBackend struct {
+	sel      x11Selection
+	c        *x11Conn
+	window   uint32
+	xfixesEv byte
+	polling  bool // no XFIXES: poll reports changes
+	watchCh  chan struct{}
+
+	atomSelection                                            uint32
+	atomTargets, atomUTF8, atomText, atomTextPlain, atomIncr uint32
+	atomTimestampTarget                                      uint32 // TIMESTAMP
+	atomProperty, atomTimestamp                              uint32
+	atomMimes                                                map[string]uint32 // types other than text
+
+	convMu sync.Mutex  // serialises conversations with the server: Read and Write
+	notify chan []byte // SelectionNotify events for Read
+	props  chan []byte // PropertyNotify events on our window
+
+	mu      sync.Mutex
+	owned   map[uint32]x11Value // targets served while we own the selection; nil otherwise
+	ownedAt uint32              // server time we took the selection over
+	settle  *time.Timer
+	closed  bool
+
+	// incr holds transfers in progress to clients receiving our selection
+	// in chunks. Used by the event loop only.
+	incr map[x11Dest]*x11Incr
+}
+
+// x11Value is the value of a target: its property type and data.
+type x11Value struct {
+	typ  uint32
+	data []byte
+}
+
+// x11Dest is a property of a client's window our selection is sent to.
+type x11Dest struct{ window, property uint32 }
+
+// x11Incr is a selection sent in chunks: each is written once the client
+// deletes the previous one, and an empty chunk ends the transfer.
+type x11Incr struct {
+	typ     uint32
+	data    []byte
+	touched time.Time
+}
+
+// newX11SelectionBackend connects to the X server named by DISPLAY. It fails
+// without an X server.
+func newX11SelectionBackend(sel x11Selection) (*x11SelectionBackend, error) {
+	c, err := dialX11()
+	if err != nil {
+		return nil, err
+	}
+	b := &x11SelectionBackend{
+		sel:       sel,
+		c:         c,
+		window:    c.newID(),
+		watchCh:   make(chan struct{}, 1),
+		notify:    make(chan []byte, 1),
+		props:     make(chan []byte, 16),
+		atomMimes: make(map[string]uint32),
+		incr:      make(map[x11Dest]*x11Incr),
+	}
+	go b.loop()
+	if err := b.setup(); err != nil {
+		b.Close()
+		return nil, err
+	}
+	if b.polling {
+		go b.poll()
+	}
+	return b, nil
+}
+
+func (b *x11SelectionBackend) setup() error {
+	// An unmapped input-only window to own the selection and receive
+	// converted data.
+	const inputOnly = 2
+	body := x11Uint32s(b.window, b.c.root)
+	body = binary.LittleEndian.AppendUint32(body, 0)       // x, y
+	body = binary.LittleEndian.AppendUint32(body, 1|1<<16) // width, height
+	body = binary.LittleEndian.AppendUint32(body, inputOnly<<16)
+	body = append(body, x11Uint32s(0, x11CWEventMask, x11PropertyChangeMask)...)
+	if err := b.c.send(x11CreateWindow, 0, body); err != nil {
+		return err
+	}
+
+	atoms := map[string]*uint32{
+		b.sel.name:                 &b.atomSelection,
+		"TARGETS":                  &b.atomTargets,
+		"TIMESTAMP":                &b.atomTimestampTarget,
+		"UTF8_STRING":              &b.atomUTF8,
+		"TEXT":                     &b.atomText,
+		"text/plain;charset=utf-8": &b.atomTextPlain,
+		"INCR":                     &b.atomIncr,
+		"SUFFUSE_SELECTION":        &b.atomProperty,
+		"SUFFUSE_TIMESTAMP":        &b.atomTimestamp,
+	}
+	for _, mime := range b.sel.mimes {
+		if mime != "text/plain" {
+			atoms[mime] = new(uint32)
+		}
+	}
+	for name, atom := range atoms {
+		var err error
+		if *atom, err = b.c.internAtom(name); err != nil {
+			return err
+		}
+	}
+	for _, mime := range b.sel.mimes {
+		if mime != "text/plain" {
+			b.atomMimes[mime] = *atoms[mime]
+		}
+	}
+
+	opcode, firstEvent, ok, err := b.c.queryExtension("XFIXES")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		slog.Info("X server lacks the XFIXES extension, polling the selection", "selection", b.sel.name)
+		b.polling = true
+		return nil
+	}
+	b.xfixesEv = firstEvent
+	if _, err := b.c.call(opcode, xfixesQueryVersion, x11Uint32s(5, 0)); err != nil {
+		return err
+	}
+	mask := uint32(xfixesSetSelectionOwnerMask | xfixesSelectionWindowDestroyMask | xfixesSelectionClientCloseMask)
+	return b.c.send(opcode, xfixesSelectSelectionInput, x11Uint32s(b.window, b.atomSelection, mask))
+}
+
+func (b *x11SelectionBackend) Name() string { return b.sel.label }
+
+func (b *x11SelectionBackend) Capabilities() Capabilities {
+	if b.polling {
+		return Capabilities{
+			MIMETypes:    b.sel.mimes,
+			Watch:        WatchPoll,
+			PollInterval: x11PollInterval,
+			AtomicWrite:  true,
+		}
+	}
+	return Capabilities{
+		MIMETypes:   b.sel.mimes,
+		Watch:       WatchEvent,
+		AtomicWrite: true,
+	}
+}
+
+// loop handles events until the connection fails or Close is called.
+func (b *x11SelectionBackend) loop() {
+	err := b.c.run(b.handle)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		slog.Error("X11 selection stopped", "selection", b.sel.name, "err", err)
+		b.closed = true
+	}
+	if b.settle != nil {
+		b.settle.Stop()
+	}
+	close(b.watchCh)
+}
+
+func (b *x11SelectionBackend) handle(ev []byte) {
+	switch code := ev[0] & 0x7f; code {
+	case 0:
+		slog.Debug("X11 request failed", "code", ev[1], "opcode", ev[10])
+	case b.xfixesEv:
+		if owner := binary.LittleEndian.Uint32(ev[8:]); owner != b.window {
+			b.changed()
+		}
+	case x11EvSelectionRequest:
+		b.serve(ev)
+	case x11EvSelectionClear:
+		b.mu.Lock()
+		b.owned = nil
+		b.mu.Unlock()
+	case x11EvSelectionNotify:
+		select {
+		case b.notify <- ev:
+		default:
+		}
+	case x11EvPropertyNotify:
+		// Our own window takes part in transfers when we read a selection
+		// we own.
+		if b.continueIncr(ev) || binary.LittleEndian.Uint32(ev[4:]) != b.window {
+			return
+		}
+		select {
+		case b.props <- ev:
+		default:
+		}
+	}
+}
+
+// poll reports a change when the selection gets another owner, or its owner
+// takes it over again, which stamps it with a new time. Owners that do not
+// answer TIMESTAMP, or took the selection as of CurrentTime and answer 0,
+// have their selection read and compared instead.
+func (b *x11SelectionBackend) poll() {
+	t := time.NewTicker(x11PollInterval)
+	defer t.Stop()
+	var (
+		lastOwner, lastStamp uint32
+		lastItems            []*pb.ClipboardItem
+		first                = true
+	)
+	for range t.C {
+		b.mu.Lock()
+		closed := b.closed
+		b.mu.Unlock()
+		if closed {
+			return
+		}
+		r, err := b.c.call(x11GetSelectionOwner, 0, x11Uint32s(b.atomSelection))
+		if err != nil {
+			continue
+		}
+		owner := binary.LittleEndian.Uint32(r[8:])
+		if owner == x11AtomNone || owner == b.window {
+			lastOwner, lastItems = owner, nil
+			first = false
+			continue
+		}
+		changed := owner != lastOwner
+		b.convMu.Lock()
+		data, ok, err := b.convert(b.atomTimestampTarget)
+		b.convMu.Unlock()
+		switch {
+		case err != nil:
+			continue
+		case ok && len(data) >= 4 && binary.LittleEndian.Uint32(data) != 0:
+			stamp := binary.LittleEndian.Uint32(data)
+			changed = changed || stamp != lastStamp
+			lastStamp, lastItems = stamp, nil
+		default:
+			items, err := b.Read()
+			if err != nil {
+				continue
+			}
+			changed = changed || !reflect.DeepEqual(items, lastItems)
+			lastStamp, lastItems = 0, items
+		}
+		lastOwner = owner
+		if changed && !first {
+			b.changed()
+		}
+		first = false
+	}
+}
+
+// changed reports a new selection once it has settled.
+func (b *x11SelectionBackend) changed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	if b.sel.settle == 0 {
+		select {
+		case b.watchCh <- struct{}{}:
+		default:
+		}
+		return
+	}
+	if b.settle != nil {
+		b.settle.Reset(b.sel.settle)
+		return
+	}
+	b.settle = time.AfterFunc(b.sel.settle, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.closed {
+			return
+		}
+		select {
+		case b.watchCh <- struct{}{}:
+		default:
+		}
+	})
+}
+
+// serve answers a client converting our selection.
+func (b *x11SelectionBackend) serve(ev []byte) {
+	le := binary.LittleEndian
+	timestamp, requestor := le.Uint32(ev[4:]), le.Uint32(ev[12:])
+	selection, target, property := le.Uint32(ev[16:]), le.Uint32(ev[20:]), le.Uint32(ev[24:])
+	if property == x11AtomNone {
+		property = target // obsolete clients
+	}
+
+	b.mu.Lock()
+	owned, ownedAt := b.owned, b.ownedAt
+	b.mu.Unlock()
+
+	var err error
+	value, ok := owned[target]
+	switch {
+	case owned == nil || selection != b.atomSelection:
+		err = errors.New("not the owner")
+	case target == b.atomTargets:
+		atoms := x11Uint32s(b.atomTargets, b.atomTimestampTarget)
+		for t := range owned {
+			atoms = binary.LittleEndian.AppendUint32(atoms, t)
+		}
+		err = b.changeProperty(requestor, property, x11AtomAtom, 32, atoms)
+	case target == b.atomTimestampTarget:
+		err = b.changeProperty(requestor, property, x11AtomInteger, 32, x11Uint32s(ownedAt))
+	case !ok:
+		err = fmt.Errorf("unsupported target %d", target)
+	case 28+len(value.data) > b.c.maxReq:
+		err = b.startIncr(x11Dest{requestor, property}, value)
+	default:
+		err = b.changeProperty(requestor, property, value.typ, 8, value.data)
+	}
+	if err != nil {
+		slog.Debug("X11 selection request refused", "selection", b.sel.name, "err", err)
+		property = x11AtomNone
+	}
+
+	notify := make([]byte, 32)
+	notify[0] = x11EvSelectionNotify
+	copy(notify[4:], x11Uint32s(timestamp, requestor, selection, target, property))
+	body := append(x11Uint32s(requestor, 0), notify...)
+	if err := b.c.send(x11SendEvent, 0, body); err != nil {
+		slog.Debug("X11 selection notify failed", "selection", b.sel.name, "err", err)
+	}
+}
+
+func (b *x11SelectionBackend) changeProperty(window, property, typ uint32, format byte, data []byte) error {
+	const modeReplace = 0
+	body := x11Uint32s(window, property, typ)
+	body = append(body, format, 0, 0, 0)
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(data)*8/int(format)))
+	body = append(body, data...)
+	return b.c.send(x11ChangeProperty, modeReplace, body)
+}
+
+// startIncr announces a transfer in chunks to dest and watches the client's
+// window for the deletions that ask for each chunk.
+func (b *x11SelectionBackend) startIncr(dest x11Dest, value x11Value) error {
+	now := time.Now()
+	for d, t := range b.incr {
+		if now.Sub(t.touched) > x11IncrTimeout {
+			delete(b.incr, d)
+		}
+	}
+	err := b.c.send(x11ChangeWindowAttributes, 0, x11Uint32s(dest.window, x11CWEventMask, x11PropertyChangeMask))
+	if err != nil {
+		return err
+	}
+	err = b.changeProperty(dest.window, dest.property, b.atomIncr, 32, x11Uint32s(uint32(len(value.data))))
+	if err != nil {
+		return err
+	}
+	b.incr[dest] = &x11Incr{typ: value.typ, data: value.data, touched: now}
+	return nil
+}
+
+// continueIncr writes the next chunk of a transfer when its client has
+// deleted the previous one. It reports whether ev was such a deletion.
+func (b *x11SelectionBackend) continueIncr(ev []byte) bool {
+	const deleted = 1
+	dest := x11Dest{binary.LittleEndian.Uint32(ev[4:]), binary.LittleEndian.Uint32(ev[8:])}
+	t, ok := b.incr[dest]
+	if !ok || ev[16] != deleted {
+		return false
+	}
+	chunk := t.data[:min(len(t.data), b.c.maxReq-28)]
+	if err := b.changeProperty(dest.window, dest.property, t.typ, 8, chunk); err != nil {
+		slog.Debug("X11 selection chunk not sent", "selection", b.sel.name, "err", err)
+		delete(b.incr, dest)
+		return true
+	}
+	if len(chunk) == 0 {
+		delete(b.incr, dest)
+		return true
+	}
+	t.data = t.data[len(chunk):]
+	t.touched = time.Now()
+	return true
+}
+
+// Read converts the selection to each supported type its owner offers. Text
+// is read as UTF-8, falling back to STRING, which is Latin-1, for old
+// clients.
+func (b *x11SelectionBackend) Read() ([]*pb.ClipboardItem, error) {
+	b.convMu.Lock()
+	defer b.convMu.Unlock()
+	// Owners that do not answer TARGETS are asked for every type.
+	offered := func(uint32) bool { return true }
+	data, ok, err := b.convert(b.atomTargets)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		var targets []uint32
+		for i := 0; i+4 <= len(data); i += 4 {
+			targets = append(targets, binary.LittleEndian.Uint32(data[i:]))
+		}
+		offered = func(t uint32) bool { return slices.Contains(targets, t) }
+	}
+
+	var items []*pb.ClipboardItem
+	for _, mime := range b.sel.mimes {
+		candidates := []uint32{b.atomUTF8, b.atomTextPlain, x11AtomString}
+		if atom, ok := b.atomMimes[mime]; ok {
+			candidates = []uint32{atom}
+		}
+		for _, target := range candidates {
+			if !offered(target) {
+				continue
+			}
+			data, ok, err := b.convert(target)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				if target == x11AtomString {
+					data = latin1ToUTF8(data)
+				}
+				if len(data) > 0 {
+					items = append(items, &pb.ClipboardItem{Mime: mime, Data: data})
+				}
+				break
+			}
+		}
+	}
+	return items, nil
+}
+
+// latin1ToUTF8 converts ISO 8859-1 text, the encoding of STRING, to UTF-8.
+func latin1ToUTF8(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for _, c := range data {
+		out = utf8.AppendRune(out, rune(c))
+	}
+	return out
+}
+
+// convert asks the owner of the selection for target. ok is false when the
+// owner refuses or there is none. Must be called with convMu held.
+func (b *x11SelectionBackend) convert(target uint32) (data []byte, ok bool, err error) {
+	b.drain()
+	const currentTime = 0
+	body := x11Uint32s(b.window, b.atomSelection, target, b.atomProperty, currentTime)
+	if err := b.c.send(x11ConvertSelection, 0, body); err != nil {
+		return nil, false, err
+	}
+	var ev []byte
+	select {
+	case ev = <-b.notify:
+	case <-time.After(x11ReadTimeout):
+		return nil, false, fmt.Errorf("%s: owner did not respond", b.sel.label)
+	}
+	if binary.LittleEndian.Uint32(ev[20:]) == x11AtomNone {
+		return nil, false, nil
+	}
+	typ, data, err := b.c.getProperty(b.window, b.atomProperty, x11MaxItem)
+	if err != nil {
+		return nil, false, err
+	}
+	if typ == b.atomIncr {
+		data, err = b.readIncr()
+	}
+	return data, err == nil, err
+}
+
+// drain discards notifications left over from earlier conversations. Must be
+// called with convMu held.
+func (b *x11SelectionBackend) drain() {
+	for {
+		select {
+		case <-b.notify:
+		case <-b.props:
+		default:
+			return
+		}
+	}
+}
+
+// readIncr receives a selection sent in chunks: the owner appends each one
+// after we delete the previous, and ends with an empty chunk.
+func (b *x11SelectionBackend) readIncr() ([]byte, error) {
+	var out []byte
+	timeout := time.NewTimer(x11ReadTimeout)
+	defer timeout.Stop()
+	for {
+		var ev []byte
+		select {
+		case ev = <-b.props:
+		case <-timeout.C:
+			return nil, fmt.Errorf("%s: incremental transfer stalled", b.sel.label)
+		}
+		const newValue = 0
+		if binary.LittleEndian.Uint32(ev[8:]) != b.atomProperty || ev[16] != newValue {
+			continue
+		}
+		typ, chunk, err := b.c.getProperty(b.window, b.atomProperty, x11MaxItem)
+		if err != nil {
+			return nil, err
+		}
+		// A property already gone (type None) or the INCR announcement
+		// itself is a stale notification, not the end of the transfer.
+		if typ == x11AtomNone || typ == b.atomIncr {
+			continue
+		}
+		if len(chunk) == 0 {
+			return out, nil
+		}
+		if len(out)+len(chunk) > x11MaxItem {
+			return nil, fmt.Errorf("%s: too large", b.sel.label)
+		}
+		out = append(out, chunk...)
+		timeout.Reset(x11ReadTimeout)
+	}
+}
+
+// Write takes the selection over with every item of a supported type. Our
+// own change is not reported by Watch.
+func (b *x11SelectionBackend) Write(items []*pb.ClipboardItem) error {
+	owned := make(map[uint32]x11Value)
+	for _, it := range items {
+		if it.Mime == "text/plain" && slices.Contains(b.sel.mimes, it.Mime) {
+			owned[b.atomUTF8] = x11Value{b.atomUTF8, it.Data}
+			owned[b.atomText] = x11Value{b.atomUTF8, it.Data}
+			owned[b.atomTextPlain] = x11Value{b.atomTextPlain, it.Data}
+			owned[x11AtomString] = x11Value{x11AtomString, it.Data}
+		} else if atom, ok := b.atomMimes[it.Mime]; ok {
+			owned[atom] = x11Value{atom, it.Data}
+		}
+	}
+	if len(owned) == 0 {
+		if len(items) > 0 {
+			return fmt.Errorf("unsupported MIME type: %s", items[0].Mime)
+		}
+		return nil
+	}
+
+	b.convMu.Lock()
+	defer b.convMu.Unlock()
+	timestamp, err := b.timestamp()
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.owned, b.ownedAt = owned, timestamp
+	b.mu.Unlock()
+	if err := b.c.send(x11SetSelectionOwner, 0, x11Uint32s(b.window, b.atomSelection, timestamp)); err != nil {
+		return err
+	}
+	r, err := b.c.call(x11GetSelectionOwner, 0, x11Uint32s(b.atomSelection))
+	if err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint32(r[8:]) != b.window {
+		return fmt.Errorf("%s: could not take ownership", b.sel.label)
+	}
+	return nil
+}
+
+// timestamp obtains the current server time from the PropertyNotify event of
+// an empty append to our window; ICCCM forbids owning a selection as of
+// CurrentTime. Must be called with convMu held.
+func (b *x11SelectionBackend) timestamp() (uint32, error) {
+	b.drain()
+	const modeAppend = 2
+	body := x11Uint32s(b.window, b.atomTimestamp, x11AtomString)
+	body = append(body, 8, 0, 0, 0)
+	body = binary.LittleEndian.AppendUint32(body, 0)
+	if err := b.c.send(x11ChangeProperty, modeAppend, body); err != nil {
+		return 0, err
+	}
+	timeout := time.After(x11ReadTimeout)
+	for {
+		select {
+		case ev := <-b.props:
+			if binary.LittleEndian.Uint32(ev[8:]) == b.atomTimestamp {
+				return binary.LittleEndian.Uint32(ev[12:]), nil
+			}
+		case <-timeout:
+			return 0, fmt.Errorf("%s: no timestamp from server", b.sel.label)
+		}
+	}
+}
+
+func (b *x11SelectionBackend) Watch() <-chan struct{} { return b.watchCh }
+
+func (b *x11SelectionBackend) Close() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	b.c.Close()
+}
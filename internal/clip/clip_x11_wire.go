@@ -0,0 +1,401 @@
+//go:build linux
+
+package clip
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// x11Conn is a minimal client for the X11 wire protocol: enough to intern
+// atoms, own and convert selections, and receive the events that go with
+// them. Requests may be sent from any goroutine; replies are matched to them
+// by sequence number by a single reader started with run.
+type x11Conn struct {
+	conn   net.Conn
+	root   uint32
+	maxReq int // maximum request length in bytes
+
+	wmu    sync.Mutex // serialises requests, sequence numbers and IDs
+	seq    uint16
+	idBase uint32
+	idMask uint32
+	nextID uint32
+
+	pmu     sync.Mutex
+	pending map[uint16]chan x11Reply
+}
+
+// x11Reply is a reply or an error for one request.
+type x11Reply struct {
+	b   []byte
+	err error
+}
+
+// Core request opcodes.
+const (
+	x11CreateWindow           = 1
+	x11ChangeWindowAttributes = 2
+	x11ChangeProperty         = 18
+	x11DeleteProperty         = 19
+	x11GetProperty            = 20
+	x11InternAtom             = 16
+	x11SetSelectionOwner      = 22
+	x11GetSelectionOwner      = 23
+	x11ConvertSelection       = 24
+	x11SendEvent              = 25
+	x11QueryExtension         = 98
+)
+
+// Window attribute and event masks.
+const (
+	x11CWEventMask        = 0x800
+	x11PropertyChangeMask = 0x400000
+)
+
+// Core event codes.
+const (
+	x11EvPropertyNotify   = 28
+	x11EvSelectionClear   = 29
+	x11EvSelectionRequest = 30
+	x11EvSelectionNotify  = 31
+)
+
+// Predefined atoms.
+const (
+	x11AtomNone    = 0
+	x11AtomPrimary = 1
+	x11AtomAtom    = 4
+	x11AtomInteger = 19
+	x11AtomString  = 31
+)
+
+// dialX11 connects to the display named by DISPLAY and completes the
+// connection setup, authenticating with an MIT-MAGIC-COOKIE-1 from the
+// Xauthority file when one matches.
+func dialX11() (*x11Conn, error) {
+	display := os.Getenv("DISPLAY")
+	if display == "" {
+		return nil, errors.New("DISPLAY is not set")
+	}
+	host, rest, ok := strings.Cut(display, ":")
+	if !ok {
+		return nil, fmt.Errorf("x11: invalid DISPLAY %q", display)
+	}
+	number, screenStr, _ := strings.Cut(rest, ".")
+	if _, err := strconv.Atoi(number); err != nil {
+		return nil, fmt.Errorf("x11: invalid DISPLAY %q", display)
+	}
+	screen, _ := strconv.Atoi(screenStr)
+
+	var conn net.Conn
+	var err error
+	if host == "" || host == "unix" {
+		conn, err = net.Dial("unix", "/tmp/.X11-unix/X"+number)
+	} else {
+		port, _ := strconv.Atoi(number)
+		conn, err = net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(6000+port)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &x11Conn{conn: conn, pending: make(map[uint16]chan x11Reply)}
+	if err := c.setup(host, number, screen); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// setup sends the connection setup request and parses the reply.
+func (c *x11Conn) setup(host, number string, screen int) error {
+	authName, authData := x11Cookie(host, number)
+	req := []byte{'l', 0}
+	req = binary.LittleEndian.AppendUint16(req, 11) // protocol 11.0
+	req = binary.LittleEndian.AppendUint16(req, 0)
+	req = binary.LittleEndian.AppendUint16(req, uint16(len(authName)))
+	req = binary.LittleEndian.AppendUint16(req, uint16(len(authData)))
+	req = append(req, 0, 0)
+	req = x11Pad(append(req, authName...))
+	req = x11Pad(append(req, authData...))
+	if _, err := c.conn.Write(req); err != nil {
+		return err
+	}
+
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(c.conn, head); err != nil {
+		return fmt.Errorf("x11: setup: %w", err)
+	}
+	body := make([]byte, int(binary.LittleEndian.Uint16(head[6:]))*4)
+	if _, err := io.ReadFull(c.conn, body); err != nil {
+		return fmt.Errorf("x11: setup: %w", err)
+	}
+	if head[0] != 1 {
+		reason := body
+		if head[0] == 0 && int(head[1]) <= len(body) {
+			reason = body[:head[1]]
+		}
+		return fmt.Errorf("x11: connection refused: %s", strings.TrimSpace(string(reason)))
+	}
+	if len(body) < 32 {
+		return errors.New("x11: short setup reply")
+	}
+	c.idBase = binary.LittleEndian.Uint32(body[4:])
+	c.idMask = binary.LittleEndian.Uint32(body[8:])
+	vendorLen := int(binary.LittleEndian.Uint16(body[16:]))
+	c.maxReq = int(binary.LittleEndian.Uint16(body[18:])) * 4
+	numScreens, numFormats := int(body[20]), int(body[21])
+	off := 32 + len(x11Pad(make([]byte, vendorLen))) + 8*numFormats
+	for i := 0; i < numScreens; i++ {
+		if off+40 > len(body) {
+			break
+		}
+		if i == screen || i == 0 {
+			c.root = binary.LittleEndian.Uint32(body[off:])
+		}
+		numDepths := int(body[off+39])
+		off += 40
+		for d := 0; d < numDepths && off+8 <= len(body); d++ {
+			off += 8 + 24*int(binary.LittleEndian.Uint16(body[off+2:]))
+		}
+	}
+	if c.root == 0 {
+		return errors.New("x11: no screen in setup reply")
+	}
+	return nil
+}
+
+// x11Cookie returns the authorization entry for the display from the
+// Xauthority file, preferring one for this host.
+func x11Cookie(host, number string) (name, data []byte) {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".Xauthority")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+
+	if host == "" || host == "unix" {
+		host, _ = os.Hostname()
+	}
+	r := bufio.NewReader(f)
+	field := func() ([]byte, error) {
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	for {
+		var family uint16
+		if binary.Read(r, binary.BigEndian, &family) != nil {
+			return name, data
+		}
+		addr, err1 := field()
+		num, err2 := field()
+		n, err3 := field()
+		d, err4 := field()
+		if err := errors.Join(err1, err2, err3, err4); err != nil {
+			return name, data
+		}
+		if string(num) != number || string(n) != "MIT-MAGIC-COOKIE-1" {
+			continue
+		}
+		const familyLocal, familyWild = 256, 65535
+		if family == familyWild || (family == familyLocal && string(addr) == host) {
+			return n, d
+		}
+		if name == nil {
+			name, data = n, d
+		}
+	}
+}
+
+// newID allocates a resource ID.
+func (c *x11Conn) newID() uint32 {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.nextID++
+	return c.idBase | (c.nextID & c.idMask)
+}
+
+// send writes a request without waiting for a reply. body excludes the
+// four-byte header and is padded here.
+func (c *x11Conn) send(opcode, data byte, body []byte) error {
+	_, err := c.write(opcode, data, body, false)
+	return err
+}
+
+// call writes a request and waits for its reply.
+func (c *x11Conn) call(opcode, data byte, body []byte) ([]byte, error) {
+	ch, err := c.write(opcode, data, body, true)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := <-ch
+	if !ok {
+		return nil, errors.New("x11: connection closed")
+	}
+	return r.b, r.err
+}
+
+func (c *x11Conn) write(opcode, data byte, body []byte, reply bool) (chan x11Reply, error) {
+	body = x11Pad(body)
+	if 4+len(body) > c.maxReq {
+		return nil, fmt.Errorf("x11: request of %d bytes exceeds the server maximum", 4+len(body))
+	}
+	msg := make([]byte, 4, 4+len(body))
+	msg[0], msg[1] = opcode, data
+	binary.LittleEndian.PutUint16(msg[2:], uint16((4+len(body))/4))
+	msg = append(msg, body...)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.seq++
+	var ch chan x11Reply
+	if reply {
+		ch = make(chan x11Reply, 1)
+		c.pmu.Lock()
+		c.pending[c.seq] = ch
+		c.pmu.Unlock()
+	}
+	if _, err := c.conn.Write(msg); err != nil {
+		if reply {
+			c.pmu.Lock()
+			delete(c.pending, c.seq)
+			c.pmu.Unlock()
+		}
+		return nil, err
+	}
+	return ch, nil
+}
+
+// run reads from the connection until it fails, completing calls and passing
+// events (32 bytes each) to handle. Errors for requests sent without waiting
+// are passed to handle as well.
+func (c *x11Conn) run(handle func(ev []byte)) error {
+	r := bufio.NewReader(c.conn)
+	defer func() {
+		c.pmu.Lock()
+		for seq, ch := range c.pending {
+			close(ch)
+			delete(c.pending, seq)
+		}
+		c.pmu.Unlock()
+	}()
+	for {
+		ev := make([]byte, 32)
+		if _, err := io.ReadFull(r, ev); err != nil {
+			return err
+		}
+		seq := binary.LittleEndian.Uint16(ev[2:])
+		switch ev[0] {
+		case 0: // error
+			if ch := c.take(seq); ch != nil {
+				ch <- x11Reply{err: fmt.Errorf("x11: error %d for request %d", ev[1], ev[10])}
+				continue
+			}
+			handle(ev)
+		case 1: // reply
+			if extra := int(binary.LittleEndian.Uint32(ev[4:])) * 4; extra > 0 {
+				ev = append(ev, make([]byte, extra)...)
+				if _, err := io.ReadFull(r, ev[32:]); err != nil {
+					return err
+				}
+			}
+			if ch := c.take(seq); ch != nil {
+				ch <- x11Reply{b: ev}
+			}
+		default:
+			handle(ev)
+		}
+	}
+}
+
+func (c *x11Conn) take(seq uint16) chan x11Reply {
+	c.pmu.Lock()
+	defer c.pmu.Unlock()
+	ch := c.pending[seq]
+	delete(c.pending, seq)
+	return ch
+}
+
+func (c *x11Conn) Close() error { return c.conn.Close() }
+
+// internAtom returns the atom for name, creating it if needed.
+func (c *x11Conn) internAtom(name string) (uint32, error) {
+	body := binary.LittleEndian.AppendUint16(nil, uint16(len(name)))
+	body = append(body, 0, 0)
+	body = append(body, name...)
+	r, err := c.call(x11InternAtom, 0, body)
+	if err != nil {
+		return 0, fmt.Errorf("intern %s: %w", name, err)
+	}
+	return binary.LittleEndian.Uint32(r[8:]), nil
+}
+
+// queryExtension returns the major opcode and first event code of an
+// extension, or ok false when the server lacks it.
+func (c *x11Conn) queryExtension(name string) (opcode, firstEvent byte, ok bool, err error) {
+	body := binary.LittleEndian.AppendUint16(nil, uint16(len(name)))
+	body = append(body, 0, 0)
+	body = append(body, name...)
+	r, err := c.call(x11QueryExtension, 0, body)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return r[9], r[10], r[8] != 0, nil
+}
+
+// getProperty reads and deletes property of window. It returns the
+// property's type and value, or an error rather than a truncated value when
+// the property holds more than maxBytes.
+func (c *x11Conn) getProperty(window, property uint32, maxBytes int) (typ uint32, value []byte, err error) {
+	body := x11Uint32s(window, property, 0, 0, uint32(maxBytes/4))
+	r, err := c.call(x11GetProperty, 1, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	format := int(r[1])
+	typ = binary.LittleEndian.Uint32(r[8:])
+	n := int(binary.LittleEndian.Uint32(r[16:])) * max(format/8, 1)
+	if 32+n > len(r) {
+		return 0, nil, errors.New("x11: short GetProperty reply")
+	}
+	if binary.LittleEndian.Uint32(r[12:]) > 0 { // bytes after
+		return 0, nil, fmt.Errorf("x11: property larger than %d bytes", maxBytes)
+	}
+	return typ, r[32 : 32+n], nil
+}
+
+// x11Uint32s encodes values as consecutive CARD32s.
+func x11Uint32s(values ...uint32) []byte {
+	b := make([]byte, 0, 4*len(values))
+	for _, v := range values {
+		b = binary.LittleEndian.AppendUint32(b, v)
+	}
+	return b
+}
+
+// x11Pad pads b to a multiple of four bytes.
+func x11Pad(b []byte) []byte {
+	return append(b, make([]byte, pad4(len(b))-len(b))...)
+}
@@ -0,0 +1,266 @@
+package clip
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math/bits"
+)
+
+// Device-independent bitmap constants, from wingdi.h.
+const (
+	biRGB       = 0
+	biBitfields = 3
+	biPNG       = 5
+
+	bitmapInfoHeaderSize = 40  // sizeof(BITMAPINFOHEADER)
+	bitmapV4HeaderSize   = 108 // sizeof(BITMAPV4HEADER)
+	bitmapV5HeaderSize   = 124 // sizeof(BITMAPV5HEADER)
+
+	lcsSRGB         = 0x73524742 // 'sRGB'
+	profileEmbedded = 0x4d424544 // 'MBED'
+	lcsGMImages     = 4
+)
+
+// maxICCProfile bounds the ICC profile read from a PNG; real ones are a few
+// kilobytes to a few megabytes.
+const maxICCProfile = 16 << 20
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// dibToPNG converts a CF_DIB or CF_DIBV5 bitmap to PNG, keeping its alpha
+// channel and its embedded ICC profile. It handles 16-, 24- and 32-bit
+// bitmaps, uncompressed or with bit fields, bottom-up or top-down, and
+// bitmaps holding a PNG; palette bitmaps are an error. A 32-bit bitmap
+// whose alpha is zero throughout, as applications that ignore alpha write
+// them, is taken as opaque.
+func dibToPNG(dib []byte) ([]byte, error) {
+	if len(dib) < bitmapInfoHeaderSize {
+		return nil, errors.New("short bitmap header")
+	}
+	le := binary.LittleEndian
+	headerSize := int(le.Uint32(dib[0:]))
+	w := int(int32(le.Uint32(dib[4:])))
+	h := int(int32(le.Uint32(dib[8:])))
+	bpp := int(le.Uint16(dib[14:]))
+	compression := le.Uint32(dib[16:])
+	if headerSize < bitmapInfoHeaderSize || headerSize > len(dib) {
+		return nil, fmt.Errorf("bitmap header size %d", headerSize)
+	}
+	topDown := h < 0
+	if topDown {
+		h = -h
+	}
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("bitmap size %dx%d", w, h)
+	}
+
+	offset := headerSize
+	if compression == biPNG {
+		size := int(le.Uint32(dib[20:]))
+		if size <= 0 || offset+size > len(dib) || !bytes.HasPrefix(dib[offset:], pngSignature) {
+			return nil, errors.New("bitmap holds no PNG")
+		}
+		return dib[offset : offset+size], nil
+	}
+
+	// Channel masks: defaults for BI_RGB, those of the header from V4 on,
+	// or the three that follow a BITMAPINFOHEADER.
+	var masks [4]uint32 // red, green, blue, alpha
+	switch {
+	case compression == biRGB && bpp == 16:
+		masks = [4]uint32{0x7c00, 0x03e0, 0x001f, 0}
+	case compression == biRGB && (bpp == 24 || bpp == 32):
+		masks = [4]uint32{0xff0000, 0x00ff00, 0x0000ff, 0}
+	case compression == biBitfields && (bpp == 16 || bpp == 32):
+		if headerSize >= bitmapV4HeaderSize {
+			for i := range masks {
+				masks[i] = le.Uint32(dib[40+4*i:])
+			}
+		} else {
+			if len(dib) < offset+12 {
+				return nil, errors.New("short bitmap masks")
+			}
+			for i := range 3 {
+				masks[i] = le.Uint32(dib[offset+4*i:])
+			}
+			offset += 12
+		}
+	default:
+		return nil, fmt.Errorf("unsupported bitmap: %d bits per pixel, compression %d", bpp, compression)
+	}
+	// Applications mark alpha in the unused byte of a 32-bit BI_RGB bitmap
+	// without saying so; it is used unless it is zero throughout.
+	guessAlpha := masks[3] == 0 && bpp == 32 && compression == biRGB
+	if guessAlpha {
+		masks[3] = 0xff000000
+	}
+
+	stride := (w*bpp + 31) / 32 * 4
+	if (len(dib)-offset)/stride < h {
+		return nil, errors.New("short bitmap data")
+	}
+	pixels := dib[offset:]
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	var alpha uint32
+	for y := range h {
+		row := pixels[(h-1-y)*stride:]
+		if topDown {
+			row = pixels[y*stride:]
+		}
+		for x := range w {
+			var v uint32
+			switch bpp {
+			case 16:
+				v = uint32(le.Uint16(row[2*x:]))
+			case 24:
+				v = uint32(row[3*x]) | uint32(row[3*x+1])<<8 | uint32(row[3*x+2])<<16
+			case 32:
+				v = le.Uint32(row[4*x:])
+			}
+			a := uint8(0xff)
+			if masks[3] != 0 {
+				a = channel(v, masks[3])
+				alpha |= uint32(a)
+			}
+			img.SetNRGBA(x, y, color.NRGBA{channel(v, masks[0]), channel(v, masks[1]), channel(v, masks[2]), a})
+		}
+	}
+	if guessAlpha && alpha == 0 {
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 0xff
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	out := buf.Bytes()
+	if headerSize >= bitmapV5HeaderSize && le.Uint32(dib[56:]) == profileEmbedded {
+		// bV5ProfileData is an offset from the start of the header.
+		start, size := int(le.Uint32(dib[112:])), int(le.Uint32(dib[116:]))
+		if size > 0 && start >= headerSize && start+size <= len(dib) {
+			out = withICCProfile(out, dib[start:start+size])
+		}
+	}
+	return out, nil
+}
+
+// channel returns the bits of v under mask scaled to eight bits.
+func channel(v, mask uint32) uint8 {
+	if mask == 0 {
+		return 0
+	}
+	n := bits.OnesCount32(mask)
+	c := (v & mask) >> bits.TrailingZeros32(mask)
+	if n >= 8 {
+		return uint8(c >> (n - 8))
+	}
+	return uint8(c * 0xff / (1<<n - 1))
+}
+
+// pngToDIBV5 converts a PNG to a bottom-up 32-bit BGRA CF_DIBV5 bitmap with
+// straight alpha, carrying the PNG's ICC profile embedded or else declaring
+// sRGB.
+func pngToDIBV5(data []byte) ([]byte, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	profile := iccProfile(data)
+
+	out := make([]byte, bitmapV5HeaderSize, bitmapV5HeaderSize+4*w*h+len(profile))
+	le := binary.LittleEndian
+	le.PutUint32(out[0:], bitmapV5HeaderSize) // bV5Size
+	le.PutUint32(out[4:], uint32(int32(w)))   // bV5Width
+	le.PutUint32(out[8:], uint32(int32(h)))   // bV5Height (positive: bottom-up)
+	le.PutUint16(out[12:], 1)                 // bV5Planes
+	le.PutUint16(out[14:], 32)                // bV5BitCount
+	le.PutUint32(out[16:], biBitfields)       // bV5Compression
+	le.PutUint32(out[20:], uint32(4*w*h))     // bV5SizeImage
+	le.PutUint32(out[40:], 0x00ff0000)        // bV5RedMask
+	le.PutUint32(out[44:], 0x0000ff00)        // bV5GreenMask
+	le.PutUint32(out[48:], 0x000000ff)        // bV5BlueMask
+	le.PutUint32(out[52:], 0xff000000)        // bV5AlphaMask
+	le.PutUint32(out[56:], lcsSRGB)           // bV5CSType
+	le.PutUint32(out[108:], lcsGMImages)      // bV5Intent
+
+	for y := bounds.Max.Y - 1; y >= bounds.Min.Y; y-- {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			out = append(out, c.B, c.G, c.R, c.A)
+		}
+	}
+	if len(profile) > 0 {
+		le.PutUint32(out[56:], profileEmbedded)   // bV5CSType
+		le.PutUint32(out[112:], uint32(len(out))) // bV5ProfileData
+		le.PutUint32(out[116:], uint32(len(profile)))
+		out = append(out, profile...)
+	}
+	return out, nil
+}
+
+// iccProfile returns the ICC profile of a PNG's iCCP chunk, or nil.
+func iccProfile(data []byte) []byte {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil
+	}
+	for rest := data[len(pngSignature):]; len(rest) >= 12; {
+		n := int(binary.BigEndian.Uint32(rest))
+		typ := string(rest[4:8])
+		if n < 0 || 12+n > len(rest) || typ == "IDAT" {
+			return nil
+		}
+		if typ == "iCCP" {
+			// Profile name, NUL, compression method 0 (zlib), profile.
+			chunk := rest[8 : 8+n]
+			i := bytes.IndexByte(chunk, 0)
+			if i < 0 || i+2 > len(chunk) || chunk[i+1] != 0 {
+				return nil
+			}
+			r, err := zlib.NewReader(bytes.NewReader(chunk[i+2:]))
+			if err != nil {
+				return nil
+			}
+			profile, err := io.ReadAll(io.LimitReader(r, maxICCProfile))
+			if err != nil {
+				return nil
+			}
+			return profile
+		}
+		rest = rest[12+n:]
+	}
+	return nil
+}
+
+// withICCProfile returns a PNG, as png.Encode writes it, with an iCCP chunk
+// holding profile after its IHDR chunk.
+func withICCProfile(data, profile []byte) []byte {
+	const ihdrEnd = 8 + 12 + 13 // signature, IHDR length, type, data and CRC
+	if len(data) < ihdrEnd || string(data[12:16]) != "IHDR" {
+		return data
+	}
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(profile) //nolint:errcheck // bytes.Buffer does not fail
+	zw.Close()        //nolint:errcheck // bytes.Buffer does not fail
+	chunk := append([]byte("iCCP"), "ICC Profile\x00\x00"...)
+	chunk = append(chunk, z.Bytes()...)
+
+	out := make([]byte, 0, len(data)+len(chunk)+8)
+	out = append(out, data[:ihdrEnd]...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(chunk)-4))
+	out = append(out, chunk...)
+	out = binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(chunk))
+	return append(out, data[ihdrEnd:]...)
+}
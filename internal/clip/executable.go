@@ -0,0 +1,89 @@
+package clip
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// executableMimes are the types of programs and scripts.
+var executableMimes = map[string]bool{
+	"application/x-msdownload":                      true,
+	"application/x-msdos-program":                   true,
+	"application/vnd.microsoft.portable-executable": true,
+	"application/x-ms-installer":                    true,
+	"application/x-msi":                             true,
+	"application/x-executable":                      true,
+	"application/x-elf":                             true,
+	"application/x-sharedlib":                       true,
+	"application/x-mach-binary":                     true,
+	"application/x-sh":                              true,
+	"application/x-shellscript":                     true,
+	"text/x-shellscript":                            true,
+	"application/x-bat":                             true,
+	"application/x-powershell":                      true,
+	"text/x-powershell":                             true,
+	"application/x-vbscript":                        true,
+	"text/vbscript":                                 true,
+	"application/hta":                               true,
+	"application/java-archive":                      true,
+	"application/x-apple-diskimage":                 true,
+}
+
+// executableExts are the file name extensions of programs and scripts that
+// run when opened.
+var executableExts = map[string]bool{
+	".exe": true, ".com": true, ".scr": true, ".msi": true, ".msp": true,
+	".dll": true, ".cpl": true, ".bat": true, ".cmd": true, ".ps1": true,
+	".psm1": true, ".vbs": true, ".vbe": true, ".js": true, ".jse": true,
+	".wsf": true, ".wsh": true, ".hta": true, ".lnk": true, ".reg": true,
+	".jar": true, ".sh": true, ".command": true, ".app": true, ".pkg": true,
+	".dmg": true, ".appimage": true, ".run": true, ".desktop": true,
+}
+
+// executableMagic are the leading bytes of native executables: PE, ELF,
+// Mach-O (both byte orders, 32 and 64 bit) and universal binaries.
+var executableMagic = [][]byte{
+	[]byte("MZ"),
+	[]byte("\x7fELF"),
+	{0xfe, 0xed, 0xfa, 0xce}, {0xce, 0xfa, 0xed, 0xfe},
+	{0xfe, 0xed, 0xfa, 0xcf}, {0xcf, 0xfa, 0xed, 0xfe},
+	{0xca, 0xfe, 0xba, 0xbe},
+}
+
+// Executable reports why items look like a program or script that could run
+// on the host pasting them, or "" if they do not: an executable type, the
+// magic number of a native executable, a text starting with "#!", or file
+// references to executables.
+func Executable(items []*pb.ClipboardItem) string {
+	for _, it := range items {
+		switch {
+		case executableMimes[it.Mime]:
+			return it.Mime
+		case it.Mime == URIListMime:
+			for _, p := range ParseURIList(it.Data) {
+				if ExecutableName(p) {
+					return "file " + filepath.Base(p)
+				}
+			}
+		case strings.HasPrefix(it.Mime, "text/"):
+			if bytes.HasPrefix(bytes.TrimLeft(it.Data, " \t\r\n"), []byte("#!")) {
+				return "script (#!)"
+			}
+		}
+		for _, magic := range executableMagic {
+			if bytes.HasPrefix(it.Data, magic) && !strings.HasPrefix(it.Mime, "text/") {
+				return "executable binary (" + it.Mime + ")"
+			}
+		}
+	}
+	return ""
+}
+
+// ExecutableName reports whether the file name has the extension of a
+// program or script.
+func ExecutableName(name string) bool {
+	return executableExts[strings.ToLower(filepath.Ext(name))]
+}
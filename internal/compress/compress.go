@@ -0,0 +1,90 @@
+// Package compress compresses clipboard content on the wire: gzip for gRPC
+// calls to a server, negotiated per connection, and gzip for the HTTP/JSON
+// gateway, negotiated per request with Accept-Encoding and Content-Encoding.
+//
+// Importing the package registers the gzip codec with gRPC, so a server
+// built with it accepts compressed calls and answers each in kind. A gRPC
+// client only learns whether a server does by trying: connections set up
+// with DialOptions compress every call until one is refused for its
+// encoding, then fall back to sending uncompressed.
+package compress
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+)
+
+// DialOptions returns options that compress the calls made on one client
+// connection for as long as the server accepts it.
+func DialOptions() []grpc.DialOption {
+	n := &negotiator{}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(n.unary),
+		grpc.WithChainStreamInterceptor(n.stream),
+	}
+}
+
+// negotiator tracks whether the server at the other end of a connection
+// accepts gzip.
+type negotiator struct {
+	off atomic.Bool
+}
+
+// unary compresses a call and repeats it uncompressed if the server refuses
+// the encoding, so the first call to an older server still succeeds.
+func (n *negotiator) unary(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if n.off.Load() {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	err := invoker(ctx, method, req, reply, cc, append(opts, grpc.UseCompressor(gzip.Name))...)
+	if n.refused(err) {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	return err
+}
+
+// stream compresses a stream. A refusal surfaces on the stream's first
+// receive and cannot be retried transparently; the caller's reconnect then
+// goes out uncompressed.
+func (n *negotiator) stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if n.off.Load() {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	cs, err := streamer(ctx, desc, cc, method, append(opts, grpc.UseCompressor(gzip.Name))...)
+	if err != nil {
+		n.refused(err)
+		return nil, err
+	}
+	return &negotiatingStream{ClientStream: cs, n: n}, nil
+}
+
+type negotiatingStream struct {
+	grpc.ClientStream
+	n *negotiator
+}
+
+func (s *negotiatingStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	s.n.refused(err)
+	return err
+}
+
+// refused reports whether err is a server's refusal of the encoding, and
+// turns compression off for the connection if so.
+func (n *negotiator) refused(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.Unimplemented || !strings.Contains(st.Message(), "grpc-encoding") {
+		return false
+	}
+	if !n.off.Swap(true) {
+		slog.Debug("server does not accept compressed calls; sending uncompressed", "err", st.Message())
+	}
+	return true
+}
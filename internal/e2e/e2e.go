@@ -0,0 +1,138 @@
+// Package e2e implements client-side end-to-end encryption of clipboards.
+//
+// A Keyring maps clipboard names to passphrases. Items copied to a keyed
+// clipboard are sealed into one opaque ClipboardItem before they leave the
+// client, or the host whose system clipboard a server syncs, so the hubs in
+// between, federated peers, and the web UI only ever see ciphertext.
+// Clipboards without a key are sent in the clear as usual.
+//
+// Key derivation:
+//
+//	Argon2id(passphrase, salt="suffuse-e2e-v1:"+clipboard, t=1, m=64MiB, p=4)
+//	→ 32-byte XChaCha20-Poly1305 key
+//
+// Envelope: version byte (1) ‖ 24-byte random nonce ‖ ciphertext. The
+// clipboard name is bound as additional data, so a payload replayed into a
+// different clipboard fails to open.
+package e2e
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"google.golang.org/protobuf/proto"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// MIME is the type of the single item carrying an encrypted payload.
+const MIME = "application/x-suffuse-e2e"
+
+const version = 1
+
+// ErrNoKey is returned by Open for a sealed payload on a clipboard the
+// Keyring has no key for.
+var ErrNoKey = errors.New("e2e: clipboard is encrypted and no key is configured")
+
+// ErrDecrypt is returned when a payload cannot be authenticated, usually
+// because the passphrase differs from the one it was sealed with.
+var ErrDecrypt = errors.New("e2e: decryption failed (wrong passphrase?)")
+
+// Keyring holds the derived keys of the encrypted clipboards. The zero of
+// *Keyring (nil) has no keys.
+type Keyring struct {
+	keys map[string][]byte // clipboard → key
+}
+
+// NewKeyring derives a key for every clipboard → passphrase entry.
+// Empty passphrases are rejected rather than silently disabling encryption.
+func NewKeyring(passphrases map[string]string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte, len(passphrases))}
+	for cb, pass := range passphrases {
+		if pass == "" {
+			return nil, fmt.Errorf("e2e: empty passphrase for clipboard %q", cb)
+		}
+		k.keys[cb] = argon2.IDKey([]byte(pass), []byte("suffuse-e2e-v1:"+cb),
+			1, 64*1024, 4, chacha20poly1305.KeySize)
+	}
+	return k, nil
+}
+
+// Clipboards returns the names of the encrypted clipboards, sorted.
+func (k *Keyring) Clipboards() []string {
+	if k == nil {
+		return nil
+	}
+	names := make([]string, 0, len(k.keys))
+	for cb := range k.keys {
+		names = append(names, cb)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Encrypted reports whether clipboard has a key.
+func (k *Keyring) Encrypted(clipboard string) bool {
+	if k == nil {
+		return false
+	}
+	_, ok := k.keys[clipboard]
+	return ok
+}
+
+// Seal encrypts items for clipboard. Items for clipboards without a key are
+// returned unchanged.
+func (k *Keyring) Seal(clipboard string, items []*pb.ClipboardItem) ([]*pb.ClipboardItem, error) {
+	if !k.Encrypted(clipboard) {
+		return items, nil
+	}
+	key := k.keys[clipboard]
+	plain, err := proto.Marshal(&pb.SealedItems{Items: items})
+	if err != nil {
+		return nil, fmt.Errorf("e2e: marshal: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("e2e: %w", err)
+	}
+	out := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(plain)+aead.Overhead())
+	out[0] = version
+	if _, err := rand.Read(out[1:]); err != nil {
+		return nil, fmt.Errorf("e2e: nonce: %w", err)
+	}
+	out = aead.Seal(out, out[1:], plain, []byte(clipboard))
+	return []*pb.ClipboardItem{{Mime: MIME, Data: out}}, nil
+}
+
+// Open reverses Seal. Items that are not a sealed payload are returned
+// unchanged, so clipboards written by clients without a key still paste.
+func (k *Keyring) Open(clipboard string, items []*pb.ClipboardItem) ([]*pb.ClipboardItem, error) {
+	if len(items) != 1 || items[0].Mime != MIME {
+		return items, nil
+	}
+	if !k.Encrypted(clipboard) {
+		return nil, ErrNoKey
+	}
+	key, data := k.keys[clipboard], items[0].Data
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("e2e: %w", err)
+	}
+	if len(data) < 1+aead.NonceSize() || data[0] != version {
+		return nil, fmt.Errorf("e2e: unsupported payload")
+	}
+	nonce, ciphertext := data[1:1+aead.NonceSize()], data[1+aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, []byte(clipboard))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	var sealed pb.SealedItems
+	if err := proto.Unmarshal(plain, &sealed); err != nil {
+		return nil, fmt.Errorf("e2e: unmarshal: %w", err)
+	}
+	return sealed.Items, nil
+}
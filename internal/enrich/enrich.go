@@ -0,0 +1,312 @@
+// Package enrich attaches items derived from clipboard content after it was
+// delivered, e.g. the text recognised in a screenshot or Markdown converted
+// from HTML.
+//
+// Each configured Processor registers with the hub as a BroadcastPeer. When
+// a publish on a matching clipboard carries an item of a type the processor
+// accepts and none of the type it produces, the processor hands the item to
+// a command or an HTTP service from its own worker, so a slow conversion
+// never delays the copy itself. What comes back is published as an update
+// of the same event: the original items plus the derived one, under the
+// same event ID, so federation links pass it on as a follow-up of the copy
+// rather than a new one. An update is dropped when the clipboard changed in
+// the meantime.
+//
+// Signed copies are not enriched, as an added item would fail their
+// verification, and neither is content encrypted end to end, whose items
+// the server cannot read.
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/hub"
+	"go.klb.dev/suffuse/internal/signing"
+)
+
+// DefaultTimeout bounds a conversion when Config.Timeout is unset.
+const DefaultTimeout = 30 * time.Second
+
+// maxOutput caps what a processor may return.
+const maxOutput = 16 << 20
+
+// queueSize is the number of events a processor buffers while a conversion
+// is running.
+const queueSize = 16
+
+// Config describes one processor. It is read from a [[processors]] table in
+// the server's config file.
+type Config struct {
+	// Name identifies the processor in logs and peer lists.
+	Name string `mapstructure:"name"`
+	// Accepts are path.Match patterns of the item types converted, e.g.
+	// "image/*". The first item of a matching type is used.
+	Accepts []string `mapstructure:"accepts"`
+	// Produces is the type of the derived item, e.g. "text/plain". Content
+	// that already has an item of this type is left alone.
+	Produces string `mapstructure:"produces"`
+	// Command is run with the item on standard input; its standard output
+	// is the derived item. Exactly one of Command and URL is set.
+	Command []string `mapstructure:"command"`
+	// URL receives the item in an HTTP POST with the item's type as
+	// Content-Type; a 2xx response body is the derived item.
+	URL string `mapstructure:"url"`
+	// Headers are added to every request to URL, e.g. an Authorization
+	// header.
+	Headers map[string]string `mapstructure:"headers"`
+	// Clipboards are path.Match patterns selecting the clipboards whose
+	// content is enriched; empty matches every clipboard.
+	Clipboards []string `mapstructure:"clipboards"`
+	// Timeout bounds each conversion; zero means DefaultTimeout.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// Processor derives items from the content of matching publishes. It
+// implements hub.BroadcastPeer.
+type Processor struct {
+	cfg   Config
+	h     *hub.Hub
+	queue *hub.Queue
+
+	connectedAt time.Time
+
+	mu       sync.Mutex
+	lastDone time.Time // end of the last conversion
+}
+
+// New validates cfg and returns a Processor. Call Run to register it with
+// the hub and start processing.
+func New(cfg Config, h *hub.Hub) (*Processor, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("processor: no name given")
+	}
+	if (len(cfg.Command) == 0) == (cfg.URL == "") {
+		return nil, fmt.Errorf("processor %s: give either command or url", cfg.Name)
+	}
+	if cfg.URL != "" {
+		u, err := url.Parse(cfg.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("processor %s: url must be an absolute http(s) URL", cfg.Name)
+		}
+	}
+	if len(cfg.Accepts) == 0 || cfg.Produces == "" {
+		return nil, fmt.Errorf("processor %s: accepts and produces are required", cfg.Name)
+	}
+	for _, patterns := range [][]string{cfg.Accepts, cfg.Clipboards} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("processor %s: pattern %q: %w", cfg.Name, pattern, err)
+			}
+		}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &Processor{
+		cfg:         cfg,
+		h:           h,
+		queue:       h.NewQueue(hub.DropProcessor, queueSize),
+		connectedAt: time.Now(),
+	}, nil
+}
+
+// ID implements hub.Peer.
+func (p *Processor) ID() string { return "processor/" + p.cfg.Name }
+
+// Info implements hub.Peer. Addr is the command's name or the URL's scheme
+// and host only, as URLs often embed secrets. LastSeen is the last
+// conversion.
+func (p *Processor) Info() *pb.PeerInfo {
+	var addr string
+	if p.cfg.URL != "" {
+		u, _ := url.Parse(p.cfg.URL) // validated by New
+		addr = u.Scheme + "://" + u.Host
+	} else {
+		addr = p.cfg.Command[0]
+	}
+	clipboards := "*"
+	if len(p.cfg.Clipboards) > 0 {
+		clipboards = strings.Join(p.cfg.Clipboards, ",")
+	}
+	p.mu.Lock()
+	lastSeen := p.lastDone
+	p.mu.Unlock()
+	if lastSeen.IsZero() {
+		lastSeen = p.connectedAt
+	}
+	return &pb.PeerInfo{
+		Source:        p.cfg.Name,
+		Addr:          addr,
+		Role:          "processor",
+		Clipboard:     clipboards,
+		AcceptedTypes: p.cfg.Accepts,
+		ConnectedAt:   timestamppb.New(p.connectedAt),
+		LastSeen:      timestamppb.New(lastSeen),
+	}
+}
+
+// Broadcast implements hub.BroadcastPeer.
+func (p *Processor) Broadcast() {}
+
+// Send implements hub.Peer. Events the processor has nothing to do for are
+// ignored.
+func (p *Processor) Send(ev hub.Event) error {
+	if _, ok := p.input(ev); !ok {
+		return nil
+	}
+	return p.queue.Put(ev)
+}
+
+// Queue implements hub.QueuePeer.
+func (p *Processor) Queue() *hub.Queue { return p.queue }
+
+// input returns the index of the item of ev to convert, reporting false
+// when there is none or ev is not to be enriched.
+func (p *Processor) input(ev hub.Event) (int, bool) {
+	if ev.ID == "" || ev.Expired || hub.IsProbeClipboard(ev.Clipboard) || !matchAny(p.cfg.Clipboards, ev.Clipboard, true) {
+		return 0, false
+	}
+	if slices.ContainsFunc(ev.Items, func(it *pb.ClipboardItem) bool {
+		return it.Mime == p.cfg.Produces || it.Mime == signing.MIME
+	}) {
+		return 0, false
+	}
+	i := slices.IndexFunc(ev.Items, func(it *pb.ClipboardItem) bool { return matchAny(p.cfg.Accepts, it.Mime, false) })
+	return i, i >= 0
+}
+
+func matchAny(patterns []string, s string, emptyMatches bool) bool {
+	if len(patterns) == 0 {
+		return emptyMatches
+	}
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		ok, _ := path.Match(pattern, s)
+		return ok
+	})
+}
+
+// Run registers the processor with the hub and enriches events until ctx is
+// cancelled.
+func (p *Processor) Run(ctx context.Context) {
+	p.h.Register(p)
+	defer p.h.Unregister(p)
+
+	slog.Info("processor enabled",
+		"processor", p.cfg.Name,
+		"accepts", p.cfg.Accepts,
+		"produces", p.cfg.Produces,
+		"clipboards", p.cfg.Clipboards,
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-p.queue.C():
+			p.enrich(ctx, ev)
+		}
+	}
+}
+
+// enrich converts the input item of ev and publishes the update.
+func (p *Processor) enrich(ctx context.Context, ev hub.Event) {
+	i, ok := p.input(ev)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	items, err := p.h.Resolve(ctx, ev.Items[i:i+1])
+	var out []byte
+	if err == nil {
+		out, err = p.convert(ctx, items[0])
+	}
+	p.mu.Lock()
+	p.lastDone = time.Now()
+	p.mu.Unlock()
+	if err != nil {
+		slog.Warn("processor failed", "processor", p.cfg.Name, "clipboard", ev.Clipboard, "event", ev.ID, "err", err)
+		return
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		slog.Debug("processor derived nothing", "processor", p.cfg.Name, "clipboard", ev.Clipboard, "event", ev.ID)
+		return
+	}
+	update := append(slices.Clone(ev.Items), &pb.ClipboardItem{Mime: p.cfg.Produces, Data: out})
+	if !p.h.PublishUpdate(ctx, ev, update, p.ID()) {
+		slog.Debug("processor result dropped, the clipboard changed meanwhile", "processor", p.cfg.Name, "clipboard", ev.Clipboard, "event", ev.ID)
+		return
+	}
+	slog.Info("content enriched",
+		"processor", p.cfg.Name,
+		"clipboard", ev.Clipboard,
+		"event", ev.ID,
+		"type", p.cfg.Produces,
+		"bytes", len(out),
+		"took", time.Since(start).Round(time.Millisecond),
+	)
+}
+
+// convert runs the command or calls the service on it.
+func (p *Processor) convert(ctx context.Context, it *pb.ClipboardItem) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+	if len(p.cfg.Command) > 0 {
+		cmd := exec.CommandContext(ctx, p.cfg.Command[0], p.cfg.Command[1:]...)
+		cmd.Stdin = bytes.NewReader(it.Data)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%w: %s", err, msg)
+			}
+			return nil, err
+		}
+		if len(out) > maxOutput {
+			return nil, fmt.Errorf("output over %d bytes", maxOutput)
+		}
+		return out, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.URL, bytes.NewReader(it.Data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", it.Mime)
+	req.Header.Set("Accept", p.cfg.Produces)
+	for k, v := range p.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	out, err := io.ReadAll(io.LimitReader(resp.Body, maxOutput+1))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("HTTP %s", resp.Status)
+	}
+	if len(out) > maxOutput {
+		return nil, fmt.Errorf("response over %d bytes", maxOutput)
+	}
+	return out, nil
+}
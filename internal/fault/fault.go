@@ -0,0 +1,160 @@
+// Package fault injects latency, lost messages and disconnects into a
+// server's gRPC calls, to exercise reconnects, redelivery and duplicate
+// suppression in integration and soak tests. It is enabled by hidden
+// developer flags and must never be used in production.
+//
+// Every decision comes from one random source seeded by Config.Seed, so a
+// run that turned up a bug can be repeated with the same sequence of faults,
+// as far as the order of calls allows.
+package fault
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Config sets the faults to inject. Zero fields inject nothing.
+type Config struct {
+	// Seed seeds the random source; zero picks one, which is logged.
+	Seed uint64
+	// Latency is the most a message or call is delayed; each delay is
+	// uniform between zero and Latency.
+	Latency time.Duration
+	// Drop is the probability that a message is lost, or that a unary call
+	// fails as if its request had been.
+	Drop float64
+	// Disconnect is the probability that a stream is cut before a message
+	// is sent.
+	Disconnect float64
+}
+
+// Injector decides when faults happen. The zero of *Injector (nil) injects
+// nothing.
+type Injector struct {
+	cfg Config
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// New validates cfg and returns its Injector, or nil when cfg injects
+// nothing.
+func New(cfg Config) (*Injector, error) {
+	if cfg.Drop < 0 || cfg.Drop > 1 || cfg.Disconnect < 0 || cfg.Disconnect > 1 {
+		return nil, errors.New("fault probabilities must be between 0 and 1")
+	}
+	if cfg.Latency <= 0 && cfg.Drop == 0 && cfg.Disconnect == 0 {
+		return nil, nil
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = rand.Uint64()
+	}
+	slog.Warn("fault injection enabled; not for production use",
+		"seed", cfg.Seed, "latency", cfg.Latency, "drop", cfg.Drop, "disconnect", cfg.Disconnect)
+	return &Injector{cfg: cfg, rng: rand.New(rand.NewPCG(cfg.Seed, cfg.Seed))}, nil
+}
+
+func (i *Injector) chance(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rng.Float64() < p
+}
+
+func (i *Injector) delay(ctx context.Context) error {
+	if i.cfg.Latency <= 0 {
+		return nil
+	}
+	i.mu.Lock()
+	d := time.Duration(i.rng.Int64N(int64(i.cfg.Latency) + 1))
+	i.mu.Unlock()
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Unary returns the interceptor that injects the faults into unary calls
+// served outside a gRPC server, such as pipelined IPC requests; nil for a
+// nil Injector.
+func (i *Injector) Unary() grpc.UnaryServerInterceptor {
+	if i == nil {
+		return nil
+	}
+	return i.unary
+}
+
+// ServerOptions returns the interceptors that inject the faults into a gRPC
+// server; none for a nil Injector.
+func (i *Injector) ServerOptions() []grpc.ServerOption {
+	if i == nil {
+		return nil
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(i.unary),
+		grpc.ChainStreamInterceptor(i.stream),
+	}
+}
+
+func (i *Injector) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := i.delay(ctx); err != nil {
+		return nil, err
+	}
+	if i.chance(i.cfg.Drop) {
+		slog.Debug("fault injected: call dropped", "method", info.FullMethod)
+		return nil, status.Error(codes.Unavailable, "fault injected: call dropped")
+	}
+	return handler(ctx, req)
+}
+
+func (i *Injector) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &faultyStream{ServerStream: ss, i: i, method: info.FullMethod})
+}
+
+// faultyStream injects faults into the messages of one stream.
+type faultyStream struct {
+	grpc.ServerStream
+	i      *Injector
+	method string
+}
+
+func (s *faultyStream) SendMsg(m any) error {
+	if err := s.i.delay(s.Context()); err != nil {
+		return err
+	}
+	if s.i.chance(s.i.cfg.Disconnect) {
+		slog.Debug("fault injected: stream disconnected", "method", s.method)
+		return status.Error(codes.Unavailable, "fault injected: disconnected")
+	}
+	if s.i.chance(s.i.cfg.Drop) {
+		slog.Debug("fault injected: message dropped", "method", s.method, "direction", "send")
+		return nil
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+func (s *faultyStream) RecvMsg(m any) error {
+	for {
+		if err := s.ServerStream.RecvMsg(m); err != nil {
+			return err
+		}
+		if !s.i.chance(s.i.cfg.Drop) {
+			return nil
+		}
+		slog.Debug("fault injected: message dropped", "method", s.method, "direction", "receive")
+	}
+}
@@ -1,21 +1,36 @@
-// Package federation manages the optional upstream connection that turns a
+// Package federation manages the optional upstream connections that turn a
 // standalone suffuse server into a federated node.
 //
-// When an upstream address is configured, the Upstream type:
-//   - Registers itself with the local hub as a peer (using a fixed sentinel ID),
-//     receiving locally-published clipboard events and forwarding them upstream.
-//   - Maintains one Watch stream per distinct clipboard that local peers subscribe
-//     to. Each stream uses the MIME accept-union for that clipboard so upstream
-//     only sends what local consumers can handle.
+// One Upstream is created per configured upstream address. Each:
+//   - Registers itself with the local hub as a peer (with an ID derived from
+//     its address), receiving locally-published clipboard events and
+//     forwarding them upstream.
+//   - Maintains a single bidirectional Federate stream to the upstream server.
+//     The stream carries a subscription (one entry per distinct clipboard that
+//     local peers watch, with the MIME accept-union for that clipboard so
+//     upstream only sends what local consumers can handle), events in both
+//     directions, and an ack for every event received.
 //   - Implements hub.PeerChangeListener: when the per-clipboard filter set
 //     changes (new clipboard watched, last watcher gone, MIME union changed),
-//     streams are opened, closed, or resubscribed accordingly.
-//   - Reconnects each stream independently with exponential back-off.
+//     an updated subscription is sent on the existing stream. Clipboards in
+//     Config.Pin stay subscribed without local watchers.
+//   - With Config.Blobs set, accepts large items from upstream as blob
+//     references and fetches their content on demand with the Fetch RPC.
+//   - With Config.Shaping set, holds back non-text items in both directions
+//     while the policy is active: local events are forwarded with their text
+//     only and the rest follows when the policy lifts; upstream is asked to
+//     send non-text items as references, which are not fetched until then.
+//   - Reconnects the stream with exponential back-off. Events forwarded
+//     upstream are kept in a bounded Outbox until acknowledged and are
+//     redelivered on the next stream if the link drops first.
 //
 // Loop prevention: events received from upstream are published to the local hub
-// with originID == upstreamOriginID. The Upstream peer is registered with the
-// same ID, so the hub will not deliver those events back to us, breaking the
-// forwarding loop.
+// with originID set to the receiving Upstream's peer ID, so the hub will not
+// deliver them back over the same link. With several upstreams an event from
+// one is forwarded to the others, which makes a mesh possible; cycles and
+// second paths through it are cut by the event ID and hop limit every event
+// carries. The hub drops an event whose ID it has already published, and
+// relays none whose hop limit, decremented at each link, is spent.
 package federation
 
 import (
@@ -24,37 +39,80 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"reflect"
+	"maps"
+	"path"
 	"slices"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
+	"go.klb.dev/suffuse/internal/compress"
 	"go.klb.dev/suffuse/internal/hub"
+	"go.klb.dev/suffuse/internal/shaping"
+	"go.klb.dev/suffuse/internal/sshtunnel"
 	"go.klb.dev/suffuse/internal/tlsconf"
+	"go.klb.dev/suffuse/internal/tracing"
 )
 
 const (
-	upstreamOriginID = "federation/upstream"
+	upstreamIDPrefix = "federation/upstream/" // + Config.Addr
 	reconnectDelay   = time.Second
 	maxReconnect     = 30 * time.Second
+	probeTimeout     = 10 * time.Second // FederationStatus, which may probe further up
 )
 
-// Config holds the configuration for the upstream federation connection.
+// reconnectBackoff paces attempts to re-establish a dropped upstream
+// connection.
+var reconnectBackoff = backoff.Config{
+	BaseDelay:  100 * time.Millisecond,
+	Multiplier: 1.6,
+	Jitter:     0.2,
+	MaxDelay:   maxReconnect,
+}
+
+// Config holds the configuration for one upstream federation connection.
 type Config struct {
 	// Addr is the upstream server address (host:port).
 	Addr string
 	// Token is the shared secret for the upstream server (may be empty).
 	Token string
+	// CA, when set, verifies the upstream server's certificate against this
+	// PEM file, or the system trust store for tlsconf.SystemRoots, instead
+	// of by the key derived from Token; for upstreams started with an
+	// operator-provided certificate.
+	CA string
+	// Tunnel, when set, reaches Addr through an SSH jump host, which
+	// resolves it; nil dials Addr directly.
+	Tunnel *sshtunnel.Tunnel
 	// Source is the identifier sent to the upstream server.
 	Source string
+	// Publish restricts which local clipboards are forwarded upstream.
+	// Entries are path.Match patterns (e.g. "default", "team/*"); empty
+	// forwards every clipboard.
+	Publish []string
+	// Pin lists clipboards subscribed from upstream, with every MIME type,
+	// whether or not a local peer watches them, so their latest content is
+	// already here when a client connects.
+	Pin []string
+	// Blobs, when set, lets upstream send large items as blob references.
+	// Content is fetched from upstream the first time a local peer needs it
+	// and cached in Blobs.
+	Blobs *blob.Store
+	// Shaping, when set, defers non-text items while the policy is active.
+	// Items from upstream are only deferred when Blobs is set too.
+	Shaping *shaping.Policy
 }
 
 // clipboardFilter is a snapshot of what a single clipboard needs from upstream.
@@ -66,85 +124,145 @@ func (f clipboardFilter) equal(other clipboardFilter) bool {
 	return slices.Equal(f.accepts, other.accepts)
 }
 
-// streamHandle manages one upstream Watch stream for one clipboard.
-type streamHandle struct {
-	cancel context.CancelFunc
-	done   chan struct{}
-}
-
-// Upstream manages persistent federation streams to one upstream server.
+// Upstream manages the persistent federation stream to one upstream server.
 // It implements hub.Peer (to receive local events for forwarding upstream)
-// and hub.PeerChangeListener (to reconcile streams when local watchers change).
+// and hub.PeerChangeListener (to keep the upstream subscription current).
 type Upstream struct {
+	id     string // peer ID, also the origin of events from upstream
 	cfg    Config
 	h      *hub.Hub
 	conn   *grpc.ClientConn
 	client pb.ClipboardServiceClient
 
-	// sendCh receives local hub events destined for the upstream server.
-	sendCh chan hub.Event
+	// removeFetcher takes fetch out of cfg.Blobs when Run returns.
+	removeFetcher func()
+
+	// queue receives local hub events destined for the upstream server.
+	queue *hub.Queue
 
-	// streamsMu guards streams and wantFilters.
-	streamsMu   sync.Mutex
-	streams     map[string]*streamHandle  // clipboard → active stream
+	// outbox holds forwarded events until upstream acknowledges them.
+	outbox *Outbox
+
+	// applied holds the last items published per clipboard from upstream,
+	// suppressing duplicates across redeliveries. Only touched by recvLoop.
+	applied map[string][]*pb.ClipboardItem
+
+	// filtersMu guards wantFilters.
+	filtersMu   sync.Mutex
 	wantFilters map[string]clipboardFilter // clipboard → desired filter
 
+	// subscribeCh is signalled when wantFilters changes so the stream loop
+	// sends an updated subscription.
+	subscribeCh chan struct{}
+
+	// handshake is the TLS handshake of the connection since the last
+	// stream was opened, if a new one was made; logged when the next is.
+	handshake atomic.Pointer[tlsconf.Handshake]
+
 	// State for UpstreamInfo reported via StatusResponse.
 	stateMu     sync.RWMutex
-	connectedAt map[string]time.Time // clipboard → connected time
-	lastSeen    map[string]time.Time // clipboard → last event time
+	connectedAt time.Time // zero while disconnected
+	lastSeen    time.Time // last message received from upstream
+	deferring   string    // why non-text items are held back; "" if not
+
+	// deferredOut holds, per clipboard, the latest local event whose
+	// non-text items were held back. Only touched by the stream loop.
+	deferredOut map[string]hub.Event
+
+	// shapeMu guards heldSums and heldClipboards: the references received
+	// while deferring, which fetch refuses, and the clipboards they arrived
+	// on, which are republished when upstream resends them.
+	shapeMu        sync.Mutex
+	heldSums       map[string]struct{}
+	heldClipboards map[string]struct{}
 }
 
 // New creates an Upstream, registers it with the hub, and returns it.
-// Call Run in a goroutine to start the connection loops.
+// Call Run in a goroutine to start the connection loop.
 func New(cfg Config, h *hub.Hub) (*Upstream, error) {
-	opts, err := dialOpts(cfg.Token, cfg.Source)
+	if err := checkPublish(cfg.Publish); err != nil {
+		return nil, err
+	}
+	// The connection is lazy, so u is set before the first handshake.
+	var u *Upstream
+	opts, err := dialOpts(cfg.Token, cfg.Source, cfg.CA, func(hs tlsconf.Handshake) { u.handshake.Store(&hs) })
 	if err != nil {
 		return nil, err
 	}
-	conn, err := grpc.NewClient(cfg.Addr, opts...)
+	target := cfg.Addr
+	if cfg.Tunnel != nil {
+		opts = append(opts, grpc.WithContextDialer(cfg.Tunnel.DialContext))
+		target = sshtunnel.Target(cfg.Addr)
+	}
+	conn, err := grpc.NewClient(target, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("federation dial %s: %w", cfg.Addr, err)
 	}
 
-	u := &Upstream{
+	u = &Upstream{
+		id:          upstreamIDPrefix + cfg.Addr,
 		cfg:         cfg,
 		h:           h,
 		conn:        conn,
 		client:      pb.NewClipboardServiceClient(conn),
-		sendCh:      make(chan hub.Event, 64),
-		streams:     make(map[string]*streamHandle),
+		queue:       h.NewQueue(hub.DropFederationUpstream, 64),
+		outbox:      NewOutbox(DefaultOutboxSize),
+		applied:     make(map[string][]*pb.ClipboardItem),
 		wantFilters: make(map[string]clipboardFilter),
-		connectedAt: make(map[string]time.Time),
-		lastSeen:    make(map[string]time.Time),
+		subscribeCh: make(chan struct{}, 1),
+
+		deferredOut:    make(map[string]hub.Event),
+		heldSums:       make(map[string]struct{}),
+		heldClipboards: make(map[string]struct{}),
 	}
 
-	h.SetPeerChangeListener(u)
+	if cfg.Blobs != nil {
+		u.removeFetcher = cfg.Blobs.AddFetcher(u.fetch)
+	}
+	h.AddPeerChangeListener(u)
 	h.Register(u)
+	// Warm the connection up so the first stream does not wait for the
+	// TCP and TLS handshakes.
+	conn.Connect()
 
 	return u, nil
 }
 
+// CheckConfig returns the error New would return for cfg, without dialing or
+// registering anything, so a reloaded configuration can be validated before
+// running links are replaced.
+func CheckConfig(cfg Config) error {
+	if err := checkPublish(cfg.Publish); err != nil {
+		return err
+	}
+	_, err := dialOpts(cfg.Token, cfg.Source, cfg.CA, nil)
+	return err
+}
+
+func checkPublish(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("federation publish pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
 // ── hub.Peer implementation ───────────────────────────────────────────────────
 
-func (u *Upstream) ID() string { return upstreamOriginID }
+func (u *Upstream) ID() string { return u.id }
 
 // Info reports the upstream peer. AcceptedTypes and Clipboard are left empty
 // because this peer spans multiple clipboards — the hub sees it as accepting
-// everything, which is correct: filtering happens per-stream upstream.
+// everything, which is correct: filtering happens upstream per subscription.
 func (u *Upstream) Info() *pb.PeerInfo {
 	u.stateMu.RLock()
-	var oldest time.Time
-	for _, t := range u.connectedAt {
-		if oldest.IsZero() || t.Before(oldest) {
-			oldest = t
-		}
-	}
+	connectedAt := u.connectedAt
 	u.stateMu.RUnlock()
 
 	var connectedAtTS *timestamppb.Timestamp
-	if !oldest.IsZero() {
-		connectedAtTS = timestamppb.New(oldest)
+	if !connectedAt.IsZero() {
+		connectedAtTS = timestamppb.New(connectedAt)
 	}
 
 	return &pb.PeerInfo{
@@ -160,21 +278,45 @@ func (u *Upstream) Info() *pb.PeerInfo {
 // events from all clipboards.
 func (u *Upstream) Broadcast() {}
 
+// Relays implements hub.RelayPeer: events forwarded upstream cross a
+// federation link.
+func (u *Upstream) Relays() {}
+
 // Send receives a local hub event and queues it for forwarding upstream.
-func (u *Upstream) Send(ev hub.Event) {
-	select {
-	case u.sendCh <- ev:
-	default:
-		slog.Warn("federation upstream send channel full, dropping",
-			"source", ev.Source, "clipboard", ev.Clipboard)
+// Events are only forwarded for clipboards that pass the Config.Publish
+// allowlist.
+func (u *Upstream) Send(ev hub.Event) error {
+	if !u.forwards(ev.Clipboard) {
+		slog.Debug("federation not forwarding clipboard upstream", "clipboard", ev.Clipboard)
+		return nil
+	}
+	return u.queue.Put(ev)
+}
+
+// Queue implements hub.QueuePeer.
+func (u *Upstream) Queue() *hub.Queue { return u.queue }
+
+// forwards reports whether events on cb should be forwarded upstream: those
+// on every clipboard, watched locally or not, unless Config.Publish narrows
+// them.
+func (u *Upstream) forwards(cb string) bool {
+	if len(u.cfg.Publish) == 0 || hub.IsProbeClipboard(cb) {
+		// Probes test the link itself, whatever it publishes.
+		return true
+	}
+	for _, pattern := range u.cfg.Publish {
+		if ok, _ := path.Match(pattern, cb); ok {
+			return true
+		}
 	}
+	return false
 }
 
 // ── hub.PeerChangeListener implementation ────────────────────────────────────
 
 // OnPeerChange is called by the hub on every peer register/unregister.
-// It reconciles the set of active upstream Watch streams against the new
-// per-clipboard filter requirements.
+// When the per-clipboard filter requirements differ from the current
+// subscription, the stream loop is signalled to send an updated one.
 func (u *Upstream) OnPeerChange(filters []hub.ClipboardFilter) {
 	// Build the desired filter map from the hub's notification.
 	want := make(map[string]clipboardFilter, len(filters))
@@ -183,85 +325,89 @@ func (u *Upstream) OnPeerChange(filters []hub.ClipboardFilter) {
 		sort.Strings(accepts)
 		want[f.Clipboard] = clipboardFilter{accepts: accepts}
 	}
-
-	u.streamsMu.Lock()
-	defer u.streamsMu.Unlock()
-
-	// Stop streams for clipboards no longer needed.
-	for cb, h := range u.streams {
-		if _, needed := want[cb]; !needed {
-			slog.Info("federation closing upstream stream", "clipboard", cb)
-			h.cancel()
-			<-h.done
-			delete(u.streams, cb)
-			u.stateMu.Lock()
-			delete(u.connectedAt, cb)
-			delete(u.lastSeen, cb)
-			u.stateMu.Unlock()
+	for _, cb := range u.cfg.Pin {
+		if cb == "" {
+			cb = hub.DefaultClipboard
 		}
+		want[cb] = clipboardFilter{} // all types, overriding narrower local filters
 	}
 
-	// Open or resubscribe streams for clipboards that need it.
-	for cb, f := range want {
-		current, active := u.wantFilters[cb]
-		if active && current.equal(f) {
-			continue // already correct
-		}
-		// Cancel existing stream if filter changed.
-		if h, exists := u.streams[cb]; exists {
-			slog.Info("federation resubscribing upstream stream",
-				"clipboard", cb, "accepts", f.accepts)
-			h.cancel()
-			<-h.done
-			delete(u.streams, cb)
-		}
-		u.wantFilters[cb] = f
-		u.streams[cb] = u.startStream(cb, f)
+	u.filtersMu.Lock()
+	changed := !maps.EqualFunc(want, u.wantFilters, clipboardFilter.equal)
+	if changed {
+		u.wantFilters = want
 	}
+	u.filtersMu.Unlock()
 
-	// Remove wantFilters for clipboards that are no longer needed.
-	for cb := range u.wantFilters {
-		if _, needed := want[cb]; !needed {
-			delete(u.wantFilters, cb)
-		}
+	if !changed {
+		return
+	}
+
+	select {
+	case u.subscribeCh <- struct{}{}:
+	default: // an update is already pending
 	}
 }
 
-// ── stream lifecycle ──────────────────────────────────────────────────────────
+// subscription builds the Subscribe message for the current wantFilters.
+func (u *Upstream) subscription() *pb.FederateMessage {
+	u.filtersMu.Lock()
+	defer u.filtersMu.Unlock()
 
-// startStream spawns a goroutine that maintains a Watch stream for cb with
-// the given filter, reconnecting on error. Must be called with streamsMu held.
-func (u *Upstream) startStream(cb string, f clipboardFilter) *streamHandle {
-	ctx, cancel := context.WithCancel(context.Background())
-	h := &streamHandle{cancel: cancel, done: make(chan struct{})}
-	go func() {
-		defer close(h.done)
-		u.streamLoop(ctx, cb, f)
-	}()
-	return h
+	sub := &pb.FederationSubscribe{
+		AcceptRefs:   u.cfg.Blobs != nil,
+		DeferNonText: u.cfg.Blobs != nil && u.deferringReason() != "",
+	}
+	for _, cb := range slices.Sorted(maps.Keys(u.wantFilters)) {
+		sub.Clipboards = append(sub.Clipboards, &pb.ClipboardSubscription{
+			Clipboard: cb,
+			Accepts:   u.wantFilters[cb].accepts,
+		})
+	}
+	return &pb.FederateMessage{Msg: &pb.FederateMessage_Subscribe{Subscribe: sub}}
 }
 
-// streamLoop runs a Watch stream for one clipboard, reconnecting with
-// exponential back-off until ctx is cancelled.
-func (u *Upstream) streamLoop(ctx context.Context, cb string, f clipboardFilter) {
+// ── Run (stream loop) ─────────────────────────────────────────────────────────
+
+// Run maintains the Federate stream, reconnecting with exponential back-off,
+// until ctx is cancelled, then closes the connection and leaves the hub.
+// Call in a goroutine alongside the hub.
+func (u *Upstream) Run(ctx context.Context) {
+	defer func() {
+		u.conn.Close()
+		u.h.Unregister(u)
+		u.h.RemovePeerChangeListener(u)
+		if u.removeFetcher != nil {
+			u.removeFetcher()
+		}
+	}()
+
 	delay := reconnectDelay
 	for {
-		err := u.runStream(ctx, cb, f)
-		if err == nil || errors.Is(err, context.Canceled) ||
-			status.Code(err) == codes.Canceled {
+		start := time.Now()
+		err := u.runStream(ctx)
+		if ctx.Err() != nil {
 			return
 		}
-		slog.Warn("federation upstream stream ended, reconnecting",
-			"clipboard", cb, "err", err, "retry_in", delay)
+		if status.Code(err) == codes.Unimplemented {
+			slog.Error("upstream does not support the Federate RPC; upgrade the upstream server",
+				"addr", u.cfg.Addr)
+		} else {
+			slog.Warn("federation upstream stream ended, reconnecting",
+				"addr", u.cfg.Addr, "err", err, "retry_in", delay)
+		}
 
 		u.stateMu.Lock()
-		delete(u.connectedAt, cb)
+		u.connectedAt = time.Time{}
 		u.stateMu.Unlock()
 
-		select {
-		case <-ctx.Done():
+		// A stream that stayed up for a while resets the back-off.
+		if time.Since(start) > maxReconnect {
+			delay = reconnectDelay
+		}
+		u.wait(ctx, delay, status.Code(err) == codes.Unavailable)
+		if ctx.Err() != nil {
 			return
-		case <-time.After(delay):
 		}
 		if delay < maxReconnect {
 			delay *= 2
@@ -269,87 +415,310 @@ func (u *Upstream) streamLoop(ctx context.Context, cb string, f clipboardFilter)
 	}
 }
 
-// runStream opens one Watch stream and runs until it errors or ctx is done.
-func (u *Upstream) runStream(ctx context.Context, cb string, f clipboardFilter) error {
-	stream, err := u.client.Watch(ctx, &pb.WatchRequest{
-		Clipboard: cb,
-		Accepts:   f.accepts,
-	})
+// wait sleeps for delay before the next stream. When the stream ended
+// because the connection dropped (dropped), it is re-established meanwhile
+// and wait returns as soon as it is ready, so a flapping link is rejoined as
+// soon as it is back rather than after the back-off.
+func (u *Upstream) wait(ctx context.Context, delay time.Duration, dropped bool) {
+	ctx, cancel := context.WithTimeout(ctx, delay)
+	defer cancel()
+	if !dropped {
+		<-ctx.Done()
+		return
+	}
+	state := u.conn.GetState()
+	if state == connectivity.Ready {
+		// The stream may report the failure before the connection does.
+		if !u.conn.WaitForStateChange(ctx, state) {
+			return
+		}
+		state = u.conn.GetState()
+	}
+	u.conn.Connect()
+	for state != connectivity.Ready && u.conn.WaitForStateChange(ctx, state) {
+		state = u.conn.GetState()
+	}
+}
+
+// runStream opens one Federate stream and runs until it errors or ctx is done.
+// The calling goroutine owns stream.Send; a second goroutine receives.
+func (u *Upstream) runStream(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := u.client.Federate(ctx)
 	if err != nil {
-		return fmt.Errorf("watch: %w", err)
+		return fmt.Errorf("federate: %w", err)
+	}
+	u.updateShaping()
+	// The initial subscription covers any pending change notification.
+	select {
+	case <-u.subscribeCh:
+	default:
+	}
+	if err := stream.Send(u.subscription()); err != nil {
+		return fmt.Errorf("subscribe: %w", err)
 	}
 
-	now := time.Now()
 	u.stateMu.Lock()
-	u.connectedAt[cb] = now
+	u.connectedAt = time.Now()
 	u.stateMu.Unlock()
 
-	slog.Info("federation upstream stream connected",
-		"addr", u.cfg.Addr, "clipboard", cb, "accepts", f.accepts)
+	if hs := u.handshake.Swap(nil); hs != nil {
+		slog.Info("federation upstream stream connected", "addr", u.cfg.Addr,
+			"handshake", hs.Duration.Round(time.Millisecond), "resumed", hs.Resumed)
+	} else {
+		slog.Info("federation upstream stream connected", "addr", u.cfg.Addr)
+	}
+
+	sid := u.outbox.Attach()
+	acks := make(chan uint64, 64)
+	recvErr := make(chan error, 1)
+	recvDone := make(chan struct{})
+	go func() {
+		defer close(recvDone)
+		recvErr <- u.recvLoop(ctx, stream, sid, acks)
+	}()
+	// Wait for the receiver before returning so recvLoops never overlap.
+	defer func() {
+		cancel()
+		<-recvDone
+	}()
+
+	sendEvent := func(ev hub.Event) error {
+		id, dropped := u.outbox.Add(sid, ev)
+		if dropped {
+			u.h.RecordDrop(hub.DropFederationOutbox, u.id)
+		}
+		sctx, span := tracing.StartFrom(ev.Trace, "federation.Forward",
+			attribute.String("suffuse.link", "upstream"),
+			attribute.String("suffuse.peer", u.cfg.Addr),
+			attribute.String("suffuse.clipboard", ev.Clipboard),
+			attribute.String("suffuse.event_id", ev.ID),
+			attribute.Int("suffuse.bytes", hub.PayloadSize(ev.Items)),
+		)
+		err := stream.Send(&pb.FederateMessage{Msg: &pb.FederateMessage_Event{Event: &pb.FederationEvent{
+			Id:           id,
+			Source:       ev.Source,
+			Clipboard:    ev.Clipboard,
+			Items:        ev.Items,
+			HopLimit:     uint32(ev.HopLimit),
+			EventId:      ev.ID,
+			Path:         ev.Path,
+			TraceContext: tracing.Inject(sctx),
+		}}})
+		tracing.End(span, err)
+		return err
+	}
+
+	if pending := u.outbox.Drain(); len(pending) > 0 {
+		slog.Info("federation redelivering unacknowledged events", "count", len(pending))
+		for _, ev := range pending {
+			if err := sendEvent(ev); err != nil {
+				return err
+			}
+		}
+	}
+	if u.deferringReason() == "" {
+		if err := u.flushDeferred(sendEvent); err != nil {
+			return err
+		}
+	}
+
+	var shapeTick <-chan time.Time
+	if u.cfg.Shaping != nil {
+		t := time.NewTicker(shaping.CheckInterval)
+		defer t.Stop()
+		shapeTick = t.C
+	}
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case rerr := <-recvErr:
+			return rerr
+		case <-u.subscribeCh:
+			err = stream.Send(u.subscription())
+		case id := <-acks:
+			err = stream.Send(&pb.FederateMessage{Msg: &pb.FederateMessage_Ack{Ack: &pb.FederationAck{Id: id}}})
+		case <-shapeTick:
+			if changed, lifted := u.updateShaping(); changed {
+				err = stream.Send(u.subscription())
+				if err == nil && lifted {
+					err = u.flushDeferred(sendEvent)
+				}
+			}
+		case ev := <-u.queue.C():
+			var ok bool
+			if ev, ok = u.holdBack(ev); !ok {
+				continue
+			}
+			hub.LogItems("federation forwarding to upstream", ev.Source, ev.Clipboard, ev.Items)
+			err = sendEvent(ev)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
 
-	var lastItems []*pb.ClipboardItem
+// recvLoop publishes events received from upstream to the local hub and hands
+// their ids to the send loop for acknowledgement. Acks from upstream apply to
+// the events sent on the outbox stream sid.
+func (u *Upstream) recvLoop(ctx context.Context, stream pb.ClipboardService_FederateClient, sid uint64, acks chan<- uint64) error {
 	for {
-		ev, err := stream.Recv()
+		msg, err := stream.Recv()
 		if err != nil {
-			if err == io.EOF {
+			if errors.Is(err, io.EOF) {
 				return fmt.Errorf("upstream closed stream")
 			}
 			return err
 		}
 
-		now := time.Now()
 		u.stateMu.Lock()
-		u.lastSeen[cb] = now
+		u.lastSeen = time.Now()
 		u.stateMu.Unlock()
 
-		if len(ev.Items) == 0 {
-			continue
-		}
-		if reflect.DeepEqual(ev.Items, lastItems) {
-			continue
+		switch m := msg.Msg.(type) {
+		case *pb.FederateMessage_Ack:
+			u.outbox.Ack(sid, m.Ack.Id)
+		case *pb.FederateMessage_Event:
+			ev := m.Event
+			republish := u.noteReceived(ev.Clipboard, ev.Items)
+			if !u.h.Writable(ev.Clipboard, ev.Source, "") {
+				slog.Warn("federation event from upstream not published: clipboard is read-only for its source",
+					"addr", u.cfg.Addr, "clipboard", ev.Clipboard, "source", ev.Source)
+				u.h.RecordRefused(ev.Items, ev.Clipboard, u.id, ev.Source, ev.EventId, hub.ErrReadOnly)
+			} else if len(ev.Items) > 0 && (republish || !blob.SameContent(ev.Items, u.applied[ev.Clipboard])) {
+				u.applied[ev.Clipboard] = ev.Items
+				items := u.h.RewriteURLs(ev.Clipboard, ev.Source, hub.RewriteOnReceive, ev.Items)
+				hub.LogItems("federation received from upstream", ev.Source, ev.Clipboard, items)
+				u.h.PublishRelayed(tracing.Extract(ctx, ev.TraceContext), items, ev.Clipboard, u.id, ev.Source,
+					hub.Relayed{EventID: ev.EventId, HopLimit: u.h.NextHopLimit(ev.HopLimit), Path: ev.Path})
+			}
+			select {
+			case acks <- ev.Id:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		}
-		lastItems = ev.Items
+	}
+}
 
-		hub.LogItems("federation received from upstream", ev.Source, ev.Clipboard, ev.Items)
-		u.h.Publish(ev.Items, ev.Clipboard, upstreamOriginID, ev.Source)
+// fetch retrieves referenced content from the upstream server.
+func (u *Upstream) fetch(ctx context.Context, sum string) ([]byte, error) {
+	if u.held(sum) {
+		return nil, blob.ErrDeferred
+	}
+	resp, err := u.client.Fetch(ctx, &pb.FetchRequest{Sha256: sum})
+	if status.Code(err) == codes.NotFound {
+		return nil, blob.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
 	}
+	slog.Debug("federation fetched blob from upstream", "sha256", sum, "size", len(resp.Data))
+	u.h.CountReceived(u.id, len(resp.Data))
+	return resp.Data, nil
 }
 
-// ── Run (forward loop) ────────────────────────────────────────────────────────
+// ── traffic shaping ──────────────────────────────────────────────────────────
 
-// Run starts the local→upstream forward loop. It blocks until ctx is cancelled.
-// Call in a goroutine alongside the hub. Watch streams are managed separately
-// via OnPeerChange; this loop only handles Copy forwarding.
-func (u *Upstream) Run(ctx context.Context) {
-	defer func() {
-		// Cancel all active streams on shutdown.
-		u.streamsMu.Lock()
-		for cb, h := range u.streams {
-			h.cancel()
-			<-h.done
-			delete(u.streams, cb)
+// deferringReason returns why non-text items are held back, or "".
+func (u *Upstream) deferringReason() string {
+	u.stateMu.RLock()
+	defer u.stateMu.RUnlock()
+	return u.deferring
+}
+
+// updateShaping re-evaluates the shaping policy. changed reports a new
+// state or reason; lifted that items are no longer held back.
+func (u *Upstream) updateShaping() (changed, lifted bool) {
+	_, reason := u.cfg.Shaping.Active(time.Now())
+	u.stateMu.Lock()
+	prev := u.deferring
+	u.deferring = reason
+	u.stateMu.Unlock()
+	if reason == prev {
+		return false, false
+	}
+	if reason != "" {
+		slog.Info("federation deferring non-text items", "reason", reason)
+		return true, false
+	}
+	slog.Info("federation no longer deferring non-text items")
+	u.shapeMu.Lock()
+	clear(u.heldSums)
+	u.shapeMu.Unlock()
+	return true, true
+}
+
+// holdBack strips the non-text items from a local event while deferring,
+// remembering the event for flushDeferred. ok is false when nothing is left
+// to forward now.
+func (u *Upstream) holdBack(ev hub.Event) (_ hub.Event, ok bool) {
+	if u.deferringReason() == "" || !shaping.HasDeferrable(ev.Items) {
+		delete(u.deferredOut, ev.Clipboard)
+		return ev, true
+	}
+	u.deferredOut[ev.Clipboard] = ev
+	text := shaping.Text(ev.Items)
+	slog.Info("federation deferring items", "clipboard", ev.Clipboard,
+		"deferred", len(ev.Items)-len(text), "forwarded", len(text))
+	if len(text) == 0 {
+		return ev, false
+	}
+	ev.Items = text
+	return ev, true
+}
+
+// flushDeferred forwards the held-back events that are still the latest
+// content of their clipboard.
+func (u *Upstream) flushDeferred(send func(hub.Event) error) error {
+	for cb, ev := range u.deferredOut {
+		delete(u.deferredOut, cb)
+		latest, src := u.h.Latest(cb, nil)
+		if src != ev.Source || !blob.SameContent(latest, ev.Items) || !u.forwards(cb) {
+			continue
 		}
-		u.streamsMu.Unlock()
-		u.conn.Close()
-		u.h.Unregister(u)
-	}()
+		hub.LogItems("federation forwarding deferred items to upstream", ev.Source, cb, ev.Items)
+		if err := send(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case ev := <-u.sendCh:
-			hub.LogItems("federation forwarding to upstream", ev.Source, ev.Clipboard, ev.Items)
-			_, err := u.client.Copy(ctx, &pb.CopyRequest{
-				Source:    ev.Source,
-				Clipboard: ev.Clipboard,
-				Items:     ev.Items,
-			})
-			if err != nil && !errors.Is(ctx.Err(), context.Canceled) {
-				slog.Warn("federation upstream copy failed", "err", err)
-			}
+// noteReceived records the non-text references in an event from upstream
+// while deferring. Afterwards it reports whether the event resends a
+// clipboard that carried such references, so it must be published again for
+// local peers to fetch them even if it is unchanged.
+func (u *Upstream) noteReceived(cb string, items []*pb.ClipboardItem) (republish bool) {
+	deferring := u.deferringReason() != ""
+	u.shapeMu.Lock()
+	defer u.shapeMu.Unlock()
+	if !deferring {
+		_, republish = u.heldClipboards[cb]
+		delete(u.heldClipboards, cb)
+		return republish
+	}
+	for _, it := range items {
+		if it.Ref != nil && shaping.Deferrable(it.Mime) {
+			u.heldSums[it.Ref.Sha256] = struct{}{}
+			u.heldClipboards[cb] = struct{}{}
 		}
 	}
+	return false
+}
+
+// held reports whether fetching sum is deferred.
+func (u *Upstream) held(sum string) bool {
+	u.shapeMu.Lock()
+	defer u.shapeMu.Unlock()
+	_, ok := u.heldSums[sum]
+	return ok
 }
 
 // ── UpstreamInfo ──────────────────────────────────────────────────────────────
@@ -360,49 +729,66 @@ func (u *Upstream) UpstreamInfo() *pb.UpstreamInfo {
 	u.stateMu.RLock()
 	defer u.stateMu.RUnlock()
 
-	// Report the oldest connectedAt across all active streams, and the
-	// most recent lastSeen.
-	var connectedAt time.Time
-	var lastSeen time.Time
-	for _, t := range u.connectedAt {
-		if connectedAt.IsZero() || t.Before(connectedAt) {
-			connectedAt = t
-		}
-	}
-	for _, t := range u.lastSeen {
-		if t.After(lastSeen) {
-			lastSeen = t
-		}
-	}
-
 	info := &pb.UpstreamInfo{
-		Addr:   u.cfg.Addr,
-		Source: u.cfg.Source,
+		Addr:      u.cfg.Addr,
+		Source:    u.cfg.Source,
+		Deferring: u.deferring,
 	}
-	if !connectedAt.IsZero() {
-		info.ConnectedAt = timestamppb.New(connectedAt)
+	if !u.connectedAt.IsZero() {
+		info.ConnectedAt = timestamppb.New(u.connectedAt)
 	}
-	if !lastSeen.IsZero() {
-		info.LastSeen = timestamppb.New(lastSeen)
+	if !u.lastSeen.IsZero() {
+		info.LastSeen = timestamppb.New(u.lastSeen)
 	}
 	return info
 }
 
+// Probe asks the upstream server for the federation tree above this one;
+// path is sent as FederationStatusRequest.path.
+func (u *Upstream) Probe(ctx context.Context, path []string) ([]*pb.FederationNode, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	resp, err := u.client.FederationStatus(ctx, &pb.FederationStatusRequest{Path: path})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Nodes, nil
+}
+
+// Connected reports whether the Federate stream to upstream is established.
+func (u *Upstream) Connected() bool {
+	u.stateMu.RLock()
+	defer u.stateMu.RUnlock()
+	return !u.connectedAt.IsZero()
+}
+
 // ── dial helpers ──────────────────────────────────────────────────────────────
 
-func dialOpts(token, source string) ([]grpc.DialOption, error) {
+// dialOpts returns the options of an upstream connection; observe is passed
+// its TLS handshakes.
+func dialOpts(token, source, ca string, observe func(tlsconf.Handshake)) ([]grpc.DialOption, error) {
 	passphrase := token
 	if passphrase == "" {
 		passphrase = tlsconf.DefaultPassphrase
 	}
 	clientCreds, err := tlsconf.ClientCredentials(passphrase)
+	if ca != "" {
+		clientCreds, err = tlsconf.CACredentials(ca)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("federation TLS credentials: %w", err)
 	}
 	opts := []grpc.DialOption{
-		grpc.WithTransportCredentials(clientCreds),
+		grpc.WithTransportCredentials(tlsconf.Observe(clientCreds, observe)),
+		// Retry a dropped connection quickly at first: with session
+		// resumption a reconnect costs one round trip, so a flapping link
+		// recovers in tens of milliseconds.
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           reconnectBackoff,
+			MinConnectTimeout: 5 * time.Second,
+		}),
 		// Keepalive: send HTTP/2 PINGs on idle connections so NAT gateways
-		// don't silently drop Watch streams between federated servers.
+		// don't silently drop the Federate stream between servers.
 		// PermitWithoutStream keeps the connection alive between stream
 		// teardown and reconnect.
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
@@ -410,7 +796,11 @@ func dialOpts(token, source string) ([]grpc.DialOption, error) {
 			Timeout:             10 * time.Second,
 			PermitWithoutStream: true,
 		}),
+		tracing.DialOption(),
 	}
+	// Compress the link if the upstream accepts it; an older upstream
+	// refuses the first stream, and the reconnect goes uncompressed.
+	opts = append(opts, compress.DialOptions()...)
 	if token != "" || source != "" {
 		opts = append(opts, grpc.WithPerRPCCredentials(&federationCreds{
 			token:  token,
@@ -0,0 +1,81 @@
+package federation
+
+import (
+	"slices"
+	"sync"
+
+	"go.klb.dev/suffuse/internal/hub"
+)
+
+// DefaultOutboxSize bounds the number of unacknowledged events retained per
+// federation link for redelivery after a reconnect.
+const DefaultOutboxSize = 256
+
+type outboxEntry struct {
+	stream uint64
+	id     uint64
+	ev     hub.Event
+}
+
+// Outbox is a bounded, ordered buffer of events sent on Federate streams but
+// not yet acknowledged by the receiver. Ids increase across every stream
+// sending from the Outbox, and acks are cumulative per stream: the receiver
+// processes a stream's events in order, so acknowledging id implies every
+// earlier event of that stream, and never touches those of an overlapping
+// one. When the buffer is full the oldest event is discarded.
+type Outbox struct {
+	mu      sync.Mutex
+	size    int
+	stream  uint64 // last stream attached
+	next    uint64 // last id issued
+	entries []outboxEntry
+}
+
+// NewOutbox returns an Outbox holding at most size events.
+func NewOutbox(size int) *Outbox {
+	return &Outbox{size: size}
+}
+
+// Attach returns the number identifying a new stream sending from o.
+func (o *Outbox) Attach() uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.stream++
+	return o.stream
+}
+
+// Add records ev as sent on stream and returns the id to send it under. It
+// reports whether an older event had to be discarded to make room.
+func (o *Outbox) Add(stream uint64, ev hub.Event) (id uint64, dropped bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.entries) >= o.size {
+		o.entries = o.entries[1:]
+		dropped = true
+	}
+	o.next++
+	o.entries = append(o.entries, outboxEntry{stream: stream, id: o.next, ev: ev})
+	return o.next, dropped
+}
+
+// Ack removes every event sent on stream with an id up to and including id.
+func (o *Outbox) Ack(stream, id uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.entries = slices.DeleteFunc(o.entries, func(e outboxEntry) bool {
+		return e.stream == stream && e.id <= id
+	})
+}
+
+// Drain removes and returns all unacknowledged events, oldest first. Callers
+// re-Add them under their own stream when redelivering on a fresh one.
+func (o *Outbox) Drain() []hub.Event {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := make([]hub.Event, len(o.entries))
+	for i, e := range o.entries {
+		out[i] = e.ev
+	}
+	o.entries = nil
+	return out
+}
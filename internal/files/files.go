@@ -0,0 +1,327 @@
+// Package files moves copied files between machines. A copied file reaches
+// the hub as a text/uri-list naming it, which is only useful where the same
+// path exists. With a Transfer, the server whose clipboard holds the files
+// attaches their content as a tar archive (ArchiveMime), and the server
+// pasting them unpacks the archive into a directory of its own and offers
+// those copies instead, so a file copied on one machine can be pasted on
+// another.
+package files
+
+import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/clip"
+)
+
+// ArchiveMime is the type of the item carrying the content of the files
+// named by an update's text/uri-list: a tar archive with one top-level entry
+// per file or directory, in list order.
+const ArchiveMime = "application/x-suffuse-files"
+
+// DefaultMaxBytes bounds the files attached to one copy when Config.MaxBytes
+// is unset.
+const DefaultMaxBytes = 32 << 20
+
+// keep is the number of pasted file sets kept in Config.Dir; older ones are
+// removed as new ones arrive.
+const keep = 16
+
+// pastePrefix names the directories pasted files are unpacked into, so
+// pruning never touches anything else in Config.Dir.
+const pastePrefix = "paste-"
+
+// Config describes a Transfer.
+type Config struct {
+	// Dir receives pasted files. It is created with mode 0700.
+	Dir string
+	// MaxBytes bounds the total size of the files attached to one copy;
+	// larger copies carry their file references only. Zero means
+	// DefaultMaxBytes.
+	MaxBytes int64
+}
+
+// DefaultDir returns the directory for pasted files in the user's cache
+// directory.
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "suffuse", "files")
+}
+
+// Transfer attaches and unpacks file content. The zero of *Transfer (nil)
+// moves file references only.
+type Transfer struct {
+	cfg Config
+	mu  sync.Mutex // serializes unpacking and pruning
+}
+
+// New returns a Transfer, creating cfg.Dir.
+func New(cfg Config) (*Transfer, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("files: empty directory")
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("files: %w", err)
+	}
+	return &Transfer{cfg: cfg}, nil
+}
+
+// Attach returns items with an archive of the files named by their
+// text/uri-list appended. Items without local files, or whose files cannot
+// be read or exceed MaxBytes, are returned unchanged.
+func (t *Transfer) Attach(items []*pb.ClipboardItem) []*pb.ClipboardItem {
+	if t == nil || slices.ContainsFunc(items, isArchive) {
+		return items
+	}
+	i := slices.IndexFunc(items, func(it *pb.ClipboardItem) bool { return it.Mime == clip.URIListMime })
+	if i < 0 {
+		return items
+	}
+	paths := clip.ParseURIList(items[i].Data)
+	if len(paths) == 0 {
+		return items
+	}
+	data, err := pack(paths, t.cfg.MaxBytes)
+	if err != nil {
+		slog.Warn("copied files not attached, sending references only", "files", len(paths), "err", err)
+		return items
+	}
+	slog.Debug("copied files attached", "files", len(paths), "bytes", len(data))
+	return append(slices.Clone(items), &pb.ClipboardItem{Mime: ArchiveMime, Data: data})
+}
+
+// Extract returns items ready for the local clipboard: an attached archive
+// is unpacked into Dir and the text/uri-list replaced by one naming the
+// unpacked files. The archive item itself is always removed. If unpacking
+// fails the original references are kept.
+func (t *Transfer) Extract(items []*pb.ClipboardItem) []*pb.ClipboardItem {
+	i := slices.IndexFunc(items, isArchive)
+	if i < 0 {
+		return items
+	}
+	archive := items[i].Data
+	out := slices.DeleteFunc(slices.Clone(items), isArchive)
+	if t == nil {
+		return out
+	}
+	paths, err := t.unpack(archive)
+	if err != nil {
+		slog.Warn("pasted files not unpacked", "dir", t.cfg.Dir, "err", err)
+		return out
+	}
+	list := &pb.ClipboardItem{Mime: clip.URIListMime, Data: clip.FormatURIList(paths)}
+	if j := slices.IndexFunc(out, func(it *pb.ClipboardItem) bool { return it.Mime == clip.URIListMime }); j >= 0 {
+		out[j] = list
+	} else {
+		out = append(out, list)
+	}
+	slog.Info("pasted files unpacked", "files", len(paths), "dir", filepath.Dir(paths[0]))
+	return out
+}
+
+func isArchive(it *pb.ClipboardItem) bool { return it.Mime == ArchiveMime }
+
+// Executable reports why the files attached to items look like programs or
+// scripts, or "" if they do not (or none are attached): a file with an
+// executable's name, execute permission or magic number.
+func Executable(items []*pb.ClipboardItem) string {
+	i := slices.IndexFunc(items, isArchive)
+	if i < 0 {
+		return ""
+	}
+	tr := tar.NewReader(bytes.NewReader(items[i].Data))
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			return ""
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Base(hdr.Name)
+		if clip.ExecutableName(name) || hdr.Mode&0o111 != 0 {
+			return "file " + name
+		}
+		head := make([]byte, 4)
+		n, _ := io.ReadFull(tr, head)
+		if clip.Executable([]*pb.ClipboardItem{{Mime: "application/octet-stream", Data: head[:n]}}) != "" {
+			return "file " + name
+		}
+	}
+}
+
+// pack archives paths, each under its base name.
+func pack(paths []string, maxBytes int64) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	seen := make(map[string]bool)
+	var total int64
+	for _, root := range paths {
+		base := filepath.Base(root)
+		if seen[base] {
+			return nil, fmt.Errorf("two files named %q", base)
+		}
+		seen[base] = true
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() && !info.IsDir() {
+				slog.Debug("copied file skipped, not a regular file", "path", path)
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			hdr, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			hdr.Name = filepath.ToSlash(filepath.Join(base, rel))
+			hdr.Uname, hdr.Gname = "", ""
+			if info.IsDir() {
+				hdr.Name += "/"
+				return tw.WriteHeader(hdr)
+			}
+			if total += info.Size(); total > maxBytes {
+				return fmt.Errorf("files are over the %d byte limit", maxBytes)
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.CopyN(tw, f, info.Size())
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unpack extracts archive into a new directory in Dir, prunes old ones and
+// returns the paths of the archive's top-level entries in order.
+func (t *Transfer) unpack(archive []byte) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	dir, err := os.MkdirTemp(t.cfg.Dir, pastePrefix)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := extract(archive, dir)
+	if err == nil && len(paths) == 0 {
+		err = errors.New("empty archive")
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	t.prune()
+	return paths, nil
+}
+
+func extract(archive []byte, dir string) ([]string, error) {
+	var paths []string
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return paths, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.FromSlash(strings.TrimSuffix(hdr.Name, "/"))
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("archive entry %q escapes the directory", hdr.Name)
+		}
+		path := filepath.Join(dir, name)
+		if top := filepath.Join(dir, strings.SplitN(filepath.ToSlash(name), "/", 2)[0]); !slices.Contains(paths, top) {
+			paths = append(paths, top)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return nil, err
+			}
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, hdr.FileInfo().Mode().Perm()|0o600)
+			if err != nil {
+				return nil, err
+			}
+			_, err = io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// prune removes all but the newest keep pasted file sets. Must be called
+// with t.mu held.
+func (t *Transfer) prune() {
+	entries, err := os.ReadDir(t.cfg.Dir)
+	if err != nil {
+		return
+	}
+	type set struct {
+		name string
+		mod  int64
+	}
+	var sets []set
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), pastePrefix) {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			sets = append(sets, set{e.Name(), info.ModTime().UnixNano()})
+		}
+	}
+	if len(sets) <= keep {
+		return
+	}
+	slices.SortFunc(sets, func(a, b set) int { return cmp.Compare(b.mod, a.mod) })
+	for _, s := range sets[keep:] {
+		if err := os.RemoveAll(filepath.Join(t.cfg.Dir, s.name)); err != nil {
+			slog.Warn("old pasted files not removed", "dir", s.name, "err", err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package grpcservice
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/localpeer"
+)
+
+// Accept implements ClipboardService.Accept.
+func (s *Service) Accept(ctx context.Context, req *pb.AcceptRequest) (*pb.AcceptResponse, error) {
+	if !fromIPC(ctx) {
+		return nil, status.Error(codes.PermissionDenied, "accept is only served on the local IPC socket")
+	}
+	if s.local == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the server has no local clipboard (started with --no-local)")
+	}
+	take := s.local.Accept
+	if req.Discard {
+		take = s.local.Discard
+	}
+	u, err := take()
+	switch {
+	case errors.Is(err, localpeer.ErrNotHolding):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, localpeer.ErrNothingHeld):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		return nil, status.Errorf(codes.Unavailable, "write the local clipboard: %v", err)
+	}
+	resp := &pb.AcceptResponse{Source: u.Source, ReceivedAt: timestamppb.New(u.At)}
+	for _, it := range u.Items {
+		resp.AvailableTypes = append(resp.AvailableTypes, it.Mime)
+	}
+	return resp, nil
+}
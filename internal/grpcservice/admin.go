@@ -0,0 +1,172 @@
+package grpcservice
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/journal"
+)
+
+// AdminService implements pb.AdminServiceServer. It shares the hub and token
+// of the ClipboardService it is created from.
+type AdminService struct {
+	pb.UnimplementedAdminServiceServer
+	svc *Service
+}
+
+// Admin returns the AdminService backed by the same hub and auth as s.
+func (s *Service) Admin() *AdminService {
+	return &AdminService{svc: s}
+}
+
+// Clear implements AdminService.Clear.
+func (a *AdminService) Clear(ctx context.Context, req *pb.ClearRequest) (*pb.ClearResponse, error) {
+	if err := a.svc.auth(ctx, accessAdmin, ""); err != nil {
+		return nil, err
+	}
+	if !req.All && len(req.Clipboards) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no clipboards given and all not set")
+	}
+	var names []string
+	if !req.All {
+		names = req.Clipboards
+	}
+	cleared := a.svc.h.Clear(names)
+	slog.Warn("clipboards cleared by admin request",
+		"source", sourceFromCtx(ctx, ""),
+		"all", req.All,
+		"cleared", cleared,
+	)
+	return &pb.ClearResponse{Cleared: cleared}, nil
+}
+
+// RotateToken implements AdminService.RotateToken.
+func (a *AdminService) RotateToken(ctx context.Context, req *pb.RotateTokenRequest) (*pb.RotateTokenResponse, error) {
+	if err := a.svc.auth(ctx, accessAdmin, ""); err != nil {
+		return nil, err
+	}
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token must not be empty")
+	}
+	grace := req.Grace.AsDuration()
+	if grace < 0 {
+		return nil, status.Error(codes.InvalidArgument, "grace must not be negative")
+	}
+	expires := a.svc.tokens.Rotate(req.Token, grace)
+	slog.Warn("token rotated by admin request",
+		"source", sourceFromCtx(ctx, ""),
+		"previous_expires", expires,
+	)
+	return &pb.RotateTokenResponse{PreviousExpiresAt: timestamppb.New(expires)}, nil
+}
+
+// PruneBlobs implements AdminService.PruneBlobs.
+func (a *AdminService) PruneBlobs(ctx context.Context, req *pb.PruneBlobsRequest) (*pb.PruneBlobsResponse, error) {
+	if err := a.svc.auth(ctx, accessAdmin, ""); err != nil {
+		return nil, err
+	}
+	unusedFor := req.UnusedFor.AsDuration()
+	if unusedFor < 0 {
+		return nil, status.Error(codes.InvalidArgument, "unused_for must not be negative")
+	}
+	res := a.svc.h.PruneBlobs(unusedFor, req.DryRun)
+	if !req.DryRun {
+		slog.Info("blobs pruned by admin request",
+			"source", sourceFromCtx(ctx, ""),
+			"count", res.Blobs,
+			"bytes", res.Bytes,
+		)
+	}
+	left := a.svc.h.Stats().Blobs
+	return &pb.PruneBlobsResponse{
+		Pruned:         uint32(res.Blobs),
+		PrunedBytes:    uint64(res.Bytes),
+		Remaining:      uint32(left.Blobs),
+		RemainingBytes: uint64(left.Bytes),
+	}, nil
+}
+
+// defaultJournalLimit is the number of entries Journal returns when the
+// request sets no limit.
+const defaultJournalLimit = 100
+
+// Journal implements AdminService.Journal.
+func (a *AdminService) Journal(ctx context.Context, req *pb.JournalRequest) (*pb.JournalResponse, error) {
+	if err := a.svc.auth(ctx, accessAdmin, ""); err != nil {
+		return nil, err
+	}
+	j := a.svc.h.Journal()
+	if j == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the server keeps no journal (start it with --journal)")
+	}
+	f := journal.Filter{
+		Source: req.Source,
+		Event:  req.EventId,
+		Limit:  int(req.Limit),
+	}
+	if req.Clipboard != "" {
+		f.Clipboard = canonicalize(req.Clipboard)
+	}
+	if req.Since != nil {
+		f.Since = req.Since.AsTime()
+	}
+	if f.Limit == 0 {
+		f.Limit = defaultJournalLimit
+	}
+	entries, err := j.Query(f)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &pb.JournalResponse{Entries: make([]*pb.JournalEntry, 0, len(entries))}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, &pb.JournalEntry{
+			Time:       timestamppb.New(e.Time),
+			Kind:       e.Kind,
+			EventId:    e.Event,
+			Clipboard:  e.Clipboard,
+			Source:     e.Source,
+			Peer:       e.Peer,
+			PeerSource: e.PeerSource,
+			MimeTypes:  e.Types,
+			Size:       uint64(e.Size),
+			Outcome:    e.Outcome,
+			Detail:     e.Detail,
+		})
+	}
+	return resp, nil
+}
+
+// Reload implements AdminService.Reload.
+func (a *AdminService) Reload(ctx context.Context, _ *pb.ReloadRequest) (*pb.ReloadResponse, error) {
+	if err := a.svc.auth(ctx, accessAdmin, ""); err != nil {
+		return nil, err
+	}
+	if a.svc.reload == nil {
+		return nil, status.Error(codes.Unimplemented, "this server cannot reload its configuration")
+	}
+	slog.Info("configuration reload requested by admin", "source", sourceFromCtx(ctx, ""))
+	changed, err := a.svc.reload()
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "configuration not reloaded: %v", err)
+	}
+	return &pb.ReloadResponse{Changed: changed}, nil
+}
+
+// Shutdown implements AdminService.Shutdown.
+func (a *AdminService) Shutdown(ctx context.Context, req *pb.ShutdownRequest) (*pb.ShutdownResponse, error) {
+	if !fromIPC(ctx) {
+		return nil, status.Error(codes.PermissionDenied, "shutdown is only served on the local IPC socket")
+	}
+	if a.svc.shutdown == nil {
+		return nil, status.Error(codes.Unimplemented, "this server cannot be shut down remotely")
+	}
+	slog.Info("shutdown requested", "restart", req.Restart)
+	a.svc.shutdown(req.Restart)
+	return &pb.ShutdownResponse{Pid: int64(os.Getpid())}, nil
+}
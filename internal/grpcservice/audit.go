@@ -0,0 +1,226 @@
+package grpcservice
+
+import (
+	"context"
+	"path"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/audit"
+)
+
+// audited lists the calls recorded in the audit log: those that move
+// clipboard content, and Status, which lists the peers.
+var audited = map[string]bool{
+	pb.ClipboardService_Copy_FullMethodName:            true,
+	pb.ClipboardService_CopyBatch_FullMethodName:       true,
+	pb.ClipboardService_CopyStream_FullMethodName:      true,
+	pb.ClipboardService_Paste_FullMethodName:           true,
+	pb.ClipboardService_PasteStream_FullMethodName:     true,
+	pb.ClipboardService_History_FullMethodName:         true,
+	pb.ClipboardService_Fetch_FullMethodName:           true,
+	pb.ClipboardService_Watch_FullMethodName:           true,
+	pb.ClipboardService_Status_FullMethodName:          true,
+	pb.ClipboardService_CreateSnapshot_FullMethodName:  true,
+	pb.ClipboardService_RestoreSnapshot_FullMethodName: true,
+}
+
+// AuditUnary returns a unary interceptor recording the audited calls in log.
+// Install it first, so calls refused by other interceptors are recorded too.
+func (s *Service) AuditUnary(log *audit.Log) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if log == nil || !audited[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		if batch, ok := req.(*pb.CopyBatchRequest); ok {
+			s.auditBatch(ctx, log, start, batch, resp, err)
+			return resp, err
+		}
+		var t tally
+		switch r := req.(type) {
+		case *pb.CopyRequest:
+			t.clipboard, t.source = canonicalize(r.GetClipboard()), r.GetSource()
+			t.add(r.GetItems())
+		case *pb.PasteRequest:
+			t.clipboard = canonicalize(r.GetClipboard())
+		case *pb.HistoryRequest:
+			t.clipboard = canonicalize(r.GetClipboard())
+		case *pb.RestoreSnapshotRequest:
+			t.source = r.GetSource()
+			for _, c := range r.GetSnapshot().GetClipboards() {
+				t.add(c.GetItems())
+			}
+		}
+		switch r := resp.(type) {
+		case *pb.PasteResponse:
+			t.origin = r.GetSource()
+			t.add(r.GetItems())
+		case *pb.HistoryResponse:
+			for _, e := range r.GetEntries() {
+				t.add(e.GetItems())
+			}
+		case *pb.FetchResponse:
+			t.bytes += int64(len(r.GetData()))
+		case *pb.Snapshot:
+			for _, c := range r.GetClipboards() {
+				t.add(c.GetItems())
+			}
+		}
+		log.Record(s.auditEntry(ctx, info.FullMethod, start, &t, err))
+		return resp, err
+	}
+}
+
+// AuditStream returns a stream interceptor recording the audited calls in
+// log when they end. Install it first, like AuditUnary.
+func (s *Service) AuditStream(log *audit.Log) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if log == nil || !audited[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		start := time.Now()
+		as := &auditStream{ServerStream: ss}
+		err := handler(srv, as)
+		log.Record(s.auditEntry(ss.Context(), info.FullMethod, start, &as.tally, err))
+		return err
+	}
+}
+
+// auditBatch records each copy of a CopyBatch call as an entry of its own.
+func (s *Service) auditBatch(ctx context.Context, log *audit.Log, start time.Time, req *pb.CopyBatchRequest, resp any, err error) {
+	r, _ := resp.(*pb.CopyBatchResponse) // nil when the call failed
+	results := r.GetResults()
+	for i, c := range req.Copies {
+		var t tally
+		t.clipboard, t.source = canonicalize(c.GetClipboard()), c.GetSource()
+		t.add(c.GetItems())
+		cerr := err
+		if i < len(results) && results[i].Code != int32(codes.OK) {
+			cerr = status.Error(codes.Code(results[i].Code), results[i].Error)
+		}
+		log.Record(s.auditEntry(ctx, pb.ClipboardService_CopyBatch_FullMethodName, start, &t, cerr))
+	}
+}
+
+// auditEntry returns the record of a call that ended with err.
+func (s *Service) auditEntry(ctx context.Context, method string, start time.Time, t *tally, err error) audit.Entry {
+	e := audit.Entry{
+		Method:     path.Base(method),
+		Transport:  "tcp",
+		Addr:       addrFromCtx(ctx),
+		Source:     t.source,
+		Clipboard:  t.clipboard,
+		Origin:     t.origin,
+		Types:      t.types,
+		Items:      t.items,
+		Events:     t.events,
+		Bytes:      t.bytes,
+		DurationMS: time.Since(start).Milliseconds(),
+		Outcome:    audit.OutcomeOK,
+	}
+	if fromIPC(ctx) {
+		e.Transport = "ipc"
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vals := md.Get("x-forwarded-for"); len(vals) > 0 {
+			e.ForwardedFor = vals[0]
+		}
+	}
+	if claimsSource(ctx, t.source) {
+		e.Source = sourceFromCtx(ctx, t.source)
+	}
+	if g, gerr := s.grant(ctx); gerr == nil {
+		e.Token = g.Name
+	}
+	if err != nil {
+		st := status.Convert(err)
+		e.Outcome = audit.OutcomeFailed
+		if c := st.Code(); c == codes.Unauthenticated || c == codes.PermissionDenied {
+			e.Outcome = audit.OutcomeDenied
+		}
+		e.Code, e.Detail = st.Code().String(), st.Message()
+	}
+	return e
+}
+
+// tally collects what a call moved.
+type tally struct {
+	clipboard, source, origin string
+	types                     []string
+	items, events             int
+	bytes                     int64
+}
+
+func (t *tally) add(items []*pb.ClipboardItem) {
+	for _, it := range items {
+		t.addType(it.Mime)
+		t.items++
+		t.bytes += int64(len(it.Data))
+	}
+}
+
+func (t *tally) addChunk(c *pb.ItemChunk) {
+	if c.GetMime() != "" {
+		t.addType(c.Mime)
+		t.items++
+	}
+	t.bytes += int64(len(c.GetData()))
+}
+
+func (t *tally) addType(mime string) {
+	if !slices.Contains(t.types, mime) {
+		t.types = append(t.types, mime)
+	}
+}
+
+// auditStream tallies the messages of a stream.
+type auditStream struct {
+	grpc.ServerStream
+	tally
+	started bool // a CopyChunk was received
+}
+
+func (a *auditStream) RecvMsg(m any) error {
+	if err := a.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	switch r := m.(type) {
+	case *pb.CopyChunk:
+		if !a.started {
+			a.started = true
+			a.clipboard, a.source = canonicalize(r.Clipboard), r.Source
+		}
+		a.addChunk(r.Chunk)
+	case *pb.PasteRequest:
+		a.clipboard = canonicalize(r.Clipboard)
+	case *pb.WatchRequest:
+		a.clipboard = canonicalize(r.Clipboard)
+	}
+	return nil
+}
+
+func (a *auditStream) SendMsg(m any) error {
+	if err := a.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+	switch r := m.(type) {
+	case *pb.PasteChunk:
+		if r.Source != "" {
+			a.origin = r.Source
+		}
+		a.addChunk(r.Chunk)
+	case *pb.WatchResponse:
+		a.events++
+		a.add(r.Items)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package grpcservice
+
+import (
+	"context"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DenyMethods returns a unary interceptor that rejects the given full method
+// names (e.g. pb.ClipboardService_Status_FullMethodName) with
+// PermissionDenied. Install it on listeners that should not expose them.
+func DenyMethods(methods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if slices.Contains(methods, info.FullMethod) {
+			return nil, status.Errorf(codes.PermissionDenied, "%s is disabled on this listener", info.FullMethod)
+		}
+		return handler(ctx, req)
+	}
+}
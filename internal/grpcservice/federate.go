@@ -0,0 +1,413 @@
+package grpcservice
+
+import (
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
+	"go.klb.dev/suffuse/internal/federation"
+	"go.klb.dev/suffuse/internal/hub"
+	"go.klb.dev/suffuse/internal/shaping"
+	"go.klb.dev/suffuse/internal/tracing"
+)
+
+// Federate implements ClipboardService.Federate — the upstream side of a
+// federation link. The downstream server subscribes to clipboards and sends
+// its local events; we publish those to the hub (acking each one) and stream
+// matching hub events back. Events not acknowledged before the stream drops
+// are redelivered when the same downstream source reconnects within
+// outboxRetention.
+func (s *Service) Federate(stream pb.ClipboardService_FederateServer) error {
+	ctx := stream.Context()
+	if err := s.auth(ctx, accessAdmin, ""); err != nil {
+		return err
+	}
+	link, _ := s.grant(ctx) // validated by auth
+	source, err := s.boundSource(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	addr := addrFromCtx(ctx)
+	fp := &federationPeer{
+		id:          addr + "/federate",
+		source:      source,
+		addr:        addr,
+		outbox:      s.attachOutbox(source),
+		done:        make(chan struct{}),
+		queue:       s.h.NewQueue(hub.DropFederationDownstream, 64),
+		connectedAt: time.Now(),
+	}
+
+	defer s.detachOutbox(source)
+	sid := fp.outbox.Attach()
+
+	s.h.Register(fp)
+	defer s.h.Unregister(fp)
+
+	slog.Info("federation downstream connected", "peer", fp.id, "source", fp.source)
+
+	// Recv runs in its own goroutine; acks for received events are handed to
+	// the send loop below because a stream must only be sent on from one
+	// goroutine.
+	acks := make(chan uint64, 64)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			fp.lastSeen.Store(time.Now().UnixNano())
+			switch m := msg.Msg.(type) {
+			case *pb.FederateMessage_Subscribe:
+				fp.acceptRefs.Store(m.Subscribe.AcceptRefs)
+				wasDeferring := fp.deferNonText.Swap(m.Subscribe.DeferNonText)
+				added := fp.setSubscriptions(m.Subscribe.Clipboards)
+				slog.Info("federation downstream subscribed",
+					"peer", fp.id, "clipboards", fp.clipboardNames(),
+					"defer_non_text", m.Subscribe.DeferNonText)
+				if wasDeferring && !m.Subscribe.DeferNonText {
+					// Resend what was held back so the items follow.
+					added = append(added, s.stillDeferred(fp)...)
+				}
+				s.h.Resubscribe(fp, added)
+			case *pb.FederateMessage_Event:
+				ev := m.Event
+				cb := canonicalize(ev.Clipboard)
+				if err := s.checkRefs(ev.Items); err != nil {
+					slog.Warn("federation event from downstream not published", "peer", fp.id, "err", err)
+					s.h.RecordRefused(ev.Items, cb, fp.id, ev.Source, ev.EventId, err)
+				} else if !s.h.Writable(cb, ev.Source, link.Name) {
+					slog.Warn("federation event from downstream not published: clipboard is read-only for its source",
+						"peer", fp.id, "clipboard", cb, "source", ev.Source)
+					s.h.RecordRefused(ev.Items, cb, fp.id, ev.Source, ev.EventId, hub.ErrReadOnly)
+				} else if len(ev.Items) > 0 {
+					items := s.h.RewriteURLs(cb, ev.Source, hub.RewriteOnReceive, ev.Items)
+					hub.LogItems("federation received from downstream", ev.Source, cb, items)
+					s.h.PublishRelayed(tracing.Extract(ctx, ev.TraceContext), items, cb, fp.id, ev.Source,
+						hub.Relayed{EventID: ev.EventId, HopLimit: s.h.NextHopLimit(ev.HopLimit), Path: ev.Path})
+				}
+				select {
+				case acks <- ev.Id:
+				case <-ctx.Done():
+					return
+				}
+			case *pb.FederateMessage_Ack:
+				fp.outbox.Ack(sid, m.Ack.Id)
+			}
+		}
+	}()
+
+	sendEvent := func(ev hub.Event) error {
+		id, dropped := fp.outbox.Add(sid, ev)
+		if dropped {
+			s.h.RecordDrop(hub.DropFederationOutbox, fp.id)
+		}
+		if !fp.AcceptsRefs() {
+			// Only events redelivered from an earlier stream can carry
+			// references this downstream has not asked for.
+			items, err := s.h.Resolve(ctx, ev.Items)
+			if err != nil {
+				slog.Warn("federation event not redelivered", "peer", fp.id, "err", err)
+				return nil
+			}
+			ev.Items = items
+		}
+		full, deferred := ev.Items, false
+		if fp.deferNonText.Load() {
+			ev.Items, deferred = s.deferNonText(ev.Items)
+		}
+		fp.markDeferred(ev.Clipboard, full, deferred)
+		sctx, span := tracing.StartFrom(ev.Trace, "federation.Forward",
+			attribute.String("suffuse.link", "downstream"),
+			attribute.String("suffuse.peer", fp.id),
+			attribute.String("suffuse.clipboard", ev.Clipboard),
+			attribute.String("suffuse.event_id", ev.ID),
+			attribute.Int("suffuse.bytes", hub.PayloadSize(ev.Items)),
+			attribute.Bool("suffuse.deferred", deferred),
+		)
+		err := stream.Send(&pb.FederateMessage{
+			Msg: &pb.FederateMessage_Event{Event: &pb.FederationEvent{
+				Id:           id,
+				Source:       ev.Source,
+				Clipboard:    ev.Clipboard,
+				Items:        ev.Items,
+				HopLimit:     uint32(ev.HopLimit),
+				EventId:      ev.ID,
+				Path:         ev.Path,
+				TraceContext: tracing.Inject(sctx),
+			}},
+		})
+		tracing.End(span, err)
+		return err
+	}
+
+	if pending := fp.outbox.Drain(); len(pending) > 0 {
+		slog.Info("federation redelivering unacknowledged events",
+			"peer", fp.id, "count", len(pending))
+		for _, ev := range pending {
+			if err := sendEvent(ev); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-recvErr:
+			slog.Info("federation downstream disconnected", "peer", fp.id, "err", err)
+			return nil
+		case <-fp.done:
+			return status.Error(codes.ResourceExhausted, "federation stream disconnected: too slow to keep up")
+		case <-s.tokens.Changed():
+			if err := s.auth(ctx, accessAdmin, ""); err != nil {
+				slog.Info("federation downstream disconnected: token no longer accepted", "peer", fp.id)
+				return err
+			}
+		case id := <-acks:
+			if err := stream.Send(&pb.FederateMessage{
+				Msg: &pb.FederateMessage_Ack{Ack: &pb.FederationAck{Id: id}},
+			}); err != nil {
+				return err
+			}
+		case ev := <-fp.queue.C():
+			if err := sendEvent(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// deferNonText replaces the non-text items sent to a downstream that is
+// deferring them with blob references, so only their metadata is sent. They
+// are left out when the hub keeps no blob store. deferred reports whether
+// any item was non-text.
+func (s *Service) deferNonText(items []*pb.ClipboardItem) (_ []*pb.ClipboardItem, deferred bool) {
+	out := make([]*pb.ClipboardItem, 0, len(items))
+	for _, it := range items {
+		switch {
+		case !shaping.Deferrable(it.Mime):
+			out = append(out, it)
+		case it.Ref != nil:
+			out = append(out, it)
+			deferred = true
+		case s.h.Blobs() != nil:
+			out = append(out, &pb.ClipboardItem{Mime: it.Mime, OriginalCharset: it.OriginalCharset, Sha256: it.Sha256, Ref: s.h.Blobs().Put(it.Data)})
+			deferred = true
+		default:
+			deferred = true
+		}
+	}
+	return out, deferred
+}
+
+// stillDeferred returns the subscriptions of fp whose clipboards were last
+// sent with items deferred and still hold that content. Clipboards changed
+// since, e.g. by the downstream itself, are not resent.
+func (s *Service) stillDeferred(fp *federationPeer) []hub.ClipboardFilter {
+	deferred := fp.takeDeferred()
+	var out []hub.ClipboardFilter
+	for _, f := range fp.Subscriptions() {
+		full, ok := deferred[f.Clipboard]
+		if !ok {
+			continue
+		}
+		if latest, _ := s.h.Latest(f.Clipboard, f.Accepts); blob.SameContent(latest, full) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// outboxRetention is how long the Outbox of a downstream source is kept
+// after its last Federate stream ends.
+const outboxRetention = 15 * time.Minute
+
+// retainedOutbox is the Outbox of a downstream source with the number of
+// Federate streams from it, and when the last one ended.
+type retainedOutbox struct {
+	outbox  *federation.Outbox
+	streams int
+	gone    time.Time
+}
+
+// attachOutbox returns the Outbox retained for a downstream source, creating
+// it on first use. Each call must be paired with detachOutbox.
+func (s *Service) attachOutbox(source string) *federation.Outbox {
+	s.outboxMu.Lock()
+	defer s.outboxMu.Unlock()
+	r, ok := s.outboxes[source]
+	if !ok {
+		r = &retainedOutbox{outbox: federation.NewOutbox(federation.DefaultOutboxSize)}
+		s.outboxes[source] = r
+	}
+	r.streams++
+	return r.outbox
+}
+
+// detachOutbox ends a stream's use of the Outbox of source. Once no stream
+// from source has been connected for outboxRetention its unacknowledged
+// events are discarded.
+func (s *Service) detachOutbox(source string) {
+	s.outboxMu.Lock()
+	defer s.outboxMu.Unlock()
+	r := s.outboxes[source]
+	if r.streams--; r.streams > 0 {
+		return
+	}
+	gone := time.Now()
+	r.gone = gone
+	time.AfterFunc(outboxRetention, func() {
+		s.outboxMu.Lock()
+		defer s.outboxMu.Unlock()
+		if r.streams == 0 && r.gone.Equal(gone) && s.outboxes[source] == r {
+			delete(s.outboxes, source)
+			slog.Debug("federation outbox discarded", "source", source)
+		}
+	})
+}
+
+// ── federationPeer ─────────────────────────────────────────────────────────
+
+// federationPeer is the hub.SubscriberPeer representing a downstream server
+// connected via Federate.
+type federationPeer struct {
+	id           string
+	source       string
+	addr         string
+	queue        *hub.Queue
+	outbox       *federation.Outbox // shared by all streams from this source
+	done         chan struct{}      // closed by Disconnect
+	closeOnce    sync.Once
+	connectedAt  time.Time
+	lastSeen     atomic.Int64
+	acceptRefs   atomic.Bool // set from the downstream's subscription
+	deferNonText atomic.Bool // likewise
+
+	mu       sync.RWMutex
+	subs     []hub.ClipboardFilter
+	deferred map[string][]*pb.ClipboardItem // clipboard → items of last event sent, if some were deferred
+}
+
+func (p *federationPeer) ID() string { return p.id }
+
+func (p *federationPeer) Info() *pb.PeerInfo {
+	ls := p.lastSeen.Load()
+	var lastSeenTS *timestamppb.Timestamp
+	if ls > 0 {
+		lastSeenTS = timestamppb.New(time.Unix(0, ls))
+	}
+	return &pb.PeerInfo{
+		Source:      p.source,
+		Addr:        p.addr,
+		Role:        "downstream",
+		Clipboard:   strings.Join(p.clipboardNames(), ","),
+		ConnectedAt: timestamppb.New(p.connectedAt),
+		LastSeen:    lastSeenTS,
+	}
+}
+
+func (p *federationPeer) Send(ev hub.Event) error {
+	return p.queue.Put(ev)
+}
+
+// Queue implements hub.QueuePeer.
+func (p *federationPeer) Queue() *hub.Queue { return p.queue }
+
+// Relays implements hub.RelayPeer: events sent downstream cross a federation
+// link.
+func (p *federationPeer) Relays() {}
+
+// AcceptsRefs implements hub.RefPeer: a downstream that announced
+// accept_refs fetches large items from us on demand.
+func (p *federationPeer) AcceptsRefs() bool { return p.acceptRefs.Load() }
+
+// Disconnect implements hub.Disconnecter by ending the Federate stream. The
+// downstream reconnects and unacknowledged events are redelivered.
+func (p *federationPeer) Disconnect(error) {
+	p.closeOnce.Do(func() { close(p.done) })
+}
+
+// Subscriptions implements hub.SubscriberPeer.
+func (p *federationPeer) Subscriptions() []hub.ClipboardFilter {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.subs
+}
+
+// setSubscriptions replaces the subscription set and returns the entries that
+// are new or whose accepted types changed.
+func (p *federationPeer) setSubscriptions(subs []*pb.ClipboardSubscription) []hub.ClipboardFilter {
+	next := make([]hub.ClipboardFilter, 0, len(subs))
+	for _, sub := range subs {
+		accepts := slices.Clone(sub.Accepts)
+		sort.Strings(accepts)
+		next = append(next, hub.ClipboardFilter{Clipboard: canonicalize(sub.Clipboard), Accepts: accepts})
+	}
+
+	p.mu.Lock()
+	prev := p.subs
+	p.subs = next
+	p.mu.Unlock()
+
+	var added []hub.ClipboardFilter
+	for _, f := range next {
+		i := slices.IndexFunc(prev, func(o hub.ClipboardFilter) bool { return o.Clipboard == f.Clipboard })
+		if i < 0 || !slices.Equal(prev[i].Accepts, f.Accepts) {
+			added = append(added, f)
+		}
+	}
+	return added
+}
+
+// markDeferred records the items of the event last sent for cb as they were
+// before deferral, and whether any of them were deferred.
+func (p *federationPeer) markDeferred(cb string, items []*pb.ClipboardItem, deferred bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !deferred {
+		delete(p.deferred, cb)
+		return
+	}
+	if p.deferred == nil {
+		p.deferred = make(map[string][]*pb.ClipboardItem)
+	}
+	p.deferred[cb] = items
+}
+
+// takeDeferred returns and forgets the clipboards whose last event had items
+// deferred, with that event's items before deferral.
+func (p *federationPeer) takeDeferred() map[string][]*pb.ClipboardItem {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := p.deferred
+	p.deferred = nil
+	return out
+}
+
+// clipboardNames returns the subscribed clipboard names, sorted.
+func (p *federationPeer) clipboardNames() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	names := make([]string, len(p.subs))
+	for i, f := range p.subs {
+		names[i] = f.Clipboard
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,85 @@
+package grpcservice
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// maxFederationDepth bounds how many servers a FederationStatus probe
+// passes through, in case sources are not unique across the chain.
+const maxFederationDepth = 16
+
+// UpstreamProber is optionally implemented by the UpstreamInfoProvider to
+// continue a FederationStatus probe on the upstream server.
+type UpstreamProber interface {
+	Probe(ctx context.Context, path []string) ([]*pb.FederationNode, error)
+}
+
+// FederationStatus implements ClipboardService.FederationStatus. The
+// upstreams are probed concurrently; each subtree follows this node in
+// configuration order.
+func (s *Service) FederationStatus(ctx context.Context, req *pb.FederationStatusRequest) (*pb.FederationStatusResponse, error) {
+	if err := s.auth(ctx, accessRead, ""); err != nil {
+		return nil, err
+	}
+	node := &pb.FederationNode{
+		Source:  s.source,
+		Version: s.version,
+		Peers:   make(map[string]uint32),
+		Stats:   s.hubStats(),
+	}
+	for _, p := range s.h.Peers() {
+		node.Peers[p.Role]++
+	}
+	resp := &pb.FederationStatusResponse{Nodes: []*pb.FederationNode{node}}
+	upstreams := s.upstreamList()
+	switch {
+	case len(upstreams) == 0:
+		return resp, nil
+	case slices.Contains(req.Path, s.source):
+		node.Error = "federation cycle: " + s.source + " was already probed"
+		return resp, nil
+	case len(req.Path) >= maxFederationDepth:
+		node.Error = "federation tree too deep to probe further"
+		return resp, nil
+	}
+
+	path := append(slices.Clone(req.Path), s.source)
+	subtrees := make([][]*pb.FederationNode, len(upstreams))
+	var wg sync.WaitGroup
+	for i, up := range upstreams {
+		wg.Go(func() { subtrees[i] = probeUpstream(ctx, up, path) })
+	}
+	wg.Wait()
+	for _, sub := range subtrees {
+		resp.Nodes = append(resp.Nodes, sub...)
+	}
+	return resp, nil
+}
+
+// probeUpstream returns the subtree above one upstream link, with hops
+// counted from this server. An upstream that cannot be probed is reported
+// as a node with only the link and an error.
+func probeUpstream(ctx context.Context, up UpstreamInfoProvider, path []string) []*pb.FederationNode {
+	link := up.UpstreamInfo()
+	prober, ok := up.(UpstreamProber)
+	if !ok {
+		return []*pb.FederationNode{{UpstreamInfo: link, Hops: 1, Error: "upstream not probed"}}
+	}
+	nodes, err := prober.Probe(ctx, path)
+	if err != nil || len(nodes) == 0 {
+		msg := "upstream returned no nodes"
+		if err != nil {
+			msg = "upstream not probed: " + err.Error()
+		}
+		return []*pb.FederationNode{{UpstreamInfo: link, Hops: 1, Error: msg}}
+	}
+	nodes[0].UpstreamInfo = link
+	for _, n := range nodes {
+		n.Hops++
+	}
+	return nodes
+}
@@ -0,0 +1,62 @@
+package grpcservice
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// unmetered lists the calls guests make without counting against their rate
+// limit: Status, which clients send to check the server is reachable before
+// every command, and health checks.
+var unmetered = map[string]bool{
+	pb.ClipboardService_Status_FullMethodName: true,
+	healthpb.Health_Check_FullMethodName:      true,
+	healthpb.Health_Watch_FullMethodName:      true,
+}
+
+// GuestUnary returns a unary interceptor counting each call made with the
+// guest token against its host's rate limit, once however many clipboards
+// the call covers.
+func (s *Service) GuestUnary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if unmetered[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		if err := s.admitGuest(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GuestStream is GuestUnary for streaming calls, which count once when they
+// open.
+func (s *Service) GuestStream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if unmetered[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		if err := s.admitGuest(ss.Context()); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// admitGuest applies the guest rate limit to a call made with the guest
+// token. Calls with other tokens, or none, are left to the handler.
+func (s *Service) admitGuest(ctx context.Context) error {
+	if g, err := s.grant(ctx); err != nil || !g.Guest {
+		return nil
+	}
+	if err := s.guests.Admit(addrFromCtx(ctx)); err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return nil
+}
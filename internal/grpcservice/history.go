@@ -0,0 +1,51 @@
+package grpcservice
+
+import (
+	"context"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/hub"
+)
+
+// History implements ClipboardService.History.
+func (s *Service) History(ctx context.Context, req *pb.HistoryRequest) (*pb.HistoryResponse, error) {
+	cb := canonicalize(req.Clipboard)
+	if err := s.auth(ctx, accessRead, cb); err != nil {
+		return nil, err
+	}
+	if !s.h.HistoryEnabled() {
+		return nil, status.Error(codes.FailedPrecondition, "the server keeps no clipboard history (start it with --history)")
+	}
+	entries := s.h.History(cb)
+	if req.Limit > 0 && len(entries) > int(req.Limit) {
+		entries = entries[:req.Limit]
+	}
+	resp := &pb.HistoryResponse{Clipboard: cb}
+	var served int
+	for _, e := range entries {
+		out := &pb.HistoryEntry{Source: e.Source, StoredAt: timestamppb.New(e.StoredAt)}
+		var items []*pb.ClipboardItem
+		for _, it := range e.Items {
+			out.AvailableTypes = append(out.AvailableTypes, it.Mime)
+			if len(req.Accepts) == 0 || slices.Contains(req.Accepts, it.Mime) {
+				items = append(items, it)
+			}
+		}
+		if !req.AcceptRefs {
+			var err error
+			if items, err = s.h.Resolve(ctx, items); err != nil {
+				return nil, status.Error(codes.Unavailable, err.Error())
+			}
+		}
+		out.Items = items
+		served += hub.PayloadSize(items)
+		resp.Entries = append(resp.Entries, out)
+	}
+	s.h.CountServed(cb, served)
+	return resp, nil
+}
@@ -0,0 +1,144 @@
+package grpcservice
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/pairing"
+	"go.klb.dev/suffuse/internal/tokens"
+)
+
+// errNoPairing is returned by the pairing RPCs when the server cannot issue
+// tokens.
+var errNoPairing = status.Error(codes.FailedPrecondition, "the server runs without a token, so it cannot issue any; start it with --token to pair devices")
+
+// StartPairing implements AdminService.StartPairing.
+func (a *AdminService) StartPairing(ctx context.Context, req *pb.StartPairingRequest) (*pb.StartPairingResponse, error) {
+	if err := a.svc.auth(ctx, accessAdmin, ""); err != nil {
+		return nil, err
+	}
+	if a.svc.pairer == nil {
+		return nil, errNoPairing
+	}
+	ttl := req.Ttl.AsDuration()
+	if ttl < 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl must not be negative")
+	}
+	code, expires, err := a.svc.pairer.Start(pairing.Offer{
+		Name:       req.Name,
+		Role:       tokens.Role(req.Role),
+		Clipboards: req.Clipboards,
+		TTL:        ttl,
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.StartPairingResponse{Code: code, ExpiresAt: timestamppb.New(expires)}, nil
+}
+
+// Pair implements ClipboardService.Pair. It takes no token: the pairing
+// code authenticates the exchange.
+func (s *Service) Pair(stream pb.ClipboardService_PairServer) error {
+	if s.pairer == nil {
+		return errNoPairing
+	}
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	device := req.Device
+	if device == "" {
+		device = addrFromCtx(stream.Context())
+	}
+	ex, shareB, confirmB, err := s.pairer.Begin(device, req.Share)
+	switch {
+	case errors.Is(err, pairing.ErrNoCode):
+		return status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := stream.Send(&pb.PairResponse{Share: shareB, Confirm: confirmB}); err != nil {
+		return err
+	}
+
+	req, err = stream.Recv()
+	if err != nil {
+		return err
+	}
+	sealed, err := ex.Finish(req.Confirm)
+	switch {
+	case errors.Is(err, pairing.ErrWrongCode):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, pairing.ErrNoCode):
+		return status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		slog.Error("pairing failed", "device", device, "err", err)
+		return status.Error(codes.Internal, "pairing failed")
+	}
+	return stream.Send(&pb.PairResponse{Credential: sealed})
+}
+
+// ListDevices implements AdminService.ListDevices.
+func (a *AdminService) ListDevices(ctx context.Context, _ *pb.ListDevicesRequest) (*pb.ListDevicesResponse, error) {
+	if err := a.svc.auth(ctx, accessAdmin, ""); err != nil {
+		return nil, err
+	}
+	if a.svc.pairer == nil {
+		return nil, errNoPairing
+	}
+	devices, err := a.svc.pairer.Devices()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &pb.ListDevicesResponse{}
+	for _, d := range devices {
+		resp.Devices = append(resp.Devices, deviceProto(d))
+	}
+	return resp, nil
+}
+
+// RevokeDevice implements AdminService.RevokeDevice.
+func (a *AdminService) RevokeDevice(ctx context.Context, req *pb.RevokeDeviceRequest) (*pb.RevokeDeviceResponse, error) {
+	if err := a.svc.auth(ctx, accessAdmin, ""); err != nil {
+		return nil, err
+	}
+	if a.svc.pairer == nil {
+		return nil, errNoPairing
+	}
+	if req.Device == "" {
+		return nil, status.Error(codes.InvalidArgument, "device must not be empty")
+	}
+	d, err := a.svc.pairer.Revoke(req.Device)
+	switch {
+	case errors.Is(err, pairing.ErrUnknownDevice):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	slog.Warn("device revoked by admin request",
+		"source", sourceFromCtx(ctx, ""),
+		"device", d.Name,
+		"fingerprint", d.Fingerprint,
+	)
+	return &pb.RevokeDeviceResponse{Device: deviceProto(d)}, nil
+}
+
+func deviceProto(d pairing.Device) *pb.Device {
+	out := &pb.Device{
+		Name:        d.Name,
+		Role:        string(d.Role),
+		Clipboards:  d.Clipboards,
+		Fingerprint: d.Fingerprint,
+		PairedAt:    timestamppb.New(d.PairedAt),
+	}
+	if !d.LastSeen.IsZero() {
+		out.LastSeen = timestamppb.New(d.LastSeen)
+	}
+	return out
+}
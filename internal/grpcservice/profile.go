@@ -0,0 +1,81 @@
+package grpcservice
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime/pprof"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// Bounds for ProfileRequest.duration.
+const (
+	defaultProfileDuration = 30 * time.Second
+	maxProfileDuration     = 5 * time.Minute
+)
+
+// defaultProfiles are captured when a ProfileRequest names none.
+var defaultProfiles = []string{"cpu", "heap", "goroutine"}
+
+// Profile implements AdminService.Profile.
+func (a *AdminService) Profile(ctx context.Context, req *pb.ProfileRequest) (*pb.ProfileResponse, error) {
+	if !fromIPC(ctx) {
+		return nil, status.Error(codes.PermissionDenied, "profiles are only served on the local IPC socket")
+	}
+	names := req.Profiles
+	if len(names) == 0 {
+		names = defaultProfiles
+	}
+	wantCPU := false
+	for _, name := range names {
+		if name == "cpu" {
+			wantCPU = true
+		} else if pprof.Lookup(name) == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown profile %q", name)
+		}
+	}
+	d := defaultProfileDuration
+	if req.Duration != nil {
+		d = req.Duration.AsDuration()
+	}
+	if wantCPU && (d <= 0 || d > maxProfileDuration) {
+		return nil, status.Errorf(codes.InvalidArgument, "duration must be positive and at most %s", maxProfileDuration)
+	}
+
+	slog.Info("capturing profiles by admin request", "profiles", names, "duration", d)
+
+	var cpu bytes.Buffer
+	if wantCPU {
+		if err := pprof.StartCPUProfile(&cpu); err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "cpu profile: %v", err)
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			pprof.StopCPUProfile()
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-t.C:
+		}
+		pprof.StopCPUProfile()
+	}
+
+	resp := &pb.ProfileResponse{}
+	for _, name := range names {
+		if name == "cpu" {
+			resp.Profiles = append(resp.Profiles, &pb.Profile{Name: name, Data: cpu.Bytes()})
+			continue
+		}
+		var buf bytes.Buffer
+		if err := pprof.Lookup(name).WriteTo(&buf, 0); err != nil {
+			return nil, status.Errorf(codes.Internal, "%s profile: %v", name, err)
+		}
+		resp.Profiles = append(resp.Profiles, &pb.Profile{Name: name, Data: buf.Bytes()})
+	}
+	return resp, nil
+}
@@ -3,10 +3,19 @@ package grpcservice
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"maps"
+	"runtime/metrics"
+	"slices"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
@@ -14,7 +23,16 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
+	"go.klb.dev/suffuse/internal/chunk"
+	"go.klb.dev/suffuse/internal/guest"
 	"go.klb.dev/suffuse/internal/hub"
+	"go.klb.dev/suffuse/internal/localpeer"
+	"go.klb.dev/suffuse/internal/pairing"
+	"go.klb.dev/suffuse/internal/quota"
+	"go.klb.dev/suffuse/internal/snapshot"
+	"go.klb.dev/suffuse/internal/tokens"
+	"go.klb.dev/suffuse/internal/tracing"
 )
 
 // UpstreamInfoProvider can optionally be implemented by the federation layer
@@ -23,80 +41,384 @@ type UpstreamInfoProvider interface {
 	UpstreamInfo() *pb.UpstreamInfo
 }
 
+// LocalClipboard is the server's own system clipboard, for Undo and Accept.
+type LocalClipboard interface {
+	Undo() (localpeer.Overwritten, error)
+	Accept() (localpeer.Pending, error)
+	Discard() (localpeer.Pending, error)
+}
+
 // Service implements pb.ClipboardServiceServer.
 type Service struct {
 	pb.UnimplementedClipboardServiceServer
-	h        *hub.Hub
-	token    string
-	upstream UpstreamInfoProvider // nil when not federated
+	h         *hub.Hub
+	tokens    *tokens.Set
+	local     LocalClipboard  // nil when the server has none
+	pairer    *pairing.Pairer // nil when the server issues no tokens
+	guests    *guest.Limiter  // nil when guest access is off
+	snapshots *snapshot.Store // nil when snapshots are not kept
+	source    string          // this server's name, for FederationStatus
+	version   string
+
+	// upstreams is empty when not federated; see SetUpstreams.
+	upstreams atomic.Pointer[[]UpstreamInfoProvider]
+
+	// reload, when set, reloads the server's configuration; see SetReload.
+	reload func() ([]string, error)
+	// shutdown, when set, stops or restarts the server; see SetShutdown.
+	shutdown func(restart bool)
+
+	// outboxes holds unacknowledged events per downstream source so they can
+	// be redelivered when that downstream reconnects via Federate.
+	outboxMu sync.Mutex
+	outboxes map[string]*retainedOutbox
+
+	watchSeq atomic.Uint64
+}
+
+// New returns a Service backed by h, accepting the tokens in ts (a set whose
+// only token is empty disables auth). upstreams is empty for standalone
+// servers, local nil for servers without a local clipboard, pairer nil for
+// servers that cannot pair devices, guests nil for servers without guest
+// access, and snapshots nil for servers that keep no snapshots. source and
+// version identify the server in FederationStatus.
+func New(h *hub.Hub, ts *tokens.Set, upstreams []UpstreamInfoProvider, local LocalClipboard, pairer *pairing.Pairer, guests *guest.Limiter, snapshots *snapshot.Store, source, version string) *Service {
+	s := &Service{
+		h:         h,
+		tokens:    ts,
+		local:     local,
+		pairer:    pairer,
+		guests:    guests,
+		snapshots: snapshots,
+		source:    source,
+		version:   version,
+		outboxes:  make(map[string]*retainedOutbox),
+	}
+	s.SetUpstreams(upstreams)
+	return s
 }
 
-// New returns a Service backed by h. token may be empty to disable auth.
-// upstream may be nil for standalone servers.
-func New(h *hub.Hub, token string, upstream UpstreamInfoProvider) *Service {
-	return &Service{h: h, token: token, upstream: upstream}
+// SetUpstreams replaces the upstreams reported by Status and probed by
+// FederationStatus, e.g. after the server's configuration is reloaded.
+func (s *Service) SetUpstreams(upstreams []UpstreamInfoProvider) {
+	s.upstreams.Store(&upstreams)
+}
+
+// SetReload sets the function AdminService.Reload calls to reload the
+// server's configuration. It returns the names of the settings that changed,
+// or an error, leaving the running configuration in force, when the new one
+// is invalid. Without one Reload is Unimplemented.
+func (s *Service) SetReload(reload func() ([]string, error)) {
+	s.reload = reload
+}
+
+// SetShutdown sets the function AdminService.Shutdown calls to stop the
+// server, or restart it when restart is set. It must return without waiting
+// for the server to stop, so the call can be answered. Without one Shutdown
+// is Unimplemented.
+func (s *Service) SetShutdown(shutdown func(restart bool)) {
+	s.shutdown = shutdown
+}
+
+// upstreamList returns the current upstreams.
+func (s *Service) upstreamList() []UpstreamInfoProvider {
+	return *s.upstreams.Load()
 }
 
 // Copy implements ClipboardService.Copy.
 func (s *Service) Copy(ctx context.Context, req *pb.CopyRequest) (*pb.CopyResponse, error) {
-	if err := s.auth(ctx); err != nil {
+	if err := s.auth(ctx, accessWrite, canonicalize(req.Clipboard)); err != nil {
 		return nil, err
 	}
-	if len(req.Items) == 0 {
-		return &pb.CopyResponse{}, nil
+	if err := s.publishCopy(ctx, req.Clipboard, req.Source, req.Items); err != nil {
+		return nil, err
 	}
-	src := sourceFromCtx(ctx, req.Source)
-	cb := canonicalize(req.Clipboard)
-	hub.LogItems("clipboard received", src, cb, req.Items)
-	s.h.Publish(req.Items, cb, addrFromCtx(ctx), src)
 	return &pb.CopyResponse{}, nil
 }
 
+// CopyBatch implements ClipboardService.CopyBatch. A caller whose token is
+// not accepted at all gets the error for the call rather than for each copy.
+func (s *Service) CopyBatch(ctx context.Context, req *pb.CopyBatchRequest) (*pb.CopyBatchResponse, error) {
+	if _, err := s.grant(ctx); err != nil {
+		return nil, err
+	}
+	resp := &pb.CopyBatchResponse{Results: make([]*pb.CopyResult, len(req.Copies))}
+	for i, c := range req.Copies {
+		_, err := s.Copy(ctx, c)
+		st := status.Convert(err)
+		resp.Results[i] = &pb.CopyResult{Code: int32(st.Code()), Error: st.Message()}
+	}
+	return resp, nil
+}
+
+// CopyStream implements ClipboardService.CopyStream. Size limits are checked
+// as chunks arrive, so an oversized copy is refused before all of it is
+// received.
+func (s *Service) CopyStream(stream pb.ClipboardService_CopyStreamServer) error {
+	ctx := stream.Context()
+	c, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		c = &pb.CopyChunk{}
+	} else if err != nil {
+		return err
+	}
+	clipboard, source := c.Clipboard, c.Source
+	cb := canonicalize(clipboard)
+	if err := s.auth(ctx, accessWrite, cb); err != nil {
+		return err
+	}
+	src, srcErr := s.boundSource(ctx, source)
+	if srcErr != nil {
+		return srcErr
+	}
+	var asm chunk.Assembler
+	for err == nil {
+		if err := asm.Add(c.Chunk); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if err := s.h.CheckSize(asm.Items()); err != nil {
+			s.h.RecordRefused(asm.Items(), cb, addrFromCtx(ctx), src, "", err)
+			return sizeStatus(err)
+		}
+		c, err = stream.Recv()
+	}
+	if !errors.Is(err, io.EOF) {
+		return err
+	}
+	if err := s.publishCopy(ctx, clipboard, source, asm.Items()); err != nil {
+		return err
+	}
+	return stream.SendAndClose(&pb.CopyResponse{})
+}
+
+// publishCopy checks the items of a copy with checkCopy and publishes them.
+func (s *Service) publishCopy(ctx context.Context, clipboard, source string, items []*pb.ClipboardItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	c, err := s.checkCopy(ctx, clipboard, source, items)
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, c)
+	return nil
+}
+
+// checkedCopy is a copy that passed checkCopy, ready to publish.
+type checkedCopy struct {
+	clipboard, source, origin string
+	items                     []*pb.ClipboardItem
+}
+
+// checkCopy checks the items of a copy against blob references, write rules,
+// content filters, the type policy, size limits and quotas, expanding a
+// snippet key copied to the snippet clipboard and rewriting links.
+func (s *Service) checkCopy(ctx context.Context, clipboard, source string, items []*pb.ClipboardItem) (checkedCopy, error) {
+	if err := s.checkRefs(items); err != nil {
+		return checkedCopy{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	src, err := s.boundSource(ctx, source)
+	if err != nil {
+		return checkedCopy{}, err
+	}
+	cb := canonicalize(clipboard)
+	g, _ := s.grant(ctx) // validated by auth
+	origin := addrFromCtx(ctx)
+	if !s.h.Writable(cb, src, g.Name) {
+		s.h.RecordRefused(items, cb, origin, src, "", hub.ErrReadOnly)
+		return checkedCopy{}, status.Errorf(codes.PermissionDenied, "clipboard %q is read-only for %s", cb, src)
+	}
+	filtered, err := s.h.FilterContent(cb, src, items)
+	if err != nil {
+		s.h.RecordRefused(items, cb, origin, src, "", err)
+		return checkedCopy{}, status.Errorf(codes.PermissionDenied, "copy refused: %v", err)
+	}
+	items = s.h.ExpandSnippet(cb, src, filtered)
+	items = s.h.RewriteURLs(cb, src, hub.RewriteOnPublish, items)
+	if items, err = s.h.CheckTypes(items); err != nil {
+		s.h.RecordRefused(filtered, cb, origin, src, "", err)
+		return checkedCopy{}, status.Errorf(codes.PermissionDenied, "copy refused: %v", err)
+	}
+	if err := s.h.CheckSize(items); err != nil {
+		s.h.RecordRefused(items, cb, origin, src, "", err)
+		return checkedCopy{}, sizeStatus(err)
+	}
+	if err := s.h.Quota().Admit(ctx, src, hub.PayloadSize(items)); err != nil {
+		s.h.RecordRefused(items, cb, origin, src, "", err)
+		if errors.Is(err, quota.ErrExceeded) {
+			slog.Debug("copy refused", "source", src, "clipboard", cb, "err", err)
+			return checkedCopy{}, status.Errorf(codes.ResourceExhausted, "%s: %v", src, err)
+		}
+		return checkedCopy{}, status.FromContextError(err).Err()
+	}
+	return checkedCopy{clipboard: cb, source: src, origin: origin, items: items}, nil
+}
+
+// publish publishes a checked copy.
+func (s *Service) publish(ctx context.Context, c checkedCopy) {
+	hub.LogItems("clipboard received", c.source, c.clipboard, c.items)
+	s.h.Publish(ctx, c.items, c.clipboard, c.origin, c.source)
+}
+
+// sizeStatus converts a *hub.SizeError to an InvalidArgument status whose
+// BadRequest detail names the offending field, so clients can tell which
+// item was too large and what the limit is.
+func sizeStatus(err error) error {
+	st := status.New(codes.InvalidArgument, err.Error())
+	var se *hub.SizeError
+	if !errors.As(err, &se) {
+		return st.Err()
+	}
+	field := "items"
+	if se.Item >= 0 {
+		field = fmt.Sprintf("items[%d].data", se.Item)
+	}
+	detailed, derr := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+			Field:       field,
+			Description: fmt.Sprintf("%d bytes; the limit is %d", se.Size, se.Limit),
+		}},
+	})
+	if derr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
 // Paste implements ClipboardService.Paste.
 func (s *Service) Paste(ctx context.Context, req *pb.PasteRequest) (*pb.PasteResponse, error) {
-	if err := s.auth(ctx); err != nil {
+	c, err := s.latest(ctx, req, !req.AcceptRefs)
+	if err != nil {
 		return nil, err
 	}
-	cb := canonicalize(req.Clipboard)
-	items, src := s.h.Latest(cb, req.Accepts)
 	return &pb.PasteResponse{
-		Source:    src,
-		Clipboard: cb,
-		Items:     items,
+		Source:    c.Source,
+		Clipboard: canonicalize(req.Clipboard),
+		Items:     c.Items,
+		StoredAt:  storedAt(c),
 	}, nil
 }
 
+// PasteStream implements ClipboardService.PasteStream.
+func (s *Service) PasteStream(req *pb.PasteRequest, stream pb.ClipboardService_PasteStreamServer) error {
+	c, err := s.latest(stream.Context(), req, true)
+	if err != nil {
+		return err
+	}
+	head := &pb.PasteChunk{Source: c.Source, Clipboard: canonicalize(req.Clipboard), StoredAt: storedAt(c)}
+	chunks := chunk.Split(c.Items)
+	if len(chunks) == 0 {
+		return stream.Send(head)
+	}
+	for i, c := range chunks {
+		msg := &pb.PasteChunk{Chunk: c}
+		if i == 0 {
+			head.Chunk, msg = c, head
+		}
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// latest returns the content requested by a Paste or PasteStream: the
+// clipboard's current content, or what it held at req.At. Blob references
+// are replaced by their content when resolve is set. StoredAt is only set
+// for req.At.
+func (s *Service) latest(ctx context.Context, req *pb.PasteRequest, resolve bool) (hub.HistoryEntry, error) {
+	cb := canonicalize(req.Clipboard)
+	if err := s.auth(ctx, accessRead, cb); err != nil {
+		return hub.HistoryEntry{}, err
+	}
+	var c hub.HistoryEntry
+	if req.At != nil {
+		if err := req.At.CheckValid(); err != nil {
+			return hub.HistoryEntry{}, status.Error(codes.InvalidArgument, err.Error())
+		}
+		var ok bool
+		if c, ok = s.h.ContentAt(cb, req.At.AsTime(), req.Accepts); !ok {
+			if !s.h.HistoryEnabled() {
+				return hub.HistoryEntry{}, status.Error(codes.FailedPrecondition, "the server keeps no clipboard history (start it with --history)")
+			}
+			return hub.HistoryEntry{}, status.Errorf(codes.NotFound, "clipboard %q held nothing at %s that the server still keeps", cb, req.At.AsTime().Format(time.RFC3339))
+		}
+	} else {
+		c.Items, c.Source = s.h.Latest(cb, req.Accepts)
+	}
+	if resolve {
+		var err error
+		if c.Items, err = s.h.Resolve(ctx, c.Items); err != nil {
+			return hub.HistoryEntry{}, status.Error(codes.Unavailable, err.Error())
+		}
+	}
+	s.h.CountServed(cb, hub.PayloadSize(c.Items))
+	return c, nil
+}
+
+// storedAt returns when c was published, or nil when that is not known.
+func storedAt(c hub.HistoryEntry) *timestamppb.Timestamp {
+	if c.StoredAt.IsZero() {
+		return nil
+	}
+	return timestamppb.New(c.StoredAt)
+}
+
 // Watch implements ClipboardService.Watch.
 func (s *Service) Watch(req *pb.WatchRequest, stream pb.ClipboardService_WatchServer) error {
-	if err := s.auth(stream.Context()); err != nil {
+	if err := s.auth(stream.Context(), accessRead, canonicalize(req.Clipboard)); err != nil {
+		return err
+	}
+	if g, _ := s.grant(stream.Context()); g.Guest {
+		release, err := s.guests.Watch()
+		if err != nil {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		defer release()
+	}
+	source, err := s.boundSource(stream.Context(), "")
+	if err != nil {
 		return err
 	}
 
 	addr := addrFromCtx(stream.Context())
 	cb := canonicalize(req.Clipboard)
-	id := addr + "/watch/" + cb
+	// The sequence number keeps IDs unique when several watchers share an
+	// address, as all IPC clients do.
+	id := fmt.Sprintf("%s/watch/%s#%d", addr, cb, s.watchSeq.Add(1))
 
 	wp := &watchPeer{
 		id:           id,
-		source:       sourceFromCtx(stream.Context(), ""),
+		source:       source,
 		addr:         addr,
 		clipboard:    cb,
 		accept:       req.Accepts,
 		metadataOnly: req.MetadataOnly,
-		ch:           make(chan hub.Event, 16),
+		acceptRefs:   req.AcceptRefs,
+		queue:        s.h.NewQueue(hub.DropWatch, 16),
+		done:         make(chan struct{}),
 		connectedAt:  time.Now(),
 	}
 
 	s.h.Register(wp)
 	defer s.h.Unregister(wp)
 
-	slog.Info("watch started", "peer", id, "accepts", req.Accepts, "metadata_only", req.MetadataOnly)
+	slog.Info("watch started", "peer", id, "accepts", req.Accepts,
+		"metadata_only", req.MetadataOnly, "accept_refs", req.AcceptRefs)
 
 	for {
 		select {
 		case <-stream.Context().Done():
 			return nil
-		case ev := <-wp.ch:
+		case <-wp.done:
+			return status.Error(codes.ResourceExhausted, "watch disconnected: too slow to keep up")
+		case <-s.tokens.Changed():
+			// End the stream once its token is revoked, expires or no
+			// longer covers the clipboard.
+			if err := s.auth(stream.Context(), accessRead, canonicalize(req.Clipboard)); err != nil {
+				return err
+			}
+		case ev := <-wp.queue.C():
 			availTypes := make([]string, len(ev.Items))
 			for i, it := range ev.Items {
 				availTypes[i] = it.Mime
@@ -107,52 +429,239 @@ func (s *Service) Watch(req *pb.WatchRequest, stream pb.ClipboardService_WatchSe
 				items = ev.Items
 			}
 
-			if err := stream.Send(&pb.WatchResponse{
+			_, span := tracing.StartFrom(ev.Trace, "watch.Deliver",
+				attribute.String("suffuse.peer", id),
+				attribute.String("suffuse.clipboard", ev.Clipboard),
+				attribute.String("suffuse.event_id", ev.ID),
+				attribute.Int("suffuse.bytes", hub.PayloadSize(items)),
+			)
+			err := stream.Send(&pb.WatchResponse{
 				Source:         ev.Source,
 				Clipboard:      ev.Clipboard,
 				Items:          items,
 				AvailableTypes: availTypes,
-			}); err != nil {
+				Expired:        ev.Expired,
+			})
+			tracing.End(span, err)
+			if err != nil {
 				return err
 			}
 		}
 	}
 }
 
-// Status implements ClipboardService.Status.
+// Fetch implements ClipboardService.Fetch. A token limited to some
+// clipboards, or a guest, may only fetch blobs that the content or history of
+// a clipboard it covers references; others are reported as not found. Other
+// tokens cover every clipboard, so they may also fetch a blob that was
+// replaced moments after they received its reference.
+func (s *Service) Fetch(ctx context.Context, req *pb.FetchRequest) (*pb.FetchResponse, error) {
+	if err := s.auth(ctx, accessRead, ""); err != nil {
+		return nil, err
+	}
+	g, _ := s.grant(ctx) // validated by auth
+	store := s.h.Blobs()
+	if store == nil {
+		return nil, status.Error(codes.NotFound, blob.ErrNotFound.Error())
+	}
+	if len(g.Clipboards) > 0 || g.Guest {
+		if !slices.ContainsFunc(s.h.Referencing(req.Sha256), g.Allows) {
+			return nil, status.Error(codes.NotFound, blob.ErrNotFound.Error())
+		}
+	}
+	data, err := store.Get(ctx, req.Sha256)
+	switch {
+	case errors.Is(err, blob.ErrNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	s.h.CountServed("", len(data))
+	return &pb.FetchResponse{Data: data}, nil
+}
+
+// checkRefs rejects blob references the hub cannot resolve. Peers always
+// publish content inline, but may pass on a reference they received.
+func (s *Service) checkRefs(items []*pb.ClipboardItem) error {
+	for _, it := range items {
+		if it.Ref == nil {
+			continue
+		}
+		if store := s.h.Blobs(); store == nil || !store.Has(it.Ref.Sha256) {
+			return fmt.Errorf("%s: unknown blob reference %s", it.Mime, it.Ref.Sha256)
+		}
+	}
+	return nil
+}
+
+// Status implements ClipboardService.Status. A token limited to some
+// clipboards sees only their usage, and only the peers on them or spanning
+// all clipboards.
 func (s *Service) Status(ctx context.Context, _ *pb.StatusRequest) (*pb.StatusResponse, error) {
-	if err := s.auth(ctx); err != nil {
+	g, err := s.grant(ctx)
+	if err != nil {
 		return nil, err
 	}
-	resp := &pb.StatusResponse{Peers: s.h.Peers()}
-	if s.upstream != nil {
-		resp.UpstreamInfo = s.upstream.UpstreamInfo()
+	resp := &pb.StatusResponse{
+		Dropped: s.h.Dropped(),
+		Stats:   s.hubStats(),
+	}
+	for _, p := range s.h.Peers() {
+		if p.Clipboard == "" || g.Allows(p.Clipboard) {
+			resp.Peers = append(resp.Peers, p)
+		}
+	}
+	usage := s.h.ClipboardUsage()
+	for _, cb := range slices.Sorted(maps.Keys(usage)) {
+		if !g.Allows(cb) {
+			continue
+		}
+		u := usage[cb]
+		resp.Usage = append(resp.Usage, &pb.ClipboardUsage{Clipboard: cb, BytesIn: u.In, BytesOut: u.Out})
+	}
+	for _, up := range s.upstreamList() {
+		resp.Upstreams = append(resp.Upstreams, up.UpstreamInfo())
+	}
+	if len(resp.Upstreams) > 0 {
+		resp.UpstreamInfo = resp.Upstreams[0]
+	}
+	for _, u := range s.h.Quota().Usage() {
+		resp.Quotas = append(resp.Quotas, &pb.SourceQuota{
+			Source:      u.Source,
+			Copies:      u.Copies,
+			CopiesLimit: u.CopiesLimit,
+			Bytes:       u.Bytes,
+			BytesLimit:  u.BytesLimit,
+			Exceeded:    u.Exceeded,
+		})
 	}
 	return resp, nil
 }
 
-// auth validates the bearer token in ctx metadata. Skipped when s.token is empty.
-func (s *Service) auth(ctx context.Context) error {
-	if s.token == "" {
-		return nil
+// processMetrics are the runtime/metrics samples reported in HubStats.
+var processMetrics = []string{"/memory/classes/heap/objects:bytes", "/sched/goroutines:goroutines"}
+
+// hubStats converts the hub's counters to their wire form, adding the
+// process's heap and goroutine counts.
+func (s *Service) hubStats() *pb.HubStats {
+	stats := s.h.Stats()
+	samples := make([]metrics.Sample, len(processMetrics))
+	for i, name := range processMetrics {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+	return &pb.HubStats{
+		Publishes:      stats.Publishes,
+		PublishedBytes: stats.PublishedBytes,
+		Clipboards:     uint32(stats.Clipboards),
+		Blobs:          uint32(stats.Blobs.Blobs),
+		BlobBytes:      uint64(stats.Blobs.Bytes),
+		BlobsPruned:    stats.Blobs.Pruned,
+		BytesIn:        stats.Usage.In,
+		BytesOut:       stats.Usage.Out,
+		Duplicates:     stats.Duplicates,
+		HopLimited:     stats.HopLimited,
+		Loops:          stats.Loops,
+		Oversized:      stats.Oversized,
+		HeapBytes:      samples[0].Value.Uint64(),
+		Goroutines:     uint32(samples[1].Value.Uint64()),
+	}
+}
+
+// access is what an RPC requires of the caller's token.
+type access int
+
+const (
+	accessRead  access = iota // paste, watch, status
+	accessWrite               // copy
+	accessAdmin               // admin RPCs and federation links
+)
+
+// auth checks that the caller's token allows need, and covers clipboard cb
+// unless cb is empty. Guests are rate limited per call by GuestUnary and
+// GuestStream, not here.
+func (s *Service) auth(ctx context.Context, need access, cb string) error {
+	g, err := s.grant(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case need == accessAdmin && !g.IsAdmin():
+		return status.Errorf(codes.PermissionDenied, "token %q is not an admin token", g.Name)
+	case need == accessWrite && !g.CanWrite():
+		return status.Errorf(codes.PermissionDenied, "token %q is read-only", g.Name)
+	case cb != "" && !g.Allows(cb):
+		return status.Errorf(codes.PermissionDenied, "token %q does not cover clipboard %q", g.Name, cb)
+	}
+	return nil
+}
+
+// grant validates the bearer token in ctx metadata against s.tokens and
+// returns what it allows. Full access is granted while the empty token is
+// accepted, and for calls over the IPC socket, which the OS already
+// restricts to the owning user.
+func (s *Service) grant(ctx context.Context) (tokens.Grant, error) {
+	if g, ok := s.tokens.Lookup(""); ok {
+		return g, nil
+	}
+	if fromIPC(ctx) {
+		return tokens.Grant{Role: tokens.RoleAdmin}, nil
 	}
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return status.Error(codes.Unauthenticated, "missing metadata")
+		return tokens.Grant{}, status.Error(codes.Unauthenticated, "missing metadata")
 	}
 	vals := md.Get("authorization")
 	if len(vals) == 0 {
-		return status.Error(codes.Unauthenticated, "missing authorization header")
+		return tokens.Grant{}, status.Error(codes.Unauthenticated, "missing authorization header")
 	}
 	const prefix = "Bearer "
 	tok := vals[0]
 	if len(tok) > len(prefix) && tok[:len(prefix)] == prefix {
 		tok = tok[len(prefix):]
 	}
-	if tok != s.token {
-		return status.Error(codes.Unauthenticated, "invalid token")
+	g, ok := s.tokens.Lookup(tok)
+	if !ok {
+		return tokens.Grant{}, status.Error(codes.Unauthenticated, "invalid token")
 	}
-	return nil
+	return g, nil
+}
+
+// fromIPC reports whether the call arrived over the local IPC socket.
+func fromIPC(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	return ok && p.Addr.Network() == "unix"
+}
+
+// boundSource returns the source name of a call, as sourceFromCtx, after
+// checking that its token may present it (tokens.Grant.AllowsSource). A call
+// naming no source under a token bound to its name gets that name.
+func (s *Service) boundSource(ctx context.Context, fallback string) (string, error) {
+	g, err := s.grant(ctx)
+	if err != nil {
+		return "", err
+	}
+	src := sourceFromCtx(ctx, fallback)
+	if g.Sources == nil {
+		return src, nil
+	}
+	if !claimsSource(ctx, fallback) {
+		src = g.Name
+	}
+	if !g.AllowsSource(src) {
+		slog.Warn("call refused, source not bound to its token", "token", g.Name, "source", src, "addr", addrFromCtx(ctx))
+		return "", status.Errorf(codes.PermissionDenied, "token %q may not act as source %q", g.Name, src)
+	}
+	return src, nil
+}
+
+// claimsSource reports whether a call names its source, in metadata or as
+// fallback from its request.
+func claimsSource(ctx context.Context, fallback string) bool {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("x-suffuse-source")) > 0 {
+		return true
+	}
+	return fallback != ""
 }
 
 func sourceFromCtx(ctx context.Context, fallback string) string {
@@ -191,7 +700,10 @@ type watchPeer struct {
 	clipboard    string
 	accept       []string
 	metadataOnly bool
-	ch           chan hub.Event
+	acceptRefs   bool
+	queue        *hub.Queue
+	done         chan struct{} // closed by Disconnect
+	closeOnce    sync.Once
 	connectedAt  time.Time
 	lastSeen     atomic.Int64
 }
@@ -215,11 +727,20 @@ func (p *watchPeer) Info() *pb.PeerInfo {
 	}
 }
 
-func (p *watchPeer) Send(ev hub.Event) {
+// AcceptsRefs implements hub.RefPeer for watchers that set accept_refs.
+func (p *watchPeer) AcceptsRefs() bool { return p.acceptRefs }
+
+func (p *watchPeer) MetadataOnly() bool { return p.metadataOnly }
+
+func (p *watchPeer) Send(ev hub.Event) error {
 	p.lastSeen.Store(time.Now().UnixNano())
-	select {
-	case p.ch <- ev:
-	default:
-		slog.Warn("watch peer channel full, dropping", "peer", p.id)
-	}
+	return p.queue.Put(ev)
+}
+
+// Queue implements hub.QueuePeer.
+func (p *watchPeer) Queue() *hub.Queue { return p.queue }
+
+// Disconnect implements hub.Disconnecter by ending the Watch stream.
+func (p *watchPeer) Disconnect(error) {
+	p.closeOnce.Do(func() { close(p.done) })
 }
@@ -0,0 +1,208 @@
+package grpcservice
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/hub"
+	"go.klb.dev/suffuse/internal/snapshot"
+)
+
+// CreateSnapshot implements ClipboardService.CreateSnapshot. Keeping a
+// snapshot on the server takes a token that may write.
+func (s *Service) CreateSnapshot(ctx context.Context, req *pb.CreateSnapshotRequest) (*pb.Snapshot, error) {
+	need := accessRead
+	if req.Name != "" {
+		need = accessWrite
+		if err := snapshot.CheckName(req.Name); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if s.snapshots == nil {
+			return nil, status.Error(codes.FailedPrecondition, "the server keeps no snapshots")
+		}
+	}
+	if err := s.auth(ctx, need, ""); err != nil {
+		return nil, err
+	}
+	var want map[string]bool
+	for _, name := range req.Clipboards {
+		cb := canonicalize(name)
+		if err := s.auth(ctx, need, cb); err != nil {
+			return nil, err
+		}
+		if want == nil {
+			want = make(map[string]bool)
+		}
+		want[cb] = true
+	}
+	g, _ := s.grant(ctx) // validated by auth
+
+	snap := &pb.Snapshot{
+		Name:      req.Name,
+		CreatedAt: timestamppb.Now(),
+		CreatedBy: sourceFromCtx(ctx, ""),
+	}
+	stored, _ := s.h.Snapshot()
+	for _, sc := range stored {
+		if want != nil && !want[sc.Clipboard] || !g.Allows(sc.Clipboard) {
+			continue
+		}
+		items, err := s.h.Resolve(ctx, sc.Items)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "clipboard %q: %v", sc.Clipboard, err)
+		}
+		c := &pb.SnapshotClipboard{Clipboard: sc.Clipboard, Source: sc.Source, Items: items}
+		for _, it := range items {
+			c.AvailableTypes = append(c.AvailableTypes, it.Mime)
+		}
+		c.Size = int64(hub.PayloadSize(items))
+		snap.Clipboards = append(snap.Clipboards, c)
+	}
+	sort.Slice(snap.Clipboards, func(i, j int) bool {
+		return snap.Clipboards[i].Clipboard < snap.Clipboards[j].Clipboard
+	})
+	if req.Name == "" {
+		return snap, nil
+	}
+	if err := s.snapshots.Save(snap); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	slog.Info("snapshot saved", "name", snap.Name, "source", snap.CreatedBy, "clipboards", len(snap.Clipboards))
+	return withoutContent(snap), nil
+}
+
+// RestoreSnapshot implements ClipboardService.RestoreSnapshot.
+func (s *Service) RestoreSnapshot(ctx context.Context, req *pb.RestoreSnapshotRequest) (*pb.RestoreSnapshotResponse, error) {
+	if err := s.auth(ctx, accessWrite, ""); err != nil {
+		return nil, err
+	}
+	snap := req.GetSnapshot()
+	if name := req.GetName(); name != "" {
+		var err error
+		if snap, err = s.loadSnapshot(name); err != nil {
+			return nil, err
+		}
+	}
+	if snap == nil {
+		return nil, status.Error(codes.InvalidArgument, "no snapshot given")
+	}
+
+	// Check every clipboard before publishing any, so a refusal leaves
+	// them all as they were.
+	checked := make([]checkedCopy, 0, len(snap.Clipboards))
+	for _, c := range snap.Clipboards {
+		cb := canonicalize(c.Clipboard)
+		if err := s.auth(ctx, accessWrite, cb); err != nil {
+			return nil, err
+		}
+		if len(c.Items) == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "snapshot holds no content for clipboard %q", cb)
+		}
+		cc, err := s.checkCopy(ctx, cb, req.Source, c.Items)
+		if err != nil {
+			st := status.Convert(err)
+			return nil, status.Errorf(st.Code(), "clipboard %q: %s; nothing restored", cb, st.Message())
+		}
+		checked = append(checked, cc)
+	}
+	resp := &pb.RestoreSnapshotResponse{}
+	for _, cc := range checked {
+		s.publish(ctx, cc)
+		resp.Restored = append(resp.Restored, cc.clipboard)
+	}
+	slog.Info("snapshot restored", "name", snap.Name, "source", sourceFromCtx(ctx, req.Source), "clipboards", resp.Restored)
+	return resp, nil
+}
+
+// ListSnapshots implements ClipboardService.ListSnapshots.
+func (s *Service) ListSnapshots(ctx context.Context, _ *pb.ListSnapshotsRequest) (*pb.ListSnapshotsResponse, error) {
+	if err := s.auth(ctx, accessRead, ""); err != nil {
+		return nil, err
+	}
+	resp := &pb.ListSnapshotsResponse{}
+	if s.snapshots == nil {
+		return resp, nil
+	}
+	snaps, err := s.snapshots.List()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	g, _ := s.grant(ctx) // validated by auth
+	for _, snap := range snaps {
+		if covers(g.Allows, snap) {
+			resp.Snapshots = append(resp.Snapshots, withoutContent(snap))
+		}
+	}
+	return resp, nil
+}
+
+// DeleteSnapshot implements ClipboardService.DeleteSnapshot.
+func (s *Service) DeleteSnapshot(ctx context.Context, req *pb.DeleteSnapshotRequest) (*pb.DeleteSnapshotResponse, error) {
+	if err := s.auth(ctx, accessWrite, ""); err != nil {
+		return nil, err
+	}
+	snap, err := s.loadSnapshot(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range snap.Clipboards {
+		if err := s.auth(ctx, accessWrite, canonicalize(c.Clipboard)); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.snapshots.Delete(req.Name); err != nil {
+		return nil, snapshotStatus(err)
+	}
+	slog.Info("snapshot deleted", "name", req.Name, "source", sourceFromCtx(ctx, ""))
+	return &pb.DeleteSnapshotResponse{}, nil
+}
+
+// loadSnapshot returns the snapshot kept on the server under name.
+func (s *Service) loadSnapshot(name string) (*pb.Snapshot, error) {
+	if s.snapshots == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the server keeps no snapshots")
+	}
+	snap, err := s.snapshots.Load(name)
+	if err != nil {
+		return nil, snapshotStatus(err)
+	}
+	return snap, nil
+}
+
+func snapshotStatus(err error) error {
+	switch {
+	case errors.Is(err, snapshot.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, snapshot.ErrInvalidName):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// covers reports whether allows accepts every clipboard of snap.
+func covers(allows func(string) bool, snap *pb.Snapshot) bool {
+	for _, c := range snap.Clipboards {
+		if !allows(canonicalize(c.Clipboard)) {
+			return false
+		}
+	}
+	return true
+}
+
+// withoutContent returns a copy of snap listing its clipboards without
+// their items.
+func withoutContent(snap *pb.Snapshot) *pb.Snapshot {
+	out := proto.Clone(snap).(*pb.Snapshot)
+	for _, c := range out.Clipboards {
+		c.Items = nil
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+package grpcservice
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/localpeer"
+)
+
+// Undo implements ClipboardService.Undo.
+func (s *Service) Undo(ctx context.Context, _ *pb.UndoRequest) (*pb.UndoResponse, error) {
+	if !fromIPC(ctx) {
+		return nil, status.Error(codes.PermissionDenied, "undo is only served on the local IPC socket")
+	}
+	if s.local == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the server has no local clipboard (started with --no-local)")
+	}
+	u, err := s.local.Undo()
+	if errors.Is(err, localpeer.ErrNothingToUndo) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "restore the local clipboard: %v", err)
+	}
+	resp := &pb.UndoResponse{ReplacedBy: u.Source, OverwrittenAt: timestamppb.New(u.At)}
+	for _, it := range u.Items {
+		resp.AvailableTypes = append(resp.AvailableTypes, it.Mime)
+	}
+	return resp, nil
+}
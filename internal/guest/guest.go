@@ -0,0 +1,159 @@
+// Package guest implements anonymous read-only access: one well-known token,
+// shared by every guest, that may only paste from and watch a few
+// clipboards, e.g. so a conference-room display can show a shared clipboard
+// without holding write credentials. Guests are rate limited per client
+// host and in how many may watch at once, as the token is meant to be
+// handed out freely.
+package guest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"go.klb.dev/suffuse/internal/tokens"
+)
+
+// Name is the name guests are known by in logs and errors.
+const Name = "guest"
+
+// Defaults for Config.
+const (
+	DefaultClipboard         = "guest"
+	DefaultRequestsPerMinute = 30
+	DefaultMaxWatchers       = 8
+)
+
+// idleAfter is how long a client host is remembered after its last call.
+const idleAfter = 10 * time.Minute
+
+var (
+	// ErrRateLimited is returned (wrapped) by Admit when a host calls too
+	// often.
+	ErrRateLimited = errors.New("guest rate limit exceeded")
+	// ErrTooManyWatchers is returned by Watch when MaxWatchers guests are
+	// already watching.
+	ErrTooManyWatchers = errors.New("too many guests are watching")
+)
+
+// Config enables guest access. An empty Token disables it.
+type Config struct {
+	Token string
+	// Clipboards are the path.Match patterns guests may read; empty means
+	// DefaultClipboard.
+	Clipboards []string
+	// RequestsPerMinute is how many calls each client host may make a
+	// minute; zero means DefaultRequestsPerMinute.
+	RequestsPerMinute int
+	// MaxWatchers is how many guest Watch streams may be open at once; zero
+	// means DefaultMaxWatchers.
+	MaxWatchers int
+}
+
+// Scoped returns the token set entry of the guest token: read-only and
+// limited to cfg's clipboards.
+func (cfg Config) Scoped() tokens.Scoped {
+	clipboards := cfg.Clipboards
+	if len(clipboards) == 0 {
+		clipboards = []string{DefaultClipboard}
+	}
+	return tokens.Scoped{Name: Name, Token: cfg.Token, Role: tokens.RoleReadOnly, Clipboards: clipboards, Guest: true}
+}
+
+// Limiter enforces the limits of a Config. The zero of *Limiter (nil)
+// admits everything.
+type Limiter struct {
+	perMinute   int
+	maxWatchers int
+
+	mu       sync.Mutex
+	hosts    map[string]*host
+	watchers int
+}
+
+// host is one client host's token bucket.
+type host struct {
+	tokens  float64
+	updated time.Time
+}
+
+// New returns the Limiter for cfg, or nil when guest access is disabled.
+func New(cfg Config) (*Limiter, error) {
+	if cfg.Token == "" {
+		return nil, nil
+	}
+	if cfg.RequestsPerMinute < 0 || cfg.MaxWatchers < 0 {
+		return nil, errors.New("guest limits must not be negative")
+	}
+	l := &Limiter{
+		perMinute:   cfg.RequestsPerMinute,
+		maxWatchers: cfg.MaxWatchers,
+		hosts:       make(map[string]*host),
+	}
+	if l.perMinute == 0 {
+		l.perMinute = DefaultRequestsPerMinute
+	}
+	if l.maxWatchers == 0 {
+		l.maxWatchers = DefaultMaxWatchers
+	}
+	return l, nil
+}
+
+// Admit counts a call from the client at addr (host:port) and returns an
+// error wrapping ErrRateLimited when its host has used up its calls.
+func (l *Limiter) Admit(addr string) error {
+	if l == nil {
+		return nil
+	}
+	key := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		key = h
+	}
+	now := time.Now()
+	size := float64(l.perMinute)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for k, h := range l.hosts {
+		if now.Sub(h.updated) > idleAfter {
+			delete(l.hosts, k)
+		}
+	}
+	h := l.hosts[key]
+	if h == nil {
+		h = &host{tokens: size, updated: now}
+		l.hosts[key] = h
+	}
+	h.tokens = min(size, h.tokens+size*now.Sub(h.updated).Minutes())
+	h.updated = now
+	if h.tokens < 1 {
+		wait := time.Duration((1 - h.tokens) / size * float64(time.Minute))
+		return fmt.Errorf("%w: next call allowed in %s", ErrRateLimited, wait.Round(time.Second))
+	}
+	h.tokens--
+	return nil
+}
+
+// Watch takes one of the guest watcher slots, returning the function that
+// gives it back.
+func (l *Limiter) Watch() (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.watchers >= l.maxWatchers {
+		return nil, fmt.Errorf("%w (%d)", ErrTooManyWatchers, l.maxWatchers)
+	}
+	l.watchers++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.watchers--
+			l.mu.Unlock()
+		})
+	}, nil
+}
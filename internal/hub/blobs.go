@@ -0,0 +1,148 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
+)
+
+// resolveTimeout bounds fetching referenced content for a single delivery,
+// which may go to the upstream server.
+const resolveTimeout = 30 * time.Second
+
+// blobGCInterval is how often RunBlobGC collects unreferenced blobs.
+const blobGCInterval = time.Minute
+
+// RefPeer is an optional interface for peers that accept blob references in
+// place of large items and fetch the content themselves when they need it.
+// Peers that do not implement it, or whose AcceptsRefs returns false,
+// receive the content inline.
+type RefPeer interface {
+	Peer
+	AcceptsRefs() bool
+}
+
+// Blobs returns the hub's blob store, or nil when referenced transfer is
+// disabled.
+func (h *Hub) Blobs() *blob.Store { return h.cfg.Blobs }
+
+// Resolve returns items with blob references replaced by their content.
+func (h *Hub) Resolve(ctx context.Context, items []*pb.ClipboardItem) ([]*pb.ClipboardItem, error) {
+	if h.cfg.Blobs == nil || !blob.HasRefs(items) {
+		return items, nil
+	}
+	return h.cfg.Blobs.Resolve(ctx, items)
+}
+
+// resolveFor prepares ev for delivery to p: references are kept for a
+// RefPeer and resolved for everyone else. Items whose content cannot be
+// resolved are left out; ok is false when nothing is left to deliver.
+func (h *Hub) resolveFor(p Peer, ev Event) (_ Event, ok bool) {
+	if !blob.HasRefs(ev.Items) {
+		return ev, true
+	}
+	if rp, isRef := p.(RefPeer); isRef && rp.AcceptsRefs() {
+		return ev, true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	items := make([]*pb.ClipboardItem, 0, len(ev.Items))
+	for _, it := range ev.Items {
+		resolved, err := h.Resolve(ctx, []*pb.ClipboardItem{it})
+		if errors.Is(err, blob.ErrDeferred) {
+			slog.Debug("blob transfer deferred, item not delivered", "peer", p.ID(), "mime", it.Mime)
+			continue
+		}
+		if err != nil {
+			slog.Warn("blob unavailable, item not delivered", "peer", p.ID(), "err", err)
+			continue
+		}
+		items = append(items, resolved...)
+	}
+	ev.Items = items
+	return ev, len(items) > 0
+}
+
+// Referencing returns the clipboards whose content or history references
+// the blob sum.
+func (h *Hub) Referencing(sum string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	refers := func(items []*pb.ClipboardItem) bool {
+		return slices.ContainsFunc(items, func(it *pb.ClipboardItem) bool {
+			return it.Ref != nil && it.Ref.Sha256 == sum
+		})
+	}
+	var out []string
+	for cb, items := range h.latest {
+		if refers(items) {
+			out = append(out, cb)
+		}
+	}
+	for cb, entries := range h.history {
+		if slices.Contains(out, cb) {
+			continue
+		}
+		if slices.ContainsFunc(entries, func(e HistoryEntry) bool { return refers(e.Items) }) {
+			out = append(out, cb)
+		}
+	}
+	return out
+}
+
+// PruneBlobs removes blobs that no clipboard or history entry references and
+// that have not been used for unusedFor. With dryRun set nothing is removed.
+func (h *Hub) PruneBlobs(unusedFor time.Duration, dryRun bool) blob.PruneResult {
+	if h.cfg.Blobs == nil {
+		return blob.PruneResult{}
+	}
+	h.mu.RLock()
+	referenced := make(map[string]struct{})
+	mark := func(items []*pb.ClipboardItem) {
+		for _, it := range items {
+			if it.Ref != nil {
+				referenced[it.Ref.Sha256] = struct{}{}
+			}
+		}
+	}
+	for _, items := range h.latest {
+		mark(items)
+	}
+	for _, entries := range h.history {
+		for _, e := range entries {
+			mark(e.Items)
+		}
+	}
+	h.mu.RUnlock()
+
+	return h.cfg.Blobs.Prune(func(sum string) bool {
+		_, ok := referenced[sum]
+		return ok
+	}, unusedFor, dryRun)
+}
+
+// RunBlobGC prunes blobs unreferenced and unused for ttl once a minute until
+// ctx is cancelled. The grace period keeps a replaced item fetchable for
+// peers and downstream servers that received its reference moments earlier.
+func (h *Hub) RunBlobGC(ctx context.Context, ttl time.Duration) {
+	if h.cfg.Blobs == nil {
+		return
+	}
+	t := time.NewTicker(blobGCInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if res := h.PruneBlobs(ttl, false); res.Blobs > 0 {
+			slog.Debug("blobs pruned", "count", res.Blobs, "bytes", res.Bytes)
+		}
+	}
+}
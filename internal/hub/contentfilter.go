@@ -0,0 +1,129 @@
+package hub
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// Content filter actions.
+const (
+	FilterDrop   = "drop"
+	FilterRedact = "redact"
+)
+
+// DefaultRedaction replaces what a redact filter matched when it names no
+// replacement.
+const DefaultRedaction = "[REDACTED]"
+
+// ContentFilter drops or redacts copies whose text matches a pattern, e.g.
+// cloud credentials or private keys that should never leave the machine they
+// were copied on. Like write rules, filters are not enforced by the hub
+// itself: the local peer and the services accepting copies from clients call
+// FilterContent before publishing.
+type ContentFilter struct {
+	// Name identifies the filter in logs, the journal and errors.
+	Name string `mapstructure:"name"`
+	// Pattern is a regular expression (RE2 syntax) matched against every
+	// text item: those of a text/* type.
+	Pattern string `mapstructure:"pattern"`
+	// Action is FilterDrop (the default), which refuses the whole copy, or
+	// FilterRedact, which replaces every match with Replacement.
+	Action      string `mapstructure:"action"`
+	Replacement string `mapstructure:"replacement"`
+	// Clipboards are path.Match patterns limiting the filter to some
+	// clipboards; empty means every clipboard.
+	Clipboards []string `mapstructure:"clipboards"`
+
+	re *regexp.Regexp
+}
+
+// CheckContentFilters validates filters and compiles their patterns before
+// they are passed in Config.
+func CheckContentFilters(filters []ContentFilter) error {
+	for i := range filters {
+		f := &filters[i]
+		if f.Name == "" {
+			f.Name = fmt.Sprintf("filter %d", i+1)
+		}
+		switch f.Action {
+		case "":
+			f.Action = FilterDrop
+		case FilterDrop, FilterRedact:
+		default:
+			return fmt.Errorf("content filter %q: action must be %q or %q", f.Name, FilterDrop, FilterRedact)
+		}
+		if f.Pattern == "" {
+			return fmt.Errorf("content filter %q: no pattern given", f.Name)
+		}
+		re, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			return fmt.Errorf("content filter %q: %w", f.Name, err)
+		}
+		f.re = re
+		if f.Action == FilterRedact && f.Replacement == "" {
+			f.Replacement = DefaultRedaction
+		}
+		for _, pattern := range f.Clipboards {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("content filter %q: pattern %q: %w", f.Name, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// FilteredError describes a copy refused by a drop filter.
+type FilteredError struct {
+	Filter string
+}
+
+func (e *FilteredError) Error() string {
+	return fmt.Sprintf("content matches filter %q", e.Filter)
+}
+
+// FilterContent applies the content filters covering clipboard name to a
+// copy from source. It returns the items to publish, with matches replaced
+// where a redact filter applied, or a *FilteredError when a drop filter
+// matched. items is not modified. Items held as blob references are not
+// inspected.
+func (h *Hub) FilterContent(name, source string, items []*pb.ClipboardItem) ([]*pb.ClipboardItem, error) {
+	cb := canonicalize(name)
+	out, cloned := items, false
+	for _, f := range h.rules.Load().ContentFilters {
+		if len(f.Clipboards) > 0 && !slices.ContainsFunc(f.Clipboards, func(pattern string) bool {
+			ok, _ := path.Match(pattern, cb)
+			return ok
+		}) {
+			continue
+		}
+		matched := false
+		for i, it := range out {
+			if !strings.HasPrefix(it.Mime, "text/") || it.Ref != nil || !f.re.Match(it.Data) {
+				continue
+			}
+			matched = true
+			if f.Action == FilterDrop {
+				break
+			}
+			if !cloned {
+				out, cloned = slices.Clone(items), true
+			}
+			out[i] = &pb.ClipboardItem{Mime: it.Mime, OriginalCharset: it.OriginalCharset, Data: f.re.ReplaceAllLiteral(it.Data, []byte(f.Replacement))}
+		}
+		if !matched {
+			continue
+		}
+		if f.Action == FilterDrop {
+			slog.Info("copy refused by content filter", "filter", f.Name, "clipboard", cb, "source", source)
+			return nil, &FilteredError{Filter: f.Name}
+		}
+		slog.Info("copy redacted by content filter", "filter", f.Name, "clipboard", cb, "source", source)
+	}
+	return out, nil
+}
@@ -0,0 +1,94 @@
+package hub
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/richtext"
+	"go.klb.dev/suffuse/internal/signing"
+)
+
+// Text representations the hub derives from one another; see Rules.Derive.
+const (
+	TypeText     = "text/plain"
+	TypeMarkdown = "text/markdown"
+	TypeHTML     = "text/html"
+)
+
+// CheckDerive validates the types of Rules.Derive.
+func CheckDerive(types []string) error {
+	for _, t := range types {
+		if t != TypeText && t != TypeMarkdown && t != TypeHTML {
+			return fmt.Errorf("derive type %q: must be %s, %s or %s", t, TypeText, TypeMarkdown, TypeHTML)
+		}
+	}
+	return nil
+}
+
+// deriveTypes returns items with the types of Rules.Derive they lack added,
+// converted from the text representation they carry, so a peer accepting
+// only some of the three still receives the content:
+//
+//   - text/plain from text/markdown, as it is, or from text/html;
+//   - text/markdown from text/html;
+//   - text/html from text/markdown or from text/plain.
+//
+// Types Rules.Types bans are not derived. Signed copies, whose signature an
+// added item would break, and items held as blob references are left alone.
+// items is not modified.
+func (h *Hub) deriveTypes(items []*pb.ClipboardItem, cb, source string) []*pb.ClipboardItem {
+	rules := h.rules.Load()
+	if len(rules.Derive) == 0 || IsProbeClipboard(cb) {
+		return items
+	}
+	have := make(map[string][]byte)
+	for _, it := range items {
+		if it.Mime == signing.MIME {
+			return items
+		}
+		if len(it.Data) > 0 && it.Ref == nil {
+			have[it.Mime] = it.Data
+		}
+	}
+
+	var derived []*pb.ClipboardItem
+	add := func(mime, data string) {
+		if data != "" {
+			derived = append(derived, &pb.ClipboardItem{Mime: mime, Data: []byte(data)})
+		}
+	}
+	for _, t := range rules.Derive {
+		if slices.ContainsFunc(items, func(it *pb.ClipboardItem) bool { return it.Mime == t }) || !rules.Types.Allowed(t) {
+			continue
+		}
+		switch t {
+		case TypeText:
+			if md, ok := have[TypeMarkdown]; ok {
+				add(t, string(md))
+			} else if src, ok := have[TypeHTML]; ok {
+				add(t, richtext.HTMLToText(src))
+			}
+		case TypeMarkdown:
+			if src, ok := have[TypeHTML]; ok {
+				add(t, richtext.HTMLToMarkdown(src))
+			}
+		case TypeHTML:
+			if md, ok := have[TypeMarkdown]; ok {
+				add(t, richtext.MarkdownToHTML(md))
+			} else if text, ok := have[TypeText]; ok {
+				add(t, richtext.TextToHTML(text))
+			}
+		}
+	}
+	if len(derived) == 0 {
+		return items
+	}
+	types := make([]string, len(derived))
+	for i, it := range derived {
+		types[i] = it.Mime
+	}
+	slog.Debug("representations derived", "clipboard", cb, "source", source, "types", types)
+	return append(slices.Clone(items), derived...)
+}
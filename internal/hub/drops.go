@@ -0,0 +1,169 @@
+package hub
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+
+	"go.klb.dev/suffuse/internal/journal"
+)
+
+// Subsystems whose dropped events are counted by the hub.
+const (
+	DropWatch                = "watch"                 // Watch stream channel full
+	DropLocal                = "localpeer"             // local clipboard writer behind
+	DropFederationUpstream   = "federation-upstream"   // upstream send channel full
+	DropFederationDownstream = "federation-downstream" // downstream Federate channel full
+	DropFederationOutbox     = "federation-outbox"     // unacknowledged event evicted
+	DropWebhook              = "webhook"               // webhook delivery queue full
+	DropProcessor            = "processor"             // enrichment processor queue full
+)
+
+// DropWarnEvery controls drop logging: the first drop in a subsystem and every
+// DropWarnEvery-th after it are logged at WARN, the rest at DEBUG, so a stuck
+// consumer is visible without flooding the log.
+const DropWarnEvery = 100
+
+// SlowConsumerPolicy selects what happens when a peer cannot accept an
+// event because its queue is full.
+type SlowConsumerPolicy string
+
+const (
+	// SlowConsumerDrop discards the event and keeps the peer connected.
+	SlowConsumerDrop SlowConsumerPolicy = "drop-newest"
+	// SlowConsumerDropOldest discards the oldest event queued for the peer
+	// to make room for the new one, so a peer that catches up receives the
+	// latest contents rather than stale ones.
+	SlowConsumerDropOldest SlowConsumerPolicy = "drop-oldest"
+	// SlowConsumerBlock waits up to Config.BlockTimeout for room and then
+	// discards the event. Events are fanned out to peers one after another,
+	// so a stalled peer delays the others by up to the timeout.
+	SlowConsumerBlock SlowConsumerPolicy = "block"
+	// SlowConsumerDisconnect discards the event and disconnects the peer if
+	// it implements Disconnecter, so it can reconnect and resync from the
+	// latest clipboard contents. Other peers fall back to dropping.
+	SlowConsumerDisconnect SlowConsumerPolicy = "disconnect"
+)
+
+// ParseSlowConsumerPolicy validates a policy name; empty and "drop", its
+// name before the others were added, mean SlowConsumerDrop.
+func ParseSlowConsumerPolicy(s string) (SlowConsumerPolicy, error) {
+	switch p := SlowConsumerPolicy(s); p {
+	case "", "drop":
+		return SlowConsumerDrop, nil
+	case SlowConsumerDrop, SlowConsumerDropOldest, SlowConsumerBlock, SlowConsumerDisconnect:
+		return p, nil
+	}
+	return "", errors.New(`slow-consumer policy must be "drop-newest", "drop-oldest", "block" or "disconnect"`)
+}
+
+// ErrPeerFull is returned (wrapped) by Peer.Send when the peer has no room
+// for another event.
+var ErrPeerFull = errors.New("peer queue full")
+
+// QueueFull returns an error wrapping ErrPeerFull that attributes the drop to
+// subsystem in Hub.Dropped.
+func QueueFull(subsystem string) error {
+	return &fullError{subsystem: subsystem}
+}
+
+// fullError reports a drop. evicted means an older event was dropped
+// instead and the one sent was queued.
+type fullError struct {
+	subsystem string
+	evicted   bool
+}
+
+func (e *fullError) Error() string {
+	if e.evicted {
+		return e.subsystem + ": " + ErrPeerFull.Error() + "; oldest event dropped"
+	}
+	return e.subsystem + ": " + ErrPeerFull.Error()
+}
+func (e *fullError) Unwrap() error { return ErrPeerFull }
+
+// Disconnecter is an optional interface for peers the hub may forcibly
+// disconnect under SlowConsumerDisconnect. Disconnect must not block; the
+// peer is expected to end its stream and Unregister itself.
+type Disconnecter interface {
+	Peer
+	Disconnect(reason error)
+}
+
+// deliver sends ev to p and applies the slow-consumer policy if p refuses it.
+// Must be called without h.mu held.
+func (h *Hub) deliver(p Peer, ev Event) {
+	if !h.relayable(p, ev) {
+		h.journalDeliver(p, ev, journal.OutcomeHopLimit, "")
+		return
+	}
+	resolved, ok := h.resolveFor(p, ev)
+	if !ok {
+		h.journalDeliver(p, ev, journal.OutcomeUnavailable, "referenced content could not be fetched")
+		return
+	}
+	ev = resolved
+	err := p.Send(ev)
+	var fe *fullError
+	isFull := errors.As(err, &fe)
+	if err == nil || isFull && fe.evicted {
+		if mp, ok := p.(MetadataPeer); !ok || !mp.MetadataOnly() {
+			h.countUsage(p.ID(), ev.Clipboard, 0, PayloadSize(ev.Items))
+		}
+		h.journalDeliver(p, ev, journal.OutcomeDelivered, "")
+		if err == nil {
+			return
+		}
+	} else {
+		h.journalDeliver(p, ev, journal.OutcomeDropped, err.Error())
+	}
+	subsystem := "peer"
+	if isFull {
+		subsystem = fe.subsystem
+	}
+
+	h.dropsMu.Lock()
+	h.peerDrops[p.ID()]++
+	h.dropsMu.Unlock()
+	h.RecordDrop(subsystem, p.ID())
+
+	if h.cfg.SlowConsumer != SlowConsumerDisconnect {
+		return
+	}
+	if d, ok := p.(Disconnecter); ok {
+		slog.Warn("disconnecting slow consumer", "peer", p.ID(), "err", err)
+		d.Disconnect(err)
+	}
+}
+
+// RecordDrop counts an event discarded by subsystem because its consumer
+// could not keep up. peer identifies the consumer in the log line. Drops
+// reported through Peer.Send are recorded by the hub itself; RecordDrop is
+// for losses elsewhere, such as an overflowing federation outbox.
+func (h *Hub) RecordDrop(subsystem, peer string) {
+	h.dropsMu.Lock()
+	h.drops[subsystem]++
+	n := h.drops[subsystem]
+	h.dropsMu.Unlock()
+
+	if n == 1 || n%DropWarnEvery == 0 {
+		slog.Warn("events dropped", "subsystem", subsystem, "peer", peer, "total", n)
+		return
+	}
+	slog.Debug("event dropped", "subsystem", subsystem, "peer", peer, "total", n)
+}
+
+// Dropped returns the cumulative number of dropped events per subsystem since
+// the hub was created. Subsystems with no drops are absent.
+func (h *Hub) Dropped() map[string]uint64 {
+	h.dropsMu.Lock()
+	defer h.dropsMu.Unlock()
+	return maps.Clone(h.drops)
+}
+
+// peerDropped returns the number of events dropped for a registered peer.
+func (h *Hub) peerDropped(id string) uint64 {
+	h.dropsMu.Lock()
+	defer h.dropsMu.Unlock()
+	return h.peerDrops[id]
+}
@@ -0,0 +1,92 @@
+package hub
+
+import (
+	"crypto/rand"
+	"slices"
+	"strings"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// seenEvents is how many recent event keys the hub remembers to recognise
+// an event arriving a second time over another federation link.
+const seenEvents = 4096
+
+// Relayed describes content that arrived over a federation link.
+type Relayed struct {
+	// EventID identifies the original publish, as carried in Event.ID.
+	// Empty for re-deliveries and from servers that predate event IDs;
+	// such content is never treated as already seen.
+	EventID string
+	// HopLimit is the limit returned by NextHopLimit.
+	HopLimit int
+	// Path is the origin path received with the event; this hub's
+	// Config.Name is appended when it is published.
+	Path []string
+}
+
+// newEventID returns a random ID for a publish made on this hub.
+func newEventID() string {
+	return rand.Text()
+}
+
+// eventKey identifies one delivery of event id to clipboard cb: the same
+// publish reaching this hub over two links carries the same clipboard and
+// types, while a follow-up that adds items held back earlier does not, and
+// neither does its copy in a host clipboard.
+func eventKey(id, cb string, items []*pb.ClipboardItem) string {
+	types := make([]string, len(items))
+	for i, it := range items {
+		types[i] = it.Mime
+	}
+	slices.Sort(types)
+	return id + "|" + cb + "|" + strings.Join(types, ",")
+}
+
+// eventSet remembers the most recent seenEvents keys.
+type eventSet struct {
+	keys  map[string]struct{}
+	order []string // ring buffer of keys in insertion order
+	next  int
+}
+
+// add records key and reports whether it was new.
+func (s *eventSet) add(key string) bool {
+	if _, ok := s.keys[key]; ok {
+		return false
+	}
+	if s.keys == nil {
+		s.keys = make(map[string]struct{}, seenEvents)
+		s.order = make([]string, seenEvents)
+	}
+	if old := s.order[s.next]; old != "" {
+		delete(s.keys, old)
+	}
+	s.order[s.next] = key
+	s.next = (s.next + 1) % seenEvents
+	s.keys[key] = struct{}{}
+	return true
+}
+
+// extendPath appends this hub to the origin path of r. It reports false,
+// counting a loop, when the path already includes this hub.
+func (h *Hub) extendPath(r *Relayed) bool {
+	if h.cfg.Name == "" {
+		return true
+	}
+	if slices.Contains(r.Path, h.cfg.Name) {
+		h.loops.Add(1)
+		return false
+	}
+	r.Path = append(slices.Clone(r.Path), h.cfg.Name)
+	return true
+}
+
+// latestPathLocked returns the origin path of the content stored for cb;
+// restored content starts here. Must be called with h.mu held.
+func (h *Hub) latestPathLocked(cb string) []string {
+	if p, ok := h.latestPath[cb]; ok || h.cfg.Name == "" {
+		return p
+	}
+	return []string{h.cfg.Name}
+}
@@ -0,0 +1,150 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/journal"
+)
+
+// expiryInterval is how often RunExpiry looks for expired content.
+const expiryInterval = time.Second
+
+// ClipboardTTL clears the content of clipboards a fixed time after it was
+// stored, e.g. so a password copied from a manager does not linger on every
+// machine.
+type ClipboardTTL struct {
+	// Clipboards is a path.Match pattern naming the clipboards; empty means
+	// every clipboard not named by another rule.
+	Clipboards string
+	TTL        time.Duration
+}
+
+// ParseClipboardTTL parses a duration such as "5m", applying to every
+// clipboard, or "<pattern>=<duration>", applying to the clipboards the
+// pattern matches.
+func ParseClipboardTTL(s string) (ClipboardTTL, error) {
+	pattern, ttl, ok := strings.Cut(s, "=")
+	if !ok {
+		pattern, ttl = "", s
+	}
+	pattern = strings.TrimSpace(pattern)
+	d, err := time.ParseDuration(strings.TrimSpace(ttl))
+	if err != nil || d <= 0 {
+		return ClipboardTTL{}, fmt.Errorf("clipboard TTL %q: want a positive duration such as 5m, or clipboard=duration", s)
+	}
+	if ok {
+		if pattern == "" {
+			return ClipboardTTL{}, fmt.Errorf("clipboard TTL %q: empty clipboard", s)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return ClipboardTTL{}, fmt.Errorf("clipboard TTL %q: %w", s, err)
+		}
+	}
+	return ClipboardTTL{Clipboards: pattern, TTL: d}, nil
+}
+
+// ttlFor returns the TTL of cb: that of the first rule naming it, else that
+// of a rule for every clipboard, else zero for none.
+func (h *Hub) ttlFor(cb string) time.Duration {
+	var all time.Duration
+	for _, t := range h.cfg.ClipboardTTLs {
+		if t.Clipboards == "" {
+			if all == 0 {
+				all = t.TTL
+			}
+			continue
+		}
+		if ok, _ := path.Match(t.Clipboards, cb); ok {
+			return t.TTL
+		}
+	}
+	return all
+}
+
+// RunExpiry clears content that has outlived its clipboard's TTL once a
+// second until ctx is cancelled.
+func (h *Hub) RunExpiry(ctx context.Context) {
+	if len(h.cfg.ClipboardTTLs) == 0 {
+		return
+	}
+	t := time.NewTicker(expiryInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		h.expire(time.Now())
+	}
+}
+
+// expire clears the clipboards whose content was stored longer than their
+// TTL ago, together with history entries as old, and sends an Expired event
+// to the peers following them. Relays are skipped: every hub of a federation
+// applies its own TTLs, so an expiry never overwrites newer content
+// elsewhere.
+func (h *Hub) expire(now time.Time) {
+	type expired struct {
+		cb, source string
+		items      []*pb.ClipboardItem
+		ttl        time.Duration
+	}
+	var cleared []expired
+	var targets []target
+
+	h.mu.Lock()
+	for cb, entries := range h.history {
+		if ttl := h.ttlFor(cb); ttl > 0 {
+			kept := slices.DeleteFunc(entries, func(e HistoryEntry) bool { return now.Sub(e.StoredAt) >= ttl })
+			if len(kept) == 0 {
+				delete(h.history, cb)
+			} else {
+				h.history[cb] = kept
+			}
+		}
+	}
+	for cb, at := range h.latestAt {
+		ttl := h.ttlFor(cb)
+		if ttl <= 0 || IsProbeClipboard(cb) || now.Sub(at) < ttl {
+			continue
+		}
+		cleared = append(cleared, expired{cb, h.latestSource[cb], h.latest[cb], ttl})
+		delete(h.latest, cb)
+		delete(h.latestSource, cb)
+		delete(h.latestAt, cb)
+		delete(h.latestHops, cb)
+		delete(h.latestPath, cb)
+		h.version++
+		for _, p := range h.peers {
+			if _, relays := p.(RelayPeer); relays {
+				continue
+			}
+			if _, isBroadcast := p.(BroadcastPeer); isBroadcast {
+				continue
+			}
+			for _, sub := range subscriptionsOf(p) {
+				if canonicalize(sub.Clipboard) == cb {
+					targets = append(targets, target{peer: p, clipboard: cb})
+					break
+				}
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	for _, e := range cleared {
+		slog.Info("clipboard content expired", "clipboard", e.cb, "source", e.source, "ttl", e.ttl)
+		h.journalPublish(e.items, e.cb, "", e.source, "", journal.OutcomeExpired, "after "+e.ttl.String())
+	}
+	for _, t := range targets {
+		h.deliver(t.peer, Event{Clipboard: t.clipboard, Expired: true})
+	}
+}
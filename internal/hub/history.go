@@ -0,0 +1,95 @@
+package hub
+
+import (
+	"slices"
+	"time"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
+)
+
+// HistoryEntry is content a clipboard held before it was replaced.
+type HistoryEntry struct {
+	Source string
+	Items  []*pb.ClipboardItem
+	// StoredAt is when the content was published.
+	StoredAt time.Time
+}
+
+// HistoryEnabled reports whether the hub keeps clipboard history.
+func (h *Hub) HistoryEnabled() bool { return h.cfg.HistorySize > 0 }
+
+// History returns the earlier contents of the named clipboard, newest first.
+// Items may be blob references; see Resolve.
+func (h *Hub) History(clipboardName string) []HistoryEntry {
+	cb := canonicalize(clipboardName)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := slices.Clone(h.history[cb])
+	slices.Reverse(out)
+	return out
+}
+
+// ContentAt returns what the named clipboard held at t, with the items of
+// the accepted types: its content if it was stored by then, otherwise the
+// newest history entry stored by then. ok is false when the clipboard held
+// nothing at t or its history no longer reaches back that far. Items may be
+// blob references; see Resolve.
+func (h *Hub) ContentAt(clipboardName string, t time.Time, accept []string) (e HistoryEntry, ok bool) {
+	cb := canonicalize(clipboardName)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if items, ok := h.latest[cb]; ok && !h.latestAt[cb].After(t) {
+		return HistoryEntry{Source: h.latestSource[cb], Items: filterItems(items, accept), StoredAt: h.latestAt[cb]}, true
+	}
+	entries := h.history[cb]
+	for i := len(entries) - 1; i >= 0; i-- {
+		if e := entries[i]; !e.StoredAt.After(t) {
+			e.Items = filterItems(e.Items, accept)
+			return e, true
+		}
+	}
+	return HistoryEntry{}, false
+}
+
+// AddHistory records content in the history of the named clipboard without
+// making it the clipboard's content or delivering it, e.g. a local copy that
+// lost a conflict with an update from another host. Content the clipboard
+// holds or already keeps in its history is not added again.
+func (h *Hub) AddHistory(clipboardName string, items []*pb.ClipboardItem, source string) {
+	cb := canonicalize(clipboardName)
+	if h.cfg.HistorySize <= 0 || len(items) == 0 || IsProbeClipboard(cb) {
+		return
+	}
+	if h.cfg.Blobs != nil {
+		items = h.cfg.Blobs.Externalize(items)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if blob.SameContent(items, h.latest[cb]) || slices.ContainsFunc(h.history[cb], func(e HistoryEntry) bool {
+		return blob.SameContent(items, e.Items)
+	}) {
+		return
+	}
+	h.appendHistoryLocked(cb, HistoryEntry{Source: source, Items: items, StoredAt: time.Now()})
+}
+
+// rememberLocked moves the content of cb to its history before it is
+// replaced with items. Must be called with h.mu held.
+func (h *Hub) rememberLocked(cb string, items []*pb.ClipboardItem) {
+	prev, ok := h.latest[cb]
+	if h.cfg.HistorySize <= 0 || !ok || IsProbeClipboard(cb) || blob.SameContent(items, prev) {
+		return
+	}
+	h.appendHistoryLocked(cb, HistoryEntry{Source: h.latestSource[cb], Items: prev, StoredAt: h.latestAt[cb]})
+}
+
+func (h *Hub) appendHistoryLocked(cb string, e HistoryEntry) {
+	entries := append(h.history[cb], e)
+	// Entries added by AddHistory may be newer than later replacements.
+	slices.SortStableFunc(entries, func(a, b HistoryEntry) int { return a.StoredAt.Compare(b.StoredAt) })
+	if n := len(entries) - h.cfg.HistorySize; n > 0 {
+		entries = slices.Delete(entries, 0, n)
+	}
+	h.history[cb] = entries
+}
@@ -0,0 +1,45 @@
+package hub
+
+import "log/slog"
+
+// DefaultMaxHops is the hop limit used when Config.MaxHops is zero.
+const DefaultMaxHops = 8
+
+// RelayPeer is an optional interface for peers that pass events on to
+// another hub, i.e. both ends of a federation link. Events whose HopLimit is
+// spent are not delivered to them, so a federation cycle or a chain deeper
+// than intended cannot carry content on indefinitely.
+type RelayPeer interface {
+	Peer
+	Relays()
+}
+
+// maxHops returns the hop limit given to content published on this hub.
+func (h *Hub) maxHops() int {
+	if h.cfg.MaxHops > 0 {
+		return h.cfg.MaxHops
+	}
+	return DefaultMaxHops
+}
+
+// NextHopLimit returns the hop limit for content received over a federation
+// link with hop limit received: one less, capped at this hub's own maximum.
+// Zero, sent by servers that predate hop limits, counts as the maximum.
+func (h *Hub) NextHopLimit(received uint32) int {
+	limit := h.maxHops()
+	if received > 0 && int64(received) < int64(limit) {
+		limit = int(received)
+	}
+	return limit - 1
+}
+
+// relayable reports whether ev may be delivered to p, counting the events
+// stopped at a relay.
+func (h *Hub) relayable(p Peer, ev Event) bool {
+	if _, ok := p.(RelayPeer); !ok || ev.HopLimit > 0 {
+		return true
+	}
+	h.hopLimited.Add(1)
+	slog.Debug("hop limit reached; event not relayed", "peer", p.ID(), "clipboard", ev.Clipboard, "source", ev.Source)
+	return false
+}
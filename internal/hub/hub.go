@@ -4,27 +4,138 @@
 package hub
 
 import (
+	"context"
 	"log/slog"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
+	"go.klb.dev/suffuse/internal/journal"
+	"go.klb.dev/suffuse/internal/quota"
+	"go.klb.dev/suffuse/internal/tracing"
 )
 
 const DefaultClipboard = "default"
 
+// HostClipboardPrefix namespaces the per-host clipboards maintained when
+// Config.HostClipboards is enabled: every publish from source "laptop" is
+// also stored as "host/laptop".
+const HostClipboardPrefix = "host/"
+
+// HostClipboard returns the name of the private clipboard for source.
+func HostClipboard(source string) string {
+	return HostClipboardPrefix + source
+}
+
+// Config holds optional hub behaviour.
+type Config struct {
+	// HostClipboards maintains a private "host/<source>" clipboard alongside
+	// every shared publish, so a host's own last copy survives overwrites of
+	// the shared clipboard by other peers.
+	HostClipboards bool
+
+	// SlowConsumer is applied when a peer cannot accept an event.
+	// Empty means SlowConsumerDrop.
+	SlowConsumer SlowConsumerPolicy
+
+	// BlockTimeout bounds the wait for room under SlowConsumerBlock. Zero
+	// means DefaultBlockTimeout.
+	BlockTimeout time.Duration
+
+	// QueueSizes override the capacity of the queues NewQueue returns for
+	// kinds of peers.
+	QueueSizes []QueueSize
+
+	// Blobs, when set, stores items above its threshold by reference. Peers
+	// implementing RefPeer receive the references; all others receive the
+	// content resolved from the store.
+	Blobs *blob.Store
+
+	// DedupWindow suppresses a publish whose content matches what the
+	// clipboard was set to less than this long ago, whichever peer it comes
+	// from, so an item echoed back by several peers of a mesh is not fanned
+	// out again. Zero disables suppression.
+	DedupWindow time.Duration
+
+	// MaxHops is the number of federation links content published on this
+	// hub may cross, and the cap applied to content arriving over one.
+	// Zero means DefaultMaxHops.
+	MaxHops int
+
+	// Name identifies this hub in the origin path of federated events, and
+	// must be unique across the federation; normally the server's source.
+	// Empty disables the path check.
+	Name string
+
+	// Rules are the policies that may change while the hub runs; see
+	// SetRules.
+	Rules
+
+	// Journal, when set, records every publish and its delivery to each
+	// peer, without their content.
+	Journal *journal.Journal
+
+	// Quota, when set, limits what each source may copy. Like WriteRules it
+	// is enforced by the services accepting copies from clients.
+	Quota *quota.Limiter
+
+	// HistorySize is the number of earlier contents kept in memory for each
+	// clipboard; see History. Zero keeps none.
+	HistorySize int
+
+	// ClipboardTTLs clear content a fixed time after it was stored; see
+	// RunExpiry.
+	ClipboardTTLs []ClipboardTTL
+}
+
 // Event is a clipboard update delivered to a peer.
 type Event struct {
 	Source    string
 	Clipboard string
 	Items     []*pb.ClipboardItem
+	// ID identifies the publish the event fans out, across every federation
+	// link it crosses. Empty when stored content is delivered to a peer that
+	// just registered or resubscribed.
+	ID string
+	// HopLimit is how many more federation links the event may cross; see
+	// RelayPeer.
+	HopLimit int
+	// Path lists the hubs the content has been published on, oldest first,
+	// ending with this one.
+	Path []string
+	// Expired marks the event telling a peer that the clipboard's content
+	// outlived its TTL and was cleared; Items is empty. See
+	// Config.ClipboardTTLs.
+	Expired bool
+	// Trace is the span of the publish, which the peer's delivery continues;
+	// invalid when nothing is traced.
+	Trace trace.SpanContext
 }
 
 // Peer is anything that can receive clipboard events from the hub.
 type Peer interface {
 	ID() string
 	Info() *pb.PeerInfo
-	// Send delivers an event to the peer. Must be non-blocking.
-	Send(Event)
+	// Send delivers an event to the peer. Must not block beyond
+	// Queue.Put: a peer that cannot take the event returns an error
+	// (normally from Queue.Put or QueueFull) and the hub counts the drop
+	// and applies Config.SlowConsumer.
+	Send(Event) error
+}
+
+// QueuePeer is an optional interface for peers holding a Queue, whose
+// length and capacity Peers reports.
+type QueuePeer interface {
+	Peer
+	Queue() *Queue
 }
 
 // BroadcastPeer is an optional interface a Peer may implement to signal that
@@ -35,6 +146,15 @@ type BroadcastPeer interface {
 	Broadcast()
 }
 
+// SubscriberPeer is an optional interface for peers that follow several
+// clipboards at once, each with its own MIME filter. The server side of a
+// federation link implements it. Info().Clipboard and Info().AcceptedTypes
+// are ignored for routing when a peer implements SubscriberPeer.
+type SubscriberPeer interface {
+	Peer
+	Subscriptions() []ClipboardFilter
+}
+
 // ClipboardFilter describes what a set of peers needs from a single clipboard.
 // An empty Accepts slice means all MIME types are accepted.
 type ClipboardFilter struct {
@@ -53,60 +173,141 @@ type PeerChangeListener interface {
 
 // Hub routes clipboard updates between all registered peers.
 type Hub struct {
+	cfg   Config
+	rules atomic.Pointer[Rules] // replaces cfg.Rules
+
 	mu           sync.RWMutex
 	peers        map[string]Peer
 	latest       map[string][]*pb.ClipboardItem // clipboard → latest items
 	latestSource map[string]string              // clipboard → source name
+	latestAt     map[string]time.Time           // clipboard → time of last store
+	latestHops   map[string]int                 // clipboard → hop limit of latest
+	latestPath   map[string][]string            // clipboard → origin path of latest
+	history      map[string][]HistoryEntry      // clipboard → earlier contents, oldest first
+	version      uint64                         // bumped on every change to latest
+	seen         eventSet                       // recently published event keys
 
 	listenerMu sync.RWMutex
-	listener   PeerChangeListener
+	listeners  []PeerChangeListener
+
+	dropsMu   sync.Mutex
+	drops     map[string]uint64 // subsystem → dropped events
+	peerDrops map[string]uint64 // peer ID → dropped events, while registered
+
+	usageMu        sync.Mutex
+	totalUsage     Usage
+	clipboardUsage map[string]Usage // clipboard → content moved
+	peerUsage      map[string]Usage // peer ID → content moved, while registered
+
+	publishes      atomic.Uint64
+	publishedBytes atomic.Uint64
+	duplicates     atomic.Uint64
+	hopLimited     atomic.Uint64
+	loops          atomic.Uint64
+	oversize       atomic.Uint64
 }
 
 // New returns an empty Hub.
-func New() *Hub {
-	return &Hub{
+func New(cfg Config) *Hub {
+	h := &Hub{
+		cfg:          cfg,
 		peers:        make(map[string]Peer),
 		latest:       make(map[string][]*pb.ClipboardItem),
 		latestSource: make(map[string]string),
+		latestAt:     make(map[string]time.Time),
+		latestHops:   make(map[string]int),
+		latestPath:   make(map[string][]string),
+		history:      make(map[string][]HistoryEntry),
+		drops:        make(map[string]uint64),
+		peerDrops:    make(map[string]uint64),
+
+		clipboardUsage: make(map[string]Usage),
+		peerUsage:      make(map[string]Usage),
 	}
+	h.SetRules(cfg.Rules)
+	return h
 }
 
-// SetPeerChangeListener registers a listener that is called whenever the peer
-// set changes. Only one listener is supported; calling again replaces it.
-func (h *Hub) SetPeerChangeListener(l PeerChangeListener) {
+// AddPeerChangeListener registers a listener that is called whenever the
+// peer set changes, e.g. one per upstream link.
+func (h *Hub) AddPeerChangeListener(l PeerChangeListener) {
 	h.listenerMu.Lock()
-	h.listener = l
+	h.listeners = append(h.listeners, l)
+	h.listenerMu.Unlock()
+}
+
+// RemovePeerChangeListener removes a listener added with
+// AddPeerChangeListener, e.g. when its upstream link is closed.
+func (h *Hub) RemovePeerChangeListener(l PeerChangeListener) {
+	h.listenerMu.Lock()
+	// notifyListener calls the listeners of the slice it read, so it is
+	// replaced rather than modified.
+	h.listeners = slices.DeleteFunc(slices.Clone(h.listeners), func(o PeerChangeListener) bool { return o == l })
 	h.listenerMu.Unlock()
 }
 
 // Register adds a peer and immediately delivers the latest clipboard contents
-// for its subscribed clipboard.
+// for its subscribed clipboard(s).
 func (h *Hub) Register(p Peer) {
+	h.usageMu.Lock()
+	h.peerUsage[p.ID()] = Usage{}
+	h.usageMu.Unlock()
+
 	h.mu.Lock()
 	h.peers[p.ID()] = p
 	info := p.Info()
-	cb := canonicalize(info.Clipboard)
-	latest := h.latest[cb]
-	src := h.latestSource[cb]
 	total := len(h.peers)
 	filters := h.clipboardFiltersLocked()
+	initial := h.latestEventsLocked(subscriptionsOf(p))
 	h.mu.Unlock()
 
 	slog.Info("peer registered",
 		"peer", p.ID(),
 		"source", info.Source,
-		"clipboard", cb,
+		"clipboard", canonicalize(info.Clipboard),
 		"total", total,
 	)
 
 	h.notifyListener(filters)
 
-	if len(latest) > 0 {
-		filtered := filterItems(latest, info.AcceptedTypes)
+	for _, ev := range initial {
+		h.deliver(p, ev)
+	}
+}
+
+// Resubscribe must be called by a SubscriberPeer after its Subscriptions
+// change. Filters are recomputed and the latest contents of newly added
+// clipboards are delivered to the peer.
+func (h *Hub) Resubscribe(p SubscriberPeer, added []ClipboardFilter) {
+	h.mu.Lock()
+	filters := h.clipboardFiltersLocked()
+	initial := h.latestEventsLocked(added)
+	h.mu.Unlock()
+
+	h.notifyListener(filters)
+
+	for _, ev := range initial {
+		h.deliver(p, ev)
+	}
+}
+
+// latestEventsLocked returns the stored contents of each subscribed clipboard
+// as events, filtered by the subscription's accepted types.
+// Must be called with h.mu held.
+func (h *Hub) latestEventsLocked(subs []ClipboardFilter) []Event {
+	var out []Event
+	for _, sub := range subs {
+		cb := canonicalize(sub.Clipboard)
+		filtered := filterItems(h.latest[cb], sub.Accepts)
 		if len(filtered) > 0 {
-			p.Send(Event{Source: src, Clipboard: cb, Items: filtered})
+			hops, ok := h.latestHops[cb]
+			if !ok {
+				hops = h.maxHops() // restored content
+			}
+			out = append(out, Event{Source: h.latestSource[cb], Clipboard: cb, Items: filtered, HopLimit: hops, Path: h.latestPathLocked(cb)})
 		}
 	}
+	return out
 }
 
 // Unregister removes a peer from the hub.
@@ -117,6 +318,14 @@ func (h *Hub) Unregister(p Peer) {
 	filters := h.clipboardFiltersLocked()
 	h.mu.Unlock()
 
+	h.dropsMu.Lock()
+	delete(h.peerDrops, p.ID())
+	h.dropsMu.Unlock()
+
+	h.usageMu.Lock()
+	delete(h.peerUsage, p.ID())
+	h.usageMu.Unlock()
+
 	slog.Info("peer unregistered",
 		"peer", p.ID(),
 		"source", p.Info().Source,
@@ -127,38 +336,179 @@ func (h *Hub) Unregister(p Peer) {
 }
 
 // Publish stores items as the latest clipboard and fans out to all peers on
-// the same clipboard except the origin.
-func (h *Hub) Publish(items []*pb.ClipboardItem, clipboardName, originID, source string) {
+// the same clipboard except the origin. With Config.HostClipboards enabled the
+// items are also stored under the source's host clipboard. ctx carries the
+// trace the publish belongs to, e.g. that of the Copy call making it; it does
+// not cancel the publish.
+func (h *Hub) Publish(ctx context.Context, items []*pb.ClipboardItem, clipboardName, originID, source string) {
+	h.PublishRelayed(ctx, items, clipboardName, originID, source, Relayed{EventID: newEventID(), HopLimit: h.maxHops()})
+}
+
+// PublishRelayed is Publish for content that arrived over a federation link.
+// An event whose path already includes this hub went round a cycle and is
+// dropped. An event this hub has already published, e.g. one reaching it
+// over two paths of a diamond-shaped topology, is suppressed and counted as
+// a duplicate.
+func (h *Hub) PublishRelayed(ctx context.Context, items []*pb.ClipboardItem, clipboardName, originID, source string, r Relayed) {
 	cb := canonicalize(clipboardName)
+	types, size := describeItems(items)
+	ctx, span := tracing.Start(ctx, "hub.Publish",
+		attribute.String("suffuse.clipboard", cb),
+		attribute.String("suffuse.source", source),
+		attribute.String("suffuse.origin", originID),
+		attribute.String("suffuse.event_id", r.EventID),
+		attribute.StringSlice("suffuse.types", types),
+		attribute.Int("suffuse.bytes", size),
+	)
+	defer span.End()
+	outcome := func(o string) { span.SetAttributes(attribute.String("suffuse.outcome", o)) }
+
+	if h.oversized(items, cb, originID, source, r.EventID) {
+		outcome(journal.OutcomeOversized)
+		return
+	}
+	if items = h.allowedTypes(items, cb, originID, source, r.EventID); items == nil {
+		outcome(journal.OutcomeRefused)
+		return
+	}
+	items = h.transcodeText(items, cb, source)
+	items = h.deriveTypes(items, cb, source)
+	if !h.extendPath(&r) {
+		outcome(journal.OutcomeLoop)
+		slog.Debug("event already passed through this hub; dropped", "clipboard", cb, "origin", originID, "source", source, "path", r.Path)
+		h.journalPublish(items, cb, originID, source, r.EventID, journal.OutcomeLoop, "")
+		return
+	}
+	probe := IsProbeClipboard(cb)
+	if !probe {
+		h.countPublish(items)
+	}
+	h.countUsage(originID, cb, PayloadSize(items), 0)
+	if h.cfg.Blobs != nil {
+		items = h.cfg.Blobs.Externalize(items)
+	}
+	items = blob.WithDigests(items)
 
 	h.mu.Lock()
+	if r.EventID != "" && !h.seen.add(eventKey(r.EventID, cb, items)) {
+		h.mu.Unlock()
+		h.duplicates.Add(1)
+		outcome(journal.OutcomeDuplicate)
+		slog.Debug("event already published; suppressed", "clipboard", cb, "origin", originID, "source", source, "event", r.EventID)
+		h.journalPublish(items, cb, originID, source, r.EventID, journal.OutcomeDuplicate, "event already published")
+		return
+	}
+	if h.duplicateLocked(items, cb) {
+		h.mu.Unlock()
+		h.duplicates.Add(1)
+		outcome(journal.OutcomeDuplicate)
+		slog.Debug("duplicate publish suppressed", "clipboard", cb, "origin", originID, "source", source)
+		h.journalPublish(items, cb, originID, source, r.EventID, journal.OutcomeDuplicate, "within the dedup window")
+		return
+	}
+	targets := h.storeLocked(items, cb, originID, source, r, true)
+	if hc := HostClipboard(source); h.cfg.HostClipboards && source != "" && cb != hc &&
+		!strings.HasPrefix(cb, HostClipboardPrefix) && !probe {
+		// BroadcastPeers are skipped for the derived copy: they already
+		// receive the shared event. Federation links relay it with its
+		// source, so a server across one keeps host clipboards only if it
+		// runs with HostClipboards itself.
+		targets = append(targets, h.storeLocked(items, hc, originID, source, r, false)...)
+	}
+	targets = append(targets, h.mirrorLocked(items, cb, originID, source, r)...)
+	h.mu.Unlock()
+	h.journalPublish(items, cb, originID, source, r.EventID, journal.OutcomeStored, "")
+	outcome(journal.OutcomeStored)
+	span.SetAttributes(attribute.Int("suffuse.peers", len(targets)))
+
+	for _, t := range targets {
+		ev := Event{Source: source, Clipboard: t.clipboard, Items: filterItems(t.items, t.accepted), ID: r.EventID, HopLimit: r.HopLimit, Path: r.Path, Trace: span.SpanContext()}
+		if len(ev.Items) == 0 {
+			ev.Items = t.items
+			h.journalDeliver(t.peer, ev, journal.OutcomeFiltered, "")
+			continue
+		}
+		h.deliver(t.peer, ev)
+	}
+	if probe {
+		h.answerProbe(ctx, items, cb)
+	}
+}
+
+// PublishUpdate publishes items as an update of ev, a publish delivered to
+// the peer originID, e.g. with an item derived from its content added. The
+// update keeps ev's event ID, so federation links pass it on as a follow-up
+// of the same copy, and is only published while the clipboard still holds
+// ev's content; it reports whether it was.
+func (h *Hub) PublishUpdate(ctx context.Context, ev Event, items []*pb.ClipboardItem, originID string) bool {
+	cb := canonicalize(ev.Clipboard)
+	h.mu.RLock()
+	current := blob.SameContent(h.latest[cb], ev.Items)
+	h.mu.RUnlock()
+	if !current || ev.ID == "" {
+		return false
+	}
+	// ev's path ends with this hub, which PublishRelayed appends again.
+	path := ev.Path
+	if n := len(path); n > 0 && h.cfg.Name != "" && path[n-1] == h.cfg.Name {
+		path = path[:n-1]
+	}
+	h.PublishRelayed(ctx, items, cb, originID, ev.Source, Relayed{EventID: ev.ID, HopLimit: ev.HopLimit, Path: path})
+	return true
+}
+
+// duplicateLocked reports whether items repeat the content cb was set to
+// within Config.DedupWindow. Must be called with h.mu held.
+func (h *Hub) duplicateLocked(items []*pb.ClipboardItem, cb string) bool {
+	if h.cfg.DedupWindow <= 0 || time.Since(h.latestAt[cb]) >= h.cfg.DedupWindow {
+		return false
+	}
+	return blob.SameContent(items, h.latest[cb])
+}
+
+// target is a peer selected for delivery by storeLocked.
+type target struct {
+	peer      Peer
+	clipboard string
+	items     []*pb.ClipboardItem // as stored for clipboard
+	accepted  []string
+}
+
+// storeLocked records items as the latest for cb and returns the peers that
+// should receive them. BroadcastPeers are included only when broadcast is set,
+// and only RelayPeers for probe clipboards. Must be called with h.mu held.
+func (h *Hub) storeLocked(items []*pb.ClipboardItem, cb, originID, source string, r Relayed, broadcast bool) []target {
+	h.rememberLocked(cb, items)
 	h.latest[cb] = items
 	h.latestSource[cb] = source
-
-	type target struct {
-		peer     Peer
-		accepted []string
+	h.latestAt[cb] = time.Now()
+	h.latestHops[cb] = r.HopLimit
+	h.latestPath[cb] = r.Path
+	probe := IsProbeClipboard(cb)
+	if !probe {
+		// Probes are not part of what Snapshot reports.
+		h.version++
 	}
+
 	var targets []target
 	for id, p := range h.peers {
 		if id == originID {
 			continue
 		}
-		info := p.Info()
-		_, isBroadcast := p.(BroadcastPeer)
-		if isBroadcast || canonicalize(info.Clipboard) == cb {
-			targets = append(targets, target{p, info.AcceptedTypes})
-		}
-	}
-	h.mu.Unlock()
-
-	for _, t := range targets {
-		filtered := filterItems(items, t.accepted)
-		if len(filtered) == 0 {
+		if _, isBroadcast := p.(BroadcastPeer); isBroadcast {
+			if _, relays := p.(RelayPeer); broadcast && (relays || !probe) {
+				targets = append(targets, target{p, cb, items, nil})
+			}
 			continue
 		}
-		t.peer.Send(Event{Source: source, Clipboard: cb, Items: filtered})
+		for _, sub := range subscriptionsOf(p) {
+			if canonicalize(sub.Clipboard) == cb {
+				targets = append(targets, target{p, cb, items, sub.Accepts})
+				break
+			}
+		}
 	}
+	return targets
 }
 
 // Latest returns the most recent items and source for the named clipboard,
@@ -170,13 +520,61 @@ func (h *Hub) Latest(clipboardName string, accept []string) ([]*pb.ClipboardItem
 	return filterItems(h.latest[cb], accept), h.latestSource[cb]
 }
 
+// Clear drops the stored contents and history of the named clipboards, or of
+// every clipboard when names is empty, and returns the clipboards that were
+// cleared. A clipboard whose content expired may still have history to clear.
+// Peers are not notified; their system clipboards are left untouched.
+func (h *Hub) Clear(names []string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(names) == 0 {
+		for cb := range h.latest {
+			names = append(names, cb)
+		}
+		for cb := range h.history {
+			if _, ok := h.latest[cb]; !ok {
+				names = append(names, cb)
+			}
+		}
+	}
+	var cleared []string
+	for _, name := range names {
+		cb := canonicalize(name)
+		_, stored := h.latest[cb]
+		if _, kept := h.history[cb]; !stored && !kept {
+			continue
+		}
+		delete(h.latest, cb)
+		delete(h.latestSource, cb)
+		delete(h.latestAt, cb)
+		delete(h.latestHops, cb)
+		delete(h.latestPath, cb)
+		delete(h.history, cb)
+		cleared = append(cleared, cb)
+	}
+	// Bumped even when nothing was cleared, so persisted copies such as the
+	// clipboard cache are rewritten too.
+	h.version++
+	sort.Strings(cleared)
+	return cleared
+}
+
 // Peers returns a snapshot of all current peer metadata.
 func (h *Hub) Peers() []*pb.PeerInfo {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	out := make([]*pb.PeerInfo, 0, len(h.peers))
-	for _, p := range h.peers {
-		out = append(out, p.Info())
+	for id, p := range h.peers {
+		info := p.Info()
+		info.Dropped = h.peerDropped(id)
+		if qp, ok := p.(QueuePeer); ok {
+			q := qp.Queue()
+			info.Queued, info.QueueSize = uint32(q.Len()), uint32(q.Cap())
+		}
+		u := h.peerUsageOf(id)
+		info.BytesIn, info.BytesOut = u.In, u.Out
+		out = append(out, info)
 	}
 	return out
 }
@@ -201,23 +599,24 @@ func (h *Hub) clipboardFiltersLocked() []ClipboardFilter {
 		if _, isBroadcast := p.(BroadcastPeer); isBroadcast {
 			continue
 		}
-		info := p.Info()
-		cb := canonicalize(info.Clipboard)
-		e, ok := m[cb]
-		if !ok {
-			e = &entry{accepts: make(map[string]struct{})}
-			m[cb] = e
-		}
-		if e.all {
-			continue // already unbounded
-		}
-		if len(info.AcceptedTypes) == 0 {
-			e.all = true
-			e.accepts = nil
-			continue
-		}
-		for _, t := range info.AcceptedTypes {
-			e.accepts[t] = struct{}{}
+		for _, sub := range subscriptionsOf(p) {
+			cb := canonicalize(sub.Clipboard)
+			e, ok := m[cb]
+			if !ok {
+				e = &entry{accepts: make(map[string]struct{})}
+				m[cb] = e
+			}
+			if e.all {
+				continue // already unbounded
+			}
+			if len(sub.Accepts) == 0 {
+				e.all = true
+				e.accepts = nil
+				continue
+			}
+			for _, t := range sub.Accepts {
+				e.accepts[t] = struct{}{}
+			}
 		}
 	}
 
@@ -234,16 +633,26 @@ func (h *Hub) clipboardFiltersLocked() []ClipboardFilter {
 	return out
 }
 
-// notifyListener calls the registered PeerChangeListener if one is set.
+// notifyListener calls the registered PeerChangeListeners.
 func (h *Hub) notifyListener(filters []ClipboardFilter) {
 	h.listenerMu.RLock()
-	l := h.listener
+	ls := h.listeners
 	h.listenerMu.RUnlock()
-	if l != nil {
+	for _, l := range ls {
 		l.OnPeerChange(filters)
 	}
 }
 
+// subscriptionsOf returns the clipboards p follows: its Subscriptions for a
+// SubscriberPeer, otherwise the single clipboard reported in Info.
+func subscriptionsOf(p Peer) []ClipboardFilter {
+	if sp, ok := p.(SubscriberPeer); ok {
+		return sp.Subscriptions()
+	}
+	info := p.Info()
+	return []ClipboardFilter{{Clipboard: info.Clipboard, Accepts: info.AcceptedTypes}}
+}
+
 // canonicalize returns the effective clipboard name, defaulting to "default".
 func canonicalize(s string) string {
 	if s == "" {
@@ -0,0 +1,67 @@
+package hub
+
+import (
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
+	"go.klb.dev/suffuse/internal/journal"
+)
+
+// Journal returns the event journal from Config; nil records nothing.
+func (h *Hub) Journal() *journal.Journal { return h.cfg.Journal }
+
+// journalPublish records what became of a publish.
+func (h *Hub) journalPublish(items []*pb.ClipboardItem, cb, originID, source, eventID, outcome, detail string) {
+	if h.cfg.Journal == nil || IsProbeClipboard(cb) {
+		return
+	}
+	types, size := describeItems(items)
+	h.cfg.Journal.Record(journal.Entry{
+		Kind:      journal.KindPublish,
+		Event:     eventID,
+		Clipboard: cb,
+		Source:    source,
+		Peer:      originID,
+		Types:     types,
+		Size:      size,
+		Outcome:   outcome,
+		Detail:    detail,
+	})
+}
+
+// journalDeliver records what became of ev on its way to p.
+func (h *Hub) journalDeliver(p Peer, ev Event, outcome, detail string) {
+	if h.cfg.Journal == nil || IsProbeClipboard(ev.Clipboard) {
+		return
+	}
+	types, size := describeItems(ev.Items)
+	h.cfg.Journal.Record(journal.Entry{
+		Kind:       journal.KindDeliver,
+		Event:      ev.ID,
+		Clipboard:  ev.Clipboard,
+		Source:     ev.Source,
+		Peer:       p.ID(),
+		PeerSource: p.Info().Source,
+		Types:      types,
+		Size:       size,
+		Outcome:    outcome,
+		Detail:     detail,
+	})
+}
+
+// describeItems returns the MIME types and total size of items, all a
+// journal entry records of them.
+func describeItems(items []*pb.ClipboardItem) ([]string, int) {
+	types := make([]string, len(items))
+	size := 0
+	for i, it := range items {
+		types[i] = it.Mime
+		size += blob.ItemSize(it)
+	}
+	return types, size
+}
+
+// RecordRefused journals a publish that a service refused before it reached
+// the hub, e.g. for a write rule or a quota.
+func (h *Hub) RecordRefused(items []*pb.ClipboardItem, cb, originID, source, eventID string, reason error) {
+	h.journalPublish(items, canonicalize(cb), originID, source, eventID, journal.OutcomeRefused, reason.Error())
+}
@@ -0,0 +1,59 @@
+package hub
+
+import (
+	"fmt"
+	"log/slog"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
+	"go.klb.dev/suffuse/internal/journal"
+)
+
+// SizeError reports content larger than Rules.MaxItemSize or
+// Rules.MaxPayloadSize.
+type SizeError struct {
+	// Item is the index of the item over MaxItemSize, or -1 when the
+	// content as a whole is over MaxPayloadSize.
+	Item  int
+	Mime  string
+	Size  int
+	Limit int
+}
+
+func (e *SizeError) Error() string {
+	if e.Item < 0 {
+		return fmt.Sprintf("content is %d bytes, over the %d byte payload limit", e.Size, e.Limit)
+	}
+	return fmt.Sprintf("item %d (%s) is %d bytes, over the %d byte item limit", e.Item, e.Mime, e.Size, e.Limit)
+}
+
+// CheckSize returns a *SizeError when items exceed the configured limits.
+// Referenced items count with the size of their content.
+func (h *Hub) CheckSize(items []*pb.ClipboardItem) error {
+	r := h.rules.Load()
+	total := 0
+	for i, it := range items {
+		n := blob.ItemSize(it)
+		if r.MaxItemSize > 0 && n > r.MaxItemSize {
+			return &SizeError{Item: i, Mime: it.Mime, Size: n, Limit: r.MaxItemSize}
+		}
+		total += n
+	}
+	if r.MaxPayloadSize > 0 && total > r.MaxPayloadSize {
+		return &SizeError{Item: -1, Size: total, Limit: r.MaxPayloadSize}
+	}
+	return nil
+}
+
+// oversized reports whether items exceed the configured limits, counting,
+// logging and journaling the refused publish.
+func (h *Hub) oversized(items []*pb.ClipboardItem, cb, originID, source, eventID string) bool {
+	err := h.CheckSize(items)
+	if err == nil {
+		return false
+	}
+	h.oversize.Add(1)
+	slog.Warn("publish refused", "clipboard", cb, "origin", originID, "source", source, "err", err)
+	h.journalPublish(items, cb, originID, source, eventID, journal.OutcomeOversized, err.Error())
+	return true
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 
 	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
 )
 
 // LogItems logs a clipboard event at DEBUG only.
@@ -19,14 +20,14 @@ func LogItems(event, source, clipboard string, items []*pb.ClipboardItem) {
 	}
 	slog.Debug(event, "source", source, "clipboard", clipboard, "types", mimes)
 	for _, it := range items {
-		if it.Mime == "text/plain" {
+		if it.Mime == "text/plain" && it.Ref == nil {
 			preview := string(it.Data)
 			if len(preview) > 120 {
 				preview = preview[:120] + "…"
 			}
 			slog.Debug("clipboard item", "mime", it.Mime, "preview", preview)
 		} else {
-			slog.Debug("clipboard item", "mime", it.Mime, "size_bytes", len(it.Data))
+			slog.Debug("clipboard item", "mime", it.Mime, "size_bytes", blob.ItemSize(it))
 		}
 	}
 }
@@ -0,0 +1,67 @@
+package hub
+
+import (
+	"fmt"
+	"log/slog"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// Mirror copies content published on one clipboard into another, e.g. the
+// text of "default" into "readonly-display" for a screen that should never
+// receive images.
+type Mirror struct {
+	// From and To name the source and destination clipboards.
+	From string `mapstructure:"from"`
+	To   string `mapstructure:"to"`
+	// Accepts restricts the MIME types copied (empty = all). Content with
+	// none of them is not mirrored.
+	Accepts []string `mapstructure:"accepts"`
+	// TwoWay also mirrors publishes on To into From, with the same Accepts.
+	TwoWay bool `mapstructure:"two-way"`
+}
+
+// CheckMirrors validates mirror rules before they are passed in Config.
+func CheckMirrors(mirrors []Mirror) error {
+	for i, m := range mirrors {
+		switch {
+		case m.From == "" || m.To == "":
+			return fmt.Errorf("mirror %d: from and to are required", i+1)
+		case canonicalize(m.From) == canonicalize(m.To):
+			return fmt.Errorf("mirror %d: %q is mirrored into itself", i+1, m.From)
+		}
+	}
+	return nil
+}
+
+// mirrorLocked stores the mirrored copies of items published on cb and
+// returns the peers that should receive them. Copies are not mirrored again,
+// so two-way rules and rules that form a cycle cannot loop. A copy that
+// already arrived under its event ID, e.g. from an upstream applying the
+// same rule, is skipped. Must be called with h.mu held.
+func (h *Hub) mirrorLocked(items []*pb.ClipboardItem, cb, originID, source string, r Relayed) []target {
+	var targets []target
+	for _, m := range h.rules.Load().Mirrors {
+		var dest string
+		switch {
+		case canonicalize(m.From) == cb:
+			dest = canonicalize(m.To)
+		case m.TwoWay && canonicalize(m.To) == cb:
+			dest = canonicalize(m.From)
+		default:
+			continue
+		}
+		copied := filterItems(items, m.Accepts)
+		if len(copied) == 0 {
+			continue
+		}
+		if r.EventID != "" && !h.seen.add(eventKey(r.EventID, dest, copied)) {
+			continue
+		}
+		slog.Debug("clipboard mirrored", "from", cb, "to", dest, "source", source, "items", len(copied))
+		// Like host clipboards, mirrors are kept per hub: BroadcastPeers
+		// replicate the original publish instead.
+		targets = append(targets, h.storeLocked(copied, dest, originID, source, r, false)...)
+	}
+	return targets
+}
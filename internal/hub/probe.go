@@ -0,0 +1,83 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// ProbeClipboardPrefix namespaces the hidden clipboards carrying end-to-end
+// probes: a server probing its federation publishes pings on
+// "_probe/<source>", and every hub they reach answers with a pong on the same
+// clipboard. Probe clipboards are relayed over federation links only; they
+// are kept out of host clipboards, webhooks, the cache, usage counters and
+// the journal.
+const ProbeClipboardPrefix = "_probe/"
+
+// ProbeMime is the MIME type of probe items. It is a text type so traffic
+// shaping never defers probes.
+const ProbeMime = "text/x-suffuse-probe"
+
+// Probe kinds.
+const (
+	ProbePing = "ping"
+	ProbePong = "pong"
+)
+
+// ProbeClipboard returns the clipboard source's probes travel on.
+func ProbeClipboard(source string) string {
+	return ProbeClipboardPrefix + source
+}
+
+// IsProbeClipboard reports whether cb carries probes.
+func IsProbeClipboard(cb string) bool {
+	return strings.HasPrefix(canonicalize(cb), ProbeClipboardPrefix)
+}
+
+// Probe is the content of a probe item.
+type Probe struct {
+	Kind string `json:"kind"`
+	// ID identifies the round a pong answers.
+	ID string `json:"id"`
+	// From is the source name of the hub that sent the probe.
+	From string `json:"from"`
+}
+
+// Items encodes p for publishing.
+func (p Probe) Items() []*pb.ClipboardItem {
+	data, _ := json.Marshal(p)
+	return []*pb.ClipboardItem{{Mime: ProbeMime, Data: data}}
+}
+
+// ParseProbe decodes the probe in items.
+func ParseProbe(items []*pb.ClipboardItem) (Probe, bool) {
+	for _, it := range items {
+		if it.Mime != ProbeMime {
+			continue
+		}
+		var p Probe
+		if json.Unmarshal(it.Data, &p) != nil || p.ID == "" {
+			return Probe{}, false
+		}
+		return p, true
+	}
+	return Probe{}, false
+}
+
+// probeResponderID is the origin of the pongs this hub publishes.
+const probeResponderID = "probe-responder"
+
+// answerProbe publishes a pong for a ping that arrived on cb from another
+// hub, in the ping's trace. Unnamed hubs cannot be told apart by the prober
+// and stay silent.
+func (h *Hub) answerProbe(ctx context.Context, items []*pb.ClipboardItem, cb string) {
+	p, ok := ParseProbe(items)
+	if !ok || p.Kind != ProbePing || h.cfg.Name == "" || p.From == h.cfg.Name {
+		return
+	}
+	slog.Debug("probe answered", "clipboard", cb, "from", p.From, "id", p.ID)
+	h.Publish(ctx, Probe{Kind: ProbePong, ID: p.ID, From: h.cfg.Name}.Items(), cb, probeResponderID, h.cfg.Name)
+}
@@ -0,0 +1,64 @@
+package hub
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+
+	"go.klb.dev/suffuse/internal/quota"
+)
+
+// WriteRule makes clipboards read-only for everyone but the listed writers,
+// e.g. an "announcements" clipboard that only the ops host may set. The hub
+// does not enforce rules itself; the services accepting publishes from
+// clients and federation links check Writable.
+type WriteRule struct {
+	// Clipboards are path.Match patterns naming the protected clipboards.
+	Clipboards []string `mapstructure:"clipboards"`
+	// Sources may publish to them. Sources are self-declared by clients,
+	// so combine them with per-peer tokens where that matters.
+	Sources []string `mapstructure:"sources"`
+	// Tokens names the per-peer tokens that may publish to them.
+	Tokens []string `mapstructure:"tokens"`
+}
+
+// CheckWriteRules validates write rules before they are passed in Config.
+func CheckWriteRules(rules []WriteRule) error {
+	for i, r := range rules {
+		if len(r.Clipboards) == 0 {
+			return fmt.Errorf("protected clipboards %d: no clipboards given", i+1)
+		}
+		for _, pattern := range r.Clipboards {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("protected clipboards %d: pattern %q: %w", i+1, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ErrReadOnly describes a publish refused because Writable is false.
+var ErrReadOnly = errors.New("clipboard is read-only for its source")
+
+// Writable reports whether source, authenticated with the per-peer token
+// named token (empty for none), may publish to clipboard name. Every rule
+// covering the clipboard must list the source or the token.
+func (h *Hub) Writable(name, source, token string) bool {
+	cb := canonicalize(name)
+	for _, r := range h.rules.Load().WriteRules {
+		if !slices.ContainsFunc(r.Clipboards, func(pattern string) bool {
+			ok, _ := path.Match(pattern, cb)
+			return ok
+		}) {
+			continue
+		}
+		if !(source != "" && slices.Contains(r.Sources, source)) && !(token != "" && slices.Contains(r.Tokens, token)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Quota returns the per-source quotas from Config; nil admits everything.
+func (h *Hub) Quota() *quota.Limiter { return h.cfg.Quota }
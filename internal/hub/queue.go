@@ -0,0 +1,131 @@
+package hub
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBlockTimeout is how long Queue.Put waits for room under
+// SlowConsumerBlock when Config.BlockTimeout is zero.
+const DefaultBlockTimeout = time.Second
+
+// QueueSize sets the capacity of the queues of one kind of peer, named by
+// the subsystem its drops are counted under, or of every kind.
+type QueueSize struct {
+	// Subsystem is one of the Drop constants; empty means every kind of
+	// peer not named by another size.
+	Subsystem string
+	Size      int
+}
+
+// queueSubsystems are the subsystems whose peers hold a Queue.
+var queueSubsystems = []string{DropWatch, DropLocal, DropFederationUpstream, DropFederationDownstream, DropWebhook, DropProcessor}
+
+// ParseQueueSize parses a capacity such as "256", applying to every kind of
+// peer, or "<subsystem>=<capacity>", e.g. "watch=256".
+func ParseQueueSize(s string) (QueueSize, error) {
+	subsystem, size, ok := strings.Cut(s, "=")
+	if !ok {
+		subsystem, size = "", s
+	}
+	subsystem = strings.TrimSpace(subsystem)
+	n, err := strconv.Atoi(strings.TrimSpace(size))
+	if err != nil || n <= 0 {
+		return QueueSize{}, fmt.Errorf("queue size %q: want a positive number such as 256, or subsystem=number", s)
+	}
+	if ok && !slices.Contains(queueSubsystems, subsystem) {
+		return QueueSize{}, fmt.Errorf("queue size %q: subsystem must be one of %s", s, strings.Join(queueSubsystems, ", "))
+	}
+	return QueueSize{Subsystem: subsystem, Size: n}, nil
+}
+
+// Queue is the bounded queue between the hub and a peer that handles events
+// on its own goroutine. Put applies Config.SlowConsumer when it is full.
+type Queue struct {
+	ch        chan Event
+	subsystem string
+	policy    SlowConsumerPolicy
+	timeout   time.Duration
+}
+
+// NewQueue returns a queue for a peer whose drops are counted under
+// subsystem, holding size events unless Config.QueueSizes says otherwise.
+func (h *Hub) NewQueue(subsystem string, size int) *Queue {
+	size = h.queueSize(subsystem, size)
+	timeout := h.cfg.BlockTimeout
+	if timeout <= 0 {
+		timeout = DefaultBlockTimeout
+	}
+	return &Queue{
+		ch:        make(chan Event, size),
+		subsystem: subsystem,
+		policy:    h.cfg.SlowConsumer,
+		timeout:   timeout,
+	}
+}
+
+// queueSize returns the capacity for the queues of subsystem: that of the
+// first size naming it, else that of a size for every kind of peer, else
+// def.
+func (h *Hub) queueSize(subsystem string, def int) int {
+	all := 0
+	for _, s := range h.cfg.QueueSizes {
+		if s.Subsystem == subsystem {
+			return s.Size
+		}
+		if s.Subsystem == "" && all == 0 {
+			all = s.Size
+		}
+	}
+	if all > 0 {
+		return all
+	}
+	return def
+}
+
+// Put queues ev. When the queue is full it drops ev, or under
+// SlowConsumerDropOldest the oldest queued event to make room, or under
+// SlowConsumerBlock waits for room until the timeout runs out; the error
+// wraps ErrPeerFull. It never blocks otherwise.
+func (q *Queue) Put(ev Event) error {
+	select {
+	case q.ch <- ev:
+		return nil
+	default:
+	}
+	switch q.policy {
+	case SlowConsumerDropOldest:
+		for {
+			select {
+			case q.ch <- ev:
+				return &fullError{subsystem: q.subsystem, evicted: true}
+			default:
+			}
+			select {
+			case <-q.ch:
+			default:
+			}
+		}
+	case SlowConsumerBlock:
+		t := time.NewTimer(q.timeout)
+		defer t.Stop()
+		select {
+		case q.ch <- ev:
+			return nil
+		case <-t.C:
+		}
+	}
+	return QueueFull(q.subsystem)
+}
+
+// C returns the channel the peer receives queued events from.
+func (q *Queue) C() <-chan Event { return q.ch }
+
+// Len returns the number of events queued.
+func (q *Queue) Len() int { return len(q.ch) }
+
+// Cap returns the capacity of the queue.
+func (q *Queue) Cap() int { return cap(q.ch) }
@@ -0,0 +1,52 @@
+package hub
+
+// Rules are the hub's policies that the server can change while it runs,
+// e.g. when its configuration file is reloaded. Publishes check the rules in
+// force when they arrive; content already stored is left as it is.
+type Rules struct {
+	// Mirrors copy content published on one clipboard into another; see
+	// Mirror. Validate them with CheckMirrors.
+	Mirrors []Mirror
+
+	// WriteRules limit who may publish to some clipboards; see WriteRule.
+	// Validate them with CheckWriteRules.
+	WriteRules []WriteRule
+
+	// ContentFilters drop or redact copies by their text; see
+	// ContentFilter. Validate them with CheckContentFilters.
+	ContentFilters []ContentFilter
+
+	// URLRewrites rewrite the links in copied text; see URLRewrite.
+	// Validate them with CheckURLRewrites.
+	URLRewrites []URLRewrite
+
+	// Snippets maps lower-case keys to the text copies of the key to
+	// SnippetClipboard are expanded to; see ExpandSnippet.
+	Snippets map[string]string
+
+	// Derive lists the text types (text/plain, text/markdown, text/html)
+	// added to copies that lack them, converted from the text
+	// representation they carry. Validate it with CheckDerive.
+	Derive []string
+
+	// Transcode converts text items that are not UTF-8 to UTF-8, recording
+	// the character set they were in.
+	Transcode bool
+
+	// Types limits the item types relayed; see TypePolicy. Validate it with
+	// CheckTypePolicy.
+	Types TypePolicy
+
+	// MaxItemSize and MaxPayloadSize limit the size of a single item and of
+	// all items of a publish in bytes; larger publishes are refused whatever
+	// peer they come from. Zero means no limit. See CheckSize.
+	MaxItemSize    int
+	MaxPayloadSize int
+}
+
+// SetRules replaces the hub's rules. The rules must have been validated like
+// those of Config; publishes in flight finish under the rules they started
+// with.
+func (h *Hub) SetRules(r Rules) {
+	h.rules.Store(&r)
+}
@@ -0,0 +1,61 @@
+package hub
+
+import (
+	"sort"
+	"time"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// StoredClipboard is the content the hub holds for one clipboard.
+type StoredClipboard struct {
+	Clipboard string
+	Source    string
+	Items     []*pb.ClipboardItem
+	UpdatedAt time.Time
+}
+
+// Snapshot returns the content of every clipboard, most recently updated
+// first, and a version that changes whenever that content does. Probe
+// clipboards are left out. Items may be blob references; see Resolve.
+func (h *Hub) Snapshot() ([]StoredClipboard, uint64) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]StoredClipboard, 0, len(h.latest))
+	for cb, items := range h.latest {
+		if IsProbeClipboard(cb) {
+			continue
+		}
+		out = append(out, StoredClipboard{
+			Clipboard: cb,
+			Source:    h.latestSource[cb],
+			Items:     items,
+			UpdatedAt: h.latestAt[cb],
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	return out, h.version
+}
+
+// Restore loads previously saved clipboards, e.g. from a cache written before
+// a restart. Clipboards that already hold content are left alone, and peers
+// are not notified: they receive restored content when they register, like
+// any other stored content.
+func (h *Hub) Restore(saved []StoredClipboard) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sc := range saved {
+		cb := canonicalize(sc.Clipboard)
+		if _, ok := h.latest[cb]; ok || len(sc.Items) == 0 {
+			continue
+		}
+		items := sc.Items
+		if h.cfg.Blobs != nil {
+			items = h.cfg.Blobs.Externalize(items)
+		}
+		h.latest[cb] = items
+		h.latestSource[cb] = sc.Source
+		h.latestAt[cb] = sc.UpdatedAt
+		h.version++
+	}
+}
@@ -0,0 +1,41 @@
+package hub
+
+import (
+	"log/slog"
+	"strings"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// SnippetClipboard is the clipboard whose copies are expanded from
+// Rules.Snippets: copying a snippet's key to it publishes the snippet's
+// text instead, to every peer of the clipboard.
+const SnippetClipboard = "snippet"
+
+// ExpandSnippet returns the items of a copy from source to clipboard name
+// with a snippet key replaced by the snippet's text. Only copies to
+// SnippetClipboard whose text/plain item, without surrounding space, is a
+// key of Rules.Snippets (compared case-insensitively) are expanded; the
+// others, and their items, are returned as they are. Like content filters,
+// snippets are not applied by the hub itself: the local peer and the
+// services accepting copies from clients call ExpandSnippet before
+// publishing.
+func (h *Hub) ExpandSnippet(name, source string, items []*pb.ClipboardItem) []*pb.ClipboardItem {
+	snippets := h.rules.Load().Snippets
+	if len(snippets) == 0 || canonicalize(name) != SnippetClipboard {
+		return items
+	}
+	for _, it := range items {
+		if it.Mime != "text/plain" {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(string(it.Data)))
+		text, ok := snippets[key]
+		if !ok {
+			return items
+		}
+		slog.Info("snippet expanded", "key", key, "source", source)
+		return []*pb.ClipboardItem{{Mime: "text/plain", Data: []byte(text)}}
+	}
+	return items
+}
@@ -0,0 +1,75 @@
+package hub
+
+import (
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
+)
+
+// Stats is a snapshot of hub-wide counters for metrics export.
+type Stats struct {
+	// Publishes is the number of Publish calls since the hub was created.
+	Publishes uint64
+	// PublishedBytes is the total size of all published items.
+	PublishedBytes uint64
+	// Duplicates is the number of publishes suppressed by
+	// Config.DedupWindow or because the same event had already arrived over
+	// another federation link; they are included in Publishes.
+	Duplicates uint64
+	// HopLimited is the number of events not delivered to a RelayPeer
+	// because their hop limit was spent.
+	HopLimited uint64
+	// Loops is the number of events from federation links dropped because
+	// their origin path already included this hub.
+	Loops uint64
+	// Oversized is the number of publishes refused for exceeding
+	// Rules.MaxItemSize or Rules.MaxPayloadSize.
+	Oversized uint64
+	// Clipboards is the number of clipboards currently holding content,
+	// including host clipboards.
+	Clipboards int
+	// Usage is the content moved through the hub; see ClipboardUsage for the
+	// per-clipboard breakdown.
+	Usage Usage
+	// Blobs describes the blob store; zero when referenced transfer is
+	// disabled.
+	Blobs blob.Stats
+}
+
+// Stats returns the current hub-wide counters.
+func (h *Hub) Stats() Stats {
+	h.mu.RLock()
+	clipboards := 0
+	for cb := range h.latest {
+		if !IsProbeClipboard(cb) {
+			clipboards++
+		}
+	}
+	h.mu.RUnlock()
+	h.usageMu.Lock()
+	usage := h.totalUsage
+	h.usageMu.Unlock()
+	stats := Stats{
+		Publishes:      h.publishes.Load(),
+		PublishedBytes: h.publishedBytes.Load(),
+		Duplicates:     h.duplicates.Load(),
+		HopLimited:     h.hopLimited.Load(),
+		Loops:          h.loops.Load(),
+		Oversized:      h.oversize.Load(),
+		Clipboards:     clipboards,
+		Usage:          usage,
+	}
+	if h.cfg.Blobs != nil {
+		stats.Blobs = h.cfg.Blobs.Stats()
+	}
+	return stats
+}
+
+// countPublish records one publish of items in the hub counters.
+func (h *Hub) countPublish(items []*pb.ClipboardItem) {
+	var n uint64
+	for _, it := range items {
+		n += uint64(blob.ItemSize(it))
+	}
+	h.publishes.Add(1)
+	h.publishedBytes.Add(n)
+}
@@ -0,0 +1,57 @@
+package hub
+
+import (
+	"log/slog"
+	"mime"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/charset"
+	"go.klb.dev/suffuse/internal/signing"
+)
+
+// transcodeText returns items with the text items that are not UTF-8
+// converted to UTF-8, each recording the character set it was in as
+// original_charset, when Rules.Transcode is set. The character set is the
+// one a charset parameter of the item's type declares, which then becomes
+// utf-8, or else the one detected, so text a legacy application put on the
+// clipboard in its code page reaches peers readable. Signed copies, whose
+// signature the conversion would break, and items held as blob references
+// are left alone. items is not modified.
+func (h *Hub) transcodeText(items []*pb.ClipboardItem, cb, source string) []*pb.ClipboardItem {
+	if !h.rules.Load().Transcode || IsProbeClipboard(cb) {
+		return items
+	}
+	if slices.ContainsFunc(items, func(it *pb.ClipboardItem) bool { return it.Mime == signing.MIME }) {
+		return items
+	}
+	var out []*pb.ClipboardItem
+	for i, it := range items {
+		mediaType, params, err := mime.ParseMediaType(it.Mime)
+		if err != nil || !strings.HasPrefix(mediaType, "text/") || len(it.Data) == 0 || it.Ref != nil || it.OriginalCharset != "" {
+			continue
+		}
+		data, from := charset.ToUTF8(it.Data, params["charset"])
+		if from == "" {
+			continue
+		}
+		conv := proto.Clone(it).(*pb.ClipboardItem)
+		conv.Data, conv.OriginalCharset, conv.Sha256 = data, from, ""
+		if _, ok := params["charset"]; ok {
+			params["charset"] = charset.UTF8
+			conv.Mime = mime.FormatMediaType(mediaType, params)
+		}
+		if out == nil {
+			out = slices.Clone(items)
+		}
+		out[i] = conv
+		slog.Debug("text converted to UTF-8", "clipboard", cb, "source", source, "type", it.Mime, "charset", from)
+	}
+	if out == nil {
+		return items
+	}
+	return out
+}
@@ -0,0 +1,111 @@
+package hub
+
+import (
+	"fmt"
+	"log/slog"
+	"mime"
+	"path"
+	"slices"
+	"strings"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/journal"
+	"go.klb.dev/suffuse/internal/signing"
+)
+
+// TypePolicy limits the item types the hub relays, whoever publishes them:
+// clients, the local clipboard and federation links alike. Unlike the types
+// a peer accepts, which only decide what that peer receives, items of a type
+// the policy bans are removed before content is stored, so they reach no
+// peer, history or upstream.
+type TypePolicy struct {
+	// Allow are path.Match patterns of the types relayed, e.g. "text/*";
+	// empty allows every type not denied.
+	Allow []string
+	// Deny are patterns of types never relayed, whatever Allow says.
+	Deny []string
+}
+
+// markerTypes carry no content of their own, only a mark on the items they
+// come with (clip.SensitiveMime) or their signature, so Allow does not remove
+// them; Deny does.
+var markerTypes = []string{"x-kde-passwordManagerHint", signing.MIME}
+
+// CheckTypePolicy validates the patterns of p before it is passed in Config.
+func CheckTypePolicy(p TypePolicy) error {
+	for _, pattern := range slices.Concat(p.Allow, p.Deny) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("type pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Allowed reports whether items of type mimeType are relayed. Parameters
+// such as charset are ignored and the type is matched case-insensitively.
+func (p TypePolicy) Allowed(mimeType string) bool {
+	t := strings.ToLower(mimeType)
+	if mt, _, err := mime.ParseMediaType(mimeType); err == nil {
+		t = mt
+	}
+	match := func(pattern string) bool {
+		ok, _ := path.Match(strings.ToLower(pattern), t)
+		return ok
+	}
+	if slices.ContainsFunc(p.Deny, match) {
+		return false
+	}
+	return len(p.Allow) == 0 || slices.ContainsFunc(p.Allow, match) || slices.Contains(markerTypes, mimeType)
+}
+
+// TypeError reports a copy none of whose items is of a type the hub relays.
+type TypeError struct {
+	Types []string
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("no item is of a type this server relays (got %s)", strings.Join(e.Types, ", "))
+}
+
+// CheckTypes returns items without those of a type Rules.Types bans, or a
+// *TypeError when none remain. items is not modified.
+func (h *Hub) CheckTypes(items []*pb.ClipboardItem) ([]*pb.ClipboardItem, error) {
+	p := h.rules.Load().Types
+	if len(p.Allow) == 0 && len(p.Deny) == 0 {
+		return items, nil
+	}
+	out := slices.DeleteFunc(slices.Clone(items), func(it *pb.ClipboardItem) bool { return !p.Allowed(it.Mime) })
+	if !slices.ContainsFunc(out, func(it *pb.ClipboardItem) bool { return !slices.Contains(markerTypes, it.Mime) }) {
+		types := make([]string, 0, len(items))
+		for _, it := range items {
+			types = append(types, it.Mime)
+		}
+		return nil, &TypeError{Types: types}
+	}
+	return out, nil
+}
+
+// allowedTypes returns items without those of a banned type, logging what was
+// removed, or nil after journaling the publish as refused when none remain.
+// Probes are exempt.
+func (h *Hub) allowedTypes(items []*pb.ClipboardItem, cb, originID, source, eventID string) []*pb.ClipboardItem {
+	if IsProbeClipboard(cb) {
+		return items
+	}
+	out, err := h.CheckTypes(items)
+	if err != nil {
+		slog.Warn("publish refused", "clipboard", cb, "origin", originID, "source", source, "err", err)
+		h.journalPublish(items, cb, originID, source, eventID, journal.OutcomeRefused, err.Error())
+		return nil
+	}
+	if len(out) < len(items) {
+		var removed []string
+		for _, it := range items {
+			if !slices.Contains(out, it) {
+				removed = append(removed, it.Mime)
+			}
+		}
+		slog.Info("items of banned types removed", "clipboard", cb, "origin", originID, "source", source, "types", removed)
+	}
+	return out
+}
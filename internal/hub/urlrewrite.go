@@ -0,0 +1,199 @@
+package hub
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/signing"
+)
+
+// Stages a URL rewrite applies at.
+const (
+	// RewriteOnPublish rewrites copies made on this server: those of the
+	// local clipboard and of clients.
+	RewriteOnPublish = "publish"
+	// RewriteOnReceive rewrites content arriving over a federation link, so
+	// each site can map links to the hosts it reaches them by.
+	RewriteOnReceive = "receive"
+)
+
+// urlPattern finds http and https URLs in text. A URL ends at white space,
+// quotes or angle brackets, and trailing punctuation is left out of it.
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"']*[^\s<>"'.,;:!?)\]]`)
+
+// URLRewrite rewrites the links in copied text, e.g. to strip tracking
+// parameters or to map an internal hostname to the name another site knows
+// the host by. Like content filters, rewrites are not applied by the hub
+// itself: the local peer, the services accepting copies from clients and the
+// federation links call RewriteURLs before publishing.
+type URLRewrite struct {
+	// Name identifies the rule in logs.
+	Name string `mapstructure:"name"`
+	// Hosts are path.Match patterns of the hosts whose URLs the rule
+	// rewrites, e.g. "*.corp.internal"; empty means every host.
+	Hosts []string `mapstructure:"hosts"`
+	// StripParams are path.Match patterns of the query parameters removed,
+	// e.g. "utm_*".
+	StripParams []string `mapstructure:"strip-params"`
+	// Host, when set, replaces the host of the URL. A URL's port is kept
+	// unless Host names one.
+	Host string `mapstructure:"host"`
+	// On is RewriteOnPublish (the default) or RewriteOnReceive.
+	On string `mapstructure:"on"`
+	// Clipboards are path.Match patterns limiting the rule to some
+	// clipboards; empty means every clipboard.
+	Clipboards []string `mapstructure:"clipboards"`
+}
+
+// CheckURLRewrites validates rules before they are passed in Rules.
+func CheckURLRewrites(rules []URLRewrite) error {
+	for i := range rules {
+		r := &rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rewrite %d", i+1)
+		}
+		switch r.On {
+		case "":
+			r.On = RewriteOnPublish
+		case RewriteOnPublish, RewriteOnReceive:
+		default:
+			return fmt.Errorf("url rewrite %q: on must be %q or %q", r.Name, RewriteOnPublish, RewriteOnReceive)
+		}
+		if len(r.StripParams) == 0 && r.Host == "" {
+			return fmt.Errorf("url rewrite %q: neither strip-params nor host given", r.Name)
+		}
+		if r.Host != "" {
+			if u, err := url.Parse("http://" + r.Host); err != nil || u.Host != r.Host || u.Path != "" {
+				return fmt.Errorf("url rewrite %q: host %q is not a host name", r.Name, r.Host)
+			}
+		}
+		r.Hosts = lowerAll(r.Hosts)
+		for _, patterns := range [][]string{r.Hosts, r.StripParams, r.Clipboards} {
+			for _, pattern := range patterns {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return fmt.Errorf("url rewrite %q: pattern %q: %w", r.Name, pattern, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func lowerAll(ss []string) []string {
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = strings.ToLower(s)
+	}
+	return out
+}
+
+// matchAny reports whether s matches one of patterns.
+func matchAny(patterns []string, s string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		ok, _ := path.Match(pattern, s)
+		return ok
+	})
+}
+
+// RewriteURLs applies the URL rewrites of stage on covering clipboard name to
+// the text/plain items of content from source, returning the items to
+// publish. items is not modified. Signed copies are returned as they are, as
+// a rewrite would break their signature, and so are items held as blob
+// references.
+func (h *Hub) RewriteURLs(name, source, on string, items []*pb.ClipboardItem) []*pb.ClipboardItem {
+	cb := canonicalize(name)
+	var rules []URLRewrite
+	for _, r := range h.rules.Load().URLRewrites {
+		if r.On == on && (len(r.Clipboards) == 0 || matchAny(r.Clipboards, cb)) {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 || slices.ContainsFunc(items, func(it *pb.ClipboardItem) bool { return it.Mime == signing.MIME }) {
+		return items
+	}
+	out, cloned := items, false
+	var applied []string
+	for i, it := range items {
+		if it.Mime != "text/plain" || len(it.Data) == 0 || it.Ref != nil {
+			continue
+		}
+		data := urlPattern.ReplaceAllFunc(it.Data, func(link []byte) []byte {
+			rewritten, names := rewriteURL(string(link), rules)
+			for _, n := range names {
+				if !slices.Contains(applied, n) {
+					applied = append(applied, n)
+				}
+			}
+			return []byte(rewritten)
+		})
+		if string(data) == string(it.Data) {
+			continue
+		}
+		if !cloned {
+			out, cloned = slices.Clone(items), true
+		}
+		out[i] = &pb.ClipboardItem{Mime: it.Mime, OriginalCharset: it.OriginalCharset, Data: data}
+	}
+	if cloned {
+		slog.Info("links rewritten", "rules", applied, "clipboard", cb, "source", source, "on", on)
+	}
+	return out
+}
+
+// rewriteURL applies rules to link in turn and returns the result and the
+// names of the rules that changed it. A link that does not parse is returned
+// as it is.
+func rewriteURL(link string, rules []URLRewrite) (string, []string) {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link, nil
+	}
+	var names []string
+	for _, r := range rules {
+		if len(r.Hosts) > 0 && !matchAny(r.Hosts, strings.ToLower(u.Hostname())) {
+			continue
+		}
+		changed := false
+		if len(r.StripParams) > 0 && u.RawQuery != "" {
+			// The query is filtered as written, keeping the order and
+			// encoding of the parameters that stay.
+			params := strings.Split(u.RawQuery, "&")
+			kept := slices.DeleteFunc(slices.Clone(params), func(p string) bool {
+				key, _, _ := strings.Cut(p, "=")
+				if k, err := url.QueryUnescape(key); err == nil {
+					key = k
+				}
+				return matchAny(r.StripParams, key)
+			})
+			if len(kept) < len(params) {
+				u.RawQuery = strings.Join(kept, "&")
+				u.ForceQuery = false
+				changed = true
+			}
+		}
+		if r.Host != "" {
+			host := r.Host
+			if _, _, err := net.SplitHostPort(host); err != nil && u.Port() != "" {
+				host = net.JoinHostPort(strings.Trim(host, "[]"), u.Port())
+			}
+			if host != u.Host {
+				u.Host = host
+				changed = true
+			}
+		}
+		if changed {
+			names = append(names, r.Name)
+		}
+	}
+	if len(names) == 0 {
+		return link, nil
+	}
+	return u.String(), names
+}
@@ -0,0 +1,86 @@
+package hub
+
+import (
+	"maps"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// Usage counts clipboard content moved through the hub, in bytes. Only
+// content that actually travels is counted: a blob reference costs nothing
+// until its content is fetched, and metadata-only deliveries are free.
+type Usage struct {
+	// In is content received: published by peers and clients, or fetched
+	// from an upstream server.
+	In uint64
+	// Out is content sent: delivered to peers, returned by Paste, or served
+	// by Fetch.
+	Out uint64
+}
+
+// MetadataPeer is an optional interface for peers that forward only the MIME
+// types of the events they receive, such as metadata-only watchers. Their
+// deliveries are not counted as content sent.
+type MetadataPeer interface {
+	Peer
+	MetadataOnly() bool
+}
+
+// ClipboardUsage returns the content moved per clipboard since the hub was
+// created, including clipboards that have since been cleared.
+func (h *Hub) ClipboardUsage() map[string]Usage {
+	h.usageMu.Lock()
+	defer h.usageMu.Unlock()
+	return maps.Clone(h.clipboardUsage)
+}
+
+// CountServed records n bytes of content sent outside event delivery, e.g.
+// by Paste for clipboard or by Fetch for a blob (clipboard empty).
+func (h *Hub) CountServed(clipboard string, n int) {
+	h.countUsage("", clipboard, 0, n)
+}
+
+// CountReceived records n bytes of content received from peer outside
+// Publish, e.g. blobs the federation link fetches from upstream.
+func (h *Hub) CountReceived(peerID string, n int) {
+	h.countUsage(peerID, "", n, 0)
+}
+
+// countUsage adds to the totals and to the peer and clipboard counters when
+// they are named. Counters of unregistered peers are not kept, nor probe
+// traffic.
+func (h *Hub) countUsage(peerID, clipboard string, in, out int) {
+	if in == 0 && out == 0 || IsProbeClipboard(clipboard) {
+		return
+	}
+	h.usageMu.Lock()
+	defer h.usageMu.Unlock()
+	add := func(u Usage) Usage {
+		u.In += uint64(in)
+		u.Out += uint64(out)
+		return u
+	}
+	h.totalUsage = add(h.totalUsage)
+	if clipboard != "" {
+		h.clipboardUsage[clipboard] = add(h.clipboardUsage[clipboard])
+	}
+	if u, ok := h.peerUsage[peerID]; ok {
+		h.peerUsage[peerID] = add(u)
+	}
+}
+
+// peerUsageOf returns the content moved for a registered peer.
+func (h *Hub) peerUsageOf(id string) Usage {
+	h.usageMu.Lock()
+	defer h.usageMu.Unlock()
+	return h.peerUsage[id]
+}
+
+// PayloadSize returns the size of the content carried inline by items.
+func PayloadSize(items []*pb.ClipboardItem) int {
+	n := 0
+	for _, it := range items {
+		n += len(it.Data)
+	}
+	return n
+}
@@ -3,12 +3,16 @@
 // instead of opening their own TCP connections to the server.
 //
 // The IPC channel is plain gRPC served over a Unix domain socket, using the
-// same ClipboardService proto as the TCP server. The client daemon listens on
-// the socket; CLI sub-commands probe for it and fall back to direct TCP if it
-// is absent.
+// same ClipboardService proto as the TCP server, alongside pipelined copy and
+// paste requests (see Split and DialPipe). The client daemon listens on the
+// socket; CLI sub-commands probe for it and fall back to direct TCP if it is
+// absent.
 package ipc
 
 import (
+	"fmt"
+	"io"
+	"log/slog"
 	"net"
 	"os"
 	"path/filepath"
@@ -40,11 +44,61 @@ func IsRunning() bool {
 	return true
 }
 
-// Listen creates and returns a net.Listener on the IPC socket path, removing
-// any stale socket file first.
+// LockPath returns the path of the lock file next to the IPC socket, which
+// the server listening on the socket holds and writes its process ID to.
+func LockPath() string {
+	return SocketPath() + ".lock"
+}
+
+// RunningError is returned by Listen when another process holds the lock of
+// the IPC socket, i.e. another server is running on this host.
+type RunningError struct {
+	Lock string
+	PID  int // 0 when the lock file names none
+}
+
+func (e *RunningError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("another suffuse server (pid %d) holds %s", e.PID, e.Lock)
+	}
+	return fmt.Sprintf("another suffuse server holds %s", e.Lock)
+}
+
+// Listen creates and returns a net.Listener on the IPC socket path. It first
+// takes the lock file (LockPath), held until the listener is closed or the
+// process exits, and fails with a *RunningError while another server holds
+// it rather than removing that server's socket. A socket a crashed server
+// left behind is removed: the lock died with it. Only the user running the
+// server may connect; see ownerOnly.
 func Listen() (net.Listener, error) {
 	path := SocketPath()
-	// Remove stale socket from a previous (crashed) run.
-	_ = os.Remove(path)
-	return net.Listen("unix", path)
+	lock, err := acquire(LockPath())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Lstat(path); err == nil {
+		slog.Info("removing IPC socket left by a previous run", "path", path)
+		_ = os.Remove(path)
+	}
+	ln, err := net.Listen("unix", path)
+	if err == nil {
+		ln, err = ownerOnly(ln, path)
+	}
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return &lockedListener{Listener: ln, lock: lock}, nil
+}
+
+// lockedListener releases the socket's lock when it is closed.
+type lockedListener struct {
+	net.Listener
+	lock io.Closer
+}
+
+func (l *lockedListener) Close() error {
+	err := l.Listener.Close()
+	l.lock.Close()
+	return err
 }
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package ipc
+
+import "io"
+
+// acquire takes no lock where flock is unavailable. On Windows, the one
+// such platform suffuse runs on, the IPC channel is not implemented yet.
+func acquire(string) (io.Closer, error) {
+	return io.NopCloser(nil), nil
+}
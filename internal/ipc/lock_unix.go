@@ -0,0 +1,38 @@
+//go:build unix
+
+package ipc
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// acquire takes an exclusive lock on the file at path, creating it, and
+// records this process's ID in it. The lock is released when the returned
+// file is closed or the process exits, however it exits, so a crashed
+// server leaves no lock behind. The file itself is never removed: a process
+// could be waiting to lock the old one while another creates a new one.
+func acquire(path string) (io.Closer, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		defer f.Close()
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			data, _ := io.ReadAll(f)
+			pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+			return nil, &RunningError{Lock: path, PID: pid}
+		}
+		return nil, err
+	}
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return f, nil
+}
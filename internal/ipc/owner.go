@@ -0,0 +1,46 @@
+package ipc
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+)
+
+// ownerOnly restricts the socket at path to the user running the server,
+// whose calls over it are trusted without a token: it makes the socket
+// file readable and writable by its owner only, as the shared temporary
+// directory it normally lives in does not, and closes connections whose
+// peer runs as another user, which covers the moment before the mode is
+// set and file systems that ignore it.
+func ownerOnly(ln net.Listener, path string) (net.Listener, error) {
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("restrict IPC socket: %w", err)
+	}
+	return &ownerListener{Listener: ln, uid: os.Getuid()}, nil
+}
+
+type ownerListener struct {
+	net.Listener
+	uid int
+}
+
+func (l *ownerListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		uid, err := peerUID(c)
+		switch {
+		case err != nil:
+			slog.Warn("IPC connection refused: peer credentials unavailable", "err", err)
+		case uid != l.uid:
+			slog.Warn("IPC connection from another user refused", "uid", uid)
+		default:
+			return c, nil
+		}
+		_ = c.Close()
+	}
+}
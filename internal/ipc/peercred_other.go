@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package ipc
+
+import (
+	"net"
+	"os"
+)
+
+// peerUID reports the server's own user: without a peer credential check
+// on this platform, the socket's file mode is what keeps other users out.
+func peerUID(net.Conn) (int, error) {
+	return os.Getuid(), nil
+}
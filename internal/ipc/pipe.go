@@ -0,0 +1,329 @@
+package ipc
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/tracing"
+)
+
+// Besides gRPC, the socket answers pipelined requests: a lighter protocol for
+// the one-shot copies and pastes of CLI invocations, which editors may run
+// dozens of times in a row. A connection opens with pipeMagic, which the
+// server echoes, and then carries any number of requests, answered in order:
+//
+//	request:  op byte, uvarint length, CopyRequest or PasteRequest
+//	response: gRPC status code byte, uvarint length, response or error message
+//
+// A request is a single write and read on a socket that is already connected,
+// with none of HTTP/2's connection setup, and is answered from the running
+// server's state, including its connections upstream.
+
+// pipeMagic opens a pipelined connection. It differs from the HTTP/2 client
+// preface gRPC connections open with from the first byte.
+const pipeMagic = "SUFFUSE-PIPE/1\n"
+
+// MaxPipeRequest is the largest request a pipelined connection carries;
+// larger copies go over gRPC, in chunks.
+const MaxPipeRequest = 4 << 20
+
+// sniffTimeout bounds the wait for a new connection's first bytes.
+const sniffTimeout = 5 * time.Second
+
+const (
+	opCopy  byte = 'c'
+	opPaste byte = 'p'
+)
+
+// ErrPipeUnsupported is returned by DialPipe when the server on the socket
+// predates pipelined requests; gRPC still reaches it.
+var ErrPipeUnsupported = errors.New("ipc: server does not answer pipelined requests")
+
+// Handler answers pipelined requests, as the ClipboardService does. Calls
+// carry the connection's address as their gRPC peer, so they are treated as
+// calls over the IPC socket.
+type Handler interface {
+	Copy(context.Context, *pb.CopyRequest) (*pb.CopyResponse, error)
+	Paste(context.Context, *pb.PasteRequest) (*pb.PasteResponse, error)
+}
+
+// Split serves the pipelined connections accepted by ln with h, through the
+// interceptors in intercept, first to last, as a gRPC server chains them,
+// and returns a listener of the others, for a gRPC server. Nil interceptors
+// are skipped. Closing it closes ln.
+func Split(ln net.Listener, h Handler, intercept ...grpc.UnaryServerInterceptor) net.Listener {
+	s := &splitListener{
+		Listener:  ln,
+		h:         h,
+		intercept: intercept,
+		conns:     make(chan net.Conn),
+		done:      make(chan struct{}),
+	}
+	go s.acceptLoop()
+	return s
+}
+
+type splitListener struct {
+	net.Listener
+	h         Handler
+	intercept []grpc.UnaryServerInterceptor
+	conns     chan net.Conn
+	done      chan struct{} // closed, with err set, when ln fails
+	err       error
+}
+
+func (s *splitListener) acceptLoop() {
+	for {
+		c, err := s.Listener.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			s.err = err
+			close(s.done)
+			return
+		}
+		go s.sniff(c)
+	}
+}
+
+// sniff reads the first bytes of c and either serves it or passes it on.
+func (s *splitListener) sniff(c net.Conn) {
+	r := bufio.NewReader(c)
+	_ = c.SetReadDeadline(time.Now().Add(sniffTimeout))
+	head, err := r.Peek(len(pipeMagic))
+	_ = c.SetReadDeadline(time.Time{})
+	if err == nil && string(head) == pipeMagic {
+		_, _ = r.Discard(len(pipeMagic))
+		s.serve(c, r)
+		return
+	}
+	if len(head) == 0 {
+		// Closed without a word, e.g. IsRunning.
+		_ = c.Close()
+		return
+	}
+	select {
+	case s.conns <- &sniffedConn{Conn: c, r: r}:
+	case <-s.done:
+		_ = c.Close()
+	}
+}
+
+func (s *splitListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-s.conns:
+		return c, nil
+	case <-s.done:
+		return nil, s.err
+	}
+}
+
+// sniffedConn replays the bytes read while sniffing.
+type sniffedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *sniffedConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+// serve answers the pipelined requests on c until it is closed.
+func (s *splitListener) serve(c net.Conn, r *bufio.Reader) {
+	defer c.Close()
+	w := bufio.NewWriter(c)
+	if _, err := w.WriteString(pipeMagic); err != nil {
+		return
+	}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: c.RemoteAddr(), LocalAddr: c.LocalAddr()})
+	for {
+		if err := w.Flush(); err != nil {
+			return
+		}
+		op, data, err := readFrame(r, MaxPipeRequest)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				slog.Debug("IPC pipeline closed", "err", err)
+			}
+			return
+		}
+		resp, err := s.handle(ctx, op, data)
+		if err != nil {
+			st := status.Convert(err)
+			err = writeFrame(w, byte(st.Code()), []byte(st.Message()))
+		} else {
+			var out []byte
+			if out, err = proto.Marshal(resp); err == nil {
+				err = writeFrame(w, byte(codes.OK), out)
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handle answers one request as the gRPC method it stands for.
+func (s *splitListener) handle(ctx context.Context, op byte, data []byte) (proto.Message, error) {
+	var (
+		req     proto.Message
+		method  string
+		handler grpc.UnaryHandler
+	)
+	switch op {
+	case opCopy:
+		req, method = &pb.CopyRequest{}, pb.ClipboardService_Copy_FullMethodName
+		handler = func(ctx context.Context, req any) (any, error) { return s.h.Copy(ctx, req.(*pb.CopyRequest)) }
+	case opPaste:
+		req, method = &pb.PasteRequest{}, pb.ClipboardService_Paste_FullMethodName
+		handler = func(ctx context.Context, req any) (any, error) { return s.h.Paste(ctx, req.(*pb.PasteRequest)) }
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "unknown pipelined request %q", op)
+	}
+	if err := proto.Unmarshal(data, req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// Traced like the gRPC calls it stands for.
+	ctx, span := tracing.Start(ctx, strings.TrimPrefix(method, "/"))
+	var resp any
+	var err error
+	defer func() { tracing.End(span, err) }()
+	info := &grpc.UnaryServerInfo{Server: s.h, FullMethod: method}
+	for _, intercept := range slices.Backward(s.intercept) {
+		if intercept == nil {
+			continue
+		}
+		next := handler
+		handler = func(ctx context.Context, req any) (any, error) { return intercept(ctx, req, info, next) }
+	}
+	resp, err = handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(proto.Message), nil
+}
+
+// readFrame reads one frame, refusing one longer than limit (0 for none).
+func readFrame(r *bufio.Reader, limit uint64) (byte, []byte, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	if limit > 0 && n > limit {
+		return 0, nil, fmt.Errorf("ipc: %d-byte request exceeds the %d-byte limit", n, limit)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	return kind, data, nil
+}
+
+func writeFrame(w *bufio.Writer, kind byte, data []byte) error {
+	_ = w.WriteByte(kind)
+	_, _ = w.Write(binary.AppendUvarint(nil, uint64(len(data))))
+	_, err := w.Write(data)
+	return err
+}
+
+// Pipe is a pipelined connection to the server on the IPC socket. It is safe
+// for concurrent use; requests are answered one after another.
+type Pipe struct {
+	mu sync.Mutex
+	c  net.Conn
+	r  *bufio.Reader
+	w  *bufio.Writer
+}
+
+// DialPipe opens a pipelined connection to the server on the IPC socket. It
+// returns ErrPipeUnsupported when the server only answers gRPC, and the dial
+// error when there is none.
+func DialPipe() (*Pipe, error) {
+	c, err := net.Dial("unix", SocketPath())
+	if err != nil {
+		return nil, err
+	}
+	_ = c.SetDeadline(time.Now().Add(sniffTimeout))
+	p := &Pipe{c: c, r: bufio.NewReader(c), w: bufio.NewWriter(c)}
+	_, _ = p.w.WriteString(pipeMagic)
+	if err := p.w.Flush(); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	// Servers without pipelining answer with HTTP/2 or hang up.
+	head := make([]byte, len(pipeMagic))
+	if _, err := io.ReadFull(p.r, head); err != nil || string(head) != pipeMagic {
+		_ = c.Close()
+		return nil, ErrPipeUnsupported
+	}
+	_ = c.SetDeadline(time.Time{})
+	return p, nil
+}
+
+// Copy sends req, which must not exceed MaxPipeRequest, like
+// ClipboardService.Copy.
+func (p *Pipe) Copy(ctx context.Context, req *pb.CopyRequest) error {
+	return p.call(ctx, opCopy, req, &pb.CopyResponse{})
+}
+
+// Paste requests content like ClipboardService.Paste.
+func (p *Pipe) Paste(ctx context.Context, req *pb.PasteRequest) (*pb.PasteResponse, error) {
+	resp := &pb.PasteResponse{}
+	if err := p.call(ctx, opPaste, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// call sends one request and reads its response into resp. Errors the
+// server returned are gRPC status errors.
+func (p *Pipe) call(ctx context.Context, op byte, req, resp proto.Message) error {
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = p.c.SetDeadline(deadline)
+		defer p.c.SetDeadline(time.Time{}) //nolint:errcheck
+	}
+	if err := writeFrame(p.w, op, data); err != nil {
+		return err
+	}
+	if err := p.w.Flush(); err != nil {
+		return err
+	}
+	code, data, err := readFrame(p.r, 0)
+	if err != nil {
+		return fmt.Errorf("ipc: %w", err)
+	}
+	if codes.Code(code) != codes.OK {
+		return status.Error(codes.Code(code), string(data))
+	}
+	return proto.Unmarshal(data, resp)
+}
+
+// Close closes the connection.
+func (p *Pipe) Close() error { return p.c.Close() }
@@ -0,0 +1,306 @@
+// Package journal records what the hub did with each publish — who
+// published which types of content to which clipboard, and whether each peer
+// got it — in a bounded file, so "my paste never arrived on host X" can be
+// answered after the fact. Clipboard content is never recorded.
+//
+// The journal is a file of JSON lines. Once it reaches half of
+// Config.MaxBytes it is renamed with a ".1" suffix, replacing the previous
+// one, and a new file is started, so at most MaxBytes are kept on disk.
+package journal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBytes bounds the journal files when Config.MaxBytes is unset.
+const DefaultMaxBytes = 16 << 20
+
+const flushInterval = time.Second
+
+// Entry kinds.
+const (
+	// KindPublish is content offered to the hub: by a client, a federation
+	// link or the local clipboard.
+	KindPublish = "publish"
+	// KindDeliver is the outcome of passing a publish to one peer.
+	KindDeliver = "deliver"
+)
+
+// Outcomes of publishes.
+const (
+	OutcomeStored    = "stored"
+	OutcomeDuplicate = "duplicate"
+	OutcomeLoop      = "loop"
+	OutcomeOversized = "oversized"
+	OutcomeRefused   = "refused"
+	// OutcomeExpired: stored content was cleared after its clipboard's TTL.
+	OutcomeExpired = "expired"
+)
+
+// Outcomes of deliveries.
+const (
+	OutcomeDelivered = "delivered"
+	// OutcomeDropped: the peer's queue was full.
+	OutcomeDropped = "dropped"
+	// OutcomeFiltered: the peer accepts none of the content's types.
+	OutcomeFiltered = "filtered"
+	// OutcomeHopLimit: the event's hop limit was spent.
+	OutcomeHopLimit = "hop-limit"
+	// OutcomeUnavailable: referenced content could not be fetched or is
+	// deferred by traffic shaping.
+	OutcomeUnavailable = "unavailable"
+)
+
+// Entry is one journal record.
+type Entry struct {
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+	// Event is the publish's event ID, shared by its deliveries and by the
+	// records of other servers it crossed.
+	Event     string `json:"event,omitempty"`
+	Clipboard string `json:"clipboard"`
+	// Source published the content.
+	Source string `json:"source,omitempty"`
+	// Peer is the peer the content came from (publish) or went to
+	// (deliver), and PeerSource that peer's source name.
+	Peer       string   `json:"peer,omitempty"`
+	PeerSource string   `json:"peer_source,omitempty"`
+	Types      []string `json:"types,omitempty"`
+	Size       int      `json:"size,omitempty"`
+	Outcome    string   `json:"outcome"`
+	// Detail explains a refusal or failure.
+	Detail string `json:"detail,omitempty"`
+}
+
+// Filter selects entries for Query. Zero fields match everything.
+type Filter struct {
+	Since time.Time
+	// Source matches entries published by it or delivered to it.
+	Source    string
+	Clipboard string
+	// Event matches entries whose event ID starts with it.
+	Event string
+	// Limit keeps the most recent entries; zero keeps all.
+	Limit int
+}
+
+func (f Filter) match(e Entry) bool {
+	switch {
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case f.Source != "" && e.Source != f.Source && e.PeerSource != f.Source:
+		return false
+	case f.Clipboard != "" && e.Clipboard != f.Clipboard:
+		return false
+	case f.Event != "" && !strings.HasPrefix(e.Event, f.Event):
+		return false
+	}
+	return true
+}
+
+// Config describes the journal file.
+type Config struct {
+	// Path is the current journal file. Its directory is created with mode
+	// 0700.
+	Path string
+	// MaxBytes bounds the journal and its predecessor together; zero means
+	// DefaultMaxBytes.
+	MaxBytes int64
+}
+
+// DefaultPath returns the journal file in the user's state directory.
+func DefaultPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "suffuse", "journal.jsonl")
+}
+
+// Journal appends entries to the journal file. The zero of *Journal (nil)
+// records nothing.
+type Journal struct {
+	cfg Config
+
+	mu   sync.Mutex
+	f    *os.File
+	w    *bufio.Writer
+	size int64
+	// failed suppresses repeated write errors until a write succeeds.
+	failed bool
+}
+
+// New opens the journal file for appending.
+func New(cfg Config) (*Journal, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("journal: empty path")
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o700); err != nil {
+		return nil, fmt.Errorf("journal: %w", err)
+	}
+	j := &Journal{cfg: cfg}
+	if err := j.open(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *Journal) open() error {
+	f, err := os.OpenFile(j.cfg.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("journal: %w", err)
+	}
+	j.f, j.w, j.size = f, bufio.NewWriter(f), info.Size()
+	return nil
+}
+
+// Record appends e, stamping it with the current time when unset.
+func (j *Journal) Record(e Entry) {
+	if j == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return // closed
+	}
+	if j.size+int64(len(line)) > j.cfg.MaxBytes/2 {
+		if j.rotateLocked(); j.w == nil {
+			return
+		}
+	}
+	n, err := j.w.Write(line)
+	j.size += int64(n)
+	j.reportLocked(err)
+}
+
+// rotateLocked moves the current file aside and starts a new one. Must be
+// called with j.mu held.
+func (j *Journal) rotateLocked() {
+	j.reportLocked(j.w.Flush())
+	j.f.Close()
+	if err := os.Rename(j.cfg.Path, j.cfg.Path+".1"); err != nil {
+		j.reportLocked(err)
+	}
+	if err := j.open(); err != nil {
+		j.reportLocked(err)
+		// Keep appending to nothing rather than failing every publish.
+		j.f, j.w = nil, nil
+	}
+}
+
+// reportLocked logs the first of a run of write errors. Must be called with
+// j.mu held.
+func (j *Journal) reportLocked(err error) {
+	if err == nil {
+		j.failed = false
+		return
+	}
+	if !j.failed {
+		slog.Error("journal write failed", "path", j.cfg.Path, "err", err)
+	}
+	j.failed = true
+}
+
+// Run flushes the journal periodically until ctx is done, then closes it.
+func (j *Journal) Run(ctx context.Context) {
+	t := time.NewTicker(flushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			j.flush()
+		case <-ctx.Done():
+			j.mu.Lock()
+			if j.f != nil {
+				j.reportLocked(j.w.Flush())
+				j.f.Close()
+				j.f, j.w = nil, nil
+			}
+			j.mu.Unlock()
+			return
+		}
+	}
+}
+
+func (j *Journal) flush() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.w != nil {
+		j.reportLocked(j.w.Flush())
+	}
+}
+
+// Query returns the entries matching f, oldest first. Lines that cannot be
+// parsed, such as one cut short by a crash, are skipped.
+func (j *Journal) Query(f Filter) ([]Entry, error) {
+	j.flush()
+	var out []Entry
+	for _, path := range []string{j.cfg.Path + ".1", j.cfg.Path} {
+		entries, err := readFile(path, f)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, entries...)
+	}
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = slices.Clone(out[len(out)-f.Limit:])
+	}
+	return out, nil
+}
+
+func readFile(path string, f Filter) ([]Entry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("journal: %w", err)
+	}
+	defer file.Close()
+	var out []Entry
+	sc := bufio.NewScanner(file)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) != nil {
+			continue
+		}
+		if f.match(e) {
+			out = append(out, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("journal: %s: %w", path, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,223 @@
+// Package launchd runs the server as a macOS launchd user agent: it writes
+// the agent's property list to ~/Library/LaunchAgents, so the server starts
+// at login and is restarted when it exits, and loads and unloads it with
+// launchctl in the user's GUI session, whose clipboard the agent shares.
+//
+// The property list is the one contrib/launchd and install.sh install, with
+// the arguments given; installing over either replaces it.
+package launchd
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Supported reports whether the platform has launchd.
+const Supported = runtime.GOOS == "darwin"
+
+// DefaultLabel is the agent's label unless another is given.
+const DefaultLabel = "dev.klb.suffuse"
+
+// Config describes the agent Install writes.
+type Config struct {
+	// Label names the agent; empty means DefaultLabel.
+	Label string
+	// Args are the arguments the executable is started with, e.g. "server"
+	// followed by its flags.
+	Args []string
+	// Manual leaves the agent loaded but not started at login, and not
+	// restarted when it exits; Start runs it.
+	Manual bool
+	// LogPath receives the agent's standard output and error; empty means
+	// ~/Library/Logs/suffuse.log.
+	LogPath string
+}
+
+// PlistPath returns the path of the property list of the agent label.
+func PlistPath(label string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", labelOr(label)+".plist"), nil
+}
+
+// DefaultLogPath returns where the agent logs unless Config.LogPath is set.
+func DefaultLogPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "Logs", "suffuse.log"), nil
+}
+
+// Install writes the property list of the agent cfg describes for the
+// running executable, replacing that of an agent of the same label, and
+// loads it, which starts it unless cfg.Manual is set.
+func Install(cfg Config) error {
+	if !Supported {
+		return errUnsupported
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	if cfg.LogPath == "" {
+		if cfg.LogPath, err = DefaultLogPath(); err != nil {
+			return err
+		}
+	}
+	cfg.Label = labelOr(cfg.Label)
+	path, err := PlistPath(cfg.Label)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.LogPath), 0o755); err != nil {
+		return err
+	}
+
+	bootout(cfg.Label) //nolint:errcheck // not loaded yet is fine
+	if err := os.WriteFile(path, plist(cfg, exe), 0o644); err != nil {
+		return err
+	}
+	return launchctl("bootstrap", domain(), path)
+}
+
+// Uninstall unloads the agent label, stopping it, and removes its property
+// list. Empty label means DefaultLabel.
+func Uninstall(label string) error {
+	if !Supported {
+		return errUnsupported
+	}
+	path, err := PlistPath(label)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("agent %s: %w", labelOr(label), err)
+	}
+	bootout(labelOr(label)) //nolint:errcheck // not loaded is fine
+	return os.Remove(path)
+}
+
+// Start loads the agent label, if it is not loaded, and starts it. Empty
+// label means DefaultLabel.
+func Start(label string) error {
+	if !Supported {
+		return errUnsupported
+	}
+	label = labelOr(label)
+	if !loaded(label) {
+		path, err := PlistPath(label)
+		if err != nil {
+			return err
+		}
+		if err := launchctl("bootstrap", domain(), path); err != nil {
+			return err
+		}
+	}
+	return launchctl("kickstart", domain()+"/"+label)
+}
+
+// Stop unloads the agent label, so launchd does not restart it, until Start
+// or the next login. Empty label means DefaultLabel.
+func Stop(label string) error {
+	if !Supported {
+		return errUnsupported
+	}
+	return bootout(labelOr(label))
+}
+
+var errUnsupported = errors.New("launchd agents are only available on macOS")
+
+func labelOr(label string) string {
+	if label == "" {
+		return DefaultLabel
+	}
+	return label
+}
+
+// domain is the launchd domain of the user's GUI session.
+func domain() string {
+	return "gui/" + strconv.Itoa(os.Getuid())
+}
+
+func loaded(label string) bool {
+	return exec.Command("launchctl", "print", domain()+"/"+label).Run() == nil
+}
+
+func bootout(label string) error {
+	return launchctl("bootout", domain()+"/"+label)
+}
+
+// launchctl runs launchctl with args, returning its output in the error
+// when it fails.
+func launchctl(args ...string) error {
+	out, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("launchctl %s: %w: %s", args[0], err, msg)
+		}
+		return fmt.Errorf("launchctl %s: %w", args[0], err)
+	}
+	return nil
+}
+
+// plist returns the property list of the agent cfg describes, running exe.
+func plist(cfg Config, exe string) []byte {
+	var b strings.Builder
+	str := func(s string) string {
+		var e strings.Builder
+		xml.EscapeText(&e, []byte(s)) //nolint:errcheck // strings.Builder does not fail
+		return "<string>" + e.String() + "</string>"
+	}
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN"
+  "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<!-- Written by "suffuse service install"; reinstall rather than edit. -->
+<plist version="1.0">
+<dict>
+    <key>Label</key>
+    ` + str(cfg.Label) + `
+
+    <key>ProgramArguments</key>
+    <array>
+`)
+	for _, arg := range append([]string{exe}, cfg.Args...) {
+		b.WriteString("        " + str(arg) + "\n")
+	}
+	b.WriteString(`    </array>
+
+    <key>StandardOutPath</key>
+    ` + str(cfg.LogPath) + `
+    <key>StandardErrorPath</key>
+    ` + str(cfg.LogPath) + `
+
+    <key>RunAtLoad</key>
+    <` + strconv.FormatBool(!cfg.Manual) + `/>
+    <key>KeepAlive</key>
+    <` + strconv.FormatBool(!cfg.Manual) + `/>
+    <key>ProcessType</key>
+    <string>Interactive</string>
+
+    <!-- Throttle restart on repeated failures -->
+    <key>ThrottleInterval</key>
+    <integer>5</integer>
+</dict>
+</plist>
+`)
+	return []byte(b.String())
+}
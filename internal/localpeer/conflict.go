@@ -0,0 +1,75 @@
+package localpeer
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"go.klb.dev/suffuse/internal/clip"
+	"go.klb.dev/suffuse/internal/hub"
+)
+
+// ConflictPolicy selects what the local peer does with an update from the
+// hub that arrives moments after the local clipboard changed, when applying
+// it would overwrite a copy the user just made.
+type ConflictPolicy string
+
+const (
+	// ConflictRemoteWins applies the update, as without a policy.
+	ConflictRemoteWins ConflictPolicy = "remote-wins"
+	// ConflictLocalWins leaves the local clipboard alone; the update is not
+	// applied here but stays the hub's content.
+	ConflictLocalWins ConflictPolicy = "local-wins"
+	// ConflictKeepBoth applies the update after adding the local content to
+	// the clipboard's history, so it can be pasted from there. It needs a
+	// hub that keeps history.
+	ConflictKeepBoth ConflictPolicy = "keep-both"
+)
+
+// DefaultConflictWindow is how soon after a local change an update counts
+// as a conflict.
+const DefaultConflictWindow = time.Second
+
+// ParseConflictPolicy validates a policy name; empty means
+// ConflictRemoteWins.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case "":
+		return ConflictRemoteWins, nil
+	case ConflictRemoteWins, ConflictLocalWins, ConflictKeepBoth:
+		return p, nil
+	}
+	return "", errors.New(`conflict policy must be "remote-wins", "local-wins" or "keep-both"`)
+}
+
+// resolveConflict applies the conflict policy to ev and reports whether it
+// should be written to the local clipboard.
+func (p *Peer) resolveConflict(ev hub.Event) bool {
+	if p.conflict == "" || p.conflict == ConflictRemoteWins || p.window <= 0 {
+		return true
+	}
+	p.mu.RLock()
+	local, age := p.lastItems, time.Since(p.localChangedAt)
+	p.mu.RUnlock()
+	if age >= p.window {
+		return true
+	}
+	switch p.conflict {
+	case ConflictLocalWins:
+		slog.Info("update not applied, the local clipboard changed moments ago",
+			"source", ev.Source, "clipboard", ev.Clipboard, "changed", age.Round(time.Millisecond))
+		return false
+	case ConflictKeepBoth:
+		// The clipboard may hold a change newer than the last one published,
+		// unless it is one kept off the hub.
+		if items, universal, err := p.read(); err == nil && len(items) > 0 && !universal && (p.sensitive || !clip.IsSensitive(items)) {
+			local = items
+		}
+		if kept, err := p.keys.Seal(p.clipboard, p.files.Attach(local)); err == nil {
+			p.h.AddHistory(p.clipboard, kept, p.source)
+		}
+		slog.Info("local clipboard kept in history, applying update",
+			"source", ev.Source, "clipboard", ev.Clipboard, "changed", age.Round(time.Millisecond))
+	}
+	return true
+}
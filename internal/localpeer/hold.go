@@ -0,0 +1,122 @@
+package localpeer
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/clip"
+	"go.klb.dev/suffuse/internal/files"
+	"go.klb.dev/suffuse/internal/hub"
+	"go.klb.dev/suffuse/internal/notify"
+)
+
+var (
+	// ErrNotHolding is returned by Accept and Discard when the peer applies
+	// updates as they arrive.
+	ErrNotHolding = errors.New("updates are not held (start the server with --hold or --hold-executables)")
+	// ErrNothingHeld is returned by Accept and Discard when no update is
+	// waiting.
+	ErrNothingHeld = errors.New("nothing to accept: no update is held")
+)
+
+// Pending is an update from the hub held until it is accepted.
+type Pending struct {
+	Items  []*pb.ClipboardItem
+	Source string
+	// At is when the update arrived.
+	At time.Time
+}
+
+// executable returns why ev looks like a program or script when executable
+// updates are held, or "". Encrypted updates are checked as they will be
+// written; one that cannot be opened fails in apply instead.
+func (p *Peer) executable(ev hub.Event) string {
+	if !p.holdExec {
+		return ""
+	}
+	items, err := p.keys.Open(p.clipboard, ev.Items)
+	if err != nil {
+		return ""
+	}
+	if reason := clip.Executable(items); reason != "" {
+		return reason
+	}
+	return files.Executable(items)
+}
+
+// stage holds ev in place of any update held before and notifies the user.
+// reason is why ev looks executable, if that is why it is held.
+func (p *Peer) stage(ev hub.Event, reason string) {
+	p.mu.Lock()
+	p.pending = &Pending{Items: ev.Items, Source: ev.Source, At: time.Now()}
+	p.lastReceived = ev.Items
+	p.mu.Unlock()
+
+	types := make([]string, 0, len(ev.Items))
+	for _, it := range ev.Items {
+		types = append(types, it.Mime)
+	}
+	title := "Clipboard update held"
+	body := fmt.Sprintf("%s copied %s. Run \"suffuse accept\" to paste it here.", ev.Source, strings.Join(types, ", "))
+	if reason != "" {
+		slog.Warn("update looks executable, held until suffuse accept applies it", "source", ev.Source, "clipboard", ev.Clipboard, "types", types, "reason", reason)
+		title = "Executable clipboard content held"
+		body = fmt.Sprintf("%s copied what looks like a program or script (%s). Run \"suffuse accept\" only if you expected it.", ev.Source, reason)
+	} else {
+		slog.Info("update held, run suffuse accept to apply it", "source", ev.Source, "clipboard", ev.Clipboard, "types", types)
+	}
+	go func() {
+		if err := notify.Send(title, body); err != nil {
+			slog.Debug("held update notification not shown", "err", err)
+		}
+	}()
+}
+
+// take removes and returns the held update.
+func (p *Peer) take() (*Pending, error) {
+	if !p.hold && !p.holdExec {
+		return nil, ErrNotHolding
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	u := p.pending
+	p.pending = nil
+	if u == nil {
+		return nil, ErrNothingHeld
+	}
+	return u, nil
+}
+
+// Accept writes the held update to the local clipboard and returns it. The
+// content it replaces can be restored with Undo.
+func (p *Peer) Accept() (Pending, error) {
+	u, err := p.take()
+	if err != nil {
+		return Pending{}, err
+	}
+	if err := p.apply(hub.Event{Clipboard: p.clipboard, Source: u.Source, Items: u.Items}); err != nil {
+		p.mu.Lock()
+		if p.pending == nil {
+			p.pending = u
+		}
+		p.mu.Unlock()
+		return Pending{}, err
+	}
+	slog.Info("held update accepted", "source", u.Source, "clipboard", p.clipboard)
+	return *u, nil
+}
+
+// Discard drops the held update and returns it, leaving the local clipboard
+// as it is.
+func (p *Peer) Discard() (Pending, error) {
+	u, err := p.take()
+	if err != nil {
+		return Pending{}, err
+	}
+	slog.Info("held update discarded", "source", u.Source, "clipboard", p.clipboard)
+	return *u, nil
+}
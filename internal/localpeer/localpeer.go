@@ -1,107 +1,254 @@
 // Package localpeer implements the hub.Peer that owns the server's local system clipboard.
+// A second Peer can follow another system selection, such as the Linux
+// primary selection, on a clipboard of its own.
 package localpeer
 
 import (
+	"context"
 	"log/slog"
-	"reflect"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
 	"go.klb.dev/suffuse/internal/clip"
+	"go.klb.dev/suffuse/internal/e2e"
+	"go.klb.dev/suffuse/internal/files"
 	"go.klb.dev/suffuse/internal/hub"
+	"go.klb.dev/suffuse/internal/signing"
+	"go.klb.dev/suffuse/internal/tracing"
 )
 
 const peerID = "local"
 
 // Peer is the hub.Peer that owns the server-side clipboard.
 type Peer struct {
-	h       *hub.Hub
-	backend clip.Backend
-	source  string
-	sendCh  chan hub.Event
+	h         *hub.Hub
+	backend   clip.Backend
+	caps      clip.Capabilities
+	source    string
+	clipboard string
+	files     *files.Transfer
+	conflict  ConflictPolicy
+	window    time.Duration
+	hold      bool
+	holdExec  bool // hold updates that look executable
+	sensitive bool // publish content marked sensitive
+	keys      *e2e.Keyring
+	signer    *signing.Key
+	trust     *signing.Trust
+	prefer    []TypePreference
+	universal UniversalPolicy
+	id        string
+	queue     *hub.Queue
+	running   atomic.Bool
 
 	mu          sync.RWMutex
 	lastItems   []*pb.ClipboardItem
 	connectedAt time.Time
 	lastSeen    time.Time
+	// lastReceived is the last update written, as it came from the hub;
+	// it differs from lastItems when files were unpacked.
+	lastReceived []*pb.ClipboardItem
+	// localChangedAt is when a change of the local clipboard was last
+	// published.
+	localChangedAt time.Time
+	// undo is the content the last update from the hub overwrote, until
+	// Undo restores it.
+	undo *Overwritten
+	// pending is the update held until Accept when hold is set.
+	pending *Pending
 }
 
-// New creates the local peer but does not start it.
-func New(h *hub.Hub, backend clip.Backend, source string) *Peer {
+// Config describes a local peer.
+type Config struct {
+	// Source names this host in the content it publishes.
+	Source string
+	// Clipboard is the hub clipboard the system clipboard is synced with.
+	Clipboard string
+	// Files transfers copied files; nil moves file references only.
+	Files *files.Transfer
+	// Conflict decides what happens to an update from the hub arriving
+	// less than ConflictWindow after the local clipboard changed. Empty
+	// means ConflictRemoteWins.
+	Conflict       ConflictPolicy
+	ConflictWindow time.Duration
+	// SyncSensitive publishes local content a password manager marked
+	// sensitive, which is otherwise kept on this host.
+	SyncSensitive bool
+	// Hold keeps updates from the hub pending, with a desktop notification,
+	// until Accept applies them; the conflict policy then has no effect.
+	Hold bool
+	// HoldExecutables holds only updates that look like programs or scripts
+	// (see clip.Executable), so they never reach the clipboard unapproved.
+	HoldExecutables bool
+	// Keys end-to-end encrypts Clipboard when it has a key: local content is
+	// sealed before it is published and updates are opened before they are
+	// written, so hubs only hold ciphertext.
+	Keys *e2e.Keyring
+	// Signer signs local content before it is published (and sealed); nil
+	// publishes it unsigned.
+	Signer *signing.Key
+	// Trust names the signers of updates in the log. Updates with an invalid
+	// signature are not written.
+	Trust *signing.Trust
+	// Prefer leaves the less preferred of the types an update carries out
+	// when it is written; see TypePreference.
+	Prefer []TypePreference
+	// Universal decides what happens to content Apple's Universal
+	// Clipboard brings from another device. Empty means UniversalPublish.
+	Universal UniversalPolicy
+}
+
+// New creates the local peer syncing backend with cfg.Clipboard but does not
+// start it. The peer for the default clipboard has ID "local"; others are
+// "local/<clipboard>".
+func New(h *hub.Hub, backend clip.Backend, cfg Config) *Peer {
 	now := time.Now()
+	id := peerID
+	if cfg.Clipboard != hub.DefaultClipboard {
+		id += "/" + cfg.Clipboard
+	}
 	return &Peer{
 		h:           h,
 		backend:     backend,
-		source:      source,
-		sendCh:      make(chan hub.Event, 64),
+		caps:        backend.Capabilities(),
+		source:      cfg.Source,
+		clipboard:   cfg.Clipboard,
+		files:       cfg.Files,
+		conflict:    cfg.Conflict,
+		window:      cfg.ConflictWindow,
+		hold:        cfg.Hold,
+		holdExec:    cfg.HoldExecutables,
+		sensitive:   cfg.SyncSensitive,
+		keys:        cfg.Keys,
+		signer:      cfg.Signer,
+		trust:       cfg.Trust,
+		prefer:      cfg.Prefer,
+		universal:   cfg.Universal,
+		id:          id,
+		queue:       h.NewQueue(hub.DropLocal, 64),
 		connectedAt: now,
 		lastSeen:    now,
 	}
 }
 
-func (p *Peer) ID() string { return peerID }
+func (p *Peer) ID() string { return p.id }
 
 func (p *Peer) Info() *pb.PeerInfo {
 	p.mu.RLock()
 	ls := p.lastSeen
 	p.mu.RUnlock()
 	return &pb.PeerInfo{
-		Source:      p.source,
-		Addr:        "local",
-		Role:        "both",
-		Clipboard:   hub.DefaultClipboard,
-		ConnectedAt: timestamppb.New(p.connectedAt),
-		LastSeen:    timestamppb.New(ls),
+		Source:        p.source,
+		Addr:          "local",
+		Role:          "both",
+		Clipboard:     p.clipboard,
+		AcceptedTypes: p.caps.MIMETypes,
+		ConnectedAt:   timestamppb.New(p.connectedAt),
+		LastSeen:      timestamppb.New(ls),
+		Backend:       p.caps.Proto(p.backend.Name()),
 	}
 }
 
-// Send implements hub.Peer — queues incoming clipboard updates to write to the local system clipboard.
-func (p *Peer) Send(ev hub.Event) {
-	select {
-	case p.sendCh <- ev:
-	default:
-		slog.Warn("local peer send channel full, dropping")
+// Subscriptions implements hub.SubscriberPeer. The local peer follows its
+// clipboard limited to the types its backend can store, so the hub
+// filters updates instead of the backend dropping them at Write time. A
+// backend that stores any type subscribes to everything, and one that stores
+// nothing (headless) to nothing.
+func (p *Peer) Subscriptions() []hub.ClipboardFilter {
+	if p.caps.AnyMIMEType {
+		return []hub.ClipboardFilter{{Clipboard: p.clipboard}}
+	}
+	if len(p.caps.MIMETypes) == 0 {
+		return nil
+	}
+	accepts := p.caps.MIMETypes
+	if p.files != nil && slices.Contains(accepts, clip.URIListMime) {
+		accepts = append(slices.Clone(accepts), files.ArchiveMime)
 	}
+	if p.keys.Encrypted(p.clipboard) {
+		accepts = append(slices.Clone(accepts), e2e.MIME)
+	}
+	accepts = append(slices.Clone(accepts), signing.MIME)
+	return []hub.ClipboardFilter{{Clipboard: p.clipboard, Accepts: accepts}}
+}
+
+// Send implements hub.Peer — queues incoming clipboard updates to write to the local system clipboard.
+func (p *Peer) Send(ev hub.Event) error {
+	return p.queue.Put(ev)
 }
 
+// Queue implements hub.QueuePeer.
+func (p *Peer) Queue() *hub.Queue { return p.queue }
+
+// Running reports whether Run has registered with the hub and the backend's
+// watch loop is still active.
+func (p *Peer) Running() bool { return p.running.Load() }
+
 // Run registers with the hub and starts the watch + write loops.
 // Blocks until the backend is closed; call in a goroutine.
 func (p *Peer) Run() {
 	p.h.Register(p)
 	defer p.h.Unregister(p)
+	p.running.Store(true)
+	defer p.running.Store(false)
 
-	slog.Info("local clipboard peer started", "backend", p.backend.Name())
+	slog.Info("local clipboard peer started", "clipboard", p.clipboard, "backend", p.backend.Name(),
+		"types", p.caps.MIMETypes, "any_type", p.caps.AnyMIMEType, "watch", p.caps.Watch, "atomic_write", p.caps.AtomicWrite)
+	p.isolate()
 
 	// Writer: apply incoming hub events to the local clipboard.
 	go func() {
-		for ev := range p.sendCh {
+		for ev := range p.queue.C() {
+			if ev.Expired {
+				p.expire()
+				continue
+			}
 			if len(ev.Items) == 0 {
 				continue
 			}
 			p.mu.Lock()
-			same := reflect.DeepEqual(ev.Items, p.lastItems)
+			same := blob.SameContent(ev.Items, p.lastItems) || blob.SameContent(ev.Items, p.lastReceived)
 			p.mu.Unlock()
 			if same {
 				continue
 			}
-			if err := p.backend.Write(ev.Items); err != nil {
-				slog.Error("local clipboard write failed", "err", err)
+			if p.hold {
+				p.stage(ev, "")
 				continue
 			}
-			p.mu.Lock()
-			p.lastItems = ev.Items
-			p.lastSeen = time.Now()
-			p.mu.Unlock()
-			hub.LogItems("local clipboard updated", ev.Source, ev.Clipboard, ev.Items)
+			if reason := p.executable(ev); reason != "" {
+				p.stage(ev, reason)
+				continue
+			}
+			if !p.resolveConflict(ev) {
+				p.mu.Lock()
+				p.lastReceived = ev.Items
+				p.mu.Unlock()
+				continue
+			}
+			_, span := tracing.StartFrom(ev.Trace, "clipboard.Write",
+				attribute.String("suffuse.clipboard", p.clipboard),
+				attribute.String("suffuse.backend", p.backend.Name()),
+				attribute.String("suffuse.event_id", ev.ID),
+			)
+			err := p.apply(ev)
+			tracing.End(span, err)
+			if err != nil {
+				slog.Error("local clipboard write failed", "err", err)
+			}
 		}
 	}()
 
 	// Watcher: publish local clipboard changes to the hub.
 	for range p.backend.Watch() {
-		items, err := p.backend.Read()
+		items, universal, err := p.read()
 		if err != nil {
 			slog.Error("local clipboard read failed", "err", err)
 			continue
@@ -109,17 +256,112 @@ func (p *Peer) Run() {
 		if len(items) == 0 {
 			continue
 		}
+		if universal {
+			slog.Info("local clipboard change not published, it came through Universal Clipboard", "clipboard", p.clipboard)
+			continue
+		}
+		if clip.IsSensitive(items) && !p.sensitive {
+			slog.Info("local clipboard change not published, a password manager marked it sensitive", "clipboard", p.clipboard)
+			continue
+		}
+		items = blob.WithDigests(items)
 		p.mu.Lock()
-		same := reflect.DeepEqual(items, p.lastItems)
+		same := blob.SameContent(items, p.lastItems)
 		if !same {
 			p.lastItems = items
 			p.lastSeen = time.Now()
+			p.localChangedAt = p.lastSeen
+			// The hub's content is about to be this; a held update is stale.
+			p.pending = nil
 		}
 		p.mu.Unlock()
 		if same {
 			continue
 		}
-		hub.LogItems("local clipboard changed, publishing", p.source, hub.DefaultClipboard, items)
-		p.h.Publish(items, hub.DefaultClipboard, peerID, p.source)
+		items = p.files.Attach(items)
+		filtered, err := p.h.FilterContent(p.clipboard, p.source, items)
+		if err != nil {
+			p.h.RecordRefused(items, p.clipboard, p.id, p.source, "", err)
+			continue
+		}
+		filtered = p.h.ExpandSnippet(p.clipboard, p.source, filtered)
+		filtered = p.h.RewriteURLs(p.clipboard, p.source, hub.RewriteOnPublish, filtered)
+		hub.LogItems("local clipboard changed, publishing", p.source, p.clipboard, filtered)
+		items, err = p.signer.Sign(filtered)
+		if err == nil {
+			items, err = p.keys.Seal(p.clipboard, items)
+		}
+		if err != nil {
+			slog.Error("local clipboard change not published", "clipboard", p.clipboard, "err", err)
+			continue
+		}
+		p.h.Publish(context.Background(), items, p.clipboard, p.id, p.source)
+	}
+}
+
+// apply writes the items of ev to the local clipboard, keeping the content
+// they replace for Undo.
+func (p *Peer) apply(ev hub.Event) error {
+	items, err := p.keys.Open(p.clipboard, ev.Items)
+	if err != nil {
+		return err
+	}
+	items, signer, err := p.trust.Verify(items)
+	if err != nil {
+		return err
+	}
+	if signer != nil {
+		slog.Info("update signed", "source", ev.Source, "clipboard", ev.Clipboard, "signer", signer.String())
+	}
+	items = p.preferTypes(p.files.Extract(items))
+	if len(items) == 0 {
+		return nil
+	}
+	p.saveUndo(ev.Source)
+	if err := p.backend.Write(items); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.lastItems = blob.WithDigests(items)
+	p.lastReceived = ev.Items
+	p.lastSeen = time.Now()
+	p.mu.Unlock()
+	hub.LogItems("local clipboard updated", ev.Source, ev.Clipboard, items)
+	return nil
+}
+
+// expire clears the local clipboard after the hub's content expired, unless
+// it changed since it was last synced: such a change is on its way to the
+// hub and was not part of what expired. Backends cannot empty the clipboard,
+// so it is overwritten with empty text.
+func (p *Peer) expire() {
+	p.mu.Lock()
+	p.pending = nil
+	p.mu.Unlock()
+	if !slices.Contains(p.caps.MIMETypes, "text/plain") {
+		return
+	}
+	current, _, err := p.read()
+	if err != nil {
+		slog.Error("local clipboard read failed", "err", err)
+		return
+	}
+	p.mu.Lock()
+	synced := blob.SameContent(current, p.lastItems)
+	p.mu.Unlock()
+	if !synced || len(current) == 0 {
+		return
+	}
+	empty := []*pb.ClipboardItem{{Mime: "text/plain", Data: []byte{}}}
+	if err := p.backend.Write(empty); err != nil {
+		slog.Error("local clipboard write failed", "err", err)
+		return
 	}
+	p.mu.Lock()
+	p.lastItems = empty
+	p.lastReceived = nil
+	p.undo = nil
+	p.lastSeen = time.Now()
+	p.mu.Unlock()
+	slog.Info("local clipboard cleared, its content expired", "clipboard", p.clipboard)
 }
@@ -0,0 +1,81 @@
+package localpeer
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// TypePreference ranks types an update may carry, most preferred first, as
+// path.Match patterns, e.g. "text/plain" then "text/*". Of the items an
+// update has that match a pattern, only those matching the best pattern
+// any of them matches are written to the system clipboard, so an update
+// with text/plain and text/html pastes as plain text everywhere. Items
+// matching none of the patterns are written as usual.
+type TypePreference []string
+
+// ParseTypePreferences parses preferences written as patterns separated by
+// ">", most preferred first, e.g. "text/plain>text/html>text/rtf".
+func ParseTypePreferences(specs []string) ([]TypePreference, error) {
+	var prefs []TypePreference
+	for _, spec := range specs {
+		var pref TypePreference
+		for pattern := range strings.SplitSeq(spec, ">") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				return nil, fmt.Errorf("type preference %q: empty type", spec)
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("type preference %q: %w", spec, err)
+			}
+			pref = append(pref, pattern)
+		}
+		if len(pref) < 2 {
+			return nil, fmt.Errorf(`type preference %q: must rank two types or more, e.g. "text/plain>text/html"`, spec)
+		}
+		prefs = append(prefs, pref)
+	}
+	return prefs, nil
+}
+
+// rank returns the index of the first pattern of pref mime matches, or -1.
+func (pref TypePreference) rank(mime string) int {
+	return slices.IndexFunc(pref, func(pattern string) bool {
+		ok, _ := path.Match(pattern, mime)
+		return ok
+	})
+}
+
+// preferTypes returns items without those a preference ranks below another
+// item. items is not modified.
+func (p *Peer) preferTypes(items []*pb.ClipboardItem) []*pb.ClipboardItem {
+	out := items
+	for _, pref := range p.prefer {
+		best := -1
+		for _, it := range out {
+			if r := pref.rank(it.Mime); r >= 0 && (best < 0 || r < best) {
+				best = r
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		out = slices.DeleteFunc(slices.Clone(out), func(it *pb.ClipboardItem) bool {
+			return pref.rank(it.Mime) > best
+		})
+	}
+	if len(out) < len(items) {
+		var dropped []string
+		for _, it := range items {
+			if !slices.Contains(out, it) {
+				dropped = append(dropped, it.Mime)
+			}
+		}
+		slog.Debug("types left out by preference", "clipboard", p.clipboard, "types", dropped)
+	}
+	return out
+}
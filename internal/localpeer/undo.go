@@ -0,0 +1,60 @@
+package localpeer
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/hub"
+)
+
+// ErrNothingToUndo is returned by Undo when no update from the hub has
+// overwritten the local clipboard since it was started or last undone.
+var ErrNothingToUndo = errors.New("nothing to undo: no update has overwritten the local clipboard")
+
+// Overwritten is local clipboard content an update from the hub replaced.
+type Overwritten struct {
+	Items []*pb.ClipboardItem
+	// Source is the source of the update that replaced it.
+	Source string
+	At     time.Time
+}
+
+// saveUndo keeps the local content an update from source is about to
+// overwrite. Empty content, as left by an expiry, is not worth restoring.
+func (p *Peer) saveUndo(source string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if hub.PayloadSize(p.lastItems) == 0 {
+		return
+	}
+	p.undo = &Overwritten{Items: p.lastItems, Source: source, At: time.Now()}
+}
+
+// Undo writes back the local clipboard content the last update from the hub
+// overwrote and returns it. The restored content is not published: it is
+// what this host held before, not a new copy. Content can be restored once.
+func (p *Peer) Undo() (Overwritten, error) {
+	p.mu.Lock()
+	u := p.undo
+	p.undo = nil
+	p.mu.Unlock()
+	if u == nil {
+		return Overwritten{}, ErrNothingToUndo
+	}
+	if err := p.backend.Write(u.Items); err != nil {
+		p.mu.Lock()
+		if p.undo == nil {
+			p.undo = u
+		}
+		p.mu.Unlock()
+		return Overwritten{}, err
+	}
+	p.mu.Lock()
+	p.lastItems = u.Items
+	p.lastSeen = time.Now()
+	p.mu.Unlock()
+	slog.Info("local clipboard restored, undoing an update", "source", u.Source, "clipboard", p.clipboard)
+	return *u, nil
+}
@@ -0,0 +1,68 @@
+package localpeer
+
+import (
+	"errors"
+	"log/slog"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/clip"
+)
+
+// UniversalPolicy selects what the local peer on a Mac does about Apple's
+// Universal Clipboard, which syncs the clipboard between the Apple devices
+// of one account by itself. A Mac running both sees content copied on
+// another Mac arrive twice, through the hub and through Universal Clipboard,
+// and publishes the second copy again unless told otherwise.
+type UniversalPolicy string
+
+const (
+	// UniversalPublish publishes content that arrived through Universal
+	// Clipboard like any other copy, as without a policy.
+	UniversalPublish UniversalPolicy = "publish"
+	// UniversalIgnore keeps content that arrived through Universal
+	// Clipboard off the hub: the device it was copied on publishes it if it
+	// runs suffuse.
+	UniversalIgnore UniversalPolicy = "ignore"
+	// UniversalIsolate ignores such content too, and writes updates from
+	// the hub for this Mac only, so Universal Clipboard does not carry them
+	// on to devices that receive them from the hub already. It needs macOS
+	// 13 or later.
+	UniversalIsolate UniversalPolicy = "isolate"
+)
+
+// ParseUniversalPolicy validates a policy name; empty means
+// UniversalPublish.
+func ParseUniversalPolicy(s string) (UniversalPolicy, error) {
+	switch p := UniversalPolicy(s); p {
+	case "":
+		return UniversalPublish, nil
+	case UniversalPublish, UniversalIgnore, UniversalIsolate:
+		return p, nil
+	}
+	return "", errors.New(`universal clipboard policy must be "publish", "ignore" or "isolate"`)
+}
+
+// isolate makes the backend keep what it writes out of Universal Clipboard
+// when the policy asks for it and the backend can.
+func (p *Peer) isolate() {
+	if p.universal != UniversalIsolate {
+		return
+	}
+	if w, ok := p.backend.(clip.HostOnlyWriter); ok {
+		w.SetHostOnly(true)
+		return
+	}
+	slog.Warn("universal clipboard isolate has no effect on this clipboard backend", "backend", p.backend.Name())
+}
+
+// read returns the local clipboard content without the Universal Clipboard
+// marker, and whether the content is to be kept off the hub because it
+// arrived through Universal Clipboard.
+func (p *Peer) read() (items []*pb.ClipboardItem, universal bool, err error) {
+	items, err = p.backend.Read()
+	if err != nil {
+		return nil, false, err
+	}
+	items, remote := clip.FromUniversalClipboard(items)
+	return items, remote && p.universal != "" && p.universal != UniversalPublish, nil
+}
@@ -49,21 +49,40 @@ func IsTTY(w io.Writer) bool {
 	return false
 }
 
+// level is the minimum level of the global logger; SetLevel changes it.
+var level slog.LevelVar
+
+// SetLevel changes the minimum level of the logger installed by Setup, e.g.
+// when a server reloads its configuration.
+func SetLevel(l slog.Level) { level.Set(l) }
+
+// Level returns the minimum level of the logger installed by Setup.
+func Level() slog.Level { return level.Level() }
+
 // Setup configures the global slog logger. Call once after flag/viper parsing.
-func Setup(format Format, level slog.Level) {
+func Setup(format Format, l slog.Level) {
+	level.Set(l)
 	w := os.Stderr
 	useTint := format == FormatText || (format == FormatAuto && IsTTY(w))
 
 	var h slog.Handler
 	if useTint {
 		h = tinter.NewHandler(w, &tinter.Options{
-			Level:      level,
+			Level:      &level,
 			TimeFormat: "15:04:05.000",
 		})
 	} else {
 		h = slog.NewJSONHandler(w, &slog.HandlerOptions{
-			Level: level,
+			Level: &level,
 		})
 	}
 	slog.SetDefault(slog.New(h))
 }
+
+// SetupHandler configures the global slog logger to write to the handler
+// newHandler returns for the logger's level, e.g. one writing to the Windows
+// Event Log. Like Setup, it is called once, instead of Setup.
+func SetupHandler(l slog.Level, newHandler func(level slog.Leveler) slog.Handler) {
+	level.Set(l)
+	slog.SetDefault(slog.New(newHandler(&level)))
+}
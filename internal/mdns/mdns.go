@@ -0,0 +1,441 @@
+// Package mdns advertises suffuse servers on the local network with multicast
+// DNS (RFC 6762) and DNS-SD (RFC 6763) under the service type _suffuse._tcp,
+// and discovers them for clients started without --host.
+//
+// Only the part of mDNS needed for that is implemented. The Responder
+// answers PTR, SRV, TXT and A queries for its own instance and announces it
+// on start; Browse sends one-shot ("legacy unicast") queries, which standard
+// responders such as Avahi and mDNSResponder answer as well, and collects
+// the replies.
+//
+// Discovery only yields candidates: clients still connect over TLS with a key
+// derived from their token, so a server advertised with another token is
+// found but cannot be used, and advertisements carry nothing secret.
+package mdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+)
+
+// ServiceType is the DNS-SD service type suffuse servers advertise.
+const ServiceType = "_suffuse._tcp"
+
+const (
+	mdnsPort     = 5353
+	domain       = "local."
+	recordTTL    = 120 // seconds, for multicast answers
+	legacyTTL    = 10  // seconds, for legacy unicast answers (RFC 6762 §6.7)
+	cacheFlush   = 1 << 15
+	unicastReply = 1 << 15
+	maxPacket    = 9000
+)
+
+var (
+	group       = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: mdnsPort}
+	serviceName = dnsmessage.MustNewName(ServiceType + "." + domain)
+	metaName    = dnsmessage.MustNewName("_services._dns-sd._udp." + domain)
+)
+
+// Config describes the advertised service.
+type Config struct {
+	// Instance names this server on the network, normally its source name.
+	Instance string
+	// Port is the server's TCP port.
+	Port int
+	// TXT holds "key=value" strings published with the service.
+	TXT []string
+}
+
+// Responder answers mDNS queries for one suffuse server.
+type Responder struct {
+	cfg      Config
+	conn     *net.UDPConn
+	instance dnsmessage.Name
+	host     dnsmessage.Name
+}
+
+// NewResponder joins the mDNS multicast group on every multicast-capable
+// interface. The socket is shared with any system responder on port 5353.
+func NewResponder(cfg Config) (*Responder, error) {
+	instance, err := dnsmessage.NewName(label(cfg.Instance) + "." + serviceName.String())
+	if err != nil {
+		return nil, fmt.Errorf("mdns: instance name: %w", err)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = cfg.Instance
+	}
+	hostname, _, _ = strings.Cut(hostname, ".")
+	host, err := dnsmessage.NewName(label(hostname) + "." + domain)
+	if err != nil {
+		return nil, fmt.Errorf("mdns: host name: %w", err)
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return nil, fmt.Errorf("mdns: %w", err)
+	}
+	// ListenMulticastUDP joins on the default interface only; add the
+	// others so queries arriving on any LAN are seen.
+	if ifaces, err := net.Interfaces(); err == nil {
+		pc := ipv4.NewPacketConn(conn)
+		for _, ifi := range ifaces {
+			if ifi.Flags&net.FlagUp != 0 && ifi.Flags&net.FlagMulticast != 0 {
+				_ = pc.JoinGroup(&ifi, group)
+			}
+		}
+	}
+	return &Responder{cfg: cfg, conn: conn, instance: instance, host: host}, nil
+}
+
+// Run announces the service and answers queries until ctx is cancelled, when
+// it sends a goodbye so browsers drop the service at once.
+func (r *Responder) Run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		r.send(r.response(0, nil, nil, 0), group)
+		r.conn.Close()
+	}()
+	go r.announce(ctx)
+
+	buf := make([]byte, maxPacket)
+	for {
+		n, src, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			slog.Debug("mdns read failed", "err", err)
+			continue
+		}
+		r.handle(buf[:n], src)
+	}
+}
+
+// announce sends unsolicited responses on start (RFC 6762 §8.3).
+func (r *Responder) announce(ctx context.Context) {
+	for i := range 2 {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+		r.send(r.response(0, nil, nil, recordTTL), group)
+	}
+}
+
+func (r *Responder) handle(pkt []byte, src *net.UDPAddr) {
+	var p dnsmessage.Parser
+	h, err := p.Start(pkt)
+	if err != nil || h.Response {
+		return
+	}
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return
+	}
+	var asked []dnsmessage.Question
+	unicast := false
+	for _, q := range questions {
+		if r.answers(q) {
+			asked = append(asked, q)
+			unicast = unicast || q.Class&unicastReply != 0
+		}
+	}
+	if len(asked) == 0 {
+		return
+	}
+
+	// A query from a port other than 5353 comes from a simple resolver
+	// ("legacy unicast"): reply to it directly, echoing ID and questions.
+	if src.Port != mdnsPort {
+		r.send(r.response(h.ID, questions, asked, legacyTTL), src)
+		return
+	}
+	dst := group
+	if unicast {
+		dst = src
+	}
+	r.send(r.response(0, nil, asked, recordTTL), dst)
+}
+
+// answers reports whether q asks for one of the responder's records.
+func (r *Responder) answers(q dnsmessage.Question) bool {
+	for _, rec := range r.records(recordTTL, false) {
+		if matches(q, rec.Header) {
+			return true
+		}
+	}
+	return false
+}
+
+// response builds a reply. Records matching asked go into the answer
+// section and the rest into additionals; with asked nil (announcement or
+// goodbye) every record is an answer.
+func (r *Responder) response(id uint16, questions, asked []dnsmessage.Question, ttl uint32) []byte {
+	legacy := questions != nil
+	var answers, extra []dnsmessage.Resource
+	for _, rec := range r.records(ttl, !legacy) {
+		switch {
+		case asked == nil && rec.Header.Name == metaName:
+			// The meta-query record is only sent when asked for.
+		case asked == nil:
+			answers = append(answers, rec)
+		case matchesAny(asked, rec.Header):
+			answers = append(answers, rec)
+		case rec.Header.Name != metaName:
+			extra = append(extra, rec)
+		}
+	}
+
+	b := dnsmessage.NewBuilder(make([]byte, 0, 512), dnsmessage.Header{ID: id, Response: true, Authoritative: true})
+	b.EnableCompression()
+	if legacy {
+		_ = b.StartQuestions()
+		for _, q := range questions {
+			_ = b.Question(q)
+		}
+	}
+	_ = b.StartAnswers()
+	for _, rec := range answers {
+		addResource(&b, rec)
+	}
+	_ = b.StartAdditionals()
+	for _, rec := range extra {
+		addResource(&b, rec)
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		slog.Debug("mdns response not built", "err", err)
+		return nil
+	}
+	return msg
+}
+
+// records returns the responder's resource records. Unique records carry
+// the cache-flush bit in multicast responses.
+func (r *Responder) records(ttl uint32, flush bool) []dnsmessage.Resource {
+	unique := dnsmessage.ClassINET
+	if flush {
+		unique |= cacheFlush
+	}
+	hdr := func(name dnsmessage.Name, typ dnsmessage.Type, class dnsmessage.Class) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: typ, Class: class, TTL: ttl}
+	}
+	txt := r.cfg.TXT
+	if len(txt) == 0 {
+		txt = []string{""}
+	}
+	recs := []dnsmessage.Resource{
+		{Header: hdr(metaName, dnsmessage.TypePTR, dnsmessage.ClassINET), Body: &dnsmessage.PTRResource{PTR: serviceName}},
+		{Header: hdr(serviceName, dnsmessage.TypePTR, dnsmessage.ClassINET), Body: &dnsmessage.PTRResource{PTR: r.instance}},
+		{Header: hdr(r.instance, dnsmessage.TypeSRV, unique), Body: &dnsmessage.SRVResource{Port: uint16(r.cfg.Port), Target: r.host}},
+		{Header: hdr(r.instance, dnsmessage.TypeTXT, unique), Body: &dnsmessage.TXTResource{TXT: txt}},
+	}
+	for _, ip := range localAddrs() {
+		recs = append(recs, dnsmessage.Resource{Header: hdr(r.host, dnsmessage.TypeA, unique), Body: &dnsmessage.AResource{A: ip}})
+	}
+	return recs
+}
+
+func (r *Responder) send(msg []byte, dst *net.UDPAddr) {
+	if msg == nil {
+		return
+	}
+	if _, err := r.conn.WriteToUDP(msg, dst); err != nil {
+		slog.Debug("mdns send failed", "dst", dst, "err", err)
+	}
+}
+
+// Server is a suffuse server found by Browse.
+type Server struct {
+	// Instance is the advertised name, normally the server's source.
+	Instance string
+	// Addr is host:port to dial: the address the answer came from and the
+	// advertised port.
+	Addr string
+	// TXT holds the advertised key=value pairs.
+	TXT map[string]string
+}
+
+// Browse queries the local network for suffuse servers until ctx is done and
+// returns those that answered, in the order they answered.
+func Browse(ctx context.Context) ([]Server, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("mdns: %w", err)
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetReadDeadline(dl)
+	}
+	go func() {
+		<-ctx.Done()
+		_ = conn.SetReadDeadline(time.Now())
+	}()
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	_ = b.StartQuestions()
+	_ = b.Question(dnsmessage.Question{Name: serviceName, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET})
+	query, err := b.Finish()
+	if err != nil {
+		return nil, fmt.Errorf("mdns: %w", err)
+	}
+	if _, err := conn.WriteToUDP(query, group); err != nil {
+		return nil, fmt.Errorf("mdns: %w", err)
+	}
+
+	var found []Server
+	seen := make(map[string]bool)
+	buf := make([]byte, maxPacket)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+		for _, s := range parseResponse(buf[:n], src) {
+			if !seen[s.Addr] {
+				seen[s.Addr] = true
+				found = append(found, s)
+			}
+		}
+	}
+	return found, nil
+}
+
+// parseResponse extracts the suffuse instances described in one response.
+func parseResponse(pkt []byte, src *net.UDPAddr) []Server {
+	var p dnsmessage.Parser
+	h, err := p.Start(pkt)
+	if err != nil || !h.Response {
+		return nil
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil
+	}
+	answers, err := p.AllAnswers()
+	if err != nil {
+		return nil
+	}
+	_ = p.SkipAllAuthorities()
+	additionals, _ := p.AllAdditionals()
+
+	var instances []string
+	ports := make(map[string]uint16)
+	txts := make(map[string]map[string]string)
+	for _, rec := range append(answers, additionals...) {
+		name := strings.ToLower(rec.Header.Name.String())
+		switch body := rec.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if strings.EqualFold(name, serviceName.String()) && rec.Header.TTL > 0 {
+				instances = append(instances, strings.ToLower(body.PTR.String()))
+			}
+		case *dnsmessage.SRVResource:
+			ports[name] = body.Port
+		case *dnsmessage.TXTResource:
+			kv := make(map[string]string)
+			for _, s := range body.TXT {
+				if k, v, ok := strings.Cut(s, "="); ok {
+					kv[k] = v
+				}
+			}
+			txts[name] = kv
+		}
+	}
+
+	var out []Server
+	for _, inst := range instances {
+		port, ok := ports[inst]
+		if !ok {
+			continue
+		}
+		out = append(out, Server{
+			Instance: strings.TrimSuffix(inst, "."+strings.ToLower(serviceName.String())),
+			Addr:     net.JoinHostPort(src.IP.String(), fmt.Sprint(port)),
+			TXT:      txts[inst],
+		})
+	}
+	return out
+}
+
+func matches(q dnsmessage.Question, h dnsmessage.ResourceHeader) bool {
+	return (q.Type == h.Type || q.Type == dnsmessage.TypeALL) &&
+		strings.EqualFold(q.Name.String(), h.Name.String())
+}
+
+func matchesAny(qs []dnsmessage.Question, h dnsmessage.ResourceHeader) bool {
+	for _, q := range qs {
+		if matches(q, h) {
+			return true
+		}
+	}
+	return false
+}
+
+func addResource(b *dnsmessage.Builder, rec dnsmessage.Resource) {
+	var err error
+	switch body := rec.Body.(type) {
+	case *dnsmessage.PTRResource:
+		err = b.PTRResource(rec.Header, *body)
+	case *dnsmessage.SRVResource:
+		err = b.SRVResource(rec.Header, *body)
+	case *dnsmessage.TXTResource:
+		err = b.TXTResource(rec.Header, *body)
+	case *dnsmessage.AResource:
+		err = b.AResource(rec.Header, *body)
+	}
+	if err != nil {
+		slog.Debug("mdns record dropped", "name", rec.Header.Name, "err", err)
+	}
+}
+
+// localAddrs returns the IPv4 addresses of the up, non-loopback interfaces.
+func localAddrs() [][4]byte {
+	var out [][4]byte
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipn, ok := a.(*net.IPNet); ok {
+				if ip4 := ipn.IP.To4(); ip4 != nil {
+					out = append(out, [4]byte(ip4))
+				}
+			}
+		}
+	}
+	return out
+}
+
+// label makes s usable as a single DNS label: dots would split it and labels
+// are limited to 63 bytes.
+func label(s string) string {
+	s = strings.ReplaceAll(s, ".", "-")
+	if len(s) > 63 {
+		s = s[:63]
+	}
+	if s == "" {
+		s = "suffuse"
+	}
+	return s
+}
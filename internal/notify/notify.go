@@ -0,0 +1,22 @@
+// Package notify shows desktop notifications with the tools each platform
+// ships, for the server to tell the user about events they may act on.
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// timeout bounds how long showing a notification may take.
+const timeout = 5 * time.Second
+
+var errUnsupported = errors.New("desktop notifications are not supported on this platform")
+
+// Send shows a notification with title and body. It fails where no
+// notification tool is available, e.g. on a headless host.
+func Send(title, body string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return send(ctx, title, body)
+}
@@ -0,0 +1,26 @@
+//go:build darwin
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// send has osascript display the notification.
+func send(ctx context.Context, title, body string) error {
+	script := fmt.Sprintf("display notification %s with title %s", appleScriptString(body), appleScriptString(title))
+	if out, err := exec.CommandContext(ctx, "osascript", "-e", script).CombinedOutput(); err != nil {
+		return fmt.Errorf("osascript: %w: %s", err, out)
+	}
+	return nil
+}
+
+// appleScriptString quotes s as an AppleScript string literal, which only
+// knows the backslash and double-quote escapes; line breaks become spaces.
+func appleScriptString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", " ", "\n", " ")
+	return `"` + r.Replace(s) + `"`
+}
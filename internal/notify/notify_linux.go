@@ -0,0 +1,18 @@
+//go:build linux
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// send runs notify-send, which reaches the desktop's notification daemon
+// over the session D-Bus.
+func send(ctx context.Context, title, body string) error {
+	if out, err := exec.CommandContext(ctx, "notify-send", "--app-name=suffuse", "--", title, body).CombinedOutput(); err != nil {
+		return fmt.Errorf("notify-send: %w: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package notify
+
+import "context"
+
+// send has no implementation on this platform.
+func send(context.Context, string, string) error { return errUnsupported }
@@ -0,0 +1,35 @@
+//go:build windows
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// toastScript shows a toast through the WinRT notification API, attributed to
+// PowerShell's application ID as suffuse has none registered. The title and
+// body come from the environment so they need no quoting.
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($env:SUFFUSE_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:SUFFUSE_NOTIFY_BODY)) > $null
+$app = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($app).Show([Windows.UI.Notifications.ToastNotification]::new($xml))
+`
+
+// send shows a toast. It fails for the service, which runs as SYSTEM in a
+// session without a desktop; a server started in the user's session shows
+// it.
+func send(ctx context.Context, title, body string) error {
+	cmd := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(), "SUFFUSE_NOTIFY_TITLE="+title, "SUFFUSE_NOTIFY_BODY="+body)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("powershell: %w: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,467 @@
+// Package pairing lets a new device obtain a per-peer token from a server by
+// entering a short code shown on the server's host, instead of having the
+// shared secret copied to it.
+//
+// The code authenticates a SPAKE2 exchange, so it never crosses the network
+// and the connection needs no verified TLS: a server without the code cannot
+// confirm the exchange, and one that is confirmed seals the issued token
+// with the key both sides derived. Codes expire after a few minutes and
+// after MaxAttempts exchanges, and are used once.
+package pairing
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.klb.dev/suffuse/internal/tlsconf"
+	"go.klb.dev/suffuse/internal/tokens"
+)
+
+const (
+	// DefaultTTL is how long a code stays valid.
+	DefaultTTL = 5 * time.Minute
+	// MaxAttempts is the number of exchanges a code allows, so it cannot be
+	// guessed online either.
+	MaxAttempts = 3
+	// codeDigits is the length of a code.
+	codeDigits = 6
+)
+
+var (
+	// ErrNoCode is returned when no code is active: none was started, or it
+	// expired, was used, or ran out of attempts.
+	ErrNoCode = errors.New("no pairing code is active; run \"suffuse pair\" on the server's host")
+	// ErrWrongCode is returned when the two sides used different codes.
+	ErrWrongCode = errors.New("pairing failed: wrong code")
+)
+
+// Offer describes the token a code is exchanged for.
+type Offer struct {
+	// Name identifies the token in logs; empty takes the name the device
+	// gives when it pairs.
+	Name       string
+	Role       tokens.Role
+	Clipboards []string
+	// TTL is how long the code is valid; zero means DefaultTTL.
+	TTL time.Duration
+}
+
+// Credential is what a device receives when pairing succeeds.
+type Credential struct {
+	Name       string      `json:"name"`
+	Token      string      `json:"token"`
+	Role       tokens.Role `json:"role"`
+	Clipboards []string    `json:"clipboards,omitempty"`
+}
+
+// record is a paired device as saved in the paired tokens file.
+type record struct {
+	Credential
+	PairedAt time.Time `json:"paired_at,omitzero"`
+}
+
+// Device is a paired device, as listed for revocation.
+type Device struct {
+	Name       string
+	Role       tokens.Role
+	Clipboards []string
+	// Fingerprint identifies the device's TLS key, derived from its token.
+	Fingerprint string
+	PairedAt    time.Time
+	// LastSeen is when the device's token was last accepted since the
+	// server started; zero if it has not been.
+	LastSeen time.Time
+}
+
+// ErrUnknownDevice is returned by Revoke when no paired device matches.
+var ErrUnknownDevice = errors.New("no paired device matches")
+
+// Pairer issues codes on a server and exchanges them for tokens, which it
+// adds to the server's token set and saves so they outlive a restart.
+type Pairer struct {
+	path   string
+	tokens *tokens.Set
+
+	mu     sync.Mutex
+	active *code
+}
+
+// code is the active pairing code.
+type code struct {
+	Offer
+	w        []byte
+	expires  time.Time
+	attempts int
+}
+
+// DefaultPath returns the file paired tokens are saved to, under the user's
+// config directory, e.g. ~/.config/suffuse/paired-tokens.json on Linux.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "suffuse", "paired-tokens.json")
+}
+
+// Load returns the tokens saved at path, for the server's token set. A
+// missing file holds none.
+func Load(path string) ([]tokens.Scoped, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pairing: %w", err)
+	}
+	var saved []record
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("pairing: %s: %w", path, err)
+	}
+	out := make([]tokens.Scoped, len(saved))
+	for i, c := range saved {
+		out[i] = tokens.Scoped{Name: c.Name, Token: c.Token, Role: c.Role, Clipboards: c.Clipboards}
+	}
+	return out, nil
+}
+
+// New returns a Pairer adding tokens to ts and saving them to path. Load
+// the tokens saved before into ts when creating it.
+func New(path string, ts *tokens.Set) *Pairer {
+	return &Pairer{path: path, tokens: ts}
+}
+
+// Start makes a new code active for o, replacing any other, and returns it
+// with its expiry.
+func (p *Pairer) Start(o Offer) (string, time.Time, error) {
+	probe := tokens.Scoped{Name: o.Name, Token: "-", Role: o.Role, Clipboards: o.Clipboards}
+	if err := probe.Check(); err != nil {
+		return "", time.Time{}, err
+	}
+	if o.TTL <= 0 {
+		o.TTL = DefaultTTL
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	value := fmt.Sprintf("%0*d", codeDigits, n)
+	w, err := passwordScalar(value)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	c := &code{Offer: o, w: w, expires: time.Now().Add(o.TTL)}
+	p.mu.Lock()
+	p.active = c
+	p.mu.Unlock()
+	slog.Info("pairing code issued", "role", o.Role, "expires", c.expires.Format(time.TimeOnly))
+	return value, c.expires, nil
+}
+
+// NormalizeCode strips the separators a code may be typed with and checks
+// its length.
+func NormalizeCode(s string) (string, error) {
+	s = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, s)
+	if len(s) != codeDigits || strings.Trim(s, "0123456789") != "" {
+		return "", fmt.Errorf("a pairing code has %d digits", codeDigits)
+	}
+	return s, nil
+}
+
+// Exchange is the server side of one pairing attempt.
+type Exchange struct {
+	p      *Pairer
+	c      *code
+	device string
+	keys   keys
+	shareB []byte
+}
+
+// Begin answers a device's key share with the server's share and
+// confirmation. Every call counts as an attempt at the active code.
+func (p *Pairer) Begin(device string, shareA []byte) (*Exchange, []byte, []byte, error) {
+	p.mu.Lock()
+	c := p.active
+	if c != nil && (time.Now().After(c.expires) || c.attempts >= MaxAttempts) {
+		p.active, c = nil, nil
+	}
+	if c != nil {
+		c.attempts++
+	}
+	p.mu.Unlock()
+	if c == nil {
+		return nil, nil, nil, ErrNoCode
+	}
+
+	y, err := randomScalar()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	shareB := share(y, c.w, pointN)
+	k, err := sharedPoint(y, c.w, shareA, pointM)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ks, err := deriveKeys(shareA, shareB, k, c.w)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	e := &Exchange{p: p, c: c, device: device, keys: ks, shareB: shareB}
+	return e, shareB, confirmation(ks.confirmB, shareA), nil
+}
+
+// Finish checks the device's confirmation and, when it holds, uses up the
+// code, issues and saves a token and returns it sealed for the device.
+func (e *Exchange) Finish(confirmA []byte) ([]byte, error) {
+	if !hmac.Equal(confirmA, confirmation(e.keys.confirmA, e.shareB)) {
+		slog.Warn("pairing attempt with a wrong code", "device", e.device)
+		return nil, ErrWrongCode
+	}
+	p := e.p
+	p.mu.Lock()
+	if p.active != e.c || time.Now().After(e.c.expires) {
+		p.mu.Unlock()
+		return nil, ErrNoCode
+	}
+	p.active = nil
+	p.mu.Unlock()
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	cred := Credential{
+		Name:       e.c.Name,
+		Token:      base64.RawURLEncoding.EncodeToString(secret),
+		Role:       e.c.Role,
+		Clipboards: e.c.Clipboards,
+	}
+	if cred.Name == "" {
+		cred.Name = e.device
+	}
+	if err := p.save(cred); err != nil {
+		return nil, err
+	}
+	p.tokens.AddScoped(tokens.Scoped{Name: cred.Name, Token: cred.Token, Role: cred.Role, Clipboards: cred.Clipboards})
+	slog.Info("device paired", "name", cred.Name, "role", cred.Role)
+	return seal(e.keys.seal, cred)
+}
+
+// save appends cred to the paired tokens file.
+func (p *Pairer) save(cred Credential) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	saved, err := p.loadLocked()
+	if err != nil {
+		return err
+	}
+	return p.storeLocked(append(saved, record{Credential: cred, PairedAt: time.Now()}))
+}
+
+func (p *Pairer) loadLocked() ([]record, error) {
+	var saved []record
+	data, err := os.ReadFile(p.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pairing: %w", err)
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("pairing: %s: %w", p.path, err)
+	}
+	return saved, nil
+}
+
+func (p *Pairer) storeLocked(saved []record) error {
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o700); err != nil {
+		return fmt.Errorf("pairing: %w", err)
+	}
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("pairing: %w", err)
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		return fmt.Errorf("pairing: %w", err)
+	}
+	return nil
+}
+
+// Devices returns the paired devices in the order they paired.
+func (p *Pairer) Devices() ([]Device, error) {
+	p.mu.Lock()
+	saved, err := p.loadLocked()
+	p.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Device, 0, len(saved))
+	for _, r := range saved {
+		out = append(out, p.device(r))
+	}
+	return out, nil
+}
+
+// Revoke forgets the paired device whose name or fingerprint is match and
+// stops accepting its token; streams it has open end. A name shared by
+// several devices is refused: revoke them by fingerprint.
+func (p *Pairer) Revoke(match string) (Device, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	saved, err := p.loadLocked()
+	if err != nil {
+		return Device{}, err
+	}
+	i, found := -1, 0
+	for j, r := range saved {
+		if r.Name == match || fingerprint(r.Token) == match {
+			i = j
+			found++
+		}
+	}
+	switch {
+	case found == 0:
+		return Device{}, fmt.Errorf("%w %q", ErrUnknownDevice, match)
+	case found > 1:
+		return Device{}, fmt.Errorf("%d paired devices are named %q; revoke one by fingerprint", found, match)
+	}
+	r := saved[i]
+	d := p.device(r)
+	if err := p.storeLocked(append(saved[:i:i], saved[i+1:]...)); err != nil {
+		return Device{}, err
+	}
+	p.tokens.RemoveScoped(r.Token)
+	return d, nil
+}
+
+func (p *Pairer) device(r record) Device {
+	return Device{
+		Name:        r.Name,
+		Role:        r.Role,
+		Clipboards:  r.Clipboards,
+		Fingerprint: fingerprint(r.Token),
+		PairedAt:    r.PairedAt,
+		LastSeen:    p.tokens.LastSeen(r.Token),
+	}
+}
+
+// fingerprint returns the fingerprint of the TLS key derived from tok.
+func fingerprint(tok string) string {
+	fp, err := tlsconf.Fingerprint(tok)
+	if err != nil {
+		return "?"
+	}
+	return fp
+}
+
+// seal encrypts cred with AES-GCM under key.
+func seal(key []byte, cred Credential) ([]byte, error) {
+	plain, err := json.Marshal(cred)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Client is the device side of one pairing attempt.
+type Client struct {
+	x, w   []byte
+	shareA []byte
+	keys   keys
+}
+
+// NewClient starts an attempt with code, as typed by the user.
+func NewClient(c string) (*Client, error) {
+	c, err := NormalizeCode(c)
+	if err != nil {
+		return nil, err
+	}
+	w, err := passwordScalar(c)
+	if err != nil {
+		return nil, err
+	}
+	x, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	return &Client{x: x, w: w, shareA: share(x, w, pointM)}, nil
+}
+
+// Share returns the key share to send to the server.
+func (c *Client) Share() []byte { return c.shareA }
+
+// Confirm checks the server's share and confirmation and returns the
+// confirmation to send back. ErrWrongCode means the server holds another
+// code.
+func (c *Client) Confirm(shareB, confirmB []byte) ([]byte, error) {
+	k, err := sharedPoint(c.x, c.w, shareB, pointN)
+	if err != nil {
+		return nil, err
+	}
+	c.keys, err = deriveKeys(c.shareA, shareB, k, c.w)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(confirmB, confirmation(c.keys.confirmB, c.shareA)) {
+		return nil, ErrWrongCode
+	}
+	return confirmation(c.keys.confirmA, shareB), nil
+}
+
+// Open decrypts the credential the server sealed, after Confirm.
+func (c *Client) Open(sealed []byte) (Credential, error) {
+	gcm, err := newGCM(c.keys.seal)
+	if err != nil {
+		return Credential{}, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return Credential{}, errors.New("pairing: credential too short")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return Credential{}, fmt.Errorf("pairing: open credential: %w", err)
+	}
+	var cred Credential
+	if err := json.Unmarshal(plain, &cred); err != nil {
+		return Credential{}, fmt.Errorf("pairing: credential: %w", err)
+	}
+	return cred, nil
+}
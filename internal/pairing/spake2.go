@@ -0,0 +1,170 @@
+package pairing
+
+import (
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"filippo.io/bigmod"
+	"filippo.io/nistec"
+	"golang.org/x/crypto/hkdf"
+)
+
+// SPAKE2 over P-256 as in RFC 9382, with the code as the password. Each side
+// sends its key share blinded by the code; only a peer that knows the code
+// derives the same keys, and a wrong guess costs an attacker one exchange
+// rather than revealing anything to test codes against offline.
+//
+// Points are nistec's and scalars bigmod's, so no operation on the code or
+// the secrets takes time that depends on them. Scalars are 32-byte
+// big-endian values reduced modulo the group order.
+
+// The RFC 9382 points M and N for P-256, whose discrete logs are unknown.
+var (
+	pointM = mustPoint("02886e2f97ace46e55ba9dd7242579f2993b64e16ef3dcab95afd497333d8fa12f")
+	pointN = mustPoint("03d8bbd6c639c62937b04d997f38c3770719c629d7014d49a24b4f98baa1292b49")
+)
+
+// order is the order of the P-256 group, and shift 2²⁵⁶ modulo it.
+var (
+	order = mustModulus("ffffffff00000000ffffffffffffffffbce6faada7179e84f3b9cac2fc632551")
+	shift = mustNat("00000000ffffffff00000000000000004319055258e8617b0c46353d039cdaaf")
+)
+
+var errBadShare = errors.New("pairing: invalid key share")
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func mustPoint(s string) *nistec.P256Point {
+	p, err := nistec.NewP256Point().SetBytes(mustHex(s))
+	if err != nil {
+		panic("pairing: invalid point " + s)
+	}
+	return p
+}
+
+func mustModulus(s string) *bigmod.Modulus {
+	m, err := bigmod.NewModulus(mustHex(s))
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func mustNat(s string) *bigmod.Nat {
+	n, err := bigmod.NewNat().SetBytes(mustHex(s), order)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// passwordScalar maps the code to the scalar w. Codes are short, so a
+// costly derivation would not help: guesses are limited online instead.
+// The 64 bytes derived are reduced as hi·2²⁵⁶ + lo so w is unbiased.
+func passwordScalar(code string) ([]byte, error) {
+	buf := make([]byte, 64)
+	r := hkdf.New(sha256.New, []byte(code), []byte("suffuse-pair-v1"), []byte("password"))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	hi, err := bigmod.NewNat().SetOverflowingBytes(buf[:32], order)
+	if err != nil {
+		return nil, err
+	}
+	lo, err := bigmod.NewNat().SetOverflowingBytes(buf[32:], order)
+	if err != nil {
+		return nil, err
+	}
+	return hi.Mul(shift, order).Add(lo, order).Bytes(order), nil
+}
+
+// randomScalar returns a uniformly random scalar in [1, N-1]: that of a
+// fresh P-256 private key.
+func randomScalar() ([]byte, error) {
+	k, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return k.Bytes(), nil
+}
+
+// share returns secret·G + w·blind, compressed.
+func share(secret, w []byte, blind *nistec.P256Point) []byte {
+	p, err := nistec.NewP256Point().ScalarBaseMult(secret)
+	if err != nil {
+		panic(err) // scalars are always 32 bytes
+	}
+	b, err := nistec.NewP256Point().ScalarMult(blind, w)
+	if err != nil {
+		panic(err)
+	}
+	return p.Add(p, b).BytesCompressed()
+}
+
+// sharedPoint returns secret·(peer − w·blind), the point both sides arrive
+// at when they used the same code.
+func sharedPoint(secret, w, peer []byte, blind *nistec.P256Point) (*nistec.P256Point, error) {
+	p, err := nistec.NewP256Point().SetBytes(peer)
+	if err != nil || len(peer) != 33 {
+		return nil, errBadShare
+	}
+	b, err := nistec.NewP256Point().ScalarMult(blind, w)
+	if err != nil {
+		return nil, err
+	}
+	p.Add(p, b.Negate(b))
+	if _, err := p.ScalarMult(p, secret); err != nil {
+		return nil, err
+	}
+	if p.IsInfinity() == 1 {
+		return nil, errBadShare
+	}
+	return p, nil
+}
+
+// keys are what an exchange yields: the key sealing the credential and the
+// keys confirming each side.
+type keys struct {
+	seal, confirmA, confirmB []byte
+}
+
+// deriveKeys hashes the transcript of an exchange into its keys.
+func deriveKeys(shareA, shareB []byte, k *nistec.P256Point, w []byte) (keys, error) {
+	h := sha256.New()
+	for _, part := range [][]byte{
+		nil, nil, // identities: neither side has one yet
+		shareA, shareB,
+		k.Bytes(),
+		w,
+	} {
+		_ = binary.Write(h, binary.LittleEndian, uint64(len(part)))
+		h.Write(part)
+	}
+	sum := h.Sum(nil)
+	ke, ka := sum[:16], sum[16:]
+	kc := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ka, nil, []byte("ConfirmationKeys")), kc); err != nil {
+		return keys{}, err
+	}
+	return keys{seal: ke, confirmA: kc[:16], confirmB: kc[16:]}, nil
+}
+
+// confirmation returns the MAC proving knowledge of key over the peer's
+// share.
+func confirmation(key, peerShare []byte) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write(peerShare)
+	return m.Sum(nil)
+}
@@ -0,0 +1,249 @@
+// Package probe measures end-to-end delivery through a server's federation.
+//
+// Every interval the Prober publishes a tiny ping on the hidden clipboard
+// hub.ProbeClipboard(source). Federation carries it like any other content
+// to the servers watching that clipboard, and each of them answers with a
+// pong naming itself, which travels back the same way. The round trip of
+// each answer is the latency to that server; a server that has answered
+// before but misses failAfter probes in a row is reported as failing.
+//
+// Results are reported in the prober's PeerInfo, so they appear in status
+// and metrics like any other peer's.
+package probe
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/hub"
+)
+
+// failAfter is the number of consecutive missed probes after which a server
+// is reported as failing.
+const failAfter = 3
+
+// forgetAfter is how long a server that stopped answering is still reported.
+const forgetAfter = 24 * time.Hour
+
+// Config describes the prober.
+type Config struct {
+	// Interval is the time between probes, and how long each probe waits
+	// for its answers.
+	Interval time.Duration
+	// Source is this server's source name, which the answering servers must
+	// not share.
+	Source string
+}
+
+// target is the record of one answering server.
+type target struct {
+	latency    time.Duration
+	lastAnswer time.Time
+	answered   uint64
+	missed     uint64
+	failing    uint32
+}
+
+// Prober sends probes and collects their answers. It implements hub.Peer.
+type Prober struct {
+	cfg Config
+	h   *hub.Hub
+
+	connectedAt time.Time
+
+	sent       atomic.Uint64
+	unanswered atomic.Uint64
+
+	mu      sync.Mutex
+	round   string              // ID of the probe in flight
+	roundAt time.Time           // when it was sent
+	replied map[string]struct{} // servers that answered it
+	targets map[string]*target
+	// quiet counts the consecutive probes no server answered, and
+	// quietWarned is set once that has been reported.
+	quiet       int
+	quietWarned bool
+}
+
+// New creates a Prober. Call Run in a goroutine to start probing.
+func New(cfg Config, h *hub.Hub) (*Prober, error) {
+	if cfg.Interval <= 0 {
+		return nil, errors.New("probe interval must be positive")
+	}
+	if cfg.Source == "" {
+		return nil, errors.New("probes need a source name")
+	}
+	return &Prober{
+		cfg:         cfg,
+		h:           h,
+		connectedAt: time.Now(),
+		targets:     make(map[string]*target),
+	}, nil
+}
+
+// ID implements hub.Peer.
+func (p *Prober) ID() string { return "probe" }
+
+// Info implements hub.Peer.
+func (p *Prober) Info() *pb.PeerInfo {
+	p.mu.Lock()
+	stats := &pb.ProbeStats{
+		Interval:   durationpb.New(p.cfg.Interval),
+		Sent:       p.sent.Load(),
+		Unanswered: p.unanswered.Load(),
+	}
+	lastSeen := p.connectedAt
+	for _, name := range slices.Sorted(maps.Keys(p.targets)) {
+		t := p.targets[name]
+		stats.Targets = append(stats.Targets, &pb.ProbeTarget{
+			Source:     name,
+			Latency:    durationpb.New(t.latency),
+			LastAnswer: timestamppb.New(t.lastAnswer),
+			Answered:   t.answered,
+			Missed:     t.missed,
+			Failing:    t.failing,
+		})
+		if t.lastAnswer.After(lastSeen) {
+			lastSeen = t.lastAnswer
+		}
+	}
+	p.mu.Unlock()
+	return &pb.PeerInfo{
+		Source:      p.cfg.Source,
+		Role:        "probe",
+		Clipboard:   hub.ProbeClipboard(p.cfg.Source),
+		ConnectedAt: timestamppb.New(p.connectedAt),
+		LastSeen:    timestamppb.New(lastSeen),
+		Probe:       stats,
+	}
+}
+
+// Send implements hub.Peer, recording answers to the probe in flight.
+// Answers to earlier probes arrived too late and are ignored.
+func (p *Prober) Send(ev hub.Event) error {
+	pong, ok := hub.ParseProbe(ev.Items)
+	if !ok || pong.Kind != hub.ProbePong || pong.From == "" {
+		return nil
+	}
+	now := time.Now()
+	p.mu.Lock()
+	if pong.ID != p.round {
+		p.mu.Unlock()
+		return nil
+	}
+	if _, dup := p.replied[pong.From]; dup {
+		p.mu.Unlock()
+		return nil
+	}
+	p.replied[pong.From] = struct{}{}
+	t := p.targets[pong.From]
+	if t == nil {
+		t = &target{}
+		p.targets[pong.From] = t
+	}
+	recovered := t.failing >= failAfter
+	t.latency = now.Sub(p.roundAt)
+	t.lastAnswer = now
+	t.answered++
+	t.failing = 0
+	latency := t.latency
+	p.mu.Unlock()
+
+	if recovered {
+		slog.Info("probe target answering again", "target", pong.From, "latency", latency)
+	} else {
+		slog.Debug("probe answered", "target", pong.From, "latency", latency)
+	}
+	return nil
+}
+
+// Run registers the prober with the hub and probes every interval until ctx
+// is cancelled.
+func (p *Prober) Run(ctx context.Context) {
+	p.h.Register(p)
+	defer p.h.Unregister(p)
+	slog.Info("end-to-end probes enabled", "clipboard", hub.ProbeClipboard(p.cfg.Source), "interval", p.cfg.Interval)
+
+	t := time.NewTicker(p.cfg.Interval)
+	defer t.Stop()
+	p.ping()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			p.evaluate()
+			p.ping()
+		}
+	}
+}
+
+// ping starts a new round.
+func (p *Prober) ping() {
+	id := rand.Text()
+	p.mu.Lock()
+	p.round, p.roundAt = id, time.Now()
+	p.replied = make(map[string]struct{})
+	p.mu.Unlock()
+	p.sent.Add(1)
+	p.h.Publish(context.Background(), hub.Probe{Kind: hub.ProbePing, ID: id, From: p.cfg.Source}.Items(),
+		hub.ProbeClipboard(p.cfg.Source), p.ID(), p.cfg.Source)
+}
+
+// evaluate closes the round in flight, counting the servers that missed it.
+func (p *Prober) evaluate() {
+	now := time.Now()
+	p.mu.Lock()
+	var failing []string
+	for name, t := range p.targets {
+		if _, ok := p.replied[name]; ok {
+			continue
+		}
+		if now.Sub(t.lastAnswer) > forgetAfter {
+			delete(p.targets, name)
+			continue
+		}
+		t.missed++
+		t.failing++
+		if t.failing == failAfter {
+			failing = append(failing, name)
+		}
+	}
+	unanswered := len(p.replied) == 0
+	recovered := !unanswered && p.quietWarned
+	if unanswered {
+		p.quiet++
+	} else {
+		p.quiet, p.quietWarned = 0, false
+	}
+	// Known servers are reported by name instead.
+	warnQuiet := p.quiet == failAfter && len(p.targets) == 0
+	if warnQuiet {
+		p.quietWarned = true
+	}
+	p.mu.Unlock()
+
+	if unanswered {
+		p.unanswered.Add(1)
+	}
+	for _, name := range failing {
+		slog.Warn("probe target not answering", "target", name, "missed", failAfter, "interval", p.cfg.Interval)
+	}
+	switch {
+	case warnQuiet:
+		slog.Warn("probes not answered by any server; is this server federated?", "clipboard", hub.ProbeClipboard(p.cfg.Source))
+	case recovered:
+		slog.Info("probes answered again")
+	}
+}
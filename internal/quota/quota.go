@@ -0,0 +1,265 @@
+// Package quota limits how much each source may copy to a server: a number
+// of copies per hour and a number of bytes per day. Both are token buckets
+// that refill continuously, so a source that stays under its rate is never
+// held back, and one that bursts recovers gradually rather than at the top
+// of the hour.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MaxThrottle is the longest ActionThrottle holds a copy back. A copy that
+// would have to wait longer is rejected.
+const MaxThrottle = time.Minute
+
+// Windows the quotas are measured over.
+const (
+	copiesWindow = time.Hour
+	bytesWindow  = 24 * time.Hour
+)
+
+// idleAfter is how long a source that is back under its quotas is kept
+// before its counters are forgotten.
+const idleAfter = bytesWindow
+
+// Action is what happens to a copy that exceeds a quota.
+type Action string
+
+const (
+	// ActionWarn accepts the copy and logs a warning, once per source until
+	// it is back under its quotas.
+	ActionWarn Action = "warn"
+	// ActionThrottle delays the copy until the quota allows it, up to
+	// MaxThrottle, and rejects it beyond that.
+	ActionThrottle Action = "throttle"
+	// ActionReject refuses the copy.
+	ActionReject Action = "reject"
+)
+
+// ParseAction validates an action name; empty means ActionReject.
+func ParseAction(s string) (Action, error) {
+	switch a := Action(s); a {
+	case "":
+		return ActionReject, nil
+	case ActionWarn, ActionThrottle, ActionReject:
+		return a, nil
+	}
+	return "", errors.New(`quota-action must be "warn", "throttle" or "reject"`)
+}
+
+// ErrExceeded is returned (wrapped) by Admit when a copy is refused.
+var ErrExceeded = errors.New("quota exceeded")
+
+// Config sets the quotas applied to every source. Zero disables a quota.
+type Config struct {
+	CopiesPerHour int
+	BytesPerDay   int64
+	Action        Action
+}
+
+// Usage describes one source's standing against its quotas. Copies and
+// Bytes are the amounts currently counted against the quotas; they fall
+// back towards zero as the buckets refill, and may exceed the quota under
+// ActionWarn.
+type Usage struct {
+	Source      string
+	Copies      uint64
+	CopiesLimit uint64
+	Bytes       uint64
+	BytesLimit  uint64
+	// Exceeded counts the copies that went over a quota since the source
+	// was last idle, whatever the action did with them.
+	Exceeded uint64
+}
+
+// bucket holds up to size tokens, refilled at rate per second. Tokens go
+// negative when a copy is admitted ahead of its time.
+type bucket struct {
+	size, rate, tokens float64
+}
+
+func newBucket(size float64, window time.Duration) bucket {
+	return bucket{size: size, rate: size / window.Seconds(), tokens: size}
+}
+
+func (b *bucket) refill(elapsed time.Duration) {
+	b.tokens = min(b.size, b.tokens+b.rate*elapsed.Seconds())
+}
+
+// wait returns how long until n tokens are available.
+func (b *bucket) wait(n float64) time.Duration {
+	if b.size == 0 || b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *bucket) take(n float64) {
+	if b.size > 0 {
+		b.tokens -= n
+	}
+}
+
+func (b *bucket) used() uint64 {
+	return uint64(max(0, math.Ceil(b.size-b.tokens)))
+}
+
+type source struct {
+	copies, bytes bucket
+	updated       time.Time
+	exceeded      uint64
+	warned        bool
+}
+
+// Limiter enforces a Config. The zero of *Limiter (nil) admits everything.
+type Limiter struct {
+	cfg Config
+
+	mu      sync.Mutex
+	sources map[string]*source
+}
+
+// New validates cfg and returns its Limiter, or nil when cfg limits nothing.
+func New(cfg Config) (*Limiter, error) {
+	if cfg.CopiesPerHour < 0 || cfg.BytesPerDay < 0 {
+		return nil, errors.New("quotas must not be negative")
+	}
+	if cfg.CopiesPerHour == 0 && cfg.BytesPerDay == 0 {
+		return nil, nil
+	}
+	if cfg.Action == "" {
+		cfg.Action = ActionReject
+	}
+	return &Limiter{cfg: cfg, sources: make(map[string]*source)}, nil
+}
+
+// Admit counts a copy of size bytes from src against its quotas. Under
+// ActionThrottle it blocks until the copy is allowed or ctx is done. It
+// returns an error wrapping ErrExceeded when the copy is refused.
+func (l *Limiter) Admit(ctx context.Context, src string, size int) error {
+	if l == nil {
+		return nil
+	}
+	n := float64(size)
+	l.mu.Lock()
+	s := l.sourceLocked(src, time.Now())
+	if n > s.bytes.size && s.bytes.size > 0 && l.cfg.Action != ActionWarn {
+		// Never fits, however long it waits.
+		s.exceeded++
+		l.mu.Unlock()
+		return fmt.Errorf("%w: copy of %d bytes is larger than the daily quota of %d", ErrExceeded, size, l.cfg.BytesPerDay)
+	}
+	wait := max(s.copies.wait(1), s.bytes.wait(n))
+	if wait == 0 {
+		s.copies.take(1)
+		s.bytes.take(n)
+		s.warned = false
+		l.mu.Unlock()
+		return nil
+	}
+	s.exceeded++
+	switch l.cfg.Action {
+	case ActionWarn:
+		s.copies.take(1)
+		s.bytes.take(n)
+		warn := !s.warned
+		s.warned = true
+		copies, bytes := s.copies.used(), s.bytes.used()
+		l.mu.Unlock()
+		if warn {
+			slog.Warn("source over quota", "source", src, "copies", copies, "bytes", bytes)
+		}
+		return nil
+	case ActionThrottle:
+		if deadline, ok := ctx.Deadline(); wait > MaxThrottle || ok && time.Until(deadline) < wait {
+			l.mu.Unlock()
+			return fmt.Errorf("%w: next copy allowed in %s", ErrExceeded, wait.Round(time.Second))
+		}
+		// Take the tokens now so concurrent copies queue up behind this one.
+		s.copies.take(1)
+		s.bytes.take(n)
+		l.mu.Unlock()
+		slog.Debug("copy throttled", "source", src, "wait", wait)
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	default:
+		l.mu.Unlock()
+		return fmt.Errorf("%w: next copy allowed in %s", ErrExceeded, wait.Round(time.Second))
+	}
+}
+
+// sourceLocked returns src's counters brought up to now, and forgets sources
+// that have been idle for a day. Must be called with l.mu held.
+func (l *Limiter) sourceLocked(src string, now time.Time) *source {
+	l.pruneLocked(now)
+	s := l.sources[src]
+	if s == nil {
+		s = &source{
+			copies:  newBucket(float64(l.cfg.CopiesPerHour), copiesWindow),
+			bytes:   newBucket(float64(l.cfg.BytesPerDay), bytesWindow),
+			updated: now,
+		}
+		l.sources[src] = s
+	}
+	s.refill(now)
+	return s
+}
+
+// pruneLocked forgets sources idle for longer than idleAfter, by which time
+// both buckets are full again. Must be called with l.mu held.
+func (l *Limiter) pruneLocked(now time.Time) {
+	for name, s := range l.sources {
+		if now.Sub(s.updated) > idleAfter {
+			delete(l.sources, name)
+		}
+	}
+}
+
+func (s *source) refill(now time.Time) {
+	elapsed := now.Sub(s.updated)
+	s.copies.refill(elapsed)
+	s.bytes.refill(elapsed)
+	s.updated = now
+}
+
+// Usage returns the standing of every source seen recently, ordered by
+// source.
+func (l *Limiter) Usage() []Usage {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.pruneLocked(now)
+	var out []Usage
+	for _, name := range slices.Sorted(maps.Keys(l.sources)) {
+		// Refill a copy: looking must not keep a source from going idle.
+		s := *l.sources[name]
+		s.refill(now)
+		out = append(out, Usage{
+			Source:      name,
+			Copies:      s.copies.used(),
+			CopiesLimit: uint64(l.cfg.CopiesPerHour),
+			Bytes:       s.bytes.used(),
+			BytesLimit:  uint64(l.cfg.BytesPerDay),
+			Exceeded:    s.exceeded,
+		})
+	}
+	return out
+}
@@ -0,0 +1,22 @@
+// Package reexec restarts the running program in place, as "suffuse
+// restart" asks the server to: the same executable with the same arguments
+// and environment, so a new binary and the current configuration file take
+// effect without the service manager noticing.
+package reexec
+
+import "os"
+
+// Exec restarts the program. The process image is replaced and the process
+// ID kept, so systemd and launchd keep tracking the server; Exec returns
+// only when that fails, as it always does outside Unix, where the IPC
+// socket "suffuse restart" reaches the server over is not implemented yet.
+//
+// Files the program opened are closed on exec, releasing locks and
+// listeners, but the caller should close them first where it can.
+func Exec() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return execve(exe, os.Args, os.Environ())
+}
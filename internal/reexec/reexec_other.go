@@ -0,0 +1,9 @@
+//go:build !unix
+
+package reexec
+
+import "errors"
+
+func execve(string, []string, []string) error {
+	return errors.New("restarting in place is not supported on this platform")
+}
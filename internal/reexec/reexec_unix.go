@@ -0,0 +1,9 @@
+//go:build unix
+
+package reexec
+
+import "golang.org/x/sys/unix"
+
+func execve(exe string, args, env []string) error {
+	return unix.Exec(exe, args, env)
+}
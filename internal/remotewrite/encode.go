@@ -0,0 +1,70 @@
+package remotewrite
+
+import (
+	"encoding/binary"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type label struct{ name, value string }
+
+type timeSeries struct {
+	labels    []label // sorted by name
+	value     float64
+	timestamp int64 // milliseconds since the Unix epoch
+}
+
+// marshalWriteRequest encodes a prometheus.WriteRequest:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label        { string name = 1; string value = 2; }
+//	message Sample       { double value = 1; int64 timestamp = 2; }
+func marshalWriteRequest(series []timeSeries) []byte {
+	var out []byte
+	for _, s := range series {
+		var ts []byte
+		for _, l := range s.labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.name)
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.value)
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, lb)
+		}
+		var sb []byte
+		sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+		sb = protowire.AppendFixed64(sb, math.Float64bits(s.value))
+		sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+		sb = protowire.AppendVarint(sb, uint64(s.timestamp))
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sb)
+
+		out = protowire.AppendTag(out, 1, protowire.BytesType)
+		out = protowire.AppendBytes(out, ts)
+	}
+	return out
+}
+
+// snappyEncode wraps src in the snappy block format using literal chunks
+// only. The payloads are a few kilobytes, so skipping compression costs
+// little and any snappy decoder accepts the result.
+func snappyEncode(src []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		n := min(len(src), 1<<16)
+		switch m := n - 1; {
+		case m < 60:
+			out = append(out, byte(m)<<2)
+		case m < 1<<8:
+			out = append(out, 60<<2, byte(m))
+		default:
+			out = append(out, 61<<2, byte(m), byte(m>>8))
+		}
+		out = append(out, src[:n]...)
+		src = src[n:]
+	}
+	return out
+}
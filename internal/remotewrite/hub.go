@@ -0,0 +1,104 @@
+package remotewrite
+
+import "go.klb.dev/suffuse/internal/hub"
+
+// HubCollector exports hub metrics:
+//
+//	suffuse_peers{role}                                          connected peers per role
+//	suffuse_clipboards                                           clipboards holding content
+//	suffuse_publishes_total                                      clipboard updates published
+//	suffuse_published_bytes_total                                bytes published
+//	suffuse_duplicates_suppressed_total                          publishes suppressed as duplicates
+//	suffuse_hop_limited_total                                    events not relayed because their hop limit was spent
+//	suffuse_loops_total                                          federated events dropped for returning to this server
+//	suffuse_oversized_total                                      publishes refused for exceeding a size limit
+//	suffuse_transfer_bytes_total{direction}                      content bytes received (in) and sent (out)
+//	suffuse_clipboard_transfer_bytes_total{clipboard,direction}  the same per clipboard
+//	suffuse_blobs                                                blobs held for referenced items
+//	suffuse_blob_bytes                                           size of held blobs
+//	suffuse_blobs_pruned_total                                   blobs removed by garbage collection
+//	suffuse_dropped_events_total{subsystem}                      events dropped by slow consumers
+//	suffuse_quota_exceeded_total{source}                         copies over a per-source quota
+//	suffuse_webhook_delivered_total{hook}                        webhook events delivered
+//	suffuse_webhook_retries_total{hook}                          webhook attempts retried
+//	suffuse_webhook_dead_lettered_total{hook}                    webhook events abandoned
+//	suffuse_probes_sent_total                                    end-to-end probes sent
+//	suffuse_probes_unanswered_total                              probes no server answered
+//	suffuse_probe_latency_seconds{target}                        round trip of the last answer from a server
+//	suffuse_probe_answered_total{target}                         probes a server answered
+//	suffuse_probe_missed_total{target}                           probes a server missed
+//	suffuse_probe_failing{target}                                consecutive probes a server has missed
+func HubCollector(h *hub.Hub) Collector {
+	return func() []Series {
+		stats := h.Stats()
+		out := []Series{
+			{Name: "suffuse_clipboards", Value: float64(stats.Clipboards)},
+			{Name: "suffuse_publishes_total", Value: float64(stats.Publishes)},
+			{Name: "suffuse_published_bytes_total", Value: float64(stats.PublishedBytes)},
+			{Name: "suffuse_duplicates_suppressed_total", Value: float64(stats.Duplicates)},
+			{Name: "suffuse_hop_limited_total", Value: float64(stats.HopLimited)},
+			{Name: "suffuse_loops_total", Value: float64(stats.Loops)},
+			{Name: "suffuse_oversized_total", Value: float64(stats.Oversized)},
+			{Name: "suffuse_blobs", Value: float64(stats.Blobs.Blobs)},
+			{Name: "suffuse_blob_bytes", Value: float64(stats.Blobs.Bytes)},
+			{Name: "suffuse_blobs_pruned_total", Value: float64(stats.Blobs.Pruned)},
+			{Name: "suffuse_transfer_bytes_total", Labels: map[string]string{"direction": "in"}, Value: float64(stats.Usage.In)},
+			{Name: "suffuse_transfer_bytes_total", Labels: map[string]string{"direction": "out"}, Value: float64(stats.Usage.Out)},
+		}
+		for cb, u := range h.ClipboardUsage() {
+			out = append(out,
+				Series{Name: "suffuse_clipboard_transfer_bytes_total", Labels: map[string]string{"clipboard": cb, "direction": "in"}, Value: float64(u.In)},
+				Series{Name: "suffuse_clipboard_transfer_bytes_total", Labels: map[string]string{"clipboard": cb, "direction": "out"}, Value: float64(u.Out)},
+			)
+		}
+		for subsystem, n := range h.Dropped() {
+			out = append(out, Series{
+				Name:   "suffuse_dropped_events_total",
+				Labels: map[string]string{"subsystem": subsystem},
+				Value:  float64(n),
+			})
+		}
+		for _, u := range h.Quota().Usage() {
+			out = append(out, Series{
+				Name:   "suffuse_quota_exceeded_total",
+				Labels: map[string]string{"source": u.Source},
+				Value:  float64(u.Exceeded),
+			})
+		}
+		roles := make(map[string]int)
+		for _, p := range h.Peers() {
+			roles[p.Role]++
+			if w := p.Webhook; w != nil {
+				hook := map[string]string{"hook": p.Source}
+				out = append(out,
+					Series{Name: "suffuse_webhook_delivered_total", Labels: hook, Value: float64(w.Delivered)},
+					Series{Name: "suffuse_webhook_retries_total", Labels: hook, Value: float64(w.Retried)},
+					Series{Name: "suffuse_webhook_dead_lettered_total", Labels: hook, Value: float64(w.DeadLettered)},
+				)
+			}
+			if pr := p.Probe; pr != nil {
+				out = append(out,
+					Series{Name: "suffuse_probes_sent_total", Value: float64(pr.Sent)},
+					Series{Name: "suffuse_probes_unanswered_total", Value: float64(pr.Unanswered)},
+				)
+				for _, t := range pr.Targets {
+					target := map[string]string{"target": t.Source}
+					out = append(out,
+						Series{Name: "suffuse_probe_latency_seconds", Labels: target, Value: t.Latency.AsDuration().Seconds()},
+						Series{Name: "suffuse_probe_answered_total", Labels: target, Value: float64(t.Answered)},
+						Series{Name: "suffuse_probe_missed_total", Labels: target, Value: float64(t.Missed)},
+						Series{Name: "suffuse_probe_failing", Labels: target, Value: float64(t.Failing)},
+					)
+				}
+			}
+		}
+		for role, n := range roles {
+			out = append(out, Series{
+				Name:   "suffuse_peers",
+				Labels: map[string]string{"role": role},
+				Value:  float64(n),
+			})
+		}
+		return out
+	}
+}
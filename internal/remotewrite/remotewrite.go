@@ -0,0 +1,194 @@
+// Package remotewrite pushes hub metrics to a Prometheus remote-write
+// endpoint (Prometheus with --web.enable-remote-write-receiver, Mimir,
+// Thanos Receive, VictoriaMetrics, Grafana Cloud, …) on a fixed interval,
+// for deployments without a Prometheus server that could scrape suffuse.
+//
+// Requests follow remote-write protocol 1.0: a snappy-compressed protobuf
+// WriteRequest. Both encodings are small enough to be written by hand here
+// rather than pulling in the Prometheus client libraries.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Defaults applied to unset Config fields.
+const (
+	DefaultInterval = time.Minute
+	DefaultTimeout  = 30 * time.Second
+)
+
+// Config describes the remote-write target.
+type Config struct {
+	// URL is the remote-write endpoint. Credentials in the URL's userinfo
+	// are sent as HTTP basic auth.
+	URL string
+	// Interval between pushes; zero means DefaultInterval.
+	Interval time.Duration
+	// Timeout bounds each push; zero means DefaultTimeout.
+	Timeout time.Duration
+	// BearerToken is sent as "Authorization: Bearer …" when set.
+	BearerToken string
+	// Labels are added to every series, e.g. {"env": "prod"}. They override
+	// the collector's labels of the same name.
+	Labels map[string]string
+}
+
+// Series is one metric sample as produced by a Collector.
+type Series struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Collector returns the current value of every exported series.
+type Collector func() []Series
+
+// Writer periodically pushes the output of a Collector.
+type Writer struct {
+	cfg     Config
+	url     string
+	user    *url.Userinfo
+	collect Collector
+	client  *http.Client
+}
+
+// ParseLabels parses "name=value" entries into a Config.Labels map.
+func ParseLabels(entries []string) (map[string]string, error) {
+	out := make(map[string]string, len(entries))
+	for _, e := range entries {
+		name, value, ok := strings.Cut(e, "=")
+		if !ok || !validLabelName(name) {
+			return nil, fmt.Errorf("remote-write label %q: expected name=value with a Prometheus label name", e)
+		}
+		out[name] = value
+	}
+	return out, nil
+}
+
+// New validates cfg and returns a Writer. Call Run to start pushing.
+func New(cfg Config, collect Collector) (*Writer, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("remote-write url %q: must be an absolute http(s) URL", cfg.URL)
+	}
+	user := u.User
+	u.User = nil
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &Writer{
+		cfg:     cfg,
+		url:     u.String(),
+		user:    user,
+		collect: collect,
+		client:  &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Run pushes once per interval until ctx is cancelled. Failed pushes are
+// logged and not retried: every exported series is cumulative or a gauge, so
+// the next push carries the same information.
+func (w *Writer) Run(ctx context.Context) {
+	slog.Info("metrics remote write enabled", "url", w.url, "interval", w.cfg.Interval)
+
+	t := time.NewTicker(w.cfg.Interval)
+	defer t.Stop()
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		err := w.push(ctx)
+		switch {
+		case err != nil && !failing:
+			slog.Warn("metrics remote write failed", "url", w.url, "err", err)
+		case err != nil:
+			slog.Debug("metrics remote write failed", "url", w.url, "err", err)
+		case failing:
+			slog.Info("metrics remote write recovered", "url", w.url)
+		}
+		failing = err != nil
+	}
+}
+
+// push sends one WriteRequest with the current series.
+func (w *Writer) push(ctx context.Context) error {
+	now := time.Now().UnixMilli()
+	var ts []timeSeries
+	for _, s := range w.collect() {
+		labels := make(map[string]string, len(s.Labels)+len(w.cfg.Labels)+1)
+		maps.Copy(labels, s.Labels)
+		maps.Copy(labels, w.cfg.Labels)
+		labels["__name__"] = s.Name
+		ts = append(ts, timeSeries{labels: sortedLabels(labels), value: s.Value, timestamp: now})
+	}
+
+	body := snappyEncode(marshalWriteRequest(ts))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("User-Agent", "suffuse-remote-write")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if w.user != nil {
+		pass, _ := w.user.Password()
+		req.SetBasicAuth(w.user.Username(), pass)
+	}
+	if w.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+w.cfg.BearerToken)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if m := strings.TrimSpace(string(msg)); m != "" {
+			return fmt.Errorf("%s: %s", resp.Status, m)
+		}
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+func sortedLabels(m map[string]string) []label {
+	out := make([]label, 0, len(m))
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		out = append(out, label{name, m[name]})
+	}
+	return out
+}
+
+func validLabelName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
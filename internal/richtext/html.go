@@ -0,0 +1,405 @@
+// Package richtext converts clipboard text between HTML, Markdown and plain
+// text, so content copied in one representation can be offered in the
+// others: a browser selection as text/plain for a terminal, or Markdown
+// written in an editor as text/html for a mail client.
+//
+// The conversions cover what clipboard content commonly holds — headings,
+// paragraphs, emphasis, code, links, images, lists, quotes and simple
+// tables — rather than the whole of either format. HTML produced from
+// Markdown or plain text is escaped throughout: raw HTML in Markdown is shown
+// as text, and links are only kept for the http, https and mailto schemes
+// and relative URLs.
+package richtext
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLToText returns the text of an HTML document or fragment, with block
+// elements on lines of their own and list items, table cells and line
+// breaks kept apart.
+func HTMLToText(src []byte) string {
+	return convertHTML(src, false)
+}
+
+// HTMLToMarkdown converts an HTML document or fragment to Markdown.
+func HTMLToMarkdown(src []byte) string {
+	return convertHTML(src, true)
+}
+
+func convertHTML(src []byte, md bool) string {
+	doc, err := html.Parse(bytes.NewReader(src))
+	if err != nil {
+		// The parser accepts any input; it only fails reading it.
+		return ""
+	}
+	w := &htmlWriter{md: md, lineStart: true}
+	w.walk(doc)
+	return strings.TrimSpace(extraLines.ReplaceAllString(w.b.String(), "\n\n"))
+}
+
+// extraLines are runs of blank lines beyond the one separating blocks.
+var extraLines = regexp.MustCompile(`\n{3,}`)
+
+// list is an open ul or ol element.
+type list struct {
+	ordered bool
+	n       int // items so far
+}
+
+// table is an open table element.
+type table struct {
+	rows  int // rows ended so far
+	cells int // cells of the current row
+}
+
+// htmlWriter renders a parsed document as text or Markdown. Output goes
+// through raw, which first writes the line breaks blocks asked for, the
+// prefix of the Markdown quotes a new line is in, and the space owed between
+// words.
+type htmlWriter struct {
+	md bool
+	b  strings.Builder
+
+	breaks    int  // line breaks owed before the next output
+	newlines  int  // line breaks ending the output so far
+	space     bool // a space is owed before the next word
+	glue      bool // the next output follows without a space, e.g. a list marker
+	lineStart bool // nothing written on the current line yet
+
+	pre    int // depth of pre elements
+	quotes int // depth of blockquote elements
+	lists  []list
+	tables []table
+}
+
+// block asks for at least n line breaks before the next output.
+func (w *htmlWriter) block(n int) {
+	w.breaks = max(w.breaks, n)
+	w.space = false
+}
+
+// flush writes the line breaks owed, unless nothing was written yet.
+func (w *htmlWriter) flush() {
+	if w.breaks > w.newlines && w.b.Len() > 0 {
+		for i := w.newlines; i < w.breaks; i++ {
+			if i > 0 && w.md && w.quotes > 0 {
+				w.b.WriteString(strings.Repeat(">", w.quotes))
+			}
+			w.b.WriteByte('\n')
+		}
+		w.newlines = w.breaks
+		w.lineStart = true
+	}
+	w.breaks = 0
+}
+
+// raw writes s as it is, after the line breaks, quote prefix and space owed.
+func (w *htmlWriter) raw(s string) {
+	w.flush()
+	if w.lineStart {
+		if w.md && w.quotes > 0 {
+			w.b.WriteString(strings.Repeat("> ", w.quotes))
+		}
+	} else if w.space && !w.glue {
+		w.b.WriteByte(' ')
+	}
+	w.space, w.glue, w.lineStart = false, false, false
+	w.b.WriteString(s)
+	w.newlines = 0
+}
+
+// closing writes the closing delimiter s of an inline element, which goes
+// before a space owed, not after it.
+func (w *htmlWriter) closing(s string) {
+	w.b.WriteString(s)
+	w.glue, w.lineStart, w.newlines = false, false, 0
+}
+
+// text writes the words of a text node, collapsing white space outside pre
+// elements.
+func (w *htmlWriter) text(s string) {
+	if w.pre > 0 {
+		if s != "" {
+			w.raw(s)
+			w.lineStart = strings.HasSuffix(s, "\n")
+		}
+		return
+	}
+	if s == "" {
+		return
+	}
+	words := strings.Fields(s)
+	if len(words) == 0 || isSpace(s[0]) {
+		w.space = true
+	}
+	for i, word := range words {
+		if i > 0 {
+			w.space = true
+		}
+		if w.md {
+			word = escapeMarkdown(word)
+		}
+		w.raw(word)
+	}
+	if len(words) > 0 && isSpace(s[len(s)-1]) {
+		w.space = true
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// markdownSpecial are the characters escaped in Markdown text.
+var markdownSpecial = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`,
+)
+
+func escapeMarkdown(s string) string {
+	s = markdownSpecial.Replace(s)
+	// A line starting with these would begin a heading, quote or list.
+	if len(s) > 0 && strings.ContainsRune("#>+-", rune(s[0])) {
+		s = `\` + s
+	}
+	return s
+}
+
+func (w *htmlWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+}
+
+func (w *htmlWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		w.children(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Head, atom.Script, atom.Style, atom.Template, atom.Noscript:
+		return
+
+	case atom.Br:
+		if w.md && w.pre == 0 && !w.lineStart && w.b.Len() > 0 {
+			w.closing(`\`)
+		}
+		w.breaks++
+		w.space = false
+		return
+
+	case atom.Hr:
+		w.block(2)
+		if w.md {
+			w.raw("---")
+		}
+		w.block(2)
+		return
+
+	case atom.Img:
+		alt := attr(n, "alt")
+		if src := attr(n, "src"); w.md && src != "" && !strings.HasPrefix(src, "data:") {
+			w.raw("![" + escapeMarkdown(alt) + "](" + src + ")")
+		} else if alt != "" {
+			w.text(alt)
+		}
+		return
+
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		w.block(2)
+		if w.md {
+			w.raw(strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		}
+		w.children(n)
+		w.block(2)
+		return
+
+	case atom.P:
+		w.block(2)
+		w.children(n)
+		w.block(2)
+		return
+
+	case atom.Blockquote:
+		w.block(2)
+		w.flush() // the blank line before the quote is not part of it
+		w.quotes++
+		w.children(n)
+		w.block(2)
+		w.quotes--
+		return
+
+	case atom.Pre:
+		w.block(2)
+		if w.md {
+			w.raw("```")
+			w.block(1)
+		}
+		w.pre++
+		w.children(n)
+		w.pre--
+		if w.md {
+			w.block(1)
+			w.raw("```")
+		}
+		w.block(2)
+		return
+
+	case atom.Ul, atom.Ol:
+		w.block(1)
+		if len(w.lists) == 0 {
+			w.block(2)
+		}
+		w.lists = append(w.lists, list{ordered: n.DataAtom == atom.Ol})
+		w.children(n)
+		w.lists = w.lists[:len(w.lists)-1]
+		if len(w.lists) == 0 {
+			w.block(2)
+		} else {
+			w.block(1)
+		}
+		return
+
+	case atom.Li:
+		w.block(1)
+		marker := "- "
+		if len(w.lists) > 0 {
+			l := &w.lists[len(w.lists)-1]
+			l.n++
+			if l.ordered {
+				marker = strconv.Itoa(l.n) + ". "
+			}
+			marker = strings.Repeat("  ", len(w.lists)-1) + marker
+		}
+		w.raw(marker)
+		w.glue = true
+		w.children(n)
+		w.block(1)
+		return
+
+	case atom.Table:
+		w.block(2)
+		w.tables = append(w.tables, table{})
+		w.children(n)
+		w.tables = w.tables[:len(w.tables)-1]
+		w.block(2)
+		return
+
+	case atom.Tr:
+		if len(w.tables) == 0 {
+			break
+		}
+		t := &w.tables[len(w.tables)-1]
+		w.block(1)
+		t.cells = 0
+		if w.md {
+			w.raw("|")
+		}
+		w.children(n)
+		if w.md && t.rows == 0 {
+			// Markdown tables need a separator under the first row.
+			w.block(1)
+			w.raw("|" + strings.Repeat(" --- |", max(t.cells, 1)))
+		}
+		t.rows++
+		w.block(1)
+		return
+
+	case atom.Td, atom.Th:
+		if len(w.tables) == 0 {
+			break
+		}
+		t := &w.tables[len(w.tables)-1]
+		if !w.md && t.cells > 0 {
+			w.space = false
+			w.raw("\t")
+			w.glue = true
+		}
+		w.space = w.md
+		w.children(n)
+		if w.md {
+			w.space = true
+			w.raw("|")
+		}
+		t.cells++
+		return
+
+	case atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Nav,
+		atom.Aside, atom.Main, atom.Figure, atom.Figcaption, atom.Dl, atom.Dt, atom.Dd,
+		atom.Address, atom.Details, atom.Summary:
+		w.block(1)
+		w.children(n)
+		w.block(1)
+		return
+	}
+
+	if !w.md || w.pre > 0 {
+		w.children(n)
+		return
+	}
+	switch n.DataAtom {
+	case atom.B, atom.Strong:
+		w.inline(n, "**", "**")
+	case atom.I, atom.Em:
+		w.inline(n, "*", "*")
+	case atom.S, atom.Del, atom.Strike:
+		w.inline(n, "~~", "~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		w.raw("`" + strings.Join(strings.Fields(textOf(n)), " ") + "`")
+	case atom.A:
+		href := attr(n, "href")
+		if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			w.children(n)
+			return
+		}
+		w.inline(n, "[", "]("+strings.ReplaceAll(href, " ", "%20")+")")
+	default:
+		w.children(n)
+	}
+}
+
+// inline writes the content of n between the delimiters open and close,
+// writing nothing for an element without text.
+func (w *htmlWriter) inline(n *html.Node, open, close string) {
+	if strings.TrimSpace(textOf(n)) == "" {
+		w.children(n)
+		return
+	}
+	w.raw(open)
+	w.glue = true
+	w.children(n)
+	w.closing(close)
+}
+
+// textOf returns the text of n and its descendants.
+func textOf(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textOf(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,524 @@
+package richtext
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fragmentPrefix starts the HTML the package writes, as browsers start the
+// HTML they put on the clipboard, so applications decode it as UTF-8.
+const fragmentPrefix = `<meta charset="utf-8">`
+
+// TextToHTML returns plain text as an HTML fragment: a paragraph for each
+// run of lines between blank lines, with its line breaks kept.
+func TextToHTML(src []byte) string {
+	var b strings.Builder
+	b.WriteString(fragmentPrefix)
+	for _, para := range paragraphs.Split(strings.TrimSpace(normalizeNewlines(string(src))), -1) {
+		if para == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(para), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+// paragraphs splits text at blank lines.
+var paragraphs = regexp.MustCompile(`\n[ \t]*\n\s*`)
+
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\r", "\n")
+}
+
+// MarkdownToHTML renders Markdown as an HTML fragment.
+func MarkdownToHTML(src []byte) string {
+	lines := strings.Split(strings.TrimRight(normalizeNewlines(string(src)), "\n"), "\n")
+	var b strings.Builder
+	b.WriteString(fragmentPrefix)
+	renderBlocks(&b, lines, false)
+	return b.String()
+}
+
+var (
+	headingLine  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	fenceLine    = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^ \t`]*)")
+	listItemLine = regexp.MustCompile(`^( *)([-*+]|(\d{1,9})[.)])(?:[ \t]+(.*))?$`)
+	quoteLine    = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	tableDivider = regexp.MustCompile(`^ *\|? *:?-+:? *(\| *:?-+:? *)*\|? *$`)
+)
+
+// isRule reports whether line is a thematic break: three or more of the
+// same one of "-", "*" and "_", optionally separated by spaces.
+func isRule(line string) bool {
+	s := strings.ReplaceAll(strings.ReplaceAll(line, " ", ""), "\t", "")
+	if len(s) < 3 || !strings.ContainsRune("-*_", rune(s[0])) {
+		return false
+	}
+	return strings.Count(s, s[:1]) == len(s)
+}
+
+func isBlank(line string) bool { return strings.TrimSpace(line) == "" }
+
+// startsBlock reports whether line interrupts a paragraph.
+func startsBlock(line string) bool {
+	return headingLine.MatchString(line) || fenceLine.MatchString(line) ||
+		quoteLine.MatchString(line) || isRule(line) || listItemLine.MatchString(line)
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// renderBlocks renders lines as a sequence of blocks. In a tight list item,
+// paragraphs are written without p elements.
+func renderBlocks(b *strings.Builder, lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+
+		case fenceLine.MatchString(line):
+			m := fenceLine.FindStringSubmatch(line)
+			fence, indent := m[1], indentOf(line)
+			var code []string
+			for i++; i < len(lines); i++ {
+				if t := strings.TrimSpace(lines[i]); strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]) == "" {
+					i++
+					break
+				}
+				code = append(code, strings.TrimPrefix(lines[i], strings.Repeat(" ", indent)))
+			}
+			b.WriteString("<pre><code")
+			if m[2] != "" {
+				b.WriteString(` class="language-` + html.EscapeString(m[2]) + `"`)
+			}
+			b.WriteString(">")
+			for _, l := range code {
+				b.WriteString(html.EscapeString(l) + "\n")
+			}
+			b.WriteString("</code></pre>\n")
+
+		case headingLine.MatchString(line):
+			m := headingLine.FindStringSubmatch(line)
+			tag := "h" + strconv.Itoa(len(m[1]))
+			b.WriteString("<" + tag + ">" + renderInline(m[2]) + "</" + tag + ">\n")
+			i++
+
+		case isRule(line):
+			b.WriteString("<hr>\n")
+			i++
+
+		case quoteLine.MatchString(line):
+			var inner []string
+			for ; i < len(lines); i++ {
+				m := quoteLine.FindStringSubmatch(lines[i])
+				if m == nil {
+					// Lazy continuation of the quote's last paragraph.
+					if isBlank(lines[i]) || startsBlock(lines[i]) || len(inner) == 0 || isBlank(inner[len(inner)-1]) {
+						break
+					}
+					inner = append(inner, lines[i])
+					continue
+				}
+				inner = append(inner, m[1])
+			}
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, inner, false)
+			b.WriteString("</blockquote>\n")
+
+		case listItemLine.MatchString(line):
+			i = renderList(b, lines, i)
+
+		case i+1 < len(lines) && strings.Contains(line, "|") && tableDivider.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			i = renderTable(b, lines, i)
+
+		default:
+			var para []string
+			for ; i < len(lines) && !isBlank(lines[i]); i++ {
+				if len(para) > 0 {
+					// A setext underline makes the paragraph a heading.
+					if t := strings.TrimSpace(lines[i]); t != "" && strings.Trim(t, "=") == "" || (strings.Trim(t, "-") == "" && len(t) >= 2) {
+						tag := "h1"
+						if t[0] == '-' {
+							tag = "h2"
+						}
+						b.WriteString("<" + tag + ">" + renderInline(strings.Join(trimAll(para), "\n")) + "</" + tag + ">\n")
+						para = nil
+						i++
+						break
+					}
+					if startsBlock(lines[i]) {
+						break
+					}
+				}
+				para = append(para, lines[i])
+			}
+			if len(para) == 0 {
+				continue
+			}
+			text := renderParagraph(para)
+			if tight {
+				b.WriteString(text)
+			} else {
+				b.WriteString("<p>" + text + "</p>\n")
+			}
+		}
+	}
+}
+
+func trimAll(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = strings.TrimSpace(l)
+	}
+	return out
+}
+
+// renderParagraph renders the lines of a paragraph, keeping hard line
+// breaks: lines ending in two spaces or a backslash.
+func renderParagraph(lines []string) string {
+	var b strings.Builder
+	for i, l := range lines {
+		l = strings.TrimLeft(l, " \t")
+		hard := strings.HasSuffix(l, "  ") || strings.HasSuffix(l, `\`)
+		l = strings.TrimRight(strings.TrimSuffix(strings.TrimRight(l, " \t"), `\`), " \t")
+		b.WriteString(renderInline(l))
+		if i < len(lines)-1 {
+			if hard {
+				b.WriteString("<br>")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// renderList renders the list starting at lines[start] and returns the
+// index of the first line after it. Lines indented past an item's marker
+// belong to the item, nested lists among them.
+func renderList(b *strings.Builder, lines []string, start int) int {
+	first := listItemLine.FindStringSubmatch(lines[start])
+	indent, ordered := len(first[1]), first[3] != ""
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+		if n, _ := strconv.Atoi(first[3]); n != 1 {
+			b.WriteString(`<ol start="` + strconv.Itoa(n) + `">` + "\n")
+		} else {
+			b.WriteString("<ol>\n")
+		}
+	} else {
+		b.WriteString("<ul>\n")
+	}
+
+	// continues reports whether line is an item of this list.
+	continues := func(line string) bool {
+		m := listItemLine.FindStringSubmatch(line)
+		return m != nil && len(m[1]) >= indent && len(m[1]) <= indent+1 && (m[3] != "") == ordered
+	}
+
+	var items [][]string
+	loose := false
+	i := start
+	for i < len(lines) && continues(lines[i]) {
+		m := listItemLine.FindStringSubmatch(lines[i])
+		contentIndent := len(m[1]) + len(m[2]) + 1
+		item := []string{m[4]}
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			if isBlank(l) {
+				// A blank line continues the item only when an indented
+				// line follows it.
+				if i+1 < len(lines) && !isBlank(lines[i+1]) && indentOf(lines[i+1]) >= contentIndent {
+					item = append(item, "")
+					loose = true
+					continue
+				}
+				if i+1 < len(lines) && continues(lines[i+1]) {
+					loose = true
+				}
+				break
+			}
+			if indentOf(l) >= contentIndent {
+				item = append(item, l[min(contentIndent, indentOf(l)):])
+				continue
+			}
+			if startsBlock(l) {
+				break
+			}
+			item = append(item, strings.TrimLeft(l, " ")) // lazy continuation
+		}
+		items = append(items, item)
+		for i < len(lines) && isBlank(lines[i]) && i+1 < len(lines) && continues(lines[i+1]) {
+			i++
+		}
+	}
+
+	for _, item := range items {
+		b.WriteString("<li>")
+		renderBlocks(b, item, !loose)
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// renderTable renders the table whose header row is lines[start] and
+// returns the index of the first line after it.
+func renderTable(b *strings.Builder, lines []string, start int) int {
+	aligns := tableCells(lines[start+1])
+	for j, a := range aligns {
+		switch {
+		case strings.HasPrefix(a, ":") && strings.HasSuffix(a, ":"):
+			aligns[j] = "center"
+		case strings.HasSuffix(a, ":"):
+			aligns[j] = "right"
+		case strings.HasPrefix(a, ":"):
+			aligns[j] = "left"
+		default:
+			aligns[j] = ""
+		}
+	}
+	row := func(line, cell string) {
+		b.WriteString("<tr>")
+		for j, c := range tableCells(line) {
+			if j < len(aligns) && aligns[j] != "" {
+				b.WriteString("<" + cell + ` style="text-align: ` + aligns[j] + `">`)
+			} else {
+				b.WriteString("<" + cell + ">")
+			}
+			b.WriteString(renderInline(c) + "</" + cell + ">")
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("<table>\n<thead>\n")
+	row(lines[start], "th")
+	b.WriteString("</thead>\n<tbody>\n")
+	i := start + 2
+	for ; i < len(lines) && !isBlank(lines[i]) && strings.Contains(lines[i], "|"); i++ {
+		row(lines[i], "td")
+	}
+	b.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+// tableCells splits a table row at the pipes not escaped with a backslash.
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cur strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cur.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cur.String()))
+}
+
+// renderInline renders the inline Markdown of s: code spans, emphasis,
+// strikethrough, links, images, autolinks and backslash escapes. Everything
+// else is escaped text.
+func renderInline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '`':
+			n := runLength(s[i:], '`')
+			fence := s[i : i+n]
+			if end := strings.Index(s[i+n:], fence); end >= 0 {
+				code := s[i+n : i+n+end]
+				if t := strings.TrimSpace(code); t != "" && strings.HasPrefix(code, " ") && strings.HasSuffix(code, " ") {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i += n + end + n
+				continue
+			}
+			b.WriteString(fence)
+			i += n
+			continue
+
+		case c == '!' && strings.HasPrefix(s[i+1:], "["):
+			if text, dest, n, ok := parseLink(s[i+1:]); ok {
+				if u, ok := safeURL(dest); ok {
+					b.WriteString(`<img src="` + html.EscapeString(u) + `" alt="` + html.EscapeString(text) + `">`)
+					i += 1 + n
+					continue
+				}
+			}
+
+		case c == '[':
+			if text, dest, n, ok := parseLink(s[i:]); ok {
+				if u, ok := safeURL(dest); ok {
+					b.WriteString(`<a href="` + html.EscapeString(u) + `">` + renderInline(text) + "</a>")
+				} else {
+					b.WriteString(renderInline(text))
+				}
+				i += n
+				continue
+			}
+
+		case c == '<':
+			if end := strings.IndexByte(s[i:], '>'); end > 0 {
+				target := s[i+1 : i+end]
+				if u, ok := safeURL(target); ok && strings.Contains(target, ":") && !strings.ContainsAny(target, " <") {
+					b.WriteString(`<a href="` + html.EscapeString(u) + `">` + html.EscapeString(target) + "</a>")
+					i += end + 1
+					continue
+				}
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			if out, n, ok := emphasis(s, i); ok {
+				b.WriteString(out)
+				i += n
+				continue
+			}
+		}
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// emphasis renders the emphasis, strong emphasis or strikethrough opening at
+// s[i], returning the HTML and the bytes consumed. Underscores only open and
+// close at word boundaries, so snake_case stays as it is.
+func emphasis(s string, i int) (string, int, bool) {
+	c := s[i]
+	n := min(runLength(s[i:], c), 3)
+	if c == '~' && n < 2 {
+		return "", 0, false
+	}
+	if c == '~' {
+		n = 2
+	}
+	if i+n >= len(s) || s[i+n] == ' ' {
+		return "", 0, false
+	}
+	if c == '_' && i > 0 && isWordChar(s[i-1]) {
+		return "", 0, false
+	}
+	delim := s[i : i+n]
+	for j := i + n + 1; j <= len(s)-n; j++ {
+		if s[j:j+n] != delim || s[j-1] == ' ' || s[j-1] == '\\' {
+			continue
+		}
+		if j+n < len(s) && s[j+n] == c {
+			continue // part of a longer run
+		}
+		if c == '_' && j+n < len(s) && isWordChar(s[j+n]) {
+			continue
+		}
+		inner := renderInline(s[i+n : j])
+		switch {
+		case c == '~':
+			inner = "<del>" + inner + "</del>"
+		case n == 1:
+			inner = "<em>" + inner + "</em>"
+		case n == 2:
+			inner = "<strong>" + inner + "</strong>"
+		default:
+			inner = "<em><strong>" + inner + "</strong></em>"
+		}
+		return inner, j + n - i, true
+	}
+	return "", 0, false
+}
+
+// parseLink parses "[text](dest)" at the start of s, returning the text, the
+// destination without a title, and the bytes consumed.
+func parseLink(s string) (text, dest string, n int, ok bool) {
+	depth := 0
+	for j := 0; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if j+1 >= len(s) || s[j+1] != '(' {
+				return "", "", 0, false
+			}
+			// The destination may hold balanced parentheses.
+			end, parens := -1, 0
+			for k := j + 2; k < len(s) && end < 0; k++ {
+				switch s[k] {
+				case '(':
+					parens++
+				case ')':
+					if parens == 0 {
+						end = k - (j + 2)
+					}
+					parens--
+				}
+			}
+			if end < 0 {
+				return "", "", 0, false
+			}
+			dest = strings.TrimSpace(s[j+2 : j+2+end])
+			if k := strings.IndexAny(dest, " \t"); k >= 0 {
+				dest = dest[:k] // drop a title
+			}
+			dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+			return s[1:j], dest, j + 2 + end + 1, true
+		}
+	}
+	return "", "", 0, false
+}
+
+// safeURL returns u if it is relative or of the http, https or mailto
+// scheme.
+func safeURL(u string) (string, bool) {
+	scheme, _, found := strings.Cut(u, ":")
+	if !found || strings.ContainsAny(scheme, "/?#") {
+		return u, true // relative
+	}
+	switch strings.ToLower(scheme) {
+	case "http", "https", "mailto":
+		return u, true
+	}
+	return "", false
+}
+
+func runLength(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,70 @@
+// Package sdnotify tells systemd about the server's state over the
+// sd_notify protocol: that it is ready once it listens, and, when the unit
+// sets WatchdogSec, that it is still alive. Outside a systemd service
+// ($NOTIFY_SOCKET unset) every call does nothing.
+package sdnotify
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notify sends state, e.g. "READY=1", to systemd. It reports false without
+// an error when the process is not run by systemd with notification.
+func Notify(state string) (bool, error) {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return false, nil
+	}
+	if path[0] == '@' {
+		path = "\x00" + path[1:] // abstract socket
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the WatchdogSec of the unit, or 0 when systemd
+// does not watch this process.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// RunWatchdog pings systemd's watchdog at half its interval for as long as
+// alive returns true, until ctx is done. alive is called on each tick; when
+// it returns false or never returns, the pings stop and systemd restarts the
+// service once the interval runs out. Without a watchdog it returns at once.
+func RunWatchdog(ctx context.Context, alive func() bool) {
+	interval := WatchdogInterval()
+	if interval == 0 {
+		return
+	}
+	t := time.NewTicker(interval / 2)
+	defer t.Stop()
+	for {
+		if alive() {
+			_, _ = Notify("WATCHDOG=1")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,30 @@
+//go:build linux
+
+package shaping
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// detectMetered asks NetworkManager for the metered state of the primary
+// connection. NMMetered values 1 (yes) and 3 (guessed yes) count as metered.
+func detectMetered() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "busctl", "--system", "get-property",
+		"org.freedesktop.NetworkManager", "/org/freedesktop/NetworkManager",
+		"org.freedesktop.NetworkManager", "Metered").Output()
+	if err != nil {
+		return false, fmt.Errorf("query NetworkManager: %w", err)
+	}
+	// Output is the D-Bus signature and value, e.g. "u 4".
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 || fields[0] != "u" {
+		return false, fmt.Errorf("query NetworkManager: unexpected reply %q", strings.TrimSpace(string(out)))
+	}
+	return fields[1] == "1" || fields[1] == "3", nil
+}
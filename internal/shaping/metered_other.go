@@ -0,0 +1,7 @@
+//go:build !linux && !windows
+
+package shaping
+
+// detectMetered has no implementation on this platform; use --defer-hours
+// instead.
+func detectMetered() (bool, error) { return false, errUnsupported }
@@ -0,0 +1,32 @@
+//go:build windows
+
+package shaping
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// detectMetered reads the cost type of the internet connection profile.
+// Fixed and Variable (data-capped or per-byte) count as metered.
+func detectMetered() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command",
+		"[Windows.Networking.Connectivity.NetworkInformation,Windows.Networking.Connectivity,ContentType=WindowsRuntime]::"+
+			"GetInternetConnectionProfile().GetConnectionCost().NetworkCostType").Output()
+	if err != nil {
+		return false, fmt.Errorf("query connection cost: %w", err)
+	}
+	switch cost := strings.TrimSpace(string(out)); cost {
+	case "Fixed", "Variable":
+		return true, nil
+	case "Unrestricted", "Unknown":
+		return false, nil
+	default:
+		return false, fmt.Errorf("query connection cost: unexpected reply %q", cost)
+	}
+}
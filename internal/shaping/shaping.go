@@ -0,0 +1,176 @@
+// Package shaping decides when the federation link holds back bulky
+// clipboard content. While a Policy is active, only text items travel between
+// servers; images, files, and other non-text items are announced by their
+// metadata and transferred once the policy allows it again.
+//
+// A Policy is active during any of its configured daily windows, and — when
+// enabled — while the default network connection is metered, as far as the
+// platform can tell.
+package shaping
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// CheckInterval is how often callers should re-evaluate a Policy. Metered
+// detection results are cached for the same period.
+const CheckInterval = 30 * time.Second
+
+// Config describes when non-text items are deferred.
+type Config struct {
+	// Hours are daily local-time windows in "HH:MM-HH:MM" form. A window
+	// whose end is before its start spans midnight ("22:00-07:00").
+	Hours []string
+	// Metered defers while the default network connection is metered.
+	Metered bool
+}
+
+// window is a daily interval in minutes since local midnight.
+type window struct {
+	start, end int
+	spec       string
+}
+
+func (w window) contains(minute int) bool {
+	if w.start <= w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// Policy evaluates a Config. The zero of *Policy (nil) is never active.
+type Policy struct {
+	windows []window
+	metered bool
+
+	mu          sync.Mutex
+	checkedAt   time.Time
+	meteredNow  bool
+	warnedError bool
+}
+
+// New validates cfg and returns its Policy, or nil when cfg defers nothing.
+func New(cfg Config) (*Policy, error) {
+	p := &Policy{metered: cfg.Metered}
+	for _, spec := range cfg.Hours {
+		w, err := parseWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		p.windows = append(p.windows, w)
+	}
+	if len(p.windows) == 0 && !p.metered {
+		return nil, nil
+	}
+	return p, nil
+}
+
+func parseWindow(spec string) (window, error) {
+	from, to, ok := strings.Cut(spec, "-")
+	if !ok {
+		return window{}, fmt.Errorf("shaping: hours %q: expected HH:MM-HH:MM", spec)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return window{}, fmt.Errorf("shaping: hours %q: %w", spec, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return window{}, fmt.Errorf("shaping: hours %q: %w", spec, err)
+	}
+	if start == end {
+		return window{}, fmt.Errorf("shaping: hours %q: window is empty", spec)
+	}
+	return window{start: start, end: end, spec: spec}, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight; "24:00" is the end
+// of the day.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if strings.TrimSpace(s) == "24:00" {
+		return 24 * 60, nil
+	}
+	return 0, fmt.Errorf("invalid time %q", s)
+}
+
+// Active reports whether non-text items are deferred at now, and why.
+func (p *Policy) Active(now time.Time) (active bool, reason string) {
+	if p == nil {
+		return false, ""
+	}
+	minute := now.Hour()*60 + now.Minute()
+	for _, w := range p.windows {
+		if w.contains(minute) {
+			return true, "schedule " + w.spec
+		}
+	}
+	if p.metered && p.meteredAt(now) {
+		return true, "metered connection"
+	}
+	return false, ""
+}
+
+// meteredAt returns the cached metered state, refreshing it once it is older
+// than CheckInterval. Detection failures count as not metered and are logged
+// once.
+func (p *Policy) meteredAt(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.checkedAt.IsZero() && now.Sub(p.checkedAt) < CheckInterval {
+		return p.meteredNow
+	}
+	p.checkedAt = now
+	metered, err := detectMetered()
+	if err != nil {
+		if !p.warnedError {
+			p.warnedError = true
+			slog.Warn("metered connection detection unavailable; treating the network as unmetered", "err", err)
+		}
+		metered = false
+	}
+	p.meteredNow = metered
+	return metered
+}
+
+// errUnsupported is returned by detectMetered on platforms without a
+// detection method.
+var errUnsupported = errors.New("not supported on this platform")
+
+// Deferrable reports whether items of mime are held back while a Policy is
+// active. Everything except text is.
+func Deferrable(mime string) bool {
+	return !strings.HasPrefix(mime, "text/")
+}
+
+// HasDeferrable reports whether any of items is deferrable.
+func HasDeferrable(items []*pb.ClipboardItem) bool {
+	for _, it := range items {
+		if Deferrable(it.Mime) {
+			return true
+		}
+	}
+	return false
+}
+
+// Text returns the items of items that are not deferrable. items itself is
+// not modified.
+func Text(items []*pb.ClipboardItem) []*pb.ClipboardItem {
+	out := make([]*pb.ClipboardItem, 0, len(items))
+	for _, it := range items {
+		if !Deferrable(it.Mime) {
+			out = append(out, it)
+		}
+	}
+	return out
+}
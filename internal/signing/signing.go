@@ -0,0 +1,258 @@
+// Package signing signs clipboard items with a per-device Ed25519 key, so
+// receivers can tell who copied them. The source name an update carries is
+// chosen by the client that copied it; a signature by a key the receiver
+// trusts cannot be forged by anyone else on the hub.
+//
+// A signed copy carries one extra item of type MIME holding a manifest: the
+// type and SHA-256 of every other item, the signing time and the signer's
+// public key, signed with its private key. The clipboard is not covered, so
+// copies mirrored or kept per host still verify. Receivers that get only some
+// of the items, e.g. because they accept fewer types, can still verify them;
+// an item missing from the manifest or with different content fails
+// verification. Items of end-to-end encrypted clipboards are signed
+// before they are sealed.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/blob"
+)
+
+// MIME is the type of the item carrying a copy's signature.
+const MIME = "application/x-suffuse-signature"
+
+// keyPrefix starts the text form of a public key.
+const keyPrefix = "ed25519:"
+
+// domain separates suffuse signatures from any other use of the key.
+const domain = "suffuse-signature-v1"
+
+var (
+	// ErrBadSignature is returned by Verify when a signature does not match
+	// the items it came with.
+	ErrBadSignature = errors.New("signature does not match the content (modified or forged)")
+	// ErrUnsigned is returned by Trust.Require for a copy without a
+	// signature.
+	ErrUnsigned = errors.New("content is not signed")
+	// ErrUntrusted is returned by Trust.Require for a signature by a key
+	// that is not trusted.
+	ErrUntrusted = errors.New("content is signed by an untrusted key")
+)
+
+// DefaultPath returns the path of this device's signing key in the user's
+// config directory.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "suffuse", "signing.key")
+}
+
+// Key is a device's signing key. The zero of *Key (nil) signs nothing.
+type Key struct {
+	priv ed25519.PrivateKey
+}
+
+// LoadKey reads the key at path, creating one (mode 0600) if there is none.
+func LoadKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("signing: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, fmt.Errorf("signing: %w", err)
+		}
+		seed := base64.StdEncoding.EncodeToString(priv.Seed()) + "\n"
+		if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+			return nil, fmt.Errorf("signing: %w", err)
+		}
+		return &Key{priv: priv}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing: %s is not a suffuse signing key", path)
+	}
+	return &Key{priv: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+// PublicKey returns the text form of k's public key, "ed25519:<base64>", as
+// receivers list it among their trusted signers.
+func (k *Key) PublicKey() string {
+	return keyPrefix + base64.StdEncoding.EncodeToString(k.priv.Public().(ed25519.PublicKey))
+}
+
+// manifest is the content of a signature item.
+type manifest struct {
+	Key   string      `json:"key"`
+	At    time.Time   `json:"at"`
+	Items []itemEntry `json:"items"`
+	Sig   []byte      `json:"sig"`
+}
+
+type itemEntry struct {
+	Mime   string `json:"mime"`
+	SHA256 string `json:"sha256"`
+}
+
+// message returns the bytes signed for m.
+func (m *manifest) message() []byte {
+	b := []byte(domain)
+	b = binary.AppendUvarint(b, uint64(len(m.Key)))
+	b = append(b, m.Key...)
+	b = binary.BigEndian.AppendUint64(b, uint64(m.At.UnixNano()))
+	b = binary.AppendUvarint(b, uint64(len(m.Items)))
+	for _, e := range m.Items {
+		b = binary.AppendUvarint(b, uint64(len(e.Mime)))
+		b = append(b, e.Mime...)
+		b = append(b, e.SHA256...)
+	}
+	return b
+}
+
+// digest returns the hex SHA-256 of an item's content, taken from its blob
+// reference when the content is held by the server.
+func digest(it *pb.ClipboardItem) string {
+	if it.Ref != nil {
+		return it.Ref.Sha256
+	}
+	return blob.Sum(it.Data)
+}
+
+// Sign returns items, without any earlier signature, followed by k's
+// signature of them. A nil k returns items unchanged.
+func (k *Key) Sign(items []*pb.ClipboardItem) ([]*pb.ClipboardItem, error) {
+	if k == nil {
+		return items, nil
+	}
+	items = Strip(items)
+	m := manifest{Key: k.PublicKey(), At: time.Now().UTC()}
+	for _, it := range items {
+		m.Items = append(m.Items, itemEntry{Mime: it.Mime, SHA256: digest(it)})
+	}
+	m.Sig = ed25519.Sign(k.priv, m.message())
+	data, err := json.Marshal(&m)
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+	return append(slices.Clone(items), &pb.ClipboardItem{Mime: MIME, Data: data}), nil
+}
+
+// Strip returns items without signature items.
+func Strip(items []*pb.ClipboardItem) []*pb.ClipboardItem {
+	if !slices.ContainsFunc(items, isSignature) {
+		return items
+	}
+	return slices.DeleteFunc(slices.Clone(items), isSignature)
+}
+
+func isSignature(it *pb.ClipboardItem) bool { return it.Mime == MIME }
+
+// Signer describes a valid signature.
+type Signer struct {
+	// Key is the signer's public key.
+	Key string
+	// Name is the name Key is trusted under, or "" for an untrusted key.
+	Name string
+	// At is when the content was signed, by the signer's clock.
+	At time.Time
+}
+
+// Trusted reports whether the signer's key is trusted.
+func (s *Signer) Trusted() bool { return s != nil && s.Name != "" }
+
+// String returns the trusted name, or the key marked untrusted.
+func (s *Signer) String() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Key + " (untrusted)"
+}
+
+// Trust maps public keys to the names they are trusted under. The zero of
+// *Trust (nil) trusts no key but still verifies signatures.
+type Trust struct {
+	names map[string]string // public key → name
+}
+
+// NewTrust returns the Trust for signers, a map of name → public key as
+// printed by Key.PublicKey.
+func NewTrust(signers map[string]string) (*Trust, error) {
+	t := &Trust{names: make(map[string]string, len(signers))}
+	for name, key := range signers {
+		if _, err := parsePublicKey(key); err != nil {
+			return nil, fmt.Errorf("signer %q: %w", name, err)
+		}
+		t.names[key] = name
+	}
+	return t, nil
+}
+
+func parsePublicKey(key string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(key, keyPrefix))
+	if !strings.HasPrefix(key, keyPrefix) || err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("signing: %q is not an %s public key", key, strings.TrimSuffix(keyPrefix, ":"))
+	}
+	return raw, nil
+}
+
+// Verify checks the signature among items. It returns the other items with
+// the signer, nil for unsigned items, or ErrBadSignature when the signature
+// is invalid or does not cover every item.
+func (t *Trust) Verify(items []*pb.ClipboardItem) ([]*pb.ClipboardItem, *Signer, error) {
+	i := slices.IndexFunc(items, isSignature)
+	if i < 0 {
+		return items, nil, nil
+	}
+	var m manifest
+	rest := Strip(items)
+	if err := json.Unmarshal(items[i].Data, &m); err != nil {
+		return rest, nil, ErrBadSignature
+	}
+	pub, err := parsePublicKey(m.Key)
+	if err != nil || !ed25519.Verify(pub, m.message(), m.Sig) {
+		return rest, nil, ErrBadSignature
+	}
+	for _, it := range rest {
+		if !slices.Contains(m.Items, itemEntry{Mime: it.Mime, SHA256: digest(it)}) {
+			return rest, nil, ErrBadSignature
+		}
+	}
+	s := &Signer{Key: m.Key, At: m.At}
+	if t != nil {
+		s.Name = t.names[m.Key]
+	}
+	return rest, s, nil
+}
+
+// Require is Verify for content that must be signed by a trusted key: it
+// also fails with ErrUnsigned or ErrUntrusted.
+func (t *Trust) Require(items []*pb.ClipboardItem) ([]*pb.ClipboardItem, *Signer, error) {
+	rest, s, err := t.Verify(items)
+	switch {
+	case err != nil:
+	case s == nil:
+		err = ErrUnsigned
+	case !s.Trusted():
+		err = fmt.Errorf("%w: %s", ErrUntrusted, s.Key)
+	}
+	return rest, s, err
+}
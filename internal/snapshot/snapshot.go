@@ -0,0 +1,161 @@
+// Package snapshot keeps named snapshots of clipboard contents on the
+// server, so the clipboards a risky script is about to rewrite can be put
+// back afterwards with "suffuse snapshot restore".
+//
+// Each snapshot is a file of its own in the store's directory, holding a
+// serialized pb.Snapshot, written to a temporary file and renamed into
+// place so a crash never leaves half a snapshot. Files are readable only by
+// the server's user but not encrypted; end-to-end encrypted clipboards are
+// kept as the ciphertext the server holds.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// ext is the extension of snapshot files.
+const ext = ".snapshot"
+
+var (
+	// ErrNotFound is returned for a snapshot the store does not hold.
+	ErrNotFound = errors.New("snapshot not found")
+	// ErrInvalidName is returned for a name CheckName refuses.
+	ErrInvalidName = errors.New("invalid snapshot name")
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// CheckName reports whether name can name a snapshot: up to 64 letters,
+// digits, dots, dashes and underscores, starting with a letter or digit.
+func CheckName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("%w %q: use up to 64 letters, digits, '.', '-' and '_', starting with a letter or digit", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// DefaultDir returns the snapshot directory under the user's configuration
+// directory, e.g. ~/.config/suffuse/snapshots on Linux.
+func DefaultDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "suffuse", "snapshots")
+}
+
+// Store holds the snapshots in a directory, created with mode 0700 when the
+// first snapshot is saved.
+type Store struct {
+	dir string
+}
+
+// New returns the store of the snapshots in dir.
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name+ext)
+}
+
+// Save keeps snap under its name, replacing any snapshot of that name.
+func (s *Store) Save(snap *pb.Snapshot) error {
+	if err := CheckName(snap.Name); err != nil {
+		return err
+	}
+	data, err := proto.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, "."+snap.Name+"-*")
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // gone once renamed
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(snap.Name)); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load returns the snapshot called name, or ErrNotFound.
+func (s *Store) Load(name string) (*pb.Snapshot, error) {
+	if err := CheckName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	snap := &pb.Snapshot{}
+	if err := proto.Unmarshal(data, snap); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", name, err)
+	}
+	snap.Name = name
+	return snap, nil
+}
+
+// List returns every snapshot held, newest first. An unreadable file is
+// skipped rather than hiding the others.
+func (s *Store) List() ([]*pb.Snapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	var out []*pb.Snapshot
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ext)
+		if !ok || e.IsDir() || CheckName(name) != nil {
+			continue
+		}
+		if snap, err := s.Load(name); err == nil {
+			out = append(out, snap)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.AsTime().After(out[j].CreatedAt.AsTime())
+	})
+	return out, nil
+}
+
+// Delete removes the snapshot called name, or returns ErrNotFound.
+func (s *Store) Delete(name string) error {
+	if err := CheckName(name); err != nil {
+		return err
+	}
+	err := os.Remove(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	return nil
+}
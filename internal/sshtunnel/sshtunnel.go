@@ -0,0 +1,221 @@
+// Package sshtunnel reaches suffuse servers through an SSH jump host, for
+// networks where only port 22 is open. Connections are forwarded by the jump
+// host (direct-tcpip, as "ssh -J" does), so the server address is resolved
+// there; the suffuse connection inside stays TLS and token authenticated.
+//
+// The jump host is authenticated against ~/.ssh/known_hosts and the user with
+// the keys of a running ssh-agent, then the unencrypted default identities in
+// ~/.ssh. Hosts missing from known_hosts are refused: connect once with ssh
+// to add them.
+package sshtunnel
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// connectTimeout bounds the TCP connection and SSH handshake with the jump
+// host.
+const connectTimeout = 15 * time.Second
+
+// identities are the private keys tried after the agent's, in ~/.ssh.
+var identities = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// Tunnel is an SSH connection to a jump host, opened on first use and again
+// after it drops. It is safe for concurrent use.
+type Tunnel struct {
+	addr string // jump host, host:port
+	cfg  *ssh.ClientConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// New returns the Tunnel through target, "[user@]host[:port]"; the user
+// defaults to the local one and the port to 22. Nothing is dialed until
+// DialContext.
+func New(target string) (*Tunnel, error) {
+	userName, host, ok := strings.Cut(target, "@")
+	if !ok {
+		userName, host = "", target
+	}
+	if userName == "" {
+		u, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("ssh %s: no user name: %w", target, err)
+		}
+		userName = u.Username
+	}
+	if host == "" {
+		return nil, fmt.Errorf("ssh %q: no host", target)
+	}
+	addr := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		addr = net.JoinHostPort(strings.Trim(host, "[]"), "22")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s: %w", target, err)
+	}
+	dir := filepath.Join(home, ".ssh")
+	hostKeys, err := knownhosts.New(filepath.Join(dir, "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s: known hosts: %w", target, err)
+	}
+	return &Tunnel{
+		addr: addr,
+		cfg: &ssh.ClientConfig{
+			User:              userName,
+			Auth:              []ssh.AuthMethod{ssh.PublicKeysCallback(signers(dir))},
+			HostKeyCallback:   hostKeys,
+			HostKeyAlgorithms: hostKeyAlgorithms(hostKeys, addr),
+			Timeout:           connectTimeout,
+		},
+	}, nil
+}
+
+// hostKeyAlgorithms returns the algorithms of the keys known_hosts lists for
+// addr, so that a jump host with several keys presents one of them rather
+// than one that would be refused as a mismatch; nil for an unknown host.
+func hostKeyAlgorithms(hostKeys ssh.HostKeyCallback, addr string) []string {
+	probe, err := ssh.NewPublicKey(ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)))
+	if err != nil {
+		return nil
+	}
+	var keyErr *knownhosts.KeyError
+	if !errors.As(hostKeys(addr, &net.TCPAddr{IP: net.IPv4zero}, probe), &keyErr) {
+		return nil
+	}
+	var algos []string
+	for _, k := range keyErr.Want {
+		switch t := k.Key.Type(); t {
+		case ssh.KeyAlgoRSA:
+			algos = append(algos, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, t)
+		default:
+			algos = append(algos, t)
+		}
+	}
+	return algos
+}
+
+// signers returns the keys offered to the jump host: the agent's, then the
+// identities in dir that are not protected by a passphrase.
+func signers(dir string) func() ([]ssh.Signer, error) {
+	return func() ([]ssh.Signer, error) {
+		var out []ssh.Signer
+		if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+			if conn, err := net.Dial("unix", sock); err == nil {
+				if s, err := agent.NewClient(conn).Signers(); err == nil {
+					out = append(out, s...)
+				}
+			}
+		}
+		for _, name := range identities {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				continue
+			}
+			if s, err := ssh.ParsePrivateKey(data); err == nil {
+				out = append(out, s)
+			}
+		}
+		if len(out) == 0 {
+			return nil, errors.New("no SSH keys: start ssh-agent or create an unencrypted key in ~/.ssh")
+		}
+		return out, nil
+	}
+}
+
+// DialContext connects to addr, resolved by the jump host, through the
+// tunnel. It has the signature of grpc.WithContextDialer.
+func (t *Tunnel) DialContext(ctx context.Context, addr string) (net.Conn, error) {
+	c, err := t.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := c.DialContext(ctx, "tcp", addr)
+	var refused *ssh.OpenChannelError
+	if err != nil && !errors.As(err, &refused) {
+		// The jump host did not answer; the next dial reconnects.
+		t.drop(c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s: %s: %w", t.addr, addr, err)
+	}
+	return conn, nil
+}
+
+// connect returns the SSH client, connecting to the jump host if there is
+// none.
+func (t *Tunnel) connect(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+	d := net.Dialer{Timeout: connectTimeout}
+	conn, err := d.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s: %w", t.addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	sc, chans, reqs, err := ssh.NewClientConn(conn, t.addr, t.cfg)
+	if err != nil {
+		_ = conn.Close()
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			return nil, fmt.Errorf("ssh %s: host key unknown, connect once with ssh to add it to known_hosts", t.addr)
+		}
+		return nil, fmt.Errorf("ssh %s: %w", t.addr, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	c := ssh.NewClient(sc, chans, reqs)
+	t.client = c
+	go func() {
+		_ = c.Wait()
+		t.drop(c)
+	}()
+	return c, nil
+}
+
+// drop closes c and forgets it if it is still the current client.
+func (t *Tunnel) drop(c *ssh.Client) {
+	t.mu.Lock()
+	if t.client == c {
+		t.client = nil
+	}
+	t.mu.Unlock()
+	_ = c.Close()
+}
+
+// Close closes the connection to the jump host, if any.
+func (t *Tunnel) Close() error {
+	t.mu.Lock()
+	c := t.client
+	t.client = nil
+	t.mu.Unlock()
+	if c == nil {
+		return nil
+	}
+	return c.Close()
+}
+
+// Target returns the gRPC target for addr reached through a tunnel: the
+// passthrough scheme, so that the jump host rather than this one resolves
+// the name.
+func Target(addr string) string { return "passthrough:///" + addr }
@@ -0,0 +1,23 @@
+// Package term queries the terminal commands drawing a full-screen view,
+// such as "suffuse top", write to.
+package term
+
+import "os"
+
+// DefaultWidth and DefaultHeight are the size assumed for a terminal whose
+// size is unknown.
+const (
+	DefaultWidth  = 80
+	DefaultHeight = 24
+)
+
+// Size returns the width and height of the terminal f, in characters and
+// lines, or the defaults when f is not a terminal or does not report its
+// size.
+func Size(f *os.File) (width, height int) {
+	w, h, ok := size(f)
+	if !ok || w <= 0 || h <= 0 {
+		return DefaultWidth, DefaultHeight
+	}
+	return w, h
+}
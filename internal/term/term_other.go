@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package term
+
+import "os"
+
+func size(*os.File) (int, int, bool) { return 0, 0, false }
+
+// EnableEscapes lets the terminal f interpret ANSI escape sequences.
+func EnableEscapes(*os.File) error { return nil }
@@ -0,0 +1,21 @@
+//go:build unix
+
+package term
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func size(f *os.File) (int, int, bool) {
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, false
+	}
+	return int(ws.Col), int(ws.Row), true
+}
+
+// EnableEscapes lets the terminal f interpret ANSI escape sequences, which
+// terminals on this platform always do.
+func EnableEscapes(*os.File) error { return nil }
@@ -0,0 +1,58 @@
+package tlsconf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ACMEProto is the ALPN protocol of the TLS-ALPN-01 challenge, which a
+// listener on port 443 answers when it offers it.
+const ACMEProto = acme.ALPNProto
+
+// ACMEConfig describes certificates obtained and renewed automatically from
+// an ACME certificate authority, Let's Encrypt unless DirectoryURL says
+// otherwise.
+type ACMEConfig struct {
+	// Domains are the public DNS names of the server; certificates are
+	// only requested for these.
+	Domains []string
+	// Email is the contact address registered with the CA (may be empty).
+	Email string
+	// CacheDir keeps the account key and certificates across restarts.
+	CacheDir string
+	// DirectoryURL is the CA's directory; empty means Let's Encrypt.
+	DirectoryURL string
+}
+
+// DefaultACMEDir returns the certificate cache directory in the user's
+// config directory.
+func DefaultACMEDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "suffuse", "acme")
+}
+
+// NewACME returns the ACME client for cfg, a CertSource that obtains a
+// certificate on the first connection naming one of cfg.Domains and renews it
+// before it expires. Its HTTPHandler answers HTTP-01 challenges.
+func NewACME(cfg ACMEConfig) (*autocert.Manager, error) {
+	if len(cfg.Domains) == 0 {
+		return nil, errors.New("tlsconf: no ACME domains")
+	}
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cfg.CacheDir),
+		HostPolicy: autocert.HostWhitelist(cfg.Domains...),
+		Email:      cfg.Email,
+	}
+	if cfg.DirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: cfg.DirectoryURL}
+	}
+	return m, nil
+}
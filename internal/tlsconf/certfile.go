@@ -0,0 +1,109 @@
+package tlsconf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// SystemRoots names the system trust store in place of a CA file.
+const SystemRoots = "system"
+
+// recheck is how often CertFile looks for renewed files.
+const recheck = time.Minute
+
+// CertFile is an operator-provided certificate, e.g. from a corporate CA or
+// Let's Encrypt, served in place of the passphrase-derived one to clients
+// that verify certificates the usual way. The files are read again when they
+// change, so a renewal needs no restart.
+type CertFile struct {
+	certPath, keyPath string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// LoadCertFile reads the PEM certificate chain and private key at certPath
+// and keyPath.
+func LoadCertFile(certPath, keyPath string) (*CertFile, error) {
+	c := &CertFile{certPath: certPath, keyPath: keyPath}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load reads the files. Must be called with c.mu held, or before c is shared.
+func (c *CertFile) load() error {
+	mod, err := c.lastModified()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certPath, c.keyPath)
+	if err != nil {
+		return fmt.Errorf("tlsconf: %w", err)
+	}
+	c.cert, c.modTime, c.checked = &cert, mod, time.Now()
+	return nil
+}
+
+// lastModified returns the later modification time of the two files.
+func (c *CertFile) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, p := range []string{c.certPath, c.keyPath} {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("tlsconf: %w", err)
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// GetCertificate implements CertSource: it returns the current certificate
+// whatever the client asked for, reading the files again when they changed
+// since. A renewal that cannot be read keeps the last certificate in use.
+func (c *CertFile) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.checked) < recheck {
+		return c.cert, nil
+	}
+	c.checked = time.Now()
+	if mod, err := c.lastModified(); err != nil || mod.Equal(c.modTime) {
+		return c.cert, nil
+	}
+	_ = c.load() // sets nothing on failure; retried at the next check
+	return c.cert, nil
+}
+
+// CACredentials returns gRPC TransportCredentials that verify the server's
+// certificate chain and host name the usual way, against the PEM
+// certificates in the file ca, or the system trust store when ca is
+// SystemRoots. They are for servers started with an operator-provided
+// certificate.
+func CACredentials(ca string) (credentials.TransportCredentials, error) {
+	cfg := resumable(&tls.Config{MinVersion: tls.VersionTLS13})
+	if ca != SystemRoots {
+		data, err := os.ReadFile(ca)
+		if err != nil {
+			return nil, fmt.Errorf("tlsconf: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errors.New("tlsconf: no PEM certificates in " + ca)
+		}
+		cfg.RootCAs = pool
+	}
+	return credentials.NewTLS(cfg), nil
+}
@@ -0,0 +1,163 @@
+package tlsconf
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// KeySet serves certificates for several passphrases at once, so a server can
+// accept clients on both sides of a token rotation. Clients announce which
+// key they expect via SNI (see serverName); clients that predate the hint, or
+// name an unknown key, get the primary certificate, or the operator's
+// certificate when one is set (see SetCertSource).
+type KeySet struct {
+	mu      sync.RWMutex
+	certs   map[string]*tls.Certificate // server name → certificate
+	pubs    [][]byte                    // public keys, primary first
+	primary *tls.Certificate
+	name    string // server name of the primary key
+	source  CertSource
+	served  map[string]bool // public keys of certificates source provided
+}
+
+// CertSource provides certificates of the operator's own: a CertFile, or an
+// ACME client such as autocert.Manager. GetCertificate fails for a client
+// hello it has no certificate for.
+type CertSource interface {
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+}
+
+// maxServed bounds the public keys KeySet remembers from its CertSource;
+// renewals beyond it start the record afresh.
+const maxServed = 8
+
+// NewKeySet returns a KeySet for passphrases; the first is the primary.
+func NewKeySet(passphrases ...string) (*KeySet, error) {
+	k := &KeySet{}
+	if err := k.Set(passphrases...); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Set replaces the served passphrases; the first is the primary. Existing
+// connections are unaffected.
+func (k *KeySet) Set(passphrases ...string) error {
+	if len(passphrases) == 0 {
+		return fmt.Errorf("tlsconf: no passphrases")
+	}
+	certs := make(map[string]*tls.Certificate, len(passphrases))
+	var (
+		pubs    [][]byte
+		primary *tls.Certificate
+		name    string
+	)
+	for i, p := range passphrases {
+		cert, pub, err := keyPair(p)
+		if err != nil {
+			return err
+		}
+		n := serverName(pub)
+		certs[n] = &cert
+		pubs = append(pubs, pub)
+		if i == 0 {
+			primary, name = &cert, n
+		}
+	}
+
+	k.mu.Lock()
+	k.certs, k.pubs, k.primary, k.name = certs, pubs, primary, name
+	k.mu.Unlock()
+	return nil
+}
+
+// SetCertSource serves certificates from src to clients that name no key of
+// the set, i.e. those verifying the server's certificate the usual way. When
+// src has no certificate for a client, it gets the primary one.
+func (k *KeySet) SetCertSource(src CertSource) {
+	k.mu.Lock()
+	k.source = src
+	k.mu.Unlock()
+}
+
+// fromSource returns src's certificate for hello and records its public key
+// for ClientCredentials, or nil.
+func (k *KeySet) fromSource(src CertSource, hello *tls.ClientHelloInfo) *tls.Certificate {
+	c, err := src.GetCertificate(hello)
+	if err != nil || c == nil || len(c.Certificate) == 0 {
+		return nil
+	}
+	leaf := c.Leaf
+	if leaf == nil {
+		if leaf, err = x509.ParseCertificate(c.Certificate[0]); err != nil {
+			return nil
+		}
+	}
+	pub, err := x509.MarshalPKIXPublicKey(leaf.PublicKey)
+	if err != nil {
+		return nil
+	}
+	k.mu.Lock()
+	if !k.served[string(pub)] {
+		if len(k.served) >= maxServed || k.served == nil {
+			k.served = make(map[string]bool)
+		}
+		k.served[string(pub)] = true
+	}
+	k.mu.Unlock()
+	return c
+}
+
+// ServerConfig returns a *tls.Config that picks the certificate per
+// connection from the current set.
+func (k *KeySet) ServerConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			k.mu.RLock()
+			c, ok := k.certs[strings.ToLower(hello.ServerName)]
+			src, primary := k.source, k.primary
+			k.mu.RUnlock()
+			if ok {
+				return c, nil
+			}
+			if src != nil {
+				if c := k.fromSource(src, hello); c != nil {
+					return c, nil
+				}
+			}
+			return primary, nil
+		},
+		NextProtos: []string{"h2", "http/1.1"},
+		MinVersion: tls.VersionTLS13,
+	}
+}
+
+// ClientCredentials returns credentials for dialing this server itself (the
+// HTTP gateway loopback). They send no key hint, so the server presents the
+// primary certificate or the operator's, and trust any key in the set and the
+// operator's, so the loopback survives rotations and renewals.
+func (k *KeySet) ClientCredentials() credentials.TransportCredentials {
+	return credentials.NewTLS(resumable(&tls.Config{
+		InsecureSkipVerify: true, //nolint:gosec // public key verified below
+		MinVersion:         tls.VersionTLS13,
+		VerifyPeerCertificate: verifyPublicKey(func(pub []byte) bool {
+			k.mu.RLock()
+			defer k.mu.RUnlock()
+			if k.served[string(pub)] {
+				return true
+			}
+			for _, p := range k.pubs {
+				if bytes.Equal(p, pub) {
+					return true
+				}
+			}
+			return false
+		}),
+	}))
+}
@@ -24,10 +24,12 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/pem"
 	"fmt"
 	"io"
 	"math/big"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/hkdf"
@@ -43,66 +45,108 @@ const DefaultPassphrase = "suffuse"
 // NextProtos ["h2", "http/1.1"] lets ALPN negotiate correctly for both gRPC
 // and HTTP/JSON clients on the same listener.
 func ServerConfig(passphrase string) (serverCfg *tls.Config, clientCreds credentials.TransportCredentials, err error) {
+	tlsCert, expectedPub, err := keyPair(passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serverCfg = &tls.Config{
+		Certificates: []tls.Certificate{tlsCert},
+		NextProtos:   []string{"h2", "http/1.1"},
+		MinVersion:   tls.VersionTLS13,
+	}
+
+	clientCreds = credentials.NewTLS(resumable(&tls.Config{
+		// Skip normal cert chain verification — we verify the public key instead.
+		InsecureSkipVerify: true, //nolint:gosec
+		// The key ID in SNI lets a server holding several passphrases (see
+		// KeySet) present the matching certificate.
+		ServerName: serverName(expectedPub),
+		MinVersion: tls.VersionTLS13,
+		// VerifyPeerCertificate checks that the server's public key matches
+		// the key derived from our passphrase. Wrong passphrase = different
+		// key = connection rejected.
+		VerifyPeerCertificate: verifyPublicKey(func(pub []byte) bool {
+			return bytes.Equal(pub, expectedPub)
+		}),
+	}))
+
+	return serverCfg, clientCreds, nil
+}
+
+// keyPair derives the TLS certificate for passphrase and returns it with the
+// DER-encoded public key clients verify against.
+func keyPair(passphrase string) (tls.Certificate, []byte, error) {
 	key, err := deriveKey(passphrase)
 	if err != nil {
-		return nil, nil, fmt.Errorf("tlsconf: derive key: %w", err)
+		return tls.Certificate{}, nil, fmt.Errorf("tlsconf: derive key: %w", err)
 	}
 
 	certPEM, err := selfSignedCert(key)
 	if err != nil {
-		return nil, nil, fmt.Errorf("tlsconf: cert: %w", err)
+		return tls.Certificate{}, nil, fmt.Errorf("tlsconf: cert: %w", err)
 	}
 
 	keyPEM, err := marshalKey(key)
 	if err != nil {
-		return nil, nil, fmt.Errorf("tlsconf: marshal key: %w", err)
+		return tls.Certificate{}, nil, fmt.Errorf("tlsconf: marshal key: %w", err)
 	}
 
 	tlsCert, err := tls.X509KeyPair(certPEM, keyPEM)
 	if err != nil {
-		return nil, nil, fmt.Errorf("tlsconf: key pair: %w", err)
+		return tls.Certificate{}, nil, fmt.Errorf("tlsconf: key pair: %w", err)
 	}
 
-	serverCfg = &tls.Config{
-		Certificates: []tls.Certificate{tlsCert},
-		NextProtos:   []string{"h2", "http/1.1"},
-		MinVersion:   tls.VersionTLS13,
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("tlsconf: marshal pubkey: %w", err)
 	}
+	return tlsCert, pub, nil
+}
 
-	// Derive the expected public key bytes once for the client verifier.
-	expectedPub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+// Fingerprint returns the ID of the TLS key derived from passphrase, as its
+// holders announce it in SNI: 16 hex digits identifying the key without
+// revealing the passphrase.
+func Fingerprint(passphrase string) (string, error) {
+	key, err := deriveKey(passphrase)
+	if err != nil {
+		return "", fmt.Errorf("tlsconf: derive key: %w", err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
 	if err != nil {
-		return nil, nil, fmt.Errorf("tlsconf: marshal pubkey: %w", err)
+		return "", fmt.Errorf("tlsconf: marshal pubkey: %w", err)
 	}
+	return strings.TrimSuffix(serverName(pub), ".suffuse"), nil
+}
 
-	clientCreds = credentials.NewTLS(&tls.Config{
-		// Skip normal cert chain verification — we verify the public key instead.
-		InsecureSkipVerify: true, //nolint:gosec
-		ServerName:         "suffuse",
-		MinVersion:         tls.VersionTLS13,
-		// VerifyPeerCertificate checks that the server's public key matches
-		// the key derived from our passphrase. Wrong passphrase = different
-		// key = connection rejected.
-		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
-			if len(rawCerts) == 0 {
-				return fmt.Errorf("tlsconf: server presented no certificate")
-			}
-			cert, err := x509.ParseCertificate(rawCerts[0])
-			if err != nil {
-				return fmt.Errorf("tlsconf: parse server cert: %w", err)
-			}
-			pub, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
-			if err != nil {
-				return fmt.Errorf("tlsconf: marshal server pubkey: %w", err)
-			}
-			if !bytes.Equal(pub, expectedPub) {
-				return fmt.Errorf("tlsconf: server public key does not match passphrase")
-			}
-			return nil
-		},
-	})
+// serverName returns the SNI value identifying the key with public key pub:
+// the first 8 bytes of its SHA-256 in hex, under the "suffuse" name the
+// certificates are issued for. It reveals nothing about the passphrase.
+func serverName(pub []byte) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8]) + ".suffuse"
+}
 
-	return serverCfg, clientCreds, nil
+// verifyPublicKey returns a VerifyPeerCertificate callback that accepts the
+// server certificate when ok reports its public key as trusted.
+func verifyPublicKey(ok func(pub []byte) bool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("tlsconf: server presented no certificate")
+		}
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return fmt.Errorf("tlsconf: parse server cert: %w", err)
+		}
+		pub, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+		if err != nil {
+			return fmt.Errorf("tlsconf: marshal server pubkey: %w", err)
+		}
+		if !ok(pub) {
+			return fmt.Errorf("tlsconf: server public key does not match passphrase")
+		}
+		return nil
+	}
 }
 
 // ClientCredentials returns gRPC TransportCredentials derived from passphrase.
@@ -164,3 +208,13 @@ func marshalKey(key *ecdsa.PrivateKey) ([]byte, error) {
 	}
 	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
 }
+
+// UnverifiedCredentials returns gRPC TransportCredentials that accept any
+// server key, for exchanges that authenticate the server themselves, such as
+// pairing, where the client has no passphrase yet.
+func UnverifiedCredentials() credentials.TransportCredentials {
+	return credentials.NewTLS(&tls.Config{
+		InsecureSkipVerify: true, //nolint:gosec // the exchange authenticates the server
+		MinVersion:         tls.VersionTLS13,
+	})
+}
@@ -0,0 +1,131 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+)
+
+// Role is what a token allows its holder to do.
+type Role string
+
+const (
+	// RoleAdmin allows everything: admin RPCs and federation links as well
+	// as reading and writing clipboards. The shared tokens have this role.
+	RoleAdmin Role = "admin"
+	// RoleReadWrite allows copying to, pasting from and watching clipboards.
+	RoleReadWrite Role = "read-write"
+	// RoleReadOnly allows pasting from and watching clipboards.
+	RoleReadOnly Role = "read-only"
+)
+
+// Scoped is a per-peer token with a role and an optional clipboard
+// allowlist, as configured in [[tokens]] tables.
+type Scoped struct {
+	// Name identifies the holder in logs.
+	Name  string `mapstructure:"name"`
+	Token string `mapstructure:"token"`
+	Role  Role   `mapstructure:"role"`
+	// Clipboards are path.Match patterns (e.g. "default", "team/*") the
+	// token is limited to; empty allows every clipboard.
+	Clipboards []string `mapstructure:"clipboards"`
+	// Sources are path.Match patterns of the source names the holder may
+	// present (x-suffuse-source); empty allows any name, or only Name when
+	// the Set binds sources (see Set.BindSources).
+	Sources []string `mapstructure:"sources"`
+	// Guest marks the shared guest token, which is rate limited.
+	Guest bool `mapstructure:"-"`
+}
+
+// Check validates t.
+func (t Scoped) Check() error {
+	if t.Token == "" {
+		return fmt.Errorf("tokens: %q has an empty token", t.Name)
+	}
+	switch t.Role {
+	case RoleAdmin, RoleReadWrite, RoleReadOnly:
+	case "":
+		return fmt.Errorf("tokens: %q has no role (admin, read-write or read-only)", t.Name)
+	default:
+		return fmt.Errorf("tokens: %q has unknown role %q (admin, read-write or read-only)", t.Name, t.Role)
+	}
+	for _, pattern := range t.Clipboards {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("tokens: %q clipboard pattern %q: %w", t.Name, pattern, err)
+		}
+	}
+	for _, pattern := range t.Sources {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("tokens: %q source pattern %q: %w", t.Name, pattern, err)
+		}
+	}
+	return nil
+}
+
+// CheckScoped validates scoped tokens before they are passed to NewSet.
+// Values must be unique, as the presented token alone selects the grant.
+func CheckScoped(scoped []Scoped) error {
+	var seen []string
+	for _, t := range scoped {
+		if err := t.Check(); err != nil {
+			return err
+		}
+		if slices.Contains(seen, t.Token) {
+			return errors.New("tokens: the same token is configured twice")
+		}
+		seen = append(seen, t.Token)
+	}
+	return nil
+}
+
+// Grant is what a presented token allows.
+type Grant struct {
+	// Name is the scoped token's name; empty for the shared tokens.
+	Name       string
+	Role       Role
+	Clipboards []string
+	// Sources are the patterns of source names the holder may present; nil
+	// allows any.
+	Sources []string
+	Guest   bool
+}
+
+// full is the grant of the shared tokens and of unauthenticated access.
+var full = Grant{Role: RoleAdmin}
+
+// CanWrite reports whether g allows publishing to clipboards.
+func (g Grant) CanWrite() bool {
+	return g.Role == RoleAdmin || g.Role == RoleReadWrite
+}
+
+// IsAdmin reports whether g allows admin RPCs and federation links.
+func (g Grant) IsAdmin() bool {
+	return g.Role == RoleAdmin
+}
+
+// AllowsSource reports whether the holder of g may present source name src.
+func (g Grant) AllowsSource(src string) bool {
+	if g.Sources == nil {
+		return true
+	}
+	for _, pattern := range g.Sources {
+		if ok, _ := path.Match(pattern, src); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether g covers clipboard cb.
+func (g Grant) Allows(cb string) bool {
+	if len(g.Clipboards) == 0 {
+		return true
+	}
+	for _, pattern := range g.Clipboards {
+		if ok, _ := path.Match(pattern, cb); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,309 @@
+// Package tokens tracks the shared secrets a server accepts.
+//
+// A Set has one primary token plus any number of additional tokens, each with
+// an optional expiry. Rotating promotes a new primary while the previous one
+// stays valid for a grace period, so a fleet can move to a new secret without
+// a synchronized flag day. The empty token means "no auth" and is treated
+// like any other value, so rotating away from an unauthenticated setup works
+// the same way.
+//
+// These shared tokens grant full access. A Set can also hold scoped tokens,
+// each limited to a Role and optionally to some clipboards; Lookup returns
+// the Grant of a presented token.
+package tokens
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token is an accepted secret. A zero Expires never expires.
+type Token struct {
+	Value   string
+	Expires time.Time
+}
+
+// Parse parses "secret" or "secret@<expiry>", where expiry is RFC 3339 or a
+// YYYY-MM-DD date (midnight UTC). Secrets containing "@" are taken verbatim
+// unless the text after the last "@" parses as an expiry.
+func Parse(s string) (Token, error) {
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		for _, layout := range []string{time.RFC3339, time.DateOnly} {
+			if t, err := time.Parse(layout, s[i+1:]); err == nil {
+				if i == 0 {
+					return Token{}, fmt.Errorf("tokens: empty secret in %q", s)
+				}
+				return Token{Value: s[:i], Expires: t}, nil
+			}
+		}
+	}
+	if s == "" {
+		return Token{}, fmt.Errorf("tokens: empty secret")
+	}
+	return Token{Value: s}, nil
+}
+
+// String formats t in the form accepted by Parse.
+func (t Token) String() string {
+	if t.Expires.IsZero() {
+		return t.Value
+	}
+	return t.Value + "@" + t.Expires.UTC().Format(time.RFC3339)
+}
+
+// Set is the set of tokens a server accepts. It is safe for concurrent use.
+type Set struct {
+	mu       sync.Mutex
+	primary  string
+	extra    []Token
+	scoped   []Scoped
+	onChange func(active []string)
+	bind     bool                 // scoped tokens without Sources present only their Name
+	changed  chan struct{}        // closed and replaced on every change
+	seen     map[string]time.Time // scoped token → last accepted
+	timer    *time.Timer
+}
+
+// NewSet returns a Set with the given primary, additional and scoped tokens.
+// Expired extras are dropped. Validate scoped tokens with CheckScoped.
+func NewSet(primary string, extra []Token, scoped []Scoped) *Set {
+	s := &Set{
+		primary: primary,
+		extra:   slices.Clone(extra),
+		scoped:  slices.Clone(scoped),
+		changed: make(chan struct{}),
+		seen:    make(map[string]time.Time),
+	}
+	s.mu.Lock()
+	s.pruneLocked(time.Now())
+	s.mu.Unlock()
+	return s
+}
+
+// OnChange registers fn to be called with the active tokens (primary first)
+// whenever they change through Rotate, AddScoped, RemoveScoped or expiry. fn
+// runs without the Set's lock held. Only one callback is kept.
+func (s *Set) OnChange(fn func(active []string)) {
+	s.mu.Lock()
+	s.onChange = fn
+	s.mu.Unlock()
+}
+
+// BindSources makes every scoped token without Sources, other than the guest
+// token, present only its Name as source, so a holder cannot pass itself off
+// as another peer in status, events and history.
+func (s *Set) BindSources() {
+	s.mu.Lock()
+	s.bind = true
+	s.mu.Unlock()
+}
+
+// Changed returns a channel closed the next time the accepted tokens change,
+// so long-lived streams can check that their token is still accepted.
+func (s *Set) Changed() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed
+}
+
+// Primary returns the current primary token.
+func (s *Set) Primary() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.primary
+}
+
+// Active returns every token currently accepted, primary first and scoped
+// tokens last.
+func (s *Set) Active() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.activeLocked(time.Now())
+}
+
+// Extra returns the additional tokens with their expiries.
+func (s *Set) Extra() []Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(time.Now())
+	return slices.Clone(s.extra)
+}
+
+// Valid reports whether tok is accepted. While the empty token is active,
+// auth is not enforced and every tok is accepted. Comparison is constant-time
+// per token.
+func (s *Set) Valid(tok string) bool {
+	_, ok := s.Lookup(tok)
+	return ok
+}
+
+// Lookup returns what tok allows and whether it is accepted at all. The
+// shared tokens, and any tok while the empty token is active, grant full
+// access. Comparison is constant-time per token.
+func (s *Set) Lookup(tok string) (Grant, bool) {
+	s.mu.Lock()
+	now := time.Now()
+	shared := []string{s.primary}
+	for _, t := range s.extra {
+		if t.Expires.IsZero() || now.Before(t.Expires) {
+			shared = append(shared, t.Value)
+		}
+	}
+	scoped, bind := s.scoped, s.bind
+	s.mu.Unlock()
+	if slices.Contains(shared, "") {
+		return full, true
+	}
+	var g Grant
+	ok := false
+	for _, a := range shared {
+		if subtle.ConstantTimeCompare([]byte(a), []byte(tok)) == 1 {
+			g, ok = full, true
+		}
+	}
+	matched := ""
+	for _, t := range scoped {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(tok)) == 1 && !ok {
+			g, ok, matched = Grant{Name: t.Name, Role: t.Role, Clipboards: t.Clipboards, Sources: t.Sources, Guest: t.Guest}, true, t.Token
+			if g.Sources == nil && bind && !t.Guest {
+				g.Sources = []string{t.Name}
+			}
+		}
+	}
+	if matched != "" {
+		s.mu.Lock()
+		s.seen[matched] = now
+		s.mu.Unlock()
+	}
+	return g, ok
+}
+
+// LastSeen returns when the scoped token tok was last accepted, or the zero
+// time if it has not been since the Set was created.
+func (s *Set) LastSeen(tok string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen[tok]
+}
+
+// Rotate makes next the primary token. The previous primary remains valid
+// until the returned time (now + grace); a grace of zero revokes it at once.
+func (s *Set) Rotate(next string, grace time.Duration) time.Time {
+	now := time.Now()
+	expires := now.Add(grace)
+
+	s.mu.Lock()
+	prev := s.primary
+	s.primary = next
+	s.extra = slices.DeleteFunc(s.extra, func(t Token) bool { return t.Value == next || t.Value == prev })
+	if grace > 0 && prev != next {
+		s.extra = append(s.extra, Token{Value: prev, Expires: expires})
+	}
+	s.pruneLocked(now)
+	s.notifyLocked()
+	fn, active := s.onChange, s.activeLocked(now)
+	s.mu.Unlock()
+
+	if fn != nil {
+		fn(active)
+	}
+	return expires
+}
+
+// AddScoped starts accepting t, e.g. a token issued by pairing. Validate it
+// with Check first.
+func (s *Set) AddScoped(t Scoped) {
+	now := time.Now()
+	s.mu.Lock()
+	// Lookup reads s.scoped outside the lock, so never append in place.
+	s.scoped = append(slices.Clip(s.scoped), t)
+	s.notifyLocked()
+	fn, active := s.onChange, s.activeLocked(now)
+	s.mu.Unlock()
+
+	if fn != nil {
+		fn(active)
+	}
+}
+
+// RemoveScoped stops accepting the scoped token tok, e.g. when a paired
+// device is revoked, and reports whether it was accepted.
+func (s *Set) RemoveScoped(tok string) bool {
+	now := time.Now()
+	s.mu.Lock()
+	n := len(s.scoped)
+	// Lookup reads s.scoped outside the lock, so never delete in place.
+	s.scoped = slices.DeleteFunc(slices.Clone(s.scoped), func(t Scoped) bool { return t.Token == tok })
+	if len(s.scoped) == n {
+		s.mu.Unlock()
+		return false
+	}
+	delete(s.seen, tok)
+	s.notifyLocked()
+	fn, active := s.onChange, s.activeLocked(now)
+	s.mu.Unlock()
+
+	if fn != nil {
+		fn(active)
+	}
+	return true
+}
+
+// notifyLocked wakes the waiters on Changed.
+func (s *Set) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Set) activeLocked(now time.Time) []string {
+	out := []string{s.primary}
+	for _, t := range s.extra {
+		if (t.Expires.IsZero() || now.Before(t.Expires)) && !slices.Contains(out, t.Value) {
+			out = append(out, t.Value)
+		}
+	}
+	for _, t := range s.scoped {
+		if !slices.Contains(out, t.Token) {
+			out = append(out, t.Token)
+		}
+	}
+	return out
+}
+
+// pruneLocked drops expired extras and arms a timer for the next expiry.
+func (s *Set) pruneLocked(now time.Time) {
+	s.extra = slices.DeleteFunc(s.extra, func(t Token) bool {
+		return !t.Expires.IsZero() && !now.Before(t.Expires)
+	})
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	var next time.Time
+	for _, t := range s.extra {
+		if !t.Expires.IsZero() && (next.IsZero() || t.Expires.Before(next)) {
+			next = t.Expires
+		}
+	}
+	if !next.IsZero() {
+		s.timer = time.AfterFunc(next.Sub(now), s.expire)
+	}
+}
+
+// expire runs when an extra token's expiry passes.
+func (s *Set) expire() {
+	now := time.Now()
+	s.mu.Lock()
+	s.pruneLocked(now)
+	s.notifyLocked()
+	fn, active := s.onChange, s.activeLocked(now)
+	s.mu.Unlock()
+
+	if fn != nil {
+		fn(active)
+	}
+}
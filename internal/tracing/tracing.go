@@ -0,0 +1,196 @@
+// Package tracing exports OpenTelemetry traces of a server's work to an OTLP
+// collector (the OpenTelemetry Collector, Jaeger, Tempo, Honeycomb, …): a
+// span for each gRPC call, for each publish on the hub, for each event
+// forwarded over a federation link, and for each delivery to a watcher or
+// the local clipboard. Forwarded events carry their trace context to the
+// next server, so one trace follows an item from the copy that made it,
+// across the federation, to every host it reached.
+//
+// Until Setup is called the global tracer provider is OpenTelemetry's no-op
+// one: spans cost next to nothing and are not exported, but trace context
+// received from a caller or another server is still passed on, so a server
+// that does not export traces does not break the traces of those that do.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/stats"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+)
+
+// DefaultSampleRatio traces every publish.
+const DefaultSampleRatio = 1.0
+
+// propagator encodes trace context for gRPC metadata and federation events.
+var propagator = propagation.TraceContext{}
+
+// Config describes the OTLP exporter.
+type Config struct {
+	// Endpoint is the collector: "host:port" for OTLP over gRPC, or an
+	// http(s):// URL for OTLP over HTTP ("/v1/traces" is appended when the
+	// URL has no path).
+	Endpoint string
+	// Insecure sends OTLP over gRPC without TLS. URLs choose with their
+	// scheme.
+	Insecure bool
+	// Headers are sent with every export, e.g. an API key.
+	Headers map[string]string
+	// SampleRatio is the fraction of traces started on this server that are
+	// recorded, from 0 to 1. Traces continued from another server follow
+	// that server's decision.
+	SampleRatio float64
+	// Instance identifies this server among the services named "suffuse",
+	// normally its source name.
+	Instance string
+	Version  string
+}
+
+// ParseHeaders parses "name=value" entries.
+func ParseHeaders(entries []string) (map[string]string, error) {
+	out := make(map[string]string, len(entries))
+	for _, e := range entries {
+		name, value, ok := strings.Cut(e, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("otlp header %q: expected name=value", e)
+		}
+		out[name] = value
+	}
+	return out, nil
+}
+
+// Setup installs a global tracer provider exporting to cfg.Endpoint. The
+// returned function flushes the spans not yet exported and stops the
+// exporter.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("trace sample ratio %g: must be between 0 and 1", cfg.SampleRatio)
+	}
+	var exp sdktrace.SpanExporter
+	if strings.Contains(cfg.Endpoint, "://") {
+		u, perr := url.Parse(cfg.Endpoint)
+		if perr != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("otlp endpoint %q: must be host:port or an absolute http(s) URL", cfg.Endpoint)
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/v1/traces"
+		}
+		exp, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(u.String()), otlptracehttp.WithHeaders(cfg.Headers))
+	} else {
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint), otlptracegrpc.WithHeaders(cfg.Headers)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exp, err = otlptracegrpc.New(ctx, opts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("otlp exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName("suffuse"),
+		semconv.ServiceVersion(cfg.Version),
+		semconv.ServiceInstanceID(cfg.Instance),
+	))
+	if err != nil && !errors.Is(err, resource.ErrSchemaURLConflict) {
+		return nil, fmt.Errorf("trace resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer("go.klb.dev/suffuse")
+}
+
+// Start starts a span as a child of ctx's span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartFrom starts a span as a child of parent, the span of the publish an
+// event came from. Events outside any traced publish, e.g. stored content
+// sent to a peer that just registered, are not traced: when parent is
+// invalid the span records nothing.
+func StartFrom(parent trace.SpanContext, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx := context.Background()
+	if !parent.IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return Start(trace.ContextWithSpanContext(ctx, parent), name, attrs...)
+}
+
+// End ends span, marking it failed when err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject returns the trace context of ctx's span as W3C Trace Context
+// headers, for FederationEvent.trace_context; nil when nothing is traced.
+func Inject(ctx context.Context) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract returns ctx carrying the remote span found in headers by Inject,
+// to continue a trace from another server.
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+	if len(headers) == 0 {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier(headers))
+}
+
+// untraced lists the streams that stay open for the life of a connection,
+// whose events are traced one by one instead since a span each would cover
+// hours and say little, and the health checks probes repeat every few
+// seconds.
+var untraced = map[string]bool{
+	pb.ClipboardService_Watch_FullMethodName:    true,
+	pb.ClipboardService_Federate_FullMethodName: true,
+	healthpb.Health_Check_FullMethodName:        true,
+	healthpb.Health_Watch_FullMethodName:        true,
+}
+
+func traced(info *stats.RPCTagInfo) bool { return !untraced[info.FullMethodName] }
+
+// ServerOption traces the calls a gRPC server answers, continuing the
+// traces of callers that send their trace context.
+func ServerOption() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithFilter(traced), otelgrpc.WithPropagators(propagator)))
+}
+
+// DialOption traces the calls made on a client connection and sends their
+// trace context.
+func DialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler(otelgrpc.WithFilter(traced), otelgrpc.WithPropagators(propagator)))
+}
@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.klb.dev/suffuse/internal/hub"
+)
+
+// Request headers set on every delivery. The delivery ID is the same for all
+// attempts of one event, so receivers can deduplicate retries.
+const (
+	HeaderDelivery  = "X-Suffuse-Delivery"
+	HeaderTimestamp = "X-Suffuse-Timestamp"
+	HeaderSignature = "X-Suffuse-Signature"
+)
+
+// Sign returns the X-Suffuse-Signature value for a request body sent at
+// timestamp (Unix seconds, as in X-Suffuse-Timestamp): "sha256=" followed by
+// the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with secret. Receivers
+// recompute it, compare in constant time, and reject stale timestamps to
+// prevent replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deadLetterMu serialises appends to dead-letter files, which several hooks
+// may share.
+var deadLetterMu sync.Mutex
+
+// deadLetterRecord is one line of a dead-letter file.
+type deadLetterRecord struct {
+	Hook     string          `json:"hook"`
+	Delivery string          `json:"delivery"`
+	Time     time.Time       `json:"time"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// deliver POSTs one event, retrying until it is accepted, a non-retryable
+// response arrives, MaxAttempts is reached, or ctx is cancelled.
+func (k *Hook) deliver(ctx context.Context, ev hub.Event) {
+	body, err := json.Marshal(k.payload(ev))
+	if err != nil {
+		slog.Error("webhook payload", "hook", k.cfg.Name, "err", err)
+		return
+	}
+	id := newDeliveryID()
+	backoff := k.cfg.RetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		retryable, err := k.post(ctx, id, body)
+		if err == nil {
+			k.delivered.Add(1)
+			k.mu.Lock()
+			k.lastDelivered = time.Now()
+			k.mu.Unlock()
+			slog.Debug("webhook delivered",
+				"hook", k.cfg.Name,
+				"delivery", id,
+				"clipboard", ev.Clipboard,
+				"attempt", attempt,
+			)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		k.mu.Lock()
+		k.lastError = err.Error()
+		k.mu.Unlock()
+
+		if !retryable || attempt >= k.cfg.MaxAttempts {
+			k.deadLetter(id, attempt, err, body)
+			return
+		}
+		k.retried.Add(1)
+		slog.Warn("webhook delivery failed, retrying",
+			"hook", k.cfg.Name,
+			"delivery", id,
+			"attempt", attempt,
+			"retry_in", backoff,
+			"err", err,
+		)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// post makes a single attempt. retryable reports whether a failure may
+// succeed on a later attempt.
+func (k *Hook) post(ctx context.Context, id string, body []byte) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "suffuse-webhook")
+	for name, value := range k.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set(HeaderDelivery, id)
+	if k.cfg.Secret != "" {
+		ts := time.Now().Unix()
+		req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+		req.Header.Set(HeaderSignature, Sign(k.cfg.Secret, ts, body))
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch code := resp.StatusCode; {
+	case code >= 200 && code <= 299:
+		return false, nil
+	case code == http.StatusRequestTimeout, code == http.StatusTooManyRequests, code >= 500:
+		return true, errors.New(resp.Status)
+	default:
+		return false, errors.New(resp.Status)
+	}
+}
+
+// deadLetter records an abandoned event in the log and, when configured, the
+// dead-letter file.
+func (k *Hook) deadLetter(id string, attempts int, cause error, body []byte) {
+	k.deadLettered.Add(1)
+	slog.Error("webhook delivery abandoned",
+		"hook", k.cfg.Name,
+		"delivery", id,
+		"attempts", attempts,
+		"err", cause,
+	)
+	if k.cfg.DeadLetter == "" {
+		return
+	}
+	line, err := json.Marshal(deadLetterRecord{
+		Hook:     k.cfg.Name,
+		Delivery: id,
+		Time:     time.Now().UTC(),
+		Attempts: attempts,
+		Error:    cause.Error(),
+		Body:     body,
+	})
+	if err != nil {
+		slog.Error("webhook dead-letter", "hook", k.cfg.Name, "err", err)
+		return
+	}
+	if err := appendLine(k.cfg.DeadLetter, line); err != nil {
+		slog.Error("webhook dead-letter", "hook", k.cfg.Name, "path", k.cfg.DeadLetter, "err", err)
+	}
+}
+
+func appendLine(path string, line []byte) error {
+	deadLetterMu.Lock()
+	defer deadLetterMu.Unlock()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// newDeliveryID returns a random identifier for one event's deliveries.
+func newDeliveryID() string {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,283 @@
+// Package webhook runs server-side HTTP hooks on clipboard changes.
+//
+// Each configured Hook registers with the hub as a BroadcastPeer, so it sees
+// every publish regardless of origin (local clients, the server's own
+// clipboard, or federation). Events on clipboards matching the hook's
+// patterns are POSTed as JSON to its URL from a per-hook worker, so a slow
+// endpoint never stalls the hub; when the worker falls behind, events are
+// dropped and counted like any other slow consumer.
+//
+// Deliveries are optionally signed (see Sign), retried with exponential
+// back-off on network errors, 408, 429, and 5xx responses, and written to a
+// dead-letter log once they are given up on. Delivery metrics are reported
+// in the hook's PeerInfo.
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "go.klb.dev/suffuse/gen/suffuse/v1"
+	"go.klb.dev/suffuse/internal/hub"
+)
+
+// Defaults applied to unset Config fields.
+const (
+	DefaultTimeout      = 10 * time.Second
+	DefaultMaxAttempts  = 5
+	DefaultRetryBackoff = time.Second
+)
+
+// maxBackoff caps the delay between attempts.
+const maxBackoff = time.Minute
+
+// queueSize is the number of events a hook buffers while a delivery is in
+// flight.
+const queueSize = 64
+
+// Config describes one webhook. It is read from a [[webhooks]] table in the
+// server's config file.
+type Config struct {
+	// Name identifies the hook in logs and peer lists. Defaults to the URL's
+	// host.
+	Name string `mapstructure:"name"`
+	// URL receives an HTTP POST per matching event.
+	URL string `mapstructure:"url"`
+	// Clipboards are path.Match patterns (e.g. "links", "ci/*") selecting
+	// the clipboards that trigger the hook; empty matches every clipboard.
+	Clipboards []string `mapstructure:"clipboards"`
+	// IncludePayload adds the clipboard items to the request body. Without
+	// it only metadata (source, clipboard, types, size) is sent.
+	IncludePayload bool `mapstructure:"include-payload"`
+	// Accepts restricts the MIME types included in the payload (empty = all).
+	Accepts []string `mapstructure:"accepts"`
+	// Headers are added to every request, e.g. an Authorization header.
+	Headers map[string]string `mapstructure:"headers"`
+	// Timeout bounds each attempt; zero means DefaultTimeout.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Secret signs every request with HMAC-SHA256; see Sign. Empty sends
+	// unsigned requests.
+	Secret string `mapstructure:"secret"`
+	// MaxAttempts is the number of tries per event, including the first;
+	// zero means DefaultMaxAttempts and 1 disables retries.
+	MaxAttempts int `mapstructure:"max-attempts"`
+	// RetryBackoff is the delay before the first retry, doubled for each
+	// further retry up to one minute; zero means DefaultRetryBackoff.
+	RetryBackoff time.Duration `mapstructure:"retry-backoff"`
+	// DeadLetter is a file that receives one JSON line per abandoned event,
+	// including the request body, for inspection or replay. Abandoned events
+	// are always logged at ERROR.
+	DeadLetter string `mapstructure:"dead-letter"`
+}
+
+// Payload is the JSON body POSTed for each event.
+type Payload struct {
+	Hook      string    `json:"hook"`
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+	Clipboard string    `json:"clipboard"`
+	Types     []string  `json:"types"`
+	Size      int       `json:"size"`
+	// Text is the text/plain content when the payload is included, so chat
+	// integrations that read a top-level "text" field (e.g. Slack incoming
+	// webhooks) work without a translation layer.
+	Text  string `json:"text,omitempty"`
+	Items []Item `json:"items,omitempty"`
+}
+
+// Item is one clipboard representation; Data is base64-encoded in JSON.
+type Item struct {
+	Mime string `json:"mime"`
+	Data []byte `json:"data"`
+}
+
+// Hook delivers matching clipboard events to one URL. It implements
+// hub.BroadcastPeer.
+type Hook struct {
+	cfg    Config
+	h      *hub.Hub
+	client *http.Client
+	queue  *hub.Queue
+
+	connectedAt time.Time
+
+	delivered    atomic.Uint64
+	retried      atomic.Uint64
+	deadLettered atomic.Uint64
+
+	mu            sync.Mutex
+	lastError     string
+	lastDelivered time.Time
+}
+
+// New validates cfg and returns a Hook. Call Run to register it with the hub
+// and start delivering.
+func New(cfg Config, h *hub.Hub) (*Hook, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("webhook %q: url must be an absolute http(s) URL", cfg.URL)
+	}
+	if cfg.Name == "" {
+		cfg.Name = u.Host
+	}
+	for _, pattern := range cfg.Clipboards {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("webhook %s: clipboard pattern %q: %w", cfg.Name, pattern, err)
+		}
+	}
+	if cfg.MaxAttempts < 0 {
+		return nil, fmt.Errorf("webhook %s: max-attempts must not be negative", cfg.Name)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+	return &Hook{
+		cfg:         cfg,
+		h:           h,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		queue:       h.NewQueue(hub.DropWebhook, queueSize),
+		connectedAt: time.Now(),
+	}, nil
+}
+
+// ID implements hub.Peer.
+func (k *Hook) ID() string { return "webhook/" + k.cfg.Name }
+
+// Info implements hub.Peer. Addr is the URL's scheme and host only; webhook
+// URLs often embed secrets in the path. LastSeen is the last successful
+// delivery.
+func (k *Hook) Info() *pb.PeerInfo {
+	u, _ := url.Parse(k.cfg.URL)
+	clipboards := "*"
+	if len(k.cfg.Clipboards) > 0 {
+		clipboards = strings.Join(k.cfg.Clipboards, ",")
+	}
+	k.mu.Lock()
+	lastErr, lastDelivered := k.lastError, k.lastDelivered
+	k.mu.Unlock()
+	lastSeen := k.connectedAt
+	stats := &pb.WebhookStats{
+		Delivered:    k.delivered.Load(),
+		Retried:      k.retried.Load(),
+		DeadLettered: k.deadLettered.Load(),
+		Queued:       uint32(k.queue.Len()),
+		LastError:    lastErr,
+	}
+	if !lastDelivered.IsZero() {
+		lastSeen = lastDelivered
+		stats.LastDeliveredAt = timestamppb.New(lastDelivered)
+	}
+	return &pb.PeerInfo{
+		Source:        k.cfg.Name,
+		Addr:          u.Scheme + "://" + u.Host,
+		Role:          "webhook",
+		Clipboard:     clipboards,
+		AcceptedTypes: k.cfg.Accepts,
+		ConnectedAt:   timestamppb.New(k.connectedAt),
+		LastSeen:      timestamppb.New(lastSeen),
+		Webhook:       stats,
+	}
+}
+
+// Broadcast implements hub.BroadcastPeer.
+func (k *Hook) Broadcast() {}
+
+// Send implements hub.Peer. Events on clipboards the hook does not match are
+// ignored.
+func (k *Hook) Send(ev hub.Event) error {
+	if !k.matches(ev.Clipboard) {
+		return nil
+	}
+	return k.queue.Put(ev)
+}
+
+// Queue implements hub.QueuePeer.
+func (k *Hook) Queue() *hub.Queue { return k.queue }
+
+func (k *Hook) matches(cb string) bool {
+	if len(k.cfg.Clipboards) == 0 {
+		return true
+	}
+	for _, pattern := range k.cfg.Clipboards {
+		if ok, _ := path.Match(pattern, cb); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Run registers the hook with the hub and delivers events until ctx is
+// cancelled.
+func (k *Hook) Run(ctx context.Context) {
+	k.h.Register(k)
+	defer k.h.Unregister(k)
+
+	slog.Info("webhook enabled",
+		"hook", k.cfg.Name,
+		"clipboards", k.cfg.Clipboards,
+		"include_payload", k.cfg.IncludePayload,
+		"signed", k.cfg.Secret != "",
+		"max_attempts", k.cfg.MaxAttempts,
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-k.queue.C():
+			k.deliver(ctx, ev)
+		}
+	}
+}
+
+func (k *Hook) payload(ev hub.Event) Payload {
+	p := Payload{
+		Hook:      k.cfg.Name,
+		Time:      time.Now().UTC(),
+		Source:    ev.Source,
+		Clipboard: ev.Clipboard,
+		Types:     []string{},
+	}
+	for _, it := range ev.Items {
+		p.Types = append(p.Types, it.Mime)
+		p.Size += len(it.Data)
+	}
+	if !k.cfg.IncludePayload {
+		return p
+	}
+	for _, it := range ev.Items {
+		if len(k.cfg.Accepts) > 0 && !contains(k.cfg.Accepts, it.Mime) {
+			continue
+		}
+		p.Items = append(p.Items, Item{Mime: it.Mime, Data: it.Data})
+		if it.Mime == "text/plain" && p.Text == "" {
+			p.Text = string(it.Data)
+		}
+	}
+	return p
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+// Package winsvc runs the server as a Windows service: it registers the
+// service with the service control manager, starts and stops it, and, when
+// the control manager starts the process, answers its control requests and
+// sends the server's logs to the Windows Event Log under the service's name.
+//
+// On other platforms Supported is false, IsService reports false and every
+// other call fails.
+package winsvc
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// DefaultName is the name the service is registered under unless another is
+// given. It is also the Event Log source of the service's logs.
+const DefaultName = "SuffuseServer"
+
+// Config describes the service Install registers.
+type Config struct {
+	// Name is the service's name; empty means DefaultName.
+	Name string
+	// DisplayName and Description are shown in the Services console.
+	DisplayName string
+	Description string
+	// Args are the arguments the executable is started with, e.g.
+	// "server" followed by its flags.
+	Args []string
+	// Manual registers a service started on demand rather than at boot.
+	Manual bool
+}
+
+// Service is the running service, as passed by Run to the function it runs.
+// A nil Service, that of a process not started by the control manager, does
+// nothing.
+type Service struct {
+	// Name is the name the service was started under.
+	Name string
+
+	ready    func()
+	handler  func(level slog.Leveler) slog.Handler
+	mu       sync.Mutex
+	onReload func()
+}
+
+// Ready reports the service as running to the control manager, which until
+// then shows it as starting.
+func (s *Service) Ready() {
+	if s != nil {
+		s.ready()
+	}
+}
+
+// LogHandler returns a handler writing records of level and above to the
+// Event Log under the service's name.
+func (s *Service) LogHandler(level slog.Leveler) slog.Handler {
+	return s.handler(level)
+}
+
+// OnReload sets the function called when the control manager sends the
+// service a parameter change ("sc.exe control <name> paramchange").
+func (s *Service) OnReload(f func()) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.onReload = f
+	s.mu.Unlock()
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package winsvc
+
+import (
+	"context"
+	"errors"
+)
+
+// Supported reports whether the platform has Windows services.
+const Supported = false
+
+var errUnsupported = errors.New("services are not supported on this platform")
+
+// IsService reports false: only Windows has a service control manager.
+func IsService() bool { return false }
+
+// Run fails; see the Windows implementation.
+func Run(func(ctx context.Context, s *Service) error) error { return errUnsupported }
+
+// Install fails; see the Windows implementation.
+func Install(Config) error { return errUnsupported }
+
+// Uninstall fails; see the Windows implementation.
+func Uninstall(string) error { return errUnsupported }
+
+// Start fails; see the Windows implementation.
+func Start(string) error { return errUnsupported }
+
+// Stop fails; see the Windows implementation.
+func Stop(string) error { return errUnsupported }
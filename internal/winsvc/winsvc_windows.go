@@ -0,0 +1,333 @@
+//go:build windows
+
+package winsvc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Supported reports whether the platform has Windows services.
+const Supported = true
+
+// eventID is the ID of every event logged. Sources registered by
+// eventlog.InstallAsEventCreate accept IDs 1 to 1000 with the message as
+// given.
+const eventID = 1
+
+// stateTimeout bounds the wait for the service to start or stop.
+const stateTimeout = 30 * time.Second
+
+// IsService reports whether the process was started by the service control
+// manager.
+func IsService() bool {
+	ok, err := svc.IsWindowsService()
+	return err == nil && ok
+}
+
+// Run runs the process as the service the control manager started and
+// returns when it stops. run is called with a context cancelled when the
+// control manager stops the service, and must call s.Ready once it serves.
+// Should run return first, the service stops, as failed when run returned an
+// error, so the recovery actions set by Install restart it.
+func Run(run func(ctx context.Context, s *Service) error) error {
+	// The name is ignored for a service running in a process of its own;
+	// Execute learns it from its arguments.
+	return svc.Run("", &handler{run: run})
+}
+
+type handler struct {
+	run func(ctx context.Context, s *Service) error
+}
+
+// Execute implements svc.Handler.
+func (h *handler) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	name := DefaultName
+	if len(args) > 0 {
+		name = args[0]
+	}
+	changes <- svc.Status{State: svc.StartPending}
+
+	elog, err := eventlog.Open(name)
+	if err != nil {
+		return true, 1
+	}
+	defer elog.Close()
+
+	const accepts = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptParamChange
+	var once sync.Once
+	s := &Service{
+		Name: name,
+		ready: func() {
+			once.Do(func() { changes <- svc.Status{State: svc.Running, Accepts: accepts} })
+		},
+		handler: func(level slog.Leveler) slog.Handler { return newEventLogHandler(elog, level) },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- h.run(ctx, s) }()
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				elog.Error(eventID, "service failed: "+err.Error()) //nolint:errcheck
+				return true, 1
+			}
+			return false, 0
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				changes <- c.CurrentStatus
+			case svc.ParamChange:
+				go s.reload()
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				cancel()
+				select {
+				case <-done:
+				case <-time.After(stateTimeout):
+				}
+				return false, 0
+			}
+		}
+	}
+}
+
+// reload calls the function set by OnReload, if any.
+func (s *Service) reload() {
+	s.mu.Lock()
+	f := s.onReload
+	s.mu.Unlock()
+	if f != nil {
+		f()
+	}
+}
+
+// Install registers the running executable as the service cfg describes,
+// restarting it when it fails, and its Event Log source. It needs
+// administrator rights.
+func Install(cfg Config) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.Abs(exe); err != nil {
+		return err
+	}
+	name := serviceName(cfg.Name)
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect() //nolint:errcheck
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s is already installed", name)
+	}
+
+	start := uint32(mgr.StartAutomatic)
+	if cfg.Manual {
+		start = mgr.StartManual
+	}
+	s, err := m.CreateService(name, exe, mgr.Config{
+		DisplayName: cfg.DisplayName,
+		Description: cfg.Description,
+		StartType:   start,
+	}, cfg.Args...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	err = s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: 10 * time.Second},
+	}, 60)
+	if err == nil {
+		err = eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info)
+		if err != nil && strings.Contains(err.Error(), "already exists") {
+			err = nil // left by an earlier installation
+		}
+	}
+	if err != nil {
+		s.Delete() //nolint:errcheck
+		return err
+	}
+	return nil
+}
+
+// Uninstall stops the service name, if it runs, and removes it and its Event
+// Log source. Empty name means DefaultName.
+func Uninstall(name string) error {
+	name = serviceName(name)
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect() //nolint:errcheck
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s: %w", name, err)
+	}
+	defer s.Close()
+
+	if st, err := s.Query(); err == nil && st.State != svc.Stopped {
+		if err := stop(s); err != nil {
+			return err
+		}
+	}
+	if err := s.Delete(); err != nil {
+		return err
+	}
+	if err := eventlog.Remove(name); err != nil {
+		return fmt.Errorf("event log source: %w", err)
+	}
+	return nil
+}
+
+// Start starts the service name and waits until it runs. Empty name means
+// DefaultName.
+func Start(name string) error {
+	return control(name, func(s *mgr.Service) error {
+		if err := s.Start(); err != nil {
+			return err
+		}
+		return waitFor(s, svc.Running)
+	})
+}
+
+// Stop stops the service name and waits until it has stopped. Empty name
+// means DefaultName.
+func Stop(name string) error {
+	return control(name, stop)
+}
+
+func stop(s *mgr.Service) error {
+	if _, err := s.Control(svc.Stop); err != nil {
+		return err
+	}
+	return waitFor(s, svc.Stopped)
+}
+
+// control opens the service name and calls f with it.
+func control(name string, f func(s *mgr.Service) error) error {
+	name = serviceName(name)
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect() //nolint:errcheck
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s: %w", name, err)
+	}
+	defer s.Close()
+	return f(s)
+}
+
+// waitFor waits up to stateTimeout for s to reach state. A service that
+// stops while it is to start has failed.
+func waitFor(s *mgr.Service, state svc.State) error {
+	deadline := time.Now().Add(stateTimeout)
+	for {
+		st, err := s.Query()
+		if err != nil {
+			return err
+		}
+		switch {
+		case st.State == state:
+			return nil
+		case st.State == svc.Stopped:
+			return errors.New("service stopped; see the Event Log for why")
+		case time.Now().After(deadline):
+			return fmt.Errorf("service still not in the requested state after %s", stateTimeout)
+		}
+		time.Sleep(300 * time.Millisecond)
+	}
+}
+
+func serviceName(name string) string {
+	if name == "" {
+		return DefaultName
+	}
+	return name
+}
+
+// eventLogHandler writes records in slog's text format to the Event Log,
+// as errors, warnings or information by their level. The Event Log records
+// the time itself.
+type eventLogHandler struct {
+	elog *eventlog.Log
+	mu   *sync.Mutex
+	buf  *bytes.Buffer // text's output, under mu
+	text slog.Handler
+}
+
+func newEventLogHandler(elog *eventlog.Log, level slog.Leveler) slog.Handler {
+	buf := new(bytes.Buffer)
+	return &eventLogHandler{
+		elog: elog,
+		mu:   new(sync.Mutex),
+		buf:  buf,
+		text: slog.NewTextHandler(buf, &slog.HandlerOptions{
+			Level: level,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if len(groups) == 0 && a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}),
+	}
+}
+
+func (h *eventLogHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.text.Enabled(ctx, l)
+}
+
+func (h *eventLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buf.Reset()
+	if err := h.text.Handle(ctx, r); err != nil {
+		return err
+	}
+	msg := strings.TrimSuffix(h.buf.String(), "\n")
+	switch {
+	case r.Level >= slog.LevelError:
+		return h.elog.Error(eventID, msg)
+	case r.Level >= slog.LevelWarn:
+		return h.elog.Warning(eventID, msg)
+	default:
+		return h.elog.Info(eventID, msg)
+	}
+}
+
+func (h *eventLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.text = h.text.WithAttrs(attrs)
+	return &c
+}
+
+func (h *eventLogHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.text = h.text.WithGroup(name)
+	return &c
+}